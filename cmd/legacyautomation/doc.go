@@ -37,10 +37,18 @@ Flags:
 
 	-build
 	  	The _BUILD flag (true/false) to Librarian CLI's -build option
+	-credentials string
+	  	Path to a service account key file used to authenticate with Cloud Build. If not set, application default credentials are used
+	-log-format string
+	  	The format of the logs, either "text" or "json" (default "text")
+	-log-level string
+	  	The minimum level of the logs, one of "debug", "info", "warn" or "error" (default "info")
 	-project string
 	  	Google Cloud Platform project ID (default "cloud-sdk-librarian-prod")
 	-push
 	  	The _PUSH flag (true/false) to Librarian CLI's -push option
+	-trigger-name string
+	  	The name of the Cloud Build trigger to run instead of the default trigger for the command
 
 # publish-release
 
@@ -53,8 +61,16 @@ Usage:
 
 Flags:
 
+	-credentials string
+	  	Path to a service account key file used to authenticate with Cloud Build. If not set, application default credentials are used
+	-log-format string
+	  	The format of the logs, either "text" or "json" (default "text")
+	-log-level string
+	  	The minimum level of the logs, one of "debug", "info", "warn" or "error" (default "info")
 	-project string
 	  	Google Cloud Platform project ID (default "cloud-sdk-librarian-prod")
+	-trigger-name string
+	  	The name of the Cloud Build trigger to run instead of the default trigger for the command
 
 # stage-release
 
@@ -67,10 +83,18 @@ Usage:
 
 Flags:
 
+	-credentials string
+	  	Path to a service account key file used to authenticate with Cloud Build. If not set, application default credentials are used
+	-log-format string
+	  	The format of the logs, either "text" or "json" (default "text")
+	-log-level string
+	  	The minimum level of the logs, one of "debug", "info", "warn" or "error" (default "info")
 	-project string
 	  	Google Cloud Platform project ID (default "cloud-sdk-librarian-prod")
 	-push
 	  	The _PUSH flag (true/false) to Librarian CLI's -push option
+	-trigger-name string
+	  	The name of the Cloud Build trigger to run instead of the default trigger for the command
 
 # version
 
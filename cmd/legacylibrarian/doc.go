@@ -26,15 +26,91 @@ Usage:
 
 The commands are:
 
+# debug
+
+Provides tools for troubleshooting Librarian.
+
+Usage:
+
+	librarian debug <command> [arguments]
+
+Commands:
+
+	parse-commit               prints how a commit message is parsed as conventional commits.
+
+# debug parse-commit
+
+The 'debug parse-commit' command prints how a commit message is
+interpreted by the conventional commit parser used by the 'release stage'
+command. This helps to find out why a commit does not trigger the expected
+version bump.
+
+The message is read from stdin, or from the commit with the given full hash in
+the language repository. Each conventional commit found in the message is
+printed as JSON, with its type, scope, subject, body, footers, whether it is a
+breaking change or a nested commit, and the library it is associated with. The
+'--library' flag sets the library of commits which do not name one.
+
+Examples:
+
+	# Parse a message from stdin
+	echo 'feat(parser)!: add a feature' | librarian debug parse-commit
+
+	# Parse a commit of the language repository
+	librarian debug parse-commit --repo=/path/to/repo <commit-hash>
+
+Usage:
+
+	librarian debug parse-commit [flags] [commit-hash]
+
+Flags:
+
+	-branch string
+	  	The branch to use with remote code repositories. It is ignored if
+	  	you are using a local repository. This is used to specify which branch to clone
+	  	and which branch to use as the base for a pull request. (default "main")
+	-library string
+	  	The library ID to generate or release (e.g. secretmanager).
+	  	This corresponds to a releasable language unit. The release stage command
+	  	also accepts a comma-separated list of library IDs to release together.
+	-log-format string
+	  	The format of the logs, either "text" or "json". (default "text")
+	-log-level string
+	  	The minimum level of the logs, one of "debug", "info", "warn" or "error".
+	  	The -v flag takes precedence and selects "debug", and -quiet selects
+	  	"error". (default "info")
+	-output string
+	  	Working directory root. When this is not specified, a working directory
+	  	will be created in /tmp.
+	-q	Shorthand for -quiet.
+	-quiet
+	  	Only log errors, and only show the output of language containers when
+	  	they fail. By default, logs are written at the level of -log-level, and the
+	  	output of containers is shown as it arrives, each line prefixed with the
+	  	library ID and command, e.g. "[secretmanager generate]". The -v flag takes
+	  	precedence over the log level.
+	-repo string
+	  	Code repository where the generated code will reside. Can be a remote
+	  	in the format of a remote URL such as https://github.com/{owner}/{repo} or a
+	  	local file path like /path/to/repo. Both absolute and relative paths are
+	  	supported. If not specified, the closest directory holding a .librarian/state.yaml
+	  	file, starting from the current working directory and walking up its parents,
+	  	is used as the language repository.
+	  	Note: When using a local repository (either by providing a path or by defaulting
+	  	to the current directory), Librarian creates a new branch from the currently checked-out
+	  	branch and commits changes. If the --push flag is also specified, a pull request is
+	  	created against the main branch. The --branch flag is ignored for local repositories.
+	-v	enables verbose logging
+
 # generate
 
 The generate command is the primary tool for all code generation
 tasks. It handles both the initial setup of a new library (onboarding) and the
 regeneration of existing ones. Librarian works by delegating language-specific
 tasks to a container, which is configured in the .librarian/state.yaml file.
-Librarian is environment aware and will check if the current directory is the
-root of a librarian repository. If you are not executing in such a directory the
-'--repo' flag must be provided.
+Librarian is environment aware and will check if the current directory is inside
+a librarian repository, looking for .librarian/state.yaml in it and its parents.
+If you are not executing in such a directory the '--repo' flag must be provided.
 
 # Onboarding a new library
 
@@ -91,12 +167,37 @@ Usage:
 
 Flags:
 
+	-allow-missing-service-config
+	  	Configure a new API even if no service config is found in its directory,
+	  	as a proto-only package. The container is sent an empty service config and
+	  	decides how to handle it. Set to false to fail instead. (default true)
+	-amend
+	  	Amend the previous commit instead of creating a new one, if it was
+	  	created by generate with -amend. Requires -commit and cannot be used with
+	  	-push. This is useful for iterating locally without cluttering history.
+	  	For release stage, update the open release pull request of a previous run
+	  	instead of creating a new one, replacing its commits and the managed changelog
+	  	section of its body, so that edits made outside of it are kept. Requires -push.
 	-api string
 	  	Relative path to the API to be configured/generated (e.g., google/cloud/functions/v2).
 	  	Must be specified when generating a new library.
 	-api-source string
 	  	The location of an API specification repository.
-	  	Can be a remote URL or a local file path. (default "https://github.com/googleapis/googleapis")
+	  	Can be a remote URL or a local file path. The generate command accepts a
+	  	comma-separated list of locations, which are layered into a single source
+	  	tree: files of later sources override those of earlier ones. (default "https://github.com/googleapis/googleapis")
+	-author string
+	  	The author of commits created by Librarian, of the form "Name <email>",
+	  	e.g. "Release Bot <release-bot@example.com>", who is also their committer unless
+	  	-committer is specified. Cannot be used with -author-name or -author-email.
+	-author-email string
+	  	The email address of the author of commits created by Librarian, who is
+	  	also their committer unless -committer is specified. If not specified, the email
+	  	address from git config is used, falling back to the Librarian bot.
+	-author-name string
+	  	The name of the author of commits created by Librarian, who is also
+	  	their committer unless -committer is specified. If not specified, the name from
+	  	git config is used, falling back to the Librarian bot.
 	-branch string
 	  	The branch to use with remote code repositories. It is ignored if
 	  	you are using a local repository. This is used to specify which branch to clone
@@ -104,9 +205,80 @@ Flags:
 	-build
 	  	If true, Librarian will build each generated library by invoking the
 	  	language-specific container.
+	-cache-dir string
+	  	A directory in which to keep the clone of -api-source between runs, when
+	  	it is a URL. An existing clone is fetched and checked out instead of cloning
+	  	again. If not specified, the repository is cloned into the work root.
+	-commit-message-template string
+	  	The Go text/template of the commit message, e.g.
+	  	"feat: generate {{.LibraryID}}". The template may use {{.LibraryID}} and
+	  	{{.Version}}, set when a single library is changed, {{.LibraryIDs}} and
+	  	{{.APIPaths}}. Lists can be joined with {{join .APIPaths ", "}}. Overrides the
+	  	template configured in config.yaml. If not specified, the default message is
+	  	used.
+	-committer string
+	  	The committer of commits created by Librarian, of the form
+	  	"Name <email>", e.g. "CI <ci@example.com>". If not specified, the author of the
+	  	commits is also their committer.
+	-concurrency int
+	  	The maximum number of libraries to generate at the same time when
+	  	generating all libraries. Each library is generated and built in its own
+	  	language container. A value of 1 generates libraries one after another. (default 1)
+	-container-cpus string
+	  	The number of CPUs language containers may use, e.g. 1.5. Passed to
+	  	docker run as --cpus. If not specified, the number of CPUs is not limited.
+	-container-log-dir string
+	  	The directory to write the output of language containers to as it is
+	  	produced, in a file per library named after the library ID, e.g.
+	  	google_cloud_foo.log. Useful to follow long-running generations with tail -f.
+	  	If not specified, the output is only written to the console.
+	-container-memory string
+	  	The maximum amount of memory language containers may use, e.g. 4g.
+	  	Passed to docker run as --memory. If not specified, memory is not limited.
+	-container-reuse
+	  	Send the generate requests of all libraries to a single long-lived
+	  	language container, started with the serve command, instead of running a
+	  	container per library. Only applies to images with the
+	  	com.google.librarian.serve=true label.
+	-container-timeout duration
+	  	The maximum duration of each language container run, e.g. 1h. A container
+	  	still running once it elapses is killed, and the command fails. If zero,
+	  	containers may run forever. (default 30m0s)
+	-dest-prefix string
+	  	A directory of the language repository, relative to its root, under which
+	  	the generated code is written. The source roots, preserve_regex and
+	  	remove_regex of libraries are then relative to this directory, while
+	  	state.yaml is left unchanged.
+	-detect-renamed-apis
+	  	Migrate the APIs whose paths no longer exist in the API source to their
+	  	new paths before generating. The new path of an API is the one configured
+	  	in api_renames in config.yaml or, if none, the only newer stable version
+	  	directory next to its old path, e.g. google/cloud/x/v2 for google/cloud/x/v1
+	  	or google/cloud/x/v1 for google/cloud/x/v1beta1. Other renames must be
+	  	configured in api_renames.
+	-dry-run
+	  	Report the libraries that would be configured and generated, without
+	  	running any containers or changing any files. No commit or pull request is
+	  	created, even if the --push flag is specified.
+	-fail-fast
+	  	Stop at the first library that fails to generate or build, and return
+	  	its error. By default, the remaining libraries are generated and all failures
+	  	are reported at the end.
+	-force
+	  	Process the libraries which are frozen in the librarian config. By default,
+	  	frozen libraries are skipped with a warning, even when named with --library.
 	-generate-unchanged
 	  	If true, librarian generates libraries even if none of their associated APIs
 	  	have changed. This does not override generation being blocked by configuration.
+	-generate-unchanged-for value
+	  	The ID of a library to generate even if none of its associated APIs have
+	  	changed. May be repeated to name several libraries. Other libraries are still
+	  	only generated when their APIs have changed. This does not override generation
+	  	being blocked by configuration.
+	-generator-input string
+	  	The path of a directory to mount as the generator input of the configure
+	  	and generate containers, instead of .librarian/generator-input in the language
+	  	repository. The directory must exist.
 	-host-mount string
 	  	For use when librarian is running in a container. A mapping of a
 	  	directory from the host to the container, in the format
@@ -114,27 +286,112 @@ Flags:
 	-image string
 	  	Language specific image used to invoke code generation and releasing.
 	  	If not specified, the image configured in the state.yaml is used.
+	-language string
+	  	Only process the libraries of the given language (e.g. rust), as set by
+	  	their language in state.yaml. This is intended for repositories whose state.yaml
+	  	holds libraries of several languages. If not specified, all libraries are
+	  	processed.
 	-library string
 	  	The library ID to generate or release (e.g. secretmanager).
-	  	This corresponds to a releasable language unit.
+	  	This corresponds to a releasable language unit. The release stage command
+	  	also accepts a comma-separated list of library IDs to release together.
+	-log-format string
+	  	The format of the logs, either "text" or "json". (default "text")
+	-log-level string
+	  	The minimum level of the logs, one of "debug", "info", "warn" or "error".
+	  	The -v flag takes precedence and selects "debug", and -quiet selects
+	  	"error". (default "info")
+	-max-containers int
+	  	The maximum number of language containers to run at the same time,
+	  	across the generation and build of all libraries. This is independent of
+	  	-concurrency, and protects machines with limited resources. Defaults to 0,
+	  	which does not limit the number of containers.
+	-mount value
+	  	An extra volume to mount into the language containers running the build,
+	  	configure and generate commands, e.g. a module cache, in the format
+	  	<host-dir>:<container-dir>[:ro]. The host directory must exist. May be
+	  	repeated to mount several volumes.
+	-no-lock
+	  	Do not lock the language repository. By default, the command fails if
+	  	another librarian process is running against the same repository.
+	-no-network value
+	  	A language container command, one of "build", "configure" or "generate",
+	  	to run without network access, passing --network=none to docker run. May be
+	  	repeated to name several commands. Containers of other commands use the default
+	  	network, as some need it, e.g. to fetch dependencies during build.
+	-normalize-line-endings string
+	  	Convert the line endings of generated text files to "lf" or "crlf" before
+	  	copying them into the language repository. Binary files are left untouched.
+	  	If not specified, line endings are left as generated.
+	-only-changed
+	  	Generate only the libraries with uncommitted changes, either in their
+	  	source roots in the language repository or in their APIs in the API source,
+	  	whether or not their APIs changed since they were last generated. When used
+	  	with -library or -api, the library is skipped unless it has such changes.
 	-output string
 	  	Working directory root. When this is not specified, a working directory
 	  	will be created in /tmp.
+	-output-manifest string
+	  	The path of a JSON file to write, for each generated library, the files
+	  	it created, modified and removed in the language repository, relative to the
+	  	root of the repository. Libraries which fail to generate, build or lint are
+	  	restored and left out.
+	-output-state string
+	  	The path of a file to write the resulting state to, in addition to
+	  	.librarian/state.yaml in the language repository.
+	-pr-template string
+	  	The path of a pull request template, relative to the root of the language
+	  	repository, e.g. .github/PULL_REQUEST_TEMPLATE.md. Its content is prepended
+	  	to the body of the created pull request, before the section managed by
+	  	Librarian. If not specified, no template is used.
+	-progress-interval duration
+	  	The minimum time between two progress logs when processing all libraries,
+	  	e.g. 1m. Each log includes the number of libraries processed so far and an
+	  	estimate of the remaining time. If zero, no progress is logged.
+	-pubsub-topic string
+	  	A Pub/Sub topic, of the form projects/{project}/topics/{topic}, to which
+	  	a JSON message is published for each library with its ID, outcome and version
+	  	as soon as the library is processed. Once a pull request is created, a message
+	  	with its URL is published for each generated library. Application default
+	  	credentials are used. Failures to publish are logged as warnings and do not fail the run.
 	-push
 	  	If true, Librarian will create a commit,
 	  	push and create a pull request for the changes.
 	  	A GitHub token with push access must be provided via the
 	  	LIBRARIAN_GITHUB_TOKEN environment variable.
+	-q	Shorthand for -quiet.
+	-quiet
+	  	Only log errors, and only show the output of language containers when
+	  	they fail. By default, logs are written at the level of -log-level, and the
+	  	output of containers is shown as it arrives, each line prefixed with the
+	  	library ID and command, e.g. "[secretmanager generate]". The -v flag takes
+	  	precedence over the log level.
+	-rebase-onto-base
+	  	Rebase the generated branch onto the latest base branch (see --branch)
+	  	before pushing it, so that the pull request can be merged cleanly. If the
+	  	rebase has conflicts, the conflicting files are reported and no pull request
+	  	is created. Requires the --push flag.
 	-repo string
 	  	Code repository where the generated code will reside. Can be a remote
 	  	in the format of a remote URL such as https://github.com/{owner}/{repo} or a
 	  	local file path like /path/to/repo. Both absolute and relative paths are
-	  	supported. If not specified, will try to detect if the current working directory
-	  	is configured as a language repository.
+	  	supported. If not specified, the closest directory holding a .librarian/state.yaml
+	  	file, starting from the current working directory and walking up its parents,
+	  	is used as the language repository.
 	  	Note: When using a local repository (either by providing a path or by defaulting
 	  	to the current directory), Librarian creates a new branch from the currently checked-out
 	  	branch and commits changes. If the --push flag is also specified, a pull request is
 	  	created against the main branch. The --branch flag is ignored for local repositories.
+	-report string
+	  	The path of a JSON file to write a summary of the run to. For each
+	  	library, the summary records whether it was configured, regenerated or skipped,
+	  	whether generation and build succeeded, how long it took, its resulting
+	  	last_generated_commit and any error. The summary is written even if some
+	  	libraries fail to generate.
+	-skip-configure
+	  	Skip configuring the library even if it appears to need configuration,
+	  	and generate it with its existing configuration instead. The library must
+	  	already have source roots in state.yaml.
 	-v	enables verbose logging
 
 # release
@@ -156,8 +413,9 @@ The 'release stage' command is the primary entry point for staging
 a new release. It automates the creation of a release pull request by parsing
 conventional commits, determining the next semantic version for each library,
 and generating a changelog. Librarian is environment aware and will check if the
-current directory is the root of a librarian repository. If you are not
-executing in such a directory the '--repo' flag must be provided.
+current directory is inside a librarian repository, looking for
+.librarian/state.yaml in it and its parents. If you are not executing in such a
+directory the '--repo' flag must be provided.
 
 This command scans the git history since the last release, identifies changes
 (feat, fix, BREAKING CHANGE), and calculates the appropriate version bump
@@ -196,6 +454,25 @@ Usage:
 
 Flags:
 
+	-amend
+	  	Amend the previous commit instead of creating a new one, if it was
+	  	created by generate with -amend. Requires -commit and cannot be used with
+	  	-push. This is useful for iterating locally without cluttering history.
+	  	For release stage, update the open release pull request of a previous run
+	  	instead of creating a new one, replacing its commits and the managed changelog
+	  	section of its body, so that edits made outside of it are kept. Requires -push.
+	-author string
+	  	The author of commits created by Librarian, of the form "Name <email>",
+	  	e.g. "Release Bot <release-bot@example.com>", who is also their committer unless
+	  	-committer is specified. Cannot be used with -author-name or -author-email.
+	-author-email string
+	  	The email address of the author of commits created by Librarian, who is
+	  	also their committer unless -committer is specified. If not specified, the email
+	  	address from git config is used, falling back to the Librarian bot.
+	-author-name string
+	  	The name of the author of commits created by Librarian, who is also
+	  	their committer unless -committer is specified. If not specified, the name from
+	  	git config is used, falling back to the Librarian bot.
 	-branch string
 	  	The branch to use with remote code repositories. It is ignored if
 	  	you are using a local repository. This is used to specify which branch to clone
@@ -203,33 +480,140 @@ Flags:
 	-commit
 	  	If true, librarian will create a commit for the change but not create
 	  	a pull request. This flag is ignored if push is set to true.
+	-commit-message-template string
+	  	The Go text/template of the commit message, e.g.
+	  	"feat: generate {{.LibraryID}}". The template may use {{.LibraryID}} and
+	  	{{.Version}}, set when a single library is changed, {{.LibraryIDs}} and
+	  	{{.APIPaths}}. Lists can be joined with {{join .APIPaths ", "}}. Overrides the
+	  	template configured in config.yaml. If not specified, the default message is
+	  	used.
+	-committer string
+	  	The committer of commits created by Librarian, of the form
+	  	"Name <email>", e.g. "CI <ci@example.com>". If not specified, the author of the
+	  	commits is also their committer.
+	-container-cpus string
+	  	The number of CPUs language containers may use, e.g. 1.5. Passed to
+	  	docker run as --cpus. If not specified, the number of CPUs is not limited.
+	-container-memory string
+	  	The maximum amount of memory language containers may use, e.g. 4g.
+	  	Passed to docker run as --memory. If not specified, memory is not limited.
+	-container-timeout duration
+	  	The maximum duration of each language container run, e.g. 1h. A container
+	  	still running once it elapses is killed, and the command fails. If zero,
+	  	containers may run forever. (default 30m0s)
+	-exclude-commit value
+	  	The hash of a commit, full or abbreviated, to leave out of the release.
+	  	The changes of the commit are neither listed in the release notes nor
+	  	considered when determining the next version. May be repeated to exclude
+	  	several commits.
+	-fetch-before-stage
+	  	Fetch the branch (see --branch) and tags of the origin remote before
+	  	staging a release, and fail if the local branch is behind the remote one, so
+	  	that versions are computed from the current tags. Repositories without an
+	  	origin remote are not fetched. Use --fetch-before-stage=false to disable. (default true)
+	-force
+	  	Process the libraries which are frozen in the librarian config. By default,
+	  	frozen libraries are skipped with a warning, even when named with --library.
+	-ignore-freeze
+	  	Stage releases even though they are frozen by the freeze_until option
+	  	of .librarian/config.yaml.
 	-image string
 	  	Language specific image used to invoke code generation and releasing.
 	  	If not specified, the image configured in the state.yaml is used.
+	-include-hidden-commits
+	  	With -report-unreleased, also list the commits since the last release
+	  	of each library which are not released on their own, such as chore,
+	  	test and build commits, in a separate "not released" section.
+	-language string
+	  	Only process the libraries of the given language (e.g. rust), as set by
+	  	their language in state.yaml. This is intended for repositories whose state.yaml
+	  	holds libraries of several languages. If not specified, all libraries are
+	  	processed.
 	-library string
 	  	The library ID to generate or release (e.g. secretmanager).
-	  	This corresponds to a releasable language unit.
+	  	This corresponds to a releasable language unit. The release stage command
+	  	also accepts a comma-separated list of library IDs to release together.
 	-library-version string
 	  	Overrides the automatic semantic version calculation and forces a specific
-	  	version for a library. Requires the --library flag to be specified.
+	  	version for a library. Requires the --library flag to be specified with a
+	  	single library ID.
+	-log-format string
+	  	The format of the logs, either "text" or "json". (default "text")
+	-log-level string
+	  	The minimum level of the logs, one of "debug", "info", "warn" or "error".
+	  	The -v flag takes precedence and selects "debug", and -quiet selects
+	  	"error". (default "info")
+	-max-changelog-entries int
+	  	The maximum number of changes to list for each library in the release
+	  	pull request body. Remaining changes are summarized with a link to the full
+	  	comparison. Defaults to 0, which lists every change.
+	-max-commits int
+	  	The maximum number of commits to scan for each library since its last
+	  	release, keeping the most recent ones. The release notes mention the number of
+	  	earlier commits. Defaults to 0, which scans every commit.
+	-no-lock
+	  	Do not lock the language repository. By default, the command fails if
+	  	another librarian process is running against the same repository.
 	-output string
 	  	Working directory root. When this is not specified, a working directory
 	  	will be created in /tmp.
+	-output-state string
+	  	The path of a file to write the resulting state to, in addition to
+	  	.librarian/state.yaml in the language repository.
+	-pr-template string
+	  	The path of a pull request template, relative to the root of the language
+	  	repository, e.g. .github/PULL_REQUEST_TEMPLATE.md. Its content is prepended
+	  	to the body of the created pull request, before the section managed by
+	  	Librarian. If not specified, no template is used.
+	-progress-interval duration
+	  	The minimum time between two progress logs when processing all libraries,
+	  	e.g. 1m. Each log includes the number of libraries processed so far and an
+	  	estimate of the remaining time. If zero, no progress is logged.
 	-push
 	  	If true, Librarian will create a commit,
 	  	push and create a pull request for the changes.
 	  	A GitHub token with push access must be provided via the
 	  	LIBRARIAN_GITHUB_TOKEN environment variable.
+	-q	Shorthand for -quiet.
+	-quiet
+	  	Only log errors, and only show the output of language containers when
+	  	they fail. By default, logs are written at the level of -log-level, and the
+	  	output of containers is shown as it arrives, each line prefixed with the
+	  	library ID and command, e.g. "[secretmanager generate]". The -v flag takes
+	  	precedence over the log level.
+	-rebase-onto-base
+	  	Rebase the generated branch onto the latest base branch (see --branch)
+	  	before pushing it, so that the pull request can be merged cleanly. If the
+	  	rebase has conflicts, the conflicting files are reported and no pull request
+	  	is created. Requires the --push flag.
 	-repo string
 	  	Code repository where the generated code will reside. Can be a remote
 	  	in the format of a remote URL such as https://github.com/{owner}/{repo} or a
 	  	local file path like /path/to/repo. Both absolute and relative paths are
-	  	supported. If not specified, will try to detect if the current working directory
-	  	is configured as a language repository.
+	  	supported. If not specified, the closest directory holding a .librarian/state.yaml
+	  	file, starting from the current working directory and walking up its parents,
+	  	is used as the language repository.
 	  	Note: When using a local repository (either by providing a path or by defaulting
 	  	to the current directory), Librarian creates a new branch from the currently checked-out
 	  	branch and commits changes. If the --push flag is also specified, a pull request is
 	  	created against the main branch. The --branch flag is ignored for local repositories.
+	-report-unreleased
+	  	Report the libraries with releasable changes since their last release,
+	  	and the version each would be released at, without staging a release.
+	  	No files are changed and no containers are run.
+	-skip-libraries-without-source
+	  	Skip, with a warning, the libraries without source roots in state.yaml,
+	  	whose onboarding is incomplete. Libraries specified with --library are never
+	  	skipped. Use --skip-libraries-without-source=false to disable. (default true)
+	-squash-merge-titles
+	  	Parse the title of the pull request of squash-merged commits, whose
+	  	subject ends with the pull request number, e.g. (#123), as their conventional
+	  	commit instead of their subject. The titles are looked up on GitHub; if a
+	  	lookup fails, the subject of the commit is kept.
+	-strict-semver
+	  	Require the current version of every library to be a complete semantic
+	  	version, e.g. 1.2.3, and fail listing the libraries with other versions. By
+	  	default, versions such as 1.0 or v2 are coerced to semantic versions.
 	-v	enables verbose logging
 
 # release tag
@@ -248,7 +632,14 @@ This command's primary responsibilities are to:
 
 You can target a specific merged pull request using the '--pr' flag. If no pull
 request is specified, the command will automatically search for and process all
-merged pull requests with the 'release:pending' label from the last 30 days.
+merged pull requests with the 'release:pending' label from the last 30 days, or
+from the window set with the '--since' flag.
+
+Tags and GitHub Releases which already exist are not created again, so the
+command can safely be re-run after a partial failure. GitHub API requests failing
+with a server error or a secondary rate limit are retried with exponential
+backoff, as configured with the '--github-max-retries' and '--github-retry-delay'
+flags.
 
 Examples:
 
@@ -258,6 +649,9 @@ Examples:
 	# Find and process all pending merged release PRs in a repository.
 	librarian release tag --repo=https://github.com/googleapis/google-cloud-go
 
+	# Reprocess pending merged release PRs from the last 90 days.
+	librarian release tag --repo=https://github.com/googleapis/google-cloud-go --since=90d
+
 Usage:
 
 	librarian release tag [arguments]
@@ -267,21 +661,60 @@ Flags:
 	-github-api-endpoint string
 	  	The GitHub API endpoint to use for all GitHub API operations.
 	  	This is intended for testing and should not be used in production.
+	-github-max-retries int
+	  	The number of times a GitHub API request failing with a server error or
+	  	a secondary rate limit is retried. Requests which create or modify resources,
+	  	e.g. pull requests, are only retried when GitHub asks to retry them later.
+	  	Zero disables retries. (default 3)
+	-github-retry-delay duration
+	  	The delay before the first retry of a GitHub API request, e.g. 2s. The
+	  	delay doubles with each further retry, unless GitHub specifies the delay with a
+	  	Retry-After header. (default 2s)
+	-log-format string
+	  	The format of the logs, either "text" or "json". (default "text")
+	-log-level string
+	  	The minimum level of the logs, one of "debug", "info", "warn" or "error".
+	  	The -v flag takes precedence and selects "debug", and -quiet selects
+	  	"error". (default "info")
+	-manifest-output string
+	  	The path of a file to write a JSON manifest of the created releases to,
+	  	listing the library, version, tag, release URL and commit of each release.
+	-post-release-update
+	  	After releasing libraries, update the versions file configured under
+	  	post_release in .librarian/config.yaml with the released versions. The update
+	  	is committed to the base branch of the release pull request, or proposed in a
+	  	new pull request if create_pull_request is set.
 	-pr string
 	  	The URL of a pull request to operate on.
 	  	It should be in the format of https://github.com/{owner}/{repo}/pull/{number}.
 	  	If not specified, will search for all merged pull requests with the label
-	  	"release:pending" in the last 30 days.
+	  	"release:pending" in the last 30 days, or the window set with --since.
+	-q	Shorthand for -quiet.
+	-quiet
+	  	Only log errors, and only show the output of language containers when
+	  	they fail. By default, logs are written at the level of -log-level, and the
+	  	output of containers is shown as it arrives, each line prefixed with the
+	  	library ID and command, e.g. "[secretmanager generate]". The -v flag takes
+	  	precedence over the log level.
 	-repo string
 	  	Code repository where the generated code will reside. Can be a remote
 	  	in the format of a remote URL such as https://github.com/{owner}/{repo} or a
 	  	local file path like /path/to/repo. Both absolute and relative paths are
-	  	supported. If not specified, will try to detect if the current working directory
-	  	is configured as a language repository.
+	  	supported. If not specified, the closest directory holding a .librarian/state.yaml
+	  	file, starting from the current working directory and walking up its parents,
+	  	is used as the language repository.
 	  	Note: When using a local repository (either by providing a path or by defaulting
 	  	to the current directory), Librarian creates a new branch from the currently checked-out
 	  	branch and commits changes. If the --push flag is also specified, a pull request is
 	  	created against the main branch. The --branch flag is ignored for local repositories.
+	-since value
+	  	How far back to search for merged pull requests with the label
+	  	"release:pending" when --pr is not specified, either as a number of days,
+	  	e.g. 90d, or as a Go duration, e.g. 36h. Must be positive. Defaults to 30d.
+	-tag-type string
+	  	The type of the tags created for released libraries, either "annotated"
+	  	or "lightweight". Annotated tags carry the release notes of the library as
+	  	the tag message. (default "annotated")
 	-v	enables verbose logging
 
 # update-image
@@ -309,9 +742,27 @@ Usage:
 
 Flags:
 
+	-allow-tag
+	  	Allow the image specified with --image to use a floating tag, e.g.
+	  	:latest. By default, the image must be pinned to a digest (@sha256:...) so that
+	  	regeneration is reproducible.
 	-api-source string
 	  	The location of an API specification repository.
-	  	Can be a remote URL or a local file path. (default "https://github.com/googleapis/googleapis")
+	  	Can be a remote URL or a local file path. The generate command accepts a
+	  	comma-separated list of locations, which are layered into a single source
+	  	tree: files of later sources override those of earlier ones. (default "https://github.com/googleapis/googleapis")
+	-author string
+	  	The author of commits created by Librarian, of the form "Name <email>",
+	  	e.g. "Release Bot <release-bot@example.com>", who is also their committer unless
+	  	-committer is specified. Cannot be used with -author-name or -author-email.
+	-author-email string
+	  	The email address of the author of commits created by Librarian, who is
+	  	also their committer unless -committer is specified. If not specified, the email
+	  	address from git config is used, falling back to the Librarian bot.
+	-author-name string
+	  	The name of the author of commits created by Librarian, who is also
+	  	their committer unless -committer is specified. If not specified, the name from
+	  	git config is used, falling back to the Librarian bot.
 	-branch string
 	  	The branch to use with remote code repositories. It is ignored if
 	  	you are using a local repository. This is used to specify which branch to clone
@@ -319,6 +770,10 @@ Flags:
 	-build
 	  	If true, Librarian will build each generated library by invoking the
 	  	language-specific container.
+	-cache-dir string
+	  	A directory in which to keep the clone of -api-source between runs, when
+	  	it is a URL. An existing clone is fetched and checked out instead of cloning
+	  	again. If not specified, the repository is cloned into the work root.
 	-check-unexpected-changes
 	  	Defaults to false. When used with --test, this flag verifies that no
 	  	unexpected files are added, deleted, or modified outside of the changes caused
@@ -327,6 +782,26 @@ Flags:
 	-commit
 	  	If true, librarian will create a commit for the change but not create
 	  	a pull request. This flag is ignored if push is set to true.
+	-committer string
+	  	The committer of commits created by Librarian, of the form
+	  	"Name <email>", e.g. "CI <ci@example.com>". If not specified, the author of the
+	  	commits is also their committer.
+	-container-cpus string
+	  	The number of CPUs language containers may use, e.g. 1.5. Passed to
+	  	docker run as --cpus. If not specified, the number of CPUs is not limited.
+	-container-memory string
+	  	The maximum amount of memory language containers may use, e.g. 4g.
+	  	Passed to docker run as --memory. If not specified, memory is not limited.
+	-container-timeout duration
+	  	The maximum duration of each language container run, e.g. 1h. A container
+	  	still running once it elapses is killed, and the command fails. If zero,
+	  	containers may run forever. (default 30m0s)
+	-diff-output string
+	  	When used with --check-unexpected-changes, the path of a file to write
+	  	a unified diff of the unexpected file changes to.
+	-force
+	  	Process the libraries which are frozen in the librarian config. By default,
+	  	frozen libraries are skipped with a warning, even when named with --library.
 	-host-mount string
 	  	For use when librarian is running in a container. A mapping of a
 	  	directory from the host to the container, in the format
@@ -337,6 +812,29 @@ Flags:
 	-library-to-test string
 	  	When used with --test, this flag specifies the library ID to test
 	  	(e.g. secretmanager). Will test on all configured libraries if omitted.
+	-log-format string
+	  	The format of the logs, either "text" or "json". (default "text")
+	-log-level string
+	  	The minimum level of the logs, one of "debug", "info", "warn" or "error".
+	  	The -v flag takes precedence and selects "debug", and -quiet selects
+	  	"error". (default "info")
+	-mount value
+	  	An extra volume to mount into the language containers running the build,
+	  	configure and generate commands, e.g. a module cache, in the format
+	  	<host-dir>:<container-dir>[:ro]. The host directory must exist. May be
+	  	repeated to mount several volumes.
+	-no-lock
+	  	Do not lock the language repository. By default, the command fails if
+	  	another librarian process is running against the same repository.
+	-no-network value
+	  	A language container command, one of "build", "configure" or "generate",
+	  	to run without network access, passing --network=none to docker run. May be
+	  	repeated to name several commands. Containers of other commands use the default
+	  	network, as some need it, e.g. to fetch dependencies during build.
+	-only-if-api-changed
+	  	Keep only the libraries whose generated output changed with the new
+	  	image. Libraries whose output is unchanged are discarded and not built, and no
+	  	commit is created if no library changed.
 	-output string
 	  	Working directory root. When this is not specified, a working directory
 	  	will be created in /tmp.
@@ -345,12 +843,25 @@ Flags:
 	  	push and create a pull request for the changes.
 	  	A GitHub token with push access must be provided via the
 	  	LIBRARIAN_GITHUB_TOKEN environment variable.
+	-q	Shorthand for -quiet.
+	-quiet
+	  	Only log errors, and only show the output of language containers when
+	  	they fail. By default, logs are written at the level of -log-level, and the
+	  	output of containers is shown as it arrives, each line prefixed with the
+	  	library ID and command, e.g. "[secretmanager generate]". The -v flag takes
+	  	precedence over the log level.
+	-rebase-onto-base
+	  	Rebase the generated branch onto the latest base branch (see --branch)
+	  	before pushing it, so that the pull request can be merged cleanly. If the
+	  	rebase has conflicts, the conflicting files are reported and no pull request
+	  	is created. Requires the --push flag.
 	-repo string
 	  	Code repository where the generated code will reside. Can be a remote
 	  	in the format of a remote URL such as https://github.com/{owner}/{repo} or a
 	  	local file path like /path/to/repo. Both absolute and relative paths are
-	  	supported. If not specified, will try to detect if the current working directory
-	  	is configured as a language repository.
+	  	supported. If not specified, the closest directory holding a .librarian/state.yaml
+	  	file, starting from the current working directory and walking up its parents,
+	  	is used as the language repository.
 	  	Note: When using a local repository (either by providing a path or by defaulting
 	  	to the current directory), Librarian creates a new branch from the currently checked-out
 	  	branch and commits changes. If the --push flag is also specified, a pull request is
@@ -362,6 +873,63 @@ Flags:
 	  	debugging. This flag can be used with 'library-to-test' and 'check-unexpected-changes'.
 	-v	enables verbose logging
 
+# validate
+
+The 'validate' command checks the '.librarian/state.yaml' file of a
+language repository, so that configuration errors are caught before running
+other commands. It reports:
+
+  - Duplicate library IDs
+  - Libraries without APIs
+  - Libraries without source roots, or with source roots that do not exist
+  - APIs referenced by multiple libraries, unless they are listed in the
+    global files allowlist of the librarian config
+
+The command exits with a non-zero status if any problem is found.
+
+Example:
+
+	librarian validate --repo=/path/to/repo
+
+Usage:
+
+	librarian validate [flags]
+
+Flags:
+
+	-branch string
+	  	The branch to use with remote code repositories. It is ignored if
+	  	you are using a local repository. This is used to specify which branch to clone
+	  	and which branch to use as the base for a pull request. (default "main")
+	-log-format string
+	  	The format of the logs, either "text" or "json". (default "text")
+	-log-level string
+	  	The minimum level of the logs, one of "debug", "info", "warn" or "error".
+	  	The -v flag takes precedence and selects "debug", and -quiet selects
+	  	"error". (default "info")
+	-output string
+	  	Working directory root. When this is not specified, a working directory
+	  	will be created in /tmp.
+	-q	Shorthand for -quiet.
+	-quiet
+	  	Only log errors, and only show the output of language containers when
+	  	they fail. By default, logs are written at the level of -log-level, and the
+	  	output of containers is shown as it arrives, each line prefixed with the
+	  	library ID and command, e.g. "[secretmanager generate]". The -v flag takes
+	  	precedence over the log level.
+	-repo string
+	  	Code repository where the generated code will reside. Can be a remote
+	  	in the format of a remote URL such as https://github.com/{owner}/{repo} or a
+	  	local file path like /path/to/repo. Both absolute and relative paths are
+	  	supported. If not specified, the closest directory holding a .librarian/state.yaml
+	  	file, starting from the current working directory and walking up its parents,
+	  	is used as the language repository.
+	  	Note: When using a local repository (either by providing a path or by defaulting
+	  	to the current directory), Librarian creates a new branch from the currently checked-out
+	  	branch and commits changes. If the --push flag is also specified, a pull request is
+	  	created against the main branch. The --branch flag is ignored for local repositories.
+	-v	enables verbose logging
+
 # version
 
 Version prints version information for the librarian binary.
@@ -0,0 +1,152 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command dart-dependency-report reports the packages required with
+// conflicting version constraints by the generated Dart libraries of a
+// repository, e.g. `http ^1.2.0` in one library and `http ^2.0.0` in another.
+//
+// The dependencies of each library are computed as for the pubspec.yaml file
+// of the generated package: its API specification is parsed with the
+// configuration of its .sidekick.toml file, merged with the top-level
+// .sidekick.toml file of the repository, and the packages imported by the
+// generated code are added to those of its `dependencies` option. The source
+// roots of the top-level .sidekick.toml file, e.g. googleapis-root, must be
+// local directories.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/sidekick/config"
+	"github.com/googleapis/librarian/internal/sidekick/dart"
+	"github.com/googleapis/librarian/internal/sidekick/parser"
+)
+
+const (
+	sidekickFile = ".sidekick.toml"
+)
+
+var (
+	errRepoNotFound = errors.New("-repo flag is required")
+	errConflicts    = errors.New("found conflicting dependency constraints")
+)
+
+func main() {
+	if err := run(os.Args, os.Stdout); err != nil {
+		slog.Error("dart-dependency-report failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, w io.Writer) error {
+	flagSet := flag.NewFlagSet("dart-dependency-report", flag.ContinueOnError)
+	repoPath := flagSet.String("repo", "", "Path to the google-cloud-dart repository (required)")
+	if err := flagSet.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *repoPath == "" {
+		return errRepoNotFound
+	}
+
+	libraries, err := readLibraries(*repoPath)
+	if err != nil {
+		return err
+	}
+	conflicts := dart.FindDependencyConflicts(libraries)
+	if err := writeReport(w, conflicts); err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%w: %d packages", errConflicts, len(conflicts))
+	}
+	return nil
+}
+
+// readLibraries returns the dependencies of each Dart library of the
+// repository, keyed by the package name of the library.
+func readLibraries(repoPath string) (map[string]map[string]string, error) {
+	rootPath := filepath.Join(repoPath, sidekickFile)
+	rootConfig, err := config.LoadRootConfig(rootPath)
+	if err != nil {
+		return nil, err
+	}
+	libraries := map[string]map[string]string{}
+	err = filepath.WalkDir(repoPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() != sidekickFile || path == rootPath {
+			return nil
+		}
+		merged, err := config.MergeConfigAndFile(rootConfig, path)
+		if err != nil {
+			return err
+		}
+		if merged.General.Language != "dart" {
+			return nil
+		}
+		model, err := parser.CreateModel(merged)
+		if err != nil {
+			return fmt.Errorf("failed to parse the API of %s: %w", path, err)
+		}
+		if model == nil {
+			return nil
+		}
+		name, deps, err := dart.LibraryDependencies(model, merged.Codec)
+		if err != nil {
+			return fmt.Errorf("failed to compute the dependencies of %s: %w", path, err)
+		}
+		libraries[name] = deps
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return libraries, nil
+}
+
+// writeReport writes the conflicts, one package per paragraph, listing the
+// libraries requiring each constraint of the package.
+func writeReport(w io.Writer, conflicts []*dart.DependencyConflict) error {
+	if len(conflicts) == 0 {
+		_, err := fmt.Fprintln(w, "No conflicting dependency constraints.")
+		return err
+	}
+	var sb strings.Builder
+	for i, conflict := range conflicts {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "%s\n", conflict.Package)
+		constraints := make([]string, 0, len(conflict.Libraries))
+		for constraint := range conflict.Libraries {
+			constraints = append(constraints, constraint)
+		}
+		slices.Sort(constraints)
+		for _, constraint := range constraints {
+			fmt.Fprintf(&sb, "  %s: %s\n", constraint, strings.Join(conflict.Libraries[constraint], ", "))
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
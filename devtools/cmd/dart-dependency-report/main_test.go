@@ -0,0 +1,164 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRun(t *testing.T) {
+	testdata, err := filepath.Abs("../../../internal/sidekick/testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := fmt.Sprintf(`[general]
+language = 'dart'
+
+[source]
+googleapis-root = '%s'
+
+[codec]
+api-keys-environment-variables = 'GOOGLE_API_KEY'
+issue-tracker-url = 'https://example.com/issues'
+'package:google_cloud_rpc' = '^0.1.0'
+'package:google_cloud_protobuf' = '^0.1.0'
+'package:http' = '^1.2.0'
+`, filepath.Join(testdata, "googleapis"))
+	// library returns the .sidekick.toml file of a library generated from the
+	// Secret Manager OpenAPI specification, with the given codec options.
+	library := func(codec string) string {
+		return fmt.Sprintf(`[general]
+specification-format = 'openapi'
+specification-source = '%s'
+service-config = 'google/cloud/secretmanager/v1/secretmanager_v1.yaml'
+
+[codec]
+%s`, filepath.Join(testdata, "openapi", "secretmanager_openapi_v1.json"), codec)
+	}
+	for _, test := range []struct {
+		name    string
+		files   map[string]string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "conflicts",
+			files: map[string]string{
+				".sidekick.toml": root,
+				"generated/foo/.sidekick.toml": library(`package-name-override = 'google_cloud_foo'
+`),
+				"generated/bar/.sidekick.toml": library(`package-name-override = 'google_cloud_bar'
+'package:http' = '^2.0.0'
+'package:google_cloud_rpc' = '^0.2.0'
+`),
+				"generated/baz/.sidekick.toml": library(`package-name-override = 'google_cloud_baz'
+'package:google_cloud_rpc' = '^0.2.0'
+`),
+			},
+			want: `google_cloud_rpc
+  ^0.1.0: google_cloud_foo
+  ^0.2.0: google_cloud_bar, google_cloud_baz
+
+http
+  ^1.2.0: google_cloud_baz, google_cloud_foo
+  ^2.0.0: google_cloud_bar
+`,
+			wantErr: errConflicts,
+		},
+		{
+			name: "constraint of a package which is not a dependency",
+			files: map[string]string{
+				".sidekick.toml": root,
+				"generated/foo/.sidekick.toml": library(`package-name-override = 'google_cloud_foo'
+`),
+				"generated/bar/.sidekick.toml": library(`package-name-override = 'google_cloud_bar'
+'package:meta' = '^2.0.0'
+`),
+				"generated/baz/.sidekick.toml": library(`package-name-override = 'google_cloud_baz'
+'package:meta' = '^1.0.0'
+`),
+			},
+			want: "No conflicting dependency constraints.\n",
+		},
+		{
+			name: "dependencies option",
+			files: map[string]string{
+				".sidekick.toml": root,
+				"generated/foo/.sidekick.toml": library(`package-name-override = 'google_cloud_foo'
+dependencies = 'meta'
+'package:meta' = '^2.0.0'
+`),
+				"generated/bar/.sidekick.toml": library(`package-name-override = 'google_cloud_bar'
+dependencies = 'meta'
+'package:meta' = '^1.0.0'
+`),
+			},
+			want: `meta
+  ^1.0.0: google_cloud_bar
+  ^2.0.0: google_cloud_foo
+`,
+			wantErr: errConflicts,
+		},
+		{
+			name: "package name of the API",
+			files: map[string]string{
+				".sidekick.toml": root,
+				"generated/foo/.sidekick.toml": library(`'package:http' = '^2.0.0'
+`),
+				"generated/bar/.sidekick.toml": library(`package-name-override = 'google_cloud_bar'
+`),
+			},
+			want: `http
+  ^1.2.0: google_cloud_bar
+  ^2.0.0: google_cloud_secretmanager_v1
+`,
+			wantErr: errConflicts,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			repo := t.TempDir()
+			for name, content := range test.files {
+				path := filepath.Join(repo, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var out bytes.Buffer
+			err := run([]string{"dart-dependency-report", "-repo", repo}, &out)
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("run() error = %v, want %v", err, test.wantErr)
+			}
+			if diff := cmp.Diff(test.want, out.String()); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRunMissingRepo(t *testing.T) {
+	if err := run([]string{"dart-dependency-report"}, &bytes.Buffer{}); !errors.Is(err, errRepoNotFound) {
+		t.Errorf("run() error = %v, want %v", err, errRepoNotFound)
+	}
+}
@@ -19,6 +19,8 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -26,6 +28,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/googleapis/librarian/internal/config"
 	"github.com/googleapis/librarian/internal/librarian"
 	"github.com/googleapis/librarian/internal/yaml"
@@ -41,6 +44,8 @@ var (
 	errSidekickNotFound = errors.New(".sidekick.toml not found")
 	errSrcNotFound      = errors.New("src/generated directory not found")
 	errTidyFailed       = errors.New("librarian tidy failed")
+	errOutputNotFound   = errors.New("output file not found")
+	errConfigDrift      = errors.New("output file differs from the migrated config")
 )
 
 // SidekickConfig represents the structure of a .sidekick.toml file.
@@ -82,6 +87,7 @@ func run(args []string) error {
 	flagSet := flag.NewFlagSet("migrate-sidekick", flag.ContinueOnError)
 	repoPath := flagSet.String("repo", "", "Path to the google-cloud-rust repository (required)")
 	outputPath := flagSet.String("output", "./librarian.yaml", "Output file path (default: ./librarian.yaml)")
+	check := flagSet.Bool("check", false, "Compare the migrated config with the output file, printing a diff and failing on differences, without writing anything")
 	if err := flagSet.Parse(args[1:]); err != nil {
 		return err
 	}
@@ -112,6 +118,10 @@ func run(args []string) error {
 
 	cfg := buildConfig(libraries, defaults)
 
+	if *check {
+		return checkConfig(os.Stdout, *outputPath, cfg)
+	}
+
 	if err := yaml.Write(*outputPath, cfg); err != nil {
 		return fmt.Errorf("failed to write config: %w", err)
 	}
@@ -125,6 +135,29 @@ func run(args []string) error {
 	return nil
 }
 
+// checkConfig compares cfg with the config in the output file at path, and
+// writes a diff to w if they differ. It writes no file.
+func checkConfig(w io.Writer, path string, cfg *config.Config) error {
+	want, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%w: %s", errOutputNotFound, path)
+		}
+		return err
+	}
+	// Compare lines, so that the diff lists the changed lines.
+	if diff := cmp.Diff(strings.Split(string(got), "\n"), strings.Split(string(want), "\n")); diff != "" {
+		fmt.Fprintf(w, "%s differs from the migrated config (-%s +migrated):\n%s", path, path, diff)
+		return errConfigDrift
+	}
+	slog.Info("Output file matches the migrated config", "path", path)
+	return nil
+}
+
 // readRootSidekick reads the root .sidekick.toml file and extracts defaults.
 func readRootSidekick(repoPath string) (*config.Config, error) {
 	rootPath := filepath.Join(repoPath, sidekickFile)
@@ -343,13 +376,7 @@ func readSidekickFiles(files []string) (map[string]*config.Library, error) {
 		}
 
 		// Parse pagination overrides
-		var paginationOverrides []config.RustPaginationOverride
-		for _, po := range sidekick.PaginationOverrides {
-			paginationOverrides = append(paginationOverrides, config.RustPaginationOverride{
-				ID:        po.ID,
-				ItemField: po.ItemField,
-			})
-		}
+		paginationOverrides := parsePaginationOverrides(sidekick)
 
 		// Set Rust-specific configuration only if there's actual config
 		rustCrate := &config.RustCrate{
@@ -467,6 +494,32 @@ func parsePackageDependencies(codec map[string]interface{}) []*config.RustPackag
 	return packageDeps
 }
 
+// parsePaginationOverrides returns the pagination overrides of sidekick.
+// Overrides repeating the same ID are merged, preserving all their item
+// fields.
+func parsePaginationOverrides(sidekick SidekickConfig) []config.RustPaginationOverride {
+	var ids []string
+	itemFields := map[string][]string{}
+	for _, po := range sidekick.PaginationOverrides {
+		if _, ok := itemFields[po.ID]; !ok {
+			ids = append(ids, po.ID)
+		}
+		itemFields[po.ID] = append(itemFields[po.ID], po.ItemField)
+	}
+
+	var paginationOverrides []config.RustPaginationOverride
+	for _, id := range ids {
+		override := config.RustPaginationOverride{ID: id}
+		if fields := itemFields[id]; len(fields) == 1 {
+			override.ItemField = fields[0]
+		} else {
+			override.ItemFields = fields
+		}
+		paginationOverrides = append(paginationOverrides, override)
+	}
+	return paginationOverrides
+}
+
 func strToBool(s string) bool {
 	return s == "true"
 }
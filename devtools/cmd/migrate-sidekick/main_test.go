@@ -17,6 +17,7 @@ package main
 import (
 	"errors"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -229,6 +230,35 @@ func TestReadSidekickFiles(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "multiple_item_fields",
+			files: []string{
+				"testdata/read-sidekick-files/multiple-item-fields/.sidekick.toml",
+			},
+			want: map[string]*config.Library{
+				"google-cloud-storage-v2": {
+					Name: "google-cloud-storage-v2",
+					Channels: []*config.Channel{
+						{
+							Path: "google/storage/v2",
+						},
+					},
+					Version: "1.0.0",
+					Rust: &config.RustCrate{
+						PaginationOverrides: []config.RustPaginationOverride{
+							{
+								ID:         ".google.storage.v2.Storage.ListObjects",
+								ItemFields: []string{"objects", "prefixes"},
+							},
+							{
+								ID:        ".google.storage.v2.Storage.ListBuckets",
+								ItemField: "buckets",
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "no_api_path",
 			files: []string{
@@ -447,3 +477,65 @@ func TestRunMigrateCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestRunMigrateCommandCheck(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		// edit changes the migrated config before it is checked. A nil edit
+		// removes the output file.
+		edit    func(string) string
+		wantErr error
+	}{
+		{
+			name: "matching",
+			edit: func(s string) string { return s },
+		},
+		{
+			name:    "drift",
+			edit:    func(s string) string { return strings.Replace(s, "language: rust", "language: go", 1) },
+			wantErr: errConfigDrift,
+		},
+		{
+			name:    "missing_output",
+			wantErr: errOutputNotFound,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			outputPath := "librarian.yaml"
+			t.Cleanup(func() {
+				if err := os.Remove(outputPath); err != nil && !os.IsNotExist(err) {
+					t.Logf("cleanup: remove %s: %v", outputPath, err)
+				}
+			})
+			repoPath := "testdata/run/success"
+			if err := run([]string{"migrate-sidekick", "-repo", repoPath}); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(outputPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want string
+			if test.edit == nil {
+				err = os.Remove(outputPath)
+			} else {
+				want = test.edit(string(data))
+				err = os.WriteFile(outputPath, []byte(want), 0644)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := run([]string{"migrate-sidekick", "-repo", repoPath, "-check"}); !errors.Is(err, test.wantErr) {
+				t.Fatalf("run() error = %v, want %v", err, test.wantErr)
+			}
+			got, err := os.ReadFile(outputPath)
+			if err != nil && !os.IsNotExist(err) {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want, string(got)); diff != "" {
+				t.Errorf("check should not write %s (-want +got):\n%s", outputPath, diff)
+			}
+		})
+	}
+}
@@ -140,7 +140,11 @@ type RustPaginationOverride struct {
 	ID string `yaml:"id"`
 
 	// ItemField is the name of the field used for items.
-	ItemField string `yaml:"item_field"`
+	ItemField string `yaml:"item_field,omitempty"`
+
+	// ItemFields lists the names of the fields used for items, for responses
+	// with several repeated fields. It is used instead of ItemField.
+	ItemFields []string `yaml:"item_fields,omitempty"`
 }
 
 // RustDiscovery contains discovery-specific configuration for LRO polling.
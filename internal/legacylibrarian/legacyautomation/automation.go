@@ -18,6 +18,7 @@ import (
 	"context"
 
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacycli"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
 )
 
 func newAutomationCommand() *legacycli.Command {
@@ -40,6 +41,9 @@ func newCmdGenerate() *legacycli.Command {
 		UsageLine: "automation generate [flags]",
 		Long:      generateLongHelp,
 		Action: func(ctx context.Context, cmd *legacycli.Command) error {
+			if err := setupLogger(cmd.Config); err != nil {
+				return err
+			}
 			runner := newGenerateRunner(cmd.Config)
 			return runner.run(ctx)
 		},
@@ -47,8 +51,12 @@ func newCmdGenerate() *legacycli.Command {
 
 	cmdGenerate.Init()
 	addFlagBuild(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagCredentials(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagLogFormat(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagLogLevel(cmdGenerate.Flags, cmdGenerate.Config)
 	addFlagProject(cmdGenerate.Flags, cmdGenerate.Config)
 	addFlagPush(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagTriggerName(cmdGenerate.Flags, cmdGenerate.Config)
 
 	return cmdGenerate
 }
@@ -59,13 +67,20 @@ func newCmdPublishRelease() *legacycli.Command {
 		UsageLine: "automation publish-release [flags]",
 		Long:      publishLongHelp,
 		Action: func(ctx context.Context, cmd *legacycli.Command) error {
+			if err := setupLogger(cmd.Config); err != nil {
+				return err
+			}
 			runner := newPublishRunner(cmd.Config)
 			return runner.run(ctx)
 		},
 	}
 
 	cmdPublishRelease.Init()
+	addFlagCredentials(cmdPublishRelease.Flags, cmdPublishRelease.Config)
+	addFlagLogFormat(cmdPublishRelease.Flags, cmdPublishRelease.Config)
+	addFlagLogLevel(cmdPublishRelease.Flags, cmdPublishRelease.Config)
 	addFlagProject(cmdPublishRelease.Flags, cmdPublishRelease.Config)
+	addFlagTriggerName(cmdPublishRelease.Flags, cmdPublishRelease.Config)
 
 	return cmdPublishRelease
 }
@@ -76,14 +91,27 @@ func newCmdStageRelease() *legacycli.Command {
 		UsageLine: "automation stage-release [flags]",
 		Long:      stageLongHelp,
 		Action: func(ctx context.Context, cmd *legacycli.Command) error {
+			if err := setupLogger(cmd.Config); err != nil {
+				return err
+			}
 			runner := newStageRunner(cmd.Config)
 			return runner.run(ctx)
 		},
 	}
 
 	cmdStageRelease.Init()
+	addFlagCredentials(cmdStageRelease.Flags, cmdStageRelease.Config)
+	addFlagLogFormat(cmdStageRelease.Flags, cmdStageRelease.Config)
+	addFlagLogLevel(cmdStageRelease.Flags, cmdStageRelease.Config)
 	addFlagProject(cmdStageRelease.Flags, cmdStageRelease.Config)
 	addFlagPush(cmdStageRelease.Flags, cmdStageRelease.Config)
+	addFlagTriggerName(cmdStageRelease.Flags, cmdStageRelease.Config)
 
 	return cmdStageRelease
 }
+
+// setupLogger sets the default logger as configured by the -log-format and
+// -log-level flags. The GitHub token is redacted from the logs.
+func setupLogger(cfg *legacyconfig.Config) error {
+	return legacycli.SetupLogger(cfg.LogFormat, cfg.LogLevel, cfg.GitHubToken)
+}
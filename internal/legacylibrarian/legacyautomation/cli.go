@@ -19,6 +19,10 @@ package legacyautomation
 import (
 	"context"
 	"flag"
+	"os"
+
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacycli"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
 )
 
 // runCommandFn is a function type that matches RunCommand, for mocking in tests.
@@ -36,8 +40,11 @@ func Run(ctx context.Context, args []string) error {
 	if err != nil {
 		return err
 	}
+	if err := legacycli.SetupLogger(options.LogFormat, options.LogLevel, os.Getenv(legacyconfig.LibrarianGithubToken)); err != nil {
+		return err
+	}
 
-	err = runCommandFn(ctx, options.Command, options.ProjectId, options.Push, options.Build)
+	err = runCommandFn(ctx, options.Command, options.ProjectId, options.Credentials, options.TriggerName, options.Push, options.Build)
 	if err != nil {
 		return err
 	}
@@ -45,26 +52,38 @@ func Run(ctx context.Context, args []string) error {
 }
 
 type runOptions struct {
-	Command   string
-	ProjectId string
-	Push      bool
-	Build     bool
+	Command     string
+	ProjectId   string
+	Credentials string
+	TriggerName string
+	Push        bool
+	Build       bool
+	LogFormat   string
+	LogLevel    string
 }
 
 func parseFlags(args []string) (*runOptions, error) {
 	flagSet := flag.NewFlagSet("dispatcher", flag.ContinueOnError)
 	projectId := flagSet.String("project", "cloud-sdk-librarian-prod", "GCP project ID")
 	command := flagSet.String("command", "generate", "The librarian command to run")
+	credentials := flagSet.String("credentials", "", "Path to a service account key file used to authenticate with Cloud Build")
+	triggerName := flagSet.String("trigger-name", "", "The name of the Cloud Build trigger to run instead of the default trigger for the command")
 	push := flagSet.Bool("push", true, "The _PUSH flag (true/false) to Librarian CLI's -push option")
 	build := flagSet.Bool("build", true, "The _BUILD flag (true/false) to Librarian CLI's -build option")
+	logFormat := flagSet.String("log-format", legacycli.LogFormatText, `The format of the logs, either "text" or "json"`)
+	logLevel := flagSet.String("log-level", "info", `The minimum level of the logs, one of "debug", "info", "warn" or "error"`)
 	err := flagSet.Parse(args)
 	if err != nil {
 		return nil, err
 	}
 	return &runOptions{
-		ProjectId: *projectId,
-		Command:   *command,
-		Push:      *push,
-		Build:     *build,
+		ProjectId:   *projectId,
+		Command:     *command,
+		Credentials: *credentials,
+		TriggerName: *triggerName,
+		Push:        *push,
+		Build:       *build,
+		LogFormat:   *logFormat,
+		LogLevel:    *logLevel,
 	}, nil
 }
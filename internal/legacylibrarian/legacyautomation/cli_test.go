@@ -40,6 +40,11 @@ func TestRun(t *testing.T) {
 			args:    []string{"--unknown-flag"},
 			wantErr: true,
 		},
+		{
+			name:    "invalid log format",
+			args:    []string{"--command=generate", "--log-format=xml"},
+			wantErr: true,
+		},
 		{
 			name:          "error from RunCommand",
 			args:          []string{"--command=generate"},
@@ -49,7 +54,7 @@ func TestRun(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			runCommandFn = func(ctx context.Context, command string, projectId string, push bool, build bool) error {
+			runCommandFn = func(ctx context.Context, command string, projectId string, credentialsPath string, triggerName string, push bool, build bool) error {
 				return test.runCommandErr
 			}
 			if err := Run(context.Background(), test.args); (err != nil) != test.wantErr {
@@ -75,6 +80,8 @@ func TestParseArgs(t *testing.T) {
 				ProjectId: "cloud-sdk-librarian-prod",
 				Push:      true,
 				Build:     true,
+				LogFormat: "text",
+				LogLevel:  "info",
 			},
 		},
 		{
@@ -86,6 +93,8 @@ func TestParseArgs(t *testing.T) {
 				ProjectId: "some-project-id",
 				Push:      true,
 				Build:     true,
+				LogFormat: "text",
+				LogLevel:  "info",
 			},
 		},
 		{
@@ -97,6 +106,8 @@ func TestParseArgs(t *testing.T) {
 				ProjectId: "cloud-sdk-librarian-prod",
 				Push:      true,
 				Build:     true,
+				LogFormat: "text",
+				LogLevel:  "info",
 			},
 		},
 		{
@@ -108,6 +119,36 @@ func TestParseArgs(t *testing.T) {
 				ProjectId: "cloud-sdk-librarian-prod",
 				Push:      false,
 				Build:     true,
+				LogFormat: "text",
+				LogLevel:  "info",
+			},
+		},
+		{
+			name:    "sets credentials",
+			args:    []string{"--credentials=/path/to/key.json"},
+			wantErr: false,
+			want: &runOptions{
+				Command:     "generate",
+				ProjectId:   "cloud-sdk-librarian-prod",
+				Credentials: "/path/to/key.json",
+				Push:        true,
+				Build:       true,
+				LogFormat:   "text",
+				LogLevel:    "info",
+			},
+		},
+		{
+			name:    "sets trigger name",
+			args:    []string{"--trigger-name=staging-trigger"},
+			wantErr: false,
+			want: &runOptions{
+				Command:     "generate",
+				ProjectId:   "cloud-sdk-librarian-prod",
+				TriggerName: "staging-trigger",
+				Push:        true,
+				Build:       true,
+				LogFormat:   "text",
+				LogLevel:    "info",
 			},
 		},
 		{
@@ -119,6 +160,21 @@ func TestParseArgs(t *testing.T) {
 				ProjectId: "cloud-sdk-librarian-prod",
 				Push:      true,
 				Build:     false,
+				LogFormat: "text",
+				LogLevel:  "info",
+			},
+		},
+		{
+			name:    "sets log format and level",
+			args:    []string{"--log-format=json", "--log-level=debug"},
+			wantErr: false,
+			want: &runOptions{
+				Command:   "generate",
+				ProjectId: "cloud-sdk-librarian-prod",
+				Push:      true,
+				Build:     true,
+				LogFormat: "json",
+				LogLevel:  "debug",
 			},
 		},
 	} {
@@ -17,6 +17,7 @@ package legacyautomation
 import (
 	"flag"
 
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacycli"
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
 )
 
@@ -24,6 +25,18 @@ func addFlagBuild(fs *flag.FlagSet, cfg *legacyconfig.Config) {
 	fs.BoolVar(&cfg.Build, "build", false, "The _BUILD flag (true/false) to Librarian CLI's -build option")
 }
 
+func addFlagCredentials(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.Credentials, "credentials", "", "Path to a service account key file used to authenticate with Cloud Build. If not set, application default credentials are used")
+}
+
+func addFlagLogFormat(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.LogFormat, "log-format", legacycli.LogFormatText, `The format of the logs, either "text" or "json"`)
+}
+
+func addFlagLogLevel(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.LogLevel, "log-level", "info", `The minimum level of the logs, one of "debug", "info", "warn" or "error"`)
+}
+
 func addFlagProject(fs *flag.FlagSet, cfg *legacyconfig.Config) {
 	fs.StringVar(&cfg.Project, "project", "cloud-sdk-librarian-prod", "Google Cloud Platform project ID")
 }
@@ -31,3 +44,7 @@ func addFlagProject(fs *flag.FlagSet, cfg *legacyconfig.Config) {
 func addFlagPush(fs *flag.FlagSet, cfg *legacyconfig.Config) {
 	fs.BoolVar(&cfg.Push, "push", false, "The _PUSH flag (true/false) to Librarian CLI's -push option")
 }
+
+func addFlagTriggerName(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.TriggerName, "trigger-name", "", "The name of the Cloud Build trigger to run instead of the default trigger for the command")
+}
@@ -25,20 +25,24 @@ const (
 )
 
 type generateRunner struct {
-	build     bool
-	projectID string
-	push      bool
+	build       bool
+	credentials string
+	projectID   string
+	triggerName string
+	push        bool
 }
 
 func newGenerateRunner(cfg *legacyconfig.Config) *generateRunner {
 	return &generateRunner{
-		build:     cfg.Build,
-		projectID: cfg.Project,
-		push:      cfg.Push,
+		build:       cfg.Build,
+		credentials: cfg.Credentials,
+		projectID:   cfg.Project,
+		triggerName: cfg.TriggerName,
+		push:        cfg.Push,
 	}
 }
 
 func (r *generateRunner) run(ctx context.Context) error {
 	// TODO(https://github.com/googleapis/librarian/issues/2890): refactor this function after all commands are migrated.
-	return runCommandFn(ctx, generateCmdName, r.projectID, r.push, r.build)
+	return runCommandFn(ctx, generateCmdName, r.projectID, r.credentials, r.triggerName, r.push, r.build)
 }
@@ -31,9 +31,11 @@ func TestNewGenerateRunner(t *testing.T) {
 		{
 			name: "create_a_runner",
 			cfg: &legacyconfig.Config{
-				Build:   true,
-				Project: "example-project",
-				Push:    true,
+				Credentials: "/path/to/key.json",
+				TriggerName: "staging-trigger",
+				Build:       true,
+				Project:     "example-project",
+				Push:        true,
 			},
 		},
 	} {
@@ -46,6 +48,12 @@ func TestNewGenerateRunner(t *testing.T) {
 			if runner.projectID != test.cfg.Project {
 				t.Errorf("newGenerateRunner() projectID is not set")
 			}
+			if runner.credentials != test.cfg.Credentials {
+				t.Errorf("newGenerateRunner() credentials is not set")
+			}
+			if runner.triggerName != test.cfg.TriggerName {
+				t.Errorf("newGenerateRunner() triggerName is not set")
+			}
 			if runner.push != test.cfg.Push {
 				t.Errorf("newGenerateRunner() push is not set")
 			}
@@ -58,26 +66,32 @@ func TestGenerateRunnerRun(t *testing.T) {
 	defer func() { runCommandFn = originalRunCommandFn }()
 
 	tests := []struct {
-		name          string
-		runner        *generateRunner
-		runCommandErr error
-		wantErr       bool
-		wantCmd       string
-		wantProjectID string
-		wantPush      bool
-		wantBuild     bool
+		name            string
+		runner          *generateRunner
+		runCommandErr   error
+		wantErr         bool
+		wantCmd         string
+		wantProjectID   string
+		wantCredentials string
+		wantTriggerName string
+		wantPush        bool
+		wantBuild       bool
 	}{
 		{
 			name: "success",
 			runner: &generateRunner{
-				build:     true,
-				projectID: "test-project",
-				push:      true,
+				credentials: "/path/to/key.json",
+				triggerName: "staging-trigger",
+				build:       true,
+				projectID:   "test-project",
+				push:        true,
 			},
-			wantCmd:       generateCmdName,
-			wantProjectID: "test-project",
-			wantPush:      true,
-			wantBuild:     true,
+			wantCmd:         generateCmdName,
+			wantProjectID:   "test-project",
+			wantCredentials: "/path/to/key.json",
+			wantTriggerName: "staging-trigger",
+			wantPush:        true,
+			wantBuild:       true,
 		},
 		{
 			name:          "error from RunCommand",
@@ -89,7 +103,7 @@ func TestGenerateRunnerRun(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			runCommandFn = func(ctx context.Context, command string, projectId string, push bool, build bool) error {
+			runCommandFn = func(ctx context.Context, command string, projectId string, credentialsPath string, triggerName string, push bool, build bool) error {
 				if command != test.wantCmd {
 					t.Errorf("runCommandFn() command = %v, want %v", command, test.wantCmd)
 				}
@@ -98,6 +112,12 @@ func TestGenerateRunnerRun(t *testing.T) {
 					if projectId != test.wantProjectID {
 						t.Errorf("runCommandFn() projectId = %v, want %v", projectId, test.wantProjectID)
 					}
+					if credentialsPath != test.wantCredentials {
+						t.Errorf("runCommandFn() credentialsPath = %v, want %v", credentialsPath, test.wantCredentials)
+					}
+					if triggerName != test.wantTriggerName {
+						t.Errorf("runCommandFn() triggerName = %v, want %v", triggerName, test.wantTriggerName)
+					}
 					if push != test.wantPush {
 						t.Errorf("runCommandFn() push = %v, want %v", push, test.wantPush)
 					}
@@ -25,15 +25,19 @@ const (
 )
 
 type publishRunner struct {
-	projectID string
+	credentials string
+	projectID   string
+	triggerName string
 }
 
 func newPublishRunner(cfg *legacyconfig.Config) *publishRunner {
 	return &publishRunner{
-		projectID: cfg.Project,
+		credentials: cfg.Credentials,
+		projectID:   cfg.Project,
+		triggerName: cfg.TriggerName,
 	}
 }
 
 func (r *publishRunner) run(ctx context.Context) error {
-	return runCommandFn(ctx, publishCmdName, r.projectID, false, false)
+	return runCommandFn(ctx, publishCmdName, r.projectID, r.credentials, r.triggerName, false, false)
 }
@@ -31,7 +31,9 @@ func TestNewPublishRunner(t *testing.T) {
 		{
 			name: "create_a_runner",
 			cfg: &legacyconfig.Config{
-				Project: "example-project",
+				Credentials: "/path/to/key.json",
+				TriggerName: "staging-trigger",
+				Project:     "example-project",
 			},
 		},
 	} {
@@ -41,28 +43,40 @@ func TestNewPublishRunner(t *testing.T) {
 			if runner.projectID != test.cfg.Project {
 				t.Errorf("newPublishRunner() projectID is not set")
 			}
+			if runner.credentials != test.cfg.Credentials {
+				t.Errorf("newPublishRunner() credentials is not set")
+			}
+			if runner.triggerName != test.cfg.TriggerName {
+				t.Errorf("newPublishRunner() triggerName is not set")
+			}
 		})
 	}
 }
 
 func TestPublishRunnerRun(t *testing.T) {
 	tests := []struct {
-		name          string
-		runner        *publishRunner
-		runCommandErr error
-		wantErr       bool
-		wantCmd       string
-		wantProjectID string
-		wantPush      bool
-		wantBuild     bool
+		name            string
+		runner          *publishRunner
+		runCommandErr   error
+		wantErr         bool
+		wantCmd         string
+		wantProjectID   string
+		wantCredentials string
+		wantTriggerName string
+		wantPush        bool
+		wantBuild       bool
 	}{
 		{
 			name: "success",
 			runner: &publishRunner{
-				projectID: "test-project",
+				credentials: "/path/to/key.json",
+				triggerName: "staging-trigger",
+				projectID:   "test-project",
 			},
-			wantCmd:       publishCmdName,
-			wantProjectID: "test-project",
+			wantCmd:         publishCmdName,
+			wantProjectID:   "test-project",
+			wantCredentials: "/path/to/key.json",
+			wantTriggerName: "staging-trigger",
 		},
 		{
 			name:          "error from RunCommand",
@@ -74,7 +88,7 @@ func TestPublishRunnerRun(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			runCommandFn = func(ctx context.Context, command string, projectId string, push bool, build bool) error {
+			runCommandFn = func(ctx context.Context, command string, projectId string, credentialsPath string, triggerName string, push bool, build bool) error {
 				if command != test.wantCmd {
 					t.Errorf("runCommandFn() command = %v, want %v", command, test.wantCmd)
 				}
@@ -83,6 +97,12 @@ func TestPublishRunnerRun(t *testing.T) {
 					if projectId != test.wantProjectID {
 						t.Errorf("runCommandFn() projectId = %v, want %v", projectId, test.wantProjectID)
 					}
+					if credentialsPath != test.wantCredentials {
+						t.Errorf("runCommandFn() credentialsPath = %v, want %v", credentialsPath, test.wantCredentials)
+					}
+					if triggerName != test.wantTriggerName {
+						t.Errorf("runCommandFn() triggerName = %v, want %v", triggerName, test.wantTriggerName)
+					}
 				}
 				return test.runCommandErr
 			}
@@ -25,17 +25,21 @@ const (
 )
 
 type stageRunner struct {
-	projectID string
-	push      bool
+	credentials string
+	projectID   string
+	triggerName string
+	push        bool
 }
 
 func newStageRunner(cfg *legacyconfig.Config) *stageRunner {
 	return &stageRunner{
-		projectID: cfg.Project,
-		push:      cfg.Push,
+		credentials: cfg.Credentials,
+		projectID:   cfg.Project,
+		triggerName: cfg.TriggerName,
+		push:        cfg.Push,
 	}
 }
 
 func (r *stageRunner) run(ctx context.Context) error {
-	return runCommandFn(ctx, stageCmdName, r.projectID, r.push, false)
+	return runCommandFn(ctx, stageCmdName, r.projectID, r.credentials, r.triggerName, r.push, false)
 }
@@ -31,7 +31,9 @@ func TestNewStageRunner(t *testing.T) {
 		{
 			name: "create_a_runner",
 			cfg: &legacyconfig.Config{
-				Project: "example-project",
+				Credentials: "/path/to/key.json",
+				TriggerName: "staging-trigger",
+				Project:     "example-project",
 			},
 		},
 	} {
@@ -41,29 +43,41 @@ func TestNewStageRunner(t *testing.T) {
 			if runner.projectID != test.cfg.Project {
 				t.Errorf("newStageRunner() projectID is not set")
 			}
+			if runner.credentials != test.cfg.Credentials {
+				t.Errorf("newStageRunner() credentials is not set")
+			}
+			if runner.triggerName != test.cfg.TriggerName {
+				t.Errorf("newStageRunner() triggerName is not set")
+			}
 		})
 	}
 }
 
 func TestStageRunnerRun(t *testing.T) {
 	tests := []struct {
-		name          string
-		runner        *stageRunner
-		runCommandErr error
-		wantErr       bool
-		wantCmd       string
-		wantProjectID string
-		wantPush      bool
+		name            string
+		runner          *stageRunner
+		runCommandErr   error
+		wantErr         bool
+		wantCmd         string
+		wantProjectID   string
+		wantCredentials string
+		wantTriggerName string
+		wantPush        bool
 	}{
 		{
 			name: "success",
 			runner: &stageRunner{
-				projectID: "test-project",
-				push:      true,
+				credentials: "/path/to/key.json",
+				triggerName: "staging-trigger",
+				projectID:   "test-project",
+				push:        true,
 			},
-			wantCmd:       stageCmdName,
-			wantProjectID: "test-project",
-			wantPush:      true,
+			wantCmd:         stageCmdName,
+			wantProjectID:   "test-project",
+			wantCredentials: "/path/to/key.json",
+			wantTriggerName: "staging-trigger",
+			wantPush:        true,
 		},
 		{
 			name:          "error from RunCommand",
@@ -75,7 +89,7 @@ func TestStageRunnerRun(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			runCommandFn = func(ctx context.Context, command string, projectId string, push bool, build bool) error {
+			runCommandFn = func(ctx context.Context, command string, projectId string, credentialsPath string, triggerName string, push bool, build bool) error {
 				if command != test.wantCmd {
 					t.Errorf("runCommandFn() command = %v, want %v", command, test.wantCmd)
 				}
@@ -84,6 +98,12 @@ func TestStageRunnerRun(t *testing.T) {
 					if projectId != test.wantProjectID {
 						t.Errorf("runCommandFn() projectId = %v, want %v", projectId, test.wantProjectID)
 					}
+					if credentialsPath != test.wantCredentials {
+						t.Errorf("runCommandFn() credentialsPath = %v, want %v", credentialsPath, test.wantCredentials)
+					}
+					if triggerName != test.wantTriggerName {
+						t.Errorf("runCommandFn() triggerName = %v, want %v", triggerName, test.wantTriggerName)
+					}
 					if push != test.wantPush {
 						t.Errorf("runCommandFn() push = %v, want %v", push, test.wantPush)
 					}
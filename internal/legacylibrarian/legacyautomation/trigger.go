@@ -16,6 +16,7 @@ package legacyautomation
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"iter"
@@ -23,11 +24,14 @@ import (
 	"os"
 	"strings"
 
+	"cloud.google.com/go/auth"
+	"cloud.google.com/go/auth/credentials"
 	cloudbuild "cloud.google.com/go/cloudbuild/apiv1/v2"
 	"cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
 	"github.com/googleapis/gax-go/v2"
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacygithub"
+	"google.golang.org/api/option"
 )
 
 var triggerNameByCommandName = map[string]string{
@@ -64,9 +68,60 @@ func (c *wrappedCloudBuildClient) ListBuildTriggers(ctx context.Context, req *cl
 	return c.client.ListBuildTriggers(ctx, req, opts...).All()
 }
 
+// credentialsLoader loads the credentials used to authenticate with Cloud
+// Build from the service account key file at path.
+type credentialsLoader func(path string) (*auth.Credentials, error)
+
+// loadServiceAccountCredentials is the [credentialsLoader] used by
+// [RunCommand]. It only accepts service account keys, as other credential
+// types can make requests to arbitrary endpoints.
+func loadServiceAccountCredentials(path string) (*auth.Credentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var key struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, fmt.Errorf("invalid credentials file: %w", err)
+	}
+	if key.Type != "service_account" {
+		return nil, fmt.Errorf("credentials file is not a service account key: type %q", key.Type)
+	}
+	return credentials.DetectDefault(&credentials.DetectOptions{
+		CredentialsJSON: data,
+		Scopes:          cloudbuild.DefaultAuthScopes(),
+	})
+}
+
+// cloudBuildClientOptions returns the options used to create the Cloud Build
+// client. If credentialsPath is empty, no options are returned and the client
+// uses application default credentials. The credentials are never logged.
+func cloudBuildClientOptions(credentialsPath string, load credentialsLoader) ([]option.ClientOption, error) {
+	if credentialsPath == "" {
+		slog.Debug("using application default credentials")
+		return nil, nil
+	}
+	slog.Debug("using service account credentials", "path", credentialsPath)
+	creds, err := load(credentialsPath)
+	if err != nil {
+		return nil, fmt.Errorf("error loading credentials: %w", err)
+	}
+	return []option.ClientOption{option.WithAuthCredentials(creds)}, nil
+}
+
 // RunCommand triggers a command for each registered repository that supports it.
-func RunCommand(ctx context.Context, command string, projectId string, push bool, build bool) error {
-	c, err := cloudbuild.NewClient(ctx)
+// If credentialsPath is not empty, the service account key file at that path is
+// used to authenticate with Cloud Build instead of application default
+// credentials. If triggerName is not empty, the Cloud Build trigger with that
+// name is run instead of the default trigger for the command.
+func RunCommand(ctx context.Context, command string, projectId string, credentialsPath string, triggerName string, push bool, build bool) error {
+	opts, err := cloudBuildClientOptions(credentialsPath, loadServiceAccountCredentials)
+	if err != nil {
+		return err
+	}
+	c, err := cloudbuild.NewClient(ctx, opts...)
 	if err != nil {
 		return fmt.Errorf("error creating cloudbuild client: %w", err)
 	}
@@ -75,23 +130,32 @@ func RunCommand(ctx context.Context, command string, projectId string, push bool
 		client: c,
 	}
 	ghClient := legacygithub.NewClient(os.Getenv(legacyconfig.LibrarianGithubToken), nil)
-	return runCommandWithClient(ctx, wrappedClient, ghClient, command, projectId, push, build)
+	return runCommandWithClient(ctx, wrappedClient, ghClient, command, projectId, triggerName, push, build)
 }
 
-func runCommandWithClient(ctx context.Context, client CloudBuildClient, ghClient GitHubClient, command string, projectId string, push bool, build bool) error {
+func runCommandWithClient(ctx context.Context, client CloudBuildClient, ghClient GitHubClient, command string, projectId string, triggerName string, push bool, build bool) error {
 	repositoriesConfig, err := loadRepositoriesConfig()
 	if err != nil {
 		return fmt.Errorf("error loading repositories config: %w", err)
 	}
-	return runCommandWithConfig(ctx, client, ghClient, command, projectId, push, build, repositoriesConfig)
+	return runCommandWithConfig(ctx, client, ghClient, command, projectId, triggerName, push, build, repositoriesConfig)
 }
 
-func runCommandWithConfig(ctx context.Context, client CloudBuildClient, ghClient GitHubClient, command string, projectId string, push bool, build bool, config *RepositoriesConfig) error {
+func runCommandWithConfig(ctx context.Context, client CloudBuildClient, ghClient GitHubClient, command string, projectId string, triggerNameOverride string, push bool, build bool, config *RepositoriesConfig) error {
 	// validate command is allowed
 	triggerName := triggerNameByCommandName[command]
 	if triggerName == "" {
 		return fmt.Errorf("unsupported command: %s", command)
 	}
+	if triggerNameOverride != "" {
+		// Fail before running anything if the trigger does not exist, rather
+		// than once per repository.
+		if _, err := findTriggerIdByName(ctx, client, projectId, region, triggerNameOverride); err != nil {
+			return fmt.Errorf("invalid trigger %q: %w", triggerNameOverride, err)
+		}
+		slog.Info("overriding trigger", "command", command, "triggerName", triggerNameOverride)
+		triggerName = triggerNameOverride
+	}
 
 	errs := make([]error, 0)
 
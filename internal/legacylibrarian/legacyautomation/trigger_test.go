@@ -16,9 +16,14 @@ package legacyautomation
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"cloud.google.com/go/auth"
 	"cloud.google.com/go/cloudbuild/apiv1/v2/cloudbuildpb"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-github/v69/github"
@@ -38,6 +43,7 @@ func TestRunCommandWithClient(t *testing.T) {
 	for _, test := range []struct {
 		name            string
 		command         string
+		triggerName     string
 		push            bool
 		build           bool
 		want            string
@@ -64,6 +70,37 @@ func TestRunCommandWithClient(t *testing.T) {
 			},
 			wantTriggersRun: []string{"generate-trigger-id"},
 		},
+		{
+			name:        "runs overridden trigger",
+			command:     "generate",
+			triggerName: "generate-staging",
+			push:        true,
+			buildTriggers: []*cloudbuildpb.BuildTrigger{
+				{
+					Name: "generate",
+					Id:   "generate-trigger-id",
+				},
+				{
+					Name: "generate-staging",
+					Id:   "generate-staging-trigger-id",
+				},
+			},
+			wantTriggersRun: []string{"generate-staging-trigger-id"},
+		},
+		{
+			name:        "overridden trigger does not exist",
+			command:     "generate",
+			triggerName: "missing-trigger",
+			push:        true,
+			wantErr:     true,
+			buildTriggers: []*cloudbuildpb.BuildTrigger{
+				{
+					Name: "generate",
+					Id:   "generate-trigger-id",
+				},
+			},
+			wantTriggersRun: nil,
+		},
 		{
 			name:    "runs prepare-release trigger",
 			command: "stage-release",
@@ -166,7 +203,7 @@ func TestRunCommandWithClient(t *testing.T) {
 				prs: test.ghPRs,
 				err: test.ghError,
 			}
-			err := runCommandWithClient(ctx, client, ghClient, test.command, "some-project", test.push, test.build)
+			err := runCommandWithClient(ctx, client, ghClient, test.command, "some-project", test.triggerName, test.push, test.build)
 			if test.wantErr && err == nil {
 				t.Fatal("expected error, but did not return one")
 			} else if !test.wantErr && err != nil {
@@ -382,7 +419,7 @@ func TestRunCommandWithConfig(t *testing.T) {
 				prs: test.ghPRs,
 				err: test.ghError,
 			}
-			err := runCommandWithConfig(ctx, client, ghClient, test.command, "some-project", true, true, test.config)
+			err := runCommandWithConfig(ctx, client, ghClient, test.command, "some-project", "", true, true, test.config)
 			if test.wantErr && err == nil {
 				t.Fatal("expected error, but did not return one")
 			} else if !test.wantErr && err != nil {
@@ -397,3 +434,92 @@ func TestRunCommandWithConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestCloudBuildClientOptions(t *testing.T) {
+	for _, test := range []struct {
+		name            string
+		credentialsPath string
+		loadErr         error
+		wantOptions     int
+		wantLoadPath    string
+		wantErr         bool
+	}{
+		{
+			name:        "application default credentials",
+			wantOptions: 0,
+		},
+		{
+			name:            "explicit credentials",
+			credentialsPath: "/path/to/key.json",
+			wantOptions:     1,
+			wantLoadPath:    "/path/to/key.json",
+		},
+		{
+			name:            "error loading credentials",
+			credentialsPath: "/path/to/key.json",
+			loadErr:         errors.New("load failed"),
+			wantLoadPath:    "/path/to/key.json",
+			wantErr:         true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var gotLoadPath string
+			fakeLoader := func(path string) (*auth.Credentials, error) {
+				gotLoadPath = path
+				if test.loadErr != nil {
+					return nil, test.loadErr
+				}
+				return auth.NewCredentials(&auth.CredentialsOptions{}), nil
+			}
+			opts, err := cloudBuildClientOptions(test.credentialsPath, fakeLoader)
+			if test.wantErr {
+				if !errors.Is(err, test.loadErr) {
+					t.Errorf("cloudBuildClientOptions() error = %v, want %v", err, test.loadErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(opts) != test.wantOptions {
+				t.Errorf("cloudBuildClientOptions() returned %d options, want %d", len(opts), test.wantOptions)
+			}
+			if gotLoadPath != test.wantLoadPath {
+				t.Errorf("credentials loaded from %q, want %q", gotLoadPath, test.wantLoadPath)
+			}
+		})
+	}
+}
+
+func TestLoadServiceAccountCredentials(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		content    string
+		wantErrMsg string
+	}{
+		{
+			name:       "invalid json",
+			content:    "not json",
+			wantErrMsg: "invalid credentials file",
+		},
+		{
+			name:       "not a service account",
+			content:    `{"type": "authorized_user"}`,
+			wantErrMsg: `credentials file is not a service account key: type "authorized_user"`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "key.json")
+			if err := os.WriteFile(path, []byte(test.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := loadServiceAccountCredentials(path)
+			if err == nil {
+				t.Fatal("loadServiceAccountCredentials() should return an error")
+			}
+			if !strings.Contains(err.Error(), test.wantErrMsg) {
+				t.Errorf("loadServiceAccountCredentials() error = %q, want to contain %q", err.Error(), test.wantErrMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacycli
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+)
+
+const (
+	// LogFormatText is the log format of slog.TextHandler, logging key=value
+	// pairs.
+	LogFormatText = "text"
+	// LogFormatJSON is the log format of slog.JSONHandler, logging one JSON
+	// object per line.
+	LogFormatJSON = "json"
+)
+
+// redacted replaces the secrets in logged values.
+const redacted = "[REDACTED]"
+
+// NewLogHandler returns a handler writing logs to w in format, either
+// LogFormatText or LogFormatJSON, at or above level, e.g. "debug" or "warn".
+// An empty format or level selects LogFormatText and "info". Every occurrence
+// of secrets in the logged message and values is replaced with [REDACTED],
+// whatever the level.
+func NewLogHandler(w io.Writer, format, level string, secrets ...string) (slog.Handler, error) {
+	logLevel := slog.LevelInfo
+	if level != "" {
+		if err := logLevel.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("invalid log level %q", level)
+		}
+	}
+	opts := &slog.HandlerOptions{
+		Level:       logLevel,
+		ReplaceAttr: redactSecrets(secrets),
+	}
+	switch format {
+	case "", LogFormatText:
+		return slog.NewTextHandler(w, opts), nil
+	case LogFormatJSON:
+		return slog.NewJSONHandler(w, opts), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q", format)
+	}
+}
+
+// SetupLogger sets the default logger to one writing to stderr, as configured
+// by NewLogHandler.
+func SetupLogger(format, level string, secrets ...string) error {
+	handler, err := NewLogHandler(os.Stderr, format, level, secrets...)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// redactSecrets returns a slog.HandlerOptions.ReplaceAttr function replacing
+// the secrets in string and arbitrary values, such as errors, with [REDACTED].
+func redactSecrets(secrets []string) func([]string, slog.Attr) slog.Attr {
+	secrets = slices.DeleteFunc(slices.Clone(secrets), func(secret string) bool {
+		return secret == ""
+	})
+	if len(secrets) == 0 {
+		return nil
+	}
+	return func(_ []string, a slog.Attr) slog.Attr {
+		a.Value = a.Value.Resolve()
+		kind := a.Value.Kind()
+		if kind != slog.KindString && kind != slog.KindAny {
+			return a
+		}
+		s := a.Value.String()
+		redactedValue := s
+		for _, secret := range secrets {
+			redactedValue = strings.ReplaceAll(redactedValue, secret, redacted)
+		}
+		if redactedValue != s {
+			a.Value = slog.StringValue(redactedValue)
+		}
+		return a
+	}
+}
@@ -23,7 +23,9 @@ import (
 	"os/user"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -59,6 +61,21 @@ const (
 	LibrarianConfigFile = "config.yaml"
 	// LibrarianGithubToken is the name of the env var used to store the GitHub token.
 	LibrarianGithubToken = "LIBRARIAN_GITHUB_TOKEN"
+	// TagTypeAnnotated is the tag type of annotated tags, which carry a message.
+	TagTypeAnnotated = "annotated"
+	// TagTypeLightweight is the tag type of lightweight tags, which only point
+	// to a commit.
+	TagTypeLightweight = "lightweight"
+	// LineEndingLF is the line ending of Unix text files, "\n".
+	LineEndingLF = "lf"
+	// LineEndingCRLF is the line ending of Windows text files, "\r\n".
+	LineEndingCRLF = "crlf"
+	// DefaultSince is how far back the tag command searches for merged release
+	// pull requests by default.
+	DefaultSince = 30 * 24 * time.Hour
+	// DefaultContainerTimeout is the maximum duration of a container run by
+	// default.
+	DefaultContainerTimeout = 30 * time.Minute
 )
 
 // are variables so it can be replaced during testing.
@@ -70,6 +87,17 @@ var (
 var (
 	// pullRequestRegexp is regular expression that describes an uri of a pull request.
 	pullRequestRegexp = regexp.MustCompile(`^https://github\.com/([a-zA-Z0-9-._]+)/([a-zA-Z0-9-._]+)/pull/([0-9]+)$`)
+	// containerMemoryRegexp describes a memory limit accepted by docker run,
+	// i.e. a positive integer with an optional b, k, m or g unit suffix.
+	containerMemoryRegexp = regexp.MustCompile(`^[1-9][0-9]*[bkmgBKMG]?$`)
+	// containerCPUsRegexp describes a CPU limit accepted by docker run, i.e. a
+	// decimal number such as "2" or "0.5".
+	containerCPUsRegexp = regexp.MustCompile(`^[0-9]*\.?[0-9]+$`)
+
+	// pubSubTopicRegexp describes the resource name of a Pub/Sub topic.
+	pubSubTopicRegexp = regexp.MustCompile(`^projects/[a-z][a-z0-9-.:]*[a-z0-9]/topics/[a-zA-Z][a-zA-Z0-9-_.~+%]{2,254}$`)
+	// commitHashRegexp matches full or abbreviated git commit hashes.
+	commitHashRegexp = regexp.MustCompile(`^[0-9a-fA-F]{4,40}$`)
 )
 
 // Config holds all configuration values parsed from flags or environment
@@ -91,6 +119,10 @@ type Config struct {
 	// When this is not specified, the googleapis repository is cloned
 	// automatically.
 	//
+	// The generate command accepts a comma-separated list of sources, e.g.
+	// googleapis and a private overlay, which are layered into a single
+	// source tree, later sources overriding the files of earlier ones.
+	//
 	// APISource is used by generate and update-image commands.
 	//
 	// APISource is specified with the -api-source flag.
@@ -100,6 +132,61 @@ type Config struct {
 	// APISource is a GitHub repository, and it is cloned.
 	APISourceDepth int
 
+	// AllowMissingServiceConfig determines whether the generate command
+	// configures a new API without a service config as a proto-only package,
+	// leaving the container to handle its absence, instead of failing. It is
+	// true by default.
+	//
+	// AllowMissingServiceConfig is specified with the
+	// -allow-missing-service-config flag.
+	AllowMissingServiceConfig bool
+
+	// AllowTag determines whether the update-image command accepts an image
+	// reference with a floating tag, e.g. "gcr.io/foo/bar:latest", instead of
+	// requiring an image pinned to a digest.
+	//
+	// AllowTag is specified with the -allow-tag flag.
+	AllowTag bool
+
+	// Amend determines whether to amend the HEAD commit, instead of creating a
+	// new commit, when the HEAD commit was created by a previous generate run
+	// with Amend set. This is intended for iterating locally, and requires
+	// Commit to be set and Push to be unset.
+	//
+	// For the release stage command, Amend instead determines whether to
+	// update the open release pull request created by a previous run, rather
+	// than creating a new one, preserving the edits made to its body outside
+	// of the managed changelog section. This requires Push to be set.
+	//
+	// Amend is specified with the -amend flag.
+	Amend bool
+
+	// Author is the author of commits created by Librarian, of the form
+	// "Name <email>", who is also their committer unless Committer is
+	// specified. It cannot be specified with AuthorName or AuthorEmail.
+	//
+	// Author is used by the generate, release stage and update-image
+	// commands.
+	//
+	// Author is specified with the -author flag.
+	Author string
+
+	// AuthorEmail is the email address of the author of commits created by
+	// Librarian, who is also their committer unless Committer is specified.
+	// When this is not specified, the email address is read from git config,
+	// falling back to the Librarian bot.
+	//
+	// AuthorEmail is specified with the -author-email flag.
+	AuthorEmail string
+
+	// AuthorName is the name of the author of commits created by Librarian,
+	// who is also their committer unless Committer is specified. When this is
+	// not specified, the name is read from git config, falling back to the
+	// Librarian bot.
+	//
+	// AuthorName is specified with the -author-name flag.
+	AuthorName string
+
 	// Branch is the remote branch of the language repository to use.
 	// This is the branch which is cloned when Repo is a URL, and also used
 	// as the base reference for any pull requests created by the command.
@@ -112,6 +199,16 @@ type Config struct {
 	// Build is specified with the -build flag.
 	Build bool
 
+	// CacheDir is the directory in which the API source repository is kept
+	// between runs when APISource is a URL. An existing clone in CacheDir is
+	// updated instead of cloning the repository again. When this is not
+	// specified, the repository is cloned into WorkRoot.
+	//
+	// CacheDir is used by generate and update-image commands.
+	//
+	// CacheDir is specified with the -cache-dir flag.
+	CacheDir string
+
 	// CheckUnexpectedChanges determines whether to do additional checks for
 	// unexpected changes during test-container generate.
 	CheckUnexpectedChanges bool
@@ -132,18 +229,183 @@ type Config struct {
 	// This flag is ignored if Push is set to true.
 	Commit bool
 
+	// CommitMessageTemplate is the template, in Go text/template syntax, of
+	// the messages of the commits created by the generate and release stage
+	// commands. It takes precedence over the template configured in
+	// config.yaml.
+	//
+	// CommitMessageTemplate is specified with the -commit-message-template
+	// flag.
+	CommitMessageTemplate string
+
+	// Committer is the committer of commits created by Librarian, of the form
+	// "Name <email>". When this is not specified, the author of the commits is
+	// also their committer.
+	//
+	// Committer is used by the generate, release stage and update-image
+	// commands.
+	//
+	// Committer is specified with the -committer flag.
+	Committer string
+
+	// Concurrency is the maximum number of libraries the generate command
+	// generates at the same time when generating all libraries. Zero or one
+	// generates libraries one after another.
+	//
+	// Concurrency is specified with the -concurrency flag.
+	Concurrency int
+
+	// ContainerCPUs is the number of CPUs that language containers are
+	// allowed to use, e.g. "1.5". If empty, the number of CPUs is not limited.
+	//
+	// ContainerCPUs is specified with the -container-cpus flag.
+	ContainerCPUs string
+
+	// ContainerLogDir is the directory the output of language containers is
+	// written to as it arrives, in a file per library, so that the progress
+	// of long-running generations can be followed with e.g. tail -f. If
+	// empty, the output is only written to the console.
+	//
+	// ContainerLogDir is specified with the -container-log-dir flag.
+	ContainerLogDir string
+
+	// ContainerMemory is the maximum amount of memory that language
+	// containers are allowed to use, e.g. "4g". If empty, memory is not
+	// limited.
+	//
+	// ContainerMemory is specified with the -container-memory flag.
+	ContainerMemory string
+
+	// ContainerReuse determines whether the generate command sends the
+	// generate requests of all libraries to a single long-lived language
+	// container, instead of running a container per library, to save the
+	// startup time of containers. It only applies to images labeled as
+	// supporting the serve command; other images run a container per library.
+	//
+	// ContainerReuse is specified with the -container-reuse flag.
+	ContainerReuse bool
+
+	// ContainerTimeout is the maximum duration of each language container
+	// run. A container still running once it elapses is killed, and the
+	// command fails. If zero, containers may run forever.
+	//
+	// ContainerTimeout is specified with the -container-timeout flag.
+	ContainerTimeout time.Duration
+
+	// Credentials is the path to a service account key file used by the
+	// automation commands to authenticate with Cloud Build. If empty,
+	// application default credentials are used.
+	//
+	// Credentials is specified with the -credentials flag.
+	Credentials string
+
+	// DestPrefix is a directory of the language repository, relative to its
+	// root, under which the generate command writes the code of libraries.
+	// The source roots of libraries are relative to DestPrefix when copying
+	// and cleaning their code, as are their preserve_regex and remove_regex
+	// patterns, while state.yaml keeps recording them unprefixed. This is used
+	// to vendor generated libraries into a subdirectory of a larger
+	// repository.
+	//
+	// DestPrefix is specified with the -dest-prefix flag.
+	DestPrefix string
+
+	// DetectRenamedAPIs determines whether the generate command migrates the
+	// APIs of libraries whose paths no longer exist in the API source to
+	// their new paths, either configured in api_renames or found next to
+	// their old paths, before generating.
+	//
+	// DetectRenamedAPIs is specified with the -detect-renamed-apis flag.
+	DetectRenamedAPIs bool
+
+	// DiffOutput is the path of a file to which the update-image command
+	// writes a unified diff of the unexpected file changes found with
+	// CheckUnexpectedChanges.
+	//
+	// DiffOutput is specified with the -diff-output flag.
+	DiffOutput string
+
+	// DryRun determines whether the generate command only reports the
+	// libraries it would configure and generate, without running any
+	// containers or changing any files. No commit or pull request is created,
+	// even if Push is set.
+	//
+	// DryRun is specified with the -dry-run flag.
+	DryRun bool
+
+	// ExcludeCommits is a list of commit hashes, full or abbreviated, whose
+	// changes are dropped by the release stage command. Excluded commits
+	// neither appear in the release notes nor contribute to the version bump,
+	// e.g., because they were reverted out-of-band.
+	//
+	// ExcludeCommits is specified with the repeatable -exclude-commit flag.
+	ExcludeCommits []string
+
+	// FailFast stops the generate command at the first library that fails
+	// to generate or build, instead of generating the remaining libraries
+	// and reporting all failures at the end.
+	//
+	// FailFast is specified with the -fail-fast flag.
+	FailFast bool
+
+	// FetchBeforeStage determines whether the release stage command fetches
+	// the branch and tags of the `origin` remote of the language repository
+	// before computing releases, and fails if the local branch is behind the
+	// remote one. Repositories without an `origin` remote are not fetched.
+	//
+	// FetchBeforeStage is specified with the -fetch-before-stage flag.
+	FetchBeforeStage bool
+
+	// Force determines whether the generate, update-image and release stage
+	// commands process the libraries frozen in the librarian config, which are
+	// skipped otherwise.
+	//
+	// Force is specified with the -force flag.
+	Force bool
+
 	// GenerateUnchanged determines whether to generate libraries where none of
 	// the associated APIs have changed since the commit at which they were last
 	// generated. Note that this does not override any configuration indicating
 	// that the library should not be automatically generated.
 	GenerateUnchanged bool
 
+	// GenerateUnchangedFor is a list of library IDs to generate even if none of
+	// their associated APIs have changed. Unlike GenerateUnchanged, other
+	// libraries are still only generated when their APIs have changed.
+	//
+	// GenerateUnchangedFor is specified with the repeatable
+	// -generate-unchanged-for flag.
+	GenerateUnchangedFor []string
+
+	// GeneratorInput is the path of a directory mounted as /input in the
+	// configure and generate containers instead of the
+	// .librarian/generator-input directory of the language repository. This
+	// lets a prepared generator input be supplied without editing the
+	// repository.
+	//
+	// GeneratorInput is specified with the -generator-input flag.
+	GeneratorInput string
+
 	// GitHubAPIEndpoint is the GitHub API endpoint to use for all GitHub API
 	// operations.
 	//
 	// This is intended for testing and should not be used in production.
 	GitHubAPIEndpoint string
 
+	// GitHubMaxRetries is the number of times the tag command retries a
+	// GitHub API request failing with a server error or a secondary rate
+	// limit. Zero disables retries.
+	//
+	// GitHubMaxRetries is specified with the -github-max-retries flag.
+	GitHubMaxRetries int
+
+	// GitHubRetryDelay is the delay before the first retry of a GitHub API
+	// request by the tag command. The delay doubles with each further retry,
+	// unless GitHub specifies the delay with a Retry-After header.
+	//
+	// GitHubRetryDelay is specified with the -github-retry-delay flag.
+	GitHubRetryDelay time.Duration
+
 	// GitHubToken is the access token to use for all operations involving
 	// GitHub.
 	//
@@ -163,6 +425,22 @@ type Config struct {
 	// HostMount is specified with the -host-mount flag.
 	HostMount string
 
+	// IgnoreFreeze determines whether the release stage command stages
+	// releases while they are frozen by the freeze_until option of the
+	// repository config.
+	//
+	// IgnoreFreeze is specified with the -ignore-freeze flag.
+	IgnoreFreeze bool
+
+	// IncludeHiddenCommits determines whether the report of the release stage
+	// command, enabled with ReportUnreleased, also lists the commits which are
+	// not released on their own, such as chore, test and build commits, in a
+	// separate section. It helps checking the boundaries of the commit range
+	// considered for each library.
+	//
+	// IncludeHiddenCommits is specified with the -include-hidden-commits flag.
+	IncludeHiddenCommits bool
+
 	// Image is the language-specific container image to use for language-specific
 	// operations. It is primarily used for testing Librarian and/or new images.
 	//
@@ -174,10 +452,21 @@ type Config struct {
 	// Image is specified with the -image flag.
 	Image string
 
+	// Language restricts the generate and release stage commands to the
+	// libraries of the given language, as set in their state, in repositories
+	// whose state file holds libraries of several languages. If empty, all
+	// libraries are processed.
+	//
+	// Language is specified with the -language flag.
+	Language string
+
 	// Library is the library ID to generate (e.g. secretmanager).
 	// This usually corresponds to a releasable language unit -- for Go this would
 	// be a Go module or for dotnet the name of a NuGet package. If neither this nor
 	// api is specified all currently managed libraries will be regenerated.
+	//
+	// The release stage command also accepts a comma-separated list of
+	// library IDs, in which case exactly those libraries are released together.
 	Library string
 
 	// LibraryToTest is the library ID to test (e.g. secretmanager).
@@ -190,12 +479,155 @@ type Config struct {
 	// This is intended for exceptional cases, such as applying a backport patch
 	// or forcing a major version bump.
 	//
-	// Requires the --library flag to be specified.
+	// Requires the --library flag to be specified with a single library.
 	LibraryVersion string
 
+	// LogFormat is the format of the logs, either "text" or "json". An empty
+	// value selects "text".
+	//
+	// LogFormat is specified with the -log-format flag.
+	LogFormat string
+
+	// LogLevel is the minimum level of the logs, one of "debug", "info", "warn"
+	// or "error". An empty value selects "info". The -v flag of librarian
+	// commands takes precedence and selects "debug", and Quiet selects "error".
+	//
+	// LogLevel is specified with the -log-level flag.
+	LogLevel string
+
+	// ManifestOutput is the path of a file to which the tag command writes a
+	// JSON manifest of the releases it created, listing the library, version,
+	// tag, release URL and commit of each release.
+	//
+	// ManifestOutput is specified with the -manifest-output flag.
+	ManifestOutput string
+
+	// MaxChangelogEntries caps the number of changes listed for each library
+	// in the body of a release pull request. Changes beyond the cap are
+	// summarized in a single line linking to the full comparison. A value of
+	// zero means no cap.
+	//
+	// MaxChangelogEntries is specified with the -max-changelog-entries flag.
+	MaxChangelogEntries int
+
+	// MaxCommits caps the number of commits the release stage command scans
+	// for each library since its last release, keeping the most recent ones.
+	// The next version is determined from the scanned commits only, and the
+	// number of earlier commits is noted in the release notes. A value of zero
+	// means no cap.
+	//
+	// MaxCommits is specified with the -max-commits flag.
+	MaxCommits int
+
+	// MaxContainers is the maximum number of language containers the generate
+	// command runs at the same time, whatever the number of libraries
+	// generated at the same time. A value of zero means no limit.
+	//
+	// MaxContainers is specified with the -max-containers flag.
+	MaxContainers int
+
+	// Mounts lists extra volumes mounted into the language containers running
+	// the build, configure and generate commands, in the format
+	// "{host-dir}:{container-dir}[:ro]", e.g. a Go module cache or a Maven
+	// .m2 directory, to speed up builds. Host directories must exist.
+	//
+	// Mounts is specified with the repeatable -mount flag.
+	Mounts []string
+
+	// NoLock determines whether the generate, release stage and update-image
+	// commands skip locking the language repository. By default, these
+	// commands hold an exclusive lock on the repository while they run, and
+	// fail if another librarian process holds it.
+	//
+	// NoLock is specified with the -no-lock flag.
+	NoLock bool
+
+	// NoNetwork lists the language container commands, among "build",
+	// "configure" and "generate", whose containers are run without network
+	// access, so that they cannot fetch anything at runtime. Containers of
+	// other commands use the default network.
+	//
+	// NoNetwork is specified with the repeatable -no-network flag.
+	NoNetwork []string
+
+	// NormalizeLineEndings is the line ending, either LineEndingLF or
+	// LineEndingCRLF, to which the generate command converts the line endings
+	// of generated text files before copying them into the language
+	// repository. Binary files are left untouched. An empty value leaves line
+	// endings as generated.
+	//
+	// NormalizeLineEndings is specified with the -normalize-line-endings flag.
+	NormalizeLineEndings string
+
+	// OnlyIfAPIChanged determines whether the update-image command keeps only
+	// the libraries whose generated output changed with the new image. Every
+	// library is regenerated, and the libraries whose output is unchanged are
+	// discarded and not built. No commit is created if no library changed.
+	//
+	// OnlyIfAPIChanged is specified with the -only-if-api-changed flag.
+	OnlyIfAPIChanged bool
+
+	// OnlyChanged determines whether the generate command generates only the
+	// libraries with uncommitted changes, either in their source roots in the
+	// language repository or in their APIs in the API source, instead of the
+	// libraries whose APIs changed since they were last generated.
+	//
+	// OnlyChanged is specified with the -only-changed flag.
+	OnlyChanged bool
+
+	// OutputManifest is the path of a JSON file to write, for each library
+	// generated by the generate command, the files it created, modified and
+	// removed in the language repository when copying the generated code or
+	// removing the outputs of its removed APIs. Libraries which fail to
+	// generate are left out. This is used for auditing and cache invalidation.
+	//
+	// OutputManifest is specified with the -output-manifest flag.
+	OutputManifest string
+
+	// OutputState is the path of a file to write the resulting librarian state
+	// to, in addition to the state.yaml file of the language repository. This
+	// lets pipelines consume the state without reading it from the repository.
+	//
+	// OutputState is used by the generate and release stage commands.
+	//
+	// OutputState is specified with the -output-state flag.
+	OutputState string
+
+	// PostReleaseUpdate determines whether the tag command updates the
+	// versions file configured in config.yaml with the versions of the
+	// released libraries.
+	//
+	// PostReleaseUpdate is specified with the -post-release-update flag.
+	PostReleaseUpdate bool
+
+	// PRTemplate is the path of a pull request template, relative to the root
+	// of the language repository, e.g. .github/PULL_REQUEST_TEMPLATE.md. Its
+	// content is prepended to the body of the pull requests created by the
+	// generate and release stage commands, before the section managed by
+	// Librarian.
+	//
+	// PRTemplate is specified with the -pr-template flag.
+	PRTemplate string
+
+	// ProgressInterval is the minimum time between two progress logs of the
+	// generate and release stage commands when processing all libraries. Each
+	// log includes an estimate of the remaining time, based on the average time
+	// taken per library so far. Zero disables progress logs.
+	//
+	// ProgressInterval is specified with the -progress-interval flag.
+	ProgressInterval time.Duration
+
 	// Project is the ID of the Google Cloud project to use.
 	Project string
 
+	// PubSubTopic is the Pub/Sub topic, of the form
+	// projects/{project}/topics/{topic}, to which the generate command
+	// publishes a message with the outcome of each library. Failures to
+	// publish are logged and do not fail the command.
+	//
+	// PubSubTopic is specified with the -pubsub-topic flag.
+	PubSubTopic string
+
 	// PullRequest to target and operate one in the context of a release.
 	//
 	// The pull request should be in the format `https://github.com/{owner}/{repo}/pull/{number}`.
@@ -220,6 +652,25 @@ type Config struct {
 	// Push is specified with the -push flag. No value is required.
 	Push bool
 
+	// Quiet determines whether only errors are logged, and the output of
+	// language containers is only written to the console when they fail.
+	// Otherwise, logs are written at LogLevel, and the output of containers
+	// is written as it arrives, each line prefixed with the library ID and
+	// command, e.g. "[secretmanager generate]".
+	//
+	// Quiet is specified with the -quiet or -q flag.
+	Quiet bool
+
+	// RebaseOntoBase determines whether to rebase the branch created by
+	// Librarian onto the latest Branch of the remote language repository
+	// before pushing it. If the rebase fails because of conflicting changes,
+	// the conflicting files are reported and no pull request is created.
+	//
+	// RebaseOntoBase requires Push to be set.
+	//
+	// RebaseOntoBase is specified with the -rebase-onto-base flag.
+	RebaseOntoBase bool
+
 	// Repo specifies the language repository to use, as either a local root directory
 	// or a URL to clone from. If a local directory is specified, it can
 	// be relative to the current working directory. The repository must
@@ -238,9 +689,83 @@ type Config struct {
 	// Repo is specified with the -repo flag.
 	Repo string
 
+	// Report is the path of a JSON file to write a summary of the generate
+	// command to. The summary lists, for each library, whether it was
+	// configured, regenerated or skipped, the outcome of its generation and
+	// build, and its resulting last generated commit. The summary is written
+	// whether or not Push is set, including when some libraries fail.
+	//
+	// Report is specified with the -report flag.
+	Report string
+
+	// ReportUnreleased determines whether the release stage command only
+	// reports the libraries with releasable changes since their last release,
+	// along with their next versions, instead of staging a release. No state
+	// is written and no containers are run.
+	//
+	// ReportUnreleased is specified with the -report-unreleased flag.
+	ReportUnreleased bool
+
+	// Since is how far back the tag command searches for merged pull requests
+	// with the release:pending label when no pull request is specified. If
+	// zero, DefaultSince is used.
+	//
+	// Since is specified with the -since flag.
+	Since time.Duration
+
+	// SkipConfigure determines whether to skip running configure for a library
+	// that appears to need it, generating the library with its existing
+	// configuration instead. This is an escape hatch for spurious configure
+	// detection, and is only valid for libraries that already have source roots.
+	//
+	// SkipConfigure is specified with the -skip-configure flag.
+	SkipConfigure bool
+
+	// SkipLibrariesWithoutSource determines whether the release stage command
+	// skips, with a warning, the libraries without source roots, whose
+	// onboarding is incomplete. Libraries specified with the -library flag are
+	// never skipped.
+	//
+	// SkipLibrariesWithoutSource is specified with the
+	// -skip-libraries-without-source flag.
+	SkipLibrariesWithoutSource bool
+
+	// SquashMergeTitles determines whether the release stage command parses
+	// the title of the pull request of squash-merged commits as their
+	// conventional commit, instead of their subject. A commit is a squash merge
+	// if its subject ends with the pull request number, e.g. "(#123)", and the
+	// title of the pull request is looked up on GitHub.
+	//
+	// SquashMergeTitles is specified with the -squash-merge-titles flag.
+	SquashMergeTitles bool
+
+	// StrictSemVer determines whether the release stage command requires the
+	// current version of every library to be a complete semantic version,
+	// e.g. 1.2.3. When set, the command fails up front listing the libraries
+	// with other versions. Otherwise, versions such as 1.0 or v2 are coerced
+	// to complete semantic versions when deriving the next version.
+	//
+	// StrictSemVer is specified with the -strict-semver flag.
+	StrictSemVer bool
+
+	// TagType is the type of the tags created for released libraries by the
+	// release tag command, either TagTypeAnnotated or TagTypeLightweight.
+	// Annotated tags carry the release notes of the library as the tag
+	// message. An empty value is treated as TagTypeAnnotated.
+	//
+	// TagType is specified with the -tag-type flag.
+	TagType string
+
 	// Test determines whether to run a test after generation.
 	Test bool
 
+	// TriggerName is the name of the Cloud Build trigger run by the
+	// automation commands. If empty, the default trigger for the command is
+	// run.
+	//
+	// TriggerName is specified with the -trigger-name flag.
+	TriggerName string
+
 	// UserGID is the group ID of the current user. It is used to run Docker
 	// containers with the same user, so that created files have the correct
 	// ownership.
@@ -311,25 +836,145 @@ func (c *Config) deriveRepo() error {
 	if err != nil {
 		return fmt.Errorf("getting working directory: %w", err)
 	}
-	stateFile := filepath.Join(wd, LibrarianDir, LibrarianStateFile)
-	if _, err := os.Stat(stateFile); err != nil {
-		return fmt.Errorf("repo flag not specified and no state file found in current working directory: %w", err)
+	repo, err := findRepoRoot(wd)
+	if err != nil {
+		return err
 	}
-	slog.Info("repo not specified, using current working directory as repo root", "path", wd)
-	c.Repo = wd
+	slog.Info("repo not specified, using the directory holding the state file as repo root", "path", repo)
+	c.Repo = repo
 	return nil
 }
 
+// findRepoRoot returns the closest directory to dir, dir included, holding a
+// librarian state file, so that librarian can be run from anywhere inside a
+// language repository.
+func findRepoRoot(dir string) (string, error) {
+	for current := dir; ; {
+		stateFile := filepath.Join(current, LibrarianDir, LibrarianStateFile)
+		if _, err := os.Stat(stateFile); err == nil {
+			return current, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("checking for state file: %w", err)
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return "", fmt.Errorf("repo flag not specified and no %s file found in %s or any of its parent directories", filepath.Join(LibrarianDir, LibrarianStateFile), dir)
+		}
+		current = parent
+	}
+}
+
 // IsValid ensures the values contained in a Config are valid.
 func (c *Config) IsValid() (bool, error) {
 	if c.Push && c.GitHubToken == "" {
 		return false, errors.New("no GitHub token supplied for push")
 	}
 
+	if c.Amend && c.CommandName == "stage" && !c.Push {
+		return false, errors.New("amend can only be used with push for release stage")
+	}
+
+	if c.Amend && c.CommandName != "stage" && (c.Push || !c.Commit) {
+		return false, errors.New("amend can only be used with commit and without push")
+	}
+
 	if c.Library == "" && c.LibraryVersion != "" {
 		return false, errors.New("specified library version without library id")
 	}
 
+	if strings.Contains(c.Library, ",") && c.LibraryVersion != "" {
+		return false, errors.New("library version can only be used with a single library id")
+	}
+
+	if c.RebaseOntoBase && !c.Push {
+		return false, errors.New("rebase-onto-base can only be used with push")
+	}
+
+	if c.ReportUnreleased && (c.Library != "" || c.LibraryVersion != "") {
+		return false, errors.New("report-unreleased cannot be used with library or library-version")
+	}
+
+	if c.OnlyChanged && (c.GenerateUnchanged || len(c.GenerateUnchangedFor) > 0) {
+		return false, errors.New("only-changed cannot be used with generate-unchanged or generate-unchanged-for")
+	}
+
+	if c.IncludeHiddenCommits && !c.ReportUnreleased {
+		return false, errors.New("include-hidden-commits can only be used with report-unreleased")
+	}
+
+	if c.TagType != "" && c.TagType != TagTypeAnnotated && c.TagType != TagTypeLightweight {
+		return false, fmt.Errorf("invalid tag type %q", c.TagType)
+	}
+
+	if c.NormalizeLineEndings != "" && c.NormalizeLineEndings != LineEndingLF && c.NormalizeLineEndings != LineEndingCRLF {
+		return false, fmt.Errorf("invalid line ending %q", c.NormalizeLineEndings)
+	}
+
+	if c.DestPrefix != "" && !filepath.IsLocal(c.DestPrefix) {
+		return false, fmt.Errorf("invalid dest prefix %q, must be a relative path within the repository", c.DestPrefix)
+	}
+
+	if c.MaxChangelogEntries < 0 {
+		return false, errors.New("max changelog entries cannot be negative")
+	}
+
+	if c.MaxCommits < 0 {
+		return false, errors.New("max commits cannot be negative")
+	}
+
+	if c.Concurrency < 0 {
+		return false, errors.New("concurrency cannot be negative")
+	}
+
+	if strings.Contains(c.APISource, ",") && c.CommandName != "generate" {
+		return false, errors.New("multiple API sources can only be used with the generate command")
+	}
+
+	if c.MaxContainers < 0 {
+		return false, errors.New("max containers cannot be negative")
+	}
+
+	if c.ContainerTimeout < 0 {
+		return false, errors.New("container timeout cannot be negative")
+	}
+
+	if c.ProgressInterval < 0 {
+		return false, errors.New("progress interval cannot be negative")
+	}
+
+	if c.GitHubMaxRetries < 0 {
+		return false, errors.New("github max retries cannot be negative")
+	}
+
+	if c.GitHubRetryDelay < 0 {
+		return false, errors.New("github retry delay cannot be negative")
+	}
+
+	if c.Since < 0 {
+		return false, errors.New("since cannot be negative")
+	}
+
+	if c.ContainerMemory != "" && !containerMemoryRegexp.MatchString(c.ContainerMemory) {
+		return false, fmt.Errorf("invalid container memory %q", c.ContainerMemory)
+	}
+
+	if c.ContainerCPUs != "" {
+		cpus, err := strconv.ParseFloat(c.ContainerCPUs, 64)
+		if !containerCPUsRegexp.MatchString(c.ContainerCPUs) || err != nil || cpus <= 0 {
+			return false, fmt.Errorf("invalid container cpus %q", c.ContainerCPUs)
+		}
+	}
+
+	if c.PubSubTopic != "" && !pubSubTopicRegexp.MatchString(c.PubSubTopic) {
+		return false, fmt.Errorf("invalid Pub/Sub topic %q, must be of the form projects/{project}/topics/{topic}", c.PubSubTopic)
+	}
+
+	for _, hash := range c.ExcludeCommits {
+		if !commitHashRegexp.MatchString(hash) {
+			return false, fmt.Errorf("invalid excluded commit %q", hash)
+		}
+	}
+
 	if c.PullRequest != "" {
 		matched := pullRequestRegexp.MatchString(c.PullRequest)
 		if !matched {
@@ -341,6 +986,16 @@ func (c *Config) IsValid() (bool, error) {
 		return false, err
 	}
 
+	if c.GeneratorInput != "" {
+		info, err := os.Stat(c.GeneratorInput)
+		if err != nil {
+			return false, fmt.Errorf("invalid generator input: %w", err)
+		}
+		if !info.IsDir() {
+			return false, fmt.Errorf("generator input %q is not a directory", c.GeneratorInput)
+		}
+	}
+
 	if c.Repo == "" {
 		return false, errors.New("language repository not specified or detected")
 	}
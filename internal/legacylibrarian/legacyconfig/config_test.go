@@ -21,6 +21,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -225,6 +226,369 @@ func TestIsValid(t *testing.T) {
 			wantErr:    true,
 			wantErrMsg: "language repository not specified or detected",
 		},
+		{
+			name: "Invalid config - negative max changelog entries",
+			cfg: Config{
+				MaxChangelogEntries: -1,
+				Repo:                "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "max changelog entries cannot be negative",
+		},
+		{
+			name: "Invalid config - negative max commits",
+			cfg: Config{
+				MaxCommits: -1,
+				Repo:       "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "max commits cannot be negative",
+		},
+		{
+			name: "Invalid config - library version with multiple libraries",
+			cfg: Config{
+				Library:        "a,b",
+				LibraryVersion: "1.2.3",
+				Repo:           "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "library version can only be used with a single library id",
+		},
+		{
+			name: "Invalid config - negative concurrency",
+			cfg: Config{
+				Concurrency: -1,
+				Repo:        "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "concurrency cannot be negative",
+		},
+		{
+			name: "Invalid config - multiple API sources outside generate",
+			cfg: Config{
+				APISource:   "/tmp/googleapis,/tmp/overlay",
+				CommandName: "update-image",
+				Repo:        "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "multiple API sources can only be used with the generate command",
+		},
+		{
+			name: "Invalid config - negative max containers",
+			cfg: Config{
+				MaxContainers: -1,
+				Repo:          "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "max containers cannot be negative",
+		},
+		{
+			name: "Invalid config - negative container timeout",
+			cfg: Config{
+				ContainerTimeout: -time.Second,
+				Repo:             "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "container timeout cannot be negative",
+		},
+		{
+			name: "Invalid config - negative progress interval",
+			cfg: Config{
+				ProgressInterval: -time.Second,
+				Repo:             "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "progress interval cannot be negative",
+		},
+		{
+			name: "Invalid config - negative github max retries",
+			cfg: Config{
+				GitHubMaxRetries: -1,
+				Repo:             "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "github max retries cannot be negative",
+		},
+		{
+			name: "Invalid config - negative github retry delay",
+			cfg: Config{
+				GitHubRetryDelay: -time.Second,
+				Repo:             "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "github retry delay cannot be negative",
+		},
+		{
+			name: "Invalid config - negative since",
+			cfg: Config{
+				Since: -time.Hour,
+				Repo:  "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "since cannot be negative",
+		},
+		{
+			name: "Valid config - generator input",
+			cfg: Config{
+				GeneratorInput: t.TempDir(),
+				Repo:           "/tmp/some/repo",
+			},
+		},
+		{
+			name: "Invalid config - missing generator input",
+			cfg: Config{
+				GeneratorInput: filepath.Join(t.TempDir(), "missing"),
+				Repo:           "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid generator input",
+		},
+		{
+			name: "Invalid config - generator input is a file",
+			cfg: Config{
+				GeneratorInput: "config_test.go",
+				Repo:           "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: `generator input "config_test.go" is not a directory`,
+		},
+		{
+			name: "Valid config - container resource limits",
+			cfg: Config{
+				ContainerCPUs:   "1.5",
+				ContainerMemory: "4g",
+				Repo:            "/tmp/some/repo",
+			},
+		},
+		{
+			name: "Invalid config - invalid container memory",
+			cfg: Config{
+				ContainerMemory: "4 gigabytes",
+				Repo:            "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid container memory "4 gigabytes"`,
+		},
+		{
+			name: "Invalid config - zero container cpus",
+			cfg: Config{
+				ContainerCPUs: "0",
+				Repo:          "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid container cpus "0"`,
+		},
+		{
+			name: "Invalid config - non-numeric container cpus",
+			cfg: Config{
+				ContainerCPUs: "NaN",
+				Repo:          "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid container cpus "NaN"`,
+		},
+		{
+			name: "Valid config - Pub/Sub topic",
+			cfg: Config{
+				PubSubTopic: "projects/my-project/topics/librarian-results",
+				Repo:        "/tmp/some/repo",
+			},
+		},
+		{
+			name: "Invalid config - Pub/Sub topic without project",
+			cfg: Config{
+				PubSubTopic: "librarian-results",
+				Repo:        "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid Pub/Sub topic "librarian-results"`,
+		},
+		{
+			name: "Valid config - amend with commit",
+			cfg: Config{
+				Amend:  true,
+				Commit: true,
+				Repo:   "/tmp/some/repo",
+			},
+		},
+		{
+			name: "Invalid config - amend without commit",
+			cfg: Config{
+				Amend: true,
+				Repo:  "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "amend can only be used with commit and without push",
+		},
+		{
+			name: "Invalid config - amend with push",
+			cfg: Config{
+				Amend:       true,
+				Commit:      true,
+				Push:        true,
+				GitHubToken: "token",
+				Repo:        "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "amend can only be used with commit and without push",
+		},
+		{
+			name: "Valid config - stage amend with push",
+			cfg: Config{
+				Amend:       true,
+				CommandName: "stage",
+				GitHubToken: "token",
+				Push:        true,
+				Repo:        "/tmp/some/repo",
+			},
+		},
+		{
+			name: "Invalid config - stage amend without push",
+			cfg: Config{
+				Amend:       true,
+				CommandName: "stage",
+				Commit:      true,
+				Repo:        "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "amend can only be used with push for release stage",
+		},
+		{
+			name: "Valid config - rebase onto base",
+			cfg: Config{
+				GitHubToken:    "token",
+				Push:           true,
+				RebaseOntoBase: true,
+				Repo:           "/tmp/some/repo",
+			},
+		},
+		{
+			name: "Invalid config - rebase onto base without push",
+			cfg: Config{
+				Commit:         true,
+				RebaseOntoBase: true,
+				Repo:           "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "rebase-onto-base can only be used with push",
+		},
+		{
+			name: "Valid config - excluded commits",
+			cfg: Config{
+				ExcludeCommits: []string{"abcdef1", "0123456789ABCDEF0123456789abcdef01234567"},
+				Repo:           "/tmp/some/repo",
+			},
+		},
+		{
+			name: "Invalid config - excluded commit is not a hash",
+			cfg: Config{
+				ExcludeCommits: []string{"main"},
+				Repo:           "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid excluded commit "main"`,
+		},
+		{
+			name: "Valid config - lightweight tag type",
+			cfg: Config{
+				TagType: TagTypeLightweight,
+				Repo:    "/tmp/some/repo",
+			},
+		},
+		{
+			name: "Invalid config - unknown tag type",
+			cfg: Config{
+				TagType: "signed",
+				Repo:    "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid tag type "signed"`,
+		},
+		{
+			name: "Valid config - normalize line endings",
+			cfg: Config{
+				NormalizeLineEndings: LineEndingLF,
+				Repo:                 "/tmp/some/repo",
+			},
+		},
+		{
+			name: "Invalid config - normalize line endings",
+			cfg: Config{
+				NormalizeLineEndings: "cr",
+				Repo:                 "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid line ending "cr"`,
+		},
+		{
+			name: "Valid config - report unreleased",
+			cfg: Config{
+				ReportUnreleased: true,
+				Repo:             "/tmp/some/repo",
+			},
+		},
+		{
+			name: "Invalid config - report unreleased with library",
+			cfg: Config{
+				Library:          "example-id",
+				ReportUnreleased: true,
+				Repo:             "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "report-unreleased cannot be used with library or library-version",
+		},
+		{
+			name: "Valid config - dest prefix",
+			cfg: Config{
+				DestPrefix: "third_party/clients",
+				Repo:       "/tmp/some/repo",
+			},
+		},
+		{
+			name: "Invalid config - dest prefix outside the repository",
+			cfg: Config{
+				DestPrefix: "../clients",
+				Repo:       "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid dest prefix "../clients", must be a relative path within the repository`,
+		},
+		{
+			name: "Invalid config - absolute dest prefix",
+			cfg: Config{
+				DestPrefix: "/clients",
+				Repo:       "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid dest prefix "/clients", must be a relative path within the repository`,
+		},
+		{
+			name: "Invalid config - only changed with generate unchanged",
+			cfg: Config{
+				GenerateUnchanged: true,
+				OnlyChanged:       true,
+				Repo:              "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "only-changed cannot be used with generate-unchanged or generate-unchanged-for",
+		},
+		{
+			name: "Valid config - include hidden commits",
+			cfg: Config{
+				IncludeHiddenCommits: true,
+				ReportUnreleased:     true,
+				Repo:                 "/tmp/some/repo",
+			},
+		},
+		{
+			name: "Invalid config - include hidden commits without report unreleased",
+			cfg: Config{
+				IncludeHiddenCommits: true,
+				Repo:                 "/tmp/some/repo",
+			},
+			wantErr:    true,
+			wantErrMsg: "include-hidden-commits can only be used with report-unreleased",
+		},
 		{
 			name: "Invalid config - invalid pull request url",
 			cfg: Config{
@@ -327,6 +691,7 @@ func TestDeriveRepo(t *testing.T) {
 		name         string
 		config       *Config
 		setup        func(t *testing.T, dir string)
+		subdir       string
 		wantErr      bool
 		wantRepoPath string
 	}{
@@ -351,11 +716,40 @@ func TestDeriveRepo(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:   "empty repo path, state file in parent directory",
+			config: &Config{},
+			setup: func(t *testing.T, dir string) {
+				stateDir := filepath.Join(dir, LibrarianDir)
+				if err := os.MkdirAll(stateDir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				stateFile := filepath.Join(stateDir, LibrarianStateFile)
+				if err := os.WriteFile(stateFile, []byte("test"), 0644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.MkdirAll(filepath.Join(dir, "packages", "foo"), 0755); err != nil {
+					t.Fatal(err)
+				}
+			},
+			subdir: filepath.Join("packages", "foo"),
+		},
 		{
 			name:    "empty repo path, no state file",
 			config:  &Config{},
 			wantErr: true,
 		},
+		{
+			name:   "empty repo path, no state file in parent directories",
+			config: &Config{},
+			setup: func(t *testing.T, dir string) {
+				if err := os.MkdirAll(filepath.Join(dir, "packages", "foo"), 0755); err != nil {
+					t.Fatal(err)
+				}
+			},
+			subdir:  filepath.Join("packages", "foo"),
+			wantErr: true,
+		},
 		{
 			name: "version command",
 			config: &Config{
@@ -370,7 +764,7 @@ func TestDeriveRepo(t *testing.T) {
 			if test.setup != nil {
 				test.setup(t, tmpDir)
 			}
-			t.Chdir(tmpDir)
+			t.Chdir(filepath.Join(tmpDir, test.subdir))
 
 			err := test.config.deriveRepo()
 			if (err != nil) != test.wantErr {
@@ -16,6 +16,14 @@ package legacyconfig
 
 import (
 	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/semver"
 )
 
 const (
@@ -26,18 +34,163 @@ const (
 
 // LibrarianConfig defines the contract for the config.yaml file.
 type LibrarianConfig struct {
+	// The renamed API paths, mapping the old path of each API, e.g.
+	// "google/cloud/x/v1", to its new path, e.g. "google/cloud/x/v2". The
+	// generate command migrates the APIs of libraries to their new paths when
+	// run with the -detect-renamed-apis flag.
+	APIRenames map[string]string `yaml:"api_renames"`
+	// The template of the URL linked from the Piper CL numbers in release
+	// notes, in which {cl} is replaced with the CL number, e.g.
+	// "https://critique.example.com/cl/{cl}". If empty, CL numbers are not
+	// linked.
+	CLURLTemplate string `yaml:"cl_url_template"`
+	// The template of the URL linked from the commit hashes in release notes,
+	// in which {sha} is replaced with the commit hash and {short_sha} with its
+	// first 8 characters, e.g. "https://git.example.com/repo/+/{sha}". If
+	// empty, commits link to the language repository on GitHub.
+	CommitURLTemplate string `yaml:"commit_url_template"`
+	// The templates of the messages of the commits created by the generate
+	// and release stage commands.
+	CommitMessageTemplates *CommitMessageTemplates `yaml:"commit_message_templates"`
+	// The GitHub users, e.g. "octocat", and teams, e.g. "googleapis/yoshi-go",
+	// requested to review every pull request created by Librarian, in addition
+	// to the maintainers of the changed libraries.
+	DefaultReviewers []string `yaml:"default_reviewers"`
+	// Regular expressions matched against the name and email of the author
+	// of each commit considered by the release stage command. Commits by a
+	// matching author, e.g. a bot, are left out of release notes and do not
+	// trigger a release.
+	ExcludeCommitAuthors []string         `yaml:"exclude_commit_authors"`
 	GlobalFilesAllowlist []*GlobalFile    `yaml:"global_files_allowlist"`
 	Libraries            []*LibraryConfig `yaml:"libraries"`
-	TagFormat            string           `yaml:"tag_format"`
+	// The time, e.g. "2025-01-06T00:00:00Z", until which the release stage
+	// command refuses to stage releases, e.g. during a holiday code freeze,
+	// unless the -ignore-freeze flag is specified. If zero, releases are not
+	// frozen.
+	FreezeUntil time.Time `yaml:"freeze_until"`
+	// A regular expression which the IDs of new libraries must fully match,
+	// e.g. `google\.cloud\.[a-z]+\.v[0-9]+`. The generate command rejects
+	// onboarding a library with a non-conforming ID before configuring it.
+	// Existing libraries are not checked.
+	LibraryIDPattern string `yaml:"library_id_pattern"`
+	// Whether release notes list chore, test and build commits in an "Other
+	// Changes" section. If false, these commits are left out of release notes.
+	ListOtherChanges bool `yaml:"list_other_changes"`
+	// The maximum number of libraries released by a single pull request
+	// created by the release stage command. When more libraries need to be
+	// released, they are split across several pull requests. If zero, all
+	// libraries are released by a single pull request.
+	MaxLibrariesPerPR int `yaml:"max_libraries_per_pr"`
+	// The minimum version of Librarian required to operate on the repository.
+	MinLibrarianVersion string `yaml:"min_librarian_version"`
+	// Whether the release stage command also attributes a commit without a
+	// Library-IDs footer to the libraries listed by an "Affects: a, b, c" line
+	// in its body, as if the line were a Library-IDs footer, even if it
+	// changes no file under their source roots.
+	ParseAffectsLines bool `yaml:"parse_affects_lines"`
+	// The automation run by the tag command after libraries are released.
+	PostRelease *PostReleaseConfig `yaml:"post_release"`
+	// Whether the release stage command releases a library whose only
+	// version-bumping commits are dependency updates, i.e. commits of type
+	// "deps" or with the "deps" scope, such as "chore(deps): ...". If set to
+	// false, such a library is not released, and its dependency updates are
+	// listed in the release notes of its next release. Defaults to true.
+	ReleaseOnDepsOnly *bool  `yaml:"release_on_deps_only"`
+	TagFormat         string `yaml:"tag_format"`
+	// Whether every library of the repository must have at least one
+	// maintainer configured in its library config. Librarian fails to load a
+	// repository with a library without maintainers.
+	RequireMaintainers bool `yaml:"require_maintainers"`
+	// The version bump, one of "major", "minor", "patch" or "none", caused
+	// by commits of each conventional commit type, e.g. {"perf": "patch"}.
+	// Configured types override the defaults of feat (minor) and fix
+	// (patch). Commits of any other type do not bump the version. Breaking
+	// changes always cause a major bump.
+	VersionBumps map[string]string `yaml:"version_bumps"`
+}
+
+// CommitMessageTemplates defines the templates, in Go text/template syntax,
+// of the messages of the commits created by Librarian. An empty template
+// keeps the default message of the command.
+type CommitMessageTemplates struct {
+	// The template of the commits created by the generate command.
+	Generate string `yaml:"generate"`
+	// The template of the commits created by the release stage command.
+	ReleaseStage string `yaml:"release_stage"`
 }
 
+// PostReleaseConfig defines the automation run by the tag command, when
+// invoked with -post-release-update, after libraries are released.
+type PostReleaseConfig struct {
+	// The path of a file tracking the released version of each library,
+	// relative to the repository root, e.g. "versions.txt".
+	VersionsFile string `yaml:"versions_file"`
+	// The format of the line of a library in the versions file, in which
+	// {id} and {version} are replaced with the library ID and the released
+	// version. If empty, DefaultVersionsFormat is used.
+	VersionsFormat string `yaml:"versions_format"`
+	// Whether to open a pull request updating the versions file, instead of
+	// committing the update directly to the base branch of the release pull
+	// request.
+	CreatePullRequest bool `yaml:"create_pull_request"`
+}
+
+// BuildFileTemplateExt is the extension of build file templates, which is
+// removed from the name of the rendered files.
+const BuildFileTemplateExt = ".tmpl"
+
+// DefaultVersionsFormat is the format of the lines of the versions file if
+// none is configured.
+const DefaultVersionsFormat = "{id}:{version}"
+
 // LibraryConfig defines configuration for a single library, identified by its ID.
 type LibraryConfig struct {
+	// The path of a template, relative to the repository root, rendered into
+	// each source root of this library when it is onboarded, e.g.
+	// "templates/BUILD.bazel.tmpl". The rendered file is named after the
+	// template, without its ".tmpl" extension.
+	BuildFileTemplate string `yaml:"build_file_template"`
+	// The path of the changelog file of this library, relative to the
+	// repository root. If empty, the container uses its default location.
+	ChangelogPath string `yaml:"changelog_path"`
+	// The directories of the repository, relative to its root, mounted
+	// read-only in the container configuring this library, in addition to
+	// the global files and existing source roots, e.g. the parent module
+	// directory holding a go.work file.
+	ConfigureMounts []string `yaml:"configure_mounts"`
+	// Whether this library is frozen: it is neither generated nor released,
+	// even when explicitly requested, unless the -force flag is specified.
+	// Unlike generate_blocked and release_blocked, naming the library is not
+	// enough to override it.
+	Frozen          bool   `yaml:"frozen"`
 	GenerateBlocked bool   `yaml:"generate_blocked"`
 	LibraryID       string `yaml:"id"`
-	NextVersion     string `yaml:"next_version"`
-	ReleaseBlocked  bool   `yaml:"release_blocked"`
-	TagFormat       string `yaml:"tag_format"`
+	// A shell command run with sh in each source root of this library after
+	// it is generated, e.g. "golangci-lint run ./...". The generation of the
+	// library fails if the command exits with a non-zero status.
+	LintCommand string `yaml:"lint_command"`
+	// The GitHub users and teams maintaining this library, requested to review
+	// the pull requests created by Librarian which change it.
+	Maintainers []string `yaml:"maintainers"`
+	// The minimum time between two releases of this library, e.g. "168h".
+	// Unless the library is explicitly requested, release stage skips it
+	// while its last release tag is more recent than this.
+	MinReleaseInterval time.Duration `yaml:"min_release_interval"`
+	NextVersion        string        `yaml:"next_version"`
+	ReleaseBlocked     bool          `yaml:"release_blocked"`
+	// The branch holding the releases of this library, e.g. "release-v1" in
+	// repositories maintaining several major versions. The tag command tags
+	// the merge commit of release pull requests merged into this branch, and
+	// the head of this branch for release pull requests merged into any
+	// other branch. If empty, the merge commit is always tagged.
+	ReleaseBranch string `yaml:"release_branch"`
+	// The service configs of the APIs of this library, mapping the path of
+	// each API, e.g. "google/cloud/x/v1", to the name of its service config
+	// file in the API directory, or a glob pattern matching it, e.g.
+	// "x_v1.yaml" or "*_v1.yaml". APIs without an entry use the only YAML
+	// file of their directory declaring "type: google.api.Service".
+	ServiceConfigs map[string]string `yaml:"service_configs"`
+	TagFormat      string            `yaml:"tag_format"`
 	// Whether to create a GitHub release for this library.
 	SkipGitHubReleaseCreation bool `yaml:"skip_github_release_creation"`
 }
@@ -54,6 +207,18 @@ var validPermissions = map[string]bool{
 	PermissionReadWrite: true,
 }
 
+// validVersionBumps lists the allowed values of LibrarianConfig.VersionBumps.
+var validVersionBumps = map[string]bool{
+	"major": true,
+	"minor": true,
+	"patch": true,
+	"none":  true,
+}
+
+// reviewerRegex matches a GitHub user login, e.g. "octocat", or team, e.g.
+// "googleapis/yoshi-go", with an optional leading "@".
+var reviewerRegex = regexp.MustCompile(`^@?[A-Za-z0-9][A-Za-z0-9-]*(/[A-Za-z0-9][A-Za-z0-9_.-]*)?$`)
+
 // Validate checks that the LibrarianConfig is valid.
 func (g *LibrarianConfig) Validate() error {
 	for i, globalFile := range g.GlobalFilesAllowlist {
@@ -65,10 +230,154 @@ func (g *LibrarianConfig) Validate() error {
 			return fmt.Errorf("invalid global file permissions at index %d: %q", i, permissions)
 		}
 	}
+	for oldPath, newPath := range g.APIRenames {
+		if !isValidRelativePath(oldPath) || !isValidRelativePath(newPath) || oldPath == newPath {
+			return fmt.Errorf("invalid api_renames entry: %q: %q", oldPath, newPath)
+		}
+	}
+	if g.CommitURLTemplate != "" && !strings.Contains(g.CommitURLTemplate, "{sha}") && !strings.Contains(g.CommitURLTemplate, "{short_sha}") {
+		return fmt.Errorf("invalid commit_url_template %q: must contain {sha} or {short_sha}", g.CommitURLTemplate)
+	}
+	if g.CLURLTemplate != "" && !strings.Contains(g.CLURLTemplate, "{cl}") {
+		return fmt.Errorf("invalid cl_url_template %q: must contain {cl}", g.CLURLTemplate)
+	}
+	for _, pattern := range g.ExcludeCommitAuthors {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid exclude_commit_authors pattern %q: %w", pattern, err)
+		}
+	}
+	if g.LibraryIDPattern != "" {
+		if _, err := regexp.Compile(g.LibraryIDPattern); err != nil {
+			return fmt.Errorf("invalid library_id_pattern %q: %w", g.LibraryIDPattern, err)
+		}
+	}
+	for _, reviewer := range g.DefaultReviewers {
+		if !reviewerRegex.MatchString(reviewer) {
+			return fmt.Errorf("invalid default_reviewers entry %q", reviewer)
+		}
+	}
+	for _, library := range g.Libraries {
+		for _, maintainer := range library.Maintainers {
+			if !reviewerRegex.MatchString(maintainer) {
+				return fmt.Errorf("invalid maintainers entry for library %q: %q", library.LibraryID, maintainer)
+			}
+		}
+		if library.ChangelogPath != "" && !isValidRelativePath(library.ChangelogPath) {
+			return fmt.Errorf("invalid changelog_path for library %q: %q", library.LibraryID, library.ChangelogPath)
+		}
+		for _, mount := range library.ConfigureMounts {
+			if !isValidRelativePath(mount) {
+				return fmt.Errorf("invalid configure_mounts entry for library %q: %q", library.LibraryID, mount)
+			}
+		}
+		if library.BuildFileTemplate != "" && (!isValidRelativePath(library.BuildFileTemplate) || !strings.HasSuffix(library.BuildFileTemplate, BuildFileTemplateExt)) {
+			return fmt.Errorf("invalid build_file_template for library %q: %q", library.LibraryID, library.BuildFileTemplate)
+		}
+		for apiPath, pattern := range library.ServiceConfigs {
+			if _, err := path.Match(pattern, ""); err != nil || pattern == "" || strings.Contains(pattern, "/") {
+				return fmt.Errorf("invalid service_configs entry for library %q: %q: %q", library.LibraryID, apiPath, pattern)
+			}
+		}
+		if library.TagFormat != "" {
+			if err := ValidateTagFormat(library.TagFormat); err != nil {
+				return fmt.Errorf("invalid tag_format for library %q: %w", library.LibraryID, err)
+			}
+		}
+		if library.MinReleaseInterval < 0 {
+			return fmt.Errorf("invalid min_release_interval for library %q: %s", library.LibraryID, library.MinReleaseInterval)
+		}
+	}
+	if g.TagFormat != "" {
+		if err := ValidateTagFormat(g.TagFormat); err != nil {
+			return fmt.Errorf("invalid tag_format: %w", err)
+		}
+	}
+	for commitType, bump := range g.VersionBumps {
+		if commitType == "" {
+			return fmt.Errorf("invalid version_bumps: commit type cannot be empty")
+		}
+		if !validVersionBumps[bump] {
+			return fmt.Errorf("invalid version_bumps entry for commit type %q: %q", commitType, bump)
+		}
+	}
+	if g.MaxLibrariesPerPR < 0 {
+		return fmt.Errorf("invalid max_libraries_per_pr: %d", g.MaxLibrariesPerPR)
+	}
+	if g.PostRelease != nil {
+		if !isValidRelativePath(g.PostRelease.VersionsFile) {
+			return fmt.Errorf("invalid post_release versions_file: %q", g.PostRelease.VersionsFile)
+		}
+		format := g.PostRelease.VersionsFormat
+		if format != "" && (!strings.Contains(format, "{id}") || !strings.Contains(format, "{version}")) {
+			return fmt.Errorf("invalid post_release versions_format: must contain {id} and {version}")
+		}
+	}
+	if g.MinLibrarianVersion != "" {
+		if _, ok := versionCore(g.MinLibrarianVersion); !ok {
+			return fmt.Errorf("invalid min_librarian_version: %q", g.MinLibrarianVersion)
+		}
+	}
 
 	return nil
 }
 
+// CheckLibraryID returns an error if the given ID of a new library does not
+// fully match LibraryIDPattern. Any ID is accepted if no pattern is
+// configured.
+func (g *LibrarianConfig) CheckLibraryID(libraryID string) error {
+	if g == nil || g.LibraryIDPattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile("^(?:" + g.LibraryIDPattern + ")$")
+	if err != nil {
+		return fmt.Errorf("invalid library_id_pattern %q: %w", g.LibraryIDPattern, err)
+	}
+	if !re.MatchString(libraryID) {
+		return fmt.Errorf("library ID %q does not match the library_id_pattern %q of the repository", libraryID, g.LibraryIDPattern)
+	}
+	return nil
+}
+
+// CheckLibrarianVersion returns an error if the given version of the running
+// Librarian binary is older than MinLibrarianVersion. Pre-release and
+// pseudo-version suffixes are ignored, so a development build of 1.2.0
+// satisfies a minimum of 1.2.0. Versions that cannot be parsed, such as those
+// of binaries built without version information, are not checked.
+func (g *LibrarianConfig) CheckLibrarianVersion(version string) error {
+	if g == nil || g.MinLibrarianVersion == "" {
+		return nil
+	}
+	current, ok := versionCore(version)
+	if !ok {
+		slog.Warn("unable to determine librarian version, skipping minimum version check",
+			"version", version, "min_librarian_version", g.MinLibrarianVersion)
+		return nil
+	}
+	minimum, ok := versionCore(g.MinLibrarianVersion)
+	if !ok {
+		return fmt.Errorf("invalid min_librarian_version: %q", g.MinLibrarianVersion)
+	}
+	if semver.Compare(current, minimum) < 0 {
+		return fmt.Errorf("librarian version %s is older than the minimum version %s required by this repository, please upgrade librarian",
+			version, g.MinLibrarianVersion)
+	}
+	return nil
+}
+
+// versionCore returns the "vMAJOR.MINOR.PATCH" form of a version, with or
+// without a leading "v", dropping any pre-release or build suffix.
+func versionCore(version string) (string, bool) {
+	v := version
+	if !strings.HasPrefix(v, "v") {
+		v = "v" + v
+	}
+	if !semver.IsValid(v) {
+		return "", false
+	}
+	v = semver.Canonical(v)
+	return strings.TrimSuffix(v, semver.Prerelease(v)), true
+}
+
 // LibraryConfigFor finds the LibraryConfig entry for a given LibraryID.
 func (g *LibrarianConfig) LibraryConfigFor(LibraryID string) *LibraryConfig {
 	for _, lib := range g.Libraries {
@@ -79,6 +388,41 @@ func (g *LibrarianConfig) LibraryConfigFor(LibraryID string) *LibraryConfig {
 	return nil
 }
 
+// PullRequestReviewers returns the reviewers of a pull request changing the
+// given libraries: the default reviewers, followed by the maintainers of each
+// library. Duplicates are removed, ignoring case and any leading "@".
+func (g *LibrarianConfig) PullRequestReviewers(libraryIDs []string) []string {
+	if g == nil {
+		return nil
+	}
+	candidates := slices.Clone(g.DefaultReviewers)
+	for _, id := range libraryIDs {
+		if libConfig := g.LibraryConfigFor(id); libConfig != nil {
+			candidates = append(candidates, libConfig.Maintainers...)
+		}
+	}
+	var reviewers []string
+	seen := make(map[string]bool)
+	for _, reviewer := range candidates {
+		key := strings.ToLower(strings.TrimPrefix(reviewer, "@"))
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		reviewers = append(reviewers, reviewer)
+	}
+	return reviewers
+}
+
+// ReleasesOnDepsOnly reports whether libraries whose only version-bumping
+// commits are dependency updates are released, see ReleaseOnDepsOnly.
+func (g *LibrarianConfig) ReleasesOnDepsOnly() bool {
+	if g == nil || g.ReleaseOnDepsOnly == nil {
+		return true
+	}
+	return *g.ReleaseOnDepsOnly
+}
+
 // IsGenerationBlocked returns true if the library is configured to block generation.
 func (g *LibrarianConfig) IsGenerationBlocked(libraryID string) bool {
 	if g == nil {
@@ -88,6 +432,50 @@ func (g *LibrarianConfig) IsGenerationBlocked(libraryID string) bool {
 	return libConfig != nil && libConfig.GenerateBlocked
 }
 
+// CheckMaintainers returns an error listing the libraries of state without
+// maintainers if RequireMaintainers is set.
+func (g *LibrarianConfig) CheckMaintainers(state *LibrarianState) error {
+	if missing := g.LibrariesWithoutMaintainers(state); len(missing) > 0 {
+		return fmt.Errorf("maintainers are required by require_maintainers but not configured for libraries: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// LibrariesWithoutMaintainers returns the IDs of the libraries of state
+// without maintainers if RequireMaintainers is set, and nil otherwise.
+func (g *LibrarianConfig) LibrariesWithoutMaintainers(state *LibrarianState) []string {
+	if g == nil || !g.RequireMaintainers || state == nil {
+		return nil
+	}
+	var missing []string
+	for _, library := range state.Libraries {
+		if library == nil {
+			continue
+		}
+		if libConfig := g.LibraryConfigFor(library.ID); libConfig == nil || len(libConfig.Maintainers) == 0 {
+			missing = append(missing, library.ID)
+		}
+	}
+	return missing
+}
+
+// IsReleaseFrozen returns true if releases of the repository are frozen at now,
+// i.e. now is before FreezeUntil, the time, e.g. "2025-01-06T00:00:00Z", until
+// which the release stage command refuses to stage releases, unless the
+// -ignore-freeze flag is specified.
+func (g *LibrarianConfig) IsReleaseFrozen(now time.Time) bool {
+	return g != nil && now.Before(g.FreezeUntil)
+}
+
+// IsFrozen returns true if the library with the given ID is frozen.
+func (g *LibrarianConfig) IsFrozen(libraryID string) bool {
+	if g == nil {
+		return false
+	}
+	libConfig := g.LibraryConfigFor(libraryID)
+	return libConfig != nil && libConfig.Frozen
+}
+
 // GetGlobalFiles returns the global files defined in the librarian config.
 func (g *LibrarianConfig) GetGlobalFiles() []string {
 	var globalFiles []string
@@ -17,6 +17,7 @@ package legacyconfig
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -64,6 +65,324 @@ func TestGlobalConfig_Validate(t *testing.T) {
 			wantErr:    true,
 			wantErrMsg: "invalid global file path",
 		},
+		{
+			name: "valid changelog path",
+			config: &LibrarianConfig{
+				Libraries: []*LibraryConfig{
+					{LibraryID: "example-library", ChangelogPath: "docs/history.md"},
+				},
+			},
+		},
+		{
+			name: "invalid changelog path",
+			config: &LibrarianConfig{
+				Libraries: []*LibraryConfig{
+					{LibraryID: "example-library", ChangelogPath: "../CHANGELOG.md"},
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid changelog_path",
+		},
+		{
+			name: "valid configure mounts",
+			config: &LibrarianConfig{
+				Libraries: []*LibraryConfig{
+					{LibraryID: "example-library", ConfigureMounts: []string{".", "parent/module"}},
+				},
+			},
+		},
+		{
+			name: "configure mount outside of repository",
+			config: &LibrarianConfig{
+				Libraries: []*LibraryConfig{
+					{LibraryID: "example-library", ConfigureMounts: []string{"module", "../workspace"}},
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid configure_mounts entry for library "example-library": "../workspace"`,
+		},
+		{
+			name: "valid build file template",
+			config: &LibrarianConfig{
+				Libraries: []*LibraryConfig{
+					{LibraryID: "example-library", BuildFileTemplate: "templates/BUILD.bazel.tmpl"},
+				},
+			},
+		},
+		{
+			name: "build file template outside of repository",
+			config: &LibrarianConfig{
+				Libraries: []*LibraryConfig{
+					{LibraryID: "example-library", BuildFileTemplate: "../BUILD.bazel.tmpl"},
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid build_file_template",
+		},
+		{
+			name: "valid reviewers",
+			config: &LibrarianConfig{
+				DefaultReviewers: []string{"octocat", "@googleapis/yoshi-go"},
+				Libraries: []*LibraryConfig{
+					{LibraryID: "example-library", Maintainers: []string{"@hubot", "googleapis/cloud-sdk_team.x"}},
+				},
+			},
+		},
+		{
+			name: "invalid default reviewer",
+			config: &LibrarianConfig{
+				DefaultReviewers: []string{"not a login"},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid default_reviewers entry",
+		},
+		{
+			name: "invalid maintainer",
+			config: &LibrarianConfig{
+				Libraries: []*LibraryConfig{
+					{LibraryID: "example-library", Maintainers: []string{"googleapis/team/extra"}},
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid maintainers entry for library",
+		},
+		{
+			name: "negative min release interval",
+			config: &LibrarianConfig{
+				Libraries: []*LibraryConfig{
+					{LibraryID: "example-library", MinReleaseInterval: -time.Hour},
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid min_release_interval",
+		},
+		{
+			name: "build file template without extension",
+			config: &LibrarianConfig{
+				Libraries: []*LibraryConfig{
+					{LibraryID: "example-library", BuildFileTemplate: "templates/BUILD.bazel"},
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid build_file_template",
+		},
+		{
+			name: "valid max libraries per pr",
+			config: &LibrarianConfig{
+				MaxLibrariesPerPR: 10,
+			},
+		},
+		{
+			name: "negative max libraries per pr",
+			config: &LibrarianConfig{
+				MaxLibrariesPerPR: -1,
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid max_libraries_per_pr",
+		},
+		{
+			name: "valid version bumps",
+			config: &LibrarianConfig{
+				VersionBumps: map[string]string{"perf": "patch", "revert": "patch", "feat": "major", "fix": "none"},
+			},
+		},
+		{
+			name: "invalid version bump",
+			config: &LibrarianConfig{
+				VersionBumps: map[string]string{"perf": "small"},
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid version_bumps entry for commit type "perf"`,
+		},
+		{
+			name: "empty version bump commit type",
+			config: &LibrarianConfig{
+				VersionBumps: map[string]string{"": "patch"},
+			},
+			wantErr:    true,
+			wantErrMsg: "commit type cannot be empty",
+		},
+		{
+			name: "invalid library id pattern",
+			config: &LibrarianConfig{
+				LibraryIDPattern: "google.cloud.(",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid library_id_pattern",
+		},
+		{
+			name: "valid min librarian version",
+			config: &LibrarianConfig{
+				MinLibrarianVersion: "0.2.0",
+			},
+		},
+		{
+			name: "invalid min librarian version",
+			config: &LibrarianConfig{
+				MinLibrarianVersion: "latest",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid min_librarian_version",
+		},
+		{
+			name: "valid post release",
+			config: &LibrarianConfig{
+				PostRelease: &PostReleaseConfig{
+					VersionsFile:   "versions.txt",
+					VersionsFormat: "{id}:{version}:{version}",
+				},
+			},
+		},
+		{
+			name: "invalid post release versions file",
+			config: &LibrarianConfig{
+				PostRelease: &PostReleaseConfig{
+					VersionsFile: "/versions.txt",
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid post_release versions_file",
+		},
+		{
+			name: "post release versions format without version",
+			config: &LibrarianConfig{
+				PostRelease: &PostReleaseConfig{
+					VersionsFile:   "versions.txt",
+					VersionsFormat: "{id}",
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid post_release versions_format",
+		},
+		{
+			name: "valid template tag format",
+			config: &LibrarianConfig{
+				TagFormat: "{{.ID}}/v{{.Version}}",
+				Libraries: []*LibraryConfig{
+					{
+						LibraryID: "example-library",
+						TagFormat: "v{version}",
+					},
+				},
+			},
+		},
+		{
+			name: "valid url templates",
+			config: &LibrarianConfig{
+				CommitURLTemplate: "https://git.example.com/repo/+/{sha}",
+				CLURLTemplate:     "https://cl.example.com/{cl}",
+			},
+		},
+		{
+			name: "commit url template without hash",
+			config: &LibrarianConfig{
+				CommitURLTemplate: "https://git.example.com/repo/+/",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid commit_url_template",
+		},
+		{
+			name: "cl url template without cl",
+			config: &LibrarianConfig{
+				CLURLTemplate: "https://cl.example.com/",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid cl_url_template",
+		},
+		{
+			name: "valid api renames",
+			config: &LibrarianConfig{
+				APIRenames: map[string]string{"google/cloud/x/v1": "google/cloud/x/v2"},
+			},
+		},
+		{
+			name: "valid service configs",
+			config: &LibrarianConfig{
+				Libraries: []*LibraryConfig{
+					{
+						LibraryID:      "example-library",
+						ServiceConfigs: map[string]string{"google/cloud/x/v1": "x_*.yaml"},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid service configs pattern",
+			config: &LibrarianConfig{
+				Libraries: []*LibraryConfig{
+					{
+						LibraryID:      "example-library",
+						ServiceConfigs: map[string]string{"google/cloud/x/v1": "x_[.yaml"},
+					},
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid service_configs entry",
+		},
+		{
+			name: "service configs pattern with directory",
+			config: &LibrarianConfig{
+				Libraries: []*LibraryConfig{
+					{
+						LibraryID:      "example-library",
+						ServiceConfigs: map[string]string{"google/cloud/x/v1": "../x.yaml"},
+					},
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid service_configs entry",
+		},
+		{
+			name: "api rename to the same path",
+			config: &LibrarianConfig{
+				APIRenames: map[string]string{"google/cloud/x/v1": "google/cloud/x/v1"},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid api_renames entry",
+		},
+		{
+			name: "api rename outside of the api source",
+			config: &LibrarianConfig{
+				APIRenames: map[string]string{"google/cloud/x/v1": "../x/v2"},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid api_renames entry",
+		},
+		{
+			name: "invalid tag format",
+			config: &LibrarianConfig{
+				TagFormat: "{id}",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid tag_format: must contain {version}",
+		},
+		{
+			name: "invalid library tag format",
+			config: &LibrarianConfig{
+				Libraries: []*LibraryConfig{
+					{
+						LibraryID: "example-library",
+						TagFormat: "{id}-{version}-{foo}",
+					},
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: `invalid tag_format for library "example-library"`,
+		},
+		{
+			name: "valid exclude commit authors",
+			config: &LibrarianConfig{
+				ExcludeCommitAuthors: []string{`^dependabot\[bot\]$`},
+			},
+		},
+		{
+			name: "invalid exclude commit authors",
+			config: &LibrarianConfig{
+				ExcludeCommitAuthors: []string{"["},
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid exclude_commit_authors pattern",
+		},
 		{
 			name: "invalid permission in config",
 			config: &LibrarianConfig{
@@ -103,6 +422,76 @@ func TestGlobalConfig_Validate(t *testing.T) {
 	}
 }
 
+func TestCheckLibrarianVersion(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		config     *LibrarianConfig
+		version    string
+		wantErr    bool
+		wantErrMsg string
+	}{
+		{
+			name:    "nil config",
+			version: "0.1.0",
+		},
+		{
+			name:    "no minimum configured",
+			config:  &LibrarianConfig{},
+			version: "0.1.0",
+		},
+		{
+			name:       "older version",
+			config:     &LibrarianConfig{MinLibrarianVersion: "0.2.0"},
+			version:    "0.1.9",
+			wantErr:    true,
+			wantErrMsg: "older than the minimum version 0.2.0",
+		},
+		{
+			name:    "equal version",
+			config:  &LibrarianConfig{MinLibrarianVersion: "0.2.0"},
+			version: "0.2.0",
+		},
+		{
+			name:    "newer version",
+			config:  &LibrarianConfig{MinLibrarianVersion: "0.2.0"},
+			version: "v0.3.0",
+		},
+		{
+			name:    "pseudo-version of the minimum",
+			config:  &LibrarianConfig{MinLibrarianVersion: "0.2.0"},
+			version: "0.2.0-123456789abc-20250101000000",
+		},
+		{
+			name:       "pseudo-version older than the minimum",
+			config:     &LibrarianConfig{MinLibrarianVersion: "0.2.0"},
+			version:    "0.1.0-123456789abc-20250101000000",
+			wantErr:    true,
+			wantErrMsg: "please upgrade librarian",
+		},
+		{
+			name:    "unknown version",
+			config:  &LibrarianConfig{MinLibrarianVersion: "0.2.0"},
+			version: "not available",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.config.CheckLibrarianVersion(test.version)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("CheckLibrarianVersion() should return error")
+				}
+				if !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Errorf("CheckLibrarianVersion() err = %v, want error containing %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("CheckLibrarianVersion() error = %v", err)
+			}
+		})
+	}
+}
+
 func TestLibraryConfigFor(t *testing.T) {
 	cases := []struct {
 		name          string
@@ -271,3 +660,223 @@ func TestIsGenerationBlocked(t *testing.T) {
 		})
 	}
 }
+
+func TestIsReleaseFrozen(t *testing.T) {
+	now := time.Date(2025, 12, 20, 12, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		name   string
+		config *LibrarianConfig
+		want   bool
+	}{
+		{
+			name: "nil config",
+		},
+		{
+			name:   "no freeze",
+			config: &LibrarianConfig{},
+		},
+		{
+			name:   "before the end of the freeze",
+			config: &LibrarianConfig{FreezeUntil: time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)},
+			want:   true,
+		},
+		{
+			name:   "after the end of the freeze",
+			config: &LibrarianConfig{FreezeUntil: time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.config.IsReleaseFrozen(now); got != test.want {
+				t.Errorf("IsReleaseFrozen() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestIsFrozen(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		config    *LibrarianConfig
+		libraryID string
+		want      bool
+	}{
+		{
+			name:      "nil config",
+			libraryID: "lib1",
+		},
+		{
+			name: "library not in config",
+			config: &LibrarianConfig{
+				Libraries: []*LibraryConfig{
+					{LibraryID: "lib2", Frozen: true},
+				},
+			},
+			libraryID: "lib1",
+		},
+		{
+			name: "library in config, frozen is true",
+			config: &LibrarianConfig{
+				Libraries: []*LibraryConfig{
+					{LibraryID: "lib1", Frozen: true},
+				},
+			},
+			libraryID: "lib1",
+			want:      true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.config.IsFrozen(test.libraryID); got != test.want {
+				t.Errorf("IsFrozen() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckMaintainers(t *testing.T) {
+	state := &LibrarianState{
+		Libraries: []*LibraryState{
+			{ID: "lib1"},
+			{ID: "lib2"},
+			{ID: "lib3"},
+		},
+	}
+	for _, test := range []struct {
+		name       string
+		config     *LibrarianConfig
+		wantErrMsg string
+	}{
+		{
+			name: "nil config",
+		},
+		{
+			name: "maintainers not required",
+			config: &LibrarianConfig{
+				Libraries: []*LibraryConfig{
+					{LibraryID: "lib1", Maintainers: []string{"octocat"}},
+				},
+			},
+		},
+		{
+			name: "all libraries with maintainers",
+			config: &LibrarianConfig{
+				RequireMaintainers: true,
+				Libraries: []*LibraryConfig{
+					{LibraryID: "lib1", Maintainers: []string{"octocat"}},
+					{LibraryID: "lib2", Maintainers: []string{"googleapis/yoshi-go"}},
+					{LibraryID: "lib3", Maintainers: []string{"@octocat"}},
+				},
+			},
+		},
+		{
+			name: "libraries without maintainers",
+			config: &LibrarianConfig{
+				RequireMaintainers: true,
+				Libraries: []*LibraryConfig{
+					{LibraryID: "lib1", Maintainers: []string{"octocat"}},
+					{LibraryID: "lib2"},
+				},
+			},
+			wantErrMsg: "maintainers are required by require_maintainers but not configured for libraries: lib2, lib3",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.config.CheckMaintainers(state)
+			if test.wantErrMsg == "" {
+				if err != nil {
+					t.Errorf("CheckMaintainers() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != test.wantErrMsg {
+				t.Errorf("CheckMaintainers() error = %v, want %q", err, test.wantErrMsg)
+			}
+		})
+	}
+}
+
+func TestReleasesOnDepsOnly(t *testing.T) {
+	yes, no := true, false
+	for _, test := range []struct {
+		name   string
+		config *LibrarianConfig
+		want   bool
+	}{
+		{
+			name: "nil config",
+			want: true,
+		},
+		{
+			name:   "not set",
+			config: &LibrarianConfig{},
+			want:   true,
+		},
+		{
+			name:   "set to true",
+			config: &LibrarianConfig{ReleaseOnDepsOnly: &yes},
+			want:   true,
+		},
+		{
+			name:   "set to false",
+			config: &LibrarianConfig{ReleaseOnDepsOnly: &no},
+			want:   false,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.config.ReleasesOnDepsOnly(); got != test.want {
+				t.Errorf("ReleasesOnDepsOnly() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPullRequestReviewers(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		config     *LibrarianConfig
+		libraryIDs []string
+		want       []string
+	}{
+		{
+			name:       "nil config",
+			libraryIDs: []string{"lib1"},
+		},
+		{
+			name: "default reviewers only",
+			config: &LibrarianConfig{
+				DefaultReviewers: []string{"googleapis/yoshi-go"},
+			},
+			libraryIDs: []string{"lib1"},
+			want:       []string{"googleapis/yoshi-go"},
+		},
+		{
+			name: "default reviewers alongside maintainers",
+			config: &LibrarianConfig{
+				DefaultReviewers: []string{"googleapis/yoshi-go", "octocat"},
+				Libraries: []*LibraryConfig{
+					{LibraryID: "lib1", Maintainers: []string{"hubot", "@OctoCat"}},
+					{LibraryID: "lib2", Maintainers: []string{"@googleapis/yoshi-go", "monalisa"}},
+					{LibraryID: "lib3", Maintainers: []string{"unchanged-maintainer"}},
+				},
+			},
+			libraryIDs: []string{"lib1", "lib2"},
+			want:       []string{"googleapis/yoshi-go", "octocat", "hubot", "monalisa"},
+		},
+		{
+			name: "maintainers only",
+			config: &LibrarianConfig{
+				Libraries: []*LibraryConfig{
+					{LibraryID: "lib1", Maintainers: []string{"hubot"}},
+				},
+			},
+			libraryIDs: []string{"lib1", "unknown"},
+			want:       []string{"hubot"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.config.PullRequestReviewers(test.libraryIDs)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("PullRequestReviewers() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -21,15 +21,31 @@ import (
 	"strings"
 
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacygitrepo"
+	"gopkg.in/yaml.v3"
 )
 
 const (
 	StatusNew      = "new"
 	StatusExisting = "existing"
+	// StatusRemoved marks an API removed from its library. The generate
+	// command removes the outputs of the API, and then drops the API from the
+	// library.
+	StatusRemoved = "removed"
 	// BulkChangeThreshold is a threshold to determine whether a commit is a bulk change.
 	BulkChangeThreshold = 10
+	// CompositeCommitSeparator separates the commits of the API sources in
+	// the last_generated_commit of a library generated from several API
+	// sources, e.g. "<base commit>+<overlay commit>".
+	CompositeCommitSeparator = "+"
 )
 
+// SplitCompositeCommit returns the commits recorded in a last generated
+// commit, one per API source, the commit of the base source first. A plain
+// commit hash is returned as the only element.
+func SplitCompositeCommit(commit string) []string {
+	return strings.Split(commit, CompositeCommitSeparator)
+}
+
 // LibrarianState defines the contract for the state.yaml file.
 type LibrarianState struct {
 	// The name and tag of the generator image to use. tag is required.
@@ -112,10 +128,25 @@ type LibraryState struct {
 	// The last released version of the library, following SemVer.
 	Version string `yaml:"version" json:"version"`
 	// The commit hash from the API definition repository at which the library was last generated.
+	// For a library generated from several API sources, the commit hashes of
+	// all sources joined by CompositeCommitSeparator, the base source first.
 	LastGeneratedCommit string `yaml:"last_generated_commit" json:"-"`
+	// The commit hash from the API definition repository at which the library
+	// is generated, instead of the HEAD commit. This pins the library to an
+	// older version of its APIs while other libraries track HEAD.
+	PinnedCommit string `yaml:"pinned_commit,omitempty" json:"-"`
 	// The changes from the language repository since the library was last released.
 	// This field is ignored when writing to state.yaml.
 	Changes []*Commit `yaml:"-" json:"changes,omitempty"`
+	// The number of earlier commits since the library was last released which
+	// are not part of Changes, because of the cap on the number of commits
+	// scanned by the release stage command.
+	// This field is ignored when writing to state.yaml.
+	EarlierChanges int `yaml:"-" json:"earlier_changes,omitempty"`
+	// The language of the library, e.g., rust. It is only needed when the
+	// state file holds libraries of several languages, to process the
+	// libraries of one language at a time.
+	Language string `yaml:"language,omitempty" json:"language,omitempty"`
 	// A list of APIs that are part of this library.
 	APIs []*API `yaml:"apis" json:"apis"`
 	// A list of directories in the language repository where Librarian contributes code.
@@ -142,6 +173,16 @@ type LibraryState struct {
 	// An error message from the docker response.
 	// This field is ignored when writing to state.yaml.
 	ErrorMessage string `yaml:"-" json:"error,omitempty"`
+	// Whether the library is deprecated. Release notes of a deprecated library
+	// start with a deprecation notice.
+	Deprecated bool `yaml:"deprecated,omitempty" json:"deprecated,omitempty"`
+	// An optional message added to the deprecation notice of a deprecated
+	// library, e.g., to point users to a replacement.
+	DeprecationMessage string `yaml:"deprecation_message,omitempty" json:"deprecation_message,omitempty"`
+	// The path of the changelog file to update when releasing this library,
+	// populated from the library's `changelog_path` in config.yaml.
+	// This field is ignored when writing to state.yaml.
+	ChangelogPath string `yaml:"-" json:"changelog_path,omitempty"`
 }
 
 // Commit represents a single commit in the release notes.
@@ -158,6 +199,11 @@ type Commit struct {
 	PiperCLNumber string `json:"piper_cl_number,omitempty"`
 	// A list of library IDs associated with the commit.
 	LibraryIDs string `json:"-"`
+	// FixesVersion is the prior version a fix applies to, populated from the
+	// `Fixes-Version` footer of the commit. It is used to track backports.
+	FixesVersion string `json:"fixes_version,omitempty"`
+	// IsBreaking indicates that the commit introduces a breaking change.
+	IsBreaking bool `json:"is_breaking,omitempty"`
 }
 
 // IsBulkCommit returns true if the commit is associated with 10 or more
@@ -188,11 +234,21 @@ func (l *LibraryState) Validate() error {
 		return fmt.Errorf("invalid version: %q", l.Version)
 	}
 	if l.LastGeneratedCommit != "" {
-		if !hexRegex.MatchString(l.LastGeneratedCommit) {
-			return fmt.Errorf("last_generated_commit must be a hex string")
+		for _, commit := range SplitCompositeCommit(l.LastGeneratedCommit) {
+			if !hexRegex.MatchString(commit) {
+				return fmt.Errorf("last_generated_commit must be a hex string")
+			}
+			if len(commit) != 40 {
+				return fmt.Errorf("last_generated_commit must be 40 characters")
+			}
 		}
-		if len(l.LastGeneratedCommit) != 40 {
-			return fmt.Errorf("last_generated_commit must be 40 characters")
+	}
+	if l.PinnedCommit != "" {
+		if !hexRegex.MatchString(l.PinnedCommit) {
+			return fmt.Errorf("pinned_commit must be a hex string")
+		}
+		if len(l.PinnedCommit) != 40 {
+			return fmt.Errorf("pinned_commit must be 40 characters")
 		}
 	}
 	for i, a := range l.APIs {
@@ -214,14 +270,8 @@ func (l *LibraryState) Validate() error {
 		}
 	}
 	if l.TagFormat != "" {
-		if !strings.Contains(l.TagFormat, "{version}") {
-			return fmt.Errorf("invalid tag_format: must contain {version}")
-		}
-		matches := tagFormatRegex.FindAllString(l.TagFormat, -1)
-		for _, match := range matches {
-			if match != "{id}" && match != "{version}" {
-				return fmt.Errorf("invalid tag_format: placeholder %s not recognized", match)
-			}
+		if err := ValidateTagFormat(l.TagFormat); err != nil {
+			return fmt.Errorf("invalid tag_format: %w", err)
 		}
 	}
 	for i, r := range l.PreserveRegex {
@@ -243,16 +293,54 @@ type API struct {
 	Path string `yaml:"path" json:"path"`
 	// The name of the service config file, relative to the API `path`.
 	ServiceConfig string `yaml:"service_config" json:"service_config"`
-	// The status of the API, one of "new" or "existing".
-	// This field is ignored when writing to state.yaml.
+	// The status of the API, one of "new", "existing" or "removed".
+	// Only the "removed" status is read from and written to state.yaml, the
+	// other statuses are determined by the generate command.
 	Status string `yaml:"-" json:"status,omitempty"`
 }
 
+// apiYAML is the representation of an API in state.yaml.
+type apiYAML struct {
+	Path          string `yaml:"path"`
+	ServiceConfig string `yaml:"service_config"`
+	Status        string `yaml:"status,omitempty"`
+}
+
+// MarshalYAML implements [yaml.Marshaler]. The status of the API is only
+// written if it is "removed".
+func (a API) MarshalYAML() (any, error) {
+	out := apiYAML{
+		Path:          a.Path,
+		ServiceConfig: a.ServiceConfig,
+	}
+	if a.Status == StatusRemoved {
+		out.Status = a.Status
+	}
+	return out, nil
+}
+
+// UnmarshalYAML implements [yaml.Unmarshaler].
+func (a *API) UnmarshalYAML(value *yaml.Node) error {
+	var in apiYAML
+	if err := value.Decode(&in); err != nil {
+		return err
+	}
+	a.Path = in.Path
+	a.ServiceConfig = in.ServiceConfig
+	a.Status = in.Status
+	return nil
+}
+
 // Validate checks that the API is valid.
 func (a *API) Validate() error {
 	if !isValidRelativePath(a.Path) {
 		return fmt.Errorf("invalid path: %q", a.Path)
 	}
+	switch a.Status {
+	case "", StatusNew, StatusExisting, StatusRemoved:
+	default:
+		return fmt.Errorf("invalid status: %q", a.Status)
+	}
 	return nil
 }
 
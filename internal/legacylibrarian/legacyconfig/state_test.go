@@ -19,6 +19,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v3"
 )
 
 func TestLibrarianState_Validate(t *testing.T) {
@@ -211,6 +212,46 @@ func TestLibrary_Validate(t *testing.T) {
 			wantErr:    true,
 			wantErrMsg: "last_generated_commit must be 40 characters",
 		},
+		{
+			name: "valid composite last generated commit",
+			library: &LibraryState{
+				ID:                  "a/b",
+				LastGeneratedCommit: strings.Repeat("a", 40) + "+" + strings.Repeat("b", 40),
+				SourceRoots:         []string{"src/a"},
+				APIs: []*API{
+					{
+						Path: "a/b/v1",
+					},
+				},
+			},
+		},
+		{
+			name: "invalid composite last generated commit",
+			library: &LibraryState{
+				ID:                  "a/b",
+				LastGeneratedCommit: strings.Repeat("a", 40) + "+deadbeef",
+			},
+			wantErr:    true,
+			wantErrMsg: "last_generated_commit must be 40 characters",
+		},
+		{
+			name: "invalid pinned commit non-hex",
+			library: &LibraryState{
+				ID:           "a/b",
+				PinnedCommit: "not-a-hex-string",
+			},
+			wantErr:    true,
+			wantErrMsg: "pinned_commit must be a hex string",
+		},
+		{
+			name: "invalid pinned commit wrong length",
+			library: &LibraryState{
+				ID:           "a/b",
+				PinnedCommit: "deadbeef",
+			},
+			wantErr:    true,
+			wantErrMsg: "pinned_commit must be 40 characters",
+		},
 		{
 			name: "valid preserve_regex",
 			library: &LibraryState{
@@ -352,12 +393,28 @@ func TestAPI_Validate(t *testing.T) {
 				Path: "a/b/v1",
 			},
 		},
+		{
+			name: "removed api",
+			api: &API{
+				Path:   "a/b/v1",
+				Status: StatusRemoved,
+			},
+		},
 		{
 			name:       "missing path",
 			api:        &API{},
 			wantErr:    true,
 			wantErrMsg: "invalid path",
 		},
+		{
+			name: "invalid status",
+			api: &API{
+				Path:   "a/b/v1",
+				Status: "deleted",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid status",
+		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			err := test.api.Validate()
@@ -379,6 +436,46 @@ func TestAPI_Validate(t *testing.T) {
 	}
 }
 
+func TestAPI_YAML(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		api  *API
+		want string
+	}{
+		{
+			name: "removed api",
+			api:  &API{Path: "a/b/v1", ServiceConfig: "b_v1.yaml", Status: StatusRemoved},
+			want: "path: a/b/v1\nservice_config: b_v1.yaml\nstatus: removed\n",
+		},
+		{
+			name: "existing api",
+			api:  &API{Path: "a/b/v1", ServiceConfig: "b_v1.yaml", Status: StatusExisting},
+			want: "path: a/b/v1\nservice_config: b_v1.yaml\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := yaml.Marshal(test.api)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, string(data)); diff != "" {
+				t.Errorf("yaml.Marshal() mismatch (-want +got):\n%s", diff)
+			}
+			got := &API{}
+			if err := yaml.Unmarshal(data, got); err != nil {
+				t.Fatal(err)
+			}
+			want := *test.api
+			if want.Status != StatusRemoved {
+				want.Status = ""
+			}
+			if diff := cmp.Diff(&want, got); diff != "" {
+				t.Errorf("yaml.Unmarshal() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestIsValidDirPath(t *testing.T) {
 	for _, test := range []struct {
 		name string
@@ -15,12 +15,23 @@
 package legacyconfig
 
 import (
+	"fmt"
 	"log/slog"
 	"strings"
 )
 
 const defaultTagFormat = "{id}-{version}"
 
+// templatePlaceholders replaces the placeholders of tag formats written in Go
+// template style, e.g. "{{.ID}}/v{{.Version}}", with their {id} and {version}
+// equivalents.
+var templatePlaceholders = strings.NewReplacer(
+	"{{.ID}}", "{id}",
+	"{{ .ID }}", "{id}",
+	"{{.Version}}", "{version}",
+	"{{ .Version }}", "{version}",
+)
+
 // DetermineTagFormat finds the tag_format config given a library ID.
 func DetermineTagFormat(libraryID string, libraryState *LibraryState, librarianConfig *LibrarianConfig) string {
 	// Order of preference:
@@ -55,5 +66,21 @@ func FormatTag(tagFormat string, libraryID string, version string) string {
 		tagFormat = defaultTagFormat
 	}
 	r := strings.NewReplacer("{id}", libraryID, "{version}", version)
-	return r.Replace(tagFormat)
+	return r.Replace(templatePlaceholders.Replace(tagFormat))
+}
+
+// ValidateTagFormat checks that tagFormat contains {version}, and no
+// placeholder other than {id} and {version}. The placeholders may also be
+// written in Go template style, as {{.ID}} and {{.Version}}.
+func ValidateTagFormat(tagFormat string) error {
+	tagFormat = templatePlaceholders.Replace(tagFormat)
+	if !strings.Contains(tagFormat, "{version}") {
+		return fmt.Errorf("must contain {version}")
+	}
+	for _, match := range tagFormatRegex.FindAllString(tagFormat, -1) {
+		if match != "{id}" && match != "{version}" {
+			return fmt.Errorf("placeholder %s not recognized", match)
+		}
+	}
+	return nil
 }
@@ -15,6 +15,7 @@
 package legacyconfig
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -122,6 +123,24 @@ func TestFormatTag(t *testing.T) {
 			},
 			want: "v1.2.3",
 		},
+		{
+			name: "monorepo_format",
+			library: &LibraryState{
+				ID:        "google.cloud.foo.v1",
+				Version:   "1.2.3",
+				TagFormat: "{id}/v{version}",
+			},
+			want: "google.cloud.foo.v1/v1.2.3",
+		},
+		{
+			name: "template_format",
+			library: &LibraryState{
+				ID:        "google.cloud.foo.v1",
+				Version:   "1.2.3",
+				TagFormat: "{{.ID}}/v{{ .Version }}",
+			},
+			want: "google.cloud.foo.v1/v1.2.3",
+		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			got := FormatTag(test.library.TagFormat, test.library.ID, test.library.Version)
@@ -131,3 +150,53 @@ func TestFormatTag(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateTagFormat(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name       string
+		tagFormat  string
+		wantErrMsg string
+	}{
+		{
+			name:      "placeholders",
+			tagFormat: "{id}/v{version}",
+		},
+		{
+			name:      "template placeholders",
+			tagFormat: "{{.ID}}/v{{.Version}}",
+		},
+		{
+			name:      "version only",
+			tagFormat: "v{{ .Version }}",
+		},
+		{
+			name:       "missing version",
+			tagFormat:  "{{.ID}}",
+			wantErrMsg: "must contain {version}",
+		},
+		{
+			name:       "unknown placeholder",
+			tagFormat:  "{version}-{foo}",
+			wantErrMsg: "placeholder {foo} not recognized",
+		},
+		{
+			name:       "unknown template field",
+			tagFormat:  "{{.Name}}-{{.Version}}",
+			wantErrMsg: "placeholder {.Name} not recognized",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateTagFormat(test.tagFormat)
+			if test.wantErrMsg == "" {
+				if err != nil {
+					t.Errorf("ValidateTagFormat() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+				t.Errorf("ValidateTagFormat() error = %v, want contains %q", err, test.wantErrMsg)
+			}
+		})
+	}
+}
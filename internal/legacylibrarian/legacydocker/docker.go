@@ -19,14 +19,22 @@
 package legacydocker
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
 )
@@ -45,6 +53,10 @@ const (
 	CommandGenerate Command = "generate"
 	// CommandReleaseStage performs release for a library.
 	CommandReleaseStage Command = "release-stage"
+	// CommandServe starts a long-lived container handling generate requests
+	// until it is stopped. It is only run for images with the ServeLabel
+	// label.
+	CommandServe Command = "serve"
 )
 
 // Docker contains all the information required to run language-specific
@@ -59,12 +71,63 @@ type Docker struct {
 	// The group ID to run the container as.
 	gid string
 
+	// The maximum amount of memory the container may use, e.g. "4g".
+	memory string
+
+	// The number of CPUs the container may use, e.g. "1.5".
+	cpus string
+
+	// The commands whose containers are run without network access.
+	noNetwork []Command
+
+	// Extra volumes mounted into the containers of the build, configure and
+	// generate commands, in the format "{host-dir}:{container-dir}[:ro]".
+	mounts []string
+
 	// HostMount specifies a mount point from the Docker host into the Docker
 	// container. The format is "{host-dir}:{local-dir}".
 	HostMount string
 
-	// run runs the docker command.
-	run func(args ...string) error
+	// The directory the output of containers is written to, one file per
+	// library. If empty, the output is only written to the console.
+	logDir string
+
+	// Whether the output of containers is only written to the console when
+	// they fail, instead of as it arrives.
+	quiet bool
+
+	// The maximum duration of a container run. If zero, containers may run
+	// forever.
+	timeout time.Duration
+
+	// The number of containers named so far, to give them unique names.
+	containers atomic.Int64
+
+	// run runs the docker command until it completes or ctx is done, writing
+	// each line of its output to the console prefixed with prefix, and also
+	// to the file at logPath unless it is empty.
+	run func(ctx context.Context, prefix, logPath string, args ...string) error
+
+	// output runs the docker command and returns its standard output.
+	output func(args ...string) ([]byte, error)
+
+	// The directory holding the files of the command, mounted in the
+	// containers serving requests.
+	workRoot string
+
+	// Whether generate requests are sent to a long-lived container, for
+	// images supporting the serve command.
+	reuseContainers bool
+
+	// How often the response of a container serving requests is checked for,
+	// and how often the container is checked to still be running.
+	servePollInterval     time.Duration
+	serveLivenessInterval time.Duration
+
+	// The containers serving requests, by image. A nil server records that
+	// the image does not support the serve command.
+	serversMu sync.Mutex
+	servers   map[string]*server
 }
 
 // BuildRequest contains all the information required for a language
@@ -73,6 +136,11 @@ type BuildRequest struct {
 	// LibraryID specifies the ID of the library to build.
 	LibraryID string
 
+	// LibrarianDir is the directory mounted as /librarian, holding the request
+	// and response files. If not specified, the .librarian directory of the
+	// language repository is used.
+	LibrarianDir string
+
 	// RepoDir is the local root directory of the language repository.
 	RepoDir string
 
@@ -91,6 +159,11 @@ type ConfigureRequest struct {
 	// ApiRoot specifies the root directory of the API specification repo.
 	ApiRoot string
 
+	// GeneratorInputDir is the directory mounted as /input, holding the
+	// generator input. If not specified, the .librarian/generator-input
+	// directory of the language repository is used.
+	GeneratorInputDir string
+
 	// libraryID specifies the ID of the library to configure.
 	LibraryID string
 
@@ -107,6 +180,11 @@ type ConfigureRequest struct {
 	// GlobalFiles are global files of the language repository.
 	GlobalFiles []string
 
+	// ConfigureMounts are directories of the language repository, relative
+	// to its root, mounted read-only in the container. They must be within
+	// the repository.
+	ConfigureMounts []string
+
 	// State is a pointer to the [legacyconfig.LibrarianState] struct, representing
 	// the overall state of the generation and release pipeline.
 	State *legacyconfig.LibrarianState
@@ -122,9 +200,19 @@ type GenerateRequest struct {
 	// ApiRoot specifies the root directory of the API specification repo.
 	ApiRoot string
 
+	// GeneratorInputDir is the directory mounted as /input, holding the
+	// generator input. If not specified, the .librarian/generator-input
+	// directory of the language repository is used.
+	GeneratorInputDir string
+
 	// LibraryID specifies the ID of the library to generate.
 	LibraryID string
 
+	// LibrarianDir is the directory mounted as /librarian, holding the request
+	// and response files. If not specified, the .librarian directory of the
+	// language repository is used.
+	LibrarianDir string
+
 	// Output specifies the empty output directory into which the command should
 	// generate code
 	Output string
@@ -198,20 +286,77 @@ type DockerOptions struct {
 	// It specifies a mount point from the Docker host into the Docker container.
 	// The format is "{host-dir}:{local-dir}".
 	HostMount string
+	// Memory is the maximum amount of memory containers may use, passed to
+	// docker run as --memory. If empty, memory is not limited.
+	Memory string
+	// CPUs is the number of CPUs containers may use, passed to docker run as
+	// --cpus. If empty, the number of CPUs is not limited.
+	CPUs string
+	// NoNetwork lists the commands whose containers are run without network
+	// access, passing --network=none to docker run, so that they cannot fetch
+	// anything at runtime. Other containers use the default network.
+	NoNetwork []Command
+	// Mounts lists extra volumes mounted into the containers running the
+	// build, configure and generate commands, e.g. a module cache, in the
+	// format "{host-dir}:{container-dir}[:ro]". The host directory must exist,
+	// and the container directory must be absolute. A ":ro" suffix mounts the
+	// volume read-only.
+	Mounts []string
+	// LogDir is the directory the output of containers is written to as it
+	// arrives, in a file per library named after the library ID, e.g.
+	// "google_cloud_foo.log". If empty, the output is only written to the
+	// console.
+	LogDir string
+	// Quiet only writes the output of containers to the console when they
+	// fail. Otherwise, it is written as it arrives, each line prefixed with the
+	// library ID and command, e.g. "[secretmanager generate]".
+	Quiet bool
+	// ReuseContainers sends the generate requests to a single long-lived
+	// container per image, started with the serve command, instead of
+	// running a container per request. It only applies to images with the
+	// ServeLabel label. Close must be called to stop the container.
+	ReuseContainers bool
+	// Timeout is the maximum duration of a container run, after which the
+	// container is killed and the command fails. If zero, containers may run
+	// forever.
+	Timeout time.Duration
 }
 
 // New constructs a Docker instance which will invoke the specified
 // Docker image as required to implement language-specific commands,
 // providing the container with required environment variables.
 func New(workRoot, image string, options *DockerOptions) (*Docker, error) {
+	var mounts []string
+	for _, mount := range options.Mounts {
+		resolved, err := resolveMount(mount)
+		if err != nil {
+			return nil, err
+		}
+		mounts = append(mounts, resolved)
+	}
 	docker := &Docker{
 		Image:     image,
 		uid:       options.UserUID,
 		gid:       options.UserGID,
+		memory:    options.Memory,
+		cpus:      options.CPUs,
+		noNetwork: options.NoNetwork,
+		mounts:    mounts,
 		HostMount: options.HostMount,
+		logDir:    options.LogDir,
+		quiet:     options.Quiet,
+		timeout:   options.Timeout,
+
+		workRoot:              workRoot,
+		reuseContainers:       options.ReuseContainers,
+		servePollInterval:     defaultServePollInterval,
+		serveLivenessInterval: defaultServeLivenessInterval,
 	}
-	docker.run = func(args ...string) error {
-		return docker.runCommand("docker", args...)
+	docker.run = func(ctx context.Context, prefix, logPath string, args ...string) error {
+		return docker.runCommand(ctx, prefix, logPath, "docker", args...)
+	}
+	docker.output = func(args ...string) ([]byte, error) {
+		return exec.Command("docker", args...).Output()
 	}
 	return docker, nil
 }
@@ -219,7 +364,8 @@ func New(workRoot, image string, options *DockerOptions) (*Docker, error) {
 // Generate performs generation for an API which is configured as part of a
 // library.
 func (c *Docker) Generate(ctx context.Context, request *GenerateRequest) error {
-	reqFilePath := filepath.Join(request.RepoDir, legacyconfig.LibrarianDir, legacyconfig.GenerateRequest)
+	librarianDir := resolveLibrarianDir(request.LibrarianDir, request.RepoDir)
+	reqFilePath := filepath.Join(librarianDir, legacyconfig.GenerateRequest)
 	if err := writeLibraryState(request.State, request.LibraryID, reqFilePath); err != nil {
 		return err
 	}
@@ -240,8 +386,7 @@ func (c *Docker) Generate(ctx context.Context, request *GenerateRequest) error {
 		"--source=/source",
 	}
 
-	generatorInput := filepath.Join(request.RepoDir, legacyconfig.GeneratorInputDir)
-	librarianDir := filepath.Join(request.RepoDir, legacyconfig.LibrarianDir)
+	generatorInput := resolveGeneratorInputDir(request.GeneratorInputDir, request.RepoDir)
 	mounts := []string{
 		fmt.Sprintf("%s:/librarian", librarianDir),
 		fmt.Sprintf("%s:/input", generatorInput),
@@ -250,13 +395,31 @@ func (c *Docker) Generate(ctx context.Context, request *GenerateRequest) error {
 	}
 
 	image := c.resolveImage(request.Image)
-	return c.runDocker(ctx, image, CommandGenerate, mounts, commandArgs)
+	if c.reuseContainers {
+		s, err := c.generateServer(ctx, image, []string{librarianDir, generatorInput, request.Output}, []string{request.ApiRoot})
+		if err != nil {
+			return err
+		}
+		if s != nil {
+			// The directories are mounted at the same paths in the
+			// container.
+			serveArgs := []string{
+				"--librarian=" + librarianDir,
+				"--input=" + generatorInput,
+				"--output=" + request.Output,
+				"--source=" + request.ApiRoot,
+			}
+			return c.serve(ctx, s, CommandGenerate, request.LibraryID, serveArgs)
+		}
+	}
+	return c.runDocker(ctx, image, CommandGenerate, request.LibraryID, mounts, commandArgs)
 }
 
 // Build builds the library with an ID of libraryID, as configured in
 // the Librarian state file for the repository with a root of repoRoot.
 func (c *Docker) Build(ctx context.Context, request *BuildRequest) error {
-	reqFilePath := filepath.Join(request.RepoDir, legacyconfig.LibrarianDir, legacyconfig.BuildRequest)
+	librarianDir := resolveLibrarianDir(request.LibrarianDir, request.RepoDir)
+	reqFilePath := filepath.Join(librarianDir, legacyconfig.BuildRequest)
 	if err := writeLibraryState(request.State, request.LibraryID, reqFilePath); err != nil {
 		return err
 	}
@@ -270,7 +433,6 @@ func (c *Docker) Build(ctx context.Context, request *BuildRequest) error {
 		}
 	}()
 
-	librarianDir := filepath.Join(request.RepoDir, legacyconfig.LibrarianDir)
 	mounts := []string{
 		fmt.Sprintf("%s:/librarian", librarianDir),
 		fmt.Sprintf("%s:/repo", request.RepoDir),
@@ -281,7 +443,7 @@ func (c *Docker) Build(ctx context.Context, request *BuildRequest) error {
 	}
 
 	image := c.resolveImage(request.Image)
-	return c.runDocker(ctx, image, CommandBuild, mounts, commandArgs)
+	return c.runDocker(ctx, image, CommandBuild, request.LibraryID, mounts, commandArgs)
 }
 
 // Configure configures an API within a repository, either adding it to an
@@ -309,7 +471,7 @@ func (c *Docker) Configure(ctx context.Context, request *ConfigureRequest) (stri
 		"--repo=/repo",
 		"--source=/source",
 	}
-	generatorInput := filepath.Join(request.RepoDir, legacyconfig.GeneratorInputDir)
+	generatorInput := resolveGeneratorInputDir(request.GeneratorInputDir, request.RepoDir)
 	librarianDir := filepath.Join(request.RepoDir, legacyconfig.LibrarianDir)
 	mounts := []string{
 		fmt.Sprintf("%s:/librarian", librarianDir),
@@ -325,9 +487,17 @@ func (c *Docker) Configure(ctx context.Context, request *ConfigureRequest) (stri
 	for _, globalFile := range request.GlobalFiles {
 		mounts = append(mounts, fmt.Sprintf("%s/%s:/repo/%s:ro", request.RepoDir, globalFile, globalFile))
 	}
+	// Mount the additional directories as a readonly volume.
+	for _, dir := range request.ConfigureMounts {
+		cleaned := filepath.Clean(dir)
+		if dir == "" || filepath.IsAbs(dir) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("configure mount %q is not within the language repository", dir)
+		}
+		mounts = append(mounts, fmt.Sprintf("%s:%s:ro", filepath.Join(request.RepoDir, cleaned), path.Join("/repo", filepath.ToSlash(cleaned))))
+	}
 
 	image := c.resolveImage(request.Image)
-	if err := c.runDocker(ctx, image, CommandConfigure, mounts, commandArgs); err != nil {
+	if err := c.runDocker(ctx, image, CommandConfigure, request.LibraryID, mounts, commandArgs); err != nil {
 		return "", err
 	}
 
@@ -363,20 +533,62 @@ func (c *Docker) ReleaseStage(ctx context.Context, request *ReleaseStageRequest)
 	}
 
 	image := c.resolveImage(request.Image)
-	if err := c.runDocker(ctx, image, CommandReleaseStage, mounts, commandArgs); err != nil {
+	if err := c.runDocker(ctx, image, CommandReleaseStage, request.LibraryID, mounts, commandArgs); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (c *Docker) runDocker(_ context.Context, image string, command Command, mounts []string, commandArgs []string) (err error) {
-	mounts = maybeRelocateMounts(c.HostMount, mounts)
+func (c *Docker) runDocker(ctx context.Context, image string, command Command, libraryID string, mounts []string, commandArgs []string) (err error) {
+	if command != CommandReleaseStage {
+		mounts = slices.Concat(mounts, c.mounts)
+	}
 	args := []string{
 		"run",
 		"--rm", // Automatically delete the container after completion
 	}
-	for _, mount := range mounts {
+	var name string
+	if c.timeout > 0 {
+		// Killing the docker CLI leaves the container running, so the
+		// container is named to be killed on timeout.
+		name = fmt.Sprintf("librarian-%d-%d", os.Getpid(), c.containers.Add(1))
+		args = append(args, "--name", name)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	args = append(args, c.containerArgs(command, mounts)...)
+	args = append(args, image)
+	args = append(args, string(command))
+	args = append(args, commandArgs...)
+	slog.Debug("running container", "command", command, "image", image, "mounts", mounts)
+	err = c.run(ctx, outputPrefix(libraryID, command), c.logPath(libraryID, command), args...)
+	if err != nil && name != "" && ctx.Err() != nil {
+		if _, killErr := c.output("kill", name); killErr != nil {
+			slog.Warn("failed to kill container", "name", name, "error", killErr)
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return timeoutError(command, libraryID, c.timeout)
+		}
+	}
+	return err
+}
+
+// timeoutError returns the error of a container running command for the
+// library with the given ID, if any, which did not complete within timeout.
+func timeoutError(command Command, libraryID string, timeout time.Duration) error {
+	if libraryID == "" {
+		return fmt.Errorf("%s container timed out after %s", command, timeout)
+	}
+	return fmt.Errorf("%s container for library %q timed out after %s", command, libraryID, timeout)
+}
+
+// containerArgs returns the docker run flags of a container running command
+// with mounts, limited as configured.
+func (c *Docker) containerArgs(command Command, mounts []string) []string {
+	var args []string
+	for _, mount := range maybeRelocateMounts(c.HostMount, mounts) {
 		args = append(args, "-v", mount)
 	}
 
@@ -385,11 +597,43 @@ func (c *Docker) runDocker(_ context.Context, image string, command Command, mou
 	if c.uid != "" && c.gid != "" {
 		args = append(args, "--user", fmt.Sprintf("%s:%s", c.uid, c.gid))
 	}
+	if c.memory != "" {
+		args = append(args, "--memory", c.memory)
+	}
+	if c.cpus != "" {
+		args = append(args, "--cpus", c.cpus)
+	}
+	if slices.Contains(c.noNetwork, command) {
+		args = append(args, "--network=none")
+	}
+	return args
+}
 
-	args = append(args, image)
-	args = append(args, string(command))
-	args = append(args, commandArgs...)
-	return c.run(args...)
+// outputPrefix returns the prefix of the lines written to the console by the
+// container running command for the library with the given ID, e.g.
+// "[secretmanager generate]". Commands run for all libraries are prefixed with
+// the command only.
+func outputPrefix(libraryID string, command Command) string {
+	if libraryID == "" {
+		return fmt.Sprintf("[%s]", command)
+	}
+	return fmt.Sprintf("[%s %s]", libraryID, command)
+}
+
+// logPath returns the path of the file the output of the container running
+// command for the library with the given ID is written to, or an empty string
+// if c.logDir is not set. The containers of a library share the same file.
+// Commands run for all libraries, such as release-stage without a library ID,
+// are written to a file named after the command.
+func (c *Docker) logPath(libraryID string, command Command) string {
+	if c.logDir == "" {
+		return ""
+	}
+	name := libraryID
+	if name == "" {
+		name = string(command)
+	}
+	return filepath.Join(c.logDir, strings.ReplaceAll(name, "/", "_")+".log")
 }
 
 func maybeRelocateMounts(hostMount string, mounts []string) []string {
@@ -410,6 +654,31 @@ func maybeRelocateMounts(hostMount string, mounts []string) []string {
 	return relocatedMounts
 }
 
+// resolveMount validates mount, in the format
+// "{host-dir}:{container-dir}[:ro]", and returns it with the host directory
+// made absolute, as docker run requires.
+func resolveMount(mount string) (string, error) {
+	parts := strings.Split(mount, ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return "", fmt.Errorf("invalid mount %q, want {host-dir}:{container-dir}[:ro]", mount)
+	}
+	if len(parts) == 3 && parts[2] != "ro" {
+		return "", fmt.Errorf("invalid mount %q, only the ro option is supported", mount)
+	}
+	if !filepath.IsAbs(parts[1]) {
+		return "", fmt.Errorf("invalid mount %q, container directory must be absolute", mount)
+	}
+	hostDir, err := filepath.Abs(parts[0])
+	if err != nil {
+		return "", fmt.Errorf("invalid mount %q: %w", mount, err)
+	}
+	if _, err := os.Stat(hostDir); err != nil {
+		return "", fmt.Errorf("invalid mount %q: %w", mount, err)
+	}
+	parts[0] = hostDir
+	return strings.Join(parts, ":"), nil
+}
+
 func (c *Docker) resolveImage(requestedImage string) string {
 	if requestedImage != "" {
 		return requestedImage
@@ -417,18 +686,128 @@ func (c *Docker) resolveImage(requestedImage string) string {
 	return c.Image
 }
 
-func (c *Docker) runCommand(cmdName string, args ...string) error {
-	cmd := exec.Command(cmdName, args...)
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
+// resolveLibrarianDir returns the requested librarian directory, falling back
+// to the .librarian directory of the language repository.
+func resolveLibrarianDir(requestedDir, repoDir string) string {
+	if requestedDir != "" {
+		return requestedDir
+	}
+	return filepath.Join(repoDir, legacyconfig.LibrarianDir)
+}
+
+// resolveGeneratorInputDir returns the requested generator input directory,
+// falling back to the generator-input directory of the language repository.
+func resolveGeneratorInputDir(requestedDir, repoDir string) string {
+	if requestedDir != "" {
+		return requestedDir
+	}
+	return filepath.Join(repoDir, legacyconfig.GeneratorInputDir)
+}
+
+// runCommand runs the command, streaming its output to the console with each
+// line prefixed with prefix, so that the output of containers running at the
+// same time can be told apart. If c.quiet is set, the output is only written
+// to the console if the command fails. If logPath is not empty, the output is
+// also appended to the file at logPath as it arrives, without prefixes, so
+// that the progress of long-running containers can be followed.
+func (c *Docker) runCommand(ctx context.Context, prefix, logPath, cmdName string, args ...string) error {
+	cmd := exec.CommandContext(ctx, cmdName, args...)
+	var quietOutput bytes.Buffer
+	var lineWriters []*linePrefixWriter
+	if c.quiet {
+		w := &linePrefixWriter{w: &quietOutput, prefix: prefix}
+		lineWriters = append(lineWriters, w)
+		cmd.Stdout = w
+	} else {
+		stdout := &linePrefixWriter{w: os.Stdout, prefix: prefix}
+		stderr := &linePrefixWriter{w: os.Stderr, prefix: prefix}
+		lineWriters = append(lineWriters, stdout, stderr)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+	}
+	if logPath != "" {
+		if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+			return fmt.Errorf("failed to make container log directory: %w", err)
+		}
+		logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open container log file: %w", err)
+		}
+		defer logFile.Close()
+		if _, err := fmt.Fprintf(logFile, "=== %s\n", cmd.String()); err != nil {
+			return fmt.Errorf("failed to write container log file: %w", err)
+		}
+		// As the writers are not files, the output of the command is read
+		// through pipes and copied to the writers as soon as it is written.
+		cmd.Stdout = io.MultiWriter(cmd.Stdout, logFile)
+		if cmd.Stderr != nil {
+			cmd.Stderr = io.MultiWriter(cmd.Stderr, logFile)
+		}
+		slog.Info("writing container output", "file", logPath)
+	}
+	if c.quiet {
+		// Using the same writer for both streams ensures they are not written
+		// to at the same time.
+		cmd.Stderr = cmd.Stdout
+	}
 	slog.Info(fmt.Sprintf("=== Docker start %s", strings.Repeat("=", 63)))
-	slog.Info(cmd.String())
+	// Only the arguments are logged: the requests are passed to containers in
+	// mounted files, which are never echoed.
+	slog.Debug("running command", "name", cmdName, "args", args)
 	slog.Info(strings.Repeat("-", 80))
 	err := cmd.Run()
+	for _, w := range lineWriters {
+		if flushErr := w.Flush(); flushErr != nil {
+			slog.Warn("failed to write container output", "error", flushErr)
+		}
+	}
+	if err != nil && c.quiet {
+		os.Stderr.Write(quietOutput.Bytes())
+	}
 	slog.Info(fmt.Sprintf("=== Docker end %s", strings.Repeat("=", 65)))
 	return err
 }
 
+// linePrefixWriter writes the lines written to it to w, each prefixed with
+// prefix and a space. Each line is written with a single call to w.Write, so
+// that lines written by concurrent writers are not interleaved. An incomplete
+// last line is held until Flush is called.
+type linePrefixWriter struct {
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (p *linePrefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if err := p.writeLine(p.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		p.buf = p.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// Flush writes the incomplete last line, if any, terminated with a newline.
+func (p *linePrefixWriter) Flush() error {
+	if len(p.buf) == 0 {
+		return nil
+	}
+	line := append(p.buf, '\n')
+	p.buf = nil
+	return p.writeLine(line)
+}
+
+func (p *linePrefixWriter) writeLine(line []byte) error {
+	_, err := p.w.Write(append([]byte(p.prefix+" "), line...))
+	return err
+}
+
 func writeLibraryState(state *legacyconfig.LibrarianState, libraryID, jsonFilePath string) error {
 	if err := os.MkdirAll(filepath.Dir(jsonFilePath), 0755); err != nil {
 		return fmt.Errorf("failed to make directory: %w", err)
@@ -467,7 +846,15 @@ func writeLibrarianState(state *legacyconfig.LibrarianState, jsonFilePath string
 	}
 	defer jsonFile.Close()
 
-	data, err := json.MarshalIndent(state, "", "  ")
+	// Like state.yaml, the request lists the libraries sorted by ID, e.g. with
+	// a library being configured at its place rather than last. The libraries
+	// of state are left in their order.
+	sorted := *state
+	sorted.Libraries = slices.Clone(state.Libraries)
+	slices.SortStableFunc(sorted.Libraries, func(a, b *legacyconfig.LibraryState) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	data, err := json.MarshalIndent(&sorted, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal state to JSON: %w", err)
 	}
@@ -15,13 +15,19 @@
 package legacydocker
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
@@ -33,10 +39,20 @@ func TestNew(t *testing.T) {
 		testImage    = "testImage"
 		testUID      = "1000"
 		testGID      = "1001"
+		testMemory   = "4g"
+		testCPUs     = "1.5"
+		testLogDir   = "testLogDir"
 	)
+	cacheDir := t.TempDir()
 	d, err := New(testWorkRoot, testImage, &DockerOptions{
-		UserUID: testUID,
-		UserGID: testGID,
+		UserUID:   testUID,
+		UserGID:   testGID,
+		Memory:    testMemory,
+		CPUs:      testCPUs,
+		NoNetwork: []Command{CommandGenerate},
+		Mounts:    []string{cacheDir + ":/cache:ro"},
+		LogDir:    testLogDir,
+		Quiet:     true,
 	})
 	if err != nil {
 		t.Fatalf("New() error = %v", err)
@@ -50,6 +66,24 @@ func TestNew(t *testing.T) {
 	if d.gid != testGID {
 		t.Errorf("d.gid = %q, want %q", d.gid, testGID)
 	}
+	if d.memory != testMemory {
+		t.Errorf("d.memory = %q, want %q", d.memory, testMemory)
+	}
+	if d.cpus != testCPUs {
+		t.Errorf("d.cpus = %q, want %q", d.cpus, testCPUs)
+	}
+	if diff := cmp.Diff([]Command{CommandGenerate}, d.noNetwork); diff != "" {
+		t.Errorf("d.noNetwork mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{cacheDir + ":/cache:ro"}, d.mounts); diff != "" {
+		t.Errorf("d.mounts mismatch (-want +got):\n%s", diff)
+	}
+	if d.logDir != testLogDir {
+		t.Errorf("d.logDir = %q, want %q", d.logDir, testLogDir)
+	}
+	if !d.quiet {
+		t.Error("d.quiet = false, want true")
+	}
 	if d.run == nil {
 		t.Error("d.run is nil")
 	}
@@ -67,6 +101,8 @@ func TestDockerRun(t *testing.T) {
 
 	state := &legacyconfig.LibrarianState{}
 	repoDir := filepath.Join(os.TempDir())
+	librarianDir := t.TempDir()
+	generatorInputDir := t.TempDir()
 	for _, test := range []struct {
 		name       string
 		docker     *Docker
@@ -105,6 +141,102 @@ func TestDockerRun(t *testing.T) {
 				"--source=/source",
 			},
 		},
+		{
+			name: "Generate with librarian dir",
+			docker: &Docker{
+				Image: testImage,
+			},
+			runCommand: func(ctx context.Context, d *Docker) error {
+				generateRequest := &GenerateRequest{
+					State:        state,
+					RepoDir:      repoDir,
+					ApiRoot:      testAPIRoot,
+					Output:       testOutput,
+					LibraryID:    testLibraryID,
+					LibrarianDir: librarianDir,
+				}
+
+				return d.Generate(ctx, generateRequest)
+			},
+			want: []string{
+				"run", "--rm",
+				"-v", fmt.Sprintf("%s:/librarian", librarianDir),
+				"-v", fmt.Sprintf("%s/.librarian/generator-input:/input", repoDir),
+				"-v", fmt.Sprintf("%s:/output", testOutput),
+				"-v", fmt.Sprintf("%s:/source:ro", testAPIRoot),
+				testImage,
+				string(CommandGenerate),
+				"--librarian=/librarian",
+				"--input=/input",
+				"--output=/output",
+				"--source=/source",
+			},
+		},
+		{
+			name: "Generate with generator input dir",
+			docker: &Docker{
+				Image: testImage,
+			},
+			runCommand: func(ctx context.Context, d *Docker) error {
+				generateRequest := &GenerateRequest{
+					State:             state,
+					RepoDir:           repoDir,
+					ApiRoot:           testAPIRoot,
+					Output:            testOutput,
+					LibraryID:         testLibraryID,
+					GeneratorInputDir: generatorInputDir,
+				}
+
+				return d.Generate(ctx, generateRequest)
+			},
+			want: []string{
+				"run", "--rm",
+				"-v", fmt.Sprintf("%s/.librarian:/librarian", repoDir),
+				"-v", fmt.Sprintf("%s:/input", generatorInputDir),
+				"-v", fmt.Sprintf("%s:/output", testOutput),
+				"-v", fmt.Sprintf("%s:/source:ro", testAPIRoot),
+				testImage,
+				string(CommandGenerate),
+				"--librarian=/librarian",
+				"--input=/input",
+				"--output=/output",
+				"--source=/source",
+			},
+		},
+		{
+			name: "Generate with resource limits",
+			docker: &Docker{
+				Image:  testImage,
+				memory: "4g",
+				cpus:   "1.5",
+			},
+			runCommand: func(ctx context.Context, d *Docker) error {
+				generateRequest := &GenerateRequest{
+					State:     state,
+					RepoDir:   repoDir,
+					ApiRoot:   testAPIRoot,
+					Output:    testOutput,
+					LibraryID: testLibraryID,
+				}
+
+				return d.Generate(ctx, generateRequest)
+			},
+			want: []string{
+				"run", "--rm",
+				"-v", fmt.Sprintf("%s/.librarian:/librarian", repoDir),
+				"-v", fmt.Sprintf("%s/.librarian/generator-input:/input", repoDir),
+				"-v", fmt.Sprintf("%s:/output", testOutput),
+				"-v", fmt.Sprintf("%s:/source:ro", testAPIRoot),
+				"--memory", "4g",
+				"--cpus", "1.5",
+				testImage,
+				string(CommandGenerate),
+				"--librarian=/librarian",
+				"--input=/input",
+				"--output=/output",
+				"--source=/source",
+			},
+		},
 		{
 			name: "Generate with invalid repo root",
 			docker: &Docker{
@@ -231,6 +363,109 @@ func TestDockerRun(t *testing.T) {
 				"--repo=/repo",
 			},
 		},
+		{
+			name: "Build without network",
+			docker: &Docker{
+				Image:     testImage,
+				noNetwork: []Command{CommandBuild, CommandConfigure},
+			},
+			runCommand: func(ctx context.Context, d *Docker) error {
+				buildRequest := &BuildRequest{
+					State:     state,
+					LibraryID: testLibraryID,
+					RepoDir:   repoDir,
+				}
+
+				return d.Build(ctx, buildRequest)
+			},
+			want: []string{
+				"run", "--rm",
+				"-v", fmt.Sprintf("%s/.librarian:/librarian", repoDir),
+				"-v", fmt.Sprintf("%s:/repo", repoDir),
+				"--network=none",
+				testImage,
+				string(CommandBuild),
+				"--librarian=/librarian",
+				"--repo=/repo",
+			},
+		},
+		{
+			name: "Build with network when other commands have none",
+			docker: &Docker{
+				Image:     testImage,
+				noNetwork: []Command{CommandGenerate},
+			},
+			runCommand: func(ctx context.Context, d *Docker) error {
+				buildRequest := &BuildRequest{
+					State:     state,
+					LibraryID: testLibraryID,
+					RepoDir:   repoDir,
+				}
+
+				return d.Build(ctx, buildRequest)
+			},
+			want: []string{
+				"run", "--rm",
+				"-v", fmt.Sprintf("%s/.librarian:/librarian", repoDir),
+				"-v", fmt.Sprintf("%s:/repo", repoDir),
+				testImage,
+				string(CommandBuild),
+				"--librarian=/librarian",
+				"--repo=/repo",
+			},
+		},
+		{
+			name: "Build with extra mounts",
+			docker: &Docker{
+				Image:  testImage,
+				mounts: []string{"/host/cache:/cache", "/host/m2:/root/.m2:ro"},
+			},
+			runCommand: func(ctx context.Context, d *Docker) error {
+				buildRequest := &BuildRequest{
+					State:     state,
+					LibraryID: testLibraryID,
+					RepoDir:   repoDir,
+				}
+
+				return d.Build(ctx, buildRequest)
+			},
+			want: []string{
+				"run", "--rm",
+				"-v", fmt.Sprintf("%s/.librarian:/librarian", repoDir),
+				"-v", fmt.Sprintf("%s:/repo", repoDir),
+				"-v", "/host/cache:/cache",
+				"-v", "/host/m2:/root/.m2:ro",
+				testImage,
+				string(CommandBuild),
+				"--librarian=/librarian",
+				"--repo=/repo",
+			},
+		},
+		{
+			name: "Build with librarian dir",
+			docker: &Docker{
+				Image: testImage,
+			},
+			runCommand: func(ctx context.Context, d *Docker) error {
+				buildRequest := &BuildRequest{
+					State:        state,
+					LibraryID:    testLibraryID,
+					RepoDir:      repoDir,
+					LibrarianDir: librarianDir,
+				}
+
+				return d.Build(ctx, buildRequest)
+			},
+			want: []string{
+				"run", "--rm",
+				"-v", fmt.Sprintf("%s:/librarian", librarianDir),
+				"-v", fmt.Sprintf("%s:/repo", repoDir),
+				testImage,
+				string(CommandBuild),
+				"--librarian=/librarian",
+				"--repo=/repo",
+			},
+		},
 		{
 			name: "Build runs in docker with image override",
 			docker: &Docker{
@@ -331,6 +566,40 @@ func TestDockerRun(t *testing.T) {
 				"--source=/source",
 			},
 		},
+		{
+			name: "Configure with generator input dir",
+			docker: &Docker{
+				Image: testImage,
+			},
+			runCommand: func(ctx context.Context, d *Docker) error {
+				configureRequest := &ConfigureRequest{
+					State:             state,
+					LibraryID:         testLibraryID,
+					RepoDir:           repoDir,
+					ApiRoot:           testAPIRoot,
+					Output:            testOutput,
+					GeneratorInputDir: generatorInputDir,
+				}
+
+				_, err := d.Configure(ctx, configureRequest)
+
+				return err
+			},
+			want: []string{
+				"run", "--rm",
+				"-v", fmt.Sprintf("%s/.librarian:/librarian", repoDir),
+				"-v", fmt.Sprintf("%s:/input", generatorInputDir),
+				"-v", fmt.Sprintf("%s:/output", testOutput),
+				"-v", fmt.Sprintf("%s:/source:ro", testAPIRoot),
+				testImage,
+				string(CommandConfigure),
+				"--librarian=/librarian",
+				"--input=/input",
+				"--output=/output",
+				"--repo=/repo",
+				"--source=/source",
+			},
+		},
 		{
 			name: "Configure runs in docker with image override",
 			docker: &Docker{
@@ -371,6 +640,66 @@ func TestDockerRun(t *testing.T) {
 				"--source=/source",
 			},
 		},
+		{
+			name: "Configure with configure mounts",
+			docker: &Docker{
+				Image: testImage,
+			},
+			runCommand: func(ctx context.Context, d *Docker) error {
+				configureRequest := &ConfigureRequest{
+					State:           state,
+					LibraryID:       testLibraryID,
+					RepoDir:         repoDir,
+					ApiRoot:         testAPIRoot,
+					Output:          testOutput,
+					GlobalFiles:     []string{"go.mod"},
+					ConfigureMounts: []string{"a/b/", "."},
+				}
+
+				_, err := d.Configure(ctx, configureRequest)
+
+				return err
+			},
+			want: []string{
+				"run", "--rm",
+				"-v", fmt.Sprintf("%s/.librarian:/librarian", repoDir),
+				"-v", fmt.Sprintf("%s/.librarian/generator-input:/input", repoDir),
+				"-v", fmt.Sprintf("%s:/output", testOutput),
+				"-v", fmt.Sprintf("%s:/source:ro", testAPIRoot),
+				"-v", fmt.Sprintf("%s/go.mod:/repo/go.mod:ro", repoDir),
+				"-v", fmt.Sprintf("%s/a/b:/repo/a/b:ro", repoDir),
+				"-v", fmt.Sprintf("%s:/repo:ro", repoDir),
+				testImage,
+				string(CommandConfigure),
+				"--librarian=/librarian",
+				"--input=/input",
+				"--output=/output",
+				"--repo=/repo",
+				"--source=/source",
+			},
+		},
+		{
+			name: "Configure with configure mount outside of repo",
+			docker: &Docker{
+				Image: testImage,
+			},
+			runCommand: func(ctx context.Context, d *Docker) error {
+				configureRequest := &ConfigureRequest{
+					State:           state,
+					LibraryID:       testLibraryID,
+					RepoDir:         repoDir,
+					ApiRoot:         testAPIRoot,
+					Output:          testOutput,
+					ConfigureMounts: []string{"a/../../b"},
+				}
+
+				_, err := d.Configure(ctx, configureRequest)
+
+				return err
+			},
+			wantErr:    true,
+			wantErrMsg: `configure mount "a/../../b" is not within the language repository`,
+		},
 		{
 			name: "configure_with_nil_global_files",
 			docker: &Docker{
@@ -619,6 +948,41 @@ func TestDockerRun(t *testing.T) {
 				"--output=/output",
 			},
 		},
+		{
+			name: "Release stage without extra mounts",
+			docker: &Docker{
+				Image:  testImage,
+				mounts: []string{"/host/cache:/cache"},
+			},
+			runCommand: func(ctx context.Context, d *Docker) error {
+				partialRepoDir := filepath.Join(repoDir, "release-stage-without-extra-mounts")
+				if err := os.MkdirAll(filepath.Join(repoDir, legacyconfig.LibrarianDir), 0755); err != nil {
+					t.Fatal(err)
+				}
+
+				releaseInitRequest := &ReleaseStageRequest{
+					State:           state,
+					Output:          testOutput,
+					LibrarianConfig: &legacyconfig.LibrarianConfig{},
+					RepoDir:         partialRepoDir,
+				}
+
+				defer os.RemoveAll(partialRepoDir)
+
+				return d.ReleaseStage(ctx, releaseInitRequest)
+			},
+			want: []string{
+				"run", "--rm",
+				"-v", fmt.Sprintf("%s/.librarian:/librarian", filepath.Join(repoDir, "release-stage-without-extra-mounts")),
+				"-v", fmt.Sprintf("%s:/repo:ro", filepath.Join(repoDir, "release-stage-without-extra-mounts")),
+				"-v", fmt.Sprintf("%s:/output", testOutput),
+				testImage,
+				string(CommandReleaseStage),
+				"--librarian=/librarian",
+				"--repo=/repo",
+				"--output=/output",
+			},
+		},
 		{
 			name: "Release stage runs in docker with image override",
 			docker: &Docker{
@@ -765,7 +1129,7 @@ func TestDockerRun(t *testing.T) {
 		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
-			test.docker.run = func(args ...string) error {
+			test.docker.run = func(_ context.Context, _, _ string, args ...string) error {
 				if test.docker.Image == mockImage {
 					return errors.New("simulate docker command failure for testing")
 				}
@@ -1047,6 +1411,43 @@ func TestWriteLibrarianState(t *testing.T) {
 	}
 }
 
+func TestWriteLibrarianState_SortsLibraries(t *testing.T) {
+	t.Parallel()
+	state := &legacyconfig.LibrarianState{
+		Image: "v1.0.0",
+		Libraries: []*legacyconfig.LibraryState{
+			{ID: "c-library"},
+			{ID: "d-library"},
+			{ID: "a-library"},
+		},
+	}
+	filePath := filepath.Join(t.TempDir(), "configure-request.json")
+	if err := writeLibrarianState(state, filePath); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written legacyconfig.LibrarianState
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatal(err)
+	}
+	ids := func(state *legacyconfig.LibrarianState) []string {
+		var ids []string
+		for _, library := range state.Libraries {
+			ids = append(ids, library.ID)
+		}
+		return ids
+	}
+	if diff := cmp.Diff([]string{"a-library", "c-library", "d-library"}, ids(&written)); diff != "" {
+		t.Errorf("written library order mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"c-library", "d-library", "a-library"}, ids(state)); diff != "" {
+		t.Errorf("state library order mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestDocker_runCommand(t *testing.T) {
 	for _, test := range []struct {
 		name    string
@@ -1069,13 +1470,419 @@ func TestDocker_runCommand(t *testing.T) {
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			c := &Docker{}
-			if err := c.runCommand(test.cmdName, test.args...); (err != nil) != test.wantErr {
+			if err := c.runCommand(t.Context(), "[test]", "", test.cmdName, test.args...); (err != nil) != test.wantErr {
 				t.Errorf("Docker.runCommand() error = %v, wantErr %v", err, test.wantErr)
 			}
 		})
 	}
 }
 
+func TestDocker_runCommand_LogFile(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "logs", "google_cloud_foo.log")
+	doneFile := filepath.Join(dir, "done")
+	// The command writes a first line, and waits for the test to create
+	// doneFile before writing a second one.
+	script := fmt.Sprintf(`echo first; while [ ! -f %q ]; do sleep 0.01; done; echo second >&2`, doneFile)
+	c := &Docker{}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- c.runCommand(t.Context(), "[google_cloud_foo generate]", logPath, "sh", "-c", script)
+	}()
+
+	// The first line is written to the log file while the command runs.
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		content, err := os.ReadFile(logPath)
+		if err == nil && strings.Contains(string(content), "\nfirst\n") {
+			if strings.Contains(string(content), "\nsecond\n") {
+				t.Fatalf("log file = %q, want only the first line before the command completes", content)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("log file = %q, %v; want the first line before the command completes", content, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := os.WriteFile(doneFile, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("runCommand() failed: %v", err)
+	}
+	content, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("=== %s\nfirst\nsecond\n", exec.Command("sh", "-c", script).String())
+	if diff := cmp.Diff(want, string(content)); diff != "" {
+		t.Errorf("log file mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDocker_runCommand_Output(t *testing.T) {
+	script := `echo out; echo err >&2; printf partial`
+	for _, test := range []struct {
+		name       string
+		quiet      bool
+		fail       bool
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "streamed",
+			wantStdout: "[foo generate] out\n[foo generate] partial\n",
+			wantStderr: "[foo generate] err\n",
+		},
+		{
+			name:  "quiet",
+			quiet: true,
+		},
+		{
+			name:       "quiet failure",
+			quiet:      true,
+			fail:       true,
+			wantStderr: "[foo generate] out\n[foo generate] err\n[foo generate] partial\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cmdScript := script
+			if test.fail {
+				cmdScript += "; exit 1"
+			}
+			c := &Docker{quiet: test.quiet}
+			var err error
+			gotStdout, gotStderr := captureOutput(t, func() {
+				err = c.runCommand(t.Context(), "[foo generate]", "", "sh", "-c", cmdScript)
+			})
+			if (err != nil) != test.fail {
+				t.Fatalf("runCommand() error = %v, want failure %t", err, test.fail)
+			}
+			if diff := cmp.Diff(test.wantStdout, gotStdout); diff != "" {
+				t.Errorf("stdout mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.wantStderr, gotStderr); diff != "" {
+				t.Errorf("stderr mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDocker_runCommand_Canceled(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	c := &Docker{quiet: true}
+	start := time.Now()
+	if err := c.runCommand(ctx, "[test]", "", "sleep", "10"); err == nil {
+		t.Fatal("runCommand() should fail once the context is done")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("runCommand() returned after %s, want the command killed", elapsed)
+	}
+}
+
+func TestDocker_runDockerTimeout(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		timeout    time.Duration
+		libraryID  string
+		wantKill   bool
+		wantErrMsg string
+	}{
+		{
+			name:       "timed out",
+			timeout:    10 * time.Millisecond,
+			libraryID:  "google-cloud-foo",
+			wantKill:   true,
+			wantErrMsg: `generate container for library "google-cloud-foo" timed out after 10ms`,
+		},
+		{
+			name:       "timed out without library",
+			timeout:    10 * time.Millisecond,
+			wantKill:   true,
+			wantErrMsg: "generate container timed out after 10ms",
+		},
+		{
+			name: "no timeout",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var outputCalls [][]string
+			d := &Docker{
+				Image:   "testImage",
+				timeout: test.timeout,
+				run: func(ctx context.Context, _, _ string, args ...string) error {
+					if test.timeout == 0 {
+						return nil
+					}
+					<-ctx.Done()
+					return errors.New("signal: killed")
+				},
+				output: func(args ...string) ([]byte, error) {
+					outputCalls = append(outputCalls, args)
+					return nil, nil
+				},
+			}
+			err := d.runDocker(t.Context(), "testImage", CommandGenerate, test.libraryID, nil, nil)
+			if test.wantErrMsg == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+			} else if err == nil || err.Error() != test.wantErrMsg {
+				t.Fatalf("runDocker() error = %v, want %q", err, test.wantErrMsg)
+			}
+			var wantCalls [][]string
+			if test.wantKill {
+				wantCalls = [][]string{{"kill", fmt.Sprintf("librarian-%d-1", os.Getpid())}}
+			}
+			if diff := cmp.Diff(wantCalls, outputCalls); diff != "" {
+				t.Errorf("output calls mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDocker_runCommand_DebugLog(t *testing.T) {
+	args := []string{"run", "--rm", "-v", "/repo/.librarian:/librarian", "-v", "/source:/source:ro", "--user", "1000:1000", "testImage", "generate", "--librarian=/librarian"}
+	for _, test := range []struct {
+		name  string
+		level slog.Level
+		want  string
+	}{
+		{
+			name:  "debug",
+			level: slog.LevelDebug,
+			want:  `level=DEBUG msg="running command" name=echo args="[run --rm -v /repo/.librarian:/librarian -v /source:/source:ro --user 1000:1000 testImage generate --librarian=/librarian]"`,
+		},
+		{
+			name:  "info",
+			level: slog.LevelInfo,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			oldLogger := slog.Default()
+			slog.SetDefault(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{
+				Level: test.level,
+				ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+					if a.Key == slog.TimeKey {
+						return slog.Attr{}
+					}
+					return a
+				},
+			})))
+			defer slog.SetDefault(oldLogger)
+			c := &Docker{quiet: true}
+			if err := c.runCommand(t.Context(), "[test]", "", "echo", args...); err != nil {
+				t.Fatal(err)
+			}
+			var got string
+			for _, line := range strings.Split(logs.String(), "\n") {
+				if strings.Contains(line, `msg="running command"`) {
+					got = line
+				}
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("debug log mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDocker_runDocker_DebugLog(t *testing.T) {
+	var logs bytes.Buffer
+	oldLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})))
+	defer slog.SetDefault(oldLogger)
+	d := &Docker{
+		mounts: []string{"/cache:/cache"},
+		run: func(ctx context.Context, _, _ string, args ...string) error {
+			return nil
+		},
+	}
+	if err := d.runDocker(t.Context(), "testImage", CommandGenerate, "", []string{"/source:/source:ro"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := `level=DEBUG msg="running container" command=generate image=testImage mounts="[/source:/source:ro /cache:/cache]"`
+	if got := strings.TrimSpace(logs.String()); got != want {
+		t.Errorf("debug log = %q, want %q", got, want)
+	}
+}
+
+// captureOutput runs f, returning what it writes to os.Stdout and os.Stderr.
+// Logs are discarded.
+func captureOutput(t *testing.T, f func()) (string, string) {
+	t.Helper()
+	oldStdout, oldStderr, oldLogger := os.Stdout, os.Stderr, slog.Default()
+	stdout, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr, err := os.Create(filepath.Join(t.TempDir(), "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout, os.Stderr = stdout, stderr
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	defer func() {
+		os.Stdout, os.Stderr = oldStdout, oldStderr
+		slog.SetDefault(oldLogger)
+	}()
+	f()
+	gotStdout, err := os.ReadFile(stdout.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotStderr, err := os.ReadFile(stderr.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(gotStdout), string(gotStderr)
+}
+
+func TestLinePrefixWriter(t *testing.T) {
+	t.Parallel()
+	var buf strings.Builder
+	w := &linePrefixWriter{w: &buf, prefix: "[foo]"}
+	for _, write := range []string{"first\nsec", "ond\n", "", "\nthi", "rd"} {
+		if _, err := w.Write([]byte(write)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := "[foo] first\n[foo] second\n[foo] \n[foo] third\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestOutputPrefix(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		libraryID string
+		command   Command
+		want      string
+	}{
+		{libraryID: "secretmanager", command: CommandGenerate, want: "[secretmanager generate]"},
+		{command: CommandReleaseStage, want: "[release-stage]"},
+	} {
+		if got := outputPrefix(test.libraryID, test.command); got != test.want {
+			t.Errorf("outputPrefix(%q, %q) = %q, want %q", test.libraryID, test.command, got, test.want)
+		}
+	}
+}
+
+func TestResolveMount(t *testing.T) {
+	t.Parallel()
+	hostDir := t.TempDir()
+	for _, test := range []struct {
+		name       string
+		mount      string
+		want       string
+		wantErrMsg string
+	}{
+		{
+			name:  "read-write",
+			mount: hostDir + ":/cache",
+			want:  hostDir + ":/cache",
+		},
+		{
+			name:  "read-only",
+			mount: hostDir + ":/cache:ro",
+			want:  hostDir + ":/cache:ro",
+		},
+		{
+			name:       "missing container dir",
+			mount:      hostDir,
+			wantErrMsg: "want {host-dir}:{container-dir}[:ro]",
+		},
+		{
+			name:       "unsupported option",
+			mount:      hostDir + ":/cache:rw",
+			wantErrMsg: "only the ro option is supported",
+		},
+		{
+			name:       "relative container dir",
+			mount:      hostDir + ":cache",
+			wantErrMsg: "container directory must be absolute",
+		},
+		{
+			name:       "missing host dir",
+			mount:      filepath.Join(hostDir, "missing") + ":/cache",
+			wantErrMsg: "no such file or directory",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := resolveMount(test.mount)
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("resolveMount(%q) error = %v, want contains %q", test.mount, err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("resolveMount(%q) mismatch (-want +got):\n%s", test.mount, diff)
+			}
+		})
+	}
+}
+
+func TestDocker_logPath(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name      string
+		logDir    string
+		libraryID string
+		command   Command
+		want      string
+	}{
+		{
+			name:      "no log dir",
+			libraryID: "google-cloud-foo",
+			command:   CommandGenerate,
+		},
+		{
+			name:      "library",
+			logDir:    "logs",
+			libraryID: "google/cloud/foo",
+			command:   CommandGenerate,
+			want:      "logs/google_cloud_foo.log",
+		},
+		{
+			name:    "all libraries",
+			logDir:  "logs",
+			command: CommandReleaseStage,
+			want:    "logs/release-stage.log",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			c := &Docker{logDir: test.logDir}
+			if got := c.logPath(test.libraryID, test.command); got != filepath.FromSlash(test.want) {
+				t.Errorf("logPath() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
 func TestReleaseStageRequestContent(t *testing.T) {
 	tmpDir := t.TempDir()
 	partialRepoDir := filepath.Join(tmpDir, "partial-repo")
@@ -1091,6 +1898,7 @@ func TestReleaseStageRequestContent(t *testing.T) {
 				ID:               "my-library",
 				Version:          "1.1.0",
 				ReleaseTriggered: true,
+				ChangelogPath:    "docs/history.md",
 				Changes: []*legacyconfig.Commit{
 					{
 						Type:          "feat",
@@ -1114,7 +1922,7 @@ func TestReleaseStageRequestContent(t *testing.T) {
 
 	// Override the run command to intercept the arguments and verify the content
 	// of the release-stage-request.json file.
-	d.run = func(args ...string) error {
+	d.run = func(_ context.Context, _, _ string, args ...string) error {
 		var librarianDir string
 		for i, arg := range args {
 			if arg == "-v" && i+1 < len(args) {
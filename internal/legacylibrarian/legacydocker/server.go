@@ -0,0 +1,327 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacydocker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// ServeLabel is the label of the images supporting the serve command, set
+	// to "true" in their Dockerfile.
+	ServeLabel = "com.google.librarian.serve"
+
+	// defaultServePollInterval is how often the exchange directory of a
+	// server is checked for a response.
+	defaultServePollInterval = 100 * time.Millisecond
+
+	// defaultServeLivenessInterval is how often the container of a server is
+	// checked to still be running while waiting for a response.
+	defaultServeLivenessInterval = 5 * time.Second
+)
+
+// server is a long-lived container started with the serve command, handling
+// the generate requests of several libraries. Librarian writes each request
+// to the exchange directory, mounted as /exchange, as request-{n}.json, and
+// the container answers with response-{n}.json once done.
+//
+// As the mounts of a container cannot change once it is started, the
+// directories of the first request are mounted at the same paths in the
+// container, along with the work root, and requests refer to them by their
+// host paths. Requests with directories outside of them are run in their own
+// container.
+type server struct {
+	name        string
+	exchangeDir string
+	// dirs are the directories mounted in the container, and readOnlyDirs
+	// the ones mounted read-only.
+	dirs         []string
+	readOnlyDirs []string
+	seq          atomic.Int64
+}
+
+// serveRequest is the content of a request-{n}.json file.
+type serveRequest struct {
+	// Command is the command to run, e.g. "generate".
+	Command Command `json:"command"`
+	// Args are the flags of the command, as passed to a container running it.
+	Args []string `json:"args"`
+}
+
+// serveResponse is the content of a response-{n}.json file.
+type serveResponse struct {
+	// ExitCode is the exit code of the command, zero on success.
+	ExitCode int `json:"exit_code"`
+	// Output is the output of the command.
+	Output string `json:"output,omitempty"`
+}
+
+// covers reports whether dir is, or is inside, one of the directories mounted
+// in the container of s, and whether it is writable when needed.
+func (s *server) covers(dir string, readOnly bool) bool {
+	dirs := s.dirs
+	if readOnly {
+		dirs = slices.Concat(s.dirs, s.readOnlyDirs)
+	}
+	return slices.ContainsFunc(dirs, func(mounted string) bool {
+		rel, err := filepath.Rel(mounted, dir)
+		return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+	})
+}
+
+// generateServer returns the server running image, starting it if needed
+// with dirs and readOnlyDirs mounted, or nil if the generate request with
+// these directories should run in its own container, either because image
+// does not support the serve command, or because the server was started with
+// other directories.
+func (c *Docker) generateServer(ctx context.Context, image string, dirs, readOnlyDirs []string) (*server, error) {
+	c.serversMu.Lock()
+	defer c.serversMu.Unlock()
+	s, ok := c.servers[image]
+	if !ok {
+		var err error
+		if s, err = c.startServer(ctx, image, dirs, readOnlyDirs); err != nil {
+			return nil, err
+		}
+		if c.servers == nil {
+			c.servers = map[string]*server{}
+		}
+		// A nil server records that image does not support the serve command.
+		c.servers[image] = s
+	}
+	if s == nil {
+		return nil, nil
+	}
+	for _, dir := range dirs {
+		if !s.covers(dir, false) {
+			return nil, nil
+		}
+	}
+	for _, dir := range readOnlyDirs {
+		if !s.covers(dir, true) {
+			return nil, nil
+		}
+	}
+	return s, nil
+}
+
+// startServer starts a container running image with the serve command, or
+// returns nil if image does not have the ServeLabel label.
+func (c *Docker) startServer(ctx context.Context, image string, dirs, readOnlyDirs []string) (*server, error) {
+	out, err := c.output("image", "inspect", "--format", fmt.Sprintf("{{index .Config.Labels %q}}", ServeLabel), image)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect image %s: %w", image, err)
+	}
+	if strings.TrimSpace(string(out)) != "true" {
+		slog.Warn("image does not support container reuse, running a container per request", "image", image, "label", ServeLabel)
+		return nil, nil
+	}
+	n := len(c.servers) + 1
+	s := &server{
+		name:         fmt.Sprintf("librarian-serve-%d-%d", os.Getpid(), n),
+		exchangeDir:  filepath.Join(c.workRoot, "serve", strconv.Itoa(n)),
+		dirs:         []string{c.workRoot},
+		readOnlyDirs: readOnlyDirs,
+	}
+	for _, dir := range dirs {
+		if !s.covers(dir, false) {
+			s.dirs = append(s.dirs, dir)
+		}
+	}
+	if err := os.MkdirAll(s.exchangeDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to make exchange directory: %w", err)
+	}
+	mounts := []string{fmt.Sprintf("%s:/exchange", s.exchangeDir)}
+	for _, dir := range s.dirs {
+		mounts = append(mounts, fmt.Sprintf("%s:%s", dir, dir))
+	}
+	for _, dir := range s.readOnlyDirs {
+		mounts = append(mounts, fmt.Sprintf("%s:%s:ro", dir, dir))
+	}
+	mounts = slices.Concat(mounts, c.mounts)
+	args := []string{"run", "--detach", "--rm", "--name", s.name}
+	args = append(args, c.containerArgs(CommandGenerate, mounts)...)
+	args = append(args, image, string(CommandServe), "--exchange=/exchange")
+	slog.Info("starting a container serving generate requests", "image", image, "name", s.name)
+	slog.Debug("running container", "command", CommandServe, "image", image, "mounts", mounts)
+	if err := c.run(ctx, outputPrefix("", CommandServe), "", args...); err != nil {
+		return nil, fmt.Errorf("failed to start container %s: %w", s.name, err)
+	}
+	return s, nil
+}
+
+// serve sends the command with args to the container of s, and waits for its
+// response, at most c.timeout if set. The output of the command is written as
+// runCommand does.
+func (c *Docker) serve(ctx context.Context, s *server, command Command, libraryID string, args []string) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	n := s.seq.Add(1)
+	requestPath := filepath.Join(s.exchangeDir, fmt.Sprintf("request-%d.json", n))
+	responsePath := filepath.Join(s.exchangeDir, fmt.Sprintf("response-%d.json", n))
+	data, err := json.Marshal(&serveRequest{Command: command, Args: args})
+	if err != nil {
+		return err
+	}
+	// The request is renamed into place so that the container never reads
+	// a partially written request.
+	if err := os.WriteFile(requestPath+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("failed to write request: %w", err)
+	}
+	if err := os.Rename(requestPath+".tmp", requestPath); err != nil {
+		return fmt.Errorf("failed to write request: %w", err)
+	}
+	slog.Info("sent request to container", "name", s.name, "command", command, "library", libraryID, "request", requestPath)
+	response, err := c.waitForResponse(ctx, s, responsePath)
+	if errors.Is(err, context.DeadlineExceeded) {
+		c.killServer(s)
+		return timeoutError(command, libraryID, c.timeout)
+	}
+	if err != nil {
+		return err
+	}
+	for _, path := range []string{requestPath, responsePath} {
+		if err := os.Remove(path); err != nil {
+			slog.Warn("fail to remove file", slog.String("name", path), slog.Any("err", err))
+		}
+	}
+	if err := c.writeServeOutput(outputPrefix(libraryID, command), c.logPath(libraryID, command), response); err != nil {
+		return err
+	}
+	if response.ExitCode != 0 {
+		return fmt.Errorf("container %s failed to run %s: exit status %d", s.name, command, response.ExitCode)
+	}
+	return nil
+}
+
+// killServer kills the container of s, which did not answer a request in
+// time, so that the following requests run in their own container.
+func (c *Docker) killServer(s *server) {
+	c.serversMu.Lock()
+	defer c.serversMu.Unlock()
+	for image, running := range c.servers {
+		if running != s {
+			continue
+		}
+		if _, err := c.output("kill", s.name); err != nil {
+			slog.Warn("failed to kill container", "name", s.name, "error", err)
+		}
+		if err := os.RemoveAll(s.exchangeDir); err != nil {
+			slog.Warn("fail to remove exchange directory", "name", s.exchangeDir, "error", err)
+		}
+		c.servers[image] = nil
+	}
+}
+
+// waitForResponse waits until the response at path is written, ctx is done,
+// or the container of s stops.
+func (c *Docker) waitForResponse(ctx context.Context, s *server, path string) (*serveResponse, error) {
+	ticker := time.NewTicker(c.servePollInterval)
+	defer ticker.Stop()
+	lastLivenessCheck := time.Now()
+	for {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			response := &serveResponse{}
+			if err := json.Unmarshal(data, response); err != nil {
+				return nil, fmt.Errorf("failed to parse response %s: %w", path, err)
+			}
+			return response, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+		if time.Since(lastLivenessCheck) >= c.serveLivenessInterval {
+			lastLivenessCheck = time.Now()
+			out, err := c.output("container", "inspect", "--format", "{{.State.Running}}", s.name)
+			if err != nil || strings.TrimSpace(string(out)) != "true" {
+				return nil, fmt.Errorf("container %s stopped before responding", s.name)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// writeServeOutput writes the output of response to the console, each line
+// prefixed with prefix, and appends it to the file at logPath unless it is
+// empty. If c.quiet is set, the output is only written to the console if the
+// command failed.
+func (c *Docker) writeServeOutput(prefix, logPath string, response *serveResponse) error {
+	if logPath != "" {
+		if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+			return fmt.Errorf("failed to make container log directory: %w", err)
+		}
+		logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open container log file: %w", err)
+		}
+		defer logFile.Close()
+		if _, err := logFile.WriteString(response.Output); err != nil {
+			return fmt.Errorf("failed to write container log file: %w", err)
+		}
+	}
+	w := &linePrefixWriter{w: os.Stdout, prefix: prefix}
+	if c.quiet {
+		if response.ExitCode == 0 {
+			return nil
+		}
+		w.w = os.Stderr
+	}
+	if _, err := w.Write([]byte(response.Output)); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// Close stops the containers started to serve requests, if any. It must be
+// called once the Docker instance is no longer used when ReuseContainers is
+// set.
+func (c *Docker) Close() error {
+	c.serversMu.Lock()
+	defer c.serversMu.Unlock()
+	var errs []error
+	for _, s := range c.servers {
+		if s == nil {
+			continue
+		}
+		slog.Info("stopping container serving requests", "name", s.name)
+		if err := c.run(context.Background(), outputPrefix("", CommandServe), "", "stop", s.name); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop container %s: %w", s.name, err))
+		}
+		if err := os.RemoveAll(s.exchangeDir); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	c.servers = nil
+	return errors.Join(errs...)
+}
@@ -0,0 +1,338 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacydocker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+)
+
+// fakeServer fakes the docker CLI running a long-lived container started
+// with the serve command, which answers the requests written to its exchange
+// directory until it is stopped.
+type fakeServer struct {
+	// serve is whether the image supports the serve command.
+	serve bool
+	// respond is whether the container answers requests. If false, the
+	// container stops without answering.
+	respond bool
+
+	mu       sync.Mutex
+	calls    [][]string
+	requests []serveRequest
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func newFakeServer(serve, respond bool) *fakeServer {
+	return &fakeServer{serve: serve, respond: respond}
+}
+
+func (f *fakeServer) run(_ context.Context, _, _ string, args ...string) error {
+	f.mu.Lock()
+	f.calls = append(f.calls, args)
+	f.mu.Unlock()
+	switch {
+	case args[0] == "run" && slices.Contains(args, "--detach"):
+		i := slices.IndexFunc(args, func(arg string) bool { return strings.HasSuffix(arg, ":/exchange") })
+		exchangeDir := strings.TrimSuffix(args[i], ":/exchange")
+		f.stop = make(chan struct{})
+		f.done = make(chan struct{})
+		go f.loop(exchangeDir)
+	case args[0] == "stop":
+		close(f.stop)
+		<-f.done
+	}
+	return nil
+}
+
+func (f *fakeServer) output(args ...string) ([]byte, error) {
+	switch args[0] {
+	case "image":
+		if f.serve {
+			return []byte("true\n"), nil
+		}
+		return []byte("\n"), nil
+	case "container":
+		if f.respond {
+			return []byte("true\n"), nil
+		}
+		return []byte("false\n"), nil
+	case "kill":
+		f.mu.Lock()
+		f.calls = append(f.calls, args)
+		f.mu.Unlock()
+		close(f.stop)
+		<-f.done
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unexpected command %v", args)
+}
+
+// loop answers the requests in exchangeDir, failing the ones of the library
+// with the ID "failing".
+func (f *fakeServer) loop(exchangeDir string) {
+	defer close(f.done)
+	for n := 1; ; {
+		select {
+		case <-f.stop:
+			return
+		case <-time.After(time.Millisecond):
+		}
+		if !f.respond {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(exchangeDir, fmt.Sprintf("request-%d.json", n)))
+		if err != nil {
+			continue
+		}
+		var request serveRequest
+		if err := json.Unmarshal(data, &request); err != nil {
+			panic(err)
+		}
+		f.mu.Lock()
+		f.requests = append(f.requests, request)
+		f.mu.Unlock()
+		response := serveResponse{Output: fmt.Sprintf("handled request %d\n", n)}
+		if slices.ContainsFunc(request.Args, func(arg string) bool { return strings.HasSuffix(arg, "failing") }) {
+			response.ExitCode = 1
+		}
+		data, err = json.Marshal(response)
+		if err != nil {
+			panic(err)
+		}
+		if err := os.WriteFile(filepath.Join(exchangeDir, fmt.Sprintf("response-%d.json", n)), data, 0644); err != nil {
+			panic(err)
+		}
+		n++
+	}
+}
+
+func newServeTestDocker(t *testing.T, f *fakeServer) *Docker {
+	t.Helper()
+	return &Docker{
+		Image:                 "testImage",
+		workRoot:              t.TempDir(),
+		reuseContainers:       true,
+		run:                   f.run,
+		output:                f.output,
+		servePollInterval:     time.Millisecond,
+		serveLivenessInterval: time.Millisecond,
+	}
+}
+
+func newServeTestRequest(d *Docker, repoDir, apiRoot, libraryID string) *GenerateRequest {
+	return &GenerateRequest{
+		ApiRoot:   apiRoot,
+		LibraryID: libraryID,
+		Output:    filepath.Join(d.workRoot, "output", libraryID),
+		RepoDir:   repoDir,
+		State: &legacyconfig.LibrarianState{
+			Libraries: []*legacyconfig.LibraryState{{ID: libraryID}},
+		},
+	}
+}
+
+func TestGenerateWithReusedContainer(t *testing.T) {
+	f := newFakeServer(true, true)
+	d := newServeTestDocker(t, f)
+	repoDir := t.TempDir()
+	apiRoot := t.TempDir()
+	for _, id := range []string{"first", "second", "failing"} {
+		err := d.Generate(t.Context(), newServeTestRequest(d, repoDir, apiRoot, id))
+		if id == "failing" {
+			if err == nil || !strings.Contains(err.Error(), "exit status 1") {
+				t.Errorf("Generate(%q) error = %v, want exit status 1", id, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Generate(%q) error = %v", id, err)
+		}
+	}
+	exchangeDir := filepath.Join(d.workRoot, "serve", "1")
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	librarianDir := filepath.Join(repoDir, legacyconfig.LibrarianDir)
+	generatorInputDir := filepath.Join(repoDir, legacyconfig.GeneratorInputDir)
+	wantCalls := [][]string{
+		{
+			"run", "--detach", "--rm", "--name", fmt.Sprintf("librarian-serve-%d-1", os.Getpid()),
+			"-v", exchangeDir + ":/exchange",
+			"-v", d.workRoot + ":" + d.workRoot,
+			"-v", librarianDir + ":" + librarianDir,
+			"-v", apiRoot + ":" + apiRoot + ":ro",
+			"testImage", "serve", "--exchange=/exchange",
+		},
+		{"stop", fmt.Sprintf("librarian-serve-%d-1", os.Getpid())},
+	}
+	if diff := cmp.Diff(wantCalls, f.calls); diff != "" {
+		t.Errorf("docker calls mismatch (-want +got):\n%s", diff)
+	}
+	var wantRequests []serveRequest
+	for _, id := range []string{"first", "second", "failing"} {
+		wantRequests = append(wantRequests, serveRequest{
+			Command: CommandGenerate,
+			Args: []string{
+				"--librarian=" + librarianDir,
+				"--input=" + generatorInputDir,
+				"--output=" + filepath.Join(d.workRoot, "output", id),
+				"--source=" + apiRoot,
+			},
+		})
+	}
+	if diff := cmp.Diff(wantRequests, f.requests); diff != "" {
+		t.Errorf("requests mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := os.Stat(exchangeDir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("exchange directory should be removed, got %v", err)
+	}
+}
+
+func TestGenerateWithReusedContainerFallback(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		serve bool
+		// otherSource requests the generation of the second library from
+		// a source directory which is not mounted in the container.
+		otherSource bool
+		wantRuns    int
+	}{
+		{
+			name:     "image without serve label",
+			wantRuns: 2,
+		},
+		{
+			name:        "directory not mounted",
+			serve:       true,
+			otherSource: true,
+			wantRuns:    2,
+		},
+		{
+			name:     "directories mounted",
+			serve:    true,
+			wantRuns: 1,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFakeServer(test.serve, true)
+			d := newServeTestDocker(t, f)
+			repoDir := t.TempDir()
+			apiRoot := t.TempDir()
+			if err := d.Generate(t.Context(), newServeTestRequest(d, repoDir, apiRoot, "first")); err != nil {
+				t.Fatal(err)
+			}
+			if test.otherSource {
+				apiRoot = t.TempDir()
+			}
+			if err := d.Generate(t.Context(), newServeTestRequest(d, repoDir, apiRoot, "second")); err != nil {
+				t.Fatal(err)
+			}
+			if err := d.Close(); err != nil {
+				t.Fatal(err)
+			}
+			var runs int
+			for _, call := range f.calls {
+				if call[0] == "run" {
+					runs++
+				}
+			}
+			if runs != test.wantRuns {
+				t.Errorf("got %d docker run calls, want %d: %v", runs, test.wantRuns, f.calls)
+			}
+		})
+	}
+}
+
+func TestGenerateWithReusedContainerStopped(t *testing.T) {
+	f := newFakeServer(true, false)
+	d := newServeTestDocker(t, f)
+	err := d.Generate(t.Context(), newServeTestRequest(d, t.TempDir(), t.TempDir(), "first"))
+	if err == nil || !strings.Contains(err.Error(), "stopped before responding") {
+		t.Errorf("Generate() error = %v, want stopped before responding", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGenerateWithReusedContainerTimeout(t *testing.T) {
+	f := newFakeServer(true, false)
+	d := newServeTestDocker(t, f)
+	d.timeout = 10 * time.Millisecond
+	// The container is never found stopped, so that only the timeout ends
+	// the wait.
+	d.serveLivenessInterval = time.Hour
+	repoDir := t.TempDir()
+	apiRoot := t.TempDir()
+	err := d.Generate(t.Context(), newServeTestRequest(d, repoDir, apiRoot, "first"))
+	if want := `generate container for library "first" timed out after 10ms`; err == nil || err.Error() != want {
+		t.Fatalf("Generate() error = %v, want %q", err, want)
+	}
+	name := fmt.Sprintf("librarian-serve-%d-1", os.Getpid())
+	if !slices.ContainsFunc(f.calls, func(call []string) bool { return slices.Equal(call, []string{"kill", name}) }) {
+		t.Errorf("container %s should be killed, got calls %v", name, f.calls)
+	}
+	// The following requests run in their own container.
+	calls := len(f.calls)
+	if err := d.Generate(t.Context(), newServeTestRequest(d, repoDir, apiRoot, "second")); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.calls[calls:]; len(got) != 1 || got[0][0] != "run" || slices.Contains(got[0], "--detach") {
+		t.Errorf("got calls %v, want a single docker run", got)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServerCovers(t *testing.T) {
+	t.Parallel()
+	s := &server{
+		dirs:         []string{"/work"},
+		readOnlyDirs: []string{"/source"},
+	}
+	for _, test := range []struct {
+		dir      string
+		readOnly bool
+		want     bool
+	}{
+		{dir: "/work", want: true},
+		{dir: "/work/output/foo", want: true},
+		{dir: "/workspace", want: false},
+		{dir: "/other", want: false},
+		{dir: "/source", want: false},
+		{dir: "/source", readOnly: true, want: true},
+		{dir: "/work/pinned", readOnly: true, want: true},
+	} {
+		if got := s.covers(test.dir, test.readOnly); got != test.want {
+			t.Errorf("covers(%q, %t) = %t, want %t", test.dir, test.readOnly, got, test.want)
+		}
+	}
+}
@@ -18,11 +18,14 @@ package legacygithub
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"math/rand/v2"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -30,33 +33,132 @@ import (
 )
 
 const (
-	maxRetries = 3
-	retryDelay = 2 * time.Second
+	// DefaultMaxRetries is the default number of times a request failing with
+	// a transient error is retried.
+	DefaultMaxRetries = 3
+	// DefaultRetryDelay is the default delay before the first retry of a
+	// request. The delay doubles with each further retry.
+	DefaultRetryDelay = 2 * time.Second
+	// maxRetryDelay caps the delay between two attempts of a request.
+	maxRetryDelay = time.Minute
 )
 
+// ErrNotFound is returned, wrapped, by GetRawContent when the file does not
+// exist at the requested ref.
+var ErrNotFound = errors.New("not found")
+
+// newClientRetryDelay is the delay before the first retry of requests made by
+// new clients. It is a variable so it can be shortened during testing.
+var newClientRetryDelay = DefaultRetryDelay
+
 type retryableTransport struct {
-	transport http.RoundTripper
+	transport  http.RoundTripper
+	maxRetries int
+	baseDelay  time.Duration
 }
 
 // RoundTrip implements the http.RoundTripper interface and adds retry logic
-// for transient server errors.
+// for transient server errors and secondary rate limits. Retries are delayed
+// with exponential backoff and jitter, or by the Retry-After header of the
+// response when present. Other errors, such as 404 and 422, are returned
+// without retrying. Requests which are not idempotent are only retried when
+// rejected with a Retry-After header, see canRetry.
 func (t *retryableTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	var resp *http.Response
-	var err error
-	for i := 0; i < maxRetries; i++ {
-		resp, err = t.transport.RoundTrip(req)
-		if err == nil && resp.StatusCode != http.StatusServiceUnavailable {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.transport.RoundTrip(req)
+		if err == nil && !isRetryable(resp) {
 			return resp, nil
 		}
+		if attempt >= t.maxRetries || (req.Body != nil && req.GetBody == nil) || !canRetry(req, resp, err) {
+			return resp, err
+		}
+		delay := t.backoff(attempt)
 		if err != nil {
-			slog.Warn("retrying due to error", "err", err)
+			slog.Warn("retrying due to error", "err", err, "attempt", attempt+1, "delay", delay)
 		} else {
-			slog.Warn("retrying due to status code", "status_code", resp.StatusCode)
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = retryAfter
+			}
+			slog.Warn("retrying due to status code", "status_code", resp.StatusCode, "attempt", attempt+1, "delay", delay)
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
 		}
+	}
+}
+
+// backoff returns the delay before the retry following the given attempt,
+// starting at the base delay and doubling with each attempt. Up to half of
+// the delay is randomized so that concurrent clients do not retry in lockstep.
+func (t *retryableTransport) backoff(attempt int) time.Duration {
+	delay := min(t.baseDelay<<attempt, maxRetryDelay)
+	if delay <= 0 {
+		return 0
+	}
+	jitter := rand.N(delay/2 + 1)
+	return delay - jitter
+}
+
+// isRetryable reports whether resp is a transient failure: a server error or
+// a secondary rate limit.
+func isRetryable(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout, http.StatusTooManyRequests:
+		return true
+	case http.StatusForbidden:
+		// GitHub reports secondary rate limits with a 403 and either a
+		// Retry-After header or no remaining requests.
+		return resp.Header.Get("Retry-After") != "" || resp.Header.Get("X-RateLimit-Remaining") == "0"
+	}
+	return false
+}
+
+// canRetry reports whether req may be sent again after failing with resp or
+// err. Idempotent requests are retried after any transient failure. Other
+// requests, e.g. creating a pull request or a comment, may have taken effect
+// despite the failure, so they are only retried when rate limited with a
+// Retry-After header, which GitHub sends for requests it did not process.
+func canRetry(req *http.Request, resp *http.Response, err error) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	if err != nil {
+		return false
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusForbidden:
+		return resp.Header.Get("Retry-After") != ""
+	}
+	return false
+}
 
-		time.Sleep(retryDelay)
+// parseRetryAfter parses the value of a Retry-After header, given either as
+// a number of seconds or as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
 	}
-	return resp, err
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
 }
 
 // PullRequest is a type alias for the go-github type.
@@ -80,6 +182,7 @@ type Client struct {
 	*github.Client
 	accessToken string
 	repo        *Repository
+	transport   *retryableTransport
 }
 
 // NewClient creates a new Client to interact with GitHub.
@@ -95,7 +198,12 @@ func newClientWithHTTP(accessToken string, repo *Repository, httpClient *http.Cl
 	if transport == nil {
 		transport = http.DefaultTransport
 	}
-	httpClient.Transport = &retryableTransport{transport: transport}
+	retryable := &retryableTransport{
+		transport:  transport,
+		maxRetries: DefaultMaxRetries,
+		baseDelay:  newClientRetryDelay,
+	}
+	httpClient.Transport = retryable
 	client := github.NewClient(httpClient)
 	if repo != nil && repo.BaseURL != "" {
 		baseURL, _ := url.Parse(repo.BaseURL)
@@ -112,9 +220,17 @@ func newClientWithHTTP(accessToken string, repo *Repository, httpClient *http.Cl
 		Client:      client,
 		accessToken: accessToken,
 		repo:        repo,
+		transport:   retryable,
 	}
 }
 
+// SetRetryPolicy sets the number of times a request failing with a transient
+// error is retried, and the delay before the first retry.
+func (c *Client) SetRetryPolicy(maxRetries int, baseDelay time.Duration) {
+	c.transport.maxRetries = maxRetries
+	c.transport.baseDelay = baseDelay
+}
+
 // Token returns the access token for Client.
 func (c *Client) Token() string {
 	return c.accessToken
@@ -175,13 +291,20 @@ func parseSSHRemote(remote string) (*Repository, error) {
 }
 
 // GetRawContent fetches the raw content of a file within a repository repo,
-// identifying the file by path, at a specific commit/tag/branch of ref.
+// identifying the file by path, at a specific commit/tag/branch of ref. If the
+// file does not exist, the returned error wraps ErrNotFound.
 func (c *Client) GetRawContent(ctx context.Context, path, ref string) ([]byte, error) {
 	options := &github.RepositoryContentGetOptions{
 		Ref: ref,
 	}
-	body, _, err := c.Repositories.DownloadContents(ctx, c.repo.Owner, c.repo.Name, path, options)
+	body, resp, err := c.Repositories.DownloadContents(ctx, c.repo.Owner, c.repo.Name, path, options)
 	if err != nil {
+		// DownloadContents lists the directory of the file, which fails with a
+		// 404 if the directory is missing, and succeeds without the file if
+		// only the file is missing.
+		if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusOK) {
+			return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
+		}
 		return nil, err
 	}
 	defer body.Close()
@@ -217,6 +340,16 @@ func (c *Client) CreatePullRequest(ctx context.Context, repo *Repository, remote
 	return pullRequestMetadata, nil
 }
 
+// UpdatePullRequestBody replaces the body of a pull request.
+func (c *Client) UpdatePullRequestBody(ctx context.Context, number int, body string) error {
+	slog.Info("updating PR body", "number", number)
+	slog.Debug("with PR body", "body", body)
+	_, _, err := c.PullRequests.Edit(ctx, c.repo.Owner, c.repo.Name, number, &github.PullRequest{
+		Body: github.Ptr(body),
+	})
+	return err
+}
+
 // GetLabels fetches the labels for an issue.
 func (c *Client) GetLabels(ctx context.Context, number int) ([]string, error) {
 	slog.Info("getting labels", "number", number)
@@ -254,6 +387,25 @@ func (c *Client) AddLabelsToIssue(ctx context.Context, repo *Repository, number
 	return err
 }
 
+// RequestReviewers requests reviews of a pull request. Each reviewer is either
+// a user login, e.g. "octocat", or a team, e.g. "googleapis/yoshi-go", with
+// an optional leading "@". Only the slug of a team is sent to GitHub, the
+// team must belong to the organization owning the repository.
+func (c *Client) RequestReviewers(ctx context.Context, repo *Repository, number int, reviewers []string) error {
+	slog.Info("requesting reviewers", "number", number, "reviewers", reviewers)
+	request := github.ReviewersRequest{}
+	for _, reviewer := range reviewers {
+		reviewer = strings.TrimPrefix(reviewer, "@")
+		if _, team, ok := strings.Cut(reviewer, "/"); ok {
+			request.TeamReviewers = append(request.TeamReviewers, team)
+			continue
+		}
+		request.Reviewers = append(request.Reviewers, reviewer)
+	}
+	_, _, err := c.PullRequests.RequestReviewers(ctx, repo.Owner, repo.Name, number, request)
+	return err
+}
+
 // SearchPullRequests searches for pull requests in the repository using the provided raw query.
 func (c *Client) SearchPullRequests(ctx context.Context, query string) ([]*PullRequest, error) {
 	var prs []*PullRequest
@@ -303,6 +455,19 @@ func (c *Client) CreateRelease(ctx context.Context, tagName, name, body, commiti
 	return r, err
 }
 
+// GetReleaseByTag returns the release of the given tag, or nil if the tag
+// has no release.
+func (c *Client) GetReleaseByTag(ctx context.Context, tagName string) (*github.RepositoryRelease, error) {
+	r, resp, err := c.Repositories.GetReleaseByTag(ctx, c.repo.Owner, c.repo.Name, tagName)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
 // CreateIssueComment adds a comment to the issue number provided.
 func (c *Client) CreateIssueComment(ctx context.Context, number int, comment string) error {
 	_, _, err := c.Issues.CreateComment(ctx, c.repo.Owner, c.repo.Name, number, &github.IssueComment{
@@ -353,6 +518,18 @@ func (c *Client) FindMergedPullRequestsWithLabel(ctx context.Context, owner, rep
 	return allPRs, nil
 }
 
+// TagExists reports whether the repository has a tag with the given name.
+func (c *Client) TagExists(ctx context.Context, tagName string) (bool, error) {
+	_, resp, err := c.Git.GetRef(ctx, c.repo.Owner, c.repo.Name, "tags/"+tagName)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
 // CreateTag creates a lightweight tag in the repository at the given commit SHA.
 // This does NOT create a release, just the tag.
 func (c *Client) CreateTag(ctx context.Context, tagName, commitSHA string) error {
@@ -366,6 +543,69 @@ func (c *Client) CreateTag(ctx context.Context, tagName, commitSHA string) error
 	return err
 }
 
+// CreateAnnotatedTag creates an annotated tag with the given message in the
+// repository at the given commit SHA.
+// This does NOT create a release, just the tag.
+func (c *Client) CreateAnnotatedTag(ctx context.Context, tagName, message, commitSHA string) error {
+	slog.Info("creating annotated tag", "tag", tagName, "commit", commitSHA)
+	tag, _, err := c.Git.CreateTag(ctx, c.repo.Owner, c.repo.Name, &github.Tag{
+		Tag:     github.Ptr(tagName),
+		Message: github.Ptr(message),
+		Object:  &github.GitObject{SHA: github.Ptr(commitSHA), Type: github.Ptr("commit")},
+	})
+	if err != nil {
+		return err
+	}
+	tagRef := &github.Reference{
+		Ref:    github.Ptr("refs/tags/" + tagName),
+		Object: &github.GitObject{SHA: tag.SHA, Type: github.Ptr("tag")},
+	}
+	_, _, err = c.Git.CreateRef(ctx, c.repo.Owner, c.repo.Name, tagRef)
+	return err
+}
+
+// CreateBranch creates a branch in the repository at the given commit SHA.
+func (c *Client) CreateBranch(ctx context.Context, branch, commitSHA string) error {
+	slog.Info("creating branch", "branch", branch, "commit", commitSHA)
+	ref := &github.Reference{
+		Ref:    github.Ptr("refs/heads/" + branch),
+		Object: &github.GitObject{SHA: github.Ptr(commitSHA)},
+	}
+	_, _, err := c.Git.CreateRef(ctx, c.repo.Owner, c.repo.Name, ref)
+	return err
+}
+
+// GetBranchHead returns the SHA of the commit at the head of the given branch.
+func (c *Client) GetBranchHead(ctx context.Context, branch string) (string, error) {
+	ref, _, err := c.Git.GetRef(ctx, c.repo.Owner, c.repo.Name, "heads/"+branch)
+	if err != nil {
+		return "", err
+	}
+	return ref.GetObject().GetSHA(), nil
+}
+
+// UpdateFile commits content to the file at path on the given branch, with
+// the given commit message. The file is created if it does not exist.
+func (c *Client) UpdateFile(ctx context.Context, branch, path, message string, content []byte) error {
+	slog.Info("updating file", "path", path, "branch", branch)
+	options := &github.RepositoryContentFileOptions{
+		Message: github.Ptr(message),
+		Content: content,
+		Branch:  github.Ptr(branch),
+	}
+	file, _, resp, err := c.Repositories.GetContents(ctx, c.repo.Owner, c.repo.Name, path, &github.RepositoryContentGetOptions{
+		Ref: branch,
+	})
+	if err != nil && (resp == nil || resp.StatusCode != http.StatusNotFound) {
+		return err
+	}
+	if err == nil {
+		options.SHA = github.Ptr(file.GetSHA())
+	}
+	_, _, err = c.Repositories.UpdateFile(ctx, c.repo.Owner, c.repo.Name, path, options)
+	return err
+}
+
 // ClosePullRequest closes the pull request specified by pull request number.
 func (c *Client) ClosePullRequest(ctx context.Context, number int) error {
 	slog.Info("closing pull request", slog.Int("number", number))
@@ -16,10 +16,12 @@ package legacygithub
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
 	"strings"
 	"testing"
 	"time"
@@ -28,6 +30,11 @@ import (
 	"github.com/google/go-github/v69/github"
 )
 
+func TestMain(m *testing.M) {
+	newClientRetryDelay = time.Millisecond
+	os.Exit(m.Run())
+}
+
 func TestToken(t *testing.T) {
 	t.Parallel()
 	want := "fake-token"
@@ -46,6 +53,7 @@ func TestGetRawContent(t *testing.T) {
 		wantContent    []byte
 		wantErr        bool
 		wantErrSubstr  string
+		wantNotFound   bool
 		wantHTTPMethod string
 		wantURLPath    string
 	}{
@@ -73,6 +81,18 @@ func TestGetRawContent(t *testing.T) {
 			},
 			wantErr:        true,
 			wantErrSubstr:  "404",
+			wantNotFound:   true,
+			wantHTTPMethod: http.MethodGet,
+			wantURLPath:    "/repos/owner/repo/contents/path/to/file",
+		},
+		{
+			name: "File Not Found",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `[{"name":"other", "download_url": "http://%s/download"}]`, r.Host)
+			},
+			wantErr:        true,
+			wantErrSubstr:  "no file named file",
+			wantNotFound:   true,
 			wantHTTPMethod: http.MethodGet,
 			wantURLPath:    "/repos/owner/repo/contents/path/to/file",
 		},
@@ -107,6 +127,9 @@ func TestGetRawContent(t *testing.T) {
 				if !strings.Contains(err.Error(), test.wantErrSubstr) {
 					t.Errorf("GetRawContent() err = %v, want error containing %q", err, test.wantErrSubstr)
 				}
+				if got := errors.Is(err, ErrNotFound); got != test.wantNotFound {
+					t.Errorf("errors.Is(err, ErrNotFound) = %t, want %t", got, test.wantNotFound)
+				}
 			} else {
 				if err != nil {
 					t.Errorf("GetRawContent() err = %v, want nil", err)
@@ -409,6 +432,76 @@ func TestAddLabelsToIssue(t *testing.T) {
 	}
 }
 
+func TestRequestReviewers(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name          string
+		handler       http.HandlerFunc
+		reviewers     []string
+		wantErr       bool
+		wantErrSubstr string
+	}{
+		{
+			name: "request users and teams",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("unexpected method: got %s, want %s", r.Method, http.MethodPost)
+				}
+				wantPath := "/repos/owner/repo/pulls/7/requested_reviewers"
+				if r.URL.Path != wantPath {
+					t.Errorf("unexpected path: got %s, want %s", r.URL.Path, wantPath)
+				}
+				var got github.ReviewersRequest
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Fatalf("failed to decode request body: %v", err)
+				}
+				want := github.ReviewersRequest{
+					Reviewers:     []string{"octocat", "hubot"},
+					TeamReviewers: []string{"yoshi-go"},
+				}
+				if diff := cmp.Diff(want, got); diff != "" {
+					t.Errorf("unexpected body (-want +got):\n%s", diff)
+				}
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprint(w, `{"number": 7}`)
+			},
+			reviewers: []string{"octocat", "@googleapis/yoshi-go", "@hubot"},
+		},
+		{
+			name:          "GitHub API error",
+			handler:       func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) },
+			reviewers:     []string{"octocat"},
+			wantErr:       true,
+			wantErrSubstr: "500",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(test.handler)
+			defer server.Close()
+
+			repo := &Repository{Owner: "owner", Name: "repo"}
+			client := newClientWithHTTP("fake-token", repo, server.Client())
+			client.BaseURL, _ = url.Parse(server.URL + "/")
+
+			err := client.RequestReviewers(t.Context(), repo, 7, test.reviewers)
+
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("RequestReviewers() should return an error")
+				}
+				if !strings.Contains(err.Error(), test.wantErrSubstr) {
+					t.Errorf("RequestReviewers() err = %v, want error containing %q", err, test.wantErrSubstr)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("RequestReviewers() err = %v, want nil", err)
+			}
+		})
+	}
+}
+
 func TestGetLabels(t *testing.T) {
 	t.Parallel()
 	for _, test := range []struct {
@@ -486,6 +579,35 @@ func TestGetLabels(t *testing.T) {
 	}
 }
 
+func TestUpdatePullRequestBody(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("unexpected method: got %s, want %s", r.Method, http.MethodPatch)
+		}
+		wantPath := "/repos/owner/repo/pulls/7"
+		if r.URL.Path != wantPath {
+			t.Errorf("unexpected path: got %s, want %s", r.URL.Path, wantPath)
+		}
+		var pr PullRequest
+		if err := json.NewDecoder(r.Body).Decode(&pr); err != nil {
+			t.Fatalf("failed to decode request body: %v", err)
+		}
+		if diff := cmp.Diff("new body", pr.GetBody()); diff != "" {
+			t.Errorf("UpdatePullRequestBody() request body mismatch (-want +got):\n%s", diff)
+		}
+		fmt.Fprint(w, `{"number": 7}`)
+	}))
+	defer server.Close()
+
+	repo := &Repository{Owner: "owner", Name: "repo"}
+	client := newClientWithHTTP("fake-token", repo, server.Client())
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	if err := client.UpdatePullRequestBody(t.Context(), 7, "new body"); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestReplaceLabels(t *testing.T) {
 	t.Parallel()
 	for _, test := range []struct {
@@ -805,6 +927,59 @@ func TestCreateRelease(t *testing.T) {
 	}
 }
 
+func TestGetReleaseByTag(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name        string
+		handler     http.HandlerFunc
+		wantRelease *github.RepositoryRelease
+		wantErr     bool
+	}{
+		{
+			name: "Success",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				wantPath := "/repos/owner/repo/releases/tags/v1.0.0"
+				if r.URL.Path != wantPath {
+					t.Errorf("unexpected path: got %s, want %s", r.URL.Path, wantPath)
+				}
+				fmt.Fprint(w, `{"tag_name": "v1.0.0"}`)
+			},
+			wantRelease: &github.RepositoryRelease{TagName: github.Ptr("v1.0.0")},
+		},
+		{
+			name:    "Not found",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) },
+		},
+		{
+			name:    "API Error",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) },
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(test.handler)
+			defer server.Close()
+
+			repo := &Repository{Owner: "owner", Name: "repo"}
+			client := newClientWithHTTP("fake-token", repo, server.Client())
+			client.BaseURL, _ = url.Parse(server.URL + "/")
+
+			release, err := client.GetReleaseByTag(t.Context(), "v1.0.0")
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("GetReleaseByTag() err = nil, expected error")
+				}
+			} else if err != nil {
+				t.Errorf("GetReleaseByTag() err = %v, want nil", err)
+			}
+			if diff := cmp.Diff(test.wantRelease, release); diff != "" {
+				t.Errorf("GetReleaseByTag() release mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestCreateIssueComment(t *testing.T) {
 	t.Parallel()
 	for _, test := range []struct {
@@ -938,6 +1113,59 @@ func TestFindMergedPullRequestsWithPendingReleaseLabel(t *testing.T) {
 		})
 	}
 }
+func TestTagExists(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name    string
+		handler http.HandlerFunc
+		want    bool
+		wantErr bool
+	}{
+		{
+			name: "Exists",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				wantPath := "/repos/owner/repo/git/ref/tags/v1.2.3"
+				if r.URL.Path != wantPath {
+					t.Errorf("unexpected path: got %s, want %s", r.URL.Path, wantPath)
+				}
+				fmt.Fprint(w, `{"ref": "refs/tags/v1.2.3"}`)
+			},
+			want: true,
+		},
+		{
+			name:    "Not found",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) },
+		},
+		{
+			name:    "API Error",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) },
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(test.handler)
+			defer server.Close()
+
+			repo := &Repository{Owner: "owner", Name: "repo"}
+			client := newClientWithHTTP("fake-token", repo, server.Client())
+			client.BaseURL, _ = url.Parse(server.URL + "/")
+
+			got, err := client.TagExists(t.Context(), "v1.2.3")
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("TagExists() err = nil, expected error")
+				}
+			} else if err != nil {
+				t.Errorf("TagExists() err = %v, want nil", err)
+			}
+			if got != test.want {
+				t.Errorf("TagExists() = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
 func TestCreateTag(t *testing.T) {
 	t.Parallel()
 	for _, test := range []struct {
@@ -1000,17 +1228,261 @@ func TestCreateTag(t *testing.T) {
 	}
 }
 
+func TestCreateAnnotatedTag(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name    string
+		handler http.HandlerFunc
+		wantErr bool
+	}{
+		{
+			name: "Success",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("unexpected method: got %s, want %s", r.Method, http.MethodPost)
+				}
+				switch r.URL.Path {
+				case "/repos/owner/repo/git/tags":
+					var req struct {
+						Tag     string `json:"tag"`
+						Message string `json:"message"`
+						Object  string `json:"object"`
+						Type    string `json:"type"`
+					}
+					if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+						t.Fatalf("failed to decode request body: %v", err)
+					}
+					if req.Tag != "v1.2.3" {
+						t.Errorf("unexpected tag: got %s, want %s", req.Tag, "v1.2.3")
+					}
+					if req.Message != "release notes" {
+						t.Errorf("unexpected message: got %s, want %s", req.Message, "release notes")
+					}
+					if req.Object != "abcdef123456" || req.Type != "commit" {
+						t.Errorf("unexpected object: got %s %s, want commit abcdef123456", req.Type, req.Object)
+					}
+					fmt.Fprint(w, `{"tag": "v1.2.3", "sha": "fedcba654321"}`)
+				case "/repos/owner/repo/git/refs":
+					var req struct {
+						Ref string `json:"ref"`
+						SHA string `json:"sha"`
+					}
+					if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+						t.Fatalf("failed to decode request body: %v", err)
+					}
+					if req.Ref != "refs/tags/v1.2.3" {
+						t.Errorf("unexpected ref: got %s, want %s", req.Ref, "refs/tags/v1.2.3")
+					}
+					if req.SHA != "fedcba654321" {
+						t.Errorf("unexpected sha: got %s, want %s", req.SHA, "fedcba654321")
+					}
+					fmt.Fprint(w, `{"ref": "refs/tags/v1.2.3"}`)
+				default:
+					t.Errorf("unexpected path: %s", r.URL.Path)
+				}
+			},
+		},
+		{
+			name:    "API Error",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) },
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(test.handler)
+			defer server.Close()
+
+			repo := &Repository{Owner: "owner", Name: "repo"}
+			client := newClientWithHTTP("fake-token", repo, server.Client())
+			client.BaseURL, _ = url.Parse(server.URL + "/")
+
+			err := client.CreateAnnotatedTag(t.Context(), "v1.2.3", "release notes", "abcdef123456")
+
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("CreateAnnotatedTag() err = nil, expected error")
+				}
+			} else if err != nil {
+				t.Errorf("CreateAnnotatedTag() err = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestCreateBranch(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name    string
+		handler http.HandlerFunc
+		wantErr bool
+	}{
+		{
+			name: "Success",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("unexpected method: got %s, want %s", r.Method, http.MethodPost)
+				}
+				wantPath := "/repos/owner/repo/git/refs"
+				if r.URL.Path != wantPath {
+					t.Errorf("unexpected path: got %s, want %s", r.URL.Path, wantPath)
+				}
+				var req struct {
+					Ref string `json:"ref"`
+					SHA string `json:"sha"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Fatalf("failed to decode request body: %v", err)
+				}
+				if req.Ref != "refs/heads/post-release" {
+					t.Errorf("unexpected ref: got %s, want %s", req.Ref, "refs/heads/post-release")
+				}
+				if req.SHA != "abcdef123456" {
+					t.Errorf("unexpected sha: got %s, want %s", req.SHA, "abcdef123456")
+				}
+				fmt.Fprint(w, `{"ref": "refs/heads/post-release"}`)
+			},
+		},
+		{
+			name:    "API Error",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) },
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(test.handler)
+			defer server.Close()
+
+			repo := &Repository{Owner: "owner", Name: "repo"}
+			client := newClientWithHTTP("fake-token", repo, server.Client())
+			client.BaseURL, _ = url.Parse(server.URL + "/")
+
+			err := client.CreateBranch(t.Context(), "post-release", "abcdef123456")
+
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("CreateBranch() err = nil, expected error")
+				}
+			} else if err != nil {
+				t.Errorf("CreateBranch() err = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestUpdateFile(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name       string
+		getStatus  int
+		putStatus  int
+		wantPutSHA string
+		wantErr    bool
+	}{
+		{
+			name:       "update existing file",
+			getStatus:  http.StatusOK,
+			putStatus:  http.StatusOK,
+			wantPutSHA: "fedcba654321",
+		},
+		{
+			name:      "create missing file",
+			getStatus: http.StatusNotFound,
+			putStatus: http.StatusCreated,
+		},
+		{
+			name:      "get error",
+			getStatus: http.StatusInternalServerError,
+			wantErr:   true,
+		},
+		{
+			name:       "update error",
+			getStatus:  http.StatusOK,
+			putStatus:  http.StatusConflict,
+			wantPutSHA: "fedcba654321",
+			wantErr:    true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				wantPath := "/repos/owner/repo/contents/versions.txt"
+				if r.URL.Path != wantPath {
+					t.Errorf("unexpected path: got %s, want %s", r.URL.Path, wantPath)
+				}
+				switch r.Method {
+				case http.MethodGet:
+					if got := r.URL.Query().Get("ref"); got != "main" {
+						t.Errorf("unexpected ref: got %s, want %s", got, "main")
+					}
+					w.WriteHeader(test.getStatus)
+					if test.getStatus == http.StatusOK {
+						fmt.Fprint(w, `{"type": "file", "path": "versions.txt", "sha": "fedcba654321"}`)
+					}
+				case http.MethodPut:
+					var req struct {
+						Message string `json:"message"`
+						Content []byte `json:"content"`
+						SHA     string `json:"sha"`
+						Branch  string `json:"branch"`
+					}
+					if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+						t.Fatalf("failed to decode request body: %v", err)
+					}
+					want := struct {
+						Message string `json:"message"`
+						Content []byte `json:"content"`
+						SHA     string `json:"sha"`
+						Branch  string `json:"branch"`
+					}{
+						Message: "chore: update versions",
+						Content: []byte("lib:1.2.3\n"),
+						SHA:     test.wantPutSHA,
+						Branch:  "main",
+					}
+					if diff := cmp.Diff(want, req); diff != "" {
+						t.Errorf("request mismatch (-want +got):\n%s", diff)
+					}
+					w.WriteHeader(test.putStatus)
+					fmt.Fprint(w, `{}`)
+				default:
+					t.Errorf("unexpected method: %s", r.Method)
+				}
+			}))
+			defer server.Close()
+
+			repo := &Repository{Owner: "owner", Name: "repo"}
+			client := newClientWithHTTP("fake-token", repo, server.Client())
+			client.BaseURL, _ = url.Parse(server.URL + "/")
+
+			err := client.UpdateFile(t.Context(), "main", "versions.txt", "chore: update versions", []byte("lib:1.2.3\n"))
+
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("UpdateFile() err = nil, expected error")
+				}
+			} else if err != nil {
+				t.Errorf("UpdateFile() err = %v, want nil", err)
+			}
+		})
+	}
+}
+
 func TestRetryableTransport(t *testing.T) {
 	t.Parallel()
 	for _, test := range []struct {
 		name             string
+		maxRetries       int
 		handler          func(w http.ResponseWriter, r *http.Request, requestCount int)
 		wantStatusCode   int
 		wantErr          bool
 		wantRequestCount int
+		method           string
 	}{
 		{
-			name: "Success after retries",
+			name:       "Success after retries",
+			maxRetries: DefaultMaxRetries,
 			handler: func(w http.ResponseWriter, r *http.Request, requestCount int) {
 				if requestCount < 3 {
 					w.WriteHeader(http.StatusServiceUnavailable)
@@ -1023,13 +1495,130 @@ func TestRetryableTransport(t *testing.T) {
 			wantRequestCount: 3,
 		},
 		{
-			name: "Failure after all retries",
+			name:       "Failure after all retries",
+			maxRetries: DefaultMaxRetries,
 			handler: func(w http.ResponseWriter, r *http.Request, _ int) {
 				w.WriteHeader(http.StatusServiceUnavailable)
 			},
 			wantStatusCode:   http.StatusServiceUnavailable,
 			wantErr:          true,
-			wantRequestCount: 3,
+			wantRequestCount: 4,
+		},
+		{
+			name:       "Custom retry count",
+			maxRetries: 1,
+			handler: func(w http.ResponseWriter, r *http.Request, _ int) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			wantStatusCode:   http.StatusBadGateway,
+			wantErr:          true,
+			wantRequestCount: 2,
+		},
+		{
+			name:       "No retries",
+			maxRetries: 0,
+			handler: func(w http.ResponseWriter, r *http.Request, _ int) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			wantStatusCode:   http.StatusInternalServerError,
+			wantErr:          true,
+			wantRequestCount: 1,
+		},
+		{
+			name:       "Secondary rate limit with Retry-After",
+			maxRetries: DefaultMaxRetries,
+			handler: func(w http.ResponseWriter, r *http.Request, requestCount int) {
+				if requestCount < 2 {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(http.StatusForbidden)
+				} else {
+					w.WriteHeader(http.StatusOK)
+				}
+			},
+			wantStatusCode:   http.StatusOK,
+			wantRequestCount: 2,
+		},
+		{
+			name:       "Rate limit without remaining requests",
+			maxRetries: DefaultMaxRetries,
+			handler: func(w http.ResponseWriter, r *http.Request, requestCount int) {
+				if requestCount < 2 {
+					w.Header().Set("X-RateLimit-Remaining", "0")
+					w.WriteHeader(http.StatusTooManyRequests)
+				} else {
+					w.WriteHeader(http.StatusOK)
+				}
+			},
+			wantStatusCode:   http.StatusOK,
+			wantRequestCount: 2,
+		},
+		{
+			name:       "Forbidden is not retried",
+			maxRetries: DefaultMaxRetries,
+			handler: func(w http.ResponseWriter, r *http.Request, _ int) {
+				w.WriteHeader(http.StatusForbidden)
+			},
+			wantStatusCode:   http.StatusForbidden,
+			wantErr:          true,
+			wantRequestCount: 1,
+		},
+		{
+			name:       "Not found is not retried",
+			maxRetries: DefaultMaxRetries,
+			handler: func(w http.ResponseWriter, r *http.Request, _ int) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+			wantStatusCode:   http.StatusNotFound,
+			wantErr:          true,
+			wantRequestCount: 1,
+		},
+		{
+			name:       "Server error of POST is not retried",
+			maxRetries: DefaultMaxRetries,
+			method:     http.MethodPost,
+			handler: func(w http.ResponseWriter, r *http.Request, _ int) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			wantStatusCode:   http.StatusServiceUnavailable,
+			wantErr:          true,
+			wantRequestCount: 1,
+		},
+		{
+			name:       "Rate limit of POST without Retry-After is not retried",
+			maxRetries: DefaultMaxRetries,
+			method:     http.MethodPost,
+			handler: func(w http.ResponseWriter, r *http.Request, _ int) {
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			wantStatusCode:   http.StatusTooManyRequests,
+			wantErr:          true,
+			wantRequestCount: 1,
+		},
+		{
+			name:       "Rate limit of POST with Retry-After",
+			maxRetries: DefaultMaxRetries,
+			method:     http.MethodPost,
+			handler: func(w http.ResponseWriter, r *http.Request, requestCount int) {
+				if requestCount < 2 {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(http.StatusTooManyRequests)
+				} else {
+					w.WriteHeader(http.StatusOK)
+				}
+			},
+			wantStatusCode:   http.StatusOK,
+			wantRequestCount: 2,
+		},
+		{
+			name:       "Unprocessable entity is not retried",
+			maxRetries: DefaultMaxRetries,
+			handler: func(w http.ResponseWriter, r *http.Request, _ int) {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+			},
+			wantStatusCode:   http.StatusUnprocessableEntity,
+			wantErr:          true,
+			wantRequestCount: 1,
 		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
@@ -1043,8 +1632,13 @@ func TestRetryableTransport(t *testing.T) {
 			repo := &Repository{Owner: "owner", Name: "repo"}
 			client := newClientWithHTTP("fake-token", repo, server.Client())
 			client.BaseURL, _ = url.Parse(server.URL + "/")
+			client.SetRetryPolicy(test.maxRetries, time.Millisecond)
 
-			req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL, nil)
+			method := test.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req, err := http.NewRequestWithContext(t.Context(), method, server.URL, nil)
 			if err != nil {
 				t.Fatalf("http.NewRequestWithContext() failed: %v", err)
 			}
@@ -1071,6 +1665,81 @@ func TestRetryableTransport(t *testing.T) {
 	}
 }
 
+func TestRetryableTransportResendsBody(t *testing.T) {
+	t.Parallel()
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var release github.RepositoryRelease
+		if err := json.NewDecoder(r.Body).Decode(&release); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		bodies = append(bodies, release.GetTagName())
+		if len(bodies) < 2 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, `{"tag_name": "v1.0.0"}`)
+	}))
+	defer server.Close()
+
+	repo := &Repository{Owner: "owner", Name: "repo"}
+	client := newClientWithHTTP("fake-token", repo, server.Client())
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	if _, err := client.CreateRelease(t.Context(), "v1.0.0", "v1.0.0", "body", "main"); err != nil {
+		t.Fatalf("CreateRelease() err = %v, want nil", err)
+	}
+	if diff := cmp.Diff([]string{"v1.0.0", "v1.0.0"}, bodies); diff != "" {
+		t.Errorf("request bodies mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	t.Parallel()
+	transport := &retryableTransport{baseDelay: 2 * time.Second}
+	for _, test := range []struct {
+		attempt int
+		wantMin time.Duration
+		wantMax time.Duration
+	}{
+		{attempt: 0, wantMin: time.Second, wantMax: 2 * time.Second},
+		{attempt: 1, wantMin: 2 * time.Second, wantMax: 4 * time.Second},
+		{attempt: 2, wantMin: 4 * time.Second, wantMax: 8 * time.Second},
+		{attempt: 10, wantMin: maxRetryDelay / 2, wantMax: maxRetryDelay},
+	} {
+		t.Run(fmt.Sprint(test.attempt), func(t *testing.T) {
+			got := transport.backoff(test.attempt)
+			if got < test.wantMin || got > test.wantMax {
+				t.Errorf("backoff(%d) = %v, want between %v and %v", test.attempt, got, test.wantMin, test.wantMax)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "seconds", value: "30", want: 30 * time.Second, wantOK: true},
+		{name: "date in the past", value: "Mon, 02 Jan 2006 15:04:05 GMT", want: 0, wantOK: true},
+		{name: "empty", value: ""},
+		{name: "negative", value: "-1"},
+		{name: "invalid", value: "soon"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(test.value)
+			if got != test.want || ok != test.wantOK {
+				t.Errorf("parseRetryAfter(%q) = %v, %t, want %v, %t", test.value, got, ok, test.want, test.wantOK)
+			}
+		})
+	}
+}
+
 func TestNewClient(t *testing.T) {
 
 	t.Parallel()
@@ -1109,3 +1778,54 @@ func TestNewClient(t *testing.T) {
 		})
 	}
 }
+
+func TestGetBranchHead(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "Success",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				wantPath := "/repos/owner/repo/git/ref/heads/release-v1"
+				if r.URL.Path != wantPath {
+					t.Errorf("unexpected path: got %s, want %s", r.URL.Path, wantPath)
+				}
+				fmt.Fprint(w, `{"ref": "refs/heads/release-v1", "object": {"sha": "abcdef123456", "type": "commit"}}`)
+			},
+			want: "abcdef123456",
+		},
+		{
+			name:    "Not found",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) },
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(test.handler)
+			defer server.Close()
+
+			repo := &Repository{Owner: "owner", Name: "repo"}
+			client := newClientWithHTTP("fake-token", repo, server.Client())
+			client.BaseURL, _ = url.Parse(server.URL + "/")
+
+			got, err := client.GetBranchHead(t.Context(), "release-v1")
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("GetBranchHead() err = nil, expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("GetBranchHead() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"regexp"
 	"strings"
 	"time"
@@ -39,6 +40,9 @@ const (
 	endNestedCommit   = "END_NESTED_COMMIT"
 	breakingChangeKey = "BREAKING CHANGE"
 	sourceLinkKey     = "Source-Link"
+	fixesVersionKey   = "Fixes-Version"
+	libraryIDsKey     = "Library-IDs"
+	affectsKey        = "Affects"
 )
 
 var (
@@ -53,6 +57,9 @@ var (
 	// For a generation PR, each commit is expected to have the libraryID in brackets
 	// ('[]').
 	libraryIDRegex = regexp.MustCompile(`\[([^]]+)]`)
+	// affectsRegex matches a line of a commit body listing the IDs of the
+	// libraries affected by the commit, e.g. "Affects: a, b, c".
+	affectsRegex = regexp.MustCompile(`^` + affectsKey + `:\s*(.*\S)`)
 )
 
 // ErrEmptyCommitMessage returns when the commit message is empty.
@@ -63,6 +70,9 @@ var ErrEmptyCommitMessage = errors.New("empty commit message")
 type ConventionalCommit struct {
 	// Type is the type of change (e.g., "feat", "fix", "docs").
 	Type string `yaml:"type" json:"type"`
+	// Scope is the optional scope of the change given in parentheses after
+	// the type, e.g. "parser" in "feat(parser): ...".
+	Scope string `yaml:"-" json:"-"`
 	// Subject is the short summary of the change.
 	Subject string `yaml:"subject" json:"subject"`
 	// Body is the long-form description of the change.
@@ -80,6 +90,8 @@ type ConventionalCommit struct {
 	CommitHash string `yaml:"-" json:"commit_hash,omitempty"`
 	// When is the timestamp of the commit.
 	When time.Time `yaml:"-" json:"-"`
+	// Author is the author of the commit.
+	Author Signature `yaml:"-" json:"-"`
 }
 
 // parsedHeader holds the result of parsing the header line.
@@ -119,6 +131,14 @@ func (c *ConventionalCommit) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// ParseOptions configures how ParseCommitsWithOptions parses commit messages.
+type ParseOptions struct {
+	// Affects enables the attribution of a commit without a Library-IDs
+	// footer to the libraries listed by an "Affects: a, b, c" line in its
+	// body or footers, as if the commit had a "Library-IDs: a,b,c" footer.
+	Affects bool
+}
+
 // ParseCommits parses a commit message into a slice of ConventionalCommit structs.
 //
 // It supports a top-level commit wrapped in BEGIN_COMMIT and END_COMMIT (BEGIN_COMMIT_OVERRIDE and
@@ -128,10 +148,18 @@ func (c *ConventionalCommit) MarshalJSON() ([]byte, error) {
 // The message can also contain multiple nested commits, each wrapped in
 // BEGIN_NESTED_COMMIT and END_NESTED_COMMIT markers.
 //
-// Malformed override or nested blocks (e.g., with a missing end marker) are
-// ignored. Any commit part that is found but fails to parse as a valid
-// conventional commit is logged and skipped.
+// A malformed override block (e.g., with a missing end marker) is ignored. A
+// nested block with a missing end marker extends to the next
+// BEGIN_NESTED_COMMIT marker or to the end of the message, and is parsed as a
+// single nested commit. Any commit part that is found but fails to parse as a
+// valid conventional commit is logged and skipped.
 func ParseCommits(commit *Commit, libraryID string) ([]*ConventionalCommit, error) {
+	return ParseCommitsWithOptions(commit, libraryID, ParseOptions{})
+}
+
+// ParseCommitsWithOptions is like ParseCommits, with the parsing configured by
+// opts.
+func ParseCommitsWithOptions(commit *Commit, libraryID string, opts ParseOptions) ([]*ConventionalCommit, error) {
 	message := commit.Message
 	if strings.TrimSpace(message) == "" {
 		return nil, ErrEmptyCommitMessage
@@ -141,7 +169,7 @@ func ParseCommits(commit *Commit, libraryID string) ([]*ConventionalCommit, erro
 	var commits []*ConventionalCommit
 	seen := make(map[string]bool)
 	for _, part := range extractCommitParts(message) {
-		simpleCommits, err := parseSimpleCommit(part, commit, libraryID)
+		simpleCommits, err := parseSimpleCommit(part, commit, libraryID, opts)
 		if err != nil {
 			slog.Warn("failed to parse commit part", "commit", part.message, "error", err)
 			continue
@@ -203,8 +231,10 @@ func extractCommitParts(message string) []commitPart {
 		nestedPart := parts[i]
 		endIndex := strings.Index(nestedPart, endNestedCommit)
 		if endIndex == -1 {
-			// Malformed, ignore.
-			continue
+			// Unterminated, e.g. truncated by a squash merge: keep the
+			// remainder of the part as a single nested commit.
+			slog.Warn("nested commit without end marker", "commit", strings.TrimSpace(nestedPart))
+			endIndex = len(nestedPart)
 		}
 		commitStr := strings.TrimSpace(nestedPart[:endIndex])
 		if commitStr == "" {
@@ -220,7 +250,7 @@ func extractCommitParts(message string) []commitPart {
 
 // parseSimpleCommit parses a simple commit message and returns a slice of ConventionalCommit.
 // A simple commit message is commit that does not include override or nested commits.
-func parseSimpleCommit(commitPart commitPart, commit *Commit, libraryID string) ([]*ConventionalCommit, error) {
+func parseSimpleCommit(commitPart commitPart, commit *Commit, libraryID string, opts ParseOptions) ([]*ConventionalCommit, error) {
 	trimmedMessage := strings.TrimSpace(commitPart.message)
 	if trimmedMessage == "" {
 		return nil, fmt.Errorf("empty commit message")
@@ -236,11 +266,21 @@ func parseSimpleCommit(commitPart commitPart, commit *Commit, libraryID string)
 	var subjects [][]string
 	// Hold the body of each commit.
 	var body [][]string
+	// Hold the library IDs listed by the Affects line of each commit.
+	var affects []string
 	// Whether it has seen an empty line.
 	var foundSeparator bool
 	// If the body lines have multiple headers, separate them into different conventional commit, all associated with
 	// the same commit sha.
 	for _, bodyLine := range bodyLines {
+		if opts.Affects && len(commits) > 0 {
+			if matches := affectsRegex.FindStringSubmatch(strings.TrimSpace(bodyLine)); matches != nil {
+				if affects[len(affects)-1] == "" {
+					affects[len(affects)-1] = matches[1]
+				}
+				continue
+			}
+		}
 		header, ok := parseHeader(bodyLine)
 		if !ok {
 			if len(commits) == 0 {
@@ -268,6 +308,7 @@ func parseSimpleCommit(commitPart commitPart, commit *Commit, libraryID string)
 
 		subjects = append(subjects, []string{})
 		body = append(body, []string{})
+		affects = append(affects, "")
 		foundSeparator = false
 		// If there is an association for the commit (i.e. the commit has '[LIBRARY_ID]' in the
 		// description), then use that libraryID. Otherwise, use the libraryID passed as the default.
@@ -278,6 +319,7 @@ func parseSimpleCommit(commitPart commitPart, commit *Commit, libraryID string)
 
 		commits = append(commits, &ConventionalCommit{
 			Type:       header.Type,
+			Scope:      header.Scope,
 			Subject:    header.Description,
 			LibraryID:  libraryID,
 			Footers:    footers,
@@ -285,6 +327,7 @@ func parseSimpleCommit(commitPart commitPart, commit *Commit, libraryID string)
 			IsNested:   commitPart.isNested,
 			CommitHash: commit.Hash.String(),
 			When:       commit.When,
+			Author:     commit.Author,
 		})
 	}
 
@@ -292,11 +335,40 @@ func parseSimpleCommit(commitPart commitPart, commit *Commit, libraryID string)
 		sub := fmt.Sprintf("%s %s", commit.Subject, strings.Join(subjects[i], " "))
 		commit.Subject = strings.TrimSpace(sub)
 		commit.Body = strings.Join(body[i], "\n")
+		if opts.Affects {
+			attributeAffectedLibraries(commit, affects[i])
+		}
 	}
 
 	return commits, nil
 }
 
+// attributeAffectedLibraries sets the Library-IDs footer of commit, unless it
+// has one, to the comma-separated library IDs of affects, the value of the
+// Affects line of its body, or failing that, of its Affects footer.
+func attributeAffectedLibraries(commit *ConventionalCommit, affects string) {
+	if _, ok := commit.Footers[libraryIDsKey]; ok {
+		return
+	}
+	if affects == "" {
+		affects = commit.Footers[affectsKey]
+	}
+	var ids []string
+	for _, id := range strings.Split(affects, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	// The footers are shared by the commits parsed from the same part of a
+	// commit message.
+	footers := maps.Clone(commit.Footers)
+	footers[libraryIDsKey] = strings.Join(ids, ",")
+	commit.Footers = footers
+}
+
 // parseHeader parses the header line of a commit message.
 func parseHeader(headerLine string) (*parsedHeader, bool) {
 	match := commitRegex.FindStringSubmatch(headerLine)
@@ -398,5 +470,10 @@ func processFooters(footers map[string]string) {
 			}
 			footers[key] = matches[2]
 		}
+		if key == fixesVersionKey {
+			// The prior version a fix applies to, without the "v" prefix of
+			// a tag.
+			footers[key] = strings.TrimPrefix(value, "v")
+		}
 	}
 }
@@ -54,6 +54,7 @@ func TestParseCommits(t *testing.T) {
 			want: []*ConventionalCommit{
 				{
 					Type:       "feat",
+					Scope:      "scope",
 					Subject:    "add new feature",
 					LibraryID:  "example-id",
 					IsNested:   false,
@@ -132,6 +133,22 @@ func TestParseCommits(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:    "commit_with_fixes_version_footer",
+			message: "fix: a backported fix\n\nFixes-Version: v1.2.0",
+			want: []*ConventionalCommit{
+				{
+					Type:      "fix",
+					Subject:   "a backported fix",
+					LibraryID: "example-id",
+					Footers: map[string]string{
+						"Fixes-Version": "1.2.0",
+					},
+					CommitHash: sha.String(),
+					When:       now,
+				},
+			},
+		},
 		{
 			name:    "commit_with_breaking_change_footer",
 			message: "feat: add new feature\n\nBREAKING CHANGE: this is a breaking change",
@@ -213,6 +230,7 @@ END_COMMIT`,
 			want: []*ConventionalCommit{
 				{
 					Type:       "fix",
+					Scope:      "override",
 					Subject:    "this is the override message",
 					Body:       "This is the body of the override.",
 					LibraryID:  "example-id",
@@ -253,6 +271,7 @@ END_NESTED_COMMIT
 			want: []*ConventionalCommit{
 				{
 					Type:       "feat",
+					Scope:      "parser",
 					Subject:    "main feature",
 					Body:       "main commit body",
 					LibraryID:  "example-id",
@@ -263,6 +282,7 @@ END_NESTED_COMMIT
 				},
 				{
 					Type:       "fix",
+					Scope:      "sub",
 					Subject:    "fix a bug",
 					Body:       "some details for the fix",
 					LibraryID:  "example-id",
@@ -273,6 +293,7 @@ END_NESTED_COMMIT
 				},
 				{
 					Type:       "chore",
+					Scope:      "deps",
 					Subject:    "update deps",
 					Body:       "",
 					LibraryID:  "example-id",
@@ -294,6 +315,7 @@ END_NESTED_COMMIT
 			want: []*ConventionalCommit{
 				{
 					Type:       "feat",
+					Scope:      "parser",
 					Subject:    "main feature 2nd line of title",
 					LibraryID:  "example-id",
 					IsNested:   false,
@@ -359,6 +381,49 @@ END_COMMIT
 				},
 			},
 		},
+		{
+			name: "squash_merge_with_unterminated_nested_commit",
+			message: `chore: librarian generate pull request (#42)
+
+BEGIN_NESTED_COMMIT
+feat: add a feature
+
+Library-IDs: a
+END_NESTED_COMMIT
+BEGIN_NESTED_COMMIT
+fix: fix a bug
+
+Library-IDs: b
+`,
+			want: []*ConventionalCommit{
+				{
+					Type:       "chore",
+					Subject:    "librarian generate pull request (#42)",
+					LibraryID:  "example-id",
+					Footers:    map[string]string{},
+					CommitHash: sha.String(),
+					When:       now,
+				},
+				{
+					Type:       "feat",
+					Subject:    "add a feature",
+					LibraryID:  "example-id",
+					IsNested:   true,
+					Footers:    map[string]string{"Library-IDs": "a"},
+					CommitHash: sha.String(),
+					When:       now,
+				},
+				{
+					Type:       "fix",
+					Subject:    "fix a bug",
+					LibraryID:  "example-id",
+					IsNested:   true,
+					Footers:    map[string]string{"Library-IDs": "b"},
+					CommitHash: sha.String(),
+					When:       now,
+				},
+			},
+		},
 		{
 			// This test verifies that the deprecated mark, BEGIN_COMMIT_OVERRIDE and END_COMMIT_OVERRIDE
 			// can be used to separate nested commits.
@@ -434,6 +499,7 @@ END_NESTED_COMMIT`,
 			want: []*ConventionalCommit{
 				{
 					Type:       "fix",
+					Scope:      "override",
 					Subject:    "this is the override message",
 					Body:       "This is the body of the override.",
 					LibraryID:  "example-id",
@@ -616,6 +682,7 @@ END_NESTED_COMMIT`,
 			want: []*ConventionalCommit{
 				{
 					Type:       "fix",
+					Scope:      "abc",
 					Subject:    "update google.golang.org/api to 0.229.0",
 					LibraryID:  "example-id",
 					IsNested:   true,
@@ -652,6 +719,88 @@ END_NESTED_COMMIT`,
 	}
 }
 
+func TestParseCommitsAuthor(t *testing.T) {
+	author := Signature{Name: "dependabot[bot]", Email: "support@github.com"}
+	commit := &Commit{
+		Message: "fix: bump a dependency\n\nBEGIN_NESTED_COMMIT\nfix: bump another dependency\nEND_NESTED_COMMIT",
+		Hash:    plumbing.NewHash("fake-sha"),
+		Author:  author,
+	}
+	got, err := ParseCommits(commit, "example-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("ParseCommits() returned %d commits, want 2", len(got))
+	}
+	for _, c := range got {
+		if diff := cmp.Diff(author, c.Author); diff != "" {
+			t.Errorf("author mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestParseCommitsWithOptions_Affects(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		message string
+		// want holds the Library-IDs footer of each parsed commit, or "" if
+		// it has none.
+		want []string
+	}{
+		{
+			name:    "affects line in body",
+			message: "feat: update clients\n\nRegenerate the clients.\nAffects: a, b ,c\nSee the design doc.",
+			want:    []string{"a,b,c"},
+		},
+		{
+			name:    "affects line as footer",
+			message: "feat: update clients\n\nRegenerate the clients.\n\nAffects: a, b\nReviewed-by: someone",
+			want:    []string{"a,b"},
+		},
+		{
+			name:    "library ids footer takes precedence",
+			message: "feat: update clients\n\nAffects: a, b\n\nLibrary-IDs: c",
+			want:    []string{"c"},
+		},
+		{
+			name:    "no affects line",
+			message: "feat: update clients\n\nThis affects: a and b.",
+			want:    []string{""},
+		},
+		{
+			name:    "empty affects line",
+			message: "feat: update clients\n\nAffects: , ",
+			want:    []string{""},
+		},
+		{
+			name:    "multiple headers",
+			message: "feat: update a\n\nUpdate a.\nAffects: a\nfix: fix b\nAffects: b, c",
+			want:    []string{"a", "b,c"},
+		},
+		{
+			name:    "nested commits",
+			message: "BEGIN_NESTED_COMMIT\nfeat: update a\n\nAffects: a\nEND_NESTED_COMMIT\nBEGIN_NESTED_COMMIT\nfix: fix b\nEND_NESTED_COMMIT",
+			want:    []string{"a", ""},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			commit := &Commit{Message: test.message, Hash: plumbing.NewHash("fake-sha")}
+			commits, err := ParseCommitsWithOptions(commit, "example-id", ParseOptions{Affects: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, commit := range commits {
+				got = append(got, commit.Footers["Library-IDs"])
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Library-IDs mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestExtractCommitParts(t *testing.T) {
 	for _, test := range []struct {
 		name    string
@@ -703,13 +852,30 @@ BEGIN_NESTED_COMMIT
 fix(sub): fix a bug that is never closed`,
 			want: []commitPart{
 				{message: "feat(parser): main feature", isNested: false},
+				{message: "fix(sub): fix a bug that is never closed", isNested: true},
 			},
 		},
 		{
 			name: "malformed nested commit without end marker, without primary commit",
 			message: `BEGIN_NESTED_COMMIT
 fix(sub): fix a bug that is never closed`,
-			want: nil,
+			want: []commitPart{
+				{message: "fix(sub): fix a bug that is never closed", isNested: true},
+			},
+		},
+		{
+			name: "malformed nested commit without end marker, followed by nested commit",
+			message: `BEGIN_NESTED_COMMIT
+fix(sub): fix a bug that is never closed
+Library-IDs: a
+BEGIN_NESTED_COMMIT
+feat(sub): add a feature
+Library-IDs: b
+END_NESTED_COMMIT`,
+			want: []commitPart{
+				{message: "fix(sub): fix a bug that is never closed\nLibrary-IDs: a", isNested: true},
+				{message: "feat(sub): add a feature\nLibrary-IDs: b", isNested: true},
+			},
 		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
@@ -16,6 +16,7 @@
 package legacygitrepo
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -23,6 +24,8 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -38,28 +41,41 @@ import (
 // ErrNoModificationsToCommit is returned when a commit is attempted on a clean worktree.
 var ErrNoModificationsToCommit = errors.New("no modifications to commit")
 
+// ErrRebaseConflict is returned when rebasing onto a branch fails because of
+// conflicting changes.
+var ErrRebaseConflict = errors.New("rebase conflict")
+
 // Repository defines the interface for git repository operations.
 type Repository interface {
 	AddAll() error
 	Commit(msg string) error
+	CommitWithAuthor(msg string, author, committer *Signature) error
+	AmendWithAuthor(msg string, author, committer *Signature) error
 	IsClean() (bool, error)
 	Remotes() ([]*Remote, error)
 	GetDir() string
 	HeadHash() (string, error)
+	CurrentBranch() (string, error)
 	ChangedFilesInCommit(commitHash string) ([]string, error)
 	ChangedFiles() ([]string, error)
+	Diff(paths []string) (string, error)
 	GetCommit(commitHash string) (*Commit, error)
 	GetLatestCommit(path string) (*Commit, error)
+	GetTagCommit(tagName string) (*Commit, error)
 	GetCommitsForPathsSinceTag(paths []string, tagName string) ([]*Commit, error)
 	GetCommitsForPathsSinceCommit(paths []string, sinceCommit string) ([]*Commit, error)
 	CreateBranchAndCheckout(name string) error
 	CheckoutCommitAndCreateBranch(name, commitHash string) error
 	NewAndDeletedFiles() ([]string, error)
 	Push(branchName string) error
+	RebaseOnto(branchName string, committer *Signature) error
+	FetchUpstream(branchName string) (int, error)
 	Restore(paths []string) error
 	CleanUntracked(paths []string) error
 	pushRefSpec(refSpec string) error
 	Checkout(commitHash string) error
+	AddWorktree(dir, commitHash string) error
+	RemoveWorktree(dir string) error
 	GetHashForPath(commitHash, path string) (string, error)
 	ResetHard() error
 	DeleteLocalBranches(names []string) error
@@ -68,6 +84,13 @@ type Repository interface {
 
 const RootPath = "."
 
+// DefaultAuthor is the identity used for commits when neither an explicit
+// author nor a git config identity is available.
+var DefaultAuthor = &Signature{
+	Name:  "Cloud SDK Librarian",
+	Email: "cloud-sdk-librarian-robot@google.com",
+}
+
 // LocalRepository represents a git repository.
 type LocalRepository struct {
 	Dir         string
@@ -80,6 +103,27 @@ type Commit struct {
 	Hash    plumbing.Hash
 	Message string
 	When    time.Time
+	// Author is the author of the commit.
+	Author Signature
+}
+
+// Signature identifies the author and committer of a commit.
+type Signature struct {
+	Name  string
+	Email string
+}
+
+// signatureRegexp matches an identity of the form "Name <email>".
+var signatureRegexp = regexp.MustCompile(`^([^<>]*[^<>\s])\s*<([^<>\s]+@[^<>\s]+)>$`)
+
+// ParseSignature parses an identity of the form "Name <email>", as used by
+// git, e.g. "Release Bot <release-bot@example.com>".
+func ParseSignature(s string) (*Signature, error) {
+	matches := signatureRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if matches == nil {
+		return nil, fmt.Errorf("invalid identity %q, must be of the form \"Name <email>\"", s)
+	}
+	return &Signature{Name: strings.TrimSpace(matches[1]), Email: matches[2]}, nil
 }
 
 // Remote represent a git remote.
@@ -106,6 +150,11 @@ type RepositoryOptions struct {
 	GitPassword string
 	// Depth controls the cloning depth if the repository needs to be cloned.
 	Depth int
+	// Refresh updates an existing repository at Dir to the latest commit of
+	// RemoteBranch instead of opening it as is. If the `origin` remote of the
+	// existing repository is not RemoteURL, the repository is removed and
+	// cloned again. Only used if MaybeClone is set to true. Optional.
+	Refresh bool
 }
 
 // NewRepository provides access to a git repository based on the provided options.
@@ -133,20 +182,56 @@ func newRepositoryWithoutUser(opts *RepositoryOptions) (*LocalRepository, error)
 	}
 	slog.Info("checking for repository", "dir", opts.Dir)
 	_, err := os.Stat(opts.Dir)
-	if err == nil {
+	if err == nil && !opts.Refresh {
 		return open(opts.Dir)
 	}
-	if os.IsNotExist(err) {
-		if opts.RemoteURL == "" {
-			return nil, fmt.Errorf("gitrepo: remote URL is required when cloning")
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to check for repository at %q: %w", opts.Dir, err)
+	}
+	if opts.RemoteURL == "" {
+		return nil, fmt.Errorf("gitrepo: remote URL is required when cloning")
+	}
+	if opts.RemoteBranch == "" {
+		return nil, fmt.Errorf("gitrepo: remote branch is required when cloning")
+	}
+	if err == nil {
+		return refresh(opts)
+	}
+	slog.Info("repository not found, executing clone")
+	return clone(opts.Dir, opts.RemoteURL, opts.RemoteBranch, opts.CI, opts.Depth)
+}
+
+// refresh updates the existing repository at opts.Dir to the latest commit of
+// opts.RemoteBranch. The repository is cloned again if it cannot be opened or
+// if its `origin` remote is not opts.RemoteURL.
+func refresh(opts *RepositoryOptions) (*LocalRepository, error) {
+	repo, err := open(opts.Dir)
+	if err == nil {
+		var remoteURL string
+		if remoteURL, err = repo.originURL(); err == nil && !sameRemoteURL(remoteURL, opts.RemoteURL) {
+			err = fmt.Errorf("origin remote is %q, want %q", remoteURL, opts.RemoteURL)
 		}
-		if opts.RemoteBranch == "" {
-			return nil, fmt.Errorf("gitrepo: remote branch is required when cloning")
+	}
+	if err != nil {
+		slog.Warn("existing repository cannot be reused, cloning again", "dir", opts.Dir, "err", err)
+		if err := os.RemoveAll(opts.Dir); err != nil {
+			return nil, fmt.Errorf("failed to remove repository at %q: %w", opts.Dir, err)
 		}
-		slog.Info("repository not found, executing clone")
 		return clone(opts.Dir, opts.RemoteURL, opts.RemoteBranch, opts.CI, opts.Depth)
 	}
-	return nil, fmt.Errorf("failed to check for repository at %q: %w", opts.Dir, err)
+	if err := repo.checkoutRemoteBranch(opts.RemoteBranch, opts.Depth); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// sameRemoteURL reports whether a and b refer to the same remote repository,
+// ignoring a trailing slash or ".git" suffix.
+func sameRemoteURL(a, b string) bool {
+	normalize := func(u string) string {
+		return strings.TrimSuffix(strings.TrimSuffix(u, "/"), ".git")
+	}
+	return normalize(a) == normalize(b)
 }
 
 func open(dir string) (*LocalRepository, error) {
@@ -205,7 +290,27 @@ func (r *LocalRepository) AddAll() error {
 // Commit creates a new commit with the provided message and author
 // information.
 func (r *LocalRepository) Commit(msg string) error {
-	slog.Info("committing", "message", msg)
+	return r.CommitWithAuthor(msg, nil, nil)
+}
+
+// CommitWithAuthor creates a new commit with the provided message, using
+// author as the author of the commit, and committer as its committer, or
+// author if committer is nil. Any field of author or committer that is empty,
+// or all fields if author is nil, is read from git config, falling back to
+// DefaultAuthor if git config has no identity.
+func (r *LocalRepository) CommitWithAuthor(msg string, author, committer *Signature) error {
+	return r.commit(msg, author, committer, false)
+}
+
+// AmendWithAuthor replaces the HEAD commit with a new commit containing the
+// staged changes on top of the HEAD commit, using the provided message. The
+// author and committer are resolved as in CommitWithAuthor.
+func (r *LocalRepository) AmendWithAuthor(msg string, author, committer *Signature) error {
+	return r.commit(msg, author, committer, true)
+}
+
+func (r *LocalRepository) commit(msg string, author, committer *Signature, amend bool) error {
+	slog.Info("committing", "message", msg, "amend", amend)
 	worktree, err := r.repo.Worktree()
 	if err != nil {
 		return err
@@ -218,8 +323,22 @@ func (r *LocalRepository) Commit(msg string) error {
 	if status.IsClean() {
 		return ErrNoModificationsToCommit
 	}
-	// The author of the commit will be read from git config.
-	hash, err := worktree.Commit(msg, &git.CommitOptions{})
+	signature, err := r.resolveAuthor(author)
+	if err != nil {
+		return err
+	}
+	committerSignature := signature
+	if committer != nil {
+		committerSignature, err = r.resolveAuthor(committer)
+		if err != nil {
+			return err
+		}
+	}
+	hash, err := worktree.Commit(msg, &git.CommitOptions{
+		Author:    signature,
+		Committer: committerSignature,
+		Amend:     amend,
+	})
 	if err != nil {
 		return err
 	}
@@ -228,6 +347,29 @@ func (r *LocalRepository) Commit(msg string) error {
 	return nil
 }
 
+// resolveAuthor fills in the empty fields of author from git config, and then
+// from DefaultAuthor.
+func (r *LocalRepository) resolveAuthor(author *Signature) (*object.Signature, error) {
+	signature := &object.Signature{When: time.Now()}
+	if author != nil {
+		signature.Name = author.Name
+		signature.Email = author.Email
+	}
+	if signature.Name == "" || signature.Email == "" {
+		cfg, err := r.repo.ConfigScoped(config.SystemScope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read git config: %w", err)
+		}
+		if signature.Name == "" {
+			signature.Name = cmp.Or(cfg.Author.Name, cfg.User.Name, DefaultAuthor.Name)
+		}
+		if signature.Email == "" {
+			signature.Email = cmp.Or(cfg.Author.Email, cfg.User.Email, DefaultAuthor.Email)
+		}
+	}
+	return signature, nil
+}
+
 // IsClean reports whether the working tree has no uncommitted changes.
 func (r *LocalRepository) IsClean() (bool, error) {
 	worktree, err := r.repo.Worktree()
@@ -263,6 +405,53 @@ func (r *LocalRepository) ChangedFiles() ([]string, error) {
 	return changedFiles, nil
 }
 
+// Diff returns a unified diff of the given paths in the working tree against
+// the HEAD commit. Untracked files are diffed against an empty file.
+func (r *LocalRepository) Diff(paths []string) (string, error) {
+	if len(paths) == 0 {
+		return "", nil
+	}
+	worktree, err := r.repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return "", err
+	}
+	var tracked, untracked []string
+	for _, path := range paths {
+		if fileStatus, ok := status[path]; ok && fileStatus.Worktree == git.Untracked {
+			untracked = append(untracked, path)
+			continue
+		}
+		tracked = append(tracked, path)
+	}
+
+	var diff strings.Builder
+	if len(tracked) > 0 {
+		output, err := r.runGit(append([]string{"diff", "HEAD", "--"}, tracked...)...)
+		if err != nil {
+			return "", err
+		}
+		if output != "" {
+			diff.WriteString(output + "\n")
+		}
+	}
+	for _, path := range untracked {
+		// git diff --no-index exits with status 1 when the files differ.
+		cmd := exec.Command("git", "diff", "--no-index", "--", os.DevNull, path)
+		cmd.Dir = r.Dir
+		output, err := cmd.Output()
+		var exitErr *exec.ExitError
+		if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+			return "", fmt.Errorf("failed to diff untracked file %s: %w", path, err)
+		}
+		diff.Write(output)
+	}
+	return diff.String(), nil
+}
+
 // NewAndDeletedFiles returns a list of files that are new or deleted.
 func (r *LocalRepository) NewAndDeletedFiles() ([]string, error) {
 	slog.Debug("getting new and deleted files")
@@ -310,6 +499,18 @@ func (r *LocalRepository) HeadHash() (string, error) {
 	return ref.Hash().String(), nil
 }
 
+// CurrentBranch returns the name of the branch checked out in the repository.
+func (r *LocalRepository) CurrentBranch() (string, error) {
+	ref, err := r.repo.Head()
+	if err != nil {
+		return "", err
+	}
+	if !ref.Name().IsBranch() {
+		return "", fmt.Errorf("HEAD is not a branch: %s", ref.Name())
+	}
+	return ref.Name().Short(), nil
+}
+
 // GetDir returns the directory of the repository.
 func (r *LocalRepository) GetDir() string {
 	return r.Dir
@@ -326,6 +527,7 @@ func (r *LocalRepository) GetCommit(commitHash string) (*Commit, error) {
 		Hash:    commit.Hash,
 		Message: commit.Message,
 		When:    commit.Author.When,
+		Author:  Signature{Name: commit.Author.Name, Email: commit.Author.Email},
 	}, nil
 }
 
@@ -350,9 +552,28 @@ func (r *LocalRepository) GetLatestCommit(path string) (*Commit, error) {
 		Hash:    commit.Hash,
 		Message: commit.Message,
 		When:    commit.Author.When,
+		Author:  Signature{Name: commit.Author.Name, Email: commit.Author.Email},
 	}, nil
 }
 
+// GetTagCommit returns the commit the tag with the given name points to. Both
+// lightweight and annotated tags are supported.
+func (r *LocalRepository) GetTagCommit(tagName string) (*Commit, error) {
+	tagRef, err := r.repo.Tag(tagName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find tag %s: %w", tagName, err)
+	}
+	hash := tagRef.Hash()
+	if tag, err := r.repo.TagObject(hash); err == nil {
+		hash = tag.Target
+	}
+	commit, err := r.GetCommit(hash.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit object for tag %s: %w", tagName, err)
+	}
+	return commit, nil
+}
+
 // GetCommitsForPathsSinceTag returns all commits since tagName that contains
 // files in paths.
 //
@@ -430,6 +651,7 @@ func (r *LocalRepository) GetCommitsForPathsSinceCommit(paths []string, sinceCom
 					Hash:    commit.Hash,
 					Message: commit.Message,
 					When:    commit.Author.When,
+					Author:  Signature{Name: commit.Author.Name, Email: commit.Author.Email},
 				})
 				return nil
 			}
@@ -573,38 +795,196 @@ func (r *LocalRepository) DeleteBranch(branchName string) error {
 
 func (r *LocalRepository) pushRefSpec(refSpec string) error {
 	slog.Info("pushing changes", "refSpec", refSpec)
-
-	// Check for the configured URI for the `origin` remote.
-	// If there are multiple URLs, the first one is selected.
-	var remoteURI string
-	remotes, err := r.Remotes()
+	auth, err := r.originAuth()
 	if err != nil {
 		return err
 	}
-	for _, remote := range remotes {
-		if remote.Name == "origin" {
-			if len(remote.URLs) > 0 {
-				remoteURI = remote.URLs[0]
-			}
-		}
+	if err := r.repo.Push(&git.PushOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{config.RefSpec(refSpec)},
+		Auth:       auth,
+	}); err != nil {
+		return err
+	}
+	slog.Info("successfully pushed changes", "refSpec", refSpec)
+	return nil
+}
+
+// originAuth returns the AuthMethod to use with the `origin` remote.
+func (r *LocalRepository) originAuth() (transport.AuthMethod, error) {
+	remoteURI, err := r.originURL()
+	if err != nil {
+		return nil, err
 	}
 
 	useSSH := canUseSSH(remoteURI)
 	// While cloning a public repo does not require any authCreds, pushing
 	// to the repo requires authentication and verification of identity
-	auth, err := r.authCreds(useSSH)
+	return r.authCreds(useSSH)
+}
+
+// originURL returns the configured URI for the `origin` remote, or an empty
+// string if there is none. If there are multiple URLs, the first one is
+// selected.
+func (r *LocalRepository) originURL() (string, error) {
+	remotes, err := r.Remotes()
+	if err != nil {
+		return "", err
+	}
+	for _, remote := range remotes {
+		if remote.Name == "origin" && len(remote.URLs) > 0 {
+			return remote.URLs[0], nil
+		}
+	}
+	return "", nil
+}
+
+// checkoutRemoteBranch fetches branchName from the `origin` remote, points the
+// local branch at the fetched commit and checks it out, discarding any local
+// changes.
+func (r *LocalRepository) checkoutRemoteBranch(branchName string, depth int) error {
+	auth, err := r.originAuth()
 	if err != nil {
 		return err
 	}
-	if err := r.repo.Push(&git.PushOptions{
+	remoteRef := plumbing.NewRemoteReferenceName("origin", branchName)
+	slog.Info("fetching branch", "branch name", branchName)
+	if err := r.repo.Fetch(&git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{config.RefSpec(fmt.Sprintf("+refs/heads/%s:%s", branchName, remoteRef))},
+		Tags:       git.AllTags,
+		Depth:      depth,
+		Auth:       auth,
+	}); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("failed to fetch %s: %w", branchName, err)
+	}
+	ref, err := r.repo.Reference(remoteRef, true)
+	if err != nil {
+		return err
+	}
+	branchRef := plumbing.NewBranchReferenceName(branchName)
+	if err := r.repo.Storer.SetReference(plumbing.NewHashReference(branchRef, ref.Hash())); err != nil {
+		return err
+	}
+	worktree, err := r.repo.Worktree()
+	if err != nil {
+		return err
+	}
+	return worktree.Checkout(&git.CheckoutOptions{Branch: branchRef, Force: true})
+}
+
+// RebaseOnto fetches branchName from the `origin` remote and rebases the
+// current branch onto it, using committer as the identity of the rebased
+// commits. Empty fields of committer are filled in as for
+// [LocalRepository.CommitWithAuthor].
+//
+// If the rebase stops because of conflicts, it is aborted, leaving the current
+// branch unchanged, and an error wrapping [ErrRebaseConflict] which lists the
+// conflicting files is returned.
+//
+// Wrap git operations in exec, because go-git does not support rebasing.
+func (r *LocalRepository) RebaseOnto(branchName string, committer *Signature) error {
+	auth, err := r.originAuth()
+	if err != nil {
+		return err
+	}
+	remoteRef := fmt.Sprintf("refs/remotes/origin/%s", branchName)
+	refSpec := fmt.Sprintf("+refs/heads/%s:%s", branchName, remoteRef)
+	slog.Info("fetching base branch", "branch name", branchName, slog.Any("refspec", refSpec))
+	if err := r.repo.Fetch(&git.FetchOptions{
 		RemoteName: "origin",
 		RefSpecs:   []config.RefSpec{config.RefSpec(refSpec)},
 		Auth:       auth,
-	}); err != nil {
+	}); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("failed to fetch %s: %w", branchName, err)
+	}
+
+	signature, err := r.resolveAuthor(committer)
+	if err != nil {
 		return err
 	}
-	slog.Info("successfully pushed changes", "refSpec", refSpec)
-	return nil
+	slog.Info("rebasing onto base branch", "branch name", branchName)
+	cmd := exec.Command("git", "rebase", remoteRef)
+	cmd.Dir = r.Dir
+	cmd.Env = append(os.Environ(),
+		"GIT_COMMITTER_NAME="+signature.Name,
+		"GIT_COMMITTER_EMAIL="+signature.Email,
+	)
+	output, rebaseErr := cmd.CombinedOutput()
+	if rebaseErr == nil {
+		return nil
+	}
+	conflicts, err := r.runGit("diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return fmt.Errorf("failed to rebase onto %s: %w: %s", branchName, rebaseErr, output)
+	}
+	if _, err := r.runGit("rebase", "--abort"); err != nil {
+		return fmt.Errorf("failed to abort rebase onto %s: %w", branchName, err)
+	}
+	if conflicts == "" {
+		return fmt.Errorf("failed to rebase onto %s: %w: %s", branchName, rebaseErr, output)
+	}
+	files := strings.Fields(conflicts)
+	return fmt.Errorf("%w onto %s in files: %s", ErrRebaseConflict, branchName, strings.Join(files, ", "))
+}
+
+// FetchUpstream fetches branchName and all tags from the `origin` remote, and
+// returns the number of commits of the fetched branch that are not in HEAD,
+// i.e., how many commits the local branch is behind the remote one. Local
+// tags are overwritten by the remote tags of the same name.
+func (r *LocalRepository) FetchUpstream(branchName string) (int, error) {
+	auth, err := r.originAuth()
+	if err != nil {
+		return 0, err
+	}
+	remoteRef := fmt.Sprintf("refs/remotes/origin/%s", branchName)
+	refSpecs := []config.RefSpec{
+		config.RefSpec(fmt.Sprintf("+refs/heads/%s:%s", branchName, remoteRef)),
+		"+refs/tags/*:refs/tags/*",
+	}
+	slog.Info("fetching branch and tags", "branch name", branchName)
+	if err := r.repo.Fetch(&git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   refSpecs,
+		Tags:       git.AllTags,
+		Auth:       auth,
+	}); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return 0, fmt.Errorf("failed to fetch %s: %w", branchName, err)
+	}
+	count, err := r.runGit("rev-list", "--count", "HEAD.."+remoteRef)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(count)
+}
+
+// AddWorktree checks out the commit with the given hash into a new linked
+// worktree at dir, leaving the worktree of the repository unchanged.
+func (r *LocalRepository) AddWorktree(dir, commitHash string) error {
+	slog.Info("adding worktree", "dir", dir, "commit", commitHash)
+	_, err := r.runGit("worktree", "add", "--detach", dir, commitHash)
+	return err
+}
+
+// RemoveWorktree removes the linked worktree at dir, discarding any changes
+// made in it.
+func (r *LocalRepository) RemoveWorktree(dir string) error {
+	_, err := r.runGit("worktree", "remove", "--force", dir)
+	return err
+}
+
+// runGit runs git with args in the repository directory, and returns its
+// trimmed standard output.
+func (r *LocalRepository) runGit(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.Dir
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, stderr.String())
+	}
+	return strings.TrimSpace(string(output)), nil
 }
 
 // canUseSSH returns if the remote URI can connect via https ssh. It attempts to
@@ -694,6 +694,158 @@ func TestCommit(t *testing.T) {
 	}
 }
 
+func TestAmendWithAuthor(t *testing.T) {
+	t.Parallel()
+	goGitRepo, dir := initTestRepo(t)
+	initial := createAndCommit(t, goGitRepo, "README.md", []byte("hello"), "initial commit")
+	repo := &LocalRepository{Dir: dir, repo: goGitRepo}
+	author := &Signature{Name: "tester", Email: "tester@example.com"}
+	for _, file := range []string{"first.txt", "second.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte("content"), 0644); err != nil {
+			t.Fatalf("os.WriteFile failed: %v", err)
+		}
+		if err := repo.AddAll(); err != nil {
+			t.Fatalf("AddAll() failed: %v", err)
+		}
+	}
+	if err := repo.CommitWithAuthor("feat: first", author, nil); err != nil {
+		t.Fatalf("CommitWithAuthor() failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "second.txt"), []byte("updated"), 0644); err != nil {
+		t.Fatalf("os.WriteFile failed: %v", err)
+	}
+	if err := repo.AddAll(); err != nil {
+		t.Fatalf("AddAll() failed: %v", err)
+	}
+	if err := repo.AmendWithAuthor("feat: amended", author, nil); err != nil {
+		t.Fatalf("AmendWithAuthor() failed: %v", err)
+	}
+
+	head, err := goGitRepo.Head()
+	if err != nil {
+		t.Fatalf("Head() failed: %v", err)
+	}
+	commit, err := goGitRepo.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatalf("CommitObject() failed: %v", err)
+	}
+	if commit.Message != "feat: amended" {
+		t.Errorf("AmendWithAuthor() message = %q, want %q", commit.Message, "feat: amended")
+	}
+	if diff := cmp.Diff([]plumbing.Hash{initial.Hash}, commit.ParentHashes); diff != "" {
+		t.Errorf("AmendWithAuthor() parents mismatch (-want +got):\n%s", diff)
+	}
+	file, err := commit.File("second.txt")
+	if err != nil {
+		t.Fatalf("File() failed: %v", err)
+	}
+	content, err := file.Contents()
+	if err != nil {
+		t.Fatalf("Contents() failed: %v", err)
+	}
+	if content != "updated" {
+		t.Errorf("second.txt = %q, want %q", content, "updated")
+	}
+}
+
+func TestCommitWithAuthor(t *testing.T) {
+	// Isolate the test from the global git config of the machine running it.
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	for _, test := range []struct {
+		name       string
+		configUser *Signature
+		author     *Signature
+		committer  *Signature
+		want       *Signature
+		// wantCommitter is the committer of the commit, if different from
+		// want.
+		wantCommitter *Signature
+	}{
+		{
+			name:       "explicit author",
+			configUser: &Signature{Name: "tester", Email: "tester@example.com"},
+			author:     &Signature{Name: "Release Bot", Email: "release-bot@example.com"},
+			want:       &Signature{Name: "Release Bot", Email: "release-bot@example.com"},
+		},
+		{
+			name:       "partial author",
+			configUser: &Signature{Name: "tester", Email: "tester@example.com"},
+			author:     &Signature{Name: "Release Bot"},
+			want:       &Signature{Name: "Release Bot", Email: "tester@example.com"},
+		},
+		{
+			name:       "author from git config",
+			configUser: &Signature{Name: "tester", Email: "tester@example.com"},
+			want:       &Signature{Name: "tester", Email: "tester@example.com"},
+		},
+		{
+			name: "default author",
+			want: DefaultAuthor,
+		},
+		{
+			name:          "explicit committer",
+			configUser:    &Signature{Name: "tester", Email: "tester@example.com"},
+			author:        &Signature{Name: "Release Bot", Email: "release-bot@example.com"},
+			committer:     &Signature{Name: "Commit Bot", Email: "commit-bot@example.com"},
+			want:          &Signature{Name: "Release Bot", Email: "release-bot@example.com"},
+			wantCommitter: &Signature{Name: "Commit Bot", Email: "commit-bot@example.com"},
+		},
+		{
+			name:          "committer without author",
+			configUser:    &Signature{Name: "tester", Email: "tester@example.com"},
+			committer:     &Signature{Name: "Commit Bot", Email: "commit-bot@example.com"},
+			want:          &Signature{Name: "tester", Email: "tester@example.com"},
+			wantCommitter: &Signature{Name: "Commit Bot", Email: "commit-bot@example.com"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			goGitRepo, dir := initTestRepo(t)
+			if test.configUser != nil {
+				cfg, err := goGitRepo.Config()
+				if err != nil {
+					t.Fatalf("gitRepo.Config failed: %v", err)
+				}
+				cfg.User.Name = test.configUser.Name
+				cfg.User.Email = test.configUser.Email
+				if err := goGitRepo.SetConfig(cfg); err != nil {
+					t.Fatalf("gitRepo.SetConfig failed: %v", err)
+				}
+			}
+			if err := os.WriteFile(filepath.Join(dir, "new.txt"), []byte("content"), 0644); err != nil {
+				t.Fatalf("os.WriteFile failed: %v", err)
+			}
+			repo := &LocalRepository{Dir: dir, repo: goGitRepo}
+			if err := repo.AddAll(); err != nil {
+				t.Fatalf("AddAll() failed: %v", err)
+			}
+			if err := repo.CommitWithAuthor("feat: add new file", test.author, test.committer); err != nil {
+				t.Fatalf("CommitWithAuthor() failed: %v", err)
+			}
+			head, err := goGitRepo.Head()
+			if err != nil {
+				t.Fatalf("Head() failed: %v", err)
+			}
+			commit, err := goGitRepo.CommitObject(head.Hash())
+			if err != nil {
+				t.Fatalf("CommitObject() failed: %v", err)
+			}
+			got := &Signature{Name: commit.Author.Name, Email: commit.Author.Email}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("CommitWithAuthor() author mismatch (-want +got):\n%s", diff)
+			}
+			wantCommitter := test.want
+			if test.wantCommitter != nil {
+				wantCommitter = test.wantCommitter
+			}
+			committer := &Signature{Name: commit.Committer.Name, Email: commit.Committer.Email}
+			if diff := cmp.Diff(wantCommitter, committer); diff != "" {
+				t.Errorf("CommitWithAuthor() committer mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestRemotes(t *testing.T) {
 	t.Parallel()
 	for _, test := range []struct {
@@ -791,6 +943,7 @@ func TestGetCommit(t *testing.T) {
 			name: "get a commit",
 			want: &Commit{
 				Message: "initial commit",
+				Author:  Signature{Name: "Test", Email: "test@example.com"},
 			},
 		},
 		{
@@ -835,6 +988,23 @@ func TestGetCommit(t *testing.T) {
 	}
 }
 
+func TestCurrentBranch(t *testing.T) {
+	t.Parallel()
+	repo, dir := initTestRepo(t)
+	createAndCommit(t, repo, "README.md", []byte("test"), "initial commit")
+	r := &LocalRepository{Dir: dir, repo: repo}
+	if err := r.CreateBranchAndCheckout("librarian-20250101T000000Z"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.CurrentBranch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "librarian-20250101T000000Z"; got != want {
+		t.Errorf("CurrentBranch() = %q, want %q", got, want)
+	}
+}
+
 func TestHeadHash(t *testing.T) {
 	t.Parallel()
 	for _, test := range []struct {
@@ -1289,6 +1459,60 @@ func TestGetCommitsForPathsSinceTag(t *testing.T) {
 	}
 }
 
+func TestGetTagCommit(t *testing.T) {
+	t.Parallel()
+	repo, commits := setupRepoForGetCommitsTest(t)
+	head, err := repo.repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.repo.CreateTag("v1.1.0", head.Hash(), &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+		Message: "v1.1.0",
+	}); err != nil {
+		t.Fatalf("CreateTag failed: %v", err)
+	}
+
+	for _, test := range []struct {
+		name          string
+		tagName       string
+		want          string
+		wantErrPhrase string
+	}{
+		{
+			name:    "lightweight tag",
+			tagName: "v1.0.0",
+			want:    commits["commit1"],
+		},
+		{
+			name:    "annotated tag",
+			tagName: "v1.1.0",
+			want:    commits["commit3"],
+		},
+		{
+			name:          "invalid tag",
+			tagName:       "non-existent-tag",
+			wantErrPhrase: "failed to find tag",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := repo.GetTagCommit(test.tagName)
+			if test.wantErrPhrase != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrPhrase) {
+					t.Fatalf("GetTagCommit() returned error %v, want to contain %q", err, test.wantErrPhrase)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got.Hash.String()); diff != "" {
+				t.Errorf("GetTagCommit() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestCreateBranchAndCheckout(t *testing.T) {
 	for _, test := range []struct {
 		name          string
@@ -1330,6 +1554,96 @@ func TestCreateBranchAndCheckout(t *testing.T) {
 	}
 }
 
+func TestRebaseOnto(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name         string
+		baseFile     string
+		wantConflict bool
+	}{
+		{
+			name:     "base moved",
+			baseFile: "base.txt",
+		},
+		{
+			name:         "conflicting changes",
+			baseFile:     "README.md",
+			wantConflict: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			origin, originDir := initTestRepo(t)
+			createAndCommit(t, origin, "README.md", []byte("hello"), "initial commit")
+			originHead, err := origin.Head()
+			if err != nil {
+				t.Fatalf("Head() failed: %v", err)
+			}
+			base := originHead.Name().Short()
+
+			dir := t.TempDir()
+			cloned, err := git.PlainClone(dir, false, &git.CloneOptions{URL: originDir})
+			if err != nil {
+				t.Fatalf("git.PlainClone failed: %v", err)
+			}
+			repo := &LocalRepository{Dir: dir, repo: cloned}
+			if err := repo.CreateBranchAndCheckout("feature"); err != nil {
+				t.Fatalf("CreateBranchAndCheckout() failed: %v", err)
+			}
+			createAndCommit(t, cloned, "README.md", []byte("feature change"), "feat: change readme")
+			featureHead, err := cloned.Head()
+			if err != nil {
+				t.Fatalf("Head() failed: %v", err)
+			}
+
+			// Move the base branch after the feature branch was created.
+			baseCommit := createAndCommit(t, origin, test.baseFile, []byte("base change"), "chore: move base")
+
+			committer := &Signature{Name: "tester", Email: "tester@example.com"}
+			err = repo.RebaseOnto(base, committer)
+			if test.wantConflict {
+				if !errors.Is(err, ErrRebaseConflict) {
+					t.Fatalf("RebaseOnto() error = %v, want %v", err, ErrRebaseConflict)
+				}
+				if !strings.Contains(err.Error(), "README.md") {
+					t.Errorf("RebaseOnto() error = %q, want to contain %q", err.Error(), "README.md")
+				}
+				head, err := cloned.Head()
+				if err != nil {
+					t.Fatalf("Head() failed: %v", err)
+				}
+				if head.Hash() != featureHead.Hash() {
+					t.Errorf("HEAD = %s after a failed rebase, want %s", head.Hash(), featureHead.Hash())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RebaseOnto() failed: %v", err)
+			}
+			head, err := cloned.Head()
+			if err != nil {
+				t.Fatalf("Head() failed: %v", err)
+			}
+			if head.Name().Short() != "feature" {
+				t.Errorf("RebaseOnto() branch = %q, want %q", head.Name().Short(), "feature")
+			}
+			commit, err := cloned.CommitObject(head.Hash())
+			if err != nil {
+				t.Fatalf("CommitObject() failed: %v", err)
+			}
+			if diff := cmp.Diff([]plumbing.Hash{baseCommit.Hash}, commit.ParentHashes); diff != "" {
+				t.Errorf("RebaseOnto() parents mismatch (-want +got):\n%s", diff)
+			}
+			if commit.Committer.Name != committer.Name || commit.Committer.Email != committer.Email {
+				t.Errorf("RebaseOnto() committer = %s <%s>, want %s <%s>", commit.Committer.Name, commit.Committer.Email, committer.Name, committer.Email)
+			}
+			if _, err := commit.File(test.baseFile); err != nil {
+				t.Errorf("rebased commit is missing %s: %v", test.baseFile, err)
+			}
+		})
+	}
+}
+
 func TestRestore(t *testing.T) {
 	for _, test := range []struct {
 		name          string
@@ -1495,6 +1809,7 @@ func TestGetLatestCommit(t *testing.T) {
 			},
 			want: &Commit{
 				Message: "second commit",
+				Author:  Signature{Name: "Test", Email: "test@example.com"},
 			},
 		},
 		{
@@ -1531,6 +1846,246 @@ func TestGetLatestCommit(t *testing.T) {
 }
 
 // initTestRepo creates a new git repository in a temporary directory.
+func TestFetchUpstream(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name          string
+		remoteCommits int
+		wantBehind    int
+	}{
+		{
+			name: "up to date",
+		},
+		{
+			name:          "behind the remote",
+			remoteCommits: 2,
+			wantBehind:    2,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			origin, originDir := initTestRepo(t)
+			createAndCommit(t, origin, "README.md", []byte("hello"), "initial commit")
+			originHead, err := origin.Head()
+			if err != nil {
+				t.Fatalf("Head() failed: %v", err)
+			}
+			base := originHead.Name().Short()
+
+			dir := t.TempDir()
+			cloned, err := git.PlainClone(dir, false, &git.CloneOptions{URL: originDir})
+			if err != nil {
+				t.Fatalf("git.PlainClone failed: %v", err)
+			}
+			repo := &LocalRepository{Dir: dir, repo: cloned}
+
+			// Move the remote branch and tag it after the repository was cloned.
+			var last *object.Commit
+			for i := range test.remoteCommits {
+				last = createAndCommit(t, origin, fmt.Sprintf("file%d.txt", i), []byte("change"), "chore: move base")
+			}
+			if last != nil {
+				if _, err := origin.CreateTag("v1.0.0", last.Hash, nil); err != nil {
+					t.Fatalf("CreateTag() failed: %v", err)
+				}
+			}
+
+			got, err := repo.FetchUpstream(base)
+			if err != nil {
+				t.Fatalf("FetchUpstream() failed: %v", err)
+			}
+			if got != test.wantBehind {
+				t.Errorf("FetchUpstream() = %d, want %d", got, test.wantBehind)
+			}
+			if last == nil {
+				return
+			}
+			tagCommit, err := repo.GetTagCommit("v1.0.0")
+			if err != nil {
+				t.Fatalf("GetTagCommit() failed: %v", err)
+			}
+			if tagCommit.Hash != last.Hash {
+				t.Errorf("GetTagCommit() = %s, want %s", tagCommit.Hash, last.Hash)
+			}
+		})
+	}
+}
+
+func TestNewRepository_Refresh(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name  string
+		setup func(t *testing.T, dir, originDir string)
+	}{
+		{
+			name: "same remote",
+			setup: func(t *testing.T, dir, originDir string) {
+				if _, err := git.PlainClone(dir, false, &git.CloneOptions{URL: originDir}); err != nil {
+					t.Fatalf("git.PlainClone failed: %v", err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("local change"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "different remote",
+			setup: func(t *testing.T, dir, originDir string) {
+				other, otherDir := initTestRepo(t)
+				createAndCommit(t, other, "other.txt", []byte("other"), "other commit")
+				if _, err := git.PlainClone(dir, false, &git.CloneOptions{URL: otherDir}); err != nil {
+					t.Fatalf("git.PlainClone failed: %v", err)
+				}
+			},
+		},
+		{
+			name: "not a repository",
+			setup: func(t *testing.T, dir, originDir string) {
+				if err := os.Mkdir(dir, 0755); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			origin, originDir := initTestRepo(t)
+			createAndCommit(t, origin, "README.md", []byte("hello"), "initial commit")
+			originHead, err := origin.Head()
+			if err != nil {
+				t.Fatalf("Head() failed: %v", err)
+			}
+			dir := filepath.Join(t.TempDir(), "cached")
+			test.setup(t, dir, originDir)
+			// Move the remote branch after the repository was cached.
+			latest := createAndCommit(t, origin, "new.txt", []byte("new"), "chore: new file")
+
+			repo, err := NewRepository(&RepositoryOptions{
+				Dir:          dir,
+				MaybeClone:   true,
+				Refresh:      true,
+				RemoteURL:    originDir,
+				RemoteBranch: originHead.Name().Short(),
+			})
+			if err != nil {
+				t.Fatalf("NewRepository() failed: %v", err)
+			}
+			got, err := repo.HeadHash()
+			if err != nil {
+				t.Fatalf("HeadHash() failed: %v", err)
+			}
+			if got != latest.Hash.String() {
+				t.Errorf("HeadHash() = %s, want %s", got, latest.Hash)
+			}
+			clean, err := repo.IsClean()
+			if err != nil {
+				t.Fatalf("IsClean() failed: %v", err)
+			}
+			if !clean {
+				t.Error("IsClean() = false, want true")
+			}
+		})
+	}
+}
+
+func TestAddWorktree(t *testing.T) {
+	t.Parallel()
+	r, dir := initTestRepo(t)
+	first := createAndCommit(t, r, "README.md", []byte("first"), "initial commit")
+	createAndCommit(t, r, "README.md", []byte("second"), "chore: update readme")
+	repo := &LocalRepository{Dir: dir, repo: r}
+
+	worktreeDir := filepath.Join(t.TempDir(), "worktree")
+	if err := repo.AddWorktree(worktreeDir, first.Hash.String()); err != nil {
+		t.Fatalf("AddWorktree() failed: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(worktreeDir, "README.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "first" {
+		t.Errorf("README.md in worktree = %q, want %q", got, "first")
+	}
+	// The worktree of the repository is left unchanged.
+	got, err = os.ReadFile(filepath.Join(dir, "README.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "second" {
+		t.Errorf("README.md in repository = %q, want %q", got, "second")
+	}
+
+	if err := repo.RemoveWorktree(worktreeDir); err != nil {
+		t.Fatalf("RemoveWorktree() failed: %v", err)
+	}
+	if _, err := os.Stat(worktreeDir); !os.IsNotExist(err) {
+		t.Errorf("worktree should be removed, got err %v", err)
+	}
+}
+
+func TestAddWorktree_UnknownCommit(t *testing.T) {
+	t.Parallel()
+	r, dir := initTestRepo(t)
+	createAndCommit(t, r, "README.md", []byte("hello"), "initial commit")
+	repo := &LocalRepository{Dir: dir, repo: r}
+	if err := repo.AddWorktree(filepath.Join(t.TempDir(), "worktree"), strings.Repeat("a", 40)); err == nil {
+		t.Error("AddWorktree() should fail for an unknown commit")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	r, dir := initTestRepo(t)
+	createAndCommit(t, r, "modified.txt", []byte("before\n"), "initial commit")
+	createAndCommit(t, r, "deleted.txt", []byte("deleted\n"), "add deleted.txt")
+	createAndCommit(t, r, "unchanged.txt", []byte("unchanged\n"), "add unchanged.txt")
+	if err := os.WriteFile(filepath.Join(dir, "modified.txt"), []byte("after\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "deleted.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "added.txt"), []byte("added\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	repo := &LocalRepository{Dir: dir, repo: r}
+
+	got, err := repo.Diff([]string{"added.txt", "deleted.txt", "modified.txt"})
+	if err != nil {
+		t.Fatalf("Diff() failed: %v", err)
+	}
+	for _, want := range []string{
+		"+++ b/added.txt\n@@ -0,0 +1 @@\n+added\n",
+		"--- a/deleted.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-deleted\n",
+		"@@ -1 +1 @@\n-before\n+after\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Diff() = %q, want it to contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, "unchanged.txt") {
+		t.Errorf("Diff() = %q, should not contain unchanged.txt", got)
+	}
+
+	got, err = repo.Diff(nil)
+	if err != nil {
+		t.Fatalf("Diff() failed: %v", err)
+	}
+	if got != "" {
+		t.Errorf("Diff(nil) = %q, want empty", got)
+	}
+}
+
+func TestFetchUpstream_NoOrigin(t *testing.T) {
+	t.Parallel()
+	r, dir := initTestRepo(t)
+	createAndCommit(t, r, "README.md", []byte("hello"), "initial commit")
+	repo := &LocalRepository{Dir: dir, repo: r}
+	if _, err := repo.FetchUpstream("main"); err == nil {
+		t.Error("FetchUpstream() should fail without an origin remote")
+	}
+}
+
 func initTestRepo(t *testing.T) (*git.Repository, string) {
 	t.Helper()
 	dir := t.TempDir()
@@ -2130,3 +2685,56 @@ func TestResetSoft(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSignature(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name    string
+		input   string
+		want    *Signature
+		wantErr bool
+	}{
+		{
+			name:  "valid identity",
+			input: "Release Bot <release-bot@example.com>",
+			want:  &Signature{Name: "Release Bot", Email: "release-bot@example.com"},
+		},
+		{
+			name:  "surrounding whitespace",
+			input: "  Release Bot   <release-bot@example.com> ",
+			want:  &Signature{Name: "Release Bot", Email: "release-bot@example.com"},
+		},
+		{
+			name:    "missing email",
+			input:   "Release Bot",
+			wantErr: true,
+		},
+		{
+			name:    "missing name",
+			input:   "<release-bot@example.com>",
+			wantErr: true,
+		},
+		{
+			name:    "invalid email",
+			input:   "Release Bot <release-bot>",
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseSignature(test.input)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("ParseSignature(%q) succeeded, want error", test.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
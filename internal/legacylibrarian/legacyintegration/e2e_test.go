@@ -686,6 +686,13 @@ libraries:
 					return
 				}
 
+				// Mock endpoint for POST /repos/{owner}/{repo}/git/tags (creating an annotated tag object)
+				if r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/git/tags") {
+					w.WriteHeader(http.StatusCreated)
+					fmt.Fprint(w, `{"tag": "go-google-cloud-pubsub-v1-v1.0.1", "sha": "abcdef123456"}`)
+					return
+				}
+
 				// Mock endpoint for POST /repos/{owner}/{repo}/git/refs (creating the release-please tag)
 				if r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/git/refs") {
 					w.WriteHeader(http.StatusCreated)
@@ -0,0 +1,163 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+)
+
+// apiVersionRegex matches the last element of a versioned API path, e.g. "v1"
+// or "v2beta1", capturing its major version and pre-release suffix.
+var apiVersionRegex = regexp.MustCompile(`^v(\d+)(?:p\d+)?((?:alpha|beta)\d*)?$`)
+
+// migrateRenamedAPIs moves the APIs of the libraries in state whose paths no
+// longer exist in sourceDir to their new paths. The new path of an API is the
+// one configured in the api_renames of librarianConfig or, if none, the only
+// newer stable version directory next to its old path which is not the path
+// of another API, e.g. "google/cloud/x/v2" for "google/cloud/x/v1" or
+// "google/cloud/x/v1" for "google/cloud/x/v1beta1". Any other rename must be
+// configured in api_renames. APIs without a new path are left unchanged.
+//
+// The service config of a migrated API is found again in its new path, using
+// the service_configs of the library, if any. The output generated from the
+// old path is removed, as configured by the remove_regex of the library, when
+// the library is next generated.
+func migrateRenamedAPIs(state *legacyconfig.LibrarianState, librarianConfig *legacyconfig.LibrarianConfig, sourceDir string) error {
+	var renames map[string]string
+	if librarianConfig != nil {
+		renames = librarianConfig.APIRenames
+	}
+	apiPaths := map[string]bool{}
+	for _, library := range state.Libraries {
+		for _, api := range library.APIs {
+			apiPaths[api.Path] = true
+		}
+	}
+	for _, library := range state.Libraries {
+		for _, api := range library.APIs {
+			exists, err := dirExists(filepath.Join(sourceDir, api.Path))
+			if err != nil {
+				return err
+			}
+			if exists {
+				continue
+			}
+			newPath, err := renamedAPIPath(api.Path, renames, apiPaths, sourceDir)
+			if err != nil {
+				return fmt.Errorf("failed to migrate API %q of library %q: %w", api.Path, library.ID, err)
+			}
+			if newPath == "" {
+				slog.Warn("API path not found in the API source and no renamed path detected", "id", library.ID, "path", api.Path)
+				continue
+			}
+			serviceConfig, err := findServiceConfig(librarianConfig, library.ID, sourceDir, newPath)
+			if err != nil {
+				return err
+			}
+			slog.Info("migrating renamed API", "id", library.ID, "from", api.Path, "to", newPath)
+			delete(apiPaths, api.Path)
+			apiPaths[newPath] = true
+			api.Path = newPath
+			api.ServiceConfig = serviceConfig
+		}
+	}
+	return nil
+}
+
+// renamedAPIPath returns the new path of the API at oldPath, or an empty
+// string if none is found. apiPaths are the paths of the configured APIs,
+// which are never the new path of another API.
+func renamedAPIPath(oldPath string, renames map[string]string, apiPaths map[string]bool, sourceDir string) (string, error) {
+	if newPath, ok := renames[oldPath]; ok {
+		if apiPaths[newPath] {
+			return "", fmt.Errorf("renamed path %q is already the path of an API", newPath)
+		}
+		exists, err := dirExists(filepath.Join(sourceDir, newPath))
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return "", fmt.Errorf("renamed path %q not found in the API source", newPath)
+		}
+		return newPath, nil
+	}
+	oldVersion := apiVersionRegex.FindStringSubmatch(path.Base(oldPath))
+	if oldVersion == nil {
+		return "", nil
+	}
+	parent := path.Dir(oldPath)
+	entries, err := os.ReadDir(filepath.Join(sourceDir, parent))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var candidates []string
+	for _, entry := range entries {
+		candidate := path.Join(parent, entry.Name())
+		if entry.IsDir() && isNewerStableVersion(entry.Name(), oldVersion) && !apiPaths[candidate] {
+			candidates = append(candidates, candidate)
+		}
+	}
+	if len(candidates) != 1 {
+		if len(candidates) > 1 {
+			slog.Warn("several renamed API paths detected, configure the rename in api_renames", "path", oldPath, "candidates", candidates)
+		}
+		return "", nil
+	}
+	return candidates[0], nil
+}
+
+// isNewerStableVersion reports whether name is a stable API version, e.g.
+// "v2", newer than oldVersion, the submatches of apiVersionRegex for the old
+// version. A stable version is newer than the pre-release versions of the
+// same major version, e.g. "v1" is newer than "v1beta1".
+func isNewerStableVersion(name string, oldVersion []string) bool {
+	version := apiVersionRegex.FindStringSubmatch(name)
+	if version == nil || version[2] != "" {
+		return false
+	}
+	major, err := strconv.Atoi(version[1])
+	if err != nil {
+		return false
+	}
+	oldMajor, err := strconv.Atoi(oldVersion[1])
+	if err != nil {
+		return false
+	}
+	return major > oldMajor || (major == oldMajor && oldVersion[2] != "")
+}
+
+// dirExists reports whether path is an existing directory.
+func dirExists(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return info.IsDir(), nil
+}
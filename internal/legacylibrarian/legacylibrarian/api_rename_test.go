@@ -0,0 +1,182 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+)
+
+func TestMigrateRenamedAPIs(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name string
+		// dirs are the API directories in the API source.
+		dirs    []string
+		renames map[string]string
+		// serviceConfigs are the service_configs of the library.
+		serviceConfigs map[string]string
+		apis           []*legacyconfig.API
+		want           []*legacyconfig.API
+		wantErrMsg     string
+	}{
+		{
+			name: "existing paths",
+			dirs: []string{"google/cloud/x/v1", "google/cloud/x/v2"},
+			apis: []*legacyconfig.API{{Path: "google/cloud/x/v1", ServiceConfig: "x_v1.yaml"}},
+			want: []*legacyconfig.API{{Path: "google/cloud/x/v1", ServiceConfig: "x_v1.yaml"}},
+		},
+		{
+			name: "renamed version",
+			dirs: []string{"google/cloud/x/v2"},
+			apis: []*legacyconfig.API{{Path: "google/cloud/x/v1", ServiceConfig: "x_v1.yaml"}},
+			want: []*legacyconfig.API{{Path: "google/cloud/x/v2", ServiceConfig: "x_v2.yaml"}},
+		},
+		{
+			name: "other version already configured",
+			dirs: []string{"google/cloud/x/v2", "google/cloud/x/v3"},
+			apis: []*legacyconfig.API{
+				{Path: "google/cloud/x/v1", ServiceConfig: "x_v1.yaml"},
+				{Path: "google/cloud/x/v2", ServiceConfig: "x_v2.yaml"},
+			},
+			want: []*legacyconfig.API{
+				{Path: "google/cloud/x/v3", ServiceConfig: "x_v3.yaml"},
+				{Path: "google/cloud/x/v2", ServiceConfig: "x_v2.yaml"},
+			},
+		},
+		{
+			name: "stable and pre-release versions",
+			dirs: []string{"google/cloud/x/v2", "google/cloud/x/v2beta"},
+			apis: []*legacyconfig.API{{Path: "google/cloud/x/v1", ServiceConfig: "x_v1.yaml"}},
+			want: []*legacyconfig.API{{Path: "google/cloud/x/v2", ServiceConfig: "x_v2.yaml"}},
+		},
+		{
+			name: "several stable versions",
+			dirs: []string{"google/cloud/x/v2", "google/cloud/x/v3"},
+			apis: []*legacyconfig.API{{Path: "google/cloud/x/v1", ServiceConfig: "x_v1.yaml"}},
+			want: []*legacyconfig.API{{Path: "google/cloud/x/v1", ServiceConfig: "x_v1.yaml"}},
+		},
+		{
+			name: "promoted to stable",
+			dirs: []string{"google/cloud/x/v1"},
+			apis: []*legacyconfig.API{{Path: "google/cloud/x/v1beta1", ServiceConfig: "x_v1beta1.yaml"}},
+			want: []*legacyconfig.API{{Path: "google/cloud/x/v1", ServiceConfig: "x_v1.yaml"}},
+		},
+		{
+			name: "only pre-release version",
+			dirs: []string{"google/cloud/x/v1beta1"},
+			apis: []*legacyconfig.API{{Path: "google/cloud/x/v1", ServiceConfig: "x_v1.yaml"}},
+			want: []*legacyconfig.API{{Path: "google/cloud/x/v1", ServiceConfig: "x_v1.yaml"}},
+		},
+		{
+			name: "only older version",
+			dirs: []string{"google/cloud/x/v1"},
+			apis: []*legacyconfig.API{{Path: "google/cloud/x/v2", ServiceConfig: "x_v2.yaml"}},
+			want: []*legacyconfig.API{{Path: "google/cloud/x/v2", ServiceConfig: "x_v2.yaml"}},
+		},
+		{
+			name: "unversioned path",
+			dirs: []string{"google/cloud/y"},
+			apis: []*legacyconfig.API{{Path: "google/cloud/x", ServiceConfig: "x.yaml"}},
+			want: []*legacyconfig.API{{Path: "google/cloud/x", ServiceConfig: "x.yaml"}},
+		},
+		{
+			name:    "configured rename",
+			dirs:    []string{"google/cloud/y/v1", "google/cloud/x/v2"},
+			renames: map[string]string{"google/cloud/x/v1": "google/cloud/y/v1"},
+			apis:    []*legacyconfig.API{{Path: "google/cloud/x/v1", ServiceConfig: "x_v1.yaml"}},
+			want:    []*legacyconfig.API{{Path: "google/cloud/y/v1", ServiceConfig: "y_v1.yaml"}},
+		},
+		{
+			name:           "configured service config",
+			dirs:           []string{"google/cloud/x/v2"},
+			serviceConfigs: map[string]string{"google/cloud/x/v2": "x_*.yaml"},
+			apis:           []*legacyconfig.API{{Path: "google/cloud/x/v1", ServiceConfig: "x_v1.yaml"}},
+			want:           []*legacyconfig.API{{Path: "google/cloud/x/v2", ServiceConfig: "x_v2.yaml"}},
+		},
+		{
+			name:           "configured service config not found",
+			dirs:           []string{"google/cloud/x/v2"},
+			serviceConfigs: map[string]string{"google/cloud/x/v2": "other.yaml"},
+			apis:           []*legacyconfig.API{{Path: "google/cloud/x/v1", ServiceConfig: "x_v1.yaml"}},
+			wantErrMsg:     `no service config matching "other.yaml"`,
+		},
+		{
+			name:       "configured rename not found",
+			renames:    map[string]string{"google/cloud/x/v1": "google/cloud/y/v1"},
+			apis:       []*legacyconfig.API{{Path: "google/cloud/x/v1"}},
+			wantErrMsg: `renamed path "google/cloud/y/v1" not found in the API source`,
+		},
+		{
+			name:    "configured rename to an existing API",
+			dirs:    []string{"google/cloud/y/v1"},
+			renames: map[string]string{"google/cloud/x/v1": "google/cloud/y/v1"},
+			apis: []*legacyconfig.API{
+				{Path: "google/cloud/x/v1"},
+				{Path: "google/cloud/y/v1"},
+			},
+			wantErrMsg: `renamed path "google/cloud/y/v1" is already the path of an API`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			sourceDir := t.TempDir()
+			for _, dir := range test.dirs {
+				writeTestServiceConfig(t, filepath.Join(sourceDir, dir))
+			}
+			state := &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{{ID: "x", APIs: test.apis}},
+			}
+			librarianConfig := &legacyconfig.LibrarianConfig{
+				APIRenames: test.renames,
+				Libraries: []*legacyconfig.LibraryConfig{
+					{LibraryID: "x", ServiceConfigs: test.serviceConfigs},
+				},
+			}
+			err := migrateRenamedAPIs(state, librarianConfig, sourceDir)
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("migrateRenamedAPIs() error = %v, want contains %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, state.Libraries[0].APIs); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// writeTestServiceConfig writes the service config of the API in dir, named
+// after the last two elements of dir, e.g. x_v1.yaml for google/cloud/x/v1.
+func writeTestServiceConfig(t *testing.T, dir string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Base(filepath.Dir(dir)) + "_" + filepath.Base(dir) + ".yaml"
+	content := "type: google.api.Service\nname: " + name + "\n"
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacygitrepo"
+)
+
+// openAPISources returns the repository of the API sources specified in cfg.
+// cfg.APISource may be a comma-separated list of sources, which are then
+// layered into a single source tree, see [layerAPISources].
+func openAPISources(cfg *legacyconfig.Config) (legacygitrepo.Repository, error) {
+	sources := strings.Split(cfg.APISource, ",")
+	if len(sources) == 1 {
+		return cloneOrOpenAPISource(cfg, cfg.APISource)
+	}
+	var repos []legacygitrepo.Repository
+	for _, source := range sources {
+		source = strings.TrimSpace(source)
+		if source == "" {
+			return nil, fmt.Errorf("empty API source in %q", cfg.APISource)
+		}
+		repo, err := cloneOrOpenAPISource(cfg, source)
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, repo)
+	}
+	return layerAPISources(filepath.Join(cfg.WorkRoot, "api-source"), repos)
+}
+
+// layeredSourceRepository is the source repository of libraries generated
+// from several API sources. It behaves as the first source, the base one,
+// except that its directory is the combined source tree of all sources.
+type layeredSourceRepository struct {
+	legacygitrepo.Repository
+	dir string
+	// overlays are the sources layered on top of the base source, in order.
+	overlays []legacygitrepo.Repository
+}
+
+// GetDir returns the directory of the combined source tree.
+func (r *layeredSourceRepository) GetDir() string {
+	return r.dir
+}
+
+// layerAPISources copies the files of the repositories, except their .git
+// directory, into dir, in order, so that the files of later repositories
+// override those of earlier ones.
+func layerAPISources(dir string, repos []legacygitrepo.Repository) (*layeredSourceRepository, error) {
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+	for _, repo := range repos {
+		slog.Info("layering API source", "source", repo.GetDir(), "destination", dir)
+		src := repo.GetDir()
+		err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.Name() == ".git" {
+				// The .git of a worktree is a file.
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+			relativePath, err := filepath.Rel(src, path)
+			if err != nil {
+				return err
+			}
+			return copyFile(filepath.Join(dir, relativePath), path)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to layer API source %s: %w", src, err)
+		}
+	}
+	return &layeredSourceRepository{
+		Repository: repos[0],
+		dir:        dir,
+		overlays:   repos[1:],
+	}, nil
+}
+
+// layerOnto layers the overlays of r onto base, instead of the base source of
+// r, into dir. It is used to generate a library from a pinned commit of the
+// base source.
+func (r *layeredSourceRepository) layerOnto(dir string, base legacygitrepo.Repository) (*layeredSourceRepository, error) {
+	return layerAPISources(dir, append([]legacygitrepo.Repository{base}, r.overlays...))
+}
+
+// sourceProvenance returns the commit recorded as the last generated commit
+// of libraries generated from sourceRepo. It is the HEAD commit of
+// sourceRepo, or, for several API sources, the composite of the HEAD commits
+// of all sources.
+func sourceProvenance(sourceRepo legacygitrepo.Repository) (string, error) {
+	hash, err := sourceRepo.HeadHash()
+	if err != nil {
+		return "", err
+	}
+	layered, ok := sourceRepo.(*layeredSourceRepository)
+	if !ok {
+		return hash, nil
+	}
+	hashes := []string{hash}
+	for _, overlay := range layered.overlays {
+		hash, err := overlay.HeadHash()
+		if err != nil {
+			return "", err
+		}
+		hashes = append(hashes, hash)
+	}
+	return strings.Join(hashes, legacyconfig.CompositeCommitSeparator), nil
+}
+
+// overlaysChanged reports whether anything under the paths of the APIs has
+// changed in the overlays between the given commits, one per overlay, and
+// their HEAD commits. If the number of commits does not match the number of
+// overlays, the sources have changed and true is returned.
+func (r *layeredSourceRepository) overlaysChanged(apis []*legacyconfig.API, commits []string) (bool, error) {
+	if len(commits) != len(r.overlays) {
+		return true, nil
+	}
+	for i, overlay := range r.overlays {
+		headHash, err := overlay.HeadHash()
+		if err != nil {
+			return false, fmt.Errorf("failed to get head hash for API source %s: %v", overlay.GetDir(), err)
+		}
+		changed, err := pathsChanged(overlay, apis, commits[i], headHash)
+		if err != nil || changed {
+			return changed, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,218 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacygitrepo"
+)
+
+// newTestAPISource creates a git repository with the given files, keyed by
+// their path relative to the repository root, in a new commit.
+func newTestAPISource(t *testing.T, files map[string]string) *legacygitrepo.LocalRepository {
+	t.Helper()
+	dir := newTestGitRepoWithCommit(t, "")
+	repo, err := legacygitrepo.NewRepository(&legacygitrepo.RepositoryOptions{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitTestFiles(t, repo, files)
+	return repo
+}
+
+// commitTestFiles writes the given files into repo, and commits them.
+func commitTestFiles(t *testing.T, repo legacygitrepo.Repository, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(repo.GetDir(), name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runGit(t, repo.GetDir(), "add", ".")
+	runGit(t, repo.GetDir(), "commit", "-m", "feat: update protos")
+}
+
+func TestOpenAPISources(t *testing.T) {
+	t.Parallel()
+	base := newTestAPISource(t, map[string]string{
+		"google/cloud/foo/v1/foo.proto":    "base foo",
+		"google/cloud/foo/v1/shared.proto": "base shared",
+	})
+	overlay := newTestAPISource(t, map[string]string{
+		"google/cloud/foo/v1/shared.proto": "overlay shared",
+		"private/bar/v1/bar.proto":         "overlay bar",
+	})
+	cfg := &legacyconfig.Config{
+		APISource: base.GetDir() + ", " + overlay.GetDir(),
+		WorkRoot:  t.TempDir(),
+	}
+	repo, err := openAPISources(cfg)
+	if err != nil {
+		t.Fatalf("openAPISources() failed: %v", err)
+	}
+	layered, ok := repo.(*layeredSourceRepository)
+	if !ok {
+		t.Fatalf("openAPISources() = %T, want *layeredSourceRepository", repo)
+	}
+	if want := filepath.Join(cfg.WorkRoot, "api-source"); layered.GetDir() != want {
+		t.Errorf("GetDir() = %q, want %q", layered.GetDir(), want)
+	}
+	for name, want := range map[string]string{
+		"README.md":                        "hello",
+		"google/cloud/foo/v1/foo.proto":    "base foo",
+		"google/cloud/foo/v1/shared.proto": "overlay shared",
+		"private/bar/v1/bar.proto":         "overlay bar",
+	} {
+		got, err := os.ReadFile(filepath.Join(layered.GetDir(), name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, string(got)); diff != "" {
+			t.Errorf("%s mismatch (-want +got):\n%s", name, diff)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(layered.GetDir(), ".git")); !os.IsNotExist(err) {
+		t.Errorf("layered source tree has a .git directory, err = %v", err)
+	}
+
+	// The base source provides the history.
+	baseHead, err := base.HeadHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlayHead, err := overlay.HeadHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotHead, err := layered.HeadHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotHead != baseHead {
+		t.Errorf("HeadHash() = %q, want %q", gotHead, baseHead)
+	}
+	gotProvenance, err := sourceProvenance(layered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := baseHead + "+" + overlayHead; gotProvenance != want {
+		t.Errorf("sourceProvenance() = %q, want %q", gotProvenance, want)
+	}
+}
+
+func TestOpenAPISources_SingleSource(t *testing.T) {
+	t.Parallel()
+	source := newTestAPISource(t, map[string]string{"google/cloud/foo/v1/foo.proto": "foo"})
+	repo, err := openAPISources(&legacyconfig.Config{APISource: source.GetDir(), WorkRoot: t.TempDir()})
+	if err != nil {
+		t.Fatalf("openAPISources() failed: %v", err)
+	}
+	if repo.GetDir() != source.GetDir() {
+		t.Errorf("GetDir() = %q, want %q", repo.GetDir(), source.GetDir())
+	}
+	head, err := source.HeadHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := sourceProvenance(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != head {
+		t.Errorf("sourceProvenance() = %q, want %q", got, head)
+	}
+}
+
+func TestOpenAPISources_EmptySource(t *testing.T) {
+	t.Parallel()
+	source := newTestGitRepoWithCommit(t, "")
+	_, err := openAPISources(&legacyconfig.Config{APISource: source + ",", WorkRoot: t.TempDir()})
+	if err == nil || !strings.Contains(err.Error(), "empty API source") {
+		t.Errorf("openAPISources() error = %v, want error containing %q", err, "empty API source")
+	}
+}
+
+func TestAPIsChangedLayered(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name         string
+		overlayFiles map[string]string
+		singleCommit bool
+		want         bool
+	}{
+		{
+			name: "no change",
+		},
+		{
+			name:         "API changed in the overlay",
+			overlayFiles: map[string]string{"google/cloud/foo/v1/shared.proto": "new overlay shared"},
+			want:         true,
+		},
+		{
+			name:         "other path changed in the overlay",
+			overlayFiles: map[string]string{"private/bar/v1/bar.proto": "new overlay bar"},
+		},
+		{
+			name:         "last generated from a single source",
+			singleCommit: true,
+			want:         true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			base := newTestAPISource(t, map[string]string{"google/cloud/foo/v1/foo.proto": "base foo"})
+			overlay := newTestAPISource(t, map[string]string{
+				"google/cloud/foo/v1/shared.proto": "overlay shared",
+				"private/bar/v1/bar.proto":         "overlay bar",
+			})
+			layered, err := layerAPISources(filepath.Join(t.TempDir(), "api-source"), []legacygitrepo.Repository{base, overlay})
+			if err != nil {
+				t.Fatal(err)
+			}
+			lastGenCommit, err := sourceProvenance(layered)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.singleCommit {
+				lastGenCommit = legacyconfig.SplitCompositeCommit(lastGenCommit)[0]
+			}
+			if test.overlayFiles != nil {
+				commitTestFiles(t, overlay, test.overlayFiles)
+			}
+			r := &generateRunner{sourceRepo: layered}
+			got, err := r.apisChanged(&legacyconfig.LibraryState{
+				ID:                  "foo",
+				APIs:                []*legacyconfig.API{{Path: "google/cloud/foo/v1"}},
+				LastGeneratedCommit: lastGenCommit,
+			})
+			if err != nil {
+				t.Fatalf("apisChanged() failed: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("apisChanged() = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
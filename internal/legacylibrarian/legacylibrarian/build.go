@@ -26,18 +26,19 @@ import (
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacygitrepo"
 )
 
-func buildSingleLibrary(ctx context.Context, containerClient ContainerClient, state *legacyconfig.LibrarianState, libraryState *legacyconfig.LibraryState, repo legacygitrepo.Repository) error {
+func buildSingleLibrary(ctx context.Context, containerClient ContainerClient, state *legacyconfig.LibrarianState, libraryState *legacyconfig.LibraryState, repo legacygitrepo.Repository, librarianDir string) error {
 	if libraryState == nil {
 		return fmt.Errorf("no libraryState provided")
 	}
 	buildRequest := &legacydocker.BuildRequest{
-		LibraryID: libraryState.ID,
-		RepoDir:   repo.GetDir(),
-		State:     state,
+		LibraryID:    libraryState.ID,
+		LibrarianDir: librarianDir,
+		RepoDir:      repo.GetDir(),
+		State:        state,
 	}
 	slog.Info("performing build for library", "id", libraryState.ID)
 	if containerErr := containerClient.Build(ctx, buildRequest); containerErr != nil {
-		if restoreErr := restoreLibrary(libraryState, repo); restoreErr != nil {
+		if restoreErr := restoreLibrary(libraryState.SourceRoots, repo); restoreErr != nil {
 			return errors.Join(containerErr, restoreErr)
 		}
 
@@ -46,8 +47,8 @@ func buildSingleLibrary(ctx context.Context, containerClient ContainerClient, st
 
 	// Read the library state from the response.
 	if _, responseErr := readLibraryState(
-		filepath.Join(buildRequest.RepoDir, legacyconfig.LibrarianDir, legacyconfig.BuildResponse)); responseErr != nil {
-		if restoreErr := restoreLibrary(libraryState, repo); restoreErr != nil {
+		filepath.Join(responseDir(librarianDir, repo), legacyconfig.BuildResponse)); responseErr != nil {
+		if restoreErr := restoreLibrary(libraryState.SourceRoots, repo); restoreErr != nil {
 			return errors.Join(responseErr, restoreErr)
 		}
 
@@ -0,0 +1,88 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+)
+
+// buildFileData is the library metadata available to build file templates.
+type buildFileData struct {
+	ID       string
+	Version  string
+	Language string
+	// SourceRoot is the source root the build file is rendered into.
+	SourceRoot string
+	// APIs are the paths of the APIs of the library, e.g. google/cloud/foo/v1.
+	APIs []string
+}
+
+// writeBuildFiles renders the build file template configured for library in
+// config.yaml, if any, into each of its source roots in repoDir, given by
+// destRoots relative to repoDir. Build files that already exist, e.g. because
+// the container created them, are left untouched.
+func writeBuildFiles(librarianConfig *legacyconfig.LibrarianConfig, repoDir string, library *legacyconfig.LibraryState, destRoots []string) error {
+	if librarianConfig == nil {
+		return nil
+	}
+	libraryConfig := librarianConfig.LibraryConfigFor(library.ID)
+	if libraryConfig == nil || libraryConfig.BuildFileTemplate == "" {
+		return nil
+	}
+	templatePath := filepath.Join(repoDir, libraryConfig.BuildFileTemplate)
+	tmpl, err := template.New(filepath.Base(templatePath)).ParseFiles(templatePath)
+	if err != nil {
+		return fmt.Errorf("failed to parse build file template: %w", err)
+	}
+	name := strings.TrimSuffix(filepath.Base(templatePath), legacyconfig.BuildFileTemplateExt)
+	var apis []string
+	for _, api := range library.APIs {
+		apis = append(apis, api.Path)
+	}
+	for i, sourceRoot := range library.SourceRoots {
+		path := filepath.Join(repoDir, destRoots[i], name)
+		if _, err := os.Stat(path); err == nil {
+			slog.Info("build file already exists, skipping", "library", library.ID, "path", path)
+			continue
+		}
+		data := &buildFileData{
+			ID:         library.ID,
+			Version:    library.Version,
+			Language:   library.Language,
+			SourceRoot: sourceRoot,
+			APIs:       apis,
+		}
+		var buffer bytes.Buffer
+		if err := tmpl.Execute(&buffer, data); err != nil {
+			return fmt.Errorf("failed to render build file %s: %w", path, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, buffer.Bytes(), 0644); err != nil {
+			return err
+		}
+		slog.Info("wrote build file", "library", library.ID, "path", path)
+	}
+	return nil
+}
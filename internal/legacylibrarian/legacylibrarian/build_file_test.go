@@ -0,0 +1,183 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+)
+
+func TestWriteBuildFiles(t *testing.T) {
+	t.Parallel()
+	const buildFileTemplate = `# Generated for {{.ID}} {{.Version}} ({{.Language}}).
+package(default_visibility = ["//visibility:public"])
+
+filegroup(
+    name = "{{.ID}}",
+    srcs = glob(["**"]),
+)
+{{range .APIs}}
+# {{.}} in {{$.SourceRoot}}
+{{- end}}
+`
+	library := &legacyconfig.LibraryState{
+		ID:       "secretmanager",
+		Version:  "0.0.0",
+		Language: "python",
+		APIs: []*legacyconfig.API{
+			{Path: "google/cloud/secretmanager/v1"},
+			{Path: "google/cloud/secretmanager/v1beta"},
+		},
+		SourceRoots: []string{"packages/secretmanager", "other/secretmanager"},
+	}
+	wantBuildFile := func(sourceRoot string) string {
+		return `# Generated for secretmanager 0.0.0 (python).
+package(default_visibility = ["//visibility:public"])
+
+filegroup(
+    name = "secretmanager",
+    srcs = glob(["**"]),
+)
+
+# google/cloud/secretmanager/v1 in ` + sourceRoot + `
+# google/cloud/secretmanager/v1beta in ` + sourceRoot + `
+`
+	}
+	for _, test := range []struct {
+		name            string
+		librarianConfig *legacyconfig.LibrarianConfig
+		template        string
+		existing        map[string]string
+		want            map[string]string
+		wantErrMsg      string
+	}{
+		{
+			name: "render into each source root",
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				Libraries: []*legacyconfig.LibraryConfig{
+					{LibraryID: "secretmanager", BuildFileTemplate: "templates/BUILD.bazel.tmpl"},
+				},
+			},
+			template: buildFileTemplate,
+			want: map[string]string{
+				"packages/secretmanager/BUILD.bazel": wantBuildFile("packages/secretmanager"),
+				"other/secretmanager/BUILD.bazel":    wantBuildFile("other/secretmanager"),
+			},
+		},
+		{
+			name: "existing build file is kept",
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				Libraries: []*legacyconfig.LibraryConfig{
+					{LibraryID: "secretmanager", BuildFileTemplate: "templates/BUILD.bazel.tmpl"},
+				},
+			},
+			template: buildFileTemplate,
+			existing: map[string]string{
+				"other/secretmanager/BUILD.bazel": "# written by the container\n",
+			},
+			want: map[string]string{
+				"packages/secretmanager/BUILD.bazel": wantBuildFile("packages/secretmanager"),
+				"other/secretmanager/BUILD.bazel":    "# written by the container\n",
+			},
+		},
+		{
+			name: "no build file template",
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				Libraries: []*legacyconfig.LibraryConfig{
+					{LibraryID: "secretmanager"},
+				},
+			},
+		},
+		{
+			name: "no librarian config",
+		},
+		{
+			name: "missing template",
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				Libraries: []*legacyconfig.LibraryConfig{
+					{LibraryID: "secretmanager", BuildFileTemplate: "templates/BUILD.bazel.tmpl"},
+				},
+			},
+			wantErrMsg: "failed to parse build file template",
+		},
+		{
+			name: "unknown field in template",
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				Libraries: []*legacyconfig.LibraryConfig{
+					{LibraryID: "secretmanager", BuildFileTemplate: "templates/BUILD.bazel.tmpl"},
+				},
+			},
+			template:   "{{.Unknown}}",
+			wantErrMsg: "failed to render build file",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repoDir := t.TempDir()
+			files := map[string]string{}
+			for path, content := range test.existing {
+				files[path] = content
+			}
+			if test.template != "" {
+				files["templates/BUILD.bazel.tmpl"] = test.template
+			}
+			for path, content := range files {
+				path = filepath.Join(repoDir, path)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := writeBuildFiles(test.librarianConfig, repoDir, library, library.SourceRoots)
+			if test.wantErrMsg != "" {
+				if err == nil {
+					t.Fatalf("writeBuildFiles() should return error")
+				}
+				if !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Errorf("got %q, want contains %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, sourceRoot := range library.SourceRoots {
+				path := filepath.Join(sourceRoot, "BUILD.bazel")
+				got, err := os.ReadFile(filepath.Join(repoDir, path))
+				want, ok := test.want[path]
+				if !ok {
+					if !os.IsNotExist(err) {
+						t.Errorf("%s should not exist, got err %v", path, err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(want, string(got)); diff != "" {
+					t.Errorf("%s mismatch (-want +got):\n%s", path, diff)
+				}
+			}
+		})
+	}
+}
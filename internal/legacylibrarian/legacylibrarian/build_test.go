@@ -107,7 +107,7 @@ func TestBuildSingleLibrary(t *testing.T) {
 			}
 
 			libraryState := state.LibraryByID(test.libraryID)
-			err := buildSingleLibrary(t.Context(), test.container, state, libraryState, repo)
+			err := buildSingleLibrary(t.Context(), test.container, state, libraryState, repo, "")
 			if test.wantErr {
 				if err == nil {
 					t.Fatal(err)
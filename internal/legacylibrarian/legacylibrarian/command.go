@@ -17,6 +17,8 @@ package legacylibrarian
 import (
 	"cmp"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -31,6 +33,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacydocker"
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacygithub"
@@ -46,6 +49,10 @@ For each failed library, open a ticket in that library’s repository and then y
 `
 )
 
+// amendableCommitFooter marks commits which may be amended by a later run with
+// amend set.
+const amendableCommitFooter = "Librarian-Amendable: true"
+
 type pullRequestType int
 
 const (
@@ -80,14 +87,22 @@ var globalPreservePatterns = []string{
 type GitHubClient interface {
 	GetRawContent(ctx context.Context, path, ref string) ([]byte, error)
 	CreatePullRequest(ctx context.Context, repo *legacygithub.Repository, remoteBranch, remoteBase, title, body string, isDraft bool) (*legacygithub.PullRequestMetadata, error)
+	UpdatePullRequestBody(ctx context.Context, number int, body string) error
 	AddLabelsToIssue(ctx context.Context, repo *legacygithub.Repository, number int, labels []string) error
+	RequestReviewers(ctx context.Context, repo *legacygithub.Repository, number int, reviewers []string) error
 	GetLabels(ctx context.Context, number int) ([]string, error)
 	ReplaceLabels(ctx context.Context, number int, labels []string) error
 	SearchPullRequests(ctx context.Context, query string) ([]*legacygithub.PullRequest, error)
 	GetPullRequest(ctx context.Context, number int) (*legacygithub.PullRequest, error)
 	CreateRelease(ctx context.Context, tagName, name, body, commitish string) (*legacygithub.RepositoryRelease, error)
+	GetReleaseByTag(ctx context.Context, tagName string) (*legacygithub.RepositoryRelease, error)
 	CreateIssueComment(ctx context.Context, number int, comment string) error
 	CreateTag(ctx context.Context, tag, commitish string) error
+	TagExists(ctx context.Context, tag string) (bool, error)
+	CreateAnnotatedTag(ctx context.Context, tag, message, commitish string) error
+	CreateBranch(ctx context.Context, branch, commitish string) error
+	GetBranchHead(ctx context.Context, branch string) (string, error)
+	UpdateFile(ctx context.Context, branch, path, message string, content []byte) error
 }
 
 // ContainerClient is an abstraction over the Docker client.
@@ -98,21 +113,58 @@ type ContainerClient interface {
 	ReleaseStage(ctx context.Context, request *legacydocker.ReleaseStageRequest) error
 }
 
+// closeContainerClient stops the long-lived containers started by client, if
+// any.
+func closeContainerClient(client ContainerClient) {
+	if limited, ok := client.(*limitedContainerClient); ok {
+		client = limited.ContainerClient
+	}
+	closer, ok := client.(io.Closer)
+	if !ok {
+		return
+	}
+	if err := closer.Close(); err != nil {
+		slog.Warn("failed to stop containers", "error", err)
+	}
+}
+
 type commitInfo struct {
+	// amend declares whether to amend the HEAD commit if it was created by a
+	// previous run with amend set, instead of creating a new branch and commit.
+	amend bool
+	// author is the author of the created commit, and its committer unless
+	// committer is set. If nil, the identity is read from git config.
+	author *legacygitrepo.Signature
 	// branch is the base branch of the created pull request.
 	branch string
 	// commit declares whether to create a commit.
 	commit bool
 	// commitMessage is used as the message on the actual git commit.
 	commitMessage string
+	// committer is the committer of the created commit, and of the commit
+	// rebased onto the base branch. If nil, author is the committer.
+	committer *legacygitrepo.Signature
 	// ghClient is used to interact with the GitHub API.
 	ghClient GitHubClient
+	// librarianConfig is the librarian config.yaml contents. The default
+	// reviewers, and the maintainers of the changed libraries, are requested to
+	// review the created pull request.
+	librarianConfig *legacyconfig.LibrarianConfig
 	// prType is an enum for which type of librarian pull request we are creating.
 	prType pullRequestType
 	// pullRequestLabels is a list of labels to add to the created pull request.
 	pullRequestLabels []string
 	// push declares whether to push the commits to GitHub.
 	push bool
+	// part and parts identify the pull request when a change is split across
+	// several pull requests, e.g., part 2 of 3. They are appended to the
+	// branch name and the pull request title, and ignored unless parts is
+	// greater than one.
+	part  int
+	parts int
+	// rebaseOntoBase declares whether to rebase the created commit onto the
+	// latest base branch before pushing it.
+	rebaseOntoBase bool
 	// languageRepo is the git repository containing the language-specific libraries.
 	languageRepo legacygitrepo.Repository
 	// sourceRepo is the git repository containing the source protos.
@@ -127,10 +179,22 @@ type commitInfo struct {
 	api string
 	// library is the ID of a library, only set this value during api onboarding.
 	library string
+	// pullRequestURL is set by commitAndPush to the URL of the pull request it
+	// creates, if any.
+	pullRequestURL string
 	// prBodyBuilder is a callback function for building the pull request body
 	prBodyBuilder func() (string, error)
+	// pullRequest is the open pull request created by a previous run, if any,
+	// which is updated instead of creating a new pull request: the commit is
+	// pushed to its branch, replacing the commits of the previous run, and the
+	// managed section of its body is replaced, see [mergeManagedSection].
+	pullRequest *legacygithub.PullRequest
 	// isDraft declares whether to create the pull request as a draft.
 	isDraft bool
+	// libraryIDsFooter declares whether to append a Library-IDs footer, listing
+	// the libraries whose source roots contain changed files, to the commit
+	// message.
+	libraryIDsFooter bool
 }
 
 type commandRunner struct {
@@ -157,21 +221,24 @@ func newCommandRunner(cfg *legacyconfig.Config) (*commandRunner, error) {
 
 	// If APISource is set, checkout the protos repository.
 	if cfg.APISource != "" {
-		sourceRepo, err = cloneOrOpenRepo(cfg.WorkRoot, cfg.APISource, cfg.APISourceDepth, defaultAPISourceBranch, cfg.CI, cfg.GitHubToken)
+		sourceRepo, err = openAPISources(cfg)
 		if err != nil {
 			return nil, err
 		}
 		sourceRepoDir = sourceRepo.GetDir()
 	}
-	state, err := loadRepoState(languageRepo, sourceRepoDir)
+	librarianConfig, err := loadLibrarianConfig(languageRepo)
 	if err != nil {
 		return nil, err
 	}
 
-	librarianConfig, err := loadLibrarianConfig(languageRepo)
+	state, err := loadRepoState(languageRepo, librarianConfig, sourceRepoDir)
 	if err != nil {
 		return nil, err
 	}
+	if err := librarianConfig.CheckMaintainers(state); err != nil {
+		return nil, err
+	}
 
 	image := deriveImage(cfg.Image, state)
 
@@ -181,10 +248,22 @@ func newCommandRunner(cfg *legacyconfig.Config) (*commandRunner, error) {
 	}
 
 	ghClient := legacygithub.NewClient(cfg.GitHubToken, gitHubRepo)
+	var noNetwork []legacydocker.Command
+	for _, command := range cfg.NoNetwork {
+		noNetwork = append(noNetwork, legacydocker.Command(command))
+	}
 	container, err := legacydocker.New(cfg.WorkRoot, image, &legacydocker.DockerOptions{
-		UserUID:   cfg.UserUID,
-		UserGID:   cfg.UserGID,
-		HostMount: cfg.HostMount,
+		UserUID:         cfg.UserUID,
+		UserGID:         cfg.UserGID,
+		HostMount:       cfg.HostMount,
+		Memory:          cfg.ContainerMemory,
+		CPUs:            cfg.ContainerCPUs,
+		NoNetwork:       noNetwork,
+		Mounts:          cfg.Mounts,
+		LogDir:          cfg.ContainerLogDir,
+		Quiet:           cfg.Quiet,
+		ReuseContainers: cfg.ContainerReuse,
+		Timeout:         cfg.ContainerTimeout,
 	})
 	if err != nil {
 		return nil, err
@@ -201,6 +280,27 @@ func newCommandRunner(cfg *legacyconfig.Config) (*commandRunner, error) {
 	}, nil
 }
 
+// cloneOrOpenAPISource returns the repository of the given API source.
+// When cfg.CacheDir is set and the API source is a URL, the repository is
+// kept in cfg.CacheDir and updated to the latest commit of the default branch
+// instead of being cloned again.
+func cloneOrOpenAPISource(cfg *legacyconfig.Config, source string) (*legacygitrepo.LocalRepository, error) {
+	if cfg.CacheDir == "" || !isURL(source) {
+		return cloneOrOpenRepo(cfg.WorkRoot, source, cfg.APISourceDepth, defaultAPISourceBranch, cfg.CI, cfg.GitHubToken)
+	}
+	repoName := path.Base(strings.TrimSuffix(source, "/"))
+	return legacygitrepo.NewRepository(&legacygitrepo.RepositoryOptions{
+		Dir:          filepath.Join(cfg.CacheDir, repoName),
+		MaybeClone:   true,
+		Refresh:      true,
+		RemoteURL:    source,
+		RemoteBranch: defaultAPISourceBranch,
+		CI:           cfg.CI,
+		GitPassword:  cfg.GitHubToken,
+		Depth:        cfg.APISourceDepth,
+	})
+}
+
 func cloneOrOpenRepo(workRoot, repo string, depth int, branch, ci string, gitPassword string) (*legacygitrepo.LocalRepository, error) {
 	if repo == "" {
 		return nil, fmt.Errorf("repo must be specified")
@@ -245,6 +345,42 @@ func cloneOrOpenRepo(workRoot, repo string, depth int, branch, ci string, gitPas
 	return githubRepo, nil
 }
 
+// commitAuthor returns the author specified in cfg with the -author flag, or
+// with the -author-name and -author-email flags, or nil if none was
+// specified.
+func commitAuthor(cfg *legacyconfig.Config) (*legacygitrepo.Signature, error) {
+	if cfg.Author != "" {
+		if cfg.AuthorName != "" || cfg.AuthorEmail != "" {
+			return nil, errors.New("author cannot be used with author-name or author-email")
+		}
+		author, err := legacygitrepo.ParseSignature(cfg.Author)
+		if err != nil {
+			return nil, fmt.Errorf("invalid author: %w", err)
+		}
+		return author, nil
+	}
+	if cfg.AuthorName == "" && cfg.AuthorEmail == "" {
+		return nil, nil
+	}
+	return &legacygitrepo.Signature{
+		Name:  cfg.AuthorName,
+		Email: cfg.AuthorEmail,
+	}, nil
+}
+
+// commitCommitter returns the committer specified in cfg with the -committer
+// flag, or nil if none was specified.
+func commitCommitter(cfg *legacyconfig.Config) (*legacygitrepo.Signature, error) {
+	if cfg.Committer == "" {
+		return nil, nil
+	}
+	committer, err := legacygitrepo.ParseSignature(cfg.Committer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid committer: %w", err)
+	}
+	return committer, nil
+}
+
 func deriveImage(imageOverride string, state *legacyconfig.LibrarianState) string {
 	if imageOverride != "" {
 		return imageOverride
@@ -274,32 +410,54 @@ func formatTimestamp(t time.Time) string {
 	return t.Format(yyyyMMddHHmmss)
 }
 
+// libraryRemovePatterns returns the remove_regex of library, defaulting to
+// patterns matching its source roots and everything within them.
+func libraryRemovePatterns(library *legacyconfig.LibraryState) []string {
+	if len(library.RemoveRegex) > 0 {
+		return library.RemoveRegex
+	}
+	slog.Info("remove_regex not provided, defaulting to source_roots")
+	removePatterns := make([]string, len(library.SourceRoots))
+	// For each SourceRoot, create a regex pattern to match the source root
+	// directory itself, and any file or subdirectory within it.
+	for i, root := range library.SourceRoots {
+		removePatterns[i] = fmt.Sprintf("^%s(/.*)?$", regexp.QuoteMeta(root))
+	}
+	return removePatterns
+}
+
 // cleanAndCopyLibrary cleans the files of the given library in repoDir and copies
-// the new files from outputDir.
-func cleanAndCopyLibrary(state *legacyconfig.LibrarianState, repoDir, libraryID, outputDir string) error {
+// the new files from outputDir. If trackChanges is set, it returns the files of
+// the library created, modified and removed by doing so.
+//
+// If destPrefix is not empty, the source roots of the library, and its remove
+// and preserve patterns, are relative to the destPrefix directory of repoDir.
+func cleanAndCopyLibrary(state *legacyconfig.LibrarianState, repoDir, destPrefix, libraryID, outputDir string, trackChanges bool) (*libraryFileChanges, error) {
 	library := state.LibraryByID(libraryID)
 	if library == nil {
-		return fmt.Errorf("library %q not found during clean and copy, despite being found in earlier steps", libraryID)
-	}
-
-	removePatterns := library.RemoveRegex
-	if len(removePatterns) == 0 {
-		slog.Info("remove_regex not provided, defaulting to source_roots")
-		removePatterns = make([]string, len(library.SourceRoots))
-		// For each SourceRoot, create a regex pattern to match the source root
-		// directory itself, and any file or subdirectory within it.
-		for i, root := range library.SourceRoots {
-			removePatterns[i] = fmt.Sprintf("^%s(/.*)?$", regexp.QuoteMeta(root))
-		}
+		return nil, fmt.Errorf("library %q not found during clean and copy, despite being found in earlier steps", libraryID)
 	}
 
+	removePatterns := libraryRemovePatterns(library)
 	preservePatterns := append(library.PreserveRegex, globalPreservePatterns...)
-
-	if err := clean(repoDir, library.SourceRoots, removePatterns, preservePatterns); err != nil {
-		return fmt.Errorf("failed to clean library, %s: %w", library.ID, err)
+	destDir := filepath.Join(repoDir, destPrefix)
+	ignore, err := loadLibrarianIgnore(destDir, library.SourceRoots)
+	if err != nil {
+		return nil, err
 	}
 
-	return copyLibraryFiles(state, repoDir, libraryID, outputDir, true)
+	// The changes are keyed by paths relative to repoDir, whatever the
+	// prefix.
+	var prefixedRoots []string
+	for _, root := range library.SourceRoots {
+		prefixedRoots = append(prefixedRoots, filepath.Join(destPrefix, root))
+	}
+	return trackFileChanges(trackChanges, repoDir, prefixedRoots, func() error {
+		if err := clean(destDir, library.SourceRoots, removePatterns, preservePatterns, ignore); err != nil {
+			return fmt.Errorf("failed to clean library, %s: %w", library.ID, err)
+		}
+		return copyLibraryFiles(state, destDir, libraryID, outputDir, true, ignore)
+	})
 }
 
 // copyLibraryFiles copies the files in state.SourceRoots relative to the src folder to the dest
@@ -313,12 +471,16 @@ func cleanAndCopyLibrary(state *legacyconfig.LibrarianState, repoDir, libraryID,
 //
 // If a file is being copied to the library's SourceRoots in the dest folder but the folder does
 // not exist, the copy fails.
-func copyLibraryFiles(state *legacyconfig.LibrarianState, dest, libraryID, src string, failOnExistingFile bool) error {
+//
+// Files of the dest folder matching the .librarianignore patterns in ignore, which may be nil,
+// are neither overwritten nor reported as existing.
+func copyLibraryFiles(state *legacyconfig.LibrarianState, dest, libraryID, src string, failOnExistingFile bool, ignore gitignore.Matcher) error {
 	library := state.LibraryByID(libraryID)
 	if library == nil {
 		return fmt.Errorf("library %q not found", libraryID)
 	}
 	slog.Info("copying library files", "id", library.ID, "destination", dest, "source", src)
+	var copied int
 	for _, srcRoot := range library.SourceRoots {
 		dstPath := filepath.Join(dest, srcRoot)
 		srcPath := filepath.Join(src, srcRoot)
@@ -330,14 +492,25 @@ func copyLibraryFiles(state *legacyconfig.LibrarianState, dest, libraryID, src s
 			slog.Debug("copying file", "file", file)
 			srcFile := filepath.Join(srcPath, file)
 			dstFile := filepath.Join(dstPath, file)
-			if _, err := os.Stat(dstFile); failOnExistingFile && err == nil {
+			_, err := os.Stat(dstFile)
+			exists := err == nil
+			if exists && isIgnored(ignore, filepath.Join(srcRoot, file), false) {
+				slog.Info("file matches .librarianignore, preserving", "file", dstFile)
+				continue
+			}
+			if failOnExistingFile && exists {
 				return fmt.Errorf("file existed in destination: %s", dstFile)
 			}
 			if err := copyFile(dstFile, srcFile); err != nil {
 				return fmt.Errorf("failed to copy file %q for library %s: %w", srcFile, library.ID, err)
 			}
+			if err := verifyCopiedFile(dstFile, srcFile); err != nil {
+				return fmt.Errorf("failed to verify copied file %q for library %s: %w", dstFile, library.ID, err)
+			}
+			copied++
 		}
 	}
+	slog.Debug("copied library files", "id", library.ID, "files", copied)
 	return nil
 }
 
@@ -393,13 +566,64 @@ func commitAndPush(ctx context.Context, info *commitInfo) error {
 		return nil
 	}
 
+	var amended *legacygitrepo.Commit
+	if info.amend {
+		if amended, err = amendableHead(repo); err != nil {
+			return err
+		}
+	}
+	amend := amended != nil
+
 	datetimeNow := formatTimestamp(time.Now())
 	branch := fmt.Sprintf("librarian-%s", datetimeNow)
-	if err := repo.CreateBranchAndCheckout(branch); err != nil {
-		return fmt.Errorf("failed to create branch and checkout: %w", err)
+	if info.parts > 1 {
+		branch = fmt.Sprintf("%s-%d", branch, info.part)
+	}
+	if info.pullRequest != nil {
+		branch = info.pullRequest.GetHead().GetRef()
+	}
+	if amend {
+		// The amended commit is pushed to the branch of the previous run.
+		if branch, err = repo.CurrentBranch(); err != nil {
+			return fmt.Errorf("failed to get current branch: %w", err)
+		}
+	} else {
+		if err := repo.CreateBranchAndCheckout(branch); err != nil {
+			return fmt.Errorf("failed to create branch and checkout: %w", err)
+		}
 	}
 
-	if err := repo.Commit(info.commitMessage); err != nil {
+	var libraryIDs []string
+	if info.libraryIDsFooter || (info.push && info.librarianConfig != nil) {
+		changedFiles, err := repo.ChangedFiles()
+		if err != nil {
+			return fmt.Errorf("failed to get changed files: %w", err)
+		}
+		libraryIDs = affectedLibraryIDs(info.state, changedFiles)
+		if amend {
+			// The changed files are relative to the amended commit, so the
+			// libraries it already changed are taken from its footer.
+			libraryIDs = mergeLibraryIDs(libraryIDs, footerLibraryIDs(amended.Message))
+		}
+	}
+	var footers []string
+	if info.libraryIDsFooter && len(libraryIDs) > 0 {
+		footers = append(footers, fmt.Sprintf("Library-IDs: %s", strings.Join(libraryIDs, ",")))
+	}
+	if info.amend {
+		footers = append(footers, amendableCommitFooter)
+	}
+	commitMessage := info.commitMessage
+	if len(footers) > 0 {
+		commitMessage = fmt.Sprintf("%s\n\n%s", commitMessage, strings.Join(footers, "\n"))
+	}
+
+	commit := repo.CommitWithAuthor
+	if amend {
+		slog.Info("amending the previous librarian commit")
+		commit = repo.AmendWithAuthor
+	}
+	if err := commit(commitMessage, info.author, info.committer); err != nil {
 		return fmt.Errorf("failed to commit: %w", err)
 	}
 
@@ -408,6 +632,16 @@ func commitAndPush(ctx context.Context, info *commitInfo) error {
 		return writePRBody(info)
 	}
 
+	if info.rebaseOntoBase {
+		committer := info.committer
+		if committer == nil {
+			committer = info.author
+		}
+		if err := repo.RebaseOnto(info.branch, committer); err != nil {
+			return fmt.Errorf("failed to rebase onto %s: %w", info.branch, err)
+		}
+	}
+
 	if err := repo.Push(branch); err != nil {
 		return fmt.Errorf("failed to push: %w", err)
 	}
@@ -418,15 +652,23 @@ func commitAndPush(ctx context.Context, info *commitInfo) error {
 	}
 
 	title := fmt.Sprintf("chore: librarian %s pull request: %s", info.prType, datetimeNow)
+	if info.parts > 1 {
+		title = fmt.Sprintf("%s (%d/%d)", title, info.part, info.parts)
+	}
 	prBody, err := info.prBodyBuilder()
 	if err != nil {
 		return fmt.Errorf("failed to create pull request body: %w", err)
 	}
 
+	if info.pullRequest != nil {
+		return updatePullRequest(ctx, info, gitHubRepo, prBody)
+	}
+
 	pullRequestMetadata, err := info.ghClient.CreatePullRequest(ctx, gitHubRepo, branch, info.branch, title, prBody, info.isDraft)
 	if err != nil {
 		return fmt.Errorf("failed to create pull request: %w", err)
 	}
+	info.pullRequestURL = fmt.Sprintf("https://github.com/%s/%s/pull/%d", gitHubRepo.Owner, gitHubRepo.Name, pullRequestMetadata.Number)
 
 	if info.failedGenerations != 0 {
 		if err := info.ghClient.CreateIssueComment(ctx, pullRequestMetadata.Number, failedGenerationComment); err != nil {
@@ -434,9 +676,79 @@ func commitAndPush(ctx context.Context, info *commitInfo) error {
 		}
 	}
 
+	if reviewers := info.librarianConfig.PullRequestReviewers(libraryIDs); len(reviewers) > 0 {
+		if err := info.ghClient.RequestReviewers(ctx, pullRequestMetadata.Repo, pullRequestMetadata.Number, reviewers); err != nil {
+			return fmt.Errorf("failed to request reviewers: %w", err)
+		}
+	}
+
 	return addLabelsToPullRequest(ctx, info.ghClient, info.pullRequestLabels, pullRequestMetadata)
 }
 
+// updatePullRequest updates the body of the open pull request of info with
+// the managed section of prBody, preserving the edits made on GitHub outside
+// of it.
+func updatePullRequest(ctx context.Context, info *commitInfo, gitHubRepo *legacygithub.Repository, prBody string) error {
+	pr := info.pullRequest
+	if err := info.ghClient.UpdatePullRequestBody(ctx, pr.GetNumber(), mergeManagedSection(pr.GetBody(), prBody)); err != nil {
+		return fmt.Errorf("failed to update pull request #%d: %w", pr.GetNumber(), err)
+	}
+	info.pullRequestURL = fmt.Sprintf("https://github.com/%s/%s/pull/%d", gitHubRepo.Owner, gitHubRepo.Name, pr.GetNumber())
+	return nil
+}
+
+// amendableHead returns the HEAD commit of repo if it was created by a
+// previous run with amend set, i.e., if it has the amendable footer, and nil
+// otherwise.
+func amendableHead(repo legacygitrepo.Repository) (*legacygitrepo.Commit, error) {
+	hash, err := repo.HeadHash()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD commit: %w", err)
+	}
+	commit, err := repo.GetCommit(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD commit: %w", err)
+	}
+	if !slices.Contains(strings.Split(commit.Message, "\n"), amendableCommitFooter) {
+		return nil, nil
+	}
+	return commit, nil
+}
+
+// footerLibraryIDs returns the IDs listed by the Library-IDs footer of the
+// given commit message, if any.
+func footerLibraryIDs(message string) []string {
+	for _, line := range strings.Split(message, "\n") {
+		if ids, ok := strings.CutPrefix(line, "Library-IDs: "); ok {
+			return strings.Split(ids, ",")
+		}
+	}
+	return nil
+}
+
+// mergeLibraryIDs returns the sorted union of the given library IDs.
+func mergeLibraryIDs(a, b []string) []string {
+	ids := slices.Concat(a, b)
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// affectedLibraryIDs returns the sorted IDs of the libraries whose source
+// roots contain any of the given files.
+func affectedLibraryIDs(state *legacyconfig.LibrarianState, files []string) []string {
+	var ids []string
+	for _, library := range state.Libraries {
+		for _, file := range files {
+			if isUnderAnyPath(file, library.SourceRoots) {
+				ids = append(ids, library.ID)
+				break
+			}
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
 // writePRBody attempts to log the body of a PR that would have been created if the
 // -push flag had been specified. This logs any errors and returns them to the
 // caller.
@@ -454,6 +766,11 @@ func writePRBody(info *commitInfo) error {
 	// as we're not checking whether the repo is clean or not. The intention is to be
 	// as light-touch as possible.
 	fullPath := filepath.Join(info.workRoot, prBodyFile)
+	// Preserve manual edits outside of the managed section when the PR body
+	// is regenerated into the same working directory.
+	if existing, err := os.ReadFile(fullPath); err == nil {
+		prBody = mergeManagedSection(strings.TrimSuffix(string(existing), "\n"), prBody)
+	}
 	// Ensure that "cat [path-to-pr-body.txt]" gives useful output.
 	prBody = prBody + "\n"
 	err = os.WriteFile(fullPath, []byte(prBody), 0644)
@@ -465,6 +782,30 @@ func writePRBody(info *commitInfo) error {
 	return nil
 }
 
+// withPRTemplate returns a builder of pull request bodies that prepends the
+// content of the pull request template at path, relative to repoDir, to the
+// body returned by build. If path is empty, build is returned.
+func withPRTemplate(build func() (string, error), repoDir, path string) func() (string, error) {
+	if path == "" {
+		return build
+	}
+	return func() (string, error) {
+		content, err := os.ReadFile(filepath.Join(repoDir, path))
+		if err != nil {
+			return "", fmt.Errorf("failed to read pull request template %s: %w", path, err)
+		}
+		body, err := build()
+		if err != nil {
+			return "", err
+		}
+		prTemplate := strings.TrimSpace(string(content))
+		if prTemplate == "" {
+			return body, nil
+		}
+		return prTemplate + "\n\n" + body, nil
+	}
+}
+
 // addLabelsToPullRequest adds a list of labels to a single pull request (specified by the id number).
 // Should only be called on a valid Github pull request.
 // Passing in `nil` for labels will no-op and an empty list for labels will clear all labels on the PR.
@@ -549,16 +890,62 @@ func copyFile(dst, src string) (err error) {
 	return err
 }
 
+// verifyCopiedFile checks that the regular file dst has the same size and
+// SHA-256 checksum as src. Symbolic links are not verified.
+func verifyCopiedFile(dst, src string) error {
+	srcInfo, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if srcInfo.Mode()&os.ModeSymlink == os.ModeSymlink {
+		return nil
+	}
+	dstInfo, err := os.Lstat(dst)
+	if err != nil {
+		return err
+	}
+	if dstInfo.Size() != srcInfo.Size() {
+		return fmt.Errorf("size of %q is %d bytes, want %d bytes", dst, dstInfo.Size(), srcInfo.Size())
+	}
+	srcSum, err := fileChecksum(src)
+	if err != nil {
+		return err
+	}
+	dstSum, err := fileChecksum(dst)
+	if err != nil {
+		return err
+	}
+	if dstSum != srcSum {
+		return fmt.Errorf("checksum of %q is %s, want %s", dst, dstSum, srcSum)
+	}
+	return nil
+}
+
+// fileChecksum returns the hex-encoded SHA-256 checksum of the file at path.
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 // clean removes files and directories from source roots based on remove and preserve patterns.
 // Limit the possible files when cleaning to those in source roots (not rootDir) as regex patterns
 // for preserve and remove should ONLY impact source root files.
 //
 // It first determines the paths to remove by applying the removePatterns and then excluding any paths
-// that match the preservePatterns. It then separates the remaining paths into files and directories and
-// removes them, ensuring that directories are removed last.
+// that match the preservePatterns or the .librarianignore patterns in ignore, which may be nil. It then
+// separates the remaining paths into files and directories and removes them, ensuring that directories
+// are removed last.
 //
 // This logic is ported from owlbot logic: https://github.com/googleapis/repo-automation-bots/blob/12dad68640960290910b660e4325630c9ace494b/packages/owl-bot/src/copy-code.ts#L1027
-func clean(rootDir string, sourceRoots, removePatterns, preservePatterns []string) error {
+func clean(rootDir string, sourceRoots, removePatterns, preservePatterns []string, ignore gitignore.Matcher) error {
 	slog.Info("cleaning directories", "source roots", sourceRoots)
 
 	// relPaths contains a list of files in source root's relative paths from rootDir. The
@@ -601,6 +988,16 @@ func clean(rootDir string, sourceRoots, removePatterns, preservePatterns []strin
 	// prepend the rootDir to each path to ensure that os.Remove can find the file
 	var paths []string
 	for _, path := range pathsToRemove {
+		if ignore != nil {
+			info, err := os.Lstat(filepath.Join(rootDir, path))
+			if err != nil {
+				return err
+			}
+			if isIgnored(ignore, path, info.IsDir()) {
+				slog.Debug("path matches .librarianignore, preserving", "path", path)
+				continue
+			}
+		}
 		paths = append(paths, filepath.Join(rootDir, path))
 	}
 
@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	gh "github.com/google/go-github/v69/github"
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacygithub"
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacygitrepo"
@@ -115,6 +116,95 @@ func newTestGitRepoWithCommit(t *testing.T, dir string) string {
 	return dir
 }
 
+func TestCommitAuthor(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		cfg        *legacyconfig.Config
+		want       *legacygitrepo.Signature
+		wantErrMsg string
+	}{
+		{
+			name: "not specified",
+			cfg:  &legacyconfig.Config{},
+		},
+		{
+			name: "author",
+			cfg:  &legacyconfig.Config{Author: "Release Bot <release-bot@example.com>"},
+			want: &legacygitrepo.Signature{Name: "Release Bot", Email: "release-bot@example.com"},
+		},
+		{
+			name: "author name and email",
+			cfg:  &legacyconfig.Config{AuthorName: "Release Bot", AuthorEmail: "release-bot@example.com"},
+			want: &legacygitrepo.Signature{Name: "Release Bot", Email: "release-bot@example.com"},
+		},
+		{
+			name:       "invalid author",
+			cfg:        &legacyconfig.Config{Author: "release-bot@example.com"},
+			wantErrMsg: `invalid author: invalid identity "release-bot@example.com", must be of the form "Name <email>"`,
+		},
+		{
+			name:       "author with author name",
+			cfg:        &legacyconfig.Config{Author: "Release Bot <release-bot@example.com>", AuthorName: "Release Bot"},
+			wantErrMsg: "author cannot be used with author-name or author-email",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := commitAuthor(test.cfg)
+			if test.wantErrMsg != "" {
+				if err == nil || err.Error() != test.wantErrMsg {
+					t.Fatalf("commitAuthor() error = %v, want %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("commitAuthor() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCommitCommitter(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		committer  string
+		want       *legacygitrepo.Signature
+		wantErrMsg string
+	}{
+		{
+			name: "not specified",
+		},
+		{
+			name:      "valid",
+			committer: "CI <ci@example.com>",
+			want:      &legacygitrepo.Signature{Name: "CI", Email: "ci@example.com"},
+		},
+		{
+			name:       "invalid",
+			committer:  "CI",
+			wantErrMsg: `invalid committer: invalid identity "CI", must be of the form "Name <email>"`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := commitCommitter(&legacyconfig.Config{Committer: test.committer})
+			if test.wantErrMsg != "" {
+				if err == nil || err.Error() != test.wantErrMsg {
+					t.Fatalf("commitCommitter() error = %v, want %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("commitCommitter() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestCloneOrOpenLanguageRepo(t *testing.T) {
 	workRoot := t.TempDir()
 
@@ -210,6 +300,58 @@ func TestCloneOrOpenLanguageRepo(t *testing.T) {
 	}
 }
 
+func TestCloneOrOpenAPISource(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name     string
+		cacheDir bool
+		local    bool
+	}{
+		{
+			name: "URL without cache dir",
+		},
+		{
+			name:     "local path with cache dir",
+			cacheDir: true,
+			local:    true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := &legacyconfig.Config{
+				WorkRoot:  t.TempDir(),
+				APISource: "https://github.com/googleapis/googleapis",
+			}
+			if test.cacheDir {
+				cfg.CacheDir = t.TempDir()
+			}
+			// Existing repositories are opened, so nothing is cloned.
+			wantDir := filepath.Join(cfg.WorkRoot, "googleapis")
+			if test.local {
+				cfg.APISource = wantDir
+			}
+			newTestGitRepoWithCommit(t, wantDir)
+
+			repo, err := cloneOrOpenAPISource(cfg, cfg.APISource)
+			if err != nil {
+				t.Fatalf("cloneOrOpenAPISource() failed: %v", err)
+			}
+			if repo.Dir != wantDir {
+				t.Errorf("repo.Dir got %q, want %q", repo.Dir, wantDir)
+			}
+			if test.cacheDir {
+				entries, err := os.ReadDir(cfg.CacheDir)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(entries) != 0 {
+					t.Errorf("cache dir has %d entries, want none", len(entries))
+				}
+			}
+		})
+	}
+}
+
 func TestCleanAndCopyLibrary(t *testing.T) {
 	t.Parallel()
 	for _, test := range []struct {
@@ -438,7 +580,7 @@ func TestCleanAndCopyLibrary(t *testing.T) {
 			if test.setup != nil {
 				test.setup(t, repoDir, outputDir)
 			}
-			err := cleanAndCopyLibrary(test.state, repoDir, test.libraryID, outputDir)
+			_, err := cleanAndCopyLibrary(test.state, repoDir, "", test.libraryID, outputDir, false)
 			if test.wantErr {
 				if err == nil {
 					t.Fatalf("%s should return error", test.name)
@@ -470,6 +612,77 @@ func TestCleanAndCopyLibrary(t *testing.T) {
 	}
 }
 
+func TestCleanAndCopyLibrary_DestPrefix(t *testing.T) {
+	t.Parallel()
+	repoDir := t.TempDir()
+	outputDir := t.TempDir()
+	state := &legacyconfig.LibrarianState{
+		Libraries: []*legacyconfig.LibraryState{
+			{
+				ID:            "foo",
+				SourceRoots:   []string{"packages/foo"},
+				PreserveRegex: []string{"^packages/foo/preserved.txt$"},
+			},
+		},
+	}
+	for path, content := range map[string]string{
+		"vendor/packages/foo/preserved.txt": "preserved",
+		"vendor/packages/foo/stale.go":      "stale",
+		"vendor/packages/foo/client.go":     "old client",
+		"packages/foo/client.go":            "unprefixed client",
+	} {
+		writeTestFile(t, filepath.Join(repoDir, path), content)
+	}
+	for path, content := range map[string]string{
+		"packages/foo/client.go": "new client",
+		"packages/foo/new.go":    "new",
+	} {
+		writeTestFile(t, filepath.Join(outputDir, path), content)
+	}
+
+	gotChanges, err := cleanAndCopyLibrary(state, repoDir, "vendor", "foo", outputDir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantChanges := &libraryFileChanges{
+		Created:  []string{"vendor/packages/foo/new.go"},
+		Modified: []string{"vendor/packages/foo/client.go"},
+		Removed:  []string{"vendor/packages/foo/stale.go"},
+	}
+	if diff := cmp.Diff(wantChanges, gotChanges); diff != "" {
+		t.Errorf("cleanAndCopyLibrary() changes mismatch (-want +got):\n%s", diff)
+	}
+	want := map[string]string{
+		"vendor/packages/foo/preserved.txt": "preserved",
+		"vendor/packages/foo/client.go":     "new client",
+		"vendor/packages/foo/new.go":        "new",
+		"packages/foo/client.go":            "unprefixed client",
+	}
+	got := make(map[string]string)
+	err = filepath.WalkDir(repoDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(repoDir, path)
+		if err != nil {
+			return err
+		}
+		got[filepath.ToSlash(relPath)] = string(content)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("cleanAndCopyLibrary() files mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestClean(t *testing.T) {
 	t.Parallel()
 	for _, test := range []struct {
@@ -737,7 +950,7 @@ func TestClean(t *testing.T) {
 			if test.setup != nil {
 				test.setup(t, tmpDir)
 			}
-			err := clean(tmpDir, test.sourceRoots, test.removePatterns, test.preservePatterns)
+			err := clean(tmpDir, test.sourceRoots, test.removePatterns, test.preservePatterns, nil)
 			if test.wantErr {
 				if err == nil {
 					t.Fatalf("%s should return error", test.name)
@@ -1244,15 +1457,20 @@ func TestCommitAndPush(t *testing.T) {
 		name              string
 		setupMockRepo     func(t *testing.T) legacygitrepo.Repository
 		setupMockClient   func(t *testing.T) GitHubClient
+		author            *legacygitrepo.Signature
+		committer         *legacygitrepo.Signature
+		libraryIDsFooter  bool
 		state             *legacyconfig.LibrarianState
 		prType            pullRequestType
 		failedGenerations int
 		commit            bool
 		push              bool
+		rebaseOntoBase    bool
 		wantErr           bool
 		expectedErrMsg    string
 		check             func(t *testing.T, repo legacygitrepo.Repository)
 		wantPRBodyFile    bool
+		wantPRURL         string
 		prBodyBuilder     func() (string, error)
 	}{
 		{
@@ -1309,6 +1527,157 @@ func TestCommitAndPush(t *testing.T) {
 			},
 			wantPRBodyFile: true,
 		},
+		{
+			name: "create a commit with an explicit author",
+			setupMockRepo: func(t *testing.T) legacygitrepo.Repository {
+				remote := &legacygitrepo.Remote{
+					Name: "origin",
+					URLs: []string{"https://github.com/googleapis/librarian.git"},
+				}
+				return &MockRepository{
+					Dir:          t.TempDir(),
+					RemotesValue: []*legacygitrepo.Remote{remote},
+				}
+			},
+			setupMockClient: func(t *testing.T) GitHubClient {
+				return nil
+			},
+			author: &legacygitrepo.Signature{Name: "Release Bot", Email: "release-bot@example.com"},
+			state:  &legacyconfig.LibrarianState{},
+			prType: pullRequestRelease,
+			commit: true,
+			check: func(t *testing.T, repo legacygitrepo.Repository) {
+				mockRepo := repo.(*MockRepository)
+				want := &legacygitrepo.Signature{Name: "Release Bot", Email: "release-bot@example.com"}
+				if diff := cmp.Diff(want, mockRepo.LastCommitAuthor); diff != "" {
+					t.Errorf("commit author mismatch (-want +got):\n%s", diff)
+				}
+			},
+			wantPRBodyFile: true,
+		},
+		{
+			name: "create a commit with an explicit committer",
+			setupMockRepo: func(t *testing.T) legacygitrepo.Repository {
+				remote := &legacygitrepo.Remote{
+					Name: "origin",
+					URLs: []string{"https://github.com/googleapis/librarian.git"},
+				}
+				return &MockRepository{
+					Dir:          t.TempDir(),
+					RemotesValue: []*legacygitrepo.Remote{remote},
+				}
+			},
+			setupMockClient: func(t *testing.T) GitHubClient {
+				return nil
+			},
+			author:    &legacygitrepo.Signature{Name: "Release Bot", Email: "release-bot@example.com"},
+			committer: &legacygitrepo.Signature{Name: "CI", Email: "ci@example.com"},
+			state:     &legacyconfig.LibrarianState{},
+			prType:    pullRequestRelease,
+			commit:    true,
+			check: func(t *testing.T, repo legacygitrepo.Repository) {
+				mockRepo := repo.(*MockRepository)
+				want := &legacygitrepo.Signature{Name: "CI", Email: "ci@example.com"}
+				if diff := cmp.Diff(want, mockRepo.LastCommitCommitter); diff != "" {
+					t.Errorf("commit committer mismatch (-want +got):\n%s", diff)
+				}
+			},
+			wantPRBodyFile: true,
+		},
+		{
+			name: "create a commit with a Library-IDs footer",
+			setupMockRepo: func(t *testing.T) legacygitrepo.Repository {
+				remote := &legacygitrepo.Remote{
+					Name: "origin",
+					URLs: []string{"https://github.com/googleapis/librarian.git"},
+				}
+				return &MockRepository{
+					Dir:          t.TempDir(),
+					RemotesValue: []*legacygitrepo.Remote{remote},
+					ChangedFilesValue: []string{
+						".librarian/state.yaml",
+						"secretmanager/apiv1/client.go",
+						"storage/storage.go",
+					},
+				}
+			},
+			setupMockClient: func(t *testing.T) GitHubClient {
+				return nil
+			},
+			libraryIDsFooter: true,
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{ID: "storage", SourceRoots: []string{"storage"}},
+					{ID: "pubsub", SourceRoots: []string{"pubsub"}},
+					{ID: "secretmanager", SourceRoots: []string{"secretmanager"}},
+				},
+			},
+			prType: pullRequestGenerate,
+			commit: true,
+			check: func(t *testing.T, repo legacygitrepo.Repository) {
+				mockRepo := repo.(*MockRepository)
+				want := "feat: generate libraries\n\nLibrary-IDs: secretmanager,storage"
+				if diff := cmp.Diff(want, mockRepo.LastCommitMessage); diff != "" {
+					t.Errorf("commit message mismatch (-want +got):\n%s", diff)
+				}
+			},
+			wantPRBodyFile: true,
+		},
+		{
+			name: "create a commit without a Library-IDs footer when no library changed",
+			setupMockRepo: func(t *testing.T) legacygitrepo.Repository {
+				remote := &legacygitrepo.Remote{
+					Name: "origin",
+					URLs: []string{"https://github.com/googleapis/librarian.git"},
+				}
+				return &MockRepository{
+					Dir:               t.TempDir(),
+					RemotesValue:      []*legacygitrepo.Remote{remote},
+					ChangedFilesValue: []string{".librarian/state.yaml"},
+				}
+			},
+			setupMockClient: func(t *testing.T) GitHubClient {
+				return nil
+			},
+			libraryIDsFooter: true,
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{ID: "storage", SourceRoots: []string{"storage"}},
+				},
+			},
+			prType: pullRequestGenerate,
+			commit: true,
+			check: func(t *testing.T, repo legacygitrepo.Repository) {
+				mockRepo := repo.(*MockRepository)
+				if diff := cmp.Diff("feat: generate libraries", mockRepo.LastCommitMessage); diff != "" {
+					t.Errorf("commit message mismatch (-want +got):\n%s", diff)
+				}
+			},
+			wantPRBodyFile: true,
+		},
+		{
+			name: "changed files error",
+			setupMockRepo: func(t *testing.T) legacygitrepo.Repository {
+				remote := &legacygitrepo.Remote{
+					Name: "origin",
+					URLs: []string{"https://github.com/googleapis/librarian.git"},
+				}
+				return &MockRepository{
+					Dir:               t.TempDir(),
+					RemotesValue:      []*legacygitrepo.Remote{remote},
+					ChangedFilesError: errors.New("changed files error"),
+				}
+			},
+			setupMockClient: func(t *testing.T) GitHubClient {
+				return nil
+			},
+			libraryIDsFooter: true,
+			state:            &legacyconfig.LibrarianState{},
+			prType:           pullRequestGenerate,
+			commit:           true,
+			wantErr:          true,
+			expectedErrMsg:   "failed to get changed files",
+		},
 		{
 			name: "create a generate pull request",
 			setupMockRepo: func(t *testing.T) legacygitrepo.Repository {
@@ -1326,9 +1695,10 @@ func TestCommitAndPush(t *testing.T) {
 					createdPR: &legacygithub.PullRequestMetadata{Number: 123, Repo: &legacygithub.Repository{Owner: "test-owner", Name: "test-repo"}},
 				}
 			},
-			state:  &legacyconfig.LibrarianState{},
-			prType: pullRequestGenerate,
-			push:   true,
+			state:     &legacyconfig.LibrarianState{},
+			prType:    pullRequestGenerate,
+			push:      true,
+			wantPRURL: "https://github.com/googleapis/librarian/pull/123",
 		},
 		{
 			name: "create a release pull request",
@@ -1347,9 +1717,10 @@ func TestCommitAndPush(t *testing.T) {
 					createdPR: &legacygithub.PullRequestMetadata{Number: 123, Repo: &legacygithub.Repository{Owner: "test-owner", Name: "test-repo"}},
 				}
 			},
-			state:  &legacyconfig.LibrarianState{},
-			prType: pullRequestRelease,
-			push:   true,
+			state:     &legacyconfig.LibrarianState{},
+			prType:    pullRequestRelease,
+			push:      true,
+			wantPRURL: "https://github.com/googleapis/librarian/pull/123",
 		},
 		{
 			name: "No GitHub Remote",
@@ -1452,6 +1823,60 @@ func TestCommitAndPush(t *testing.T) {
 			wantErr:        true,
 			expectedErrMsg: "push error",
 		},
+		{
+			name: "rebase onto base before push",
+			setupMockRepo: func(t *testing.T) legacygitrepo.Repository {
+				remote := &legacygitrepo.Remote{
+					Name: "origin",
+					URLs: []string{"https://github.com/googleapis/librarian.git"},
+				}
+				return &MockRepository{
+					Dir:          t.TempDir(),
+					RemotesValue: []*legacygitrepo.Remote{remote},
+				}
+			},
+			setupMockClient: func(t *testing.T) GitHubClient {
+				return &mockGitHubClient{
+					createdPR: &legacygithub.PullRequestMetadata{Number: 123, Repo: &legacygithub.Repository{Owner: "test-owner", Name: "test-repo"}},
+				}
+			},
+			state:          &legacyconfig.LibrarianState{},
+			prType:         pullRequestGenerate,
+			push:           true,
+			rebaseOntoBase: true,
+			check: func(t *testing.T, repo legacygitrepo.Repository) {
+				mockRepo := repo.(*MockRepository)
+				if mockRepo.RebaseOntoCalls != 1 {
+					t.Errorf("RebaseOnto was called %d times, expected 1", mockRepo.RebaseOntoCalls)
+				}
+				if mockRepo.PushCalls != 1 {
+					t.Errorf("Push was called %d times, expected 1", mockRepo.PushCalls)
+				}
+			},
+			wantPRURL: "https://github.com/googleapis/librarian/pull/123",
+		},
+		{
+			name: "rebase conflict",
+			setupMockRepo: func(t *testing.T) legacygitrepo.Repository {
+				remote := &legacygitrepo.Remote{
+					Name: "origin",
+					URLs: []string{"https://github.com/googleapis/librarian.git"},
+				}
+				return &MockRepository{
+					Dir:             t.TempDir(),
+					RemotesValue:    []*legacygitrepo.Remote{remote},
+					RebaseOntoError: fmt.Errorf("%w onto main in files: README.md", legacygitrepo.ErrRebaseConflict),
+				}
+			},
+			setupMockClient: func(t *testing.T) GitHubClient {
+				return nil
+			},
+			prType:         pullRequestGenerate,
+			push:           true,
+			rebaseOntoBase: true,
+			wantErr:        true,
+			expectedErrMsg: "rebase conflict onto main in files: README.md",
+		},
 		{
 			name: "Create PR body error",
 			setupMockRepo: func(t *testing.T) legacygitrepo.Repository {
@@ -1552,16 +1977,20 @@ func TestCommitAndPush(t *testing.T) {
 			}
 
 			commitInfo := &commitInfo{
+				author:            test.author,
+				committer:         test.committer,
 				commit:            test.commit,
-				commitMessage:     "",
+				commitMessage:     "feat: generate libraries",
 				ghClient:          client,
 				prType:            test.prType,
 				push:              test.push,
+				rebaseOntoBase:    test.rebaseOntoBase,
 				languageRepo:      repo,
 				state:             test.state,
 				failedGenerations: test.failedGenerations,
 				workRoot:          t.TempDir(),
 				prBodyBuilder:     test.prBodyBuilder,
+				libraryIDsFooter:  test.libraryIDsFooter,
 			}
 
 			err := commitAndPush(t.Context(), commitInfo)
@@ -1584,6 +2013,10 @@ func TestCommitAndPush(t *testing.T) {
 				test.check(t, repo)
 			}
 
+			if diff := cmp.Diff(test.wantPRURL, commitInfo.pullRequestURL); diff != "" {
+				t.Errorf("pull request URL mismatch (-want +got):\n%s", diff)
+			}
+
 			gotPRBodyFile := gotPRBodyFile(t, commitInfo.workRoot)
 			if test.wantPRBodyFile != gotPRBodyFile {
 				t.Errorf("commitAndPush() wantPRBodyFile = %t, gotPRBodyFile = %t", test.wantPRBodyFile, gotPRBodyFile)
@@ -1592,6 +2025,240 @@ func TestCommitAndPush(t *testing.T) {
 	}
 }
 
+func TestCommitAndPush_AmendTwice(t *testing.T) {
+	dir := newTestGitRepoWithCommit(t, "")
+	repo, err := legacygitrepo.NewRepository(&legacygitrepo.RepositoryOptions{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 2 {
+		if err := os.WriteFile(filepath.Join(dir, "generated.txt"), []byte(fmt.Sprintf("run %d", i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		info := &commitInfo{
+			amend:         true,
+			commit:        true,
+			commitMessage: "feat: generate libraries",
+			languageRepo:  repo,
+			state:         &legacyconfig.LibrarianState{},
+			workRoot:      t.TempDir(),
+			prBodyBuilder: func() (string, error) { return "some pr body", nil },
+		}
+		if err := commitAndPush(t.Context(), info); err != nil {
+			t.Fatalf("commitAndPush() run %d: %v", i, err)
+		}
+	}
+
+	cmd := exec.Command("git", "log", "--format=%s")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "feat: generate libraries\ninitial commit\n"
+	if diff := cmp.Diff(want, string(out)); diff != "" {
+		t.Errorf("git log mismatch (-want +got):\n%s", diff)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "generated.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "run 1" {
+		t.Errorf("generated.txt = %q, want %q", content, "run 1")
+	}
+}
+
+func TestCommitAndPush_UpdatesPullRequest(t *testing.T) {
+	t.Parallel()
+	mockRepo := &MockRepository{
+		Dir: t.TempDir(),
+		RemotesValue: []*legacygitrepo.Remote{
+			{Name: "origin", URLs: []string{"https://github.com/googleapis/librarian.git"}},
+		},
+	}
+	existing := "Notes added on GitHub.\n\n" + changelogSectionStart + "\nold release notes\n" + changelogSectionEnd + "\n\nMore notes."
+	number := 12
+	ref := "librarian-20250101T000000Z"
+	ghClient := &mockGitHubClient{}
+	info := &commitInfo{
+		branch:        "main",
+		commit:        true,
+		commitMessage: "chore: create a release",
+		ghClient:      ghClient,
+		languageRepo:  mockRepo,
+		push:          true,
+		state:         &legacyconfig.LibrarianState{},
+		workRoot:      t.TempDir(),
+		prBodyBuilder: func() (string, error) {
+			return changelogSectionStart + "\nnew release notes\n" + changelogSectionEnd, nil
+		},
+		pullRequest: &legacygithub.PullRequest{
+			Number: &number,
+			Body:   &existing,
+			Head:   &gh.PullRequestBranch{Ref: &ref},
+		},
+	}
+	if err := commitAndPush(t.Context(), info); err != nil {
+		t.Fatal(err)
+	}
+	if mockRepo.PushedBranch != ref {
+		t.Errorf("Push() branch = %q, want %q", mockRepo.PushedBranch, ref)
+	}
+	if ghClient.createPullRequestCalls != 0 {
+		t.Errorf("CreatePullRequest() called %d times, want 0", ghClient.createPullRequestCalls)
+	}
+	want := map[int]string{
+		12: "Notes added on GitHub.\n\n" + changelogSectionStart + "\nnew release notes\n" + changelogSectionEnd + "\n\nMore notes.",
+	}
+	if diff := cmp.Diff(want, ghClient.updatedPullRequestBodies); diff != "" {
+		t.Errorf("updated pull request bodies mismatch (-want +got):\n%s", diff)
+	}
+	if want := "https://github.com/googleapis/librarian/pull/12"; info.pullRequestURL != want {
+		t.Errorf("pullRequestURL = %q, want %q", info.pullRequestURL, want)
+	}
+}
+
+func TestCommitAndPush_AmendKeepsLibraryIDs(t *testing.T) {
+	t.Parallel()
+	mockRepo := &MockRepository{
+		Dir:           t.TempDir(),
+		HeadHashValue: "1234",
+		GetCommitByHash: map[string]*legacygitrepo.Commit{
+			"1234": {Message: "feat: generate libraries\n\nLibrary-IDs: storage\n" + amendableCommitFooter},
+		},
+		ChangedFilesValue: []string{"pubsub/pubsub.go"},
+	}
+	info := &commitInfo{
+		amend:            true,
+		commit:           true,
+		commitMessage:    "feat: generate libraries",
+		languageRepo:     mockRepo,
+		libraryIDsFooter: true,
+		state: &legacyconfig.LibrarianState{
+			Libraries: []*legacyconfig.LibraryState{
+				{ID: "storage", SourceRoots: []string{"storage"}},
+				{ID: "pubsub", SourceRoots: []string{"pubsub"}},
+			},
+		},
+		workRoot:      t.TempDir(),
+		prBodyBuilder: func() (string, error) { return "some pr body", nil },
+	}
+	if err := commitAndPush(t.Context(), info); err != nil {
+		t.Fatal(err)
+	}
+	want := "feat: generate libraries\n\nLibrary-IDs: pubsub,storage\n" + amendableCommitFooter
+	if diff := cmp.Diff(want, mockRepo.LastCommitMessage); diff != "" {
+		t.Errorf("commit message mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCommitAndPush_AmendWithoutAmendableHead(t *testing.T) {
+	mockRepo := &MockRepository{
+		Dir:           t.TempDir(),
+		HeadHashValue: "1234",
+		GetCommitByHash: map[string]*legacygitrepo.Commit{
+			"1234": {Message: "chore: not created by librarian"},
+		},
+	}
+	info := &commitInfo{
+		amend:         true,
+		commit:        true,
+		commitMessage: "feat: generate libraries",
+		languageRepo:  mockRepo,
+		state:         &legacyconfig.LibrarianState{},
+		workRoot:      t.TempDir(),
+		prBodyBuilder: func() (string, error) { return "some pr body", nil },
+	}
+	if err := commitAndPush(t.Context(), info); err != nil {
+		t.Fatal(err)
+	}
+	if mockRepo.AmendCalls != 0 {
+		t.Errorf("AmendWithAuthor() called %d times, want 0", mockRepo.AmendCalls)
+	}
+	want := "feat: generate libraries\n\n" + amendableCommitFooter
+	if diff := cmp.Diff(want, mockRepo.LastCommitMessage); diff != "" {
+		t.Errorf("commit message mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCommitAndPush_RequestReviewers(t *testing.T) {
+	t.Parallel()
+	state := &legacyconfig.LibrarianState{
+		Libraries: []*legacyconfig.LibraryState{
+			{ID: "lib1", SourceRoots: []string{"lib1"}},
+			{ID: "lib2", SourceRoots: []string{"lib2"}},
+		},
+	}
+	for _, test := range []struct {
+		name                string
+		librarianConfig     *legacyconfig.LibrarianConfig
+		requestReviewersErr error
+		want                []string
+		wantErr             string
+	}{
+		{
+			name: "no librarian config",
+		},
+		{
+			name: "default reviewers alongside maintainers of changed libraries",
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				DefaultReviewers: []string{"googleapis/yoshi-go", "octocat"},
+				Libraries: []*legacyconfig.LibraryConfig{
+					{LibraryID: "lib1", Maintainers: []string{"hubot", "octocat"}},
+					{LibraryID: "lib2", Maintainers: []string{"unchanged-maintainer"}},
+				},
+			},
+			want: []string{"googleapis/yoshi-go", "octocat", "hubot"},
+		},
+		{
+			name: "request reviewers error",
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				DefaultReviewers: []string{"octocat"},
+			},
+			requestReviewersErr: errors.New("request reviewers error"),
+			want:                []string{"octocat"},
+			wantErr:             "failed to request reviewers",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repo := &MockRepository{
+				Dir: t.TempDir(),
+				RemotesValue: []*legacygitrepo.Remote{
+					{Name: "origin", URLs: []string{"https://github.com/googleapis/librarian.git"}},
+				},
+				ChangedFilesValue: []string{"lib1/file.go"},
+			}
+			ghClient := &mockGitHubClient{
+				createdPR:           &legacygithub.PullRequestMetadata{Number: 123, Repo: &legacygithub.Repository{Owner: "googleapis", Name: "librarian"}},
+				requestReviewersErr: test.requestReviewersErr,
+			}
+			info := &commitInfo{
+				commitMessage:   "feat: generate libraries",
+				ghClient:        ghClient,
+				librarianConfig: test.librarianConfig,
+				prType:          pullRequestGenerate,
+				push:            true,
+				languageRepo:    repo,
+				state:           state,
+				workRoot:        t.TempDir(),
+				prBodyBuilder:   func() (string, error) { return "some pr body", nil },
+			}
+			err := commitAndPush(t.Context(), info)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("commitAndPush() error = %v, want error containing %q", err, test.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, ghClient.requestedReviewers); diff != "" {
+				t.Errorf("requested reviewers mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestWritePRBody(t *testing.T) {
 	for _, test := range []struct {
 		name     string
@@ -1695,6 +2362,114 @@ func TestWritePRBody(t *testing.T) {
 	}
 }
 
+func TestWritePRBody_PreservesManualEdits(t *testing.T) {
+	workRoot := t.TempDir()
+	existing := `Manual note above.
+
+<!-- librarian:changelog -->
+old changelog
+<!-- /librarian:changelog -->
+
+Manual note below.
+`
+	if err := os.WriteFile(filepath.Join(workRoot, prBodyFile), []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info := &commitInfo{
+		prType:   pullRequestRelease,
+		state:    &legacyconfig.LibrarianState{},
+		workRoot: workRoot,
+		prBodyBuilder: func() (string, error) {
+			return "header\n\n<!-- librarian:changelog -->\nnew changelog\n<!-- /librarian:changelog -->", nil
+		},
+	}
+	if err := writePRBody(info); err != nil {
+		t.Fatalf("writePRBody() unexpected error: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(workRoot, prBodyFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `Manual note above.
+
+<!-- librarian:changelog -->
+new changelog
+<!-- /librarian:changelog -->
+
+Manual note below.
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("writePRBody() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWithPRTemplate(t *testing.T) {
+	t.Parallel()
+	repoDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repoDir, "template.md"), []byte("## Checklist\n\n- [ ] Tests pass\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "empty.md"), []byte("\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	body := "<!-- librarian:changelog -->\nchangelog\n<!-- /librarian:changelog -->"
+	for _, test := range []struct {
+		name       string
+		path       string
+		buildErr   error
+		want       string
+		wantErrMsg string
+	}{
+		{
+			name: "no template",
+			want: body,
+		},
+		{
+			name: "template precedes managed section",
+			path: "template.md",
+			want: "## Checklist\n\n- [ ] Tests pass\n\n" + body,
+		},
+		{
+			name: "empty template",
+			path: "empty.md",
+			want: body,
+		},
+		{
+			name:       "missing template",
+			path:       "missing.md",
+			wantErrMsg: "failed to read pull request template missing.md",
+		},
+		{
+			name:       "build fails",
+			path:       "template.md",
+			buildErr:   errors.New("build error"),
+			wantErrMsg: "build error",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			build := func() (string, error) {
+				if test.buildErr != nil {
+					return "", test.buildErr
+				}
+				return body, nil
+			}
+			got, err := withPRTemplate(build, repoDir, test.path)()
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("withPRTemplate() error = %v, want contains %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("withPRTemplate() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func gotPRBodyFile(t *testing.T, workRoot string) bool {
 	possibleFilePath := filepath.Join(workRoot, prBodyFile)
 	_, err := os.Stat(possibleFilePath)
@@ -1999,7 +2774,7 @@ func TestCopyLibraryFiles(t *testing.T) {
 			if test.setup != nil {
 				test.setup(t, test.outputDir)
 			}
-			err := copyLibraryFiles(test.state, test.repoDir, test.libraryID, test.outputDir, test.failOnExistingFile)
+			err := copyLibraryFiles(test.state, test.repoDir, test.libraryID, test.outputDir, test.failOnExistingFile, nil)
 			if test.wantErr {
 				if err == nil {
 					t.Fatal("copyLibraryFiles() should fail")
@@ -2109,6 +2884,74 @@ func TestCopyFile(t *testing.T) {
 	}
 }
 
+func TestVerifyCopiedFile(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name       string
+		src        string
+		dst        string
+		symlink    bool
+		wantErrMsg string
+	}{
+		{
+			name: "same content",
+			src:  "some content",
+			dst:  "some content",
+		},
+		{
+			name: "empty",
+		},
+		{
+			name:    "symlink",
+			symlink: true,
+		},
+		{
+			name:       "truncated",
+			src:        "some content",
+			dst:        "some",
+			wantErrMsg: "is 4 bytes, want 12 bytes",
+		},
+		{
+			name:       "different content",
+			src:        "some content",
+			dst:        "some CONTENT",
+			wantErrMsg: "checksum of",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			src := filepath.Join(dir, "src.txt")
+			dst := filepath.Join(dir, "dst.txt")
+			if test.symlink {
+				if err := os.Symlink("target.txt", src); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink("target.txt", dst); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				if err := os.WriteFile(src, []byte(test.src), 0644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(dst, []byte(test.dst), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := verifyCopiedFile(dst, src)
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("verifyCopiedFile() error = %v, want contains %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
 func TestCopyGlobalAllowlist(t *testing.T) {
 	t.Parallel()
 	for _, test := range []struct {
@@ -2343,3 +3186,42 @@ a: 1s
 		t.Errorf("writeTiming() mismatch (-want +got):%s", diff)
 	}
 }
+
+// closingContainerClient is a container client recording whether it was
+// closed.
+type closingContainerClient struct {
+	mockContainerClient
+	closed bool
+}
+
+func (c *closingContainerClient) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestCloseContainerClient(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name  string
+		limit int
+	}{
+		{
+			name: "unlimited",
+		},
+		{
+			name:  "limited",
+			limit: 2,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			client := &closingContainerClient{}
+			closeContainerClient(limitContainers(client, test.limit))
+			if !client.closed {
+				t.Error("container client should be closed")
+			}
+		})
+	}
+	// Clients without a Close method are left alone.
+	closeContainerClient(&mockContainerClient{})
+}
@@ -0,0 +1,107 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+)
+
+const (
+	// defaultGenerateCommitMessage is the commit message template of the
+	// generate command if none is configured.
+	defaultGenerateCommitMessage = "feat: generate libraries"
+	// defaultReleaseCommitMessage is the commit message template of the
+	// release stage command if none is configured.
+	defaultReleaseCommitMessage = "chore: create a release"
+)
+
+// commitMessageData is the data available to commit message templates.
+type commitMessageData struct {
+	// LibraryID is the ID of the library changed by the commit, or empty if
+	// the commit changes several libraries.
+	LibraryID string
+	// LibraryIDs are the IDs of all libraries changed by the commit.
+	LibraryIDs []string
+	// Version is the version of the library changed by the commit, or empty
+	// if the commit changes several libraries.
+	Version string
+	// APIPaths are the paths of the APIs of the libraries changed by the
+	// commit, e.g. google/cloud/foo/v1.
+	APIPaths []string
+}
+
+// parseCommitMessageTemplate parses the commit message template text, or
+// defaultText if text is empty. Templates may call join, i.e. strings.Join,
+// e.g. {{join .APIPaths ", "}}. The template is executed against sample data,
+// so that references to unknown variables are reported before any library is
+// processed.
+func parseCommitMessageTemplate(text, defaultText string) (*template.Template, error) {
+	if text == "" {
+		text = defaultText
+	}
+	tmpl, err := template.New("commit message").Funcs(template.FuncMap{"join": strings.Join}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid commit message template: %w", err)
+	}
+	sample := &commitMessageData{
+		LibraryID:  "library",
+		LibraryIDs: []string{"library"},
+		Version:    "1.0.0",
+		APIPaths:   []string{"google/cloud/library/v1"},
+	}
+	if err := tmpl.Execute(io.Discard, sample); err != nil {
+		return nil, fmt.Errorf("invalid commit message template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// renderCommitMessage renders tmpl for the libraries with the given IDs. If
+// tmpl is nil, defaultText is returned.
+func renderCommitMessage(tmpl *template.Template, defaultText string, state *legacyconfig.LibrarianState, libraryIDs []string) (string, error) {
+	if tmpl == nil {
+		return defaultText, nil
+	}
+	data := &commitMessageData{
+		LibraryIDs: libraryIDs,
+	}
+	for _, id := range libraryIDs {
+		library := state.LibraryByID(id)
+		if library == nil {
+			continue
+		}
+		if len(libraryIDs) == 1 {
+			data.LibraryID = library.ID
+			data.Version = library.Version
+		}
+		for _, api := range library.APIs {
+			data.APIPaths = append(data.APIPaths, api.Path)
+		}
+	}
+	var builder strings.Builder
+	if err := tmpl.Execute(&builder, data); err != nil {
+		return "", fmt.Errorf("failed to render commit message: %w", err)
+	}
+	message := strings.TrimSpace(builder.String())
+	if message == "" {
+		return "", errors.New("commit message template rendered an empty message")
+	}
+	return message, nil
+}
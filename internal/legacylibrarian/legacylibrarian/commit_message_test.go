@@ -0,0 +1,152 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+)
+
+func TestParseCommitMessageTemplate(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name       string
+		text       string
+		want       string
+		wantErrMsg string
+	}{
+		{
+			name: "default",
+			want: defaultGenerateCommitMessage,
+		},
+		{
+			name: "custom template",
+			text: "feat(TICKET-1): generate {{.LibraryID}}",
+			want: "feat(TICKET-1): generate library",
+		},
+		{
+			name:       "syntax error",
+			text:       "feat: generate {{.LibraryID",
+			wantErrMsg: "invalid commit message template",
+		},
+		{
+			name:       "unknown variable",
+			text:       "feat: generate {{.Library}}",
+			wantErrMsg: "invalid commit message template",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			tmpl, err := parseCommitMessageTemplate(test.text, defaultGenerateCommitMessage)
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("parseCommitMessageTemplate() error = %v, want error containing %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var builder strings.Builder
+			if err := tmpl.Execute(&builder, &commitMessageData{LibraryID: "library"}); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, builder.String()); diff != "" {
+				t.Errorf("rendered template mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRenderCommitMessage(t *testing.T) {
+	t.Parallel()
+	state := &legacyconfig.LibrarianState{
+		Libraries: []*legacyconfig.LibraryState{
+			{
+				ID:      "library-a",
+				Version: "1.2.3",
+				APIs:    []*legacyconfig.API{{Path: "google/cloud/a/v1"}, {Path: "google/cloud/a/v2"}},
+			},
+			{
+				ID:      "library-b",
+				Version: "2.0.0",
+				APIs:    []*legacyconfig.API{{Path: "google/cloud/b/v1"}},
+			},
+		},
+	}
+	for _, test := range []struct {
+		name       string
+		text       string
+		libraryIDs []string
+		want       string
+		wantErrMsg string
+	}{
+		{
+			name:       "default message",
+			libraryIDs: []string{"library-a"},
+			want:       defaultReleaseCommitMessage,
+		},
+		{
+			name:       "single library",
+			text:       "chore: release {{.LibraryID}} {{.Version}}\n\nAPIs: {{join .APIPaths \", \"}}",
+			libraryIDs: []string{"library-a"},
+			want:       "chore: release library-a 1.2.3\n\nAPIs: google/cloud/a/v1, google/cloud/a/v2",
+		},
+		{
+			name:       "several libraries",
+			text:       "chore: release{{range .LibraryIDs}} {{.}}{{end}}{{if .LibraryID}} unexpected{{end}}\n\n{{len .APIPaths}} APIs",
+			libraryIDs: []string{"library-a", "library-b"},
+			want:       "chore: release library-a library-b\n\n3 APIs",
+		},
+		{
+			name:       "sign-off line",
+			text:       "[PROJ-42] chore: create a release\n\nSigned-off-by: Release Bot <bot@example.com>",
+			libraryIDs: []string{"library-b"},
+			want:       "[PROJ-42] chore: create a release\n\nSigned-off-by: Release Bot <bot@example.com>",
+		},
+		{
+			name:       "empty message",
+			text:       "{{if .LibraryID}}chore: release {{.LibraryID}}{{end}}",
+			libraryIDs: []string{"library-a", "library-b"},
+			wantErrMsg: "empty message",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var tmpl *template.Template
+			if test.text != "" {
+				var err error
+				if tmpl, err = parseCommitMessageTemplate(test.text, ""); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := renderCommitMessage(tmpl, defaultReleaseCommitMessage, state, test.libraryIDs)
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("renderCommitMessage() error = %v, want error containing %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("renderCommitMessage() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
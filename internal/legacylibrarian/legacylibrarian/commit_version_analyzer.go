@@ -25,13 +25,56 @@ import (
 	"github.com/googleapis/librarian/internal/semver"
 )
 
+// releaseCommitsOptions configures getConventionalCommitsSinceLastRelease.
+type releaseCommitsOptions struct {
+	// maxCommits, if positive, is the number of most recent commits
+	// considered.
+	maxCommits int
+	// rewrite, if not nil, replaces each commit before it is parsed.
+	rewrite func(*legacygitrepo.Commit) (*legacygitrepo.Commit, error)
+	// parseAffects also attributes commits to the libraries listed by an
+	// "Affects:" line in their body, whether or not they change the source
+	// roots of the library.
+	parseAffects bool
+}
+
 // getConventionalCommitsSinceLastRelease returns all conventional commits for the given library since the
 // version specified in the state file. The repo should be the language repo.
-func getConventionalCommitsSinceLastRelease(repo legacygitrepo.Repository, library *legacyconfig.LibraryState, tag string) ([]*legacygitrepo.ConventionalCommit, error) {
+//
+// If opts.maxCommits is positive, the number of earlier commits left out is
+// also returned.
+func getConventionalCommitsSinceLastRelease(repo legacygitrepo.Repository, library *legacyconfig.LibraryState, tag string, opts releaseCommitsOptions) ([]*legacygitrepo.ConventionalCommit, int, error) {
 	commits, err := repo.GetCommitsForPathsSinceTag(library.SourceRoots, tag)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to get commits for library %q with source roots %q at tag %q: %w", library.ID, library.SourceRoots, tag, err)
+		return nil, 0, fmt.Errorf("failed to get commits for library %q with source roots %q at tag %q: %w", library.ID, library.SourceRoots, tag, err)
+	}
+	isAttributed := func(message string) bool {
+		return isForAllLibraries(message) || opts.parseAffects && affectsLibrary(message, library.ID)
+	}
+	commits, err = addAttributedCommits(repo, commits, tag, isAttributed)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get commits for library %q at tag %q: %w", library.ID, tag, err)
+	}
+
+	// The commits are ordered with the most recent first.
+	var earlier int
+	if opts.maxCommits > 0 && len(commits) > opts.maxCommits {
+		earlier = len(commits) - opts.maxCommits
+		commits = commits[:opts.maxCommits]
+		slog.Info("commit cap reached, skipping earlier commits", "library", library.ID, "max_commits", opts.maxCommits, "skipped", earlier)
+	}
+
+	if opts.rewrite != nil {
+		rewritten := make([]*legacygitrepo.Commit, 0, len(commits))
+		for _, commit := range commits {
+			c, err := opts.rewrite(commit)
+			if err != nil {
+				return nil, 0, fmt.Errorf("failed to rewrite commit %s for library %q: %w", commit.Hash.String(), library.ID, err)
+			}
+			rewritten = append(rewritten, c)
+		}
+		commits = rewritten
 	}
 
 	// checks that if the files in the commit are in the sources root. The release
@@ -40,11 +83,55 @@ func getConventionalCommitsSinceLastRelease(repo legacygitrepo.Repository, libra
 		return shouldIncludeForRelease(files, library.SourceRoots, library.ReleaseExcludePaths)
 	}
 
-	conventionalCommits, err := convertToConventionalCommits(repo, library, commits, shouldIncludeFiles)
+	conventionalCommits, err := convertToConventionalCommits(repo, library, commits, shouldIncludeFiles, isAttributed, opts.parseAffects)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert commits to conventional commits for library %q: %w", library.ID, err)
+		return nil, 0, fmt.Errorf("failed to convert commits to conventional commits for library %q: %w", library.ID, err)
 	}
-	return conventionalCommits, nil
+	return conventionalCommits, earlier, nil
+}
+
+// addAttributedCommits adds to commits, which change the source roots of a
+// library, the commits since tag whose message isAttributed to the library,
+// which may change no file of the library. The commits keep the order of the
+// repository log.
+func addAttributedCommits(repo legacygitrepo.Repository, commits []*legacygitrepo.Commit, tag string, isAttributed func(message string) bool) ([]*legacygitrepo.Commit, error) {
+	allCommits, err := repo.GetCommitsForPathsSinceTag([]string{legacygitrepo.RootPath}, tag)
+	if err != nil {
+		return nil, err
+	}
+	inSourceRoots := make(map[string]bool, len(commits))
+	for _, commit := range commits {
+		inSourceRoots[commit.Hash.String()] = true
+	}
+	var merged []*legacygitrepo.Commit
+	for _, commit := range allCommits {
+		if inSourceRoots[commit.Hash.String()] || isAttributed(commit.Message) {
+			merged = append(merged, commit)
+		}
+	}
+	return merged, nil
+}
+
+// isForAllLibraries reports whether the commit message has a Library-IDs
+// footer including the allLibrariesID wildcard.
+func isForAllLibraries(message string) bool {
+	for _, line := range strings.Split(message, "\n") {
+		if ids, ok := strings.CutPrefix(line, "Library-IDs:"); ok && libraryIDsContain(ids, allLibrariesID) {
+			return true
+		}
+	}
+	return false
+}
+
+// affectsLibrary reports whether the commit message has an Affects line
+// listing libraryID, see [legacygitrepo.ParseOptions].
+func affectsLibrary(message, libraryID string) bool {
+	for _, line := range strings.Split(message, "\n") {
+		if ids, ok := strings.CutPrefix(strings.TrimSpace(line), "Affects:"); ok && libraryIDsContain(ids, libraryID) {
+			return true
+		}
+	}
+	return false
 }
 
 // shouldIncludeForRelease determines if a commit should be included in a release.
@@ -84,7 +171,7 @@ func getConventionalCommitsSinceLastGeneration(sourceRepo legacygitrepo.Reposito
 		return shouldIncludeForGeneration(sourceFiles, library)
 	}
 
-	return convertToConventionalCommits(sourceRepo, library, sourceCommits, shouldIncludeFiles)
+	return convertToConventionalCommits(sourceRepo, library, sourceCommits, shouldIncludeFiles, isForAllLibraries, false)
 }
 
 // shouldIncludeForGeneration determines if a commit should be included in generation.
@@ -104,17 +191,30 @@ func shouldIncludeForGeneration(sourceFiles []string, library *legacyconfig.Libr
 	return false
 }
 
+// allLibrariesID is the entry of a Library-IDs footer attributing a commit to
+// every library, e.g. for a repository-wide dependency update.
+const allLibrariesID = "*"
+
+// libraryIDsContain reports whether the comma-separated libraryIDs of a
+// Library-IDs footer include libraryID, either exactly or through the
+// allLibrariesID wildcard.
+func libraryIDsContain(libraryIDs, libraryID string) bool {
+	for _, id := range strings.Split(libraryIDs, ",") {
+		id = strings.TrimSpace(id)
+		if id == libraryID || id == allLibrariesID {
+			return true
+		}
+	}
+	return false
+}
+
 // libraryFilter filters a list of conventional commits by library ID.
 func libraryFilter(commits []*legacygitrepo.ConventionalCommit, libraryID string) []*legacygitrepo.ConventionalCommit {
 	var filteredCommits []*legacygitrepo.ConventionalCommit
 	for _, commit := range commits {
 		if libraryIDs, ok := commit.Footers["Library-IDs"]; ok {
-			ids := strings.Split(libraryIDs, ",")
-			for _, id := range ids {
-				if strings.TrimSpace(id) == libraryID {
-					filteredCommits = append(filteredCommits, commit)
-					break
-				}
+			if libraryIDsContain(libraryIDs, libraryID) {
+				filteredCommits = append(filteredCommits, commit)
 			}
 		} else if commit.LibraryID == libraryID {
 			filteredCommits = append(filteredCommits, commit)
@@ -125,18 +225,23 @@ func libraryFilter(commits []*legacygitrepo.ConventionalCommit, libraryID string
 
 // convertToConventionalCommits converts a list of commits in a git repo into a list
 // of conventional commits. The filesFilter parameter is custom filter out non-matching
-// files depending on a generation or a release change.
-func convertToConventionalCommits(sourceRepo legacygitrepo.Repository, library *legacyconfig.LibraryState, commits []*legacygitrepo.Commit, filesFilter func(files []string) bool) ([]*legacygitrepo.ConventionalCommit, error) {
+// files depending on a generation or a release change, except for commits whose
+// message isAttributed to the library, e.g. by [isForAllLibraries]. If parseAffects is true,
+// commits are also attributed to the libraries listed by an "Affects:" line in
+// their body, see [legacygitrepo.ParseOptions].
+func convertToConventionalCommits(sourceRepo legacygitrepo.Repository, library *legacyconfig.LibraryState, commits []*legacygitrepo.Commit, filesFilter func(files []string) bool, isAttributed func(message string) bool, parseAffects bool) ([]*legacygitrepo.ConventionalCommit, error) {
 	var conventionalCommits []*legacygitrepo.ConventionalCommit
 	for _, commit := range commits {
-		files, err := sourceRepo.ChangedFilesInCommit(commit.Hash.String())
-		if err != nil {
-			return nil, fmt.Errorf("failed to get changed files for commit %s: %w", commit.Hash.String(), err)
-		}
-		if !filesFilter(files) {
-			continue
+		if !isAttributed(commit.Message) {
+			files, err := sourceRepo.ChangedFilesInCommit(commit.Hash.String())
+			if err != nil {
+				return nil, fmt.Errorf("failed to get changed files for commit %s: %w", commit.Hash.String(), err)
+			}
+			if !filesFilter(files) {
+				continue
+			}
 		}
-		parsedCommits, err := legacygitrepo.ParseCommits(commit, library.ID)
+		parsedCommits, err := legacygitrepo.ParseCommitsWithOptions(commit, library.ID, legacygitrepo.ParseOptions{Affects: parseAffects})
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse commit %s: %w", commit.Hash.String(), err)
 		}
@@ -169,13 +274,30 @@ func isUnderAnyPath(file string, paths []string) bool {
 }
 
 // NextVersion calculates the next semantic version based on a slice of conventional commits.
-func NextVersion(commits []*legacygitrepo.ConventionalCommit, currentVersion string) (string, error) {
-	highestChange := getHighestChange(commits)
+// versionBumps maps commit types to the version bump they cause, overriding
+// the defaults, see [legacyconfig.LibrarianConfig.VersionBumps].
+func NextVersion(commits []*legacygitrepo.ConventionalCommit, currentVersion string, versionBumps map[string]string) (string, error) {
+	highestChange := getHighestChange(commits, versionBumps)
 	return semver.DeriveNext(highestChange, currentVersion)
 }
 
+// defaultVersionBumps maps the commit types which bump the version by default
+// to their change level. Commits of other types do not bump the version.
+var defaultVersionBumps = map[string]semver.ChangeLevel{
+	"feat": semver.Minor,
+	"fix":  semver.Patch,
+}
+
+// changeLevels maps the values of versionBumps to their change level.
+var changeLevels = map[string]semver.ChangeLevel{
+	"none":  semver.None,
+	"patch": semver.Patch,
+	"minor": semver.Minor,
+	"major": semver.Major,
+}
+
 // getHighestChange determines the highest-ranking change type from a slice of commits.
-func getHighestChange(commits []*legacygitrepo.ConventionalCommit) semver.ChangeLevel {
+func getHighestChange(commits []*legacygitrepo.ConventionalCommit, versionBumps map[string]string) semver.ChangeLevel {
 	highestChange := semver.None
 	for _, commit := range commits {
 		var currentChange semver.ChangeLevel
@@ -186,10 +308,8 @@ func getHighestChange(commits []*legacygitrepo.ConventionalCommit) semver.Change
 			currentChange = semver.Minor
 		case commit.IsBreaking:
 			currentChange = semver.Major
-		case commit.Type == "feat":
-			currentChange = semver.Minor
-		case commit.Type == "fix":
-			currentChange = semver.Patch
+		default:
+			currentChange = changeLevelFor(commit.Type, versionBumps)
 		}
 		if currentChange > highestChange {
 			highestChange = currentChange
@@ -197,3 +317,12 @@ func getHighestChange(commits []*legacygitrepo.ConventionalCommit) semver.Change
 	}
 	return highestChange
 }
+
+// changeLevelFor returns the change level of a commit of the given type,
+// looking it up in versionBumps before defaultVersionBumps.
+func changeLevelFor(commitType string, versionBumps map[string]string) semver.ChangeLevel {
+	if bump, ok := versionBumps[commitType]; ok {
+		return changeLevels[bump]
+	}
+	return defaultVersionBumps[commitType]
+}
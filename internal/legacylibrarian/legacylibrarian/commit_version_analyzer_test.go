@@ -271,7 +271,9 @@ END_COMMIT`,
 		name          string
 		repo          legacygitrepo.Repository
 		library       *legacyconfig.LibraryState
+		maxCommits    int
 		want          []*legacygitrepo.ConventionalCommit
+		wantEarlier   int
 		wantErr       bool
 		wantErrPhrase string
 	}{
@@ -322,6 +324,48 @@ END_COMMIT`,
 				},
 			},
 		},
+		{
+			name: "max_commits_keeps_most_recent_commits",
+			repo: repoWithCommits,
+			library: &legacyconfig.LibraryState{
+				ID:          "foo",
+				Version:     "1.0.0",
+				TagFormat:   "{id}-v{version}",
+				SourceRoots: []string{"foo"},
+			},
+			maxCommits: 2,
+			want: []*legacygitrepo.ConventionalCommit{
+				{
+					Type:      "fix",
+					Subject:   "a bug1 fix",
+					LibraryID: "foo",
+					Footers: map[string]string{
+						"PiperOrigin-RevId": "573342",
+						"Library-IDs":       "foo",
+						"Source-link":       "[googleapis/googleapis@fedcba09](https://github.com/googleapis/googleapis/commit/fedcba09)",
+					},
+					IsNested: true,
+				},
+				{
+					Type:      "fix",
+					Subject:   "a bug3 fix",
+					LibraryID: "foo",
+					Footers: map[string]string{
+						"PiperOrigin-RevId": "573342",
+						"Library-IDs":       "foo, bar",
+						"Source-link":       "[googleapis/googleapis@fedcba09](https://github.com/googleapis/googleapis/commit/fedcba09)",
+					},
+					IsNested: true,
+				},
+				{
+					Type:      "feat",
+					Subject:   "another feature for foo",
+					LibraryID: "foo",
+					Footers:   make(map[string]string),
+				},
+			},
+			wantEarlier: 2,
+		},
 		{
 			name: "no_matching_commits_for_foo",
 			repo: repoWithCommits,
@@ -385,7 +429,7 @@ END_COMMIT`,
 		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
-			got, err := getConventionalCommitsSinceLastRelease(test.repo, test.library, "")
+			got, earlier, err := getConventionalCommitsSinceLastRelease(test.repo, test.library, "", releaseCommitsOptions{maxCommits: test.maxCommits})
 			if test.wantErr {
 				if err == nil {
 					t.Fatal("getConventionalCommitsSinceLastRelease() should have failed")
@@ -398,13 +442,141 @@ END_COMMIT`,
 			if err != nil {
 				t.Fatalf("getConventionalCommitsSinceLastRelease() failed: %v", err)
 			}
-			if diff := cmp.Diff(test.want, got, cmpopts.IgnoreFields(legacygitrepo.ConventionalCommit{}, "CommitHash", "Body", "IsBreaking", "When")); diff != "" {
+			if diff := cmp.Diff(test.want, got, cmpopts.IgnoreFields(legacygitrepo.ConventionalCommit{}, "CommitHash", "Body", "IsBreaking", "When", "Author", "Scope")); diff != "" {
+				t.Errorf("getConventionalCommitsSinceLastRelease() mismatch (-want +got):\n%s", diff)
+			}
+			if earlier != test.wantEarlier {
+				t.Errorf("getConventionalCommitsSinceLastRelease() earlier = %d, want %d", earlier, test.wantEarlier)
+			}
+		})
+	}
+}
+
+func TestGetConventionalCommitsSinceLastRelease_ParseAffects(t *testing.T) {
+	t.Parallel()
+	repo := &MockRepository{
+		GetCommitsForPathsSinceTagValue: []*legacygitrepo.Commit{
+			{Message: "feat: update clients\n\nRegenerate the clients.\nAffects: foo, bar"},
+			{Message: "fix: fix a client\n\nFix the client.\nAffects: bar"},
+			{Message: "docs: update docs\n\nUpdate the docs."},
+		},
+		ChangedFilesInCommitValue: []string{"foo/a.txt", "bar/a.txt"},
+	}
+	library := &legacyconfig.LibraryState{ID: "foo", SourceRoots: []string{"foo"}}
+	got, _, err := getConventionalCommitsSinceLastRelease(repo, library, "", releaseCommitsOptions{parseAffects: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*legacygitrepo.ConventionalCommit{
+		{
+			Type:      "feat",
+			Subject:   "update clients",
+			LibraryID: "foo",
+			Footers:   map[string]string{"Library-IDs": "foo,bar"},
+		},
+		{
+			Type:      "docs",
+			Subject:   "update docs",
+			LibraryID: "foo",
+			Footers:   map[string]string{},
+		},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(legacygitrepo.ConventionalCommit{}, "CommitHash", "Body", "When")); diff != "" {
+		t.Errorf("getConventionalCommitsSinceLastRelease() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGetConventionalCommitsSinceLastRelease_AffectsOutsideSourceRoots(t *testing.T) {
+	t.Parallel()
+	repo := &MockRepository{
+		GetCommitsForPathsSinceTagValue: []*legacygitrepo.Commit{
+			{Message: "feat: update clients\n\nRegenerate the clients.\nAffects: foo, bar"},
+			{Message: "fix: fix a client\n\nFix the client."},
+		},
+		ChangedFilesInCommitValue: []string{"bar/a.txt"},
+	}
+	library := &legacyconfig.LibraryState{ID: "foo", SourceRoots: []string{"foo"}}
+	for _, test := range []struct {
+		name         string
+		parseAffects bool
+		want         []*legacygitrepo.ConventionalCommit
+	}{
+		{
+			name:         "affects lines parsed",
+			parseAffects: true,
+			want: []*legacygitrepo.ConventionalCommit{
+				{
+					Type:      "feat",
+					Subject:   "update clients",
+					LibraryID: "foo",
+					Footers:   map[string]string{"Library-IDs": "foo,bar"},
+				},
+			},
+		},
+		{
+			name: "affects lines ignored",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, _, err := getConventionalCommitsSinceLastRelease(repo, library, "", releaseCommitsOptions{parseAffects: test.parseAffects})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got, cmpopts.IgnoreFields(legacygitrepo.ConventionalCommit{}, "CommitHash", "Body", "When")); diff != "" {
 				t.Errorf("getConventionalCommitsSinceLastRelease() mismatch (-want +got):\n%s", diff)
 			}
 		})
 	}
 }
 
+func TestGetConventionalCommitsSinceLastRelease_AllLibraries(t *testing.T) {
+	t.Parallel()
+	repo := setupRepoForGetCommits(t, []pathAndMessage{
+		{
+			path:    "foo/a.txt",
+			message: "feat(foo): initial commit for foo",
+		},
+		{
+			path:    "bar/a.txt",
+			message: "feat(bar): a feature for bar",
+		},
+		{
+			path:    "go.mod",
+			message: "chore(deps): update dependencies\n\nLibrary-IDs: *",
+		},
+		{
+			path:    "foo/b.txt",
+			message: "fix(foo): a fix for foo",
+		},
+	}, []string{"foo-v1.0.0"})
+	library := &legacyconfig.LibraryState{ID: "foo", SourceRoots: []string{"foo"}}
+	got, _, err := getConventionalCommitsSinceLastRelease(repo, library, "foo-v1.0.0", releaseCommitsOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*legacygitrepo.ConventionalCommit{
+		{
+			Type:      "fix",
+			Scope:     "foo",
+			Subject:   "a fix for foo",
+			LibraryID: "foo",
+			Footers:   map[string]string{},
+			Author:    legacygitrepo.Signature{Name: "Test", Email: "test@example.com"},
+		},
+		{
+			Type:      "chore",
+			Scope:     "deps",
+			Subject:   "update dependencies",
+			LibraryID: "foo",
+			Footers:   map[string]string{"Library-IDs": "*"},
+			Author:    legacygitrepo.Signature{Name: "Test", Email: "test@example.com"},
+		},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(legacygitrepo.ConventionalCommit{}, "CommitHash", "Body", "When")); diff != "" {
+		t.Errorf("getConventionalCommitsSinceLastRelease() mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestGetConventionalCommitsSinceLastGeneration(t *testing.T) {
 	t.Parallel()
 	for _, test := range []struct {
@@ -547,7 +719,7 @@ func TestGetConventionalCommitsSinceLastGeneration(t *testing.T) {
 			if err != nil {
 				t.Fatalf("getConventionalCommitsSinceLastRelease() failed: %v", err)
 			}
-			if diff := cmp.Diff(test.want, got, cmpopts.IgnoreFields(legacygitrepo.ConventionalCommit{}, "CommitHash", "Body", "IsBreaking", "When")); diff != "" {
+			if diff := cmp.Diff(test.want, got, cmpopts.IgnoreFields(legacygitrepo.ConventionalCommit{}, "CommitHash", "Body", "IsBreaking", "When", "Author", "Scope")); diff != "" {
 				t.Errorf("getConventionalCommitsSinceLastRelease() mismatch (-want +got):\n%s", diff)
 			}
 		})
@@ -559,6 +731,7 @@ func TestGetHighestChange(t *testing.T) {
 	for _, test := range []struct {
 		name           string
 		commits        []*legacygitrepo.ConventionalCommit
+		versionBumps   map[string]string
 		expectedChange semver.ChangeLevel
 	}{
 		{
@@ -638,9 +811,50 @@ func TestGetHighestChange(t *testing.T) {
 			},
 			expectedChange: semver.Minor,
 		},
+		{
+			name: "unknown type does not bump",
+			commits: []*legacygitrepo.ConventionalCommit{
+				{Type: "perf"},
+				{Type: "revert"},
+			},
+			expectedChange: semver.None,
+		},
+		{
+			name: "configured type",
+			commits: []*legacygitrepo.ConventionalCommit{
+				{Type: "perf"},
+				{Type: "revert"},
+			},
+			versionBumps:   map[string]string{"perf": "patch", "revert": "minor"},
+			expectedChange: semver.Minor,
+		},
+		{
+			name: "configured type overrides default",
+			commits: []*legacygitrepo.ConventionalCommit{
+				{Type: "feat"},
+			},
+			versionBumps:   map[string]string{"feat": "patch"},
+			expectedChange: semver.Patch,
+		},
+		{
+			name: "configured none",
+			commits: []*legacygitrepo.ConventionalCommit{
+				{Type: "fix"},
+			},
+			versionBumps:   map[string]string{"fix": "none"},
+			expectedChange: semver.None,
+		},
+		{
+			name: "breaking change ignores configured type",
+			commits: []*legacygitrepo.ConventionalCommit{
+				{Type: "perf", IsBreaking: true},
+			},
+			versionBumps:   map[string]string{"perf": "none"},
+			expectedChange: semver.Major,
+		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
-			highestChange := getHighestChange(test.commits)
+			highestChange := getHighestChange(test.commits, test.versionBumps)
 			if diff := cmp.Diff(test.expectedChange, highestChange); diff != "" {
 				t.Errorf("mismatch (-want +got):\n%s", diff)
 			}
@@ -654,6 +868,7 @@ func TestNextVersion(t *testing.T) {
 		name           string
 		commits        []*legacygitrepo.ConventionalCommit
 		currentVersion string
+		versionBumps   map[string]string
 		wantVersion    string
 		wantErr        bool
 	}{
@@ -691,9 +906,18 @@ func TestNextVersion(t *testing.T) {
 			currentVersion: "1.2.3",
 			wantVersion:    "2.0.0",
 		},
+		{
+			name: "configured version bump",
+			commits: []*legacygitrepo.ConventionalCommit{
+				{Type: "perf"},
+			},
+			currentVersion: "1.2.3",
+			versionBumps:   map[string]string{"perf": "patch"},
+			wantVersion:    "1.2.4",
+		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
-			gotVersion, err := NextVersion(test.commits, test.currentVersion)
+			gotVersion, err := NextVersion(test.commits, test.currentVersion, test.versionBumps)
 			if (err != nil) != test.wantErr {
 				t.Errorf("NextVersion() error = %v, wantErr %v", err, test.wantErr)
 				return
@@ -736,6 +960,17 @@ func TestLibraryFilter(t *testing.T) {
 				"Library-IDs": "foo,bar",
 			},
 		},
+		{
+			LibraryID: "foo",
+			Footers: map[string]string{
+				"Library-IDs": "*",
+			},
+		},
+		{
+			Footers: map[string]string{
+				"Library-IDs": "foo-suffix",
+			},
+		},
 	}
 	for _, test := range []struct {
 		name      string
@@ -750,6 +985,7 @@ func TestLibraryFilter(t *testing.T) {
 				commits[2],
 				commits[4],
 				commits[5],
+				commits[6],
 			},
 		},
 		{
@@ -760,12 +996,23 @@ func TestLibraryFilter(t *testing.T) {
 				commits[3],
 				commits[4],
 				commits[5],
+				commits[6],
 			},
 		},
 		{
 			name:      "filter by baz",
 			libraryID: "baz",
-			want:      nil,
+			want: []*legacygitrepo.ConventionalCommit{
+				commits[6],
+			},
+		},
+		{
+			name:      "filter by foo-suffix",
+			libraryID: "foo-suffix",
+			want: []*legacygitrepo.ConventionalCommit{
+				commits[6],
+				commits[7],
+			},
 		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
@@ -0,0 +1,141 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacygitrepo"
+)
+
+type parseCommitRunner struct {
+	// hash is the hash of the commit to parse. If empty, the commit message
+	// is read from in.
+	hash      string
+	in        io.Reader
+	libraryID string
+	out       io.Writer
+	repo      legacygitrepo.Repository
+}
+
+// parsedCommit is the debug representation of a
+// [legacygitrepo.ConventionalCommit], which includes the fields that are
+// not serialized in container requests.
+type parsedCommit struct {
+	Type       string            `json:"type"`
+	Scope      string            `json:"scope,omitempty"`
+	Subject    string            `json:"subject"`
+	Body       string            `json:"body,omitempty"`
+	Footers    map[string]string `json:"footers,omitempty"`
+	IsBreaking bool              `json:"breaking"`
+	IsNested   bool              `json:"nested"`
+	LibraryID  string            `json:"library_id,omitempty"`
+	CommitHash string            `json:"commit_hash,omitempty"`
+}
+
+func newParseCommitRunner(cfg *legacyconfig.Config, args []string) (*parseCommitRunner, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("expected at most one commit hash, got %d arguments", len(args))
+	}
+	runner := &parseCommitRunner{
+		in:        os.Stdin,
+		libraryID: cfg.Library,
+		out:       os.Stdout,
+	}
+	if len(args) == 0 {
+		return runner, nil
+	}
+	runner.hash = args[0]
+	if isURL(cfg.Repo) {
+		repo, err := cloneOrOpenRepo(cfg.WorkRoot, cfg.Repo, cfg.APISourceDepth, cfg.Branch, cfg.CI, cfg.GitHubToken)
+		if err != nil {
+			return nil, err
+		}
+		runner.repo = repo
+		return runner, nil
+	}
+	if cfg.Repo == "" {
+		return nil, errors.New("repo must be specified")
+	}
+	repo, err := legacygitrepo.NewRepository(&legacygitrepo.RepositoryOptions{Dir: cfg.Repo})
+	if err != nil {
+		return nil, err
+	}
+	runner.repo = repo
+	return runner, nil
+}
+
+// run parses the commit message as the release stage command does, and prints
+// the resulting conventional commits as JSON.
+func (r *parseCommitRunner) run(ctx context.Context) error {
+	commit, err := r.commit()
+	if err != nil {
+		return err
+	}
+	commits, err := legacygitrepo.ParseCommits(commit, r.libraryID)
+	if err != nil {
+		return fmt.Errorf("failed to parse commit: %w", err)
+	}
+	if len(commits) == 0 {
+		return errors.New("no conventional commit found in the commit message")
+	}
+	parsed := make([]*parsedCommit, 0, len(commits))
+	for _, c := range commits {
+		p := &parsedCommit{
+			Type:       c.Type,
+			Scope:      c.Scope,
+			Subject:    c.Subject,
+			Body:       c.Body,
+			Footers:    c.Footers,
+			IsBreaking: c.IsBreaking,
+			IsNested:   c.IsNested,
+			LibraryID:  c.LibraryID,
+		}
+		// Messages read from stdin have no commit hash.
+		if r.hash != "" {
+			p.CommitHash = c.CommitHash
+		}
+		parsed = append(parsed, p)
+	}
+	data, err := json.MarshalIndent(parsed, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(r.out, string(data))
+	return err
+}
+
+// commit returns the commit to parse, read from the repository if a hash was
+// given, or from r.in otherwise.
+func (r *parseCommitRunner) commit() (*legacygitrepo.Commit, error) {
+	if r.hash != "" {
+		commit, err := r.repo.GetCommit(r.hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get commit %s: %w", r.hash, err)
+		}
+		return commit, nil
+	}
+	message, err := io.ReadAll(r.in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit message: %w", err)
+	}
+	return &legacygitrepo.Commit{Message: string(message)}, nil
+}
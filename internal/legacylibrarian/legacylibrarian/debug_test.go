@@ -0,0 +1,221 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacygitrepo"
+)
+
+func TestParseCommitRun(t *testing.T) {
+	t.Parallel()
+	hash := "1234567890abcdef1234567890abcdef12345678"
+	for _, test := range []struct {
+		name       string
+		message    string
+		hash       string
+		libraryID  string
+		repo       *MockRepository
+		want       []*parsedCommit
+		wantErrMsg string
+	}{
+		{
+			name:    "simple commit",
+			message: "fix: correct a typo",
+			want: []*parsedCommit{
+				{
+					Type:    "fix",
+					Subject: "correct a typo",
+				},
+			},
+		},
+		{
+			name: "breaking change with scope, body and footers",
+			message: `feat(parser)!: change the output
+
+The output is now sorted.
+
+Reviewed-by: Jane Doe`,
+			libraryID: "secretmanager",
+			want: []*parsedCommit{
+				{
+					Type:       "feat",
+					Scope:      "parser",
+					Subject:    "change the output",
+					Body:       "The output is now sorted.",
+					Footers:    map[string]string{"Reviewed-by": "Jane Doe"},
+					IsBreaking: true,
+					LibraryID:  "secretmanager",
+				},
+			},
+		},
+		{
+			name: "nested commits with library IDs",
+			message: `chore: regenerate libraries
+
+BEGIN_NESTED_COMMIT
+feat: [pubsub] add a field
+END_NESTED_COMMIT
+BEGIN_NESTED_COMMIT
+fix: [storage] fix a bug
+END_NESTED_COMMIT`,
+			want: []*parsedCommit{
+				{
+					Type:    "chore",
+					Subject: "regenerate libraries",
+				},
+				{
+					Type:      "feat",
+					Subject:   "[pubsub] add a field",
+					IsNested:  true,
+					LibraryID: "pubsub",
+				},
+				{
+					Type:      "fix",
+					Subject:   "[storage] fix a bug",
+					IsNested:  true,
+					LibraryID: "storage",
+				},
+			},
+		},
+		{
+			name: "commit read from the repository",
+			hash: hash,
+			repo: &MockRepository{
+				GetCommitByHash: map[string]*legacygitrepo.Commit{
+					hash: {
+						Message: "docs: update the README",
+						Hash:    plumbing.NewHash(hash),
+					},
+				},
+			},
+			want: []*parsedCommit{
+				{
+					Type:       "docs",
+					Subject:    "update the README",
+					CommitHash: hash,
+				},
+			},
+		},
+		{
+			name: "get commit fails",
+			hash: hash,
+			repo: &MockRepository{
+				GetCommitError: errors.New("not found"),
+			},
+			wantErrMsg: "failed to get commit",
+		},
+		{
+			name:       "empty message",
+			message:    "  \n",
+			wantErrMsg: "failed to parse commit",
+		},
+		{
+			name:       "not a conventional commit",
+			message:    "Update the README",
+			wantErrMsg: "no conventional commit found",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			var out bytes.Buffer
+			r := &parseCommitRunner{
+				hash:      test.hash,
+				in:        strings.NewReader(test.message),
+				libraryID: test.libraryID,
+				out:       &out,
+			}
+			if test.repo != nil {
+				r.repo = test.repo
+			}
+			err := r.run(t.Context())
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("run() error = %v, want error containing %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run() failed: %v", err)
+			}
+			var got []*parsedCommit
+			if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+				t.Fatalf("json.Unmarshal() failed: %v", err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("run() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewParseCommitRunner(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name       string
+		cfg        *legacyconfig.Config
+		args       []string
+		wantHash   string
+		wantErrMsg string
+	}{
+		{
+			name: "read from stdin",
+			cfg:  &legacyconfig.Config{},
+		},
+		{
+			name:     "read from the repository",
+			cfg:      &legacyconfig.Config{Repo: newTestGitRepo(t).GetDir()},
+			args:     []string{"abcdef"},
+			wantHash: "abcdef",
+		},
+		{
+			name:       "no repository",
+			cfg:        &legacyconfig.Config{},
+			args:       []string{"abcdef"},
+			wantErrMsg: "repo must be specified",
+		},
+		{
+			name:       "too many arguments",
+			cfg:        &legacyconfig.Config{},
+			args:       []string{"abcdef", "123456"},
+			wantErrMsg: "expected at most one commit hash",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := newParseCommitRunner(test.cfg, test.args)
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("newParseCommitRunner() error = %v, want error containing %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newParseCommitRunner() failed: %v", err)
+			}
+			if got.hash != test.wantHash {
+				t.Errorf("hash = %q, want %q", got.hash, test.wantHash)
+			}
+		})
+	}
+}
@@ -15,10 +15,17 @@
 package legacylibrarian
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacycli"
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacydocker"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacygithub"
 )
 
 func addFlagAPI(fs *flag.FlagSet, cfg *legacyconfig.Config) {
@@ -30,7 +37,54 @@ Must be specified when generating a new library.`)
 func addFlagAPISource(fs *flag.FlagSet, cfg *legacyconfig.Config) {
 	fs.StringVar(&cfg.APISource, "api-source", "https://github.com/googleapis/googleapis",
 		`The location of an API specification repository.
-Can be a remote URL or a local file path.`)
+Can be a remote URL or a local file path. The generate command accepts a
+comma-separated list of locations, which are layered into a single source
+tree: files of later sources override those of earlier ones.`)
+}
+
+func addFlagAllowMissingServiceConfig(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.BoolVar(&cfg.AllowMissingServiceConfig, "allow-missing-service-config", true,
+		`Configure a new API even if no service config is found in its directory,
+as a proto-only package. The container is sent an empty service config and
+decides how to handle it. Set to false to fail instead.`)
+}
+
+func addFlagAllowTag(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.BoolVar(&cfg.AllowTag, "allow-tag", false,
+		`Allow the image specified with --image to use a floating tag, e.g.
+:latest. By default, the image must be pinned to a digest (@sha256:...) so that
+regeneration is reproducible.`)
+}
+
+func addFlagAmend(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.BoolVar(&cfg.Amend, "amend", false,
+		`Amend the previous commit instead of creating a new one, if it was
+created by generate with -amend. Requires -commit and cannot be used with
+-push. This is useful for iterating locally without cluttering history.
+For release stage, update the open release pull request of a previous run
+instead of creating a new one, replacing its commits and the managed changelog
+section of its body, so that edits made outside of it are kept. Requires -push.`)
+}
+
+func addFlagAuthor(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.Author, "author", "",
+		`The author of commits created by Librarian, of the form "Name <email>",
+e.g. "Release Bot <release-bot@example.com>", who is also their committer unless
+-committer is specified. Cannot be used with -author-name or -author-email.`)
+}
+
+func addFlagAuthorEmail(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.AuthorEmail, "author-email", "",
+		`The email address of the author of commits created by Librarian, who is
+also their committer unless -committer is specified. If not specified, the email
+address from git config is used, falling back to the Librarian bot.`)
+}
+
+func addFlagAuthorName(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.AuthorName, "author-name", "",
+		`The name of the author of commits created by Librarian, who is also
+their committer unless -committer is specified. If not specified, the name from
+git config is used, falling back to the Librarian bot.`)
 }
 
 func addFlagBuild(fs *flag.FlagSet, cfg *legacyconfig.Config) {
@@ -39,6 +93,13 @@ func addFlagBuild(fs *flag.FlagSet, cfg *legacyconfig.Config) {
 language-specific container.`)
 }
 
+func addFlagCacheDir(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.CacheDir, "cache-dir", "",
+		`A directory in which to keep the clone of -api-source between runs, when
+it is a URL. An existing clone is fetched and checked out instead of cloning
+again. If not specified, the repository is cloned into the work root.`)
+}
+
 func addFlagBranch(fs *flag.FlagSet, cfg *legacyconfig.Config) {
 	fs.StringVar(&cfg.Branch, "branch", "main",
 		`The branch to use with remote code repositories. It is ignored if
@@ -60,18 +121,181 @@ func addFlagCommit(fs *flag.FlagSet, cfg *legacyconfig.Config) {
 a pull request. This flag is ignored if push is set to true.`)
 }
 
+func addFlagCommitMessageTemplate(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.CommitMessageTemplate, "commit-message-template", "",
+		`The Go text/template of the commit message, e.g.
+"feat: generate {{.LibraryID}}". The template may use {{.LibraryID}} and
+{{.Version}}, set when a single library is changed, {{.LibraryIDs}} and
+{{.APIPaths}}. Lists can be joined with {{join .APIPaths ", "}}. Overrides the
+template configured in config.yaml. If not specified, the default message is
+used.`)
+}
+
+func addFlagCommitter(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.Committer, "committer", "",
+		`The committer of commits created by Librarian, of the form
+"Name <email>", e.g. "CI <ci@example.com>". If not specified, the author of the
+commits is also their committer.`)
+}
+
+func addFlagConcurrency(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.IntVar(&cfg.Concurrency, "concurrency", 1,
+		`The maximum number of libraries to generate at the same time when
+generating all libraries. Each library is generated and built in its own
+language container. A value of 1 generates libraries one after another.`)
+}
+
+func addFlagContainerCPUs(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.ContainerCPUs, "container-cpus", "",
+		`The number of CPUs language containers may use, e.g. 1.5. Passed to
+docker run as --cpus. If not specified, the number of CPUs is not limited.`)
+}
+
+func addFlagContainerLogDir(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.ContainerLogDir, "container-log-dir", "",
+		`The directory to write the output of language containers to as it is
+produced, in a file per library named after the library ID, e.g.
+google_cloud_foo.log. Useful to follow long-running generations with tail -f.
+If not specified, the output is only written to the console.`)
+}
+
+func addFlagContainerMemory(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.ContainerMemory, "container-memory", "",
+		`The maximum amount of memory language containers may use, e.g. 4g.
+Passed to docker run as --memory. If not specified, memory is not limited.`)
+}
+
+func addFlagContainerReuse(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.BoolVar(&cfg.ContainerReuse, "container-reuse", false,
+		`Send the generate requests of all libraries to a single long-lived
+language container, started with the serve command, instead of running a
+container per library. Only applies to images with the
+com.google.librarian.serve=true label.`)
+}
+
+func addFlagContainerTimeout(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.DurationVar(&cfg.ContainerTimeout, "container-timeout", legacyconfig.DefaultContainerTimeout,
+		`The maximum duration of each language container run, e.g. 1h. A container
+still running once it elapses is killed, and the command fails. If zero,
+containers may run forever.`)
+}
+
+func addFlagDestPrefix(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.DestPrefix, "dest-prefix", "",
+		`A directory of the language repository, relative to its root, under which
+the generated code is written. The source roots, preserve_regex and
+remove_regex of libraries are then relative to this directory, while
+state.yaml is left unchanged.`)
+}
+
+func addFlagDetectRenamedAPIs(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.BoolVar(&cfg.DetectRenamedAPIs, "detect-renamed-apis", false,
+		`Migrate the APIs whose paths no longer exist in the API source to their
+new paths before generating. The new path of an API is the one configured
+in api_renames in config.yaml or, if none, the only newer stable version
+directory next to its old path, e.g. google/cloud/x/v2 for google/cloud/x/v1
+or google/cloud/x/v1 for google/cloud/x/v1beta1. Other renames must be
+configured in api_renames.`)
+}
+
+func addFlagDiffOutput(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.DiffOutput, "diff-output", "",
+		`When used with --check-unexpected-changes, the path of a file to write
+a unified diff of the unexpected file changes to.`)
+}
+
+func addFlagDryRun(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.BoolVar(&cfg.DryRun, "dry-run", false,
+		`Report the libraries that would be configured and generated, without
+running any containers or changing any files. No commit or pull request is
+created, even if the --push flag is specified.`)
+}
+
+func addFlagExcludeCommit(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.Func("exclude-commit",
+		`The hash of a commit, full or abbreviated, to leave out of the release.
+The changes of the commit are neither listed in the release notes nor
+considered when determining the next version. May be repeated to exclude
+several commits.`,
+		func(s string) error {
+			if s == "" {
+				return errors.New("commit hash cannot be empty")
+			}
+			cfg.ExcludeCommits = append(cfg.ExcludeCommits, s)
+			return nil
+		})
+}
+
+func addFlagFailFast(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.BoolVar(&cfg.FailFast, "fail-fast", false,
+		`Stop at the first library that fails to generate or build, and return
+its error. By default, the remaining libraries are generated and all failures
+are reported at the end.`)
+}
+
+func addFlagFetchBeforeStage(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.BoolVar(&cfg.FetchBeforeStage, "fetch-before-stage", true,
+		`Fetch the branch (see --branch) and tags of the origin remote before
+staging a release, and fail if the local branch is behind the remote one, so
+that versions are computed from the current tags. Repositories without an
+origin remote are not fetched. Use --fetch-before-stage=false to disable.`)
+}
+
+func addFlagForce(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.BoolVar(&cfg.Force, "force", false,
+		`Process the libraries which are frozen in the librarian config. By default,
+frozen libraries are skipped with a warning, even when named with --library.`)
+}
+
 func addFlagGenerateUnchanged(fs *flag.FlagSet, cfg *legacyconfig.Config) {
 	fs.BoolVar(&cfg.GenerateUnchanged, "generate-unchanged", false,
 		`If true, librarian generates libraries even if none of their associated APIs
 have changed. This does not override generation being blocked by configuration.`)
 }
 
+func addFlagGenerateUnchangedFor(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.Func("generate-unchanged-for",
+		`The ID of a library to generate even if none of its associated APIs have
+changed. May be repeated to name several libraries. Other libraries are still
+only generated when their APIs have changed. This does not override generation
+being blocked by configuration.`,
+		func(s string) error {
+			if s == "" {
+				return errors.New("library ID cannot be empty")
+			}
+			cfg.GenerateUnchangedFor = append(cfg.GenerateUnchangedFor, s)
+			return nil
+		})
+}
+
+func addFlagGeneratorInput(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.GeneratorInput, "generator-input", "",
+		`The path of a directory to mount as the generator input of the configure
+and generate containers, instead of .librarian/generator-input in the language
+repository. The directory must exist.`)
+}
+
 func addFlagGitHubAPIEndpoint(fs *flag.FlagSet, cfg *legacyconfig.Config) {
 	fs.StringVar(&cfg.GitHubAPIEndpoint, "github-api-endpoint", "",
 		`The GitHub API endpoint to use for all GitHub API operations.
 This is intended for testing and should not be used in production.`)
 }
 
+func addFlagGitHubMaxRetries(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.IntVar(&cfg.GitHubMaxRetries, "github-max-retries", legacygithub.DefaultMaxRetries,
+		`The number of times a GitHub API request failing with a server error or
+a secondary rate limit is retried. Requests which create or modify resources,
+e.g. pull requests, are only retried when GitHub asks to retry them later.
+Zero disables retries.`)
+}
+
+func addFlagGitHubRetryDelay(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.DurationVar(&cfg.GitHubRetryDelay, "github-retry-delay", legacygithub.DefaultRetryDelay,
+		`The delay before the first retry of a GitHub API request, e.g. 2s. The
+delay doubles with each further retry, unless GitHub specifies the delay with a
+Retry-After header.`)
+}
+
 func addFlagHostMount(fs *flag.FlagSet, cfg *legacyconfig.Config) {
 	defaultValue := ""
 	fs.StringVar(&cfg.HostMount, "host-mount", defaultValue,
@@ -80,16 +304,38 @@ directory from the host to the container, in the format
 <host-mount>:<local-mount>.`)
 }
 
+func addFlagIgnoreFreeze(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.BoolVar(&cfg.IgnoreFreeze, "ignore-freeze", false,
+		`Stage releases even though they are frozen by the freeze_until option
+of .librarian/config.yaml.`)
+}
+
+func addFlagIncludeHiddenCommits(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.BoolVar(&cfg.IncludeHiddenCommits, "include-hidden-commits", false,
+		`With -report-unreleased, also list the commits since the last release
+of each library which are not released on their own, such as chore,
+test and build commits, in a separate "not released" section.`)
+}
+
 func addFlagImage(fs *flag.FlagSet, cfg *legacyconfig.Config) {
 	fs.StringVar(&cfg.Image, "image", "",
 		`Language specific image used to invoke code generation and releasing.
 If not specified, the image configured in the state.yaml is used.`)
 }
 
+func addFlagLanguage(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.Language, "language", "",
+		`Only process the libraries of the given language (e.g. rust), as set by
+their language in state.yaml. This is intended for repositories whose state.yaml
+holds libraries of several languages. If not specified, all libraries are
+processed.`)
+}
+
 func addFlagLibrary(fs *flag.FlagSet, cfg *legacyconfig.Config) {
 	fs.StringVar(&cfg.Library, "library", "",
 		`The library ID to generate or release (e.g. secretmanager).
-This corresponds to a releasable language unit.`)
+This corresponds to a releasable language unit. The release stage command
+also accepts a comma-separated list of library IDs to release together.`)
 }
 
 func addFlagLibraryToTest(fs *flag.FlagSet, cfg *legacyconfig.Config) {
@@ -101,7 +347,134 @@ func addFlagLibraryToTest(fs *flag.FlagSet, cfg *legacyconfig.Config) {
 func addFlagLibraryVersion(fs *flag.FlagSet, cfg *legacyconfig.Config) {
 	fs.StringVar(&cfg.LibraryVersion, "library-version", "",
 		`Overrides the automatic semantic version calculation and forces a specific
-version for a library. Requires the --library flag to be specified.`)
+version for a library. Requires the --library flag to be specified with a
+single library ID.`)
+}
+
+func addFlagLogFormat(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.LogFormat, "log-format", legacycli.LogFormatText,
+		`The format of the logs, either "text" or "json".`)
+}
+
+func addFlagLogLevel(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.LogLevel, "log-level", "info",
+		`The minimum level of the logs, one of "debug", "info", "warn" or "error".
+The -v flag takes precedence and selects "debug", and -quiet selects
+"error".`)
+}
+
+func addFlagManifestOutput(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.ManifestOutput, "manifest-output", "",
+		`The path of a file to write a JSON manifest of the created releases to,
+listing the library, version, tag, release URL and commit of each release.`)
+}
+
+func addFlagMaxChangelogEntries(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.IntVar(&cfg.MaxChangelogEntries, "max-changelog-entries", 0,
+		`The maximum number of changes to list for each library in the release
+pull request body. Remaining changes are summarized with a link to the full
+comparison. Defaults to 0, which lists every change.`)
+}
+
+func addFlagMaxCommits(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.IntVar(&cfg.MaxCommits, "max-commits", 0,
+		`The maximum number of commits to scan for each library since its last
+release, keeping the most recent ones. The release notes mention the number of
+earlier commits. Defaults to 0, which scans every commit.`)
+}
+
+func addFlagMaxContainers(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.IntVar(&cfg.MaxContainers, "max-containers", 0,
+		`The maximum number of language containers to run at the same time,
+across the generation and build of all libraries. This is independent of
+-concurrency, and protects machines with limited resources. Defaults to 0,
+which does not limit the number of containers.`)
+}
+
+func addFlagMount(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.Func("mount",
+		`An extra volume to mount into the language containers running the build,
+configure and generate commands, e.g. a module cache, in the format
+<host-dir>:<container-dir>[:ro]. The host directory must exist. May be
+repeated to mount several volumes.`,
+		func(s string) error {
+			cfg.Mounts = append(cfg.Mounts, s)
+			return nil
+		})
+}
+
+func addFlagNoLock(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.BoolVar(&cfg.NoLock, "no-lock", false,
+		`Do not lock the language repository. By default, the command fails if
+another librarian process is running against the same repository.`)
+}
+
+func addFlagNoNetwork(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.Func("no-network",
+		`A language container command, one of "build", "configure" or "generate",
+to run without network access, passing --network=none to docker run. May be
+repeated to name several commands. Containers of other commands use the default
+network, as some need it, e.g. to fetch dependencies during build.`,
+		func(s string) error {
+			switch legacydocker.Command(s) {
+			case legacydocker.CommandBuild, legacydocker.CommandConfigure, legacydocker.CommandGenerate:
+				cfg.NoNetwork = append(cfg.NoNetwork, s)
+				return nil
+			}
+			return fmt.Errorf("invalid command %q", s)
+		})
+}
+
+func addFlagNormalizeLineEndings(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.NormalizeLineEndings, "normalize-line-endings", "",
+		`Convert the line endings of generated text files to "lf" or "crlf" before
+copying them into the language repository. Binary files are left untouched.
+If not specified, line endings are left as generated.`)
+}
+
+func addFlagOnlyChanged(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.BoolVar(&cfg.OnlyChanged, "only-changed", false,
+		`Generate only the libraries with uncommitted changes, either in their
+source roots in the language repository or in their APIs in the API source,
+whether or not their APIs changed since they were last generated. When used
+with -library or -api, the library is skipped unless it has such changes.`)
+}
+
+func addFlagOnlyIfAPIChanged(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.BoolVar(&cfg.OnlyIfAPIChanged, "only-if-api-changed", false,
+		`Keep only the libraries whose generated output changed with the new
+image. Libraries whose output is unchanged are discarded and not built, and no
+commit is created if no library changed.`)
+}
+
+func addFlagOutputManifest(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.OutputManifest, "output-manifest", "",
+		`The path of a JSON file to write, for each generated library, the files
+it created, modified and removed in the language repository, relative to the
+root of the repository. Libraries which fail to generate, build or lint are
+restored and left out.`)
+}
+
+func addFlagOutputState(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.OutputState, "output-state", "",
+		`The path of a file to write the resulting state to, in addition to
+.librarian/state.yaml in the language repository.`)
+}
+
+func addFlagPostReleaseUpdate(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.BoolVar(&cfg.PostReleaseUpdate, "post-release-update", false,
+		`After releasing libraries, update the versions file configured under
+post_release in .librarian/config.yaml with the released versions. The update
+is committed to the base branch of the release pull request, or proposed in a
+new pull request if create_pull_request is set.`)
+}
+
+func addFlagPRTemplate(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.PRTemplate, "pr-template", "",
+		`The path of a pull request template, relative to the root of the language
+repository, e.g. .github/PULL_REQUEST_TEMPLATE.md. Its content is prepended
+to the body of the created pull request, before the section managed by
+Librarian. If not specified, no template is used.`)
 }
 
 func addFlagPR(fs *flag.FlagSet, cfg *legacyconfig.Config) {
@@ -109,7 +482,23 @@ func addFlagPR(fs *flag.FlagSet, cfg *legacyconfig.Config) {
 		`The URL of a pull request to operate on.
 It should be in the format of https://github.com/{owner}/{repo}/pull/{number}.
 If not specified, will search for all merged pull requests with the label
-"release:pending" in the last 30 days.`)
+"release:pending" in the last 30 days, or the window set with --since.`)
+}
+
+func addFlagProgressInterval(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.DurationVar(&cfg.ProgressInterval, "progress-interval", 0,
+		`The minimum time between two progress logs when processing all libraries,
+e.g. 1m. Each log includes the number of libraries processed so far and an
+estimate of the remaining time. If zero, no progress is logged.`)
+}
+
+func addFlagPubSubTopic(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.PubSubTopic, "pubsub-topic", "",
+		`A Pub/Sub topic, of the form projects/{project}/topics/{topic}, to which
+a JSON message is published for each library with its ID, outcome and version
+as soon as the library is processed. Once a pull request is created, a message
+with its URL is published for each generated library. Application default
+credentials are used. Failures to publish are logged as warnings and do not fail the run.`)
 }
 
 func addFlagPush(fs *flag.FlagSet, cfg *legacyconfig.Config) {
@@ -120,19 +509,129 @@ A GitHub token with push access must be provided via the
 %s environment variable.`, legacyconfig.LibrarianGithubToken))
 }
 
+func addFlagQuiet(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	const usage = `Only log errors, and only show the output of language containers when
+they fail. By default, logs are written at the level of -log-level, and the
+output of containers is shown as it arrives, each line prefixed with the
+library ID and command, e.g. "[secretmanager generate]". The -v flag takes
+precedence over the log level.`
+	fs.BoolVar(&cfg.Quiet, "quiet", false, usage)
+	fs.BoolVar(&cfg.Quiet, "q", false, "Shorthand for -quiet.")
+}
+
+func addFlagRebaseOntoBase(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.BoolVar(&cfg.RebaseOntoBase, "rebase-onto-base", false,
+		`Rebase the generated branch onto the latest base branch (see --branch)
+before pushing it, so that the pull request can be merged cleanly. If the
+rebase has conflicts, the conflicting files are reported and no pull request
+is created. Requires the --push flag.`)
+}
+
+func addFlagReport(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.Report, "report", "",
+		`The path of a JSON file to write a summary of the run to. For each
+library, the summary records whether it was configured, regenerated or skipped,
+whether generation and build succeeded, how long it took, its resulting
+last_generated_commit and any error. The summary is written even if some
+libraries fail to generate.`)
+}
+
 func addFlagRepo(fs *flag.FlagSet, cfg *legacyconfig.Config) {
 	fs.StringVar(&cfg.Repo, "repo", "",
 		`Code repository where the generated code will reside. Can be a remote
 in the format of a remote URL such as https://github.com/{owner}/{repo} or a
 local file path like /path/to/repo. Both absolute and relative paths are
-supported. If not specified, will try to detect if the current working directory
-is configured as a language repository.
+supported. If not specified, the closest directory holding a .librarian/state.yaml
+file, starting from the current working directory and walking up its parents,
+is used as the language repository.
 Note: When using a local repository (either by providing a path or by defaulting
 to the current directory), Librarian creates a new branch from the currently checked-out
 branch and commits changes. If the --push flag is also specified, a pull request is
 created against the main branch. The --branch flag is ignored for local repositories.`)
 }
 
+func addFlagReportUnreleased(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.BoolVar(&cfg.ReportUnreleased, "report-unreleased", false,
+		`Report the libraries with releasable changes since their last release,
+and the version each would be released at, without staging a release.
+No files are changed and no containers are run.`)
+}
+
+func addFlagSince(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	cfg.Since = legacyconfig.DefaultSince
+	fs.Func("since",
+		`How far back to search for merged pull requests with the label
+"release:pending" when --pr is not specified, either as a number of days,
+e.g. 90d, or as a Go duration, e.g. 36h. Must be positive. Defaults to 30d.`,
+		func(s string) error {
+			since, err := parseSince(s)
+			if err != nil {
+				return err
+			}
+			cfg.Since = since
+			return nil
+		})
+}
+
+// parseSince parses a positive duration given either as a number of days,
+// e.g. "90d", or in the format accepted by [time.ParseDuration].
+func parseSince(s string) (time.Duration, error) {
+	var since time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number of days %q", s)
+		}
+		since = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, err
+		}
+		since = d
+	}
+	if since <= 0 {
+		return 0, fmt.Errorf("duration %q must be positive", s)
+	}
+	return since, nil
+}
+
+func addFlagSkipConfigure(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.BoolVar(&cfg.SkipConfigure, "skip-configure", false,
+		`Skip configuring the library even if it appears to need configuration,
+and generate it with its existing configuration instead. The library must
+already have source roots in state.yaml.`)
+}
+
+func addFlagSkipLibrariesWithoutSource(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.BoolVar(&cfg.SkipLibrariesWithoutSource, "skip-libraries-without-source", true,
+		`Skip, with a warning, the libraries without source roots in state.yaml,
+whose onboarding is incomplete. Libraries specified with --library are never
+skipped. Use --skip-libraries-without-source=false to disable.`)
+}
+
+func addFlagSquashMergeTitles(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.BoolVar(&cfg.SquashMergeTitles, "squash-merge-titles", false,
+		`Parse the title of the pull request of squash-merged commits, whose
+subject ends with the pull request number, e.g. (#123), as their conventional
+commit instead of their subject. The titles are looked up on GitHub; if a
+lookup fails, the subject of the commit is kept.`)
+}
+
+func addFlagStrictSemVer(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.BoolVar(&cfg.StrictSemVer, "strict-semver", false,
+		`Require the current version of every library to be a complete semantic
+version, e.g. 1.2.3, and fail listing the libraries with other versions. By
+default, versions such as 1.0 or v2 are coerced to semantic versions.`)
+}
+
+func addFlagTagType(fs *flag.FlagSet, cfg *legacyconfig.Config) {
+	fs.StringVar(&cfg.TagType, "tag-type", legacyconfig.TagTypeAnnotated,
+		`The type of the tags created for released libraries, either "annotated"
+or "lightweight". Annotated tags carry the release notes of the library as
+the tag message.`)
+}
+
 func addFlagTest(fs *flag.FlagSet, cfg *legacyconfig.Config) {
 	fs.BoolVar(&cfg.Test, "test", false,
 		`If true, run container tests after generation but before committing and pushing.
@@ -0,0 +1,132 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+)
+
+func TestAddFlagSince(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		args       []string
+		want       time.Duration
+		wantErrMsg string
+	}{
+		{
+			name: "default",
+			want: 30 * 24 * time.Hour,
+		},
+		{
+			name: "days",
+			args: []string{"-since=90d"},
+			want: 90 * 24 * time.Hour,
+		},
+		{
+			name: "go duration",
+			args: []string{"-since=36h"},
+			want: 36 * time.Hour,
+		},
+		{
+			name:       "zero",
+			args:       []string{"-since=0d"},
+			wantErrMsg: "must be positive",
+		},
+		{
+			name:       "negative",
+			args:       []string{"-since=-1h"},
+			wantErrMsg: "must be positive",
+		},
+		{
+			name:       "invalid days",
+			args:       []string{"-since=1.5d"},
+			wantErrMsg: "invalid number of days",
+		},
+		{
+			name:       "invalid duration",
+			args:       []string{"-since=a week"},
+			wantErrMsg: "invalid duration",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := &legacyconfig.Config{}
+			fs := flag.NewFlagSet("tag", flag.ContinueOnError)
+			fs.SetOutput(&strings.Builder{})
+			addFlagSince(fs, cfg)
+			err := fs.Parse(test.args)
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("Parse() error = %v, want contains %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Since != test.want {
+				t.Errorf("Since = %s, want %s", cfg.Since, test.want)
+			}
+		})
+	}
+}
+
+func TestAddFlagNoNetwork(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		args       []string
+		want       []string
+		wantErrMsg string
+	}{
+		{
+			name: "default",
+		},
+		{
+			name: "repeated",
+			args: []string{"-no-network=generate", "-no-network=build"},
+			want: []string{"generate", "build"},
+		},
+		{
+			name:       "release stage",
+			args:       []string{"-no-network=release-stage"},
+			wantErrMsg: `invalid command "release-stage"`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := &legacyconfig.Config{}
+			fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+			fs.SetOutput(&strings.Builder{})
+			addFlagNoNetwork(fs, cfg)
+			err := fs.Parse(test.args)
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("Parse() error = %v, want contains %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, cfg.NoNetwork); diff != "" {
+				t.Errorf("NoNetwork mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
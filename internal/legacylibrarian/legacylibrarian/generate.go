@@ -27,53 +27,81 @@ import (
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacygitrepo"
 )
 
-func generateSingleLibrary(ctx context.Context, containerClient ContainerClient, state *legacyconfig.LibrarianState, libraryState *legacyconfig.LibraryState, repo legacygitrepo.Repository, sourceRepo legacygitrepo.Repository, outputDir string) error {
+// generateSingleLibrary generates the library with the given state into
+// outputDir and copies the generated code into the language repository. The
+// request and response files of the container are written to librarianDir,
+// or to the .librarian directory of the repository if librarianDir is empty.
+// The container reads its generator input from generatorInputDir, or from the
+// .librarian/generator-input directory of the repository if it is empty.
+// The line endings of the generated text files are converted to lineEnding,
+// unless it is empty. The generated code is copied under the destPrefix
+// directory of the repository, unless it is empty. If trackChanges is set, it
+// returns the files of the library changed in the language repository.
+func generateSingleLibrary(ctx context.Context, containerClient ContainerClient, state *legacyconfig.LibrarianState, libraryState *legacyconfig.LibraryState, repo legacygitrepo.Repository, sourceRepo legacygitrepo.Repository, outputDir, librarianDir, generatorInputDir, lineEnding, destPrefix string, trackChanges bool) (*libraryFileChanges, error) {
 	// For each library, create a separate output directory. This avoids
 	// libraries interfering with each other, and makes it easier to see what
 	// was generated for each library when debugging.
 	safeLibraryDirectory := getSafeDirectoryName(libraryState.ID)
 	libraryOutputDir := filepath.Join(outputDir, safeLibraryDirectory)
 	if err := os.MkdirAll(libraryOutputDir, 0755); err != nil {
-		return fmt.Errorf("error making output directory %w", err)
+		return nil, fmt.Errorf("error making output directory %w", err)
 	}
 
 	apiRoot, err := filepath.Abs(sourceRepo.GetDir())
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	generateRequest := &legacydocker.GenerateRequest{
-		ApiRoot:   apiRoot,
-		LibraryID: libraryState.ID,
-		Output:    libraryOutputDir,
-		RepoDir:   repo.GetDir(),
-		State:     state,
-		Image:     state.Image,
+		ApiRoot:           apiRoot,
+		GeneratorInputDir: generatorInputDir,
+		LibraryID:         libraryState.ID,
+		LibrarianDir:      librarianDir,
+		Output:            libraryOutputDir,
+		RepoDir:           repo.GetDir(),
+		State:             state,
+		Image:             state.Image,
 	}
 	slog.Info("performing generation for library", "id", libraryState.ID, "outputDir", libraryOutputDir)
 	if err := containerClient.Generate(ctx, generateRequest); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Read the library state from the response.
 	if _, err := readLibraryState(
-		filepath.Join(generateRequest.RepoDir, legacyconfig.LibrarianDir, legacyconfig.GenerateResponse)); err != nil {
-		return err
+		filepath.Join(responseDir(librarianDir, repo), legacyconfig.GenerateResponse)); err != nil {
+		return nil, err
 	}
 
-	if err := cleanAndCopyLibrary(state, repo.GetDir(), libraryState.ID, libraryOutputDir); err != nil {
-		return err
+	if err := normalizeLineEndings(libraryOutputDir, lineEnding); err != nil {
+		return nil, fmt.Errorf("failed to normalize line endings of library %s: %w", libraryState.ID, err)
+	}
+
+	changes, err := cleanAndCopyLibrary(state, repo.GetDir(), destPrefix, libraryState.ID, libraryOutputDir, trackChanges)
+	if err != nil {
+		return nil, err
 	}
 
 	slog.Info("generation succeeds", "id", libraryState.ID)
-	return nil
+	return changes, nil
+}
+
+// responseDir returns the directory holding the response files of the
+// container, which is librarianDir if set.
+func responseDir(librarianDir string, repo legacygitrepo.Repository) string {
+	if librarianDir != "" {
+		return librarianDir
+	}
+	return filepath.Join(repo.GetDir(), legacyconfig.LibrarianDir)
 }
 
-func restoreLibrary(libraryState *legacyconfig.LibraryState, repo legacygitrepo.Repository) error {
-	if err := repo.Restore(libraryState.SourceRoots); err != nil {
+// restoreLibrary restores the given source roots of a library in repo,
+// relative to its root, to their committed state.
+func restoreLibrary(sourceRoots []string, repo legacygitrepo.Repository) error {
+	if err := repo.Restore(sourceRoots); err != nil {
 		return err
 	}
-	return repo.CleanUntracked(libraryState.SourceRoots)
+	return repo.CleanUntracked(sourceRoots)
 }
 
 // getSafeDirectoryName returns a directory name which doesn't contain slashes
@@ -18,9 +18,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"text/template"
+	"time"
 
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacydocker"
@@ -48,6 +56,40 @@ type generateRunner struct {
 	state             *legacyconfig.LibrarianState
 	librarianConfig   *legacyconfig.LibrarianConfig
 	workRoot          string
+
+	amend                bool
+	author               *legacygitrepo.Signature
+	committer            *legacygitrepo.Signature
+	concurrency          int
+	destPrefix           string
+	detectRenamedAPIs    bool
+	dryRun               bool
+	failFast             bool
+	force                bool
+	generateUnchangedFor []string
+	generatorInput       string
+	language             string
+	lineEnding           string
+	locallyChanged       map[string]bool
+	onlyChanged          bool
+	out                  io.Writer
+	outputManifest       string
+	outputState          string
+	prTemplate           string
+	progressInterval     time.Duration
+	publisher            Publisher
+	pubSubTopic          string
+	rebaseOntoBase       bool
+	report               string
+	requireServiceConfig bool
+	result               *GenerationReport
+	skipConfigure        bool
+	// commitTemplate renders the message of the created commit. If
+	// nil, the default message is used.
+	commitTemplate *template.Template
+	// mu guards state and the work tree of repo while libraries are
+	// generated concurrently.
+	mu sync.Mutex
 }
 
 // generationStatus represents the result of a single library generation.
@@ -55,39 +97,142 @@ type generationStatus struct {
 	// oldCommit is the SHA of the previously generated version of the library.
 	oldCommit string
 	prType    pullRequestType
+	// files are the files of the library changed by copying its generated
+	// code, or nil if no code was generated.
+	files *libraryFileChanges
 }
 
 func newGenerateRunner(cfg *legacyconfig.Config) (*generateRunner, error) {
+	author, err := commitAuthor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	committer, err := commitCommitter(cfg)
+	if err != nil {
+		return nil, err
+	}
 	runner, err := newCommandRunner(cfg)
 	if err != nil {
 		return nil, err
 	}
+	templateText := cfg.CommitMessageTemplate
+	if templateText == "" && runner.librarianConfig != nil && runner.librarianConfig.CommitMessageTemplates != nil {
+		templateText = runner.librarianConfig.CommitMessageTemplates.Generate
+	}
+	commitTemplate, err := parseCommitMessageTemplate(templateText, defaultGenerateCommitMessage)
+	if err != nil {
+		return nil, err
+	}
+	var generatorInput string
+	if cfg.GeneratorInput != "" {
+		// The directory is mounted into containers, which requires an
+		// absolute path.
+		if generatorInput, err = filepath.Abs(cfg.GeneratorInput); err != nil {
+			return nil, err
+		}
+	}
 	return &generateRunner{
-		api:               cfg.API,
-		branch:            cfg.Branch,
-		build:             cfg.Build,
-		commit:            cfg.Commit,
-		containerClient:   runner.containerClient,
-		generateUnchanged: cfg.GenerateUnchanged,
-		ghClient:          runner.ghClient,
-		hostMount:         cfg.HostMount,
-		image:             runner.image,
-		library:           cfg.Library,
-		push:              cfg.Push,
-		repo:              runner.repo,
-		sourceRepo:        runner.sourceRepo,
-		state:             runner.state,
-		librarianConfig:   runner.librarianConfig,
-		workRoot:          runner.workRoot,
+		amend:                cfg.Amend,
+		api:                  cfg.API,
+		author:               author,
+		branch:               cfg.Branch,
+		build:                cfg.Build,
+		commit:               cfg.Commit,
+		commitTemplate:       commitTemplate,
+		committer:            committer,
+		concurrency:          cfg.Concurrency,
+		containerClient:      limitContainers(runner.containerClient, cfg.MaxContainers),
+		destPrefix:           cfg.DestPrefix,
+		detectRenamedAPIs:    cfg.DetectRenamedAPIs,
+		dryRun:               cfg.DryRun,
+		failFast:             cfg.FailFast,
+		force:                cfg.Force,
+		generateUnchanged:    cfg.GenerateUnchanged,
+		generateUnchangedFor: cfg.GenerateUnchangedFor,
+		generatorInput:       generatorInput,
+		ghClient:             runner.ghClient,
+		hostMount:            cfg.HostMount,
+		image:                runner.image,
+		language:             cfg.Language,
+		library:              cfg.Library,
+		lineEnding:           cfg.NormalizeLineEndings,
+		onlyChanged:          cfg.OnlyChanged,
+		out:                  os.Stdout,
+		outputManifest:       cfg.OutputManifest,
+		outputState:          cfg.OutputState,
+		prTemplate:           cfg.PRTemplate,
+		progressInterval:     cfg.ProgressInterval,
+		pubSubTopic:          cfg.PubSubTopic,
+		push:                 cfg.Push,
+		rebaseOntoBase:       cfg.RebaseOntoBase,
+		repo:                 runner.repo,
+		report:               cfg.Report,
+		requireServiceConfig: !cfg.AllowMissingServiceConfig,
+		skipConfigure:        cfg.SkipConfigure,
+		sourceRepo:           runner.sourceRepo,
+		state:                runner.state,
+		librarianConfig:      runner.librarianConfig,
+		workRoot:             runner.workRoot,
 	}, nil
 }
 
+// NewGenerateConfig returns the configuration of the generate command with
+// the default values of its flags, to be adjusted and passed to Generate.
+func NewGenerateConfig() *legacyconfig.Config {
+	return newCmdGenerate().Config
+}
+
+// Generate runs the generate command as configured by cfg, whose fields are
+// set by the flags of the command, and returns the outcome for each library
+// considered. cfg is typically created with NewGenerateConfig. The report is
+// returned along with the error of the command, if any, and is nil if no
+// library was considered, e.g. because cfg is invalid.
+//
+// Programs outside this module use the Generate function of the
+// legacylibrarian/api package, which wraps this one.
+func Generate(ctx context.Context, cfg *legacyconfig.Config) (*GenerationReport, error) {
+	if err := cfg.SetDefaults(); err != nil {
+		return nil, fmt.Errorf("failed to initialize config: %w", err)
+	}
+	if _, err := cfg.IsValid(); err != nil {
+		return nil, fmt.Errorf("failed to validate config: %s", err)
+	}
+	runner, err := newGenerateRunner(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer closeContainerClient(runner.containerClient)
+	err = runWithRepoLock(cfg, runner.repo.GetDir(), func() error {
+		return runner.run(ctx)
+	})
+	return runner.result, err
+}
+
 // run executes the library generation process.
 //
 // It determines whether to generate a single library or all configured libraries based on the
 // command-line flags. If an API or library is specified, it generates a single library. Otherwise,
 // it iterates through all libraries defined in the state and generates them.
 func (r *generateRunner) run(ctx context.Context) error {
+	if r.detectRenamedAPIs && r.sourceRepo != nil {
+		if err := migrateRenamedAPIs(r.state, r.librarianConfig, r.sourceRepo.GetDir()); err != nil {
+			return err
+		}
+	}
+	if r.onlyChanged {
+		changed, err := r.locallyChangedLibraries()
+		if err != nil {
+			return err
+		}
+		r.locallyChanged = changed
+	}
+	plan, err := r.planGeneration()
+	if err != nil {
+		return err
+	}
+	if r.dryRun {
+		return r.writeGenerationPlan(r.out, plan)
+	}
 	outputDir := filepath.Join(r.workRoot, "output")
 	if err := os.Mkdir(outputDir, 0755); err != nil {
 		return fmt.Errorf("failed to make output directory, %s: %w", outputDir, err)
@@ -98,13 +243,49 @@ func (r *generateRunner) run(ctx context.Context) error {
 	idToCommits := make(map[string]string)
 	var failedLibraries []string
 	prType := pullRequestGenerate
+	report := &GenerationReport{}
+	r.result = report
+	manifest := map[string]*libraryFileChanges{}
+	var pullRequestURL string
+	r.initPublisher()
+	// Libraries are published as soon as they are processed, and once more
+	// with the pull request, if any, once it is created.
+	addResult := func(library *LibraryGenerationReport) {
+		report.add(library)
+		r.publishGenerationResult(ctx, library)
+	}
+	defer func() {
+		r.publishPullRequest(ctx, report, pullRequestURL)
+	}()
 	if r.api != "" || r.library != "" {
-		libraryID := r.library
-		if libraryID == "" {
-			libraryID = findLibraryIDByAPIPath(r.state, r.api)
+		planned := plan[0]
+		libraryID := planned.id
+		if !planned.generate {
+			// We assume that the cause will have been logged in planGeneration.
+			addResult(&LibraryGenerationReport{ID: libraryID, Action: reportActionSkipped})
+			return r.writeReport(report)
+		}
+		action := reportActionRegenerated
+		if planned.configure {
+			action = reportActionConfigured
 		}
+		if planned.err != nil {
+			addResult(r.newLibraryGenerationReport(libraryID, action, 0, planned.err))
+			return errors.Join(planned.err, r.writeReport(report))
+		}
+		start := time.Now()
 		status, err := r.generateSingleLibrary(ctx, libraryID, outputDir)
+		addResult(r.newLibraryGenerationReport(libraryID, action, time.Since(start), err))
 		if err != nil {
+			return errors.Join(err, r.writeReport(report))
+		}
+		if err := r.writeReport(report); err != nil {
+			return err
+		}
+		if status.files != nil {
+			manifest[libraryID] = status.files
+		}
+		if err := r.writeManifest(manifest); err != nil {
 			return err
 		}
 		idToCommits[libraryID] = status.oldCommit
@@ -112,31 +293,52 @@ func (r *generateRunner) run(ctx context.Context) error {
 	} else {
 		var succeededGenerations int
 		var skippedGenerations int
-		for _, library := range r.state.Libraries {
-			shouldGenerate, err := r.shouldGenerate(library)
-			if err != nil {
-				slog.Error("failed to determine whether or not to generate library", "id", library.ID, "err", err)
+		var libraryIDs []string
+		for _, planned := range plan {
+			if planned.err != nil {
+				slog.Error("failed to determine whether or not to generate library", "id", planned.id, "err", planned.err)
 				// While this isn't strictly a failed generation, it's a library for which
 				// the generate command failed, so it's close enough.
-				failedLibraries = append(failedLibraries, library.ID)
+				failedLibraries = append(failedLibraries, planned.id)
+				addResult(r.newLibraryGenerationReport(planned.id, reportActionRegenerated, 0, planned.err))
+				if r.failFast {
+					return errors.Join(planned.err, r.writeReport(report))
+				}
 				continue
 			}
-			if !shouldGenerate {
-				// We assume that the cause will have been logged in shouldGenerateLibrary.
+			if !planned.generate {
+				// We assume that the cause will have been logged in planGeneration.
 				skippedGenerations++
+				addResult(&LibraryGenerationReport{ID: planned.id, Action: reportActionSkipped})
 				continue
 			}
-			status, err := r.generateSingleLibrary(ctx, library.ID, outputDir)
-			if err != nil {
-				slog.Error("failed to generate library", "id", library.ID, "err", err)
-				failedLibraries = append(failedLibraries, library.ID)
+			libraryIDs = append(libraryIDs, planned.id)
+		}
+		results, err := r.generateLibraries(ctx, libraryIDs, outputDir)
+		for i, result := range results {
+			if result.skipped {
+				skippedGenerations++
+				addResult(&LibraryGenerationReport{ID: libraryIDs[i], Action: reportActionSkipped})
+				continue
+			}
+			// The generated libraries were published by generateLibraries.
+			report.add(r.newLibraryGenerationReport(libraryIDs[i], reportActionRegenerated, result.duration, result.err))
+			if result.err != nil {
+				slog.Error("failed to generate library", "id", libraryIDs[i], "err", result.err)
+				failedLibraries = append(failedLibraries, libraryIDs[i])
 			} else {
 				// Only add the mapping if library generation is successful so that
 				// failed library will not appear in generation PR body.
-				idToCommits[library.ID] = status.oldCommit
+				idToCommits[libraryIDs[i]] = result.status.oldCommit
+				if result.status.files != nil {
+					manifest[libraryIDs[i]] = result.status.files
+				}
 				succeededGenerations++
 			}
 		}
+		if err != nil {
+			return errors.Join(err, r.writeReport(report), r.writeManifest(manifest))
+		}
 
 		slog.Info(
 			"generation statistics",
@@ -144,6 +346,12 @@ func (r *generateRunner) run(ctx context.Context) error {
 			"successes", succeededGenerations,
 			"skipped", skippedGenerations,
 			"failures", len(failedLibraries))
+		if err := r.writeReport(report); err != nil {
+			return err
+		}
+		if err := r.writeManifest(manifest); err != nil {
+			return err
+		}
 		if len(failedLibraries) > 0 && len(failedLibraries)+skippedGenerations == len(r.state.Libraries) {
 			return fmt.Errorf("all %d libraries failed to generate (skipped: %d)",
 				len(failedLibraries), skippedGenerations)
@@ -153,6 +361,11 @@ func (r *generateRunner) run(ctx context.Context) error {
 	if err := saveLibrarianState(r.repo.GetDir(), r.state); err != nil {
 		return err
 	}
+	if r.outputState != "" {
+		if err := writeLibrarianState(r.outputState, r.state); err != nil {
+			return fmt.Errorf("failed to write output state: %w", err)
+		}
+	}
 
 	var prBodyBuilder func() (string, error)
 	switch prType {
@@ -180,14 +393,24 @@ func (r *generateRunner) run(ctx context.Context) error {
 	default:
 		return fmt.Errorf("unexpected prType %s", prType)
 	}
+	prBodyBuilder = withPRTemplate(prBodyBuilder, r.repo.GetDir(), r.prTemplate)
 
+	commitMessage, err := renderCommitMessage(r.commitTemplate, defaultGenerateCommitMessage, r.state, slices.Sorted(maps.Keys(idToCommits)))
+	if err != nil {
+		return err
+	}
 	commitInfo := &commitInfo{
+		amend:             r.amend,
+		author:            r.author,
 		branch:            r.branch,
 		commit:            r.commit,
-		commitMessage:     "feat: generate libraries",
+		commitMessage:     commitMessage,
+		committer:         r.committer,
 		ghClient:          r.ghClient,
+		librarianConfig:   r.librarianConfig,
 		prType:            prType,
 		push:              r.push,
+		rebaseOntoBase:    r.rebaseOntoBase,
 		languageRepo:      r.repo,
 		sourceRepo:        r.sourceRepo,
 		state:             r.state,
@@ -196,29 +419,125 @@ func (r *generateRunner) run(ctx context.Context) error {
 		library:           r.library,
 		failedGenerations: len(failedLibraries),
 		prBodyBuilder:     prBodyBuilder,
+		libraryIDsFooter:  true,
 	}
 
 	if err := commitAndPush(ctx, commitInfo); err != nil {
 		return fmt.Errorf("failed to commit and push changes: %w", err)
 	}
+	pullRequestURL = commitInfo.pullRequestURL
 	return nil
 }
 
+// generationResult is the outcome of the generation of a single library.
+type generationResult struct {
+	status   *generationStatus
+	err      error
+	duration time.Duration
+	// skipped is true if the generation was not started because another
+	// library failed to generate and r.failFast is set.
+	skipped bool
+}
+
+// generateLibraries generates the libraries with the given IDs, running up to
+// r.concurrency generations at the same time. The results are returned in the
+// order of libraryIDs, whatever the order in which the generations finish.
+// The result of each generation is published as soon as it finishes.
+//
+// If r.failFast is set, no generation is started after the first failure,
+// whose error is returned. Generations already running are canceled.
+func (r *generateRunner) generateLibraries(ctx context.Context, libraryIDs []string, outputDir string) ([]generationResult, error) {
+	// Results are still published once the remaining generations are
+	// canceled.
+	publishCtx := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make([]generationResult, len(libraryIDs))
+	progress := newProgressLogger("generate", len(libraryIDs), r.progressInterval)
+	var (
+		mu       sync.Mutex
+		firstErr error
+	)
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr != nil
+	}
+	generate := func(i int) {
+		start := time.Now()
+		results[i].status, results[i].err = r.generateSingleLibrary(ctx, libraryIDs[i], outputDir)
+		results[i].duration = time.Since(start)
+		progress.libraryDone()
+		r.publishGenerationResult(publishCtx, r.newLibraryGenerationReport(libraryIDs[i], reportActionRegenerated, results[i].duration, results[i].err))
+		if results[i].err == nil || !r.failFast {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = fmt.Errorf("failed to generate library %q: %w", libraryIDs[i], results[i].err)
+			cancel()
+		}
+	}
+	if r.concurrency <= 1 {
+		for i := range libraryIDs {
+			if failed() {
+				results[i].skipped = true
+				continue
+			}
+			generate(i)
+		}
+		return results, firstErr
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(r.concurrency, len(libraryIDs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				generate(i)
+			}
+		}()
+	}
+	for i := range libraryIDs {
+		if failed() {
+			results[i].skipped = true
+			continue
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results, firstErr
+}
+
 // generateSingleLibrary manages the generation of a single client library.
 //
 // The single library generation executes as follows:
 //
-// 1. Configure the library, if the library is not configured in the state.yaml.
+// 1. Configure the library, if the library is not configured in the state.yaml
+// and configure is not skipped.
 //
 // 2. Generate the library.
 //
 // 3. Build the library.
 //
 // 4. Update the last generated commit or initial piper id if the library needs configure.
-func (r *generateRunner) generateSingleLibrary(ctx context.Context, libraryID, outputDir string) (*generationStatus, error) {
+func (r *generateRunner) generateSingleLibrary(ctx context.Context, libraryID, outputDir string) (_ *generationStatus, err error) {
 	safeLibraryDirectory := getSafeDirectoryName(libraryID)
 	prType := pullRequestGenerate
-	if r.needsConfigure() {
+	needsConfigure := r.needsConfigure()
+	if needsConfigure && r.skipConfigure {
+		libraryState := r.state.LibraryByID(libraryID)
+		if libraryState == nil || len(libraryState.SourceRoots) == 0 {
+			return nil, fmt.Errorf("library %q has no source roots, configure cannot be skipped", libraryID)
+		}
+		slog.Warn("library appears to need configuration, skipping configure as requested", "library", libraryID)
+		needsConfigure = false
+	}
+	if needsConfigure {
 		slog.Info("library not configured, start initial configuration", "library", r.library)
 		configureOutputDir := filepath.Join(outputDir, safeLibraryDirectory, "configure")
 		if err := os.MkdirAll(configureOutputDir, 0755); err != nil {
@@ -238,26 +557,110 @@ func (r *generateRunner) generateSingleLibrary(ctx context.Context, libraryID, o
 	if libraryState == nil {
 		return nil, fmt.Errorf("library %q not configured yet, generation stopped", libraryID)
 	}
-	lastGenCommit := libraryState.LastGeneratedCommit
+	// The history of the API sources is read from the base source only.
+	lastGenCommit := legacyconfig.SplitCompositeCommit(libraryState.LastGeneratedCommit)[0]
+
+	// APIs removed from the library are not generated. Their outputs are
+	// removed by the clean step of the generation of the library, or below if
+	// no API remains. The removed APIs are kept in the state if generation
+	// fails, as the library is then left or restored as it was.
+	apis := libraryState.APIs
+	activeAPIs, removedAPIs := splitRemovedAPIs(apis)
+	r.setAPIs(libraryState, activeAPIs)
+	defer func() {
+		if err != nil {
+			r.setAPIs(libraryState, apis)
+		}
+	}()
 
 	if len(libraryState.APIs) == 0 {
+		files, err := r.removeAPIOutputs(libraryState, removedAPIs)
+		if err != nil {
+			return nil, err
+		}
 		slog.Info("library has no APIs; skipping generation", "library", libraryID)
 		return &generationStatus{
 			oldCommit: "",
 			prType:    prType,
+			files:     files,
 		}, nil
 	}
 
-	if err := generateSingleLibrary(ctx, r.containerClient, r.state, libraryState, r.repo, r.sourceRepo, outputDir); err != nil {
+	// When libraries are generated concurrently, each generation gets its own
+	// librarian directory for the container requests and responses, and a
+	// snapshot of the state, since the state of other libraries may be
+	// updated meanwhile.
+	state, repo, librarianDir := r.state, r.repo, ""
+	if r.concurrency > 1 {
+		librarianDir = filepath.Join(r.workRoot, "librarian", safeLibraryDirectory)
+		if err := os.MkdirAll(librarianDir, 0755); err != nil {
+			return nil, err
+		}
+		state = r.stateSnapshot()
+		repo = &lockedRepository{Repository: r.repo, mu: &r.mu}
+	}
+
+	// A library with a pinned commit is generated from a worktree of the source
+	// repository at that commit, so that other libraries are not affected.
+	sourceRepo := r.sourceRepo
+	if libraryState.PinnedCommit != "" {
+		commit, err := r.sourceCommit(libraryState)
+		if err != nil {
+			return nil, err
+		}
+		worktreeDir := filepath.Join(r.workRoot, "pinned-source", safeLibraryDirectory)
+		if err := r.sourceRepo.AddWorktree(worktreeDir, commit); err != nil {
+			return nil, fmt.Errorf("failed to check out pinned commit %s of library %q: %w", commit, libraryID, err)
+		}
+		defer func() {
+			if err := r.sourceRepo.RemoveWorktree(worktreeDir); err != nil {
+				slog.Warn("failed to remove worktree", "dir", worktreeDir, "error", err)
+			}
+		}()
+		sourceRepo = &pinnedSourceRepository{Repository: r.sourceRepo, dir: worktreeDir}
+		// The overlays of layered API sources are layered onto the worktree.
+		if layered, ok := r.sourceRepo.(*layeredSourceRepository); ok {
+			layeredDir := filepath.Join(r.workRoot, "pinned-api-source", safeLibraryDirectory)
+			pinnedLayered, err := layered.layerOnto(layeredDir, &pinnedSourceRepository{Repository: layered.Repository, dir: worktreeDir})
+			if err != nil {
+				return nil, err
+			}
+			defer func() {
+				if err := os.RemoveAll(layeredDir); err != nil {
+					slog.Warn("failed to remove layered API sources", "dir", layeredDir, "error", err)
+				}
+			}()
+			sourceRepo = pinnedLayered
+		}
+	}
+
+	files, err := generateSingleLibrary(ctx, r.containerClient, state, libraryState, repo, sourceRepo, outputDir, librarianDir, r.generatorInput, r.lineEnding, r.destPrefix, r.outputManifest != "")
+	if err != nil {
 		return nil, err
 	}
 
-	if r.build {
-		if err := buildSingleLibrary(ctx, r.containerClient, r.state, libraryState, r.repo); err != nil {
+	destRoots := r.destRoots(libraryState)
+	if prType == pullRequestOnboard {
+		if err := writeBuildFiles(r.librarianConfig, r.repo.GetDir(), libraryState, destRoots); err != nil {
 			return nil, err
 		}
 	}
 
+	// As when the build fails, the library is restored if it fails to lint.
+	if lintErr := lintLibrary(ctx, r.librarianConfig, r.repo.GetDir(), libraryState, destRoots); lintErr != nil {
+		if restoreErr := restoreLibrary(destRoots, repo); restoreErr != nil {
+			return nil, errors.Join(lintErr, restoreErr)
+		}
+		return nil, lintErr
+	}
+
+	if r.build {
+		buildState, buildLibrary := r.destLibrary(state, libraryState)
+		if err := buildSingleLibrary(ctx, r.containerClient, buildState, buildLibrary, repo, librarianDir); err != nil {
+			return nil, &buildError{err: err}
+		}
+	}
+
 	if err := r.updateLastGeneratedCommitState(libraryID); err != nil {
 		return nil, err
 	}
@@ -265,9 +668,55 @@ func (r *generateRunner) generateSingleLibrary(ctx context.Context, libraryID, o
 	return &generationStatus{
 		oldCommit: lastGenCommit,
 		prType:    prType,
+		files:     files,
 	}, nil
 }
 
+// destRoots returns the source roots of library relative to the root of the
+// language repository, which are under the destPrefix directory, if set, where
+// the generated code is copied.
+func (r *generateRunner) destRoots(library *legacyconfig.LibraryState) []string {
+	if r.destPrefix == "" {
+		return library.SourceRoots
+	}
+	roots := make([]string, len(library.SourceRoots))
+	for i, root := range library.SourceRoots {
+		roots[i] = filepath.Join(r.destPrefix, root)
+	}
+	return roots
+}
+
+// destLibrary returns copies of library, and of state holding it, in which
+// the source roots of library are its destRoots, for the build container to
+// build and restore the copied code. state and library are returned unchanged
+// if there is no destPrefix.
+func (r *generateRunner) destLibrary(state *legacyconfig.LibrarianState, library *legacyconfig.LibraryState) (*legacyconfig.LibrarianState, *legacyconfig.LibraryState) {
+	if r.destPrefix == "" {
+		return state, library
+	}
+	destLibrary := *library
+	destLibrary.SourceRoots = r.destRoots(library)
+	destState := *state
+	destState.Libraries = slices.Clone(state.Libraries)
+	for i, l := range destState.Libraries {
+		if l.ID == library.ID {
+			destState.Libraries[i] = &destLibrary
+		}
+	}
+	return &destState, &destLibrary
+}
+
+// checkLanguage returns an error if the library with the given ID, which is
+// generated on its own, is not of the requested language. A library that is
+// not configured yet takes the requested language.
+func (r *generateRunner) checkLanguage(libraryID string) error {
+	library := r.state.LibraryByID(libraryID)
+	if library == nil || matchesLanguage(library, r.language) {
+		return nil
+	}
+	return fmt.Errorf("library %q has language %q, not %q", libraryID, library.Language, r.language)
+}
+
 func (r *generateRunner) needsConfigure() bool {
 	if r.api == "" || r.library == "" {
 		return false
@@ -284,14 +733,45 @@ func (r *generateRunner) needsConfigure() bool {
 	return true
 }
 
+// stateSnapshot returns a copy of the state in which the libraries can be
+// read while the state of the libraries is updated.
+func (r *generateRunner) stateSnapshot() *legacyconfig.LibrarianState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state := *r.state
+	state.Libraries = make([]*legacyconfig.LibraryState, len(r.state.Libraries))
+	for i, library := range r.state.Libraries {
+		snapshot := *library
+		state.Libraries[i] = &snapshot
+	}
+	return &state
+}
+
+// setAPIs sets the APIs of library, which may be read concurrently by
+// stateSnapshot.
+func (r *generateRunner) setAPIs(library *legacyconfig.LibraryState, apis []*legacyconfig.API) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	library.APIs = apis
+}
+
 func (r *generateRunner) updateLastGeneratedCommitState(libraryID string) error {
-	hash, err := r.sourceRepo.HeadHash()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	hash, err := sourceProvenance(r.sourceRepo)
 	if err != nil {
 		return err
 	}
 	for _, l := range r.state.Libraries {
 		if l.ID == libraryID {
 			l.LastGeneratedCommit = hash
+			if l.PinnedCommit != "" {
+				// The pinned commit replaces the HEAD commit of the base
+				// source, while any overlays were layered at their HEAD.
+				commits := legacyconfig.SplitCompositeCommit(hash)
+				commits[0] = l.PinnedCommit
+				l.LastGeneratedCommit = strings.Join(commits, legacyconfig.CompositeCommitSeparator)
+			}
 			break
 		}
 	}
@@ -305,7 +785,9 @@ func (r *generateRunner) updateLastGeneratedCommitState(libraryID string) error
 // 1. Constructs a request for the language-specific container, including the API
 // root, library ID, and repository directory.
 //
-// 2. Populates a service configuration if one is missing.
+// 2. Populates a service configuration if one is missing. If none is found
+// for the API, it is configured as a proto-only package, unless
+// -allow-missing-service-config is false, in which case configuring fails.
 //
 // 3. Delegates the configuration task to the container's `Configure` command.
 //
@@ -317,9 +799,17 @@ func (r *generateRunner) updateLastGeneratedCommitState(libraryID string) error
 // 6. Writes the complete, updated librarian state back to the `state.yaml` file
 // in the repository.
 //
+// The ID of a new library is first checked against the library ID pattern of
+// the repository, see [legacyconfig.LibrarianConfig.CheckLibraryID].
+//
 // If successful, it returns the ID of the newly configured library; otherwise,
 // it returns an empty string and an error.
 func (r *generateRunner) runConfigureCommand(ctx context.Context, outputDir string) (string, error) {
+	if r.state.LibraryByID(r.library) == nil {
+		if err := r.librarianConfig.CheckLibraryID(r.library); err != nil {
+			return "", err
+		}
+	}
 
 	apiRoot, err := filepath.Abs(r.sourceRepo.GetDir())
 	if err != nil {
@@ -328,24 +818,33 @@ func (r *generateRunner) runConfigureCommand(ctx context.Context, outputDir stri
 
 	setAllAPIStatus(r.state, legacyconfig.StatusExisting)
 	addAPIToLibrary(r.state, r.library, r.api)
+	if err := r.checkServiceConfig(apiRoot); err != nil {
+		return "", err
+	}
 
 	if err := populateServiceConfigIfEmpty(
 		r.state,
+		r.librarianConfig,
 		apiRoot); err != nil {
 		return "", err
 	}
 
-	var globalFiles []string
+	var globalFiles, configureMounts []string
 	if r.librarianConfig != nil {
 		globalFiles = r.librarianConfig.GetGlobalFiles()
+		if libraryConfig := r.librarianConfig.LibraryConfigFor(r.library); libraryConfig != nil {
+			configureMounts = libraryConfig.ConfigureMounts
+		}
 	}
 
 	configureRequest := &legacydocker.ConfigureRequest{
 		ApiRoot:             apiRoot,
+		GeneratorInputDir:   r.generatorInput,
 		LibraryID:           r.library,
 		Output:              outputDir,
 		RepoDir:             r.repo.GetDir(),
 		GlobalFiles:         globalFiles,
+		ConfigureMounts:     configureMounts,
 		ExistingSourceRoots: r.getExistingSrc(r.library),
 		State:               r.state,
 	}
@@ -369,6 +868,9 @@ func (r *generateRunner) runConfigureCommand(ctx context.Context, outputDir stri
 		slog.Info("library doesn't receive a version, apply the default version", "id", r.library)
 		libraryState.Version = "0.0.0"
 	}
+	if libraryState.Language == "" {
+		libraryState.Language = r.language
+	}
 
 	// Update the library state in the librarian state.
 	for i, library := range r.state.Libraries {
@@ -378,7 +880,7 @@ func (r *generateRunner) runConfigureCommand(ctx context.Context, outputDir stri
 		r.state.Libraries[i] = libraryState
 	}
 
-	if err := copyLibraryFiles(r.state, r.repo.GetDir(), libraryState.ID, outputDir, false); err != nil {
+	if err := copyLibraryFiles(r.state, r.repo.GetDir(), libraryState.ID, outputDir, false, nil); err != nil {
 		return "", err
 	}
 
@@ -407,14 +909,63 @@ func (r *generateRunner) getExistingSrc(libraryID string) []string {
 	return existingSrc
 }
 
+// setAllAPIStatus sets the status of all APIs, except the removed ones.
 func setAllAPIStatus(state *legacyconfig.LibrarianState, status string) {
 	for _, library := range state.Libraries {
 		for _, api := range library.APIs {
+			if api.Status == legacyconfig.StatusRemoved {
+				continue
+			}
 			api.Status = status
 		}
 	}
 }
 
+// splitRemovedAPIs splits apis into the active APIs and the APIs with the
+// removed status.
+func splitRemovedAPIs(apis []*legacyconfig.API) (active, removed []*legacyconfig.API) {
+	for _, api := range apis {
+		if api.Status == legacyconfig.StatusRemoved {
+			removed = append(removed, api)
+			continue
+		}
+		active = append(active, api)
+	}
+	return active, removed
+}
+
+// removeAPIOutputs removes the outputs of library in the language repository,
+// once all its APIs were removed, given by apis. The outputs are the files of
+// the source roots matching the remove_regex of the library, as when the
+// library is cleaned before its generated files are copied. Files matching
+// the preserve_regex of the library are kept.
+//
+// When a library still has active APIs, the outputs of its removed APIs are
+// removed by the clean step of its generation instead.
+//
+// If a manifest is written, it returns the removed files of the library.
+func (r *generateRunner) removeAPIOutputs(library *legacyconfig.LibraryState, apis []*legacyconfig.API) (*libraryFileChanges, error) {
+	if len(apis) == 0 {
+		return nil, nil
+	}
+	for _, api := range apis {
+		slog.Info("removing outputs of removed API", "library", library.ID, "api", api.Path)
+	}
+	// As in cleanAndCopyLibrary, the patterns are relative to destDir.
+	destDir := filepath.Join(r.repo.GetDir(), r.destPrefix)
+	preservePatterns := slices.Concat(library.PreserveRegex, globalPreservePatterns)
+	ignore, err := loadLibrarianIgnore(destDir, library.SourceRoots)
+	if err != nil {
+		return nil, err
+	}
+	return trackFileChanges(r.outputManifest != "", r.repo.GetDir(), r.destRoots(library), func() error {
+		if err := clean(destDir, library.SourceRoots, libraryRemovePatterns(library), preservePatterns, ignore); err != nil {
+			return fmt.Errorf("failed to remove outputs of removed APIs of library %s: %w", library.ID, err)
+		}
+		return nil
+	})
+}
+
 // shouldGenerate determines whether a library should be generated by the generate
 // command. It does *not* observe the -library or -api flag, as those are handled
 // higher up in run. If this function returns false (with a nil error), it always
@@ -424,6 +975,13 @@ func setAllAPIStatus(state *legacyconfig.LibrarianState, status string) {
 // and should be kept centrally in this function, with a comment for each path in the flow
 // for clarity.
 func (r *generateRunner) shouldGenerate(library *legacyconfig.LibraryState) (bool, error) {
+	// A frozen library is skipped unless -force is specified, whatever the
+	// other flags.
+	if r.librarianConfig.IsFrozen(library.ID) && !r.force {
+		slog.Warn("library is frozen, skipping; use -force to generate it", "id", library.ID)
+		return false, nil
+	}
+
 	// If the library has a manual configuration which indicates generation is blocked,
 	// the library is skipped.
 	if r.librarianConfig.IsGenerationBlocked(library.ID) {
@@ -437,9 +995,19 @@ func (r *generateRunner) shouldGenerate(library *legacyconfig.LibraryState) (boo
 		return false, nil
 	}
 
+	// With -only-changed, the uncommitted changes of the library decide
+	// instead of the changes of its APIs since it was last generated.
+	if r.onlyChanged {
+		if !r.locallyChanged[library.ID] {
+			slog.Info("library has no local changes, skipping", "id", library.ID)
+		}
+		return r.locallyChanged[library.ID], nil
+	}
+
 	// If we've been asked to generate libraries even with unchanged APIs,
 	// we don't need to check whether any have changed: we should definitely generate.
-	if r.generateUnchanged {
+	// The same applies if this particular library was named for forced generation.
+	if r.generateUnchanged || slices.Contains(r.generateUnchangedFor, library.ID) {
 		return true, nil
 	}
 
@@ -450,30 +1018,269 @@ func (r *generateRunner) shouldGenerate(library *legacyconfig.LibraryState) (boo
 	}
 
 	// Most common case: a non-generation-blocked library with APIs, and without the
-	// -generate-unchanged flag. Check each API to see whether anything under API.Path
-	// has changed between the last_generated_commit and the HEAD commit of r.sourceRepo.
-	// If any API has changed, the library is generated - otherwise it's skipped.
-	headHash, err := r.sourceRepo.HeadHash()
+	// -generate-unchanged flag. If any API has changed, the library is generated -
+	// otherwise it's skipped.
+	changed, err := r.apisChanged(library)
+	if err != nil {
+		return false, err
+	}
+	if !changed {
+		slog.Info("no APIs have changed; skipping", "library", library.ID)
+	}
+	return changed, nil
+}
+
+// apisChanged reports whether anything under the path of any API of the library
+// has changed between its last_generated_commit and the commit of r.sourceRepo
+// it is generated from. The library must have a last_generated_commit.
+func (r *generateRunner) apisChanged(library *legacyconfig.LibraryState) (bool, error) {
+	headHash, err := r.sourceCommit(library)
+	if err != nil {
+		return false, err
+	}
+	lastGenCommits := legacyconfig.SplitCompositeCommit(library.LastGeneratedCommit)
+	changed, err := pathsChanged(r.sourceRepo, library.APIs, lastGenCommits[0], headHash)
+	if err != nil || changed {
+		return changed, err
+	}
+	layered, ok := r.sourceRepo.(*layeredSourceRepository)
+	if !ok {
+		// The library was last generated from several API sources.
+		return len(lastGenCommits) > 1, nil
+	}
+	return layered.overlaysChanged(library.APIs, lastGenCommits[1:])
+}
+
+// locallyChangedLibraries returns the IDs of the libraries with uncommitted
+// changes, either in one of their source roots in the language repository or
+// in one of their APIs in the API source. When onlyChanged is set, run records
+// them in locallyChanged.
+func (r *generateRunner) locallyChangedLibraries() (map[string]bool, error) {
+	repoFiles, err := r.repo.ChangedFiles()
 	if err != nil {
-		return false, fmt.Errorf("failed to get head hash for source repo: %v", err)
+		return nil, fmt.Errorf("failed to get changed files of the language repository: %w", err)
+	}
+	var sourceFiles []string
+	if r.sourceRepo != nil {
+		if sourceFiles, err = r.sourceRepo.ChangedFiles(); err != nil {
+			return nil, fmt.Errorf("failed to get changed files of the API source: %w", err)
+		}
+	}
+	changed := map[string]bool{}
+	for _, library := range r.state.Libraries {
+		var apiPaths []string
+		for _, api := range library.APIs {
+			apiPaths = append(apiPaths, api.Path)
+		}
+		for _, file := range repoFiles {
+			if isUnderAnyPath(file, library.SourceRoots) {
+				changed[library.ID] = true
+			}
+		}
+		for _, file := range sourceFiles {
+			if isUnderAnyPath(file, apiPaths) {
+				changed[library.ID] = true
+			}
+		}
 	}
-	for _, api := range library.APIs {
-		oldHash, err := r.sourceRepo.GetHashForPath(library.LastGeneratedCommit, api.Path)
+	return changed, nil
+}
+
+// pathsChanged reports whether anything under the path of any of the APIs
+// differs in repo between the two commits.
+func pathsChanged(repo legacygitrepo.Repository, apis []*legacyconfig.API, oldCommit, newCommit string) (bool, error) {
+	for _, api := range apis {
+		oldHash, err := repo.GetHashForPath(oldCommit, api.Path)
 		if err != nil {
-			return false, fmt.Errorf("failed to get hash for path %v at commit %v: %v", api.Path, library.LastGeneratedCommit, err)
+			return false, fmt.Errorf("failed to get hash for path %v at commit %v: %v", api.Path, oldCommit, err)
 		}
-		newHash, err := r.sourceRepo.GetHashForPath(headHash, api.Path)
+		newHash, err := repo.GetHashForPath(newCommit, api.Path)
 		if err != nil {
-			return false, fmt.Errorf("failed to get hash for path %v at commit %v: %v", api.Path, headHash, err)
+			return false, fmt.Errorf("failed to get hash for path %v at commit %v: %v", api.Path, newCommit, err)
 		}
 		if oldHash != newHash {
 			return true, nil
 		}
 	}
-	slog.Info("no APIs have changed; skipping", "library", library.ID)
 	return false, nil
 }
 
+// sourceCommit returns the commit of r.sourceRepo the library is generated
+// from, which is its pinned commit if set, or the HEAD commit otherwise.
+func (r *generateRunner) sourceCommit(library *legacyconfig.LibraryState) (string, error) {
+	if library.PinnedCommit == "" {
+		headHash, err := r.sourceRepo.HeadHash()
+		if err != nil {
+			return "", fmt.Errorf("failed to get head hash for source repo: %v", err)
+		}
+		return headHash, nil
+	}
+	if _, err := r.sourceRepo.GetCommit(library.PinnedCommit); err != nil {
+		return "", fmt.Errorf("pinned commit %s of library %q not found in source repo: %w", library.PinnedCommit, library.ID, err)
+	}
+	return library.PinnedCommit, nil
+}
+
+// pinnedSourceRepository is the source repository of a library with a pinned
+// commit, whose directory is a worktree of the source repository at that
+// commit.
+type pinnedSourceRepository struct {
+	legacygitrepo.Repository
+	dir string
+}
+
+// GetDir returns the directory of the worktree.
+func (r *pinnedSourceRepository) GetDir() string {
+	return r.dir
+}
+
+// plannedGeneration is the entry of the generation plan for a library.
+type plannedGeneration struct {
+	// id is the ID of the library.
+	id string
+	// library is the state of the library, or nil if it is not configured yet.
+	library *legacyconfig.LibraryState
+	// generate reports whether the library is generated.
+	generate bool
+	// configure reports whether the library is configured before it is
+	// generated.
+	configure bool
+	// err is the error found while planning the generation of the library, if
+	// any. The library is neither configured nor generated.
+	err error
+}
+
+// planGeneration returns the libraries the generate command processes, in the
+// order they are processed, stating for each library whether it would be
+// configured and generated or skipped. It is used both to run the generate
+// command and to write the plan of -dry-run, so that both agree. No containers
+// are run, and neither the state of the runner nor any files are modified.
+func (r *generateRunner) planGeneration() ([]*plannedGeneration, error) {
+	if r.api != "" || r.library != "" {
+		libraryID := r.library
+		if libraryID == "" {
+			libraryID = findLibraryIDByAPIPath(r.state, r.api)
+		}
+		if err := r.checkLanguage(libraryID); err != nil {
+			return nil, err
+		}
+		// A frozen library is skipped even when explicitly named, unless
+		// -force is specified.
+		if r.librarianConfig.IsFrozen(libraryID) && !r.force {
+			slog.Warn("library is frozen, skipping; use -force to generate it", "id", libraryID)
+			return []*plannedGeneration{{id: libraryID, library: r.state.LibraryByID(libraryID)}}, nil
+		}
+		planned := &plannedGeneration{
+			id:        libraryID,
+			library:   r.state.LibraryByID(libraryID),
+			generate:  !r.onlyChanged || r.locallyChanged[libraryID],
+			configure: r.needsConfigure(),
+		}
+		if !planned.generate {
+			slog.Info("library has no local changes, skipping", "id", libraryID)
+		}
+		if planned.configure && r.skipConfigure {
+			if planned.library == nil || len(planned.library.SourceRoots) == 0 {
+				planned.err = fmt.Errorf("library %q has no source roots, configure cannot be skipped", libraryID)
+			}
+			planned.configure = false
+		}
+		if planned.library == nil && !planned.configure && planned.err == nil {
+			planned.err = fmt.Errorf("library %q not configured yet, generation stopped", libraryID)
+		}
+		return []*plannedGeneration{planned}, nil
+	}
+	for _, id := range r.generateUnchangedFor {
+		if r.state.LibraryByID(id) == nil {
+			return nil, fmt.Errorf("library %q specified by generate-unchanged-for is not configured", id)
+		}
+	}
+	var plan []*plannedGeneration
+	for _, library := range r.state.Libraries {
+		planned := &plannedGeneration{id: library.ID, library: library}
+		plan = append(plan, planned)
+		if !matchesLanguage(library, r.language) {
+			slog.Info("library is not of the requested language, skipping", "id", library.ID, "language", library.Language)
+			continue
+		}
+		planned.generate, planned.err = r.shouldGenerate(library)
+	}
+	return plan, nil
+}
+
+// writeGenerationPlan writes a table of the libraries of plan, stating for
+// each library whether it would be configured and generated or skipped,
+// whether its APIs have changed since the last generated commit, that commit,
+// and the source roots that would be cleaned. Unknown values are listed as
+// "-".
+func (r *generateRunner) writeGenerationPlan(w io.Writer, plan []*plannedGeneration) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LIBRARY\tACTION\tCONFIGURE\tAPIS CHANGED\tLAST GENERATED COMMIT\tSOURCE ROOTS")
+	for _, planned := range plan {
+		if planned.err != nil {
+			return fmt.Errorf("failed to plan generation of library %s: %w", planned.id, planned.err)
+		}
+		action := "skip"
+		// A library without APIs is only configured, if at all.
+		if planned.generate && (planned.configure || len(planned.library.APIs) > 0) {
+			action = "generate"
+		}
+		if err := r.writeGenerationPlanEntry(tw, planned.id, planned.library, action, planned.configure); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
+
+// writeGenerationPlanEntry writes the row of the generation plan for the
+// library with the given ID. library is nil if the library is not configured
+// yet.
+func (r *generateRunner) writeGenerationPlanEntry(w io.Writer, libraryID string, library *legacyconfig.LibraryState, action string, needsConfigure bool) error {
+	configure := "no"
+	if needsConfigure {
+		configure = "yes"
+	}
+	apisChanged, lastGenCommit, sourceRoots := "-", "-", "-"
+	if library != nil {
+		if library.LastGeneratedCommit != "" {
+			lastGenCommit = library.LastGeneratedCommit
+		}
+		if library.LastGeneratedCommit != "" && len(library.APIs) > 0 {
+			changed, err := r.apisChanged(library)
+			if err != nil {
+				return err
+			}
+			apisChanged = fmt.Sprintf("%t", changed)
+		}
+		if len(library.SourceRoots) > 0 && action == "generate" {
+			sourceRoots = strings.Join(library.SourceRoots, ",")
+		}
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", libraryID, action, configure, apisChanged, lastGenCommit, sourceRoots)
+	return nil
+}
+
+// checkServiceConfig returns an error if requireServiceConfig is set and no
+// service config is set or found in apiRoot for the API to configure.
+func (r *generateRunner) checkServiceConfig(apiRoot string) error {
+	if !r.requireServiceConfig {
+		return nil
+	}
+	for _, api := range r.state.LibraryByID(r.library).APIs {
+		if api.Path != r.api || api.ServiceConfig != "" {
+			continue
+		}
+		serviceConfig, err := findServiceConfig(r.librarianConfig, r.library, apiRoot, api.Path)
+		if err != nil {
+			return err
+		}
+		if serviceConfig == "" {
+			return fmt.Errorf("no service config found for API %q", r.api)
+		}
+	}
+	return nil
+}
+
 // addAPIToLibrary adds a new API to a library in the state.
 // If the library does not exist, it creates a new one.
 // If the API already exists in the library, do nothing.
@@ -498,3 +1305,86 @@ func addAPIToLibrary(state *legacyconfig.LibrarianState, libraryID, apiPath stri
 	// For new API paths, set the status to "new".
 	lib.APIs = append(lib.APIs, &legacyconfig.API{Path: apiPath, Status: legacyconfig.StatusNew})
 }
+
+// lockedRepository is a [legacygitrepo.Repository] whose changes to the work
+// tree are serialized, so that libraries generated concurrently do not run
+// git commands on the same repository at the same time.
+type lockedRepository struct {
+	legacygitrepo.Repository
+	mu *sync.Mutex
+}
+
+// Restore restores the given paths, holding the lock.
+func (r *lockedRepository) Restore(paths []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Repository.Restore(paths)
+}
+
+// CleanUntracked removes untracked files within the given paths, holding the
+// lock.
+func (r *lockedRepository) CleanUntracked(paths []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Repository.CleanUntracked(paths)
+}
+
+// limitedContainerClient is a [ContainerClient] which runs at most cap(sem)
+// containers at the same time.
+type limitedContainerClient struct {
+	ContainerClient
+	sem chan struct{}
+}
+
+// limitContainers returns a [ContainerClient] running at most limit containers
+// of client at the same time, or client itself if limit is zero.
+func limitContainers(client ContainerClient, limit int) ContainerClient {
+	if limit <= 0 {
+		return client
+	}
+	return &limitedContainerClient{ContainerClient: client, sem: make(chan struct{}, limit)}
+}
+
+// acquire waits until a container can be run, or until ctx is done.
+func (c *limitedContainerClient) acquire(ctx context.Context) error {
+	select {
+	case c.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *limitedContainerClient) release() {
+	<-c.sem
+}
+
+// Build runs the build container once fewer than the maximum number of
+// containers are running.
+func (c *limitedContainerClient) Build(ctx context.Context, request *legacydocker.BuildRequest) error {
+	if err := c.acquire(ctx); err != nil {
+		return err
+	}
+	defer c.release()
+	return c.ContainerClient.Build(ctx, request)
+}
+
+// Configure runs the configure container once fewer than the maximum number
+// of containers are running.
+func (c *limitedContainerClient) Configure(ctx context.Context, request *legacydocker.ConfigureRequest) (string, error) {
+	if err := c.acquire(ctx); err != nil {
+		return "", err
+	}
+	defer c.release()
+	return c.ContainerClient.Configure(ctx, request)
+}
+
+// Generate runs the generate container once fewer than the maximum number of
+// containers are running.
+func (c *limitedContainerClient) Generate(ctx context.Context, request *legacydocker.GenerateRequest) error {
+	if err := c.acquire(ctx); err != nil {
+		return err
+	}
+	defer c.release()
+	return c.ContainerClient.Generate(ctx, request)
+}
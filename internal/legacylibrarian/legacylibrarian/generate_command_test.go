@@ -15,14 +15,21 @@
 package legacylibrarian
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacydocker"
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacygitrepo"
 )
 
@@ -59,6 +66,20 @@ func TestNewGenerateRunner(t *testing.T) {
 			wantErr:    true,
 			wantErrMsg: "repository does not exist",
 		},
+		{
+			name: "invalid commit message template",
+			cfg: &legacyconfig.Config{
+				API:                   "some/api",
+				APISource:             newTestGitRepo(t).GetDir(),
+				Branch:                "test-branch",
+				CommitMessageTemplate: "feat: generate {{.LibraryID",
+				Repo:                  newTestGitRepo(t).GetDir(),
+				WorkRoot:              t.TempDir(),
+				CommandName:           generateCmdName,
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid commit message template",
+		},
 		{
 			name: "no state file",
 			cfg: &legacyconfig.Config{
@@ -187,15 +208,23 @@ func TestNewGenerateRunner(t *testing.T) {
 func TestRunConfigureCommand(t *testing.T) {
 	t.Parallel()
 	for _, test := range []struct {
-		name               string
-		api                string
-		repo               legacygitrepo.Repository
-		state              *legacyconfig.LibrarianState
-		librarianConfig    *legacyconfig.LibrarianConfig
-		container          *mockContainerClient
-		wantConfigureCalls int
-		wantErr            bool
-		wantErrMsg         string
+		name            string
+		api             string
+		library         string
+		repo            legacygitrepo.Repository
+		state           *legacyconfig.LibrarianState
+		librarianConfig *legacyconfig.LibrarianConfig
+		container       *mockContainerClient
+		// noServiceConfig removes the service config of the API.
+		noServiceConfig bool
+		// otherServiceConfig adds a second service config to the API.
+		otherServiceConfig   bool
+		requireServiceConfig bool
+		wantConfigureCalls   int
+		wantConfigureMounts  []string
+		wantServiceConfig    string
+		wantErr              bool
+		wantErrMsg           string
 	}{
 		{
 			name: "configures library successfully",
@@ -229,6 +258,106 @@ func TestRunConfigureCommand(t *testing.T) {
 			wantErr:            true,
 			wantErrMsg:         "failed to read dir",
 		},
+		{
+			name:    "missing service config",
+			api:     "some/api",
+			library: "some-library",
+			repo:    newTestGitRepo(t),
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID: "some-library",
+					},
+				},
+			},
+			noServiceConfig:    true,
+			container:          &mockContainerClient{},
+			wantConfigureCalls: 1,
+		},
+		{
+			name:    "missing service config required",
+			api:     "some/api",
+			library: "some-library",
+			repo:    newTestGitRepo(t),
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID: "some-library",
+					},
+				},
+			},
+			noServiceConfig:      true,
+			requireServiceConfig: true,
+			container:            &mockContainerClient{},
+			wantErr:              true,
+			wantErrMsg:           `no service config found for API "some/api"`,
+		},
+		{
+			name:    "several service configs",
+			api:     "some/api",
+			library: "some-library",
+			repo:    newTestGitRepo(t),
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID: "some-library",
+					},
+				},
+			},
+			otherServiceConfig: true,
+			container:          &mockContainerClient{},
+			wantErr:            true,
+			wantErrMsg:         "found 2 service configs",
+		},
+		{
+			name:    "service config of library config",
+			api:     "some/api",
+			library: "some-library",
+			repo:    newTestGitRepo(t),
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID: "some-library",
+					},
+				},
+			},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				Libraries: []*legacyconfig.LibraryConfig{
+					{
+						LibraryID:      "some-library",
+						ServiceConfigs: map[string]string{"some/api": "other_*.yaml"},
+					},
+				},
+			},
+			otherServiceConfig: true,
+			container:          &mockContainerClient{},
+			wantConfigureCalls: 1,
+			wantServiceConfig:  "other_service_v2.yaml",
+		},
+		{
+			name:    "service config of library config not found",
+			api:     "some/api",
+			library: "some-library",
+			repo:    newTestGitRepo(t),
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID: "some-library",
+					},
+				},
+			},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				Libraries: []*legacyconfig.LibraryConfig{
+					{
+						LibraryID:      "some-library",
+						ServiceConfigs: map[string]string{"some/api": "missing.yaml"},
+					},
+				},
+			},
+			container:  &mockContainerClient{},
+			wantErr:    true,
+			wantErrMsg: `no service config matching "missing.yaml"`,
+		},
 		{
 			name: "configures library with error message in response",
 			api:  "some/api",
@@ -306,6 +435,34 @@ func TestRunConfigureCommand(t *testing.T) {
 			container:          &mockContainerClient{},
 			wantConfigureCalls: 1,
 		},
+		{
+			name:    "configure mounts of library config",
+			api:     "some/api",
+			library: "some-library",
+			repo:    newTestGitRepo(t),
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID: "some-library",
+					},
+				},
+			},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				Libraries: []*legacyconfig.LibraryConfig{
+					{
+						LibraryID:       "some-library",
+						ConfigureMounts: []string{"parent/module"},
+					},
+					{
+						LibraryID:       "other-library",
+						ConfigureMounts: []string{"other/module"},
+					},
+				},
+			},
+			container:           &mockContainerClient{},
+			wantConfigureCalls:  1,
+			wantConfigureMounts: []string{"parent/module"},
+		},
 		{
 			name: "configure command failed",
 			api:  "some/api",
@@ -326,17 +483,75 @@ func TestRunConfigureCommand(t *testing.T) {
 			wantErr:            true,
 			wantErrMsg:         "simulated configure command error",
 		},
+		{
+			name:    "new library id matches pattern",
+			api:     "some/api",
+			library: "google.cloud.secretmanager.v1",
+			repo:    newTestGitRepo(t),
+			state:   &legacyconfig.LibrarianState{},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				LibraryIDPattern: `google\.cloud\.[a-z]+\.v[0-9]+`,
+			},
+			container:          &mockContainerClient{},
+			wantConfigureCalls: 1,
+		},
+		{
+			name:    "new library id does not match pattern",
+			api:     "some/api",
+			library: "google.cloud.secretmanager",
+			repo:    newTestGitRepo(t),
+			state:   &legacyconfig.LibrarianState{},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				LibraryIDPattern: `google\.cloud\.[a-z]+\.v[0-9]+`,
+			},
+			container:  &mockContainerClient{},
+			wantErr:    true,
+			wantErrMsg: `library ID "google.cloud.secretmanager" does not match the library_id_pattern`,
+		},
+		{
+			name:    "pattern must match the whole library id",
+			api:     "some/api",
+			library: "x.google.cloud.secretmanager.v1beta",
+			repo:    newTestGitRepo(t),
+			state:   &legacyconfig.LibrarianState{},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				LibraryIDPattern: `google\.cloud\.[a-z]+\.v[0-9]+`,
+			},
+			container:  &mockContainerClient{},
+			wantErr:    true,
+			wantErrMsg: "does not match the library_id_pattern",
+		},
+		{
+			name:    "existing library id is not checked",
+			api:     "some/api",
+			library: "some-library",
+			repo:    newTestGitRepo(t),
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID: "some-library",
+					},
+				},
+			},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				LibraryIDPattern: `google\.cloud\.[a-z]+\.v[0-9]+`,
+			},
+			container:          &mockContainerClient{},
+			wantConfigureCalls: 1,
+		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
 			outputDir := t.TempDir()
 			r := &generateRunner{
-				api:             test.api,
-				repo:            test.repo,
-				sourceRepo:      newTestGitRepo(t),
-				state:           test.state,
-				librarianConfig: test.librarianConfig,
-				containerClient: test.container,
+				api:                  test.api,
+				library:              test.library,
+				repo:                 test.repo,
+				sourceRepo:           newTestGitRepo(t),
+				state:                test.state,
+				librarianConfig:      test.librarianConfig,
+				containerClient:      test.container,
+				requireServiceConfig: test.requireServiceConfig,
 			}
 
 			// Create a service config
@@ -344,9 +559,17 @@ func TestRunConfigureCommand(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			data := []byte("type: google.api.Service")
-			if err := os.WriteFile(filepath.Join(r.sourceRepo.GetDir(), test.api, "example_service_v2.yaml"), data, 0755); err != nil {
-				t.Fatal(err)
+			if !test.noServiceConfig {
+				data := []byte("type: google.api.Service")
+				if err := os.WriteFile(filepath.Join(r.sourceRepo.GetDir(), test.api, "example_service_v2.yaml"), data, 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if test.otherServiceConfig {
+				data := []byte("type: google.api.Service")
+				if err := os.WriteFile(filepath.Join(r.sourceRepo.GetDir(), test.api, "other_service_v2.yaml"), data, 0755); err != nil {
+					t.Fatal(err)
+				}
 			}
 
 			if test.name == "configures library with non-existent api source" {
@@ -378,6 +601,15 @@ func TestRunConfigureCommand(t *testing.T) {
 			if diff := cmp.Diff(test.wantConfigureCalls, test.container.configureCalls); diff != "" {
 				t.Errorf("runConfigureCommand() configureCalls mismatch (-want +got):%s", diff)
 			}
+			if diff := cmp.Diff(test.wantConfigureMounts, test.container.configureRequest.ConfigureMounts); diff != "" {
+				t.Errorf("runConfigureCommand() ConfigureMounts mismatch (-want +got):%s", diff)
+			}
+			if test.wantServiceConfig != "" {
+				got := test.state.LibraryByID(test.library).APIs[0].ServiceConfig
+				if got != test.wantServiceConfig {
+					t.Errorf("runConfigureCommand() service config = %q, want %q", got, test.wantServiceConfig)
+				}
+			}
 		})
 	}
 }
@@ -393,6 +625,10 @@ func TestGenerateScenarios(t *testing.T) {
 		container                *mockContainerClient
 		ghClient                 GitHubClient
 		build                    bool
+		concurrency              int
+		generateUnchangedFor     []string
+		language                 string
+		onlyChanged              bool
 		forceShouldGenerateError bool
 		wantErr                  bool
 		wantErrMsg               string
@@ -588,6 +824,99 @@ func TestGenerateScenarios(t *testing.T) {
 			wantGenerateCalls: 2,
 			wantBuildCalls:    2,
 		},
+		{
+			name:     "generate only libraries of the requested language",
+			language: "rust",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:          "library1",
+						Language:    "rust",
+						APIs:        []*legacyconfig.API{{Path: "some/api1"}},
+						SourceRoots: []string{"src/a"},
+					},
+					{
+						ID:          "library2",
+						Language:    "dart",
+						APIs:        []*legacyconfig.API{{Path: "some/api2"}},
+						SourceRoots: []string{"src/b"},
+					},
+					{
+						ID:          "library3",
+						APIs:        []*legacyconfig.API{{Path: "some/api3"}},
+						SourceRoots: []string{"src/c"},
+					},
+				},
+			},
+			container: &mockContainerClient{
+				wantLibraryGen: true,
+			},
+			ghClient:          &mockGitHubClient{},
+			build:             true,
+			wantGenerateCalls: 1,
+			wantBuildCalls:    1,
+		},
+		{
+			name:     "generate single library of another language should fail",
+			library:  "library1",
+			language: "rust",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:          "library1",
+						Language:    "dart",
+						APIs:        []*legacyconfig.API{{Path: "some/api1"}},
+						SourceRoots: []string{"src/a"},
+					},
+				},
+			},
+			container:  &mockContainerClient{},
+			ghClient:   &mockGitHubClient{},
+			wantErr:    true,
+			wantErrMsg: `library "library1" has language "dart", not "rust"`,
+		},
+		{
+			name:    "generate single library without local changes with only changed",
+			library: "library1",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:          "library1",
+						APIs:        []*legacyconfig.API{{Path: "some/api1"}},
+						SourceRoots: []string{"src/a"},
+					},
+				},
+			},
+			onlyChanged:       true,
+			container:         &mockContainerClient{},
+			ghClient:          &mockGitHubClient{},
+			wantGenerateCalls: 0,
+		},
+		{
+			name:    "generate single frozen library is skipped",
+			library: "library1",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:          "library1",
+						APIs:        []*legacyconfig.API{{Path: "some/api1"}},
+						SourceRoots: []string{"src/a"},
+					},
+				},
+			},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				Libraries: []*legacyconfig.LibraryConfig{
+					{LibraryID: "library1", Frozen: true},
+				},
+			},
+			container:         &mockContainerClient{},
+			ghClient:          &mockGitHubClient{},
+			wantGenerateCalls: 0,
+		},
 		{
 			name: "generate single library, corrupted api",
 			api:  "corrupted/api/path",
@@ -698,24 +1027,26 @@ func TestGenerateScenarios(t *testing.T) {
 			wantBuildCalls:    1,
 		},
 		{
-			name: "generate skips blocked libraries",
+			name: "generate all, lint failure does not halt execution",
 			state: &legacyconfig.LibrarianState{
 				Image: "gcr.io/test/image:v1.2.3",
 				Libraries: []*legacyconfig.LibraryState{
 					{
-						ID:   "google.cloud.texttospeech.v1",
-						APIs: []*legacyconfig.API{{Path: "google/cloud/texttospeech/v1"}},
+						ID:          "lib1",
+						APIs:        []*legacyconfig.API{{Path: "some/api1"}},
+						SourceRoots: []string{"src/a"},
 					},
 					{
-						ID:   "google.cloud.vision.v1",
-						APIs: []*legacyconfig.API{{Path: "google/cloud/vision/v1"}},
+						ID:          "lib2",
+						APIs:        []*legacyconfig.API{{Path: "some/api2"}},
+						SourceRoots: []string{"src/b"},
 					},
 				},
 			},
 			librarianConfig: &legacyconfig.LibrarianConfig{
 				Libraries: []*legacyconfig.LibraryConfig{
-					{LibraryID: "google.cloud.texttospeech.v1"},
-					{LibraryID: "google.cloud.vision.v1", GenerateBlocked: true},
+					{LibraryID: "lib1", LintCommand: "exit 1"},
+					{LibraryID: "lib2", LintCommand: "true"},
 				},
 			},
 			container: &mockContainerClient{
@@ -723,29 +1054,30 @@ func TestGenerateScenarios(t *testing.T) {
 			},
 			ghClient:          &mockGitHubClient{},
 			build:             true,
-			wantGenerateCalls: 1,
+			wantGenerateCalls: 2,
 			wantBuildCalls:    1,
 		},
 		{
-			name:    "generate runs blocked libraries if explicitly requested",
-			library: "google.cloud.vision.v1",
+			name:        "generate all libraries concurrently",
+			concurrency: 2,
 			state: &legacyconfig.LibrarianState{
 				Image: "gcr.io/test/image:v1.2.3",
 				Libraries: []*legacyconfig.LibraryState{
 					{
-						ID:   "google.cloud.texttospeech.v1",
-						APIs: []*legacyconfig.API{{Path: "google/cloud/texttospeech/v1"}},
+						ID:          "lib1",
+						APIs:        []*legacyconfig.API{{Path: "some/api1"}},
+						SourceRoots: []string{"src/a"},
 					},
 					{
-						ID:   "google.cloud.vision.v1",
-						APIs: []*legacyconfig.API{{Path: "google/cloud/vision/v1"}},
+						ID:          "lib2",
+						APIs:        []*legacyconfig.API{{Path: "some/api2"}},
+						SourceRoots: []string{"src/b"},
+					},
+					{
+						ID:          "lib3",
+						APIs:        []*legacyconfig.API{{Path: "some/api3"}},
+						SourceRoots: []string{"src/c"},
 					},
-				},
-			},
-			librarianConfig: &legacyconfig.LibrarianConfig{
-				Libraries: []*legacyconfig.LibraryConfig{
-					{LibraryID: "google.cloud.texttospecech.v1"},
-					{LibraryID: "google.cloud.vision.v1", GenerateBlocked: true},
 				},
 			},
 			container: &mockContainerClient{
@@ -753,26 +1085,118 @@ func TestGenerateScenarios(t *testing.T) {
 			},
 			ghClient:          &mockGitHubClient{},
 			build:             true,
-			wantGenerateCalls: 1,
-			wantBuildCalls:    1,
+			wantGenerateCalls: 3,
+			wantBuildCalls:    3,
 		},
 		{
-			name: "generate skips a blocked library and the rest fail. should report error",
+			name:        "generate all concurrently, partial failure does not halt execution",
+			concurrency: 2,
 			state: &legacyconfig.LibrarianState{
 				Image: "gcr.io/test/image:v1.2.3",
 				Libraries: []*legacyconfig.LibraryState{
 					{
-						ID:   "google.cloud.texttospeech.v1",
-						APIs: []*legacyconfig.API{{Path: "google/cloud/texttospeech/v1"}},
+						ID:          "lib1",
+						APIs:        []*legacyconfig.API{{Path: "some/api1"}},
+						SourceRoots: []string{"src/a"},
 					},
 					{
-						ID:   "google.cloud.vision.v1",
-						APIs: []*legacyconfig.API{{Path: "google/cloud/vision/v1"}},
+						ID:          "lib2",
+						APIs:        []*legacyconfig.API{{Path: "some/api2"}},
+						SourceRoots: []string{"src/b"},
+					},
+					{
+						ID:          "lib3",
+						APIs:        []*legacyconfig.API{{Path: "some/api3"}},
+						SourceRoots: []string{"src/c"},
 					},
 				},
 			},
-			librarianConfig: &legacyconfig.LibrarianConfig{
-				Libraries: []*legacyconfig.LibraryConfig{
+			container: &mockContainerClient{
+				wantLibraryGen:    true,
+				failGenerateForID: "lib2",
+				generateErrForID:  errors.New("generate error"),
+			},
+			ghClient:          &mockGitHubClient{},
+			build:             true,
+			wantGenerateCalls: 3,
+			wantBuildCalls:    2,
+		},
+		{
+			name: "generate skips blocked libraries",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:   "google.cloud.texttospeech.v1",
+						APIs: []*legacyconfig.API{{Path: "google/cloud/texttospeech/v1"}},
+					},
+					{
+						ID:   "google.cloud.vision.v1",
+						APIs: []*legacyconfig.API{{Path: "google/cloud/vision/v1"}},
+					},
+				},
+			},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				Libraries: []*legacyconfig.LibraryConfig{
+					{LibraryID: "google.cloud.texttospeech.v1"},
+					{LibraryID: "google.cloud.vision.v1", GenerateBlocked: true},
+				},
+			},
+			container: &mockContainerClient{
+				wantLibraryGen: true,
+			},
+			ghClient:          &mockGitHubClient{},
+			build:             true,
+			wantGenerateCalls: 1,
+			wantBuildCalls:    1,
+		},
+		{
+			name:    "generate runs blocked libraries if explicitly requested",
+			library: "google.cloud.vision.v1",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:   "google.cloud.texttospeech.v1",
+						APIs: []*legacyconfig.API{{Path: "google/cloud/texttospeech/v1"}},
+					},
+					{
+						ID:   "google.cloud.vision.v1",
+						APIs: []*legacyconfig.API{{Path: "google/cloud/vision/v1"}},
+					},
+				},
+			},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				Libraries: []*legacyconfig.LibraryConfig{
+					{LibraryID: "google.cloud.texttospecech.v1"},
+					{LibraryID: "google.cloud.vision.v1", GenerateBlocked: true},
+				},
+			},
+			container: &mockContainerClient{
+				wantLibraryGen: true,
+			},
+			ghClient:          &mockGitHubClient{},
+			build:             true,
+			wantGenerateCalls: 1,
+			wantBuildCalls:    1,
+		},
+		{
+			name: "generate skips a blocked library and the rest fail. should report error",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:   "google.cloud.texttospeech.v1",
+						APIs: []*legacyconfig.API{{Path: "google/cloud/texttospeech/v1"}},
+					},
+					{
+						ID:   "google.cloud.vision.v1",
+						APIs: []*legacyconfig.API{{Path: "google/cloud/vision/v1"}},
+					},
+				},
+			},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				Libraries: []*legacyconfig.LibraryConfig{
 					{LibraryID: "google.cloud.texttospeech.v1"},
 					{LibraryID: "google.cloud.vision.v1", GenerateBlocked: true},
 				},
@@ -873,6 +1297,22 @@ func TestGenerateScenarios(t *testing.T) {
 			wantBuildCalls:     2,
 			wantConfigureCalls: 0,
 		},
+		{
+			name: "generate-unchanged-for names an unknown library",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:   "some-library",
+						APIs: []*legacyconfig.API{{Path: "some/api"}},
+					},
+				},
+			},
+			container:            &mockContainerClient{},
+			generateUnchangedFor: []string{"unknown-library"},
+			wantErr:              true,
+			wantErrMsg:           `library "unknown-library" specified by generate-unchanged-for is not configured`,
+		},
 		// We only have one library to generate, and we force shouldGenerate
 		// to fail by making the source repo's HeadHash function fail.
 		// As this ends up being all the libraries, the overall result is an error.
@@ -900,16 +1340,20 @@ func TestGenerateScenarios(t *testing.T) {
 			repo := newTestGitRepoWithState(t, test.state)
 
 			r := &generateRunner{
-				api:             test.api,
-				library:         test.library,
-				build:           test.build,
-				repo:            repo,
-				sourceRepo:      newTestGitRepo(t),
-				state:           test.state,
-				librarianConfig: test.librarianConfig,
-				containerClient: test.container,
-				ghClient:        test.ghClient,
-				workRoot:        t.TempDir(),
+				api:                  test.api,
+				library:              test.library,
+				build:                test.build,
+				concurrency:          test.concurrency,
+				generateUnchangedFor: test.generateUnchangedFor,
+				language:             test.language,
+				onlyChanged:          test.onlyChanged,
+				repo:                 repo,
+				sourceRepo:           newTestGitRepo(t),
+				state:                test.state,
+				librarianConfig:      test.librarianConfig,
+				containerClient:      test.container,
+				ghClient:             test.ghClient,
+				workRoot:             t.TempDir(),
 			}
 
 			// Create a service config in api path.
@@ -979,16 +1423,19 @@ func TestGenerateScenarios(t *testing.T) {
 func TestGenerateSingleLibraryCommand(t *testing.T) {
 	t.Parallel()
 	for _, test := range []struct {
-		name       string
-		api        string
-		library    string
-		state      *legacyconfig.LibrarianState
-		container  *mockContainerClient
-		ghClient   GitHubClient
-		build      bool
-		wantErr    bool
-		wantErrMsg string
-		wantPRType pullRequestType
+		name               string
+		api                string
+		library            string
+		state              *legacyconfig.LibrarianState
+		container          *mockContainerClient
+		ghClient           GitHubClient
+		build              bool
+		skipConfigure      bool
+		wantErr            bool
+		wantErrMsg         string
+		wantPRType         pullRequestType
+		wantConfigureCalls int
+		wantGenerateCalls  int
 	}{
 		{
 			name:    "onboard library returns pullRequestOnboard",
@@ -1003,9 +1450,11 @@ func TestGenerateSingleLibraryCommand(t *testing.T) {
 					"src/a",
 				},
 			},
-			ghClient:   &mockGitHubClient{},
-			build:      true,
-			wantPRType: pullRequestOnboard,
+			ghClient:           &mockGitHubClient{},
+			build:              true,
+			wantPRType:         pullRequestOnboard,
+			wantConfigureCalls: 1,
+			wantGenerateCalls:  1,
 		},
 		{
 			name:    "generate existing library returns pullRequestGenerate",
@@ -1025,9 +1474,47 @@ func TestGenerateSingleLibraryCommand(t *testing.T) {
 			container: &mockContainerClient{
 				wantLibraryGen: true,
 			},
-			ghClient:   &mockGitHubClient{},
-			build:      true,
-			wantPRType: pullRequestGenerate,
+			ghClient:          &mockGitHubClient{},
+			build:             true,
+			wantPRType:        pullRequestGenerate,
+			wantGenerateCalls: 1,
+		},
+		{
+			name:    "skip configure generates library with existing configuration",
+			api:     "some/new-api",
+			library: "some-library",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:   "some-library",
+						APIs: []*legacyconfig.API{{Path: "some/api"}},
+						SourceRoots: []string{
+							"src/a",
+						},
+					},
+				},
+			},
+			container: &mockContainerClient{
+				wantLibraryGen: true,
+			},
+			ghClient:          &mockGitHubClient{},
+			skipConfigure:     true,
+			wantPRType:        pullRequestGenerate,
+			wantGenerateCalls: 1,
+		},
+		{
+			name:    "skip configure for library without source roots",
+			api:     "some/api",
+			library: "some-library",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+			},
+			container:     &mockContainerClient{},
+			ghClient:      &mockGitHubClient{},
+			skipConfigure: true,
+			wantErr:       true,
+			wantErrMsg:    "configure cannot be skipped",
 		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
@@ -1037,6 +1524,7 @@ func TestGenerateSingleLibraryCommand(t *testing.T) {
 				api:             test.api,
 				library:         test.library,
 				build:           test.build,
+				skipConfigure:   test.skipConfigure,
 				repo:            repo,
 				sourceRepo:      sourceRepo,
 				state:           test.state,
@@ -1081,124 +1569,911 @@ func TestGenerateSingleLibraryCommand(t *testing.T) {
 			if status.prType != test.wantPRType {
 				t.Errorf("generateSingleLibrary() prType = %v, want %v", status.prType, test.wantPRType)
 			}
-		})
-	}
-}
-
-func TestGetExistingSrc(t *testing.T) {
-	t.Parallel()
-	for _, test := range []struct {
-		name      string
-		libraryID string
-		paths     []string
-		want      []string
-	}{
-		{
-			name:      "all_source_paths_existed",
-			libraryID: "some-library",
-			paths: []string{
-				"a/path",
-				"another/path",
-			},
-			want: []string{
-				"a/path",
-				"another/path",
-			},
-		},
-		{
-			name:      "one_source_paths_existed",
-			libraryID: "some-library",
-			paths: []string{
-				"a/path",
-			},
-			want: []string{
-				"a/path",
-			},
-		},
-		{
-			name:      "no_source_paths_existed",
-			libraryID: "some-library",
-			want:      nil,
-		},
-		{
-			name:      "no_library_existed",
-			libraryID: "another-library",
-			want:      nil,
-		},
-	} {
-		t.Run(test.name, func(t *testing.T) {
-			t.Parallel()
-			repo := newTestGitRepo(t)
-			state := &legacyconfig.LibrarianState{
-				Libraries: []*legacyconfig.LibraryState{
-					{
-						ID: "some-library",
-						SourceRoots: []string{
-							"a/path",
-							"another/path",
-						},
-					},
-				},
-			}
-			for _, path := range test.paths {
-				relPath := filepath.Join(repo.GetDir(), path)
-				if err := os.MkdirAll(relPath, 0755); err != nil {
-					t.Fatal(err)
-				}
-			}
-
-			r := &generateRunner{
-				repo:  repo,
-				state: state,
+			if test.container.configureCalls != test.wantConfigureCalls {
+				t.Errorf("generateSingleLibrary() configureCalls = %d, want %d", test.container.configureCalls, test.wantConfigureCalls)
 			}
-
-			got := r.getExistingSrc(test.libraryID)
-			if diff := cmp.Diff(test.want, got); diff != "" {
-				t.Errorf("getExistingSrc() mismatch (-want +got):%s", diff)
+			if test.container.generateCalls != test.wantGenerateCalls {
+				t.Errorf("generateSingleLibrary() generateCalls = %d, want %d", test.container.generateCalls, test.wantGenerateCalls)
 			}
 		})
 	}
 }
 
-func TestUpdateLastGeneratedCommitState(t *testing.T) {
+func TestGenerateRunGeneratorInput(t *testing.T) {
 	t.Parallel()
+	const api = "some/api"
 	sourceRepo := newTestGitRepo(t)
-	hash, err := sourceRepo.HeadHash()
-	if err != nil {
+	if err := os.MkdirAll(filepath.Join(sourceRepo.GetDir(), api), 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("type: google.api.Service")
+	if err := os.WriteFile(filepath.Join(sourceRepo.GetDir(), api, "example_service_v2.yaml"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := sourceRepo.AddAll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sourceRepo.Commit("feat: add an api\n\nPiperOrigin-RevId: 123456"); err != nil {
 		t.Fatal(err)
 	}
+	state := &legacyconfig.LibrarianState{
+		Image: "gcr.io/test/image:v1.2.3",
+	}
+	container := &mockContainerClient{
+		wantLibraryGen:        true,
+		configureLibraryPaths: []string{"src/a"},
+	}
+	generatorInput := t.TempDir()
 	r := &generateRunner{
-		sourceRepo: sourceRepo,
-		state: &legacyconfig.LibrarianState{
-			Libraries: []*legacyconfig.LibraryState{
-				{
-					ID: "some-library",
-				},
-			},
-		},
+		api:             api,
+		library:         "some-library",
+		containerClient: container,
+		generatorInput:  generatorInput,
+		ghClient:        &mockGitHubClient{},
+		repo:            newTestGitRepoWithState(t, state),
+		sourceRepo:      sourceRepo,
+		state:           state,
+		workRoot:        t.TempDir(),
 	}
-	if err := r.updateLastGeneratedCommitState("some-library"); err != nil {
+	if err := r.run(t.Context()); err != nil {
 		t.Fatal(err)
 	}
-	if r.state.Libraries[0].LastGeneratedCommit != hash {
-		t.Errorf("updateState() got = %v, want %v", r.state.Libraries[0].LastGeneratedCommit, hash)
+	if container.configureRequest == nil || container.generateRequest == nil {
+		t.Fatalf("got %d configure and %d generate calls, want 1 of each", container.configureCalls, container.generateCalls)
+	}
+	if diff := cmp.Diff(generatorInput, container.configureRequest.GeneratorInputDir); diff != "" {
+		t.Errorf("configure generator input mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(generatorInput, container.generateRequest.GeneratorInputDir); diff != "" {
+		t.Errorf("generate generator input mismatch (-want +got):\n%s", diff)
 	}
 }
 
-func TestShouldGenerate(t *testing.T) {
+func TestGenerateRunCommitMessageTemplate(t *testing.T) {
 	t.Parallel()
-	for _, test := range []struct {
-		name              string
-		config            *legacyconfig.LibrarianConfig
-		state             *legacyconfig.LibrarianState
-		generateUnchanged bool
-		sourceRepo        legacygitrepo.Repository
-		libraryIDToTest   string
-		want              bool
-		wantErr           bool
-	}{
-		// Tests that don't get as far as checking for hashes.
-		// (The mock repo will fail if we do get that far.)
+	const api = "some/api"
+	sourceRepo := newTestGitRepo(t)
+	if err := os.MkdirAll(filepath.Join(sourceRepo.GetDir(), api), 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("type: google.api.Service")
+	if err := os.WriteFile(filepath.Join(sourceRepo.GetDir(), api, "example_service_v2.yaml"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := sourceRepo.AddAll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sourceRepo.Commit("feat: add an api\n\nPiperOrigin-RevId: 123456"); err != nil {
+		t.Fatal(err)
+	}
+	state := &legacyconfig.LibrarianState{
+		Image: "gcr.io/test/image:v1.2.3",
+	}
+	commitTemplate, err := parseCommitMessageTemplate("feat(PROJ-1): generate {{.LibraryID}} ({{join .APIPaths \",\"}})", defaultGenerateCommitMessage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := newTestGitRepoWithState(t, state)
+	r := &generateRunner{
+		api:            api,
+		library:        "some-library",
+		commit:         true,
+		commitTemplate: commitTemplate,
+		containerClient: &mockContainerClient{
+			wantLibraryGen:        true,
+			configureLibraryPaths: []string{"src/a"},
+		},
+		ghClient:   &mockGitHubClient{},
+		repo:       repo,
+		sourceRepo: sourceRepo,
+		state:      state,
+		workRoot:   t.TempDir(),
+	}
+	if err := r.run(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	head, err := repo.HeadHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := repo.GetCommit(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "feat(PROJ-1): generate some-library (some/api)"
+	if got, _, _ := strings.Cut(commit.Message, "\n"); got != want {
+		t.Errorf("commit subject = %q, want %q", got, want)
+	}
+}
+
+func TestGenerateRunRemovedAPI(t *testing.T) {
+	t.Parallel()
+	const api = "some/api"
+	sourceRepo := newTestGitRepo(t)
+	if err := os.MkdirAll(filepath.Join(sourceRepo.GetDir(), api), 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("type: google.api.Service")
+	if err := os.WriteFile(filepath.Join(sourceRepo.GetDir(), api, "example_service_v2.yaml"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := sourceRepo.AddAll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sourceRepo.Commit("feat: add an api\n\nPiperOrigin-RevId: 123456"); err != nil {
+		t.Fatal(err)
+	}
+	state := &legacyconfig.LibrarianState{
+		Image: "gcr.io/test/image:v1.2.3",
+		Libraries: []*legacyconfig.LibraryState{
+			{
+				ID: "some-library",
+				APIs: []*legacyconfig.API{
+					{Path: api, ServiceConfig: "example_service_v2.yaml"},
+					{Path: "old/api", ServiceConfig: "old_v1.yaml", Status: legacyconfig.StatusRemoved},
+				},
+				SourceRoots: []string{"src/a"},
+				RemoveRegex: []string{"^src/a/(some|old)/api"},
+			},
+		},
+	}
+	repo := newTestGitRepoWithState(t, state)
+	for _, file := range []string{"src/a/old/api/client.go", "src/a/other/client.go"} {
+		path := filepath.Join(repo.GetDir(), file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("package api"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := &generateRunner{
+		library: "some-library",
+		containerClient: &mockContainerClient{
+			wantLibraryGen: true,
+		},
+		ghClient:   &mockGitHubClient{},
+		repo:       repo,
+		sourceRepo: sourceRepo,
+		state:      state,
+		workRoot:   t.TempDir(),
+	}
+	if err := r.run(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(repo.GetDir(), "src/a/old/api")); !os.IsNotExist(err) {
+		t.Errorf("outputs of removed API should be removed, got err %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo.GetDir(), "src/a/other/client.go")); err != nil {
+		t.Errorf("outputs of other APIs should be kept, got err %v", err)
+	}
+	gotState, err := parseLibrarianState(filepath.Join(repo.GetDir(), legacyconfig.LibrarianDir, legacyconfig.LibrarianStateFile), nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, a := range gotState.Libraries[0].APIs {
+		got = append(got, a.Path)
+	}
+	want := []string{api}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("APIs mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerateRunRestoresLibraryOnLintFailure(t *testing.T) {
+	t.Parallel()
+	state := &legacyconfig.LibrarianState{
+		Image: "gcr.io/test/image:v1.2.3",
+		Libraries: []*legacyconfig.LibraryState{
+			{
+				ID:          "lib1",
+				APIs:        []*legacyconfig.API{{Path: "some/api1"}},
+				SourceRoots: []string{"src/a"},
+			},
+		},
+	}
+	repo := newTestGitRepoWithState(t, state)
+	r := &generateRunner{
+		library: "lib1",
+		containerClient: &mockContainerClient{
+			wantLibraryGen: true,
+		},
+		ghClient: &mockGitHubClient{},
+		librarianConfig: &legacyconfig.LibrarianConfig{
+			Libraries: []*legacyconfig.LibraryConfig{
+				{LibraryID: "lib1", LintCommand: "exit 1"},
+			},
+		},
+		repo:       repo,
+		sourceRepo: newTestGitRepo(t),
+		state:      state,
+		workRoot:   t.TempDir(),
+	}
+	err := r.run(t.Context())
+	if err == nil || !strings.Contains(err.Error(), "lint command failed") {
+		t.Fatalf("run() error = %v, want lint failure", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo.GetDir(), "src/a/random_file.txt")); err != nil {
+		t.Errorf("committed file should be restored, got err %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo.GetDir(), "src/a/example.txt")); !os.IsNotExist(err) {
+		t.Errorf("generated file should be removed, got err %v", err)
+	}
+}
+
+func TestGenerateRunKeepsRemovedAPIsOnFailure(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name            string
+		build           bool
+		buildErr        error
+		librarianConfig *legacyconfig.LibrarianConfig
+		wantErrMsg      string
+	}{
+		{
+			name: "lint failure",
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				Libraries: []*legacyconfig.LibraryConfig{
+					{LibraryID: "lib1", LintCommand: "exit 1"},
+				},
+			},
+			wantErrMsg: "lint command failed",
+		},
+		{
+			name:       "build failure",
+			build:      true,
+			buildErr:   errors.New("build failed"),
+			wantErrMsg: "build failed",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			apis := []*legacyconfig.API{
+				{Path: "some/api1"},
+				{Path: "old/api", Status: legacyconfig.StatusRemoved},
+			}
+			state := &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:          "lib1",
+						APIs:        apis,
+						SourceRoots: []string{"src/a"},
+					},
+				},
+			}
+			r := &generateRunner{
+				build:   test.build,
+				library: "lib1",
+				containerClient: &mockContainerClient{
+					wantLibraryGen: true,
+					buildErr:       test.buildErr,
+				},
+				ghClient:        &mockGitHubClient{},
+				librarianConfig: test.librarianConfig,
+				repo:            newTestGitRepoWithState(t, state),
+				sourceRepo:      newTestGitRepo(t),
+				state:           state,
+				workRoot:        t.TempDir(),
+			}
+			err := r.run(t.Context())
+			if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+				t.Fatalf("run() error = %v, want %q", err, test.wantErrMsg)
+			}
+			if diff := cmp.Diff(apis, state.Libraries[0].APIs); diff != "" {
+				t.Errorf("APIs mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGenerateRunRestoresPrefixedLibraryOnBuildFailure(t *testing.T) {
+	t.Parallel()
+	state := &legacyconfig.LibrarianState{
+		Image: "gcr.io/test/image:v1.2.3",
+		Libraries: []*legacyconfig.LibraryState{
+			{
+				ID:          "lib1",
+				APIs:        []*legacyconfig.API{{Path: "some/api1"}},
+				SourceRoots: []string{"src/a"},
+			},
+		},
+	}
+	repo := newTestGitRepoWithState(t, state)
+	existing := filepath.Join(repo.GetDir(), "vendor/src/a/existing.txt")
+	if err := os.MkdirAll(filepath.Dir(existing), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(existing, []byte("existing content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.AddAll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Commit("chore: add existing code"); err != nil {
+		t.Fatal(err)
+	}
+	r := &generateRunner{
+		build:   true,
+		library: "lib1",
+		containerClient: &mockContainerClient{
+			wantLibraryGen: true,
+			buildErr:       errors.New("build failed"),
+		},
+		destPrefix: "vendor",
+		ghClient:   &mockGitHubClient{},
+		repo:       repo,
+		sourceRepo: newTestGitRepo(t),
+		state:      state,
+		workRoot:   t.TempDir(),
+	}
+	err := r.run(t.Context())
+	if err == nil || !strings.Contains(err.Error(), "build failed") {
+		t.Fatalf("run() error = %v, want build failure", err)
+	}
+	if _, err := os.Stat(existing); err != nil {
+		t.Errorf("committed file should be restored, got err %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo.GetDir(), "vendor/src/a/example.txt")); !os.IsNotExist(err) {
+		t.Errorf("generated file should be removed, got err %v", err)
+	}
+	if diff := cmp.Diff([]string{"src/a"}, state.Libraries[0].SourceRoots); diff != "" {
+		t.Errorf("source roots mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerateRunAllAPIsRemoved(t *testing.T) {
+	t.Parallel()
+	state := &legacyconfig.LibrarianState{
+		Image: "gcr.io/test/image:v1.2.3",
+		Libraries: []*legacyconfig.LibraryState{
+			{
+				ID: "some-library",
+				APIs: []*legacyconfig.API{
+					{Path: "old/api", ServiceConfig: "old_v1.yaml", Status: legacyconfig.StatusRemoved},
+				},
+				SourceRoots: []string{"src/a"},
+				RemoveRegex: []string{"^src/a/gen"},
+			},
+		},
+	}
+	repo := newTestGitRepoWithState(t, state)
+	for _, file := range []string{"src/a/gen/old_client.go", "src/a/README.md"} {
+		path := filepath.Join(repo.GetDir(), file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	containerClient := &mockContainerClient{}
+	r := &generateRunner{
+		library:         "some-library",
+		containerClient: containerClient,
+		ghClient:        &mockGitHubClient{},
+		repo:            repo,
+		sourceRepo:      newTestGitRepo(t),
+		state:           state,
+		workRoot:        t.TempDir(),
+	}
+	if err := r.run(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if containerClient.generateCalls != 0 {
+		t.Errorf("generate called %d times, want 0", containerClient.generateCalls)
+	}
+	if _, err := os.Stat(filepath.Join(repo.GetDir(), "src/a/gen/old_client.go")); !os.IsNotExist(err) {
+		t.Errorf("outputs of removed API should be removed, got err %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo.GetDir(), "src/a/README.md")); err != nil {
+		t.Errorf("files not matching remove_regex should be kept, got err %v", err)
+	}
+	if got := state.Libraries[0].APIs; len(got) != 0 {
+		t.Errorf("APIs = %v, want none", got)
+	}
+}
+
+func TestGenerateRunPinnedCommit(t *testing.T) {
+	t.Parallel()
+	const api = "some/api"
+	for _, test := range []struct {
+		name       string
+		missingPin bool
+		wantErrMsg string
+	}{
+		{
+			name: "generated from pinned commit",
+		},
+		{
+			name:       "pinned commit not in source repo",
+			missingPin: true,
+			wantErrMsg: "not found in source repo",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			sourceRepo := newTestGitRepo(t)
+			if err := os.MkdirAll(filepath.Join(sourceRepo.GetDir(), api), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(sourceRepo.GetDir(), api, "example_service_v2.yaml"), []byte("type: google.api.Service"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := sourceRepo.AddAll(); err != nil {
+				t.Fatal(err)
+			}
+			if err := sourceRepo.Commit("feat: add an api"); err != nil {
+				t.Fatal(err)
+			}
+			pinned, err := sourceRepo.HeadHash()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.missingPin {
+				pinned = strings.Repeat("a", 40)
+			}
+			// Move HEAD past the pinned commit.
+			if err := os.WriteFile(filepath.Join(sourceRepo.GetDir(), api, "new.proto"), []byte("syntax = \"proto3\";"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := sourceRepo.AddAll(); err != nil {
+				t.Fatal(err)
+			}
+			if err := sourceRepo.Commit("feat: add a proto"); err != nil {
+				t.Fatal(err)
+			}
+
+			state := &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:           "some-library",
+						APIs:         []*legacyconfig.API{{Path: api, ServiceConfig: "example_service_v2.yaml"}},
+						SourceRoots:  []string{"src/a"},
+						PinnedCommit: pinned,
+					},
+				},
+			}
+			containerClient := &mockContainerClient{wantLibraryGen: true}
+			workRoot := t.TempDir()
+			r := &generateRunner{
+				library:         "some-library",
+				containerClient: containerClient,
+				ghClient:        &mockGitHubClient{},
+				repo:            newTestGitRepoWithState(t, state),
+				sourceRepo:      sourceRepo,
+				state:           state,
+				workRoot:        workRoot,
+			}
+			err = r.run(t.Context())
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("run() error = %v, want error containing %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			worktreeDir := filepath.Join(workRoot, "pinned-source", "some-library")
+			if got := containerClient.generateRequest.ApiRoot; got != worktreeDir {
+				t.Errorf("ApiRoot = %q, want %q", got, worktreeDir)
+			}
+			if _, err := os.Stat(worktreeDir); !os.IsNotExist(err) {
+				t.Errorf("worktree should be removed after generation, got err %v", err)
+			}
+			if got := state.Libraries[0].LastGeneratedCommit; got != pinned {
+				t.Errorf("LastGeneratedCommit = %q, want %q", got, pinned)
+			}
+		})
+	}
+}
+
+func TestGenerateRunPinnedCommitLayered(t *testing.T) {
+	t.Parallel()
+	const api = "some/api"
+	base := newTestAPISource(t, map[string]string{
+		api + "/example_service_v2.yaml": "type: google.api.Service",
+	})
+	pinned, err := base.HeadHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Move HEAD of the base source past the pinned commit.
+	commitTestFiles(t, base, map[string]string{api + "/new.proto": "syntax = \"proto3\";"})
+	overlay := newTestAPISource(t, map[string]string{api + "/private.proto": "syntax = \"proto3\";"})
+	overlayHead, err := overlay.HeadHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	workRoot := t.TempDir()
+	layered, err := layerAPISources(filepath.Join(workRoot, "api-source"), []legacygitrepo.Repository{base, overlay})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := &legacyconfig.LibrarianState{
+		Image: "gcr.io/test/image:v1.2.3",
+		Libraries: []*legacyconfig.LibraryState{
+			{
+				ID:           "some-library",
+				APIs:         []*legacyconfig.API{{Path: api, ServiceConfig: "example_service_v2.yaml"}},
+				SourceRoots:  []string{"src/a"},
+				PinnedCommit: pinned,
+			},
+		},
+	}
+	containerClient := &mockContainerClient{wantLibraryGen: true}
+	r := &generateRunner{
+		library:         "some-library",
+		containerClient: containerClient,
+		ghClient:        &mockGitHubClient{},
+		repo:            newTestGitRepoWithState(t, state),
+		sourceRepo:      layered,
+		state:           state,
+		workRoot:        workRoot,
+	}
+	if err := r.run(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	layeredDir := filepath.Join(workRoot, "pinned-api-source", "some-library")
+	if got := containerClient.generateRequest.ApiRoot; got != layeredDir {
+		t.Errorf("ApiRoot = %q, want %q", got, layeredDir)
+	}
+	if _, err := os.Stat(layeredDir); !os.IsNotExist(err) {
+		t.Errorf("layered API sources should be removed after generation, got err %v", err)
+	}
+	want := pinned + legacyconfig.CompositeCommitSeparator + overlayHead
+	if got := state.Libraries[0].LastGeneratedCommit; got != want {
+		t.Errorf("LastGeneratedCommit = %q, want %q", got, want)
+	}
+	changed, err := r.apisChanged(state.Libraries[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed {
+		t.Error("apisChanged() = true after generation, want false")
+	}
+}
+
+func TestSplitRemovedAPIs(t *testing.T) {
+	t.Parallel()
+	active := &legacyconfig.API{Path: "google/cloud/foo/v2", Status: legacyconfig.StatusExisting}
+	removed := &legacyconfig.API{Path: "google/cloud/foo/v1", Status: legacyconfig.StatusRemoved}
+	for _, test := range []struct {
+		name        string
+		apis        []*legacyconfig.API
+		wantActive  []*legacyconfig.API
+		wantRemoved []*legacyconfig.API
+	}{
+		{
+			name: "no APIs",
+		},
+		{
+			name:       "no removed APIs",
+			apis:       []*legacyconfig.API{active},
+			wantActive: []*legacyconfig.API{active},
+		},
+		{
+			name:        "removed and active APIs",
+			apis:        []*legacyconfig.API{removed, active},
+			wantActive:  []*legacyconfig.API{active},
+			wantRemoved: []*legacyconfig.API{removed},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			gotActive, gotRemoved := splitRemovedAPIs(test.apis)
+			if diff := cmp.Diff(test.wantActive, gotActive); diff != "" {
+				t.Errorf("active APIs mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.wantRemoved, gotRemoved); diff != "" {
+				t.Errorf("removed APIs mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRemoveAPIOutputs(t *testing.T) {
+	t.Parallel()
+	files := []string{
+		"src/foo/google/cloud/foo/v1/client.go",
+		"src/foo/google/cloud/foo/v1/handwritten.go",
+		"src/foo/README.md",
+		"test/foo/google/cloud/foo/v1/client_test.go",
+		"other/google/cloud/foo/v1/client.go",
+	}
+	removed := []*legacyconfig.API{{Path: "google/cloud/foo/v1", Status: legacyconfig.StatusRemoved}}
+	for _, test := range []struct {
+		name          string
+		apis          []*legacyconfig.API
+		removeRegex   []string
+		preserveRegex []string
+		wantRemoved   []string
+	}{
+		{
+			name: "no removed APIs",
+		},
+		{
+			name: "source roots by default",
+			apis: removed,
+			wantRemoved: []string{
+				"src/foo/google/cloud/foo/v1/client.go",
+				"src/foo/google/cloud/foo/v1/handwritten.go",
+				"src/foo/README.md",
+				"test/foo/google/cloud/foo/v1/client_test.go",
+			},
+		},
+		{
+			name:        "remove regex",
+			apis:        removed,
+			removeRegex: []string{"^src/foo/google"},
+			wantRemoved: []string{
+				"src/foo/google/cloud/foo/v1/client.go",
+				"src/foo/google/cloud/foo/v1/handwritten.go",
+			},
+		},
+		{
+			name:          "preserved file",
+			apis:          removed,
+			removeRegex:   []string{"^src/foo/google"},
+			preserveRegex: []string{"handwritten.go"},
+			wantRemoved: []string{
+				"src/foo/google/cloud/foo/v1/client.go",
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			repoDir := t.TempDir()
+			for _, file := range files {
+				path := filepath.Join(repoDir, file)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			library := &legacyconfig.LibraryState{
+				ID:            "foo",
+				SourceRoots:   []string{"src/foo", "test/foo"},
+				RemoveRegex:   test.removeRegex,
+				PreserveRegex: test.preserveRegex,
+			}
+			r := &generateRunner{
+				repo:           &MockRepository{Dir: repoDir},
+				outputManifest: filepath.Join(repoDir, "manifest.json"),
+			}
+			changes, err := r.removeAPIOutputs(library, test.apis)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var gotRemoved []string
+			for _, file := range files {
+				if _, err := os.Stat(filepath.Join(repoDir, file)); os.IsNotExist(err) {
+					gotRemoved = append(gotRemoved, file)
+				}
+			}
+			if diff := cmp.Diff(test.wantRemoved, gotRemoved); diff != "" {
+				t.Errorf("removed files mismatch (-want +got):\n%s", diff)
+			}
+			var gotChanges []string
+			if changes != nil {
+				gotChanges = changes.Removed
+			}
+			if diff := cmp.Diff(test.wantRemoved, gotChanges, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+				t.Errorf("removed files changes mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetExistingSrc(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name      string
+		libraryID string
+		paths     []string
+		want      []string
+	}{
+		{
+			name:      "all_source_paths_existed",
+			libraryID: "some-library",
+			paths: []string{
+				"a/path",
+				"another/path",
+			},
+			want: []string{
+				"a/path",
+				"another/path",
+			},
+		},
+		{
+			name:      "one_source_paths_existed",
+			libraryID: "some-library",
+			paths: []string{
+				"a/path",
+			},
+			want: []string{
+				"a/path",
+			},
+		},
+		{
+			name:      "no_source_paths_existed",
+			libraryID: "some-library",
+			want:      nil,
+		},
+		{
+			name:      "no_library_existed",
+			libraryID: "another-library",
+			want:      nil,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repo := newTestGitRepo(t)
+			state := &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID: "some-library",
+						SourceRoots: []string{
+							"a/path",
+							"another/path",
+						},
+					},
+				},
+			}
+			for _, path := range test.paths {
+				relPath := filepath.Join(repo.GetDir(), path)
+				if err := os.MkdirAll(relPath, 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			r := &generateRunner{
+				repo:  repo,
+				state: state,
+			}
+
+			got := r.getExistingSrc(test.libraryID)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("getExistingSrc() mismatch (-want +got):%s", diff)
+			}
+		})
+	}
+}
+
+func TestUpdateLastGeneratedCommitState(t *testing.T) {
+	t.Parallel()
+	sourceRepo := newTestGitRepo(t)
+	hash, err := sourceRepo.HeadHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &generateRunner{
+		sourceRepo: sourceRepo,
+		state: &legacyconfig.LibrarianState{
+			Libraries: []*legacyconfig.LibraryState{
+				{
+					ID: "some-library",
+				},
+			},
+		},
+	}
+	if err := r.updateLastGeneratedCommitState("some-library"); err != nil {
+		t.Fatal(err)
+	}
+	if r.state.Libraries[0].LastGeneratedCommit != hash {
+		t.Errorf("updateState() got = %v, want %v", r.state.Libraries[0].LastGeneratedCommit, hash)
+	}
+}
+
+func TestShouldGenerate(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name                 string
+		config               *legacyconfig.LibrarianConfig
+		state                *legacyconfig.LibrarianState
+		force                bool
+		generateUnchanged    bool
+		generateUnchangedFor []string
+		onlyChanged          bool
+		locallyChanged       map[string]bool
+		sourceRepo           legacygitrepo.Repository
+		libraryIDToTest      string
+		want                 bool
+		wantErr              bool
+	}{
+		// Tests that don't get as far as checking for hashes.
+		// (The mock repo will fail if we do get that far.)
+		{
+			name: "only changed with local changes",
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:                  "TestLibrary",
+						APIs:                []*legacyconfig.API{{Path: "google/cloud/test"}},
+						LastGeneratedCommit: "LastGeneratedCommit",
+					},
+				},
+			},
+			onlyChanged:    true,
+			locallyChanged: map[string]bool{"TestLibrary": true},
+			sourceRepo: &MockRepository{
+				HeadHashError: errors.New("Shouldn't get as far as checking head"),
+			},
+			libraryIDToTest: "TestLibrary",
+			want:            true,
+		},
+		{
+			name: "only changed without local changes",
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:   "TestLibrary",
+						APIs: []*legacyconfig.API{{Path: "google/cloud/test"}},
+					},
+				},
+			},
+			onlyChanged:    true,
+			locallyChanged: map[string]bool{"OtherLibrary": true},
+			sourceRepo: &MockRepository{
+				HeadHashError: errors.New("Shouldn't get as far as checking head"),
+			},
+			libraryIDToTest: "TestLibrary",
+			want:            false,
+		},
+		{
+			name: "frozen",
+			config: &legacyconfig.LibrarianConfig{
+				Libraries: []*legacyconfig.LibraryConfig{
+					{
+						LibraryID: "TestLibrary",
+						Frozen:    true,
+					},
+				},
+			},
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:   "TestLibrary",
+						APIs: []*legacyconfig.API{{Path: "google/cloud/test"}},
+					},
+				},
+			},
+			generateUnchangedFor: []string{"TestLibrary"},
+			sourceRepo: &MockRepository{
+				HeadHashError: errors.New("Shouldn't get as far as checking head"),
+			},
+			libraryIDToTest: "TestLibrary",
+			want:            false,
+		},
+		{
+			name: "frozen with force",
+			config: &legacyconfig.LibrarianConfig{
+				Libraries: []*legacyconfig.LibraryConfig{
+					{
+						LibraryID: "TestLibrary",
+						Frozen:    true,
+					},
+				},
+			},
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:   "TestLibrary",
+						APIs: []*legacyconfig.API{{Path: "google/cloud/test"}},
+					},
+				},
+			},
+			force: true,
+			sourceRepo: &MockRepository{
+				HeadHashError: errors.New("Shouldn't get as far as checking head"),
+			},
+			libraryIDToTest: "TestLibrary",
+			want:            true,
+		},
 		{
 			name: "generation blocked",
 			config: &legacyconfig.LibrarianConfig{
@@ -1252,12 +2527,56 @@ func TestShouldGenerate(t *testing.T) {
 					},
 				},
 			},
-			generateUnchanged: true,
+			generateUnchanged: true,
+			sourceRepo: &MockRepository{
+				HeadHashError: errors.New("Shouldn't get as far as checking head"),
+			},
+			libraryIDToTest: "TestLibrary",
+			want:            true,
+		},
+		{
+			name: "generateUnchangedFor names the library",
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:                  "TestLibrary",
+						APIs:                []*legacyconfig.API{{Path: "google/cloud/test"}},
+						LastGeneratedCommit: "LastGeneratedCommit",
+					},
+				},
+			},
+			generateUnchangedFor: []string{"OtherLibrary", "TestLibrary"},
+			sourceRepo: &MockRepository{
+				HeadHashError: errors.New("Shouldn't get as far as checking head"),
+			},
+			libraryIDToTest: "TestLibrary",
+			want:            true,
+		},
+		{
+			name: "generateUnchangedFor names the library, generation blocked",
+			config: &legacyconfig.LibrarianConfig{
+				Libraries: []*legacyconfig.LibraryConfig{
+					{
+						LibraryID:       "TestLibrary",
+						GenerateBlocked: true,
+					},
+				},
+			},
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:                  "TestLibrary",
+						APIs:                []*legacyconfig.API{{Path: "google/cloud/test"}},
+						LastGeneratedCommit: "LastGeneratedCommit",
+					},
+				},
+			},
+			generateUnchangedFor: []string{"TestLibrary"},
 			sourceRepo: &MockRepository{
 				HeadHashError: errors.New("Shouldn't get as far as checking head"),
 			},
 			libraryIDToTest: "TestLibrary",
-			want:            true,
+			want:            false,
 		},
 		{
 			name: "no LastGeneratedCommit",
@@ -1365,6 +2684,28 @@ func TestShouldGenerate(t *testing.T) {
 			libraryIDToTest: "TestLibrary",
 			want:            false,
 		},
+		{
+			name: "API hasn't changed, generateUnchangedFor names another library",
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:                  "TestLibrary",
+						APIs:                []*legacyconfig.API{{Path: "google/cloud/test"}},
+						LastGeneratedCommit: "LastGeneratedCommit",
+					},
+				},
+			},
+			generateUnchangedFor: []string{"OtherLibrary"},
+			sourceRepo: &MockRepository{
+				HeadHashValue: "HeadCommit",
+				GetHashForPathValue: map[string]string{
+					"LastGeneratedCommit:google/cloud/test": "hash",
+					"HeadCommit:google/cloud/test":          "hash",
+				},
+			},
+			libraryIDToTest: "TestLibrary",
+			want:            false,
+		},
 		{
 			name: "one API hasn't changed, one has",
 			state: &legacyconfig.LibrarianState{
@@ -1413,13 +2754,84 @@ func TestShouldGenerate(t *testing.T) {
 			libraryIDToTest: "TestLibrary",
 			wantErr:         true,
 		},
+		{
+			name: "pinned commit, API changed since HEAD but not since pinned commit",
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:                  "TestLibrary",
+						APIs:                []*legacyconfig.API{{Path: "google/cloud/test"}},
+						LastGeneratedCommit: "LastGeneratedCommit",
+						PinnedCommit:        "PinnedCommit",
+					},
+				},
+			},
+			sourceRepo: &MockRepository{
+				HeadHashError: errors.New("Shouldn't check head of a pinned library"),
+				GetCommitByHash: map[string]*legacygitrepo.Commit{
+					"PinnedCommit": {Message: "feat: pinned"},
+				},
+				GetHashForPathValue: map[string]string{
+					"LastGeneratedCommit:google/cloud/test": "hash",
+					"PinnedCommit:google/cloud/test":        "hash",
+				},
+			},
+			libraryIDToTest: "TestLibrary",
+			want:            false,
+		},
+		{
+			name: "pinned commit, API changed since last generation",
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:                  "TestLibrary",
+						APIs:                []*legacyconfig.API{{Path: "google/cloud/test"}},
+						LastGeneratedCommit: "LastGeneratedCommit",
+						PinnedCommit:        "PinnedCommit",
+					},
+				},
+			},
+			sourceRepo: &MockRepository{
+				GetCommitByHash: map[string]*legacygitrepo.Commit{
+					"PinnedCommit": {Message: "feat: pinned"},
+				},
+				GetHashForPathValue: map[string]string{
+					"LastGeneratedCommit:google/cloud/test": "hash1",
+					"PinnedCommit:google/cloud/test":        "hash2",
+				},
+			},
+			libraryIDToTest: "TestLibrary",
+			want:            true,
+		},
+		{
+			name: "pinned commit not in source repo",
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:                  "TestLibrary",
+						APIs:                []*legacyconfig.API{{Path: "google/cloud/test"}},
+						LastGeneratedCommit: "LastGeneratedCommit",
+						PinnedCommit:        "PinnedCommit",
+					},
+				},
+			},
+			sourceRepo: &MockRepository{
+				GetCommitError: errors.New("object not found"),
+			},
+			libraryIDToTest: "TestLibrary",
+			wantErr:         true,
+		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			r := &generateRunner{
-				generateUnchanged: test.generateUnchanged,
-				librarianConfig:   test.config,
-				state:             test.state,
-				sourceRepo:        test.sourceRepo,
+				force:                test.force,
+				generateUnchanged:    test.generateUnchanged,
+				generateUnchangedFor: test.generateUnchangedFor,
+				librarianConfig:      test.config,
+				locallyChanged:       test.locallyChanged,
+				onlyChanged:          test.onlyChanged,
+				state:                test.state,
+				sourceRepo:           test.sourceRepo,
 			}
 			library := test.state.LibraryByID(test.libraryIDToTest)
 			got, err := r.shouldGenerate(library)
@@ -1433,6 +2845,198 @@ func TestShouldGenerate(t *testing.T) {
 	}
 }
 
+func TestLocallyChangedLibraries(t *testing.T) {
+	t.Parallel()
+	state := &legacyconfig.LibrarianState{
+		Libraries: []*legacyconfig.LibraryState{
+			{
+				ID:          "source-changed",
+				SourceRoots: []string{"packages/source-changed"},
+				APIs:        []*legacyconfig.API{{Path: "google/cloud/a/v1"}},
+			},
+			{
+				ID:          "api-changed",
+				SourceRoots: []string{"packages/api-changed"},
+				APIs:        []*legacyconfig.API{{Path: "google/cloud/b/v1"}},
+			},
+			{
+				ID:          "unchanged",
+				SourceRoots: []string{"packages/unchanged"},
+				APIs:        []*legacyconfig.API{{Path: "google/cloud/c/v1"}},
+			},
+		},
+	}
+	for _, test := range []struct {
+		name       string
+		repo       *MockRepository
+		sourceRepo *MockRepository
+		want       map[string]bool
+		wantErrMsg string
+	}{
+		{
+			name: "changes in both repositories",
+			repo: &MockRepository{
+				ChangedFilesValue: []string{"packages/source-changed/client.go", "README.md"},
+			},
+			sourceRepo: &MockRepository{
+				ChangedFilesValue: []string{"google/cloud/b/v1/service.proto", "google/cloud/c/v1beta/service.proto"},
+			},
+			want: map[string]bool{"source-changed": true, "api-changed": true},
+		},
+		{
+			name:       "no changes",
+			repo:       &MockRepository{},
+			sourceRepo: &MockRepository{},
+			want:       map[string]bool{},
+		},
+		{
+			name:       "language repository error",
+			repo:       &MockRepository{ChangedFilesError: errors.New("status failed")},
+			sourceRepo: &MockRepository{},
+			wantErrMsg: "failed to get changed files of the language repository",
+		},
+		{
+			name:       "api source error",
+			repo:       &MockRepository{},
+			sourceRepo: &MockRepository{ChangedFilesError: errors.New("status failed")},
+			wantErrMsg: "failed to get changed files of the API source",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			r := &generateRunner{
+				repo:       test.repo,
+				sourceRepo: test.sourceRepo,
+				state:      state,
+			}
+			got, err := r.locallyChangedLibraries()
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("locallyChangedLibraries() error = %v, want contains %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("locallyChangedLibraries() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGenerateRunDryRun(t *testing.T) {
+	t.Parallel()
+	sourceRepo := &MockRepository{
+		HeadHashValue: "newhash",
+		GetHashForPathValue: map[string]string{
+			"oldhash:google/cloud/a": "hash1",
+			"newhash:google/cloud/a": "hash2",
+			"oldhash:google/cloud/b": "hash3",
+			"newhash:google/cloud/b": "hash3",
+		},
+	}
+	for _, test := range []struct {
+		name    string
+		api     string
+		library string
+		want    string
+	}{
+		{
+			name: "all libraries",
+			want: `LIBRARY       ACTION    CONFIGURE  APIS CHANGED  LAST GENERATED COMMIT  SOURCE ROOTS
+changed-id    generate  no         true          oldhash                dir1
+new-id        generate  no         -             -                      dir3
+no-apis-id    skip      no         -             -                      -
+unchanged-id  skip      no         false         oldhash                -
+`,
+		},
+		{
+			name:    "single library",
+			library: "unchanged-id",
+			want: `LIBRARY       ACTION    CONFIGURE  APIS CHANGED  LAST GENERATED COMMIT  SOURCE ROOTS
+unchanged-id  generate  no         false         oldhash                dir2
+`,
+		},
+		{
+			name:    "library needs configure",
+			api:     "google/cloud/d",
+			library: "configure-id",
+			want: `LIBRARY       ACTION    CONFIGURE  APIS CHANGED  LAST GENERATED COMMIT  SOURCE ROOTS
+configure-id  generate  yes        -             -                      -
+`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			state := &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:                  "changed-id",
+						APIs:                []*legacyconfig.API{{Path: "google/cloud/a"}},
+						LastGeneratedCommit: "oldhash",
+						SourceRoots:         []string{"dir1"},
+					},
+					{
+						ID:          "new-id",
+						APIs:        []*legacyconfig.API{{Path: "google/cloud/c"}},
+						SourceRoots: []string{"dir3"},
+					},
+					{
+						ID: "no-apis-id",
+					},
+					{
+						ID:                  "unchanged-id",
+						APIs:                []*legacyconfig.API{{Path: "google/cloud/b"}},
+						LastGeneratedCommit: "oldhash",
+						SourceRoots:         []string{"dir2"},
+					},
+				},
+			}
+			containerClient := &mockContainerClient{}
+			ghClient := &mockGitHubClient{}
+			repo := &MockRepository{
+				Dir: t.TempDir(),
+			}
+			var out bytes.Buffer
+			r := &generateRunner{
+				api:             test.api,
+				containerClient: containerClient,
+				dryRun:          true,
+				ghClient:        ghClient,
+				library:         test.library,
+				librarianConfig: &legacyconfig.LibrarianConfig{},
+				out:             &out,
+				push:            true,
+				repo:            repo,
+				sourceRepo:      sourceRepo,
+				state:           state,
+				workRoot:        t.TempDir(),
+			}
+			if err := r.run(t.Context()); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, out.String()); diff != "" {
+				t.Errorf("run() mismatch (-want +got):\n%s", diff)
+			}
+			if containerClient.configureCalls != 0 || containerClient.generateCalls != 0 {
+				t.Errorf("container calls = %d configure, %d generate, want none", containerClient.configureCalls, containerClient.generateCalls)
+			}
+			if repo.CommitCalls != 0 || ghClient.createPullRequestCalls != 0 {
+				t.Errorf("got %d commits and %d pull requests, want none", repo.CommitCalls, ghClient.createPullRequestCalls)
+			}
+			entries, err := os.ReadDir(r.workRoot)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 0 {
+				t.Errorf("work root should be empty, got %d entries", len(entries))
+			}
+		})
+	}
+}
+
 func TestAddAPIToLibrary(t *testing.T) {
 	t.Parallel()
 	testCases := []struct {
@@ -1650,3 +3254,208 @@ func TestNeedsConfigure(t *testing.T) {
 		})
 	}
 }
+
+// countingContainerClient records the largest number of containers running at
+// the same time.
+type countingContainerClient struct {
+	ContainerClient
+	running atomic.Int32
+	peak    atomic.Int32
+	calls   atomic.Int32
+}
+
+func (c *countingContainerClient) run() {
+	running := c.running.Add(1)
+	for {
+		peak := c.peak.Load()
+		if running <= peak || c.peak.CompareAndSwap(peak, running) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	c.running.Add(-1)
+	c.calls.Add(1)
+}
+
+func (c *countingContainerClient) Build(ctx context.Context, request *legacydocker.BuildRequest) error {
+	c.run()
+	return nil
+}
+
+func (c *countingContainerClient) Configure(ctx context.Context, request *legacydocker.ConfigureRequest) (string, error) {
+	c.run()
+	return "", nil
+}
+
+func (c *countingContainerClient) Generate(ctx context.Context, request *legacydocker.GenerateRequest) error {
+	c.run()
+	return nil
+}
+
+func TestLimitContainers(t *testing.T) {
+	t.Parallel()
+	const containers = 12
+	for _, test := range []struct {
+		name     string
+		limit    int
+		wantPeak int32
+	}{
+		{
+			name:     "one container at a time",
+			limit:    1,
+			wantPeak: 1,
+		},
+		{
+			name:     "limit below the number of containers",
+			limit:    3,
+			wantPeak: 3,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			fake := &countingContainerClient{}
+			client := limitContainers(fake, test.limit)
+			var wg sync.WaitGroup
+			for i := range containers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					var err error
+					switch i % 3 {
+					case 0:
+						err = client.Generate(t.Context(), &legacydocker.GenerateRequest{})
+					case 1:
+						err = client.Build(t.Context(), &legacydocker.BuildRequest{})
+					default:
+						_, err = client.Configure(t.Context(), &legacydocker.ConfigureRequest{})
+					}
+					if err != nil {
+						t.Errorf("container call failed: %v", err)
+					}
+				}()
+			}
+			wg.Wait()
+			if got := fake.calls.Load(); got != containers {
+				t.Errorf("calls = %d, want %d", got, containers)
+			}
+			if got := fake.peak.Load(); got > test.wantPeak {
+				t.Errorf("peak running containers = %d, want at most %d", got, test.wantPeak)
+			}
+		})
+	}
+}
+
+func TestLimitContainers_NoLimit(t *testing.T) {
+	t.Parallel()
+	fake := &countingContainerClient{}
+	if got := limitContainers(fake, 0); got != fake {
+		t.Errorf("limitContainers() = %v, want the client itself", got)
+	}
+}
+
+func TestLimitContainers_ContextDone(t *testing.T) {
+	t.Parallel()
+	client := limitContainers(&countingContainerClient{}, 1).(*limitedContainerClient)
+	// Fill the only slot, so that the next container has to wait.
+	client.sem <- struct{}{}
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if err := client.Generate(ctx, &legacydocker.GenerateRequest{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Generate() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestGenerateRunFailFast(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name              string
+		failFast          bool
+		container         *mockContainerClient
+		wantErrMsg        string
+		wantGenerateCalls int
+		wantBuildCalls    int
+	}{
+		{
+			name:     "generate failure halts execution",
+			failFast: true,
+			container: &mockContainerClient{
+				wantLibraryGen:    true,
+				failGenerateForID: "lib2",
+				generateErrForID:  errors.New("generate error"),
+			},
+			wantErrMsg:        `failed to generate library "lib2": generate error`,
+			wantGenerateCalls: 2,
+			wantBuildCalls:    1,
+		},
+		{
+			name:     "build failure halts execution",
+			failFast: true,
+			container: &mockContainerClient{
+				wantLibraryGen: true,
+				failBuildForID: "lib1",
+				buildErrForID:  errors.New("build error"),
+			},
+			wantErrMsg:        `failed to generate library "lib1": build error`,
+			wantGenerateCalls: 1,
+			wantBuildCalls:    1,
+		},
+		{
+			name: "without fail fast, failure does not halt execution",
+			container: &mockContainerClient{
+				wantLibraryGen:    true,
+				failGenerateForID: "lib2",
+				generateErrForID:  errors.New("generate error"),
+			},
+			wantGenerateCalls: 3,
+			wantBuildCalls:    2,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			state := &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:          "lib1",
+						APIs:        []*legacyconfig.API{{Path: "some/api1"}},
+						SourceRoots: []string{"src/a"},
+					},
+					{
+						ID:          "lib2",
+						APIs:        []*legacyconfig.API{{Path: "some/api2"}},
+						SourceRoots: []string{"src/b"},
+					},
+					{
+						ID:          "lib3",
+						APIs:        []*legacyconfig.API{{Path: "some/api3"}},
+						SourceRoots: []string{"src/c"},
+					},
+				},
+			}
+			r := &generateRunner{
+				build:           true,
+				containerClient: test.container,
+				failFast:        test.failFast,
+				ghClient:        &mockGitHubClient{},
+				repo:            newTestGitRepoWithState(t, state),
+				sourceRepo:      newTestGitRepo(t),
+				state:           state,
+				workRoot:        t.TempDir(),
+			}
+			err := r.run(t.Context())
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Errorf("run() error = %v, want error containing %q", err, test.wantErrMsg)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.wantGenerateCalls, test.container.generateCalls); diff != "" {
+				t.Errorf("run() generateCalls mismatch (-want +got):%s", diff)
+			}
+			if diff := cmp.Diff(test.wantBuildCalls, test.container.buildCalls); diff != "" {
+				t.Errorf("run() buildCalls mismatch (-want +got):%s", diff)
+			}
+		})
+	}
+}
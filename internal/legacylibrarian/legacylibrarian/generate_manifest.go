@@ -0,0 +1,146 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// libraryFileChanges lists the files of a library created, modified and
+// removed by cleaning and copying its generated code. The paths are relative
+// to the root of the language repository and use forward slashes.
+type libraryFileChanges struct {
+	Created  []string `json:"created"`
+	Modified []string `json:"modified"`
+	Removed  []string `json:"removed"`
+}
+
+// sourceRootChecksums returns the SHA-256 checksums of the files under the
+// given source roots of repoDir, keyed by their path relative to repoDir.
+// Symbolic links are recorded by their target rather than by their content.
+// Source roots which do not exist are skipped.
+func sourceRootChecksums(repoDir string, sourceRoots []string) (map[string]string, error) {
+	checksums := map[string]string{}
+	for _, sourceRoot := range sourceRoots {
+		root := filepath.Join(repoDir, sourceRoot)
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(repoDir, path)
+			if err != nil {
+				return err
+			}
+			var checksum string
+			if d.Type()&fs.ModeSymlink != 0 {
+				target, err := os.Readlink(path)
+				if err != nil {
+					return err
+				}
+				checksum = "symlink:" + target
+			} else {
+				checksum, err = fileChecksum(path)
+				if err != nil {
+					return err
+				}
+			}
+			checksums[filepath.ToSlash(rel)] = checksum
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to compute checksums of %q: %w", sourceRoot, err)
+		}
+	}
+	return checksums, nil
+}
+
+// trackFileChanges calls change, which changes the files under the given
+// source roots of repoDir, and returns the files it created, modified and
+// removed. If track is false, as when no manifest is written, change is
+// called without walking the source roots and nil is returned.
+func trackFileChanges(track bool, repoDir string, sourceRoots []string, change func() error) (*libraryFileChanges, error) {
+	if !track {
+		return nil, change()
+	}
+	before, err := sourceRootChecksums(repoDir, sourceRoots)
+	if err != nil {
+		return nil, err
+	}
+	if err := change(); err != nil {
+		return nil, err
+	}
+	after, err := sourceRootChecksums(repoDir, sourceRoots)
+	if err != nil {
+		return nil, err
+	}
+	return newLibraryFileChanges(before, after), nil
+}
+
+// newLibraryFileChanges returns the changes between the files with the before
+// checksums and those with the after checksums, as returned by
+// sourceRootChecksums. The paths are sorted.
+func newLibraryFileChanges(before, after map[string]string) *libraryFileChanges {
+	changes := &libraryFileChanges{
+		Created:  []string{},
+		Modified: []string{},
+		Removed:  []string{},
+	}
+	for path, checksum := range after {
+		previous, ok := before[path]
+		switch {
+		case !ok:
+			changes.Created = append(changes.Created, path)
+		case previous != checksum:
+			changes.Modified = append(changes.Modified, path)
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changes.Removed = append(changes.Removed, path)
+		}
+	}
+	slices.Sort(changes.Created)
+	slices.Sort(changes.Modified)
+	slices.Sort(changes.Removed)
+	return changes
+}
+
+// writeManifest writes the file changes of the generated libraries, keyed by
+// library ID, to the file specified with the -output-manifest flag, if any.
+func (r *generateRunner) writeManifest(manifest map[string]*libraryFileChanges) error {
+	if r.outputManifest == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.outputManifest), 0755); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := os.WriteFile(r.outputManifest, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+)
+
+func TestGenerateRunManifest(t *testing.T) {
+	t.Parallel()
+	state := &legacyconfig.LibrarianState{
+		Image: "gcr.io/test/image:v1.2.3",
+		Libraries: []*legacyconfig.LibraryState{
+			{
+				ID:          "changed",
+				APIs:        []*legacyconfig.API{{Path: "some/api1"}},
+				SourceRoots: []string{"src/a"},
+			},
+			{
+				ID:          "other",
+				APIs:        []*legacyconfig.API{{Path: "some/api2"}},
+				SourceRoots: []string{"src/b"},
+			},
+			{
+				ID:          "removed",
+				APIs:        []*legacyconfig.API{{Path: "some/api3", Status: legacyconfig.StatusRemoved}},
+				SourceRoots: []string{"src/c"},
+			},
+			{
+				ID:          "broken",
+				APIs:        []*legacyconfig.API{{Path: "some/api4"}},
+				SourceRoots: []string{"src/d"},
+			},
+		},
+	}
+	// The repository has a random_file.txt file in each source root, and the
+	// mock container generates an empty example.txt file in each source root.
+	// The build of the broken library fails, so that it is restored and left
+	// out of the manifest.
+	repo := newTestGitRepoWithState(t, state)
+	for path, content := range map[string]string{
+		"src/a/example.txt": "previous content",
+		"src/a/old.txt":     "removed content",
+	} {
+		fullPath := filepath.Join(repo.GetDir(), path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.AddAll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Commit("chore: add existing code"); err != nil {
+		t.Fatal(err)
+	}
+	manifestPath := filepath.Join(t.TempDir(), "out", "manifest.json")
+	r := &generateRunner{
+		repo:           repo,
+		outputManifest: manifestPath,
+		sourceRepo:     newTestGitRepo(t),
+		state:          state,
+		build:          true,
+		containerClient: &mockContainerClient{
+			wantLibraryGen: true,
+			failBuildForID: "broken",
+			buildErrForID:  errors.New("build failed"),
+		},
+		ghClient: &mockGitHubClient{},
+		workRoot: t.TempDir(),
+	}
+	if err := r.run(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]*libraryFileChanges
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]*libraryFileChanges{
+		"changed": {
+			Created:  []string{},
+			Modified: []string{"src/a/example.txt"},
+			Removed:  []string{"src/a/old.txt", "src/a/random_file.txt"},
+		},
+		"other": {
+			Created:  []string{"src/b/example.txt"},
+			Modified: []string{},
+			Removed:  []string{"src/b/random_file.txt"},
+		},
+		"removed": {
+			Created:  []string{},
+			Modified: []string{},
+			Removed:  []string{"src/c/random_file.txt"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("manifest mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := os.Stat(filepath.Join(repo.GetDir(), "src/d/example.txt")); !os.IsNotExist(err) {
+		t.Errorf("broken library not restored, stat error = %v", err)
+	}
+}
+
+func TestNewLibraryFileChanges(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name   string
+		before map[string]string
+		after  map[string]string
+		want   *libraryFileChanges
+	}{
+		{
+			name: "no files",
+			want: &libraryFileChanges{Created: []string{}, Modified: []string{}, Removed: []string{}},
+		},
+		{
+			name:   "created, modified and removed",
+			before: map[string]string{"a/same.txt": "1", "a/changed.txt": "2", "a/removed.txt": "3"},
+			after:  map[string]string{"a/same.txt": "1", "a/changed.txt": "4", "a/new.txt": "5", "a/b/new.txt": "6"},
+			want: &libraryFileChanges{
+				Created:  []string{"a/b/new.txt", "a/new.txt"},
+				Modified: []string{"a/changed.txt"},
+				Removed:  []string{"a/removed.txt"},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got := newLibraryFileChanges(test.before, test.after)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSourceRootChecksums(t *testing.T) {
+	t.Parallel()
+	repoDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repoDir, "a", "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "a", "b", "file.txt"), []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("b/file.txt", filepath.Join(repoDir, "a", "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	got, err := sourceRootChecksums(repoDir, []string{"a", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"a/b/file.txt": "ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73",
+		"a/link.txt":   "symlink:b/file.txt",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacypubsub"
+)
+
+// Publisher publishes messages to a Pub/Sub topic.
+type Publisher interface {
+	Publish(ctx context.Context, topic string, data []byte) error
+}
+
+// generationMessage is the message published for each library to the topic
+// specified with the -pubsub-topic flag.
+type generationMessage struct {
+	Library string `json:"library"`
+	// Outcome is one of "succeeded", "failed" or "skipped" for the message
+	// published as soon as the library is processed, and "pull_request_created"
+	// for the message published once the pull request containing the generated
+	// library is created.
+	Outcome string `json:"outcome"`
+	Version string `json:"version,omitempty"`
+	// PullRequestURL is the URL of the pull request containing the generated
+	// library. It is only set when Outcome is "pull_request_created".
+	PullRequestURL string `json:"pull_request_url,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// outcomePullRequestCreated is the outcome of the message published for a
+// generated library once its pull request is created.
+const outcomePullRequestCreated = "pull_request_created"
+
+// initPublisher creates the Pub/Sub client used to publish the generation
+// results, if the -pubsub-topic flag is specified and no publisher is set.
+// Failures to create the client are logged, and no results are published.
+func (r *generateRunner) initPublisher() {
+	if r.pubSubTopic == "" || r.publisher != nil {
+		return
+	}
+	client, err := legacypubsub.NewClient()
+	if err != nil {
+		slog.Warn("failed to create Pub/Sub client, not publishing generation results", "topic", r.pubSubTopic, "err", err)
+		return
+	}
+	r.publisher = client
+}
+
+// publishGenerationResult publishes a message with the outcome of library to
+// the topic specified with the -pubsub-topic flag, if any. It is called as soon
+// as the library is processed, and may be called concurrently.
+func (r *generateRunner) publishGenerationResult(ctx context.Context, library *LibraryGenerationReport) {
+	message := &generationMessage{
+		Library: library.ID,
+		Outcome: reportStatusSucceeded,
+		Error:   library.Error,
+	}
+	switch {
+	case library.Action == reportActionSkipped:
+		message.Outcome = reportActionSkipped
+	case library.Error != "":
+		message.Outcome = reportStatusFailed
+	}
+	r.publish(ctx, message)
+}
+
+// publishPullRequest publishes a message for each library of report which was
+// generated successfully, linking to the pull request containing it.
+func (r *generateRunner) publishPullRequest(ctx context.Context, report *GenerationReport, pullRequestURL string) {
+	if pullRequestURL == "" {
+		return
+	}
+	for _, library := range report.Libraries {
+		if library.Action == reportActionSkipped || library.Error != "" {
+			continue
+		}
+		r.publish(ctx, &generationMessage{
+			Library:        library.ID,
+			Outcome:        outcomePullRequestCreated,
+			PullRequestURL: pullRequestURL,
+		})
+	}
+}
+
+// publish publishes message to the topic specified with the -pubsub-topic
+// flag, if any, adding the version of the library. Failures to publish are
+// logged and otherwise ignored.
+func (r *generateRunner) publish(ctx context.Context, message *generationMessage) {
+	if r.pubSubTopic == "" || r.publisher == nil {
+		return
+	}
+	r.mu.Lock()
+	if libraryState := r.state.LibraryByID(message.Library); libraryState != nil {
+		message.Version = libraryState.Version
+	}
+	r.mu.Unlock()
+	data, err := json.Marshal(message)
+	if err != nil {
+		slog.Warn("failed to encode generation result", "id", message.Library, "err", err)
+		return
+	}
+	if err := r.publisher.Publish(ctx, r.pubSubTopic, data); err != nil {
+		slog.Warn("failed to publish generation result", "id", message.Library, "topic", r.pubSubTopic, "err", err)
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+)
+
+func TestPublishGenerationResult(t *testing.T) {
+	t.Parallel()
+	const topic = "projects/my-project/topics/librarian"
+	state := &legacyconfig.LibrarianState{
+		Libraries: []*legacyconfig.LibraryState{
+			{ID: "lib1", Version: "1.2.3"},
+		},
+	}
+	for _, test := range []struct {
+		name        string
+		library     *LibraryGenerationReport
+		pubSubTopic string
+		publisher   *mockPublisher
+		want        []*generationMessage
+	}{
+		{
+			name:        "succeeded",
+			library:     &LibraryGenerationReport{ID: "lib1", Action: reportActionRegenerated, Generate: reportStatusSucceeded},
+			pubSubTopic: topic,
+			publisher:   &mockPublisher{},
+			want:        []*generationMessage{{Library: "lib1", Outcome: "succeeded", Version: "1.2.3"}},
+		},
+		{
+			name:        "failed",
+			library:     &LibraryGenerationReport{ID: "lib1", Action: reportActionRegenerated, Generate: reportStatusFailed, Error: "generate error"},
+			pubSubTopic: topic,
+			publisher:   &mockPublisher{},
+			want:        []*generationMessage{{Library: "lib1", Outcome: "failed", Version: "1.2.3", Error: "generate error"}},
+		},
+		{
+			name:        "skipped",
+			library:     &LibraryGenerationReport{ID: "lib1", Action: reportActionSkipped},
+			pubSubTopic: topic,
+			publisher:   &mockPublisher{},
+			want:        []*generationMessage{{Library: "lib1", Outcome: "skipped", Version: "1.2.3"}},
+		},
+		{
+			name:        "unknown library",
+			library:     &LibraryGenerationReport{ID: "lib2", Action: reportActionConfigured, Generate: reportStatusSucceeded},
+			pubSubTopic: topic,
+			publisher:   &mockPublisher{},
+			want:        []*generationMessage{{Library: "lib2", Outcome: "succeeded"}},
+		},
+		{
+			name:        "publish failures are ignored",
+			library:     &LibraryGenerationReport{ID: "lib1", Action: reportActionRegenerated, Generate: reportStatusSucceeded},
+			pubSubTopic: topic,
+			publisher:   &mockPublisher{err: errors.New("permission denied")},
+			want:        []*generationMessage{{Library: "lib1", Outcome: "succeeded", Version: "1.2.3"}},
+		},
+		{
+			name:      "no topic",
+			library:   &LibraryGenerationReport{ID: "lib1", Action: reportActionRegenerated, Generate: reportStatusSucceeded},
+			publisher: &mockPublisher{},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			r := &generateRunner{
+				publisher:   test.publisher,
+				pubSubTopic: test.pubSubTopic,
+				state:       state,
+			}
+			r.publishGenerationResult(t.Context(), test.library)
+			if diff := cmp.Diff(test.want, publishedMessages(t, test.publisher, topic)); diff != "" {
+				t.Errorf("published messages mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPublishPullRequest(t *testing.T) {
+	t.Parallel()
+	const topic = "projects/my-project/topics/librarian"
+	report := &GenerationReport{
+		Libraries: []*LibraryGenerationReport{
+			{ID: "lib1", Action: reportActionRegenerated, Generate: reportStatusSucceeded},
+			{ID: "lib2", Action: reportActionRegenerated, Generate: reportStatusFailed, Error: "generate error"},
+			{ID: "lib3", Action: reportActionSkipped},
+		},
+	}
+	state := &legacyconfig.LibrarianState{
+		Libraries: []*legacyconfig.LibraryState{
+			{ID: "lib1", Version: "1.2.3"},
+		},
+	}
+	for _, test := range []struct {
+		name           string
+		pullRequestURL string
+		want           []*generationMessage
+	}{
+		{
+			name:           "pull request created",
+			pullRequestURL: "https://github.com/googleapis/google-cloud-go/pull/123",
+			want: []*generationMessage{
+				{
+					Library:        "lib1",
+					Outcome:        "pull_request_created",
+					Version:        "1.2.3",
+					PullRequestURL: "https://github.com/googleapis/google-cloud-go/pull/123",
+				},
+			},
+		},
+		{
+			name: "no pull request",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			publisher := &mockPublisher{}
+			r := &generateRunner{
+				publisher:   publisher,
+				pubSubTopic: topic,
+				state:       state,
+			}
+			r.publishPullRequest(t.Context(), report, test.pullRequestURL)
+			if diff := cmp.Diff(test.want, publishedMessages(t, publisher, topic)); diff != "" {
+				t.Errorf("published messages mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// publishedMessages returns the messages published by publisher, checking
+// that they were published to topic.
+func publishedMessages(t *testing.T, publisher *mockPublisher, topic string) []*generationMessage {
+	t.Helper()
+	var got []*generationMessage
+	for i, data := range publisher.messages {
+		if publisher.topics[i] != topic {
+			t.Errorf("published to %q, want %q", publisher.topics[i], topic)
+		}
+		message := &generationMessage{}
+		if err := json.Unmarshal(data, message); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, message)
+	}
+	return got
+}
+
+func TestGenerateRunPublishesResults(t *testing.T) {
+	t.Parallel()
+	state := &legacyconfig.LibrarianState{
+		Image: "gcr.io/test/image:v1.2.3",
+		Libraries: []*legacyconfig.LibraryState{
+			{
+				ID:          "lib1",
+				Version:     "1.0.0",
+				APIs:        []*legacyconfig.API{{Path: "some/api1"}},
+				SourceRoots: []string{"src/a"},
+			},
+			{
+				ID:          "lib2",
+				Version:     "2.0.0",
+				APIs:        []*legacyconfig.API{{Path: "some/api2"}},
+				SourceRoots: []string{"src/b"},
+			},
+		},
+	}
+	publisher := &mockPublisher{}
+	r := &generateRunner{
+		repo:       newTestGitRepoWithState(t, state),
+		sourceRepo: newTestGitRepo(t),
+		state:      state,
+		containerClient: &mockContainerClient{
+			wantLibraryGen:    true,
+			failGenerateForID: "lib2",
+			generateErrForID:  errors.New("generate error"),
+		},
+		ghClient:    &mockGitHubClient{},
+		publisher:   publisher,
+		pubSubTopic: "projects/my-project/topics/librarian",
+		workRoot:    t.TempDir(),
+	}
+	if err := r.run(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	got := publishedMessages(t, publisher, "projects/my-project/topics/librarian")
+	want := []*generationMessage{
+		{Library: "lib1", Outcome: "succeeded", Version: "1.0.0"},
+		{Library: "lib2", Outcome: "failed", Version: "2.0.0", Error: "generate error"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("published messages mismatch (-want +got):\n%s", diff)
+	}
+}
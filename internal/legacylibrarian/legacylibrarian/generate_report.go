@@ -0,0 +1,146 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	reportActionConfigured  = "configured"
+	reportActionRegenerated = "regenerated"
+	reportActionSkipped     = "skipped"
+
+	reportStatusSucceeded = "succeeded"
+	reportStatusFailed    = "failed"
+)
+
+// GenerationReport is the summary of a run of the generate command, returned
+// by Generate and written to the file specified with the -report flag.
+type GenerationReport struct {
+	Generated int                        `json:"generated"`
+	Skipped   int                        `json:"skipped"`
+	Failed    int                        `json:"failed"`
+	Libraries []*LibraryGenerationReport `json:"libraries"`
+}
+
+// LibraryGenerationReport is the outcome of the generate command for a single
+// library.
+type LibraryGenerationReport struct {
+	ID string `json:"id"`
+	// Action is one of "configured", "regenerated" or "skipped".
+	Action string `json:"action"`
+	// Generate is "succeeded" or "failed", and empty for skipped libraries.
+	Generate string `json:"generate,omitempty"`
+	// Build is "succeeded" or "failed", and empty if the library was not built.
+	Build               string  `json:"build,omitempty"`
+	DurationSeconds     float64 `json:"duration_seconds"`
+	LastGeneratedCommit string  `json:"last_generated_commit,omitempty"`
+	Error               string  `json:"error,omitempty"`
+}
+
+// buildError is returned by generateSingleLibrary when a library was generated
+// but failed to build.
+type buildError struct {
+	err error
+}
+
+func (e *buildError) Error() string {
+	return e.err.Error()
+}
+
+func (e *buildError) Unwrap() error {
+	return e.err
+}
+
+// add records the outcome for a library and updates the totals of report.
+func (report *GenerationReport) add(library *LibraryGenerationReport) {
+	switch {
+	case library.Action == reportActionSkipped:
+		report.Skipped++
+	case library.Error != "":
+		report.Failed++
+	default:
+		report.Generated++
+	}
+	report.Libraries = append(report.Libraries, library)
+}
+
+// newLibraryGenerationReport returns the report of a library for which
+// generation was attempted, given the error returned by the attempt.
+func (r *generateRunner) newLibraryGenerationReport(libraryID, action string, duration time.Duration, err error) *LibraryGenerationReport {
+	library := &LibraryGenerationReport{
+		ID:              libraryID,
+		Action:          action,
+		DurationSeconds: duration.Seconds(),
+	}
+	var buildErr *buildError
+	switch {
+	case err == nil:
+		library.Generate = reportStatusSucceeded
+		if r.build {
+			library.Build = reportStatusSucceeded
+		}
+	case errors.As(err, &buildErr):
+		library.Generate = reportStatusSucceeded
+		library.Build = reportStatusFailed
+	default:
+		library.Generate = reportStatusFailed
+	}
+	if err != nil {
+		library.Error = err.Error()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if libraryState := r.state.LibraryByID(libraryID); libraryState != nil {
+		library.LastGeneratedCommit = libraryState.LastGeneratedCommit
+	}
+	return library
+}
+
+// writeReport writes report to the file specified with the -report flag, if
+// any.
+func (r *generateRunner) writeReport(report *GenerationReport) error {
+	if r.report == "" {
+		return nil
+	}
+	if err := writeGenerationReport(r.report, report); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// writeGenerationReport writes report as JSON to the file at path, creating
+// its parent directory if needed. Libraries are sorted by ID.
+func writeGenerationReport(path string, report *GenerationReport) error {
+	slices.SortFunc(report.Libraries, func(a, b *LibraryGenerationReport) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
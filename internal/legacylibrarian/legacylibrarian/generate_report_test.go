@@ -0,0 +1,233 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+)
+
+func TestGenerateRunReport(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name      string
+		api       string
+		library   string
+		language  string
+		state     *legacyconfig.LibrarianState
+		container *mockContainerClient
+		wantErr   bool
+		// want uses "HEAD" as the last generated commit of libraries
+		// generated at the head of the source repository.
+		want *GenerationReport
+	}{
+		{
+			name:     "all libraries with partial failure",
+			language: "python",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:          "lib1",
+						Language:    "python",
+						APIs:        []*legacyconfig.API{{Path: "some/api1"}},
+						SourceRoots: []string{"src/a"},
+					},
+					{
+						ID:          "lib2",
+						Language:    "python",
+						APIs:        []*legacyconfig.API{{Path: "some/api2"}},
+						SourceRoots: []string{"src/b"},
+					},
+					{
+						ID:          "lib3",
+						Language:    "python",
+						APIs:        []*legacyconfig.API{{Path: "some/api3"}},
+						SourceRoots: []string{"src/c"},
+					},
+					{
+						ID:          "lib4",
+						Language:    "go",
+						APIs:        []*legacyconfig.API{{Path: "some/api4"}},
+						SourceRoots: []string{"src/d"},
+					},
+				},
+			},
+			container: &mockContainerClient{
+				wantLibraryGen:    true,
+				failGenerateForID: "lib2",
+				generateErrForID:  errors.New("generate error"),
+				failBuildForID:    "lib3",
+				buildErrForID:     errors.New("build error"),
+			},
+			want: &GenerationReport{
+				Generated: 1,
+				Skipped:   1,
+				Failed:    2,
+				Libraries: []*LibraryGenerationReport{
+					{
+						ID:                  "lib1",
+						Action:              reportActionRegenerated,
+						Generate:            reportStatusSucceeded,
+						Build:               reportStatusSucceeded,
+						LastGeneratedCommit: "HEAD",
+					},
+					{
+						ID:       "lib2",
+						Action:   reportActionRegenerated,
+						Generate: reportStatusFailed,
+						Error:    "generate error",
+					},
+					{
+						ID:       "lib3",
+						Action:   reportActionRegenerated,
+						Generate: reportStatusSucceeded,
+						Build:    reportStatusFailed,
+						Error:    "build error",
+					},
+					{
+						ID:     "lib4",
+						Action: reportActionSkipped,
+					},
+				},
+			},
+		},
+		{
+			name:    "configure single library",
+			api:     "some/api",
+			library: "some-library",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+			},
+			container: &mockContainerClient{
+				wantLibraryGen:        true,
+				configureLibraryPaths: []string{"src/a"},
+			},
+			want: &GenerationReport{
+				Generated: 1,
+				Libraries: []*LibraryGenerationReport{
+					{
+						ID:                  "some-library",
+						Action:              reportActionConfigured,
+						Generate:            reportStatusSucceeded,
+						Build:               reportStatusSucceeded,
+						LastGeneratedCommit: "HEAD",
+					},
+				},
+			},
+		},
+		{
+			name:    "single library fails",
+			library: "some-library",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:          "some-library",
+						APIs:        []*legacyconfig.API{{Path: "some/api"}},
+						SourceRoots: []string{"src/a"},
+					},
+				},
+			},
+			container: &mockContainerClient{
+				wantLibraryGen:    true,
+				failGenerateForID: "some-library",
+				generateErrForID:  errors.New("generate error"),
+			},
+			wantErr: true,
+			want: &GenerationReport{
+				Failed: 1,
+				Libraries: []*LibraryGenerationReport{
+					{
+						ID:       "some-library",
+						Action:   reportActionRegenerated,
+						Generate: reportStatusFailed,
+						Error:    "generate error",
+					},
+				},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			sourceRepo := newTestGitRepo(t)
+			if test.api != "" {
+				// Configuring a library requires a service config in the API path.
+				if err := os.MkdirAll(filepath.Join(sourceRepo.GetDir(), test.api), 0755); err != nil {
+					t.Fatal(err)
+				}
+				data := []byte("type: google.api.Service")
+				if err := os.WriteFile(filepath.Join(sourceRepo.GetDir(), test.api, "example_service_v2.yaml"), data, 0644); err != nil {
+					t.Fatal(err)
+				}
+				if err := sourceRepo.AddAll(); err != nil {
+					t.Fatal(err)
+				}
+				if err := sourceRepo.Commit("feat: add an api\n\nPiperOrigin-RevId: 123456"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			head, err := sourceRepo.HeadHash()
+			if err != nil {
+				t.Fatal(err)
+			}
+			reportPath := filepath.Join(t.TempDir(), "out", "report.json")
+			r := &generateRunner{
+				api:             test.api,
+				library:         test.library,
+				language:        test.language,
+				build:           true,
+				repo:            newTestGitRepoWithState(t, test.state),
+				report:          reportPath,
+				sourceRepo:      sourceRepo,
+				state:           test.state,
+				containerClient: test.container,
+				ghClient:        &mockGitHubClient{},
+				workRoot:        t.TempDir(),
+			}
+			err = r.run(t.Context())
+			if test.wantErr && err == nil {
+				t.Fatal("run() should return error")
+			}
+			if !test.wantErr && err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(reportPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := &GenerationReport{}
+			if err := json.Unmarshal(data, got); err != nil {
+				t.Fatal(err)
+			}
+			for _, library := range test.want.Libraries {
+				if library.LastGeneratedCommit == "HEAD" {
+					library.LastGeneratedCommit = head
+				}
+			}
+			if diff := cmp.Diff(test.want, got, cmpopts.IgnoreFields(LibraryGenerationReport{}, "DurationSeconds")); diff != "" {
+				t.Errorf("report mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -19,13 +19,33 @@ const (
 
 	releaseLongHelp = "Manages releases of libraries."
 
+	debugLongHelp = "Provides tools for troubleshooting Librarian."
+
+	parseCommitLongHelp = `The 'debug parse-commit' command prints how a commit message is
+interpreted by the conventional commit parser used by the 'release stage'
+command. This helps to find out why a commit does not trigger the expected
+version bump.
+
+The message is read from stdin, or from the commit with the given full hash in
+the language repository. Each conventional commit found in the message is
+printed as JSON, with its type, scope, subject, body, footers, whether it is a
+breaking change or a nested commit, and the library it is associated with. The
+'--library' flag sets the library of commits which do not name one.
+
+Examples:
+  # Parse a message from stdin
+  echo 'feat(parser)!: add a feature' | librarian debug parse-commit
+
+  # Parse a commit of the language repository
+  librarian debug parse-commit --repo=/path/to/repo <commit-hash>`
+
 	generateLongHelp = `The generate command is the primary tool for all code generation
 tasks. It handles both the initial setup of a new library (onboarding) and the
 regeneration of existing ones. Librarian works by delegating language-specific
 tasks to a container, which is configured in the .librarian/state.yaml file.
-Librarian is environment aware and will check if the current directory is the
-root of a librarian repository. If you are not executing in such a directory the
-'--repo' flag must be provided.
+Librarian is environment aware and will check if the current directory is inside
+a librarian repository, looking for .librarian/state.yaml in it and its parents.
+If you are not executing in such a directory the '--repo' flag must be provided.
 
 # Onboarding a new library
 
@@ -77,8 +97,9 @@ Example with build and push:
 a new release. It automates the creation of a release pull request by parsing
 conventional commits, determining the next semantic version for each library,
 and generating a changelog. Librarian is environment aware and will check if the
-current directory is the root of a librarian repository. If you are not
-executing in such a directory the '--repo' flag must be provided.
+current directory is inside a librarian repository, looking for
+.librarian/state.yaml in it and its parents. If you are not executing in such a
+directory the '--repo' flag must be provided.
 
 This command scans the git history since the last release, identifies changes
 (feat, fix, BREAKING CHANGE), and calculates the appropriate version bump
@@ -124,14 +145,24 @@ This command's primary responsibilities are to:
 
 You can target a specific merged pull request using the '--pr' flag. If no pull
 request is specified, the command will automatically search for and process all
-merged pull requests with the 'release:pending' label from the last 30 days.
+merged pull requests with the 'release:pending' label from the last 30 days, or
+from the window set with the '--since' flag.
+
+Tags and GitHub Releases which already exist are not created again, so the
+command can safely be re-run after a partial failure. GitHub API requests failing
+with a server error or a secondary rate limit are retried with exponential
+backoff, as configured with the '--github-max-retries' and '--github-retry-delay'
+flags.
 
 Examples:
   # Tag and create a GitHub release for a specific merged PR.
   librarian release tag --repo=https://github.com/googleapis/google-cloud-go --pr=https://github.com/googleapis/google-cloud-go/pull/123
 
   # Find and process all pending merged release PRs in a repository.
-  librarian release tag --repo=https://github.com/googleapis/google-cloud-go`
+  librarian release tag --repo=https://github.com/googleapis/google-cloud-go
+
+  # Reprocess pending merged release PRs from the last 90 days.
+  librarian release tag --repo=https://github.com/googleapis/google-cloud-go --since=90d`
 
 	updateImageLongHelp = `The 'update-image' command is used to update the 'image' SHA
 of the language container for a language repository.
@@ -148,4 +179,19 @@ Examples:
 
   # Create a PR that updates the language container to the specified image.
   librarian update-image --commit --push --image=<some-image-with-sha>`
+
+	validateLongHelp = `The 'validate' command checks the '.librarian/state.yaml' file of a
+language repository, so that configuration errors are caught before running
+other commands. It reports:
+
+  - Duplicate library IDs
+  - Libraries without APIs
+  - Libraries without source roots, or with source roots that do not exist
+  - APIs referenced by multiple libraries, unless they are listed in the
+    global files allowlist of the librarian config
+
+The command exits with a non-zero status if any problem is found.
+
+Example:
+  librarian validate --repo=/path/to/repo`
 )
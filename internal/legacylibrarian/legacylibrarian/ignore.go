@@ -0,0 +1,73 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// librarianIgnoreFile is the name of the file, in gitignore syntax, listing
+// the paths of a source root which are preserved when the library is cleaned
+// and which generated files do not overwrite.
+const librarianIgnoreFile = ".librarianignore"
+
+// loadLibrarianIgnore reads the .librarianignore files of the source roots in
+// repoDir. The patterns of a file are relative to its source root, and the
+// .librarianignore files themselves always match. It returns nil if no source
+// root has a .librarianignore file.
+func loadLibrarianIgnore(repoDir string, sourceRoots []string) (gitignore.Matcher, error) {
+	var patterns []gitignore.Pattern
+	for _, sourceRoot := range sourceRoots {
+		path := filepath.Join(repoDir, sourceRoot, librarianIgnoreFile)
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		domain := strings.Split(filepath.ToSlash(filepath.Clean(sourceRoot)), "/")
+		patterns = append(patterns, gitignore.ParsePattern("/"+librarianIgnoreFile, domain))
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := strings.TrimRight(scanner.Text(), " \t\r")
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			patterns = append(patterns, gitignore.ParsePattern(line, domain))
+		}
+	}
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	return gitignore.NewMatcher(patterns), nil
+}
+
+// isIgnored reports whether relPath, relative to the repository root, matches
+// the .librarianignore patterns in ignore, which may be nil.
+func isIgnored(ignore gitignore.Matcher, relPath string, isDir bool) bool {
+	if ignore == nil {
+		return false
+	}
+	return ignore.Match(strings.Split(filepath.ToSlash(relPath), "/"), isDir)
+}
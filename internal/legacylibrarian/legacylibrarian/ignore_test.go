@@ -0,0 +1,156 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+)
+
+func TestLoadLibrarianIgnore(t *testing.T) {
+	t.Parallel()
+	repoDir := t.TempDir()
+	ignoreFile := `# Hand-written files.
+hand_written.go
+custom/
+*.md
+!README.md
+`
+	if err := os.MkdirAll(filepath.Join(repoDir, "packages/foo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "packages/foo", librarianIgnoreFile), []byte(ignoreFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ignore, err := loadLibrarianIgnore(repoDir, []string{"packages/foo", "packages/bar"})
+	if err != nil {
+		t.Fatalf("loadLibrarianIgnore() failed: %v", err)
+	}
+	for _, test := range []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{path: "packages/foo/.librarianignore", want: true},
+		{path: "packages/foo/hand_written.go", want: true},
+		{path: "packages/foo/sub/hand_written.go", want: true},
+		{path: "packages/foo/custom", isDir: true, want: true},
+		{path: "packages/foo/custom/file.txt", want: true},
+		{path: "packages/foo/CHANGES.md", want: true},
+		{path: "packages/foo/README.md", want: false},
+		{path: "packages/foo/client.go", want: false},
+		{path: "packages/bar/hand_written.go", want: false},
+		{path: "hand_written.go", want: false},
+	} {
+		if got := isIgnored(ignore, test.path, test.isDir); got != test.want {
+			t.Errorf("isIgnored(%q) = %t, want %t", test.path, got, test.want)
+		}
+	}
+}
+
+func TestLoadLibrarianIgnore_NoFile(t *testing.T) {
+	t.Parallel()
+	ignore, err := loadLibrarianIgnore(t.TempDir(), []string{"packages/foo"})
+	if err != nil {
+		t.Fatalf("loadLibrarianIgnore() failed: %v", err)
+	}
+	if ignore != nil {
+		t.Errorf("loadLibrarianIgnore() = %v, want nil", ignore)
+	}
+	if isIgnored(ignore, "packages/foo/client.go", false) {
+		t.Errorf("isIgnored() = true, want false")
+	}
+}
+
+func TestCleanAndCopyLibrary_LibrarianIgnore(t *testing.T) {
+	t.Parallel()
+	repoDir := t.TempDir()
+	outputDir := t.TempDir()
+	state := &legacyconfig.LibrarianState{
+		Libraries: []*legacyconfig.LibraryState{
+			{
+				ID:            "foo",
+				SourceRoots:   []string{"packages/foo"},
+				PreserveRegex: []string{"^packages/foo/preserved.txt$"},
+			},
+		},
+	}
+	for path, content := range map[string]string{
+		"packages/foo/.librarianignore":     "hand_written.go\ncustom/\n",
+		"packages/foo/hand_written.go":      "hand-written",
+		"packages/foo/custom/notes.txt":     "notes",
+		"packages/foo/preserved.txt":        "preserved",
+		"packages/foo/stale_generated.go":   "stale",
+		"packages/foo/client.go":            "old client",
+		"packages/foo/custom/generated.txt": "old generated",
+	} {
+		writeTestFile(t, filepath.Join(repoDir, path), content)
+	}
+	for path, content := range map[string]string{
+		"packages/foo/hand_written.go": "generated",
+		"packages/foo/client.go":       "new client",
+	} {
+		writeTestFile(t, filepath.Join(outputDir, path), content)
+	}
+
+	if _, err := cleanAndCopyLibrary(state, repoDir, "", "foo", outputDir, false); err != nil {
+		t.Fatalf("cleanAndCopyLibrary() failed: %v", err)
+	}
+
+	want := map[string]string{
+		"packages/foo/.librarianignore":     "hand_written.go\ncustom/\n",
+		"packages/foo/hand_written.go":      "hand-written",
+		"packages/foo/custom/notes.txt":     "notes",
+		"packages/foo/custom/generated.txt": "old generated",
+		"packages/foo/preserved.txt":        "preserved",
+		"packages/foo/client.go":            "new client",
+	}
+	got := make(map[string]string)
+	err := filepath.WalkDir(repoDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(repoDir, path)
+		if err != nil {
+			return err
+		}
+		got[filepath.ToSlash(relPath)] = string(content)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("cleanAndCopyLibrary() files mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -19,9 +19,9 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"os"
 
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacycli"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
 )
 
 // Run executes the Librarian CLI with the given command line arguments.
@@ -30,21 +30,34 @@ func Run(ctx context.Context, arg ...string) error {
 	return cmd.Run(ctx, arg)
 }
 
-func setupLogger(verbose bool) {
-	level := slog.LevelInfo
-	if verbose {
-		level = slog.LevelDebug
+// setupLogger sets the default logger as configured by the -log-format flag,
+// at the level returned by logLevel. The GitHub token is redacted from the
+// logs.
+func setupLogger(cfg *legacyconfig.Config, verbose bool) error {
+	return legacycli.SetupLogger(cfg.LogFormat, logLevel(cfg, verbose), cfg.GitHubToken)
+}
+
+// logLevel returns the minimum level of the logs: debug if verbose is set,
+// error if the -quiet flag is set, and the level of the -log-level flag
+// otherwise.
+func logLevel(cfg *legacyconfig.Config, verbose bool) string {
+	switch {
+	case verbose:
+		return "debug"
+	case cfg.Quiet:
+		return "error"
+	default:
+		return cfg.LogLevel
 	}
-	opts := &slog.HandlerOptions{Level: level}
-	handler := slog.NewTextHandler(os.Stderr, opts)
-	slog.SetDefault(slog.New(handler))
 }
 
 func newLibrarianCommand() *legacycli.Command {
 	commands := []*legacycli.Command{
+		newCmdDebug(),
 		newCmdGenerate(),
 		newCmdRelease(),
 		newCmdUpdateImage(),
+		newCmdValidate(),
 	}
 
 	return legacycli.NewCommandSet(
@@ -54,40 +67,124 @@ func newLibrarianCommand() *legacycli.Command {
 		librarianLongHelp)
 }
 
-func newCmdGenerate() *legacycli.Command {
+func newCmdDebug() *legacycli.Command {
+	cmdDebug := &legacycli.Command{
+		Short:     "debug provides tools for troubleshooting Librarian.",
+		UsageLine: "librarian debug <command> [arguments]",
+		Long:      debugLongHelp,
+		Commands: []*legacycli.Command{
+			newCmdParseCommit(),
+		},
+	}
+	cmdDebug.Init()
+	return cmdDebug
+}
+
+func newCmdParseCommit() *legacycli.Command {
 	var verbose bool
-	cmdGenerate := &legacycli.Command{
-		Short:     "generate onboards and generates client library code",
-		UsageLine: "librarian generate [flags]",
-		Long:      generateLongHelp,
+	cmdParseCommit := &legacycli.Command{
+		Short:     "parse-commit prints how a commit message is parsed as conventional commits.",
+		UsageLine: "librarian debug parse-commit [flags] [commit-hash]",
+		Long:      parseCommitLongHelp,
 		Action: func(ctx context.Context, cmd *legacycli.Command) error {
-			setupLogger(verbose)
-			slog.Debug("generate command verbose logging")
-			if err := cmd.Config.SetDefaults(); err != nil {
-				return fmt.Errorf("failed to initialize config: %w", err)
+			if err := setupLogger(cmd.Config, verbose); err != nil {
+				return err
+			}
+			slog.Debug("parse-commit command verbose logging")
+			// The language repository is only needed to read a commit by hash.
+			if cmd.Flags.NArg() > 0 {
+				if err := cmd.Config.SetDefaults(); err != nil {
+					return fmt.Errorf("failed to initialize config: %w", err)
+				}
 			}
 			if _, err := cmd.Config.IsValid(); err != nil {
 				return fmt.Errorf("failed to validate config: %s", err)
 			}
-			runner, err := newGenerateRunner(cmd.Config)
+			runner, err := newParseCommitRunner(cmd.Config, cmd.Flags.Args())
 			if err != nil {
 				return err
 			}
 			return runner.run(ctx)
 		},
 	}
+	cmdParseCommit.Init()
+	addFlagLibrary(cmdParseCommit.Flags, cmdParseCommit.Config)
+	addFlagRepo(cmdParseCommit.Flags, cmdParseCommit.Config)
+	addFlagBranch(cmdParseCommit.Flags, cmdParseCommit.Config)
+	addFlagWorkRoot(cmdParseCommit.Flags, cmdParseCommit.Config)
+	addFlagLogFormat(cmdParseCommit.Flags, cmdParseCommit.Config)
+	addFlagLogLevel(cmdParseCommit.Flags, cmdParseCommit.Config)
+	addFlagQuiet(cmdParseCommit.Flags, cmdParseCommit.Config)
+	addFlagVerbose(cmdParseCommit.Flags, &verbose)
+	return cmdParseCommit
+}
+
+func newCmdGenerate() *legacycli.Command {
+	var verbose bool
+	cmdGenerate := &legacycli.Command{
+		Short:     "generate onboards and generates client library code",
+		UsageLine: "librarian generate [flags]",
+		Long:      generateLongHelp,
+		Action: func(ctx context.Context, cmd *legacycli.Command) error {
+			if err := setupLogger(cmd.Config, verbose); err != nil {
+				return err
+			}
+			slog.Debug("generate command verbose logging")
+			_, err := Generate(ctx, cmd.Config)
+			return err
+		},
+	}
 	cmdGenerate.Init()
 	addFlagAPI(cmdGenerate.Flags, cmdGenerate.Config)
 	addFlagAPISource(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagAllowMissingServiceConfig(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagAmend(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagAuthor(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagAuthorEmail(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagAuthorName(cmdGenerate.Flags, cmdGenerate.Config)
 	addFlagBuild(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagCacheDir(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagCommitMessageTemplate(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagCommitter(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagConcurrency(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagContainerCPUs(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagContainerLogDir(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagContainerMemory(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagContainerReuse(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagContainerTimeout(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagDestPrefix(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagDetectRenamedAPIs(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagDryRun(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagFailFast(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagForce(cmdGenerate.Flags, cmdGenerate.Config)
 	addFlagGenerateUnchanged(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagGenerateUnchangedFor(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagGeneratorInput(cmdGenerate.Flags, cmdGenerate.Config)
 	addFlagHostMount(cmdGenerate.Flags, cmdGenerate.Config)
 	addFlagImage(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagLanguage(cmdGenerate.Flags, cmdGenerate.Config)
 	addFlagLibrary(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagMaxContainers(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagMount(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagNoLock(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagNoNetwork(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagNormalizeLineEndings(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagOnlyChanged(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagOutputManifest(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagOutputState(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagPRTemplate(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagProgressInterval(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagPubSubTopic(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagQuiet(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagRebaseOntoBase(cmdGenerate.Flags, cmdGenerate.Config)
 	addFlagRepo(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagReport(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagSkipConfigure(cmdGenerate.Flags, cmdGenerate.Config)
 	addFlagBranch(cmdGenerate.Flags, cmdGenerate.Config)
 	addFlagWorkRoot(cmdGenerate.Flags, cmdGenerate.Config)
 	addFlagPush(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagLogFormat(cmdGenerate.Flags, cmdGenerate.Config)
+	addFlagLogLevel(cmdGenerate.Flags, cmdGenerate.Config)
 	addFlagVerbose(cmdGenerate.Flags, &verbose)
 	return cmdGenerate
 }
@@ -113,7 +210,9 @@ func newCmdTag() *legacycli.Command {
 		UsageLine: "librarian release tag [arguments]",
 		Long:      tagLongHelp,
 		Action: func(ctx context.Context, cmd *legacycli.Command) error {
-			setupLogger(verbose)
+			if err := setupLogger(cmd.Config, verbose); err != nil {
+				return err
+			}
 			slog.Debug("tag command verbose logging")
 			if err := cmd.Config.SetDefaults(); err != nil {
 				return fmt.Errorf("failed to initialize config: %w", err)
@@ -132,6 +231,15 @@ func newCmdTag() *legacycli.Command {
 	addFlagRepo(cmdTag.Flags, cmdTag.Config)
 	addFlagPR(cmdTag.Flags, cmdTag.Config)
 	addFlagGitHubAPIEndpoint(cmdTag.Flags, cmdTag.Config)
+	addFlagGitHubMaxRetries(cmdTag.Flags, cmdTag.Config)
+	addFlagGitHubRetryDelay(cmdTag.Flags, cmdTag.Config)
+	addFlagManifestOutput(cmdTag.Flags, cmdTag.Config)
+	addFlagPostReleaseUpdate(cmdTag.Flags, cmdTag.Config)
+	addFlagSince(cmdTag.Flags, cmdTag.Config)
+	addFlagTagType(cmdTag.Flags, cmdTag.Config)
+	addFlagLogFormat(cmdTag.Flags, cmdTag.Config)
+	addFlagLogLevel(cmdTag.Flags, cmdTag.Config)
+	addFlagQuiet(cmdTag.Flags, cmdTag.Config)
 	addFlagVerbose(cmdTag.Flags, &verbose)
 	return cmdTag
 }
@@ -143,7 +251,9 @@ func newCmdStage() *legacycli.Command {
 		UsageLine: "librarian release stage [flags]",
 		Long:      releaseStageLongHelp,
 		Action: func(ctx context.Context, cmd *legacycli.Command) error {
-			setupLogger(verbose)
+			if err := setupLogger(cmd.Config, verbose); err != nil {
+				return err
+			}
 			slog.Debug("stage command verbose logging")
 			if err := cmd.Config.SetDefaults(); err != nil {
 				return fmt.Errorf("failed to initialize config: %w", err)
@@ -155,18 +265,49 @@ func newCmdStage() *legacycli.Command {
 			if err != nil {
 				return err
 			}
-			return runner.run(ctx)
+			return runWithRepoLock(cmd.Config, runner.repo.GetDir(), func() error {
+				return runner.run(ctx)
+			})
 		},
 	}
 	cmdStage.Init()
+	addFlagAmend(cmdStage.Flags, cmdStage.Config)
+	addFlagAuthor(cmdStage.Flags, cmdStage.Config)
+	addFlagAuthorEmail(cmdStage.Flags, cmdStage.Config)
+	addFlagAuthorName(cmdStage.Flags, cmdStage.Config)
 	addFlagCommit(cmdStage.Flags, cmdStage.Config)
+	addFlagCommitMessageTemplate(cmdStage.Flags, cmdStage.Config)
+	addFlagCommitter(cmdStage.Flags, cmdStage.Config)
+	addFlagContainerCPUs(cmdStage.Flags, cmdStage.Config)
+	addFlagContainerMemory(cmdStage.Flags, cmdStage.Config)
+	addFlagContainerTimeout(cmdStage.Flags, cmdStage.Config)
+	addFlagExcludeCommit(cmdStage.Flags, cmdStage.Config)
+	addFlagFetchBeforeStage(cmdStage.Flags, cmdStage.Config)
+	addFlagForce(cmdStage.Flags, cmdStage.Config)
 	addFlagPush(cmdStage.Flags, cmdStage.Config)
+	addFlagIgnoreFreeze(cmdStage.Flags, cmdStage.Config)
 	addFlagImage(cmdStage.Flags, cmdStage.Config)
+	addFlagIncludeHiddenCommits(cmdStage.Flags, cmdStage.Config)
+	addFlagLanguage(cmdStage.Flags, cmdStage.Config)
 	addFlagLibrary(cmdStage.Flags, cmdStage.Config)
 	addFlagLibraryVersion(cmdStage.Flags, cmdStage.Config)
+	addFlagMaxChangelogEntries(cmdStage.Flags, cmdStage.Config)
+	addFlagMaxCommits(cmdStage.Flags, cmdStage.Config)
+	addFlagNoLock(cmdStage.Flags, cmdStage.Config)
+	addFlagOutputState(cmdStage.Flags, cmdStage.Config)
+	addFlagPRTemplate(cmdStage.Flags, cmdStage.Config)
+	addFlagProgressInterval(cmdStage.Flags, cmdStage.Config)
+	addFlagQuiet(cmdStage.Flags, cmdStage.Config)
+	addFlagRebaseOntoBase(cmdStage.Flags, cmdStage.Config)
 	addFlagRepo(cmdStage.Flags, cmdStage.Config)
+	addFlagReportUnreleased(cmdStage.Flags, cmdStage.Config)
+	addFlagSkipLibrariesWithoutSource(cmdStage.Flags, cmdStage.Config)
+	addFlagSquashMergeTitles(cmdStage.Flags, cmdStage.Config)
+	addFlagStrictSemVer(cmdStage.Flags, cmdStage.Config)
 	addFlagBranch(cmdStage.Flags, cmdStage.Config)
 	addFlagWorkRoot(cmdStage.Flags, cmdStage.Config)
+	addFlagLogFormat(cmdStage.Flags, cmdStage.Config)
+	addFlagLogLevel(cmdStage.Flags, cmdStage.Config)
 	addFlagVerbose(cmdStage.Flags, &verbose)
 	return cmdStage
 }
@@ -178,7 +319,9 @@ func newCmdUpdateImage() *legacycli.Command {
 		UsageLine: "librarian update-image [flags]",
 		Long:      updateImageLongHelp,
 		Action: func(ctx context.Context, cmd *legacycli.Command) error {
-			setupLogger(verbose)
+			if err := setupLogger(cmd.Config, verbose); err != nil {
+				return err
+			}
 			slog.Debug("update image command verbose logging")
 			if err := cmd.Config.SetDefaults(); err != nil {
 				return fmt.Errorf("failed to initialize config: %w", err)
@@ -190,15 +333,33 @@ func newCmdUpdateImage() *legacycli.Command {
 			if err != nil {
 				return err
 			}
-			return runner.run(ctx)
+			return runWithRepoLock(cmd.Config, runner.repo.GetDir(), func() error {
+				return runner.run(ctx)
+			})
 		},
 	}
 	cmdUpdateImage.Init()
 	addFlagAPISource(cmdUpdateImage.Flags, cmdUpdateImage.Config)
+	addFlagAllowTag(cmdUpdateImage.Flags, cmdUpdateImage.Config)
+	addFlagAuthor(cmdUpdateImage.Flags, cmdUpdateImage.Config)
+	addFlagAuthorEmail(cmdUpdateImage.Flags, cmdUpdateImage.Config)
+	addFlagAuthorName(cmdUpdateImage.Flags, cmdUpdateImage.Config)
 	addFlagBuild(cmdUpdateImage.Flags, cmdUpdateImage.Config)
+	addFlagCacheDir(cmdUpdateImage.Flags, cmdUpdateImage.Config)
 	addFlagCommit(cmdUpdateImage.Flags, cmdUpdateImage.Config)
+	addFlagCommitter(cmdUpdateImage.Flags, cmdUpdateImage.Config)
+	addFlagContainerCPUs(cmdUpdateImage.Flags, cmdUpdateImage.Config)
+	addFlagContainerMemory(cmdUpdateImage.Flags, cmdUpdateImage.Config)
+	addFlagContainerTimeout(cmdUpdateImage.Flags, cmdUpdateImage.Config)
+	addFlagForce(cmdUpdateImage.Flags, cmdUpdateImage.Config)
 	addFlagHostMount(cmdUpdateImage.Flags, cmdUpdateImage.Config)
 	addFlagImage(cmdUpdateImage.Flags, cmdUpdateImage.Config)
+	addFlagMount(cmdUpdateImage.Flags, cmdUpdateImage.Config)
+	addFlagNoLock(cmdUpdateImage.Flags, cmdUpdateImage.Config)
+	addFlagNoNetwork(cmdUpdateImage.Flags, cmdUpdateImage.Config)
+	addFlagOnlyIfAPIChanged(cmdUpdateImage.Flags, cmdUpdateImage.Config)
+	addFlagQuiet(cmdUpdateImage.Flags, cmdUpdateImage.Config)
+	addFlagRebaseOntoBase(cmdUpdateImage.Flags, cmdUpdateImage.Config)
 	addFlagRepo(cmdUpdateImage.Flags, cmdUpdateImage.Config)
 	addFlagBranch(cmdUpdateImage.Flags, cmdUpdateImage.Config)
 	addFlagWorkRoot(cmdUpdateImage.Flags, cmdUpdateImage.Config)
@@ -206,6 +367,44 @@ func newCmdUpdateImage() *legacycli.Command {
 	addFlagTest(cmdUpdateImage.Flags, cmdUpdateImage.Config)
 	addFlagLibraryToTest(cmdUpdateImage.Flags, cmdUpdateImage.Config)
 	addFlagCheckUnexpectedChanges(cmdUpdateImage.Flags, cmdUpdateImage.Config)
+	addFlagDiffOutput(cmdUpdateImage.Flags, cmdUpdateImage.Config)
+	addFlagLogFormat(cmdUpdateImage.Flags, cmdUpdateImage.Config)
+	addFlagLogLevel(cmdUpdateImage.Flags, cmdUpdateImage.Config)
 	addFlagVerbose(cmdUpdateImage.Flags, &verbose)
 	return cmdUpdateImage
 }
+
+func newCmdValidate() *legacycli.Command {
+	var verbose bool
+	cmdValidate := &legacycli.Command{
+		Short:     "validate checks the librarian state of a language repository",
+		UsageLine: "librarian validate [flags]",
+		Long:      validateLongHelp,
+		Action: func(ctx context.Context, cmd *legacycli.Command) error {
+			if err := setupLogger(cmd.Config, verbose); err != nil {
+				return err
+			}
+			slog.Debug("validate command verbose logging")
+			if err := cmd.Config.SetDefaults(); err != nil {
+				return fmt.Errorf("failed to initialize config: %w", err)
+			}
+			if _, err := cmd.Config.IsValid(); err != nil {
+				return fmt.Errorf("failed to validate config: %s", err)
+			}
+			runner, err := newValidateRunner(cmd.Config)
+			if err != nil {
+				return err
+			}
+			return runner.run(ctx)
+		},
+	}
+	cmdValidate.Init()
+	addFlagRepo(cmdValidate.Flags, cmdValidate.Config)
+	addFlagBranch(cmdValidate.Flags, cmdValidate.Config)
+	addFlagWorkRoot(cmdValidate.Flags, cmdValidate.Config)
+	addFlagLogFormat(cmdValidate.Flags, cmdValidate.Config)
+	addFlagLogLevel(cmdValidate.Flags, cmdValidate.Config)
+	addFlagQuiet(cmdValidate.Flags, cmdValidate.Config)
+	addFlagVerbose(cmdValidate.Flags, &verbose)
+	return cmdValidate
+}
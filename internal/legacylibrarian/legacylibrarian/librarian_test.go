@@ -34,6 +34,7 @@ import (
 	"gopkg.in/yaml.v3"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
 func TestRun(t *testing.T) {
@@ -60,6 +61,17 @@ func TestVerboseFlag(t *testing.T) {
 			args:           []string{"generate"},
 			expectDebugLog: false,
 		},
+		{
+			name:              "generate with -log-level=debug flag",
+			args:              []string{"generate", "-log-level=debug"},
+			expectDebugLog:    true,
+			expectDebugSubstr: "generate command verbose logging",
+		},
+		{
+			name:           "generate with -log-level=warn flag",
+			args:           []string{"generate", "-log-level=warn"},
+			expectDebugLog: false,
+		},
 		{
 			name:              "release stage with -v flag",
 			args:              []string{"release", "stage", "-v"},
@@ -117,6 +129,88 @@ func TestVerboseFlag(t *testing.T) {
 	}
 }
 
+func TestQuietFlag(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		args []string
+	}{
+		{
+			name: "generate with -quiet flag",
+			args: []string{"generate", "-quiet"},
+		},
+		{
+			name: "generate with -q flag",
+			args: []string{"generate", "-q", "-log-level=debug"},
+		},
+		{
+			name: "release tag with -quiet flag",
+			args: []string{"release", "tag", "-quiet"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			// Redirect stderr to capture logs.
+			oldStderr := os.Stderr
+			r, w, _ := os.Pipe()
+			os.Stderr = w
+			// Reset logger to default for test isolation.
+			slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+
+			_ = Run(t.Context(), test.args...)
+			// The logger set up by the command still writes to the pipe.
+			slog.Info("info log")
+			slog.Error("error log")
+
+			// Restore stderr and read the output.
+			w.Close()
+			os.Stderr = oldStderr
+			var buf bytes.Buffer
+			io.Copy(&buf, r)
+			output := buf.String()
+
+			for _, level := range []string{"level=DEBUG", "level=INFO", "level=WARN"} {
+				if strings.Contains(output, level) {
+					t.Errorf("expected no log below error level, found %s. Output:\n%s", level, output)
+				}
+			}
+			if !strings.Contains(output, "level=ERROR msg=\"error log\"") {
+				t.Errorf("expected error log to be present, but it wasn't. Output:\n%s", output)
+			}
+		})
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		cfg     *legacyconfig.Config
+		verbose bool
+		want    string
+	}{
+		{
+			name: "log level",
+			cfg:  &legacyconfig.Config{LogLevel: "warn"},
+			want: "warn",
+		},
+		{
+			name: "quiet",
+			cfg:  &legacyconfig.Config{LogLevel: "warn", Quiet: true},
+			want: "error",
+		},
+		{
+			name:    "verbose",
+			cfg:     &legacyconfig.Config{LogLevel: "warn", Quiet: true},
+			verbose: true,
+			want:    "debug",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := logLevel(test.cfg, test.verbose); got != test.want {
+				t.Errorf("logLevel() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
 func TestGenerate_DefaultBehavior(t *testing.T) {
 	ctx := t.Context()
 
@@ -160,6 +254,41 @@ func TestGenerate_DefaultBehavior(t *testing.T) {
 	if mockContainer.generateCalls != expectedGenerateCalls {
 		t.Errorf("Run(ctx, \"generate\"): got %d generate calls, want %d", mockContainer.generateCalls, expectedGenerateCalls)
 	}
+	if runner.result == nil || runner.result.Generated != 1 || runner.result.Failed != 0 {
+		t.Errorf("runner.run() result = %+v, want 1 generated library", runner.result)
+	}
+}
+
+func TestNewGenerateConfig(t *testing.T) {
+	cfg := NewGenerateConfig()
+	want := &legacyconfig.Config{
+		AllowMissingServiceConfig: true,
+		APISource:                 "https://github.com/googleapis/googleapis",
+		Branch:                    "main",
+		CommandName:               "generate",
+		Concurrency:               1,
+		ContainerTimeout:          legacyconfig.DefaultContainerTimeout,
+		LogFormat:                 "text",
+		LogLevel:                  "info",
+	}
+	if diff := cmp.Diff(want, cfg, cmpopts.IgnoreFields(legacyconfig.Config{}, "GitHubToken", "HostMount")); diff != "" {
+		t.Errorf("NewGenerateConfig() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerateInvalidConfig(t *testing.T) {
+	cfg := NewGenerateConfig()
+	cfg.Repo = t.TempDir()
+	cfg.Amend = true
+	cfg.Push = true
+	cfg.GitHubToken = "token"
+	report, err := Generate(t.Context(), cfg)
+	if err == nil || !strings.Contains(err.Error(), "failed to validate config") {
+		t.Errorf("Generate() error = %v, want failed to validate config", err)
+	}
+	if report != nil {
+		t.Errorf("Generate() report = %+v, want nil", report)
+	}
 }
 
 func TestIsURL(t *testing.T) {
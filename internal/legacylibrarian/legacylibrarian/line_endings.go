@@ -0,0 +1,88 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+)
+
+// binaryDetectionSize is the number of leading bytes of a file searched for
+// a NUL byte to detect binary files, as git does.
+const binaryDetectionSize = 8000
+
+// normalizeLineEndings converts the line endings of the text files under dir
+// to lineEnding, either legacyconfig.LineEndingLF or
+// legacyconfig.LineEndingCRLF. Binary files and symlinks are left untouched.
+// An empty lineEnding leaves every file untouched.
+func normalizeLineEndings(dir, lineEnding string) error {
+	if lineEnding == "" {
+		return nil
+	}
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if isBinary(content) {
+			slog.Debug("skipping line ending normalization of binary file", "file", path)
+			return nil
+		}
+		normalized, err := convertLineEndings(content, lineEnding)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(normalized, content) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, normalized, info.Mode().Perm())
+	})
+}
+
+// isBinary reports whether content looks like the content of a binary file,
+// i.e. contains a NUL byte in its first binaryDetectionSize bytes.
+func isBinary(content []byte) bool {
+	return bytes.IndexByte(content[:min(len(content), binaryDetectionSize)], 0) != -1
+}
+
+// convertLineEndings returns content with every "\r\n" and "\n" line ending
+// replaced by the given lineEnding.
+func convertLineEndings(content []byte, lineEnding string) ([]byte, error) {
+	lf := bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+	switch lineEnding {
+	case legacyconfig.LineEndingLF:
+		return lf, nil
+	case legacyconfig.LineEndingCRLF:
+		return bytes.ReplaceAll(lf, []byte("\n"), []byte("\r\n")), nil
+	default:
+		return nil, fmt.Errorf("invalid line ending %q", lineEnding)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+)
+
+func TestNormalizeLineEndings(t *testing.T) {
+	t.Parallel()
+	binary := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\r\n"
+	for _, test := range []struct {
+		name       string
+		lineEnding string
+		files      map[string]string
+		want       map[string]string
+	}{
+		{
+			name:       "to lf",
+			lineEnding: legacyconfig.LineEndingLF,
+			files: map[string]string{
+				"a.go":       "package a\r\n\r\nfunc A() {}\r\n",
+				"sub/b.txt":  "mixed\r\nendings\n",
+				"image.png":  binary,
+				"unix.proto": "syntax = \"proto3\";\n",
+			},
+			want: map[string]string{
+				"a.go":       "package a\n\nfunc A() {}\n",
+				"sub/b.txt":  "mixed\nendings\n",
+				"image.png":  binary,
+				"unix.proto": "syntax = \"proto3\";\n",
+			},
+		},
+		{
+			name:       "to crlf",
+			lineEnding: legacyconfig.LineEndingCRLF,
+			files: map[string]string{
+				"a.go":      "package a\n\nfunc A() {}\r\n",
+				"image.png": binary,
+			},
+			want: map[string]string{
+				"a.go":      "package a\r\n\r\nfunc A() {}\r\n",
+				"image.png": binary,
+			},
+		},
+		{
+			name: "disabled",
+			files: map[string]string{
+				"a.go": "package a\r\n",
+			},
+			want: map[string]string{
+				"a.go": "package a\r\n",
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			for name, content := range test.files {
+				writeTestFile(t, filepath.Join(dir, name), content)
+			}
+			if err := normalizeLineEndings(dir, test.lineEnding); err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]string{}
+			for name := range test.want {
+				content, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				got[name] = string(content)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNormalizeLineEndings_Symlink(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	target := filepath.Join(t.TempDir(), "target.txt")
+	writeTestFile(t, target, "outside\r\n")
+	if err := os.Symlink(target, filepath.Join(dir, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := normalizeLineEndings(dir, legacyconfig.LineEndingLF); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("outside\r\n", string(got)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
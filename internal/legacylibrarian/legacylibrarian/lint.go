@@ -0,0 +1,50 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+)
+
+// lintLibrary runs the lint command configured for library in config.yaml, if
+// any, with sh in each of its source roots in repoDir, given by destRoots
+// relative to repoDir. An error is returned if the command exits with a
+// non-zero status in any source root.
+func lintLibrary(ctx context.Context, librarianConfig *legacyconfig.LibrarianConfig, repoDir string, library *legacyconfig.LibraryState, destRoots []string) error {
+	if librarianConfig == nil {
+		return nil
+	}
+	libraryConfig := librarianConfig.LibraryConfigFor(library.ID)
+	if libraryConfig == nil || libraryConfig.LintCommand == "" {
+		return nil
+	}
+	for _, sourceRoot := range destRoots {
+		dir := filepath.Join(repoDir, sourceRoot)
+		slog.Info("running lint command", "library", library.ID, "dir", dir, "command", libraryConfig.LintCommand)
+		cmd := exec.CommandContext(ctx, "sh", "-c", libraryConfig.LintCommand)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("lint command failed in %s: %w: %s", sourceRoot, err, strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
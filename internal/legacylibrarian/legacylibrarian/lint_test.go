@@ -0,0 +1,87 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+)
+
+func TestLintLibrary(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name            string
+		librarianConfig *legacyconfig.LibrarianConfig
+		wantErrMsg      string
+	}{
+		{
+			name: "no librarian config",
+		},
+		{
+			name: "no lint command",
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				Libraries: []*legacyconfig.LibraryConfig{{LibraryID: "secretmanager"}},
+			},
+		},
+		{
+			name: "passing lint command",
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				Libraries: []*legacyconfig.LibraryConfig{
+					{LibraryID: "secretmanager", LintCommand: "test -f generated.txt"},
+				},
+			},
+		},
+		{
+			name: "failing lint command",
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				Libraries: []*legacyconfig.LibraryConfig{
+					{LibraryID: "secretmanager", LintCommand: "echo found 2 problems; exit 1"},
+				},
+			},
+			wantErrMsg: "lint command failed in packages/secretmanager: exit status 1: found 2 problems",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repoDir := t.TempDir()
+			library := &legacyconfig.LibraryState{
+				ID:          "secretmanager",
+				SourceRoots: []string{"packages/secretmanager", "other/secretmanager"},
+			}
+			for _, sourceRoot := range library.SourceRoots {
+				if err := os.MkdirAll(filepath.Join(repoDir, sourceRoot), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(repoDir, sourceRoot, "generated.txt"), nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := lintLibrary(t.Context(), test.librarianConfig, repoDir, library, library.SourceRoots)
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("lintLibrary() error = %v, want error containing %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("lintLibrary() failed: %v", err)
+			}
+		})
+	}
+}
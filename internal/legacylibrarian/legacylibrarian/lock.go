@@ -0,0 +1,187 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+)
+
+// lockFile is the name of the file, in the git directory of the language
+// repository, holding the PID of the process running a command against the
+// repository. Keeping it out of the working tree ensures it is never
+// committed.
+const lockFile = "librarian.lock"
+
+// acquireRepoLock takes an exclusive lock on the language repository at
+// repoDir, so that two commands do not modify its working tree and state at
+// the same time. The lock is a file holding the PID of the current process,
+// which is written to a temporary file first and then linked into place, so
+// that the lock file is never seen without its PID. A lock left behind by a
+// process which is no longer running is removed. The returned function
+// releases the lock.
+func acquireRepoLock(repoDir string) (func(), error) {
+	gitDir, err := findGitDir(repoDir)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(gitDir, lockFile)
+	tmp, err := writeLockPID(gitDir)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp)
+	// Retry once after removing a stale lock.
+	for range 2 {
+		err := os.Link(tmp, path)
+		if err == nil {
+			return func() {
+				if err := os.Remove(path); err != nil {
+					slog.Warn("failed to release lock", "path", path, "error", err)
+				}
+			}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock file %s: %w", path, err)
+		}
+		pid, err := readLockPID(path)
+		if err != nil {
+			return nil, err
+		}
+		if pid == 0 {
+			// The lock was released meanwhile.
+			continue
+		}
+		if processRunning(pid) {
+			return nil, fmt.Errorf("repository %s is locked by another librarian process (PID %d); wait for it to finish, or use -no-lock to skip locking", repoDir, pid)
+		}
+		if err := removeStaleLock(path, pid); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("failed to acquire lock %s", path)
+}
+
+// writeLockPID writes the PID of the current process to a new temporary file
+// in dir, and returns its path.
+func writeLockPID(dir string) (string, error) {
+	f, err := os.CreateTemp(dir, lockFile+".*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create lock file: %w", err)
+	}
+	_, err = fmt.Fprintf(f, "%d\n", os.Getpid())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write lock file %s: %w", f.Name(), err)
+	}
+	return f.Name(), nil
+}
+
+// removeStaleLock removes the lock file at path held by the process with the
+// given PID, which is no longer running. As another process may replace the
+// stale lock meanwhile, the lock file is first moved aside, and put back if
+// it turns out to be held by another process.
+func removeStaleLock(path string, pid int) error {
+	stale := fmt.Sprintf("%s.stale.%d", path, os.Getpid())
+	if err := os.Rename(path, stale); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to remove stale lock file %s: %w", path, err)
+	}
+	defer os.Remove(stale)
+	got, err := readLockPID(stale)
+	if err != nil {
+		return err
+	}
+	if got != pid {
+		// Another process took the lock after the stale one was found.
+		if err := os.Link(stale, path); err != nil && !errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("failed to restore lock file %s: %w", path, err)
+		}
+		return nil
+	}
+	slog.Warn("removing stale lock", "path", path, "pid", pid)
+	return nil
+}
+
+// findGitDir returns the git directory of the repository at repoDir. The .git
+// entry of a linked worktree or submodule is a file pointing to the git
+// directory.
+func findGitDir(repoDir string) (string, error) {
+	dotGit := filepath.Join(repoDir, ".git")
+	info, err := os.Stat(dotGit)
+	if err != nil {
+		return "", fmt.Errorf("failed to find git directory of %s: %w", repoDir, err)
+	}
+	if info.IsDir() {
+		return dotGit, nil
+	}
+	data, err := os.ReadFile(dotGit)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", dotGit, err)
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return "", fmt.Errorf("invalid git directory reference in %s", dotGit)
+	}
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(repoDir, gitDir)
+	}
+	return gitDir, nil
+}
+
+// readLockPID returns the PID recorded in the lock file at path, or 0 if the
+// lock file no longer exists. A lock file which cannot be read or does not
+// hold a PID is never considered stale, and yields an error.
+func readLockPID(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read lock file %s: %w", path, err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("lock file %s does not hold a PID; remove it if no librarian process is running, or use -no-lock to skip locking", path)
+	}
+	return pid, nil
+}
+
+// runWithRepoLock runs run while holding the lock on the language repository
+// at repoDir, unless cfg.NoLock is set.
+func runWithRepoLock(cfg *legacyconfig.Config, repoDir string, run func() error) error {
+	if cfg.NoLock {
+		return run()
+	}
+	release, err := acquireRepoLock(repoDir)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return run()
+}
@@ -0,0 +1,248 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+)
+
+func TestAcquireRepoLock(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name       string
+		lock       string
+		wantErr    bool
+		wantErrMsg string
+		emptyLock  bool
+	}{
+		{
+			name: "no lock",
+		},
+		{
+			name: "stale lock",
+			// PIDs are limited to 2^22 on Linux, so this PID is never running.
+			lock: "99999999\n",
+		},
+		{
+			name:       "invalid lock",
+			lock:       "not a pid",
+			wantErr:    true,
+			wantErrMsg: "does not hold a PID",
+		},
+		{
+			name:       "empty lock",
+			wantErr:    true,
+			wantErrMsg: "does not hold a PID",
+			emptyLock:  true,
+		},
+		{
+			name:       "held lock",
+			lock:       fmt.Sprintf("%d\n", os.Getpid()),
+			wantErr:    true,
+			wantErrMsg: "is locked by another librarian process",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repoDir := t.TempDir()
+			lockPath := filepath.Join(repoDir, ".git", lockFile)
+			if err := os.MkdirAll(filepath.Dir(lockPath), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if test.lock != "" || test.emptyLock {
+				writeTestFile(t, lockPath, test.lock)
+			}
+			release, err := acquireRepoLock(repoDir)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("acquireRepoLock() should fail")
+				}
+				if !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Errorf("want error message %q, got %q", test.wantErrMsg, err.Error())
+				}
+				if _, err := os.Stat(lockPath); err != nil {
+					t.Errorf("lock file should be kept, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(lockPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(fmt.Sprintf("%d\n", os.Getpid()), string(got)); diff != "" {
+				t.Errorf("lock file mismatch (-want +got):\n%s", diff)
+			}
+			release()
+			entries, err := os.ReadDir(filepath.Dir(lockPath))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 0 {
+				t.Errorf("lock files should be removed, got %v", entries)
+			}
+		})
+	}
+}
+
+func TestRemoveStaleLock(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name      string
+		lock      string
+		want      bool
+		wantFiles int
+	}{
+		{
+			name: "stale lock",
+			lock: "99999999\n",
+		},
+		{
+			name:      "lock taken by another process",
+			lock:      "12345\n",
+			want:      true,
+			wantFiles: 1,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			lockPath := filepath.Join(t.TempDir(), lockFile)
+			writeTestFile(t, lockPath, test.lock)
+			if err := removeStaleLock(lockPath, 99999999); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(lockPath)
+			if test.want {
+				if err != nil {
+					t.Fatalf("lock file should be restored, got %v", err)
+				}
+				if diff := cmp.Diff(test.lock, string(got)); diff != "" {
+					t.Errorf("lock file mismatch (-want +got):\n%s", diff)
+				}
+			} else if !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("lock file should be removed, got %v", err)
+			}
+			entries, err := os.ReadDir(filepath.Dir(lockPath))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != test.wantFiles {
+				t.Errorf("got %d files, want %d", len(entries), test.wantFiles)
+			}
+		})
+	}
+}
+
+func TestFindGitDir(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name    string
+		dotGit  string
+		isFile  bool
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "directory",
+			want: ".git",
+		},
+		{
+			name:   "relative gitdir file",
+			dotGit: "gitdir: ../main/.git/worktrees/feature\n",
+			isFile: true,
+			want:   "../main/.git/worktrees/feature",
+		},
+		{
+			name:    "invalid gitdir file",
+			dotGit:  "something else",
+			isFile:  true,
+			wantErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repoDir := t.TempDir()
+			dotGit := filepath.Join(repoDir, ".git")
+			if test.isFile {
+				writeTestFile(t, dotGit, test.dotGit)
+			} else if err := os.Mkdir(dotGit, 0755); err != nil {
+				t.Fatal(err)
+			}
+			got, err := findGitDir(repoDir)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("findGitDir() should fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(filepath.Join(repoDir, test.want), got); diff != "" {
+				t.Errorf("findGitDir() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRunWithRepoLock(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name   string
+		noLock bool
+	}{
+		{
+			name: "lock",
+		},
+		{
+			name:   "no lock",
+			noLock: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repoDir := t.TempDir()
+			if err := os.Mkdir(filepath.Join(repoDir, ".git"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			lockPath := filepath.Join(repoDir, ".git", lockFile)
+			var locked bool
+			cfg := &legacyconfig.Config{NoLock: test.noLock}
+			if err := runWithRepoLock(cfg, repoDir, func() error {
+				_, err := os.Stat(lockPath)
+				locked = err == nil
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(!test.noLock, locked); diff != "" {
+				t.Errorf("locked mismatch (-want +got):\n%s", diff)
+			}
+			if _, err := os.Stat(lockPath); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("lock file should be removed, got %v", err)
+			}
+		})
+	}
+}
@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sync"
 
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacydocker"
@@ -36,13 +38,17 @@ type mockGitHubClient struct {
 	rawErr                  error
 	createPullRequestCalls  int
 	addLabelsToIssuesCalls  int
+	requestedReviewers      []string
+	requestReviewersErr     error
 	getLabelsCalls          int
 	replaceLabelsCalls      int
 	searchPullRequestsCalls int
+	searchPullRequestsQuery string
 	getPullRequestCalls     int
 	createReleaseCalls      int
 	createIssueCalls        int
 	createTagCalls          int
+	annotatedTags           map[string]string
 	createPullRequestErr    error
 	addLabelsToIssuesErr    error
 	getLabelsErr            error
@@ -52,13 +58,38 @@ type mockGitHubClient struct {
 	createReleaseErr        error
 	createIssueErr          error
 	createTagErr            error
-	createdPR               *legacygithub.PullRequestMetadata
-	labels                  []string
-	pullRequests            []*legacygithub.PullRequest
-	pullRequest             *legacygithub.PullRequest
-	createdRelease          *legacygithub.RepositoryRelease
-	librarianState          *legacyconfig.LibrarianState
-	librarianConfig         *legacyconfig.LibrarianConfig
+	createAnnotatedTagErr   error
+	createBranchErr         error
+	updateFileErr           error
+	createdBranches         []string
+	// branchHeads maps branch names to the SHA of their head commit.
+	branchHeads      map[string]string
+	getBranchHeadErr error
+	// taggedCommits maps the name of each tag created along with a release
+	// to the commit it targets.
+	taggedCommits map[string]string
+	// updatedFiles maps the branch and path of each updated file, joined
+	// with a colon, to its content.
+	updatedFiles       map[string]string
+	createdPR          *legacygithub.PullRequestMetadata
+	labels             []string
+	pullRequests       []*legacygithub.PullRequest
+	pullRequest        *legacygithub.PullRequest
+	createdRelease     *legacygithub.RepositoryRelease
+	createdReleaseBody string
+	librarianState     *legacyconfig.LibrarianState
+	librarianConfig    *legacyconfig.LibrarianConfig
+
+	// releases maps the tag name of each existing release to the release.
+	releases           map[string]*legacygithub.RepositoryRelease
+	getReleaseByTagErr error
+	// existingTags holds the names of the tags which already exist.
+	existingTags []string
+	tagExistsErr error
+	// updatedPullRequestBodies maps the number of each pull request whose
+	// body was updated to its new body.
+	updatedPullRequestBodies map[int]string
+	updatePullRequestBodyErr error
 }
 
 func (m *mockGitHubClient) GetRawContent(ctx context.Context, path, ref string) ([]byte, error) {
@@ -86,6 +117,11 @@ func (m *mockGitHubClient) AddLabelsToIssue(ctx context.Context, repo *legacygit
 	return m.addLabelsToIssuesErr
 }
 
+func (m *mockGitHubClient) RequestReviewers(ctx context.Context, repo *legacygithub.Repository, number int, reviewers []string) error {
+	m.requestedReviewers = append(m.requestedReviewers, reviewers...)
+	return m.requestReviewersErr
+}
+
 func (m *mockGitHubClient) GetLabels(ctx context.Context, number int) ([]string, error) {
 	m.getLabelsCalls++
 	return m.labels, m.getLabelsErr
@@ -98,9 +134,18 @@ func (m *mockGitHubClient) ReplaceLabels(ctx context.Context, number int, labels
 
 func (m *mockGitHubClient) SearchPullRequests(ctx context.Context, query string) ([]*legacygithub.PullRequest, error) {
 	m.searchPullRequestsCalls++
+	m.searchPullRequestsQuery = query
 	return m.pullRequests, m.searchPullRequestsErr
 }
 
+func (m *mockGitHubClient) UpdatePullRequestBody(ctx context.Context, number int, body string) error {
+	if m.updatedPullRequestBodies == nil {
+		m.updatedPullRequestBodies = make(map[int]string)
+	}
+	m.updatedPullRequestBodies[number] = body
+	return m.updatePullRequestBodyErr
+}
+
 func (m *mockGitHubClient) GetPullRequest(ctx context.Context, number int) (*legacygithub.PullRequest, error) {
 	m.getPullRequestCalls++
 	return m.pullRequest, m.getPullRequestErr
@@ -108,9 +153,22 @@ func (m *mockGitHubClient) GetPullRequest(ctx context.Context, number int) (*leg
 
 func (m *mockGitHubClient) CreateRelease(ctx context.Context, tagName, releaseName, body, commitish string) (*legacygithub.RepositoryRelease, error) {
 	m.createReleaseCalls++
+	if m.taggedCommits == nil {
+		m.taggedCommits = make(map[string]string)
+	}
+	m.taggedCommits[tagName] = commitish
+	m.createdReleaseBody = body
 	return m.createdRelease, m.createReleaseErr
 }
 
+func (m *mockGitHubClient) GetReleaseByTag(ctx context.Context, tagName string) (*legacygithub.RepositoryRelease, error) {
+	return m.releases[tagName], m.getReleaseByTagErr
+}
+
+func (m *mockGitHubClient) TagExists(ctx context.Context, tagName string) (bool, error) {
+	return slices.Contains(m.existingTags, tagName), m.tagExistsErr
+}
+
 func (m *mockGitHubClient) CreateIssueComment(ctx context.Context, number int, comment string) error {
 	m.createIssueCalls++
 	return m.createIssueErr
@@ -121,9 +179,36 @@ func (m *mockGitHubClient) CreateTag(ctx context.Context, tagName, commitish str
 	return m.createTagErr
 }
 
+func (m *mockGitHubClient) CreateAnnotatedTag(ctx context.Context, tagName, message, commitish string) error {
+	if m.annotatedTags == nil {
+		m.annotatedTags = make(map[string]string)
+	}
+	m.annotatedTags[tagName] = message
+	return m.createAnnotatedTagErr
+}
+
+func (m *mockGitHubClient) CreateBranch(ctx context.Context, branch, commitish string) error {
+	m.createdBranches = append(m.createdBranches, branch)
+	return m.createBranchErr
+}
+
+func (m *mockGitHubClient) GetBranchHead(ctx context.Context, branch string) (string, error) {
+	return m.branchHeads[branch], m.getBranchHeadErr
+}
+
+func (m *mockGitHubClient) UpdateFile(ctx context.Context, branch, path, message string, content []byte) error {
+	if m.updatedFiles == nil {
+		m.updatedFiles = make(map[string]string)
+	}
+	m.updatedFiles[branch+":"+path] = string(content)
+	return m.updateFileErr
+}
+
 // mockContainerClient is a mock implementation of the ContainerClient interface for testing.
 type mockContainerClient struct {
 	ContainerClient
+	// mu guards the mock when libraries are generated concurrently.
+	mu             sync.Mutex
 	generateCalls  int
 	buildCalls     int
 	configureCalls int
@@ -157,17 +242,22 @@ type mockContainerClient struct {
 	// Set this value if you want the configure-response
 	// has library source roots and remove regex.
 	configureLibraryPaths []string
+	// The last configure request
+	configureRequest *legacydocker.ConfigureRequest
 	// The last generation request
 	generateRequest *legacydocker.GenerateRequest
 }
 
 func (m *mockContainerClient) Build(ctx context.Context, request *legacydocker.BuildRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.buildCalls++
 	if m.noBuildResponse {
 		return m.buildErr
 	}
 	// Write a build-response.json unless we're configured not to.
-	if err := os.MkdirAll(filepath.Join(request.RepoDir, ".librarian"), 0755); err != nil {
+	librarianDir := mockLibrarianDir(request.LibrarianDir, request.RepoDir)
+	if err := os.MkdirAll(librarianDir, 0755); err != nil {
 		return err
 	}
 
@@ -175,7 +265,7 @@ func (m *mockContainerClient) Build(ctx context.Context, request *legacydocker.B
 	if m.wantErrorMsg {
 		libraryStr = "{error: simulated error message}"
 	}
-	if err := os.WriteFile(filepath.Join(request.RepoDir, ".librarian", legacyconfig.BuildResponse), []byte(libraryStr), 0755); err != nil {
+	if err := os.WriteFile(filepath.Join(librarianDir, legacyconfig.BuildResponse), []byte(libraryStr), 0755); err != nil {
 		return err
 	}
 
@@ -188,8 +278,17 @@ func (m *mockContainerClient) Build(ctx context.Context, request *legacydocker.B
 	return m.buildErr
 }
 
+// mockLibrarianDir returns the directory the container writes responses to.
+func mockLibrarianDir(librarianDir, repoDir string) string {
+	if librarianDir != "" {
+		return librarianDir
+	}
+	return filepath.Join(repoDir, legacyconfig.LibrarianDir)
+}
+
 func (m *mockContainerClient) Configure(ctx context.Context, request *legacydocker.ConfigureRequest) (string, error) {
 	m.configureCalls++
+	m.configureRequest = request
 
 	if m.noConfigureResponse {
 		return "", m.configureErr
@@ -242,6 +341,8 @@ func (m *mockContainerClient) Configure(ctx context.Context, request *legacydock
 }
 
 func (m *mockContainerClient) Generate(ctx context.Context, request *legacydocker.GenerateRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.generateCalls++
 	m.generateRequest = request
 
@@ -250,7 +351,8 @@ func (m *mockContainerClient) Generate(ctx context.Context, request *legacydocke
 	}
 
 	// // Write a generate-response.json unless we're configured not to.
-	if err := os.MkdirAll(filepath.Join(request.RepoDir, legacyconfig.LibrarianDir), 0755); err != nil {
+	librarianDir := mockLibrarianDir(request.LibrarianDir, request.RepoDir)
+	if err := os.MkdirAll(librarianDir, 0755); err != nil {
 		return err
 	}
 
@@ -264,7 +366,7 @@ func (m *mockContainerClient) Generate(ctx context.Context, request *legacydocke
 		return err
 	}
 
-	if err := os.WriteFile(filepath.Join(request.RepoDir, legacyconfig.LibrarianDir, legacyconfig.GenerateResponse), b, 0755); err != nil {
+	if err := os.WriteFile(filepath.Join(librarianDir, legacyconfig.GenerateResponse), b, 0755); err != nil {
 		return err
 	}
 
@@ -330,8 +432,11 @@ type MockRepository struct {
 	RemotesValue                           []*legacygitrepo.Remote
 	RemotesError                           error
 	CommitCalls                            int
+	AmendCalls                             int
 	ResetHardCalls                         int
 	LastCommitMessage                      string
+	LastCommitAuthor                       *legacygitrepo.Signature
+	LastCommitCommitter                    *legacygitrepo.Signature
 	GetCommitError                         error
 	GetLatestCommitError                   error
 	GetCommitByHash                        map[string]*legacygitrepo.Commit
@@ -343,23 +448,38 @@ type MockRepository struct {
 	GetCommitsForPathsSinceLastGenByCommit map[string][]*legacygitrepo.Commit
 	GetCommitsForPathsSinceLastGenByPath   map[string][]*legacygitrepo.Commit
 	GetLatestCommitByPath                  map[string]*legacygitrepo.Commit
+	GetTagCommitByTag                      map[string]*legacygitrepo.Commit
+	GetTagCommitError                      error
 	GetCommitsForPathsSinceLastGenError    error
 	ChangedFilesInCommitValue              []string
 	ChangedFilesInCommitValueByHash        map[string][]string
 	ChangedFilesInCommitError              error
 	ChangedFilesValue                      []string
 	ChangedFilesError                      error
+	DiffPaths                              []string
+	DiffValue                              string
+	DiffError                              error
 	NewAndDeletedFilesValue                []string
 	NewAndDeletedFilesError                error
 	CreateBranchAndCheckoutError           error
 	CheckoutCommitAndCreateBranchError     error
 	PushCalls                              int
+	PushedBranch                           string
 	PushError                              error
+	RebaseOntoCalls                        int
+	RebaseOntoError                        error
+	FetchUpstreamCalls                     int
+	FetchUpstreamBehind                    int
+	FetchUpstreamError                     error
 	RestoreError                           error
 	HeadHashValue                          string
+	CurrentBranchValue                     string
 	HeadHashError                          error
 	CheckoutCalls                          int
 	CheckoutError                          error
+	AddWorktreeCommits                     []string
+	AddWorktreeError                       error
+	RemoveWorktreeCalls                    int
 	ResetHardError                         error
 	DeleteLocalBranchesCalls               int
 	DeleteLocalBranchesError               error
@@ -380,6 +500,10 @@ func (m *MockRepository) HeadHash() (string, error) {
 	return m.HeadHashValue, nil
 }
 
+func (m *MockRepository) CurrentBranch() (string, error) {
+	return m.CurrentBranchValue, nil
+}
+
 func (m *MockRepository) IsClean() (bool, error) {
 	if m.IsCleanError != nil {
 		return false, m.IsCleanError
@@ -400,6 +524,17 @@ func (m *MockRepository) Commit(msg string) error {
 	return m.CommitError
 }
 
+func (m *MockRepository) CommitWithAuthor(msg string, author, committer *legacygitrepo.Signature) error {
+	m.LastCommitAuthor = author
+	m.LastCommitCommitter = committer
+	return m.Commit(msg)
+}
+
+func (m *MockRepository) AmendWithAuthor(msg string, author, committer *legacygitrepo.Signature) error {
+	m.AmendCalls++
+	return m.CommitWithAuthor(msg, author, committer)
+}
+
 func (m *MockRepository) Remotes() ([]*legacygitrepo.Remote, error) {
 	if m.RemotesError != nil {
 		return nil, m.RemotesError
@@ -439,6 +574,16 @@ func (m *MockRepository) GetLatestCommit(path string) (*legacygitrepo.Commit, er
 	return nil, errors.New("should not reach here")
 }
 
+func (m *MockRepository) GetTagCommit(tagName string) (*legacygitrepo.Commit, error) {
+	if m.GetTagCommitError != nil {
+		return nil, m.GetTagCommitError
+	}
+	if commit, ok := m.GetTagCommitByTag[tagName]; ok {
+		return commit, nil
+	}
+	return nil, fmt.Errorf("tag %s not found", tagName)
+}
+
 func (m *MockRepository) GetCommitsForPathsSinceTag(paths []string, tagName string) ([]*legacygitrepo.Commit, error) {
 	m.GetCommitsForPathsSinceTagLastTagName = tagName
 	if m.GetCommitsForPathsSinceTagError != nil {
@@ -495,6 +640,14 @@ func (m *MockRepository) ChangedFiles() ([]string, error) {
 	return m.ChangedFilesValue, nil
 }
 
+func (m *MockRepository) Diff(paths []string) (string, error) {
+	m.DiffPaths = paths
+	if m.DiffError != nil {
+		return "", m.DiffError
+	}
+	return m.DiffValue, nil
+}
+
 func (m *MockRepository) NewAndDeletedFiles() ([]string, error) {
 	if m.NewAndDeletedFilesError != nil {
 		return nil, m.NewAndDeletedFilesError
@@ -518,12 +671,23 @@ func (m *MockRepository) CheckoutCommitAndCreateBranch(name, commitHash string)
 
 func (m *MockRepository) Push(name string) error {
 	m.PushCalls++
+	m.PushedBranch = name
 	if m.PushError != nil {
 		return m.PushError
 	}
 	return nil
 }
 
+func (m *MockRepository) RebaseOnto(branchName string, committer *legacygitrepo.Signature) error {
+	m.RebaseOntoCalls++
+	return m.RebaseOntoError
+}
+
+func (m *MockRepository) FetchUpstream(branchName string) (int, error) {
+	m.FetchUpstreamCalls++
+	return m.FetchUpstreamBehind, m.FetchUpstreamError
+}
+
 func (m *MockRepository) Restore(paths []string) error {
 	return m.RestoreError
 }
@@ -562,6 +726,22 @@ func (m *mockImagesClient) FindLatest(ctx context.Context, imageName string) (st
 	return m.latestImage, m.err
 }
 
+type mockPublisher struct {
+	// mu guards the messages published by concurrent generations.
+	mu       sync.Mutex
+	err      error
+	topics   []string
+	messages [][]byte
+}
+
+func (m *mockPublisher) Publish(ctx context.Context, topic string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.topics = append(m.topics, topic)
+	m.messages = append(m.messages, data)
+	return m.err
+}
+
 func (m *MockRepository) GetHashForPath(commitHash, path string) (string, error) {
 	if m.GetHashForPathError != nil {
 		return "", m.GetHashForPathError
@@ -579,6 +759,16 @@ func (m *MockRepository) GetHashForPath(commitHash, path string) (string, error)
 	return "", fmt.Errorf("should not reach here: GetHashForPath called with unhandled input (commitHash: %q, path: %q)", commitHash, path)
 }
 
+func (m *MockRepository) AddWorktree(dir, commitHash string) error {
+	m.AddWorktreeCommits = append(m.AddWorktreeCommits, commitHash)
+	return m.AddWorktreeError
+}
+
+func (m *MockRepository) RemoveWorktree(dir string) error {
+	m.RemoveWorktreeCalls++
+	return nil
+}
+
 func (m *MockRepository) ResetSoft(commit string) error {
 	m.ResetSoftCalls++
 	return m.ResetSoftError
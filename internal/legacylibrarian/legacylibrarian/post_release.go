@@ -0,0 +1,117 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacygithub"
+)
+
+// runPostReleaseUpdate records the versions of the libraries released by the
+// pull request in the versions file configured in config.yaml. The update is
+// committed to the base branch of the pull request, or to a new branch for
+// which a follow-up pull request is opened.
+func (r *tagRunner) runPostReleaseUpdate(ctx context.Context, p *legacygithub.PullRequest, librarianConfig *legacyconfig.LibrarianConfig, releases []libraryRelease) error {
+	if librarianConfig == nil || librarianConfig.PostRelease == nil {
+		slog.Warn("no post_release configured in config.yaml, skipping post-release update")
+		return nil
+	}
+	postRelease := librarianConfig.PostRelease
+	targetBranch := p.GetBase().GetRef()
+	// A follow-up pull request starts from the release commit, so the file is
+	// read at that commit too.
+	ref := targetBranch
+	if postRelease.CreatePullRequest {
+		ref = p.GetMergeCommitSHA()
+	}
+	content, err := r.ghClient.GetRawContent(ctx, postRelease.VersionsFile, ref)
+	// A missing versions file is created with the released versions.
+	if errors.Is(err, legacygithub.ErrNotFound) {
+		slog.Info("versions file not found, creating it", "path", postRelease.VersionsFile)
+		content, err = nil, nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read versions file %s: %w", postRelease.VersionsFile, err)
+	}
+	updated := updateVersions(content, postRelease.VersionsFormat, releases)
+	if bytes.Equal(content, updated) {
+		slog.Info("versions file already up to date", "path", postRelease.VersionsFile)
+		return nil
+	}
+
+	message := fmt.Sprintf("chore: update %s for release #%d", postRelease.VersionsFile, p.GetNumber())
+	branch := targetBranch
+	if postRelease.CreatePullRequest {
+		branch = fmt.Sprintf("librarian-post-release-%d", p.GetNumber())
+		if err := r.ghClient.CreateBranch(ctx, branch, p.GetMergeCommitSHA()); err != nil {
+			return fmt.Errorf("failed to create branch %s: %w", branch, err)
+		}
+	}
+	if err := r.ghClient.UpdateFile(ctx, branch, postRelease.VersionsFile, message, updated); err != nil {
+		return fmt.Errorf("failed to update versions file %s: %w", postRelease.VersionsFile, err)
+	}
+	if !postRelease.CreatePullRequest {
+		return nil
+	}
+	body := fmt.Sprintf("Updates %s with the versions of the libraries released by #%d.", postRelease.VersionsFile, p.GetNumber())
+	if _, err := r.ghClient.CreatePullRequest(ctx, r.repo, branch, targetBranch, message, body, false); err != nil {
+		return fmt.Errorf("failed to create post-release pull request: %w", err)
+	}
+	return nil
+}
+
+// updateVersions returns the content of a versions file with the line of each
+// released library set to its released version. Lines are written with format,
+// in which {id} and {version} are replaced with the library ID and version. The
+// line of a library is the one matching format for its ID and any version.
+// Libraries without a line are appended at the end of the file.
+func updateVersions(content []byte, format string, releases []libraryRelease) []byte {
+	if format == "" {
+		format = legacyconfig.DefaultVersionsFormat
+	}
+	var lines []string
+	if len(content) > 0 {
+		lines = strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	}
+	for _, release := range releases {
+		line := strings.NewReplacer("{id}", release.Library, "{version}", release.Version).Replace(format)
+		pattern := versionsLineRegexp(format, release.Library)
+		i := slices.IndexFunc(lines, pattern.MatchString)
+		if i < 0 {
+			lines = append(lines, line)
+			continue
+		}
+		lines[i] = line
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// versionsLineRegexp returns a regular expression matching the line of the
+// library with the given ID in a versions file with the given format.
+func versionsLineRegexp(format, libraryID string) *regexp.Regexp {
+	r := strings.NewReplacer(
+		regexp.QuoteMeta("{id}"), regexp.QuoteMeta(libraryID),
+		regexp.QuoteMeta("{version}"), `\S+`)
+	return regexp.MustCompile("^" + r.Replace(regexp.QuoteMeta(format)) + "$")
+}
@@ -0,0 +1,249 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	gh "github.com/google/go-github/v69/github"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacygithub"
+)
+
+func TestUpdateVersions(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		content  string
+		format   string
+		releases []libraryRelease
+		want     string
+	}{
+		{
+			name:    "update existing line",
+			content: "# Format: id:version\nlib-a:1.0.0\nlib-b:2.0.0\n",
+			releases: []libraryRelease{
+				{Library: "lib-b", Version: "2.1.0"},
+			},
+			want: "# Format: id:version\nlib-a:1.0.0\nlib-b:2.1.0\n",
+		},
+		{
+			name:    "append missing library",
+			content: "lib-a:1.0.0\n",
+			releases: []libraryRelease{
+				{Library: "lib-a", Version: "1.0.1"},
+				{Library: "lib-c", Version: "0.1.0"},
+			},
+			want: "lib-a:1.0.1\nlib-c:0.1.0\n",
+		},
+		{
+			name: "empty file",
+			releases: []libraryRelease{
+				{Library: "lib-a", Version: "1.0.0"},
+			},
+			want: "lib-a:1.0.0\n",
+		},
+		{
+			name:    "custom format",
+			content: "lib-a:1.0.0:1.0.0\nlib-a-extra:3.0.0:3.0.0\n",
+			format:  "{id}:{version}:{version}",
+			releases: []libraryRelease{
+				{Library: "lib-a", Version: "1.1.0"},
+			},
+			want: "lib-a:1.1.0:1.1.0\nlib-a-extra:3.0.0:3.0.0\n",
+		},
+		{
+			name:    "library ID with regexp characters",
+			content: "lib.a==1.0.0\nlibxa==2.0.0\n",
+			format:  "{id}=={version}",
+			releases: []libraryRelease{
+				{Library: "lib.a", Version: "1.0.1"},
+			},
+			want: "lib.a==1.0.1\nlibxa==2.0.0\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := updateVersions([]byte(test.content), test.format, test.releases)
+			if diff := cmp.Diff(test.want, string(got)); diff != "" {
+				t.Errorf("updateVersions() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRunPostReleaseUpdate(t *testing.T) {
+	prNumber := 123
+	mergeCommitSHA := "abcdef"
+	branch := "main"
+	pr := &legacygithub.PullRequest{
+		Number:         &prNumber,
+		MergeCommitSHA: &mergeCommitSHA,
+		Base: &gh.PullRequestBranch{
+			Ref: &branch,
+		},
+	}
+	releases := []libraryRelease{
+		{Library: "google-cloud-storage", Version: "1.2.3"},
+	}
+
+	for _, test := range []struct {
+		name                   string
+		ghClient               *mockGitHubClient
+		librarianConfig        *legacyconfig.LibrarianConfig
+		wantUpdatedFiles       map[string]string
+		wantCreatedBranches    []string
+		wantCreatePullRequests int
+		wantErrMsg             string
+	}{
+		{
+			name: "commit to base branch",
+			ghClient: &mockGitHubClient{
+				rawContent: []byte("google-cloud-storage:1.2.2\n"),
+			},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				PostRelease: &legacyconfig.PostReleaseConfig{
+					VersionsFile: "versions.txt",
+				},
+			},
+			wantUpdatedFiles: map[string]string{
+				"main:versions.txt": "google-cloud-storage:1.2.3\n",
+			},
+		},
+		{
+			name: "open follow-up pull request",
+			ghClient: &mockGitHubClient{
+				rawContent: []byte("google-cloud-storage:1.2.2:1.2.2\n"),
+			},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				PostRelease: &legacyconfig.PostReleaseConfig{
+					VersionsFile:      "versions.txt",
+					VersionsFormat:    "{id}:{version}:{version}",
+					CreatePullRequest: true,
+				},
+			},
+			wantUpdatedFiles: map[string]string{
+				"librarian-post-release-123:versions.txt": "google-cloud-storage:1.2.3:1.2.3\n",
+			},
+			wantCreatedBranches:    []string{"librarian-post-release-123"},
+			wantCreatePullRequests: 1,
+		},
+		{
+			name: "already up to date",
+			ghClient: &mockGitHubClient{
+				rawContent: []byte("google-cloud-storage:1.2.3\n"),
+			},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				PostRelease: &legacyconfig.PostReleaseConfig{
+					VersionsFile: "versions.txt",
+				},
+			},
+		},
+		{
+			name:     "no post release configured",
+			ghClient: &mockGitHubClient{},
+		},
+		{
+			name: "create missing versions file",
+			ghClient: &mockGitHubClient{
+				rawErr: fmt.Errorf("%w: no file named versions.txt found in .", legacygithub.ErrNotFound),
+			},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				PostRelease: &legacyconfig.PostReleaseConfig{
+					VersionsFile: "versions.txt",
+				},
+			},
+			wantUpdatedFiles: map[string]string{
+				"main:versions.txt": "google-cloud-storage:1.2.3\n",
+			},
+		},
+		{
+			name: "read versions file fails",
+			ghClient: &mockGitHubClient{
+				rawErr: errors.New("internal server error"),
+			},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				PostRelease: &legacyconfig.PostReleaseConfig{
+					VersionsFile: "versions.txt",
+				},
+			},
+			wantErrMsg: "failed to read versions file versions.txt",
+		},
+		{
+			name: "update versions file fails",
+			ghClient: &mockGitHubClient{
+				rawContent:    []byte("google-cloud-storage:1.2.2\n"),
+				updateFileErr: errors.New("conflict"),
+			},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				PostRelease: &legacyconfig.PostReleaseConfig{
+					VersionsFile: "versions.txt",
+				},
+			},
+			wantUpdatedFiles: map[string]string{
+				"main:versions.txt": "google-cloud-storage:1.2.3\n",
+			},
+			wantErrMsg: "failed to update versions file versions.txt",
+		},
+		{
+			name: "create pull request fails",
+			ghClient: &mockGitHubClient{
+				rawContent:           []byte("google-cloud-storage:1.2.2\n"),
+				createPullRequestErr: errors.New("create pull request error"),
+			},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				PostRelease: &legacyconfig.PostReleaseConfig{
+					VersionsFile:      "versions.txt",
+					CreatePullRequest: true,
+				},
+			},
+			wantUpdatedFiles: map[string]string{
+				"librarian-post-release-123:versions.txt": "google-cloud-storage:1.2.3\n",
+			},
+			wantCreatedBranches:    []string{"librarian-post-release-123"},
+			wantCreatePullRequests: 1,
+			wantErrMsg:             "failed to create post-release pull request",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := &tagRunner{
+				ghClient: test.ghClient,
+			}
+			err := r.runPostReleaseUpdate(t.Context(), pr, test.librarianConfig, releases)
+			if err != nil {
+				if test.wantErrMsg == "" {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("got %q, want contains %q", err, test.wantErrMsg)
+				}
+			} else if test.wantErrMsg != "" {
+				t.Fatalf("expected error containing %q, got nil", test.wantErrMsg)
+			}
+
+			if diff := cmp.Diff(test.wantUpdatedFiles, test.ghClient.updatedFiles); diff != "" {
+				t.Errorf("updated files mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.wantCreatedBranches, test.ghClient.createdBranches); diff != "" {
+				t.Errorf("created branches mismatch (-want +got):\n%s", diff)
+			}
+			if test.ghClient.createPullRequestCalls != test.wantCreatePullRequests {
+				t.Errorf("createPullRequestCalls = %v, want %v", test.ghClient.createPullRequestCalls, test.wantCreatePullRequests)
+			}
+		})
+	}
+}
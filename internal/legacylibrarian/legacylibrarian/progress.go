@@ -0,0 +1,89 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// progressLogger logs the progress of a command processing many libraries, at
+// most once per interval. Each log includes an estimate of the remaining time,
+// based on the average time taken per library so far.
+type progressLogger struct {
+	operation string
+	total     int
+	interval  time.Duration
+	logger    *slog.Logger
+	now       func() time.Time
+
+	mu      sync.Mutex
+	start   time.Time
+	lastLog time.Time
+	done    int
+}
+
+// newProgressLogger returns a progressLogger for an operation on total
+// libraries, starting now. An interval of zero disables logging.
+func newProgressLogger(operation string, total int, interval time.Duration) *progressLogger {
+	return newProgressLoggerWithClock(operation, total, interval, slog.Default(), time.Now)
+}
+
+func newProgressLoggerWithClock(operation string, total int, interval time.Duration, logger *slog.Logger, now func() time.Time) *progressLogger {
+	start := now()
+	return &progressLogger{
+		operation: operation,
+		total:     total,
+		interval:  interval,
+		logger:    logger,
+		now:       now,
+		start:     start,
+		lastLog:   start,
+	}
+}
+
+// libraryDone records that a library has been processed, and logs the progress
+// if at least interval has passed since the last log. It is safe to call from
+// multiple goroutines.
+func (p *progressLogger) libraryDone() {
+	if p.interval <= 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	now := p.now()
+	if now.Sub(p.lastLog) < p.interval {
+		return
+	}
+	p.lastLog = now
+	p.logger.Info("progress",
+		"operation", p.operation,
+		"done", p.done,
+		"total", p.total,
+		"elapsed", now.Sub(p.start).Round(time.Second),
+		"eta", p.eta(now).Round(time.Second))
+}
+
+// eta returns the estimated time remaining at now to process the libraries
+// not done yet, assuming they take the average time of those done so far.
+func (p *progressLogger) eta(now time.Time) time.Duration {
+	if p.done == 0 {
+		return 0
+	}
+	average := now.Sub(p.start) / time.Duration(p.done)
+	return average * time.Duration(p.total-p.done)
+}
@@ -0,0 +1,97 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestProgressLogger(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		interval time.Duration
+		// durations is the time taken by each library.
+		durations []time.Duration
+		want      []string
+	}{
+		{
+			name:      "log every library",
+			interval:  time.Second,
+			durations: []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second},
+			want: []string{
+				"level=INFO msg=progress operation=generate done=1 total=4 elapsed=10s eta=30s",
+				"level=INFO msg=progress operation=generate done=2 total=4 elapsed=30s eta=30s",
+				"level=INFO msg=progress operation=generate done=3 total=4 elapsed=1m0s eta=20s",
+			},
+		},
+		{
+			name:      "log at most once per interval",
+			interval:  time.Minute,
+			durations: []time.Duration{40 * time.Second, 40 * time.Second, 40 * time.Second, 40 * time.Second},
+			want: []string{
+				"level=INFO msg=progress operation=generate done=2 total=4 elapsed=1m20s eta=1m20s",
+				"level=INFO msg=progress operation=generate done=4 total=4 elapsed=2m40s eta=0s",
+			},
+		},
+		{
+			name:      "disabled",
+			durations: []time.Duration{time.Hour, time.Hour},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+			clock := func() time.Time { return now }
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+				ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+					if a.Key == slog.TimeKey {
+						return slog.Attr{}
+					}
+					return a
+				},
+			}))
+			progress := newProgressLoggerWithClock("generate", 4, test.interval, logger, clock)
+			for _, duration := range test.durations {
+				now = now.Add(duration)
+				progress.libraryDone()
+			}
+			var got []string
+			if buf.Len() > 0 {
+				got = strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("progress logs mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProgressLoggerETA(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	progress := newProgressLoggerWithClock("release stage", 10, time.Minute, slog.Default(), func() time.Time { return start })
+	if got := progress.eta(start.Add(time.Minute)); got != 0 {
+		t.Errorf("eta() with no library done = %v, want 0", got)
+	}
+	progress.done = 4
+	if got, want := progress.eta(start.Add(2*time.Minute)), 3*time.Minute; got != want {
+		t.Errorf("eta() = %v, want %v", got, want)
+	}
+}
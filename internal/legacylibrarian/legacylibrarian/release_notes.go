@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"html/template"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -28,6 +29,17 @@ import (
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacygithub"
 )
 
+const (
+	breakingChangesHeading = "Breaking Changes"
+	otherChangesHeading    = "Other Changes"
+
+	// changelogSectionStart and changelogSectionEnd delimit the section of a
+	// release pull request body that is managed by Librarian. Content outside
+	// of the section is preserved when the body is regenerated.
+	changelogSectionStart = "<!-- librarian:changelog -->"
+	changelogSectionEnd   = "<!-- /librarian:changelog -->"
+)
+
 var (
 	errPiperNotFound = errors.New("piper id not found")
 
@@ -46,7 +58,7 @@ var (
 	}
 
 	// commitTypeOrder is the order in which commit types should appear in release notes.
-	// Only these listed are included in release notes.
+	// Only these listed are included in release notes, after breaking changes.
 	commitTypeOrder = []string{
 		"feat",
 		"fix",
@@ -55,6 +67,15 @@ var (
 		"docs",
 	}
 
+	// otherChangesTypes are the commit types listed together under
+	// otherChangesHeading, after the types of commitTypeOrder, when
+	// list_other_changes is set in config.yaml.
+	otherChangesTypes = []string{
+		"chore",
+		"test",
+		"build",
+	}
+
 	shortSHA = func(sha string) string {
 		if len(sha) < 8 {
 			return sha
@@ -62,10 +83,22 @@ var (
 		return sha[:8]
 	}
 
+	// html/template strips comments from the template text, so the managed
+	// section start marker is emitted by a function instead.
 	releaseNotesTemplate = template.Must(template.New("releaseNotes").Funcs(template.FuncMap{
-		"shortSHA": shortSHA,
+		"shortSHA":       shortSHA,
+		"changelogStart": func() template.HTML { return changelogSectionStart },
+		// The notice is markdown, which must not be escaped.
+		"deprecationNotice": func(library *legacyconfig.LibraryState) template.HTML {
+			return template.HTML(deprecationNotice(library))
+		},
 	}).Parse(`PR created by the Librarian CLI to initialize a release. Merging this PR will auto trigger a release.
 
+{{changelogStart}}
+{{range .DeprecatedLibraries -}}
+{{deprecationNotice .}}
+
+{{end -}}
 Librarian Version: {{.LibrarianVersion}}
 Language Image: {{.ImageVersion}}
 {{ $prInfo := . }}
@@ -78,14 +111,20 @@ Language Image: {{.ImageVersion}}
 {{ range .Commits }}
 {{ if not .IsBulkCommit -}}
 {{ if .PiperCLNumber -}}
-* {{.Subject}} (PiperOrigin-RevId: {{.PiperCLNumber}}) ([{{shortSHA .CommitHash}}]({{"https://github.com/"}}{{$prInfo.RepoOwner}}/{{$prInfo.RepoName}}/commit/{{shortSHA .CommitHash}}))
+* {{.Subject}}{{if .FixesVersion}} (fixes {{.FixesVersion}}){{end}} (PiperOrigin-RevId: {{$prInfo.CLLink .PiperCLNumber}}) ([{{shortSHA .CommitHash}}]({{$prInfo.CommitURL .CommitHash}}))
 {{- else -}}
-* {{.Subject}} ([{{shortSHA .CommitHash}}]({{"https://github.com/"}}{{$prInfo.RepoOwner}}/{{$prInfo.RepoName}}/commit/{{shortSHA .CommitHash}}))
+* {{.Subject}}{{if .FixesVersion}} (fixes {{.FixesVersion}}){{end}} ([{{shortSHA .CommitHash}}]({{$prInfo.CommitURL .CommitHash}}))
 {{- end }}
 {{- end }}
 {{ end }}
 
 {{- end }}
+{{- if .OmittedChanges }}
+…and {{.OmittedChanges}} more {{if eq .OmittedChanges 1}}change{{else}}changes{{end}}, see the [full comparison]({{"https://github.com/"}}{{$prInfo.RepoOwner}}/{{$prInfo.RepoName}}/compare/{{.PreviousTag}}...{{.NewTag}}).
+{{ end }}
+{{- if .EarlierChanges }}
+…and {{.EarlierChanges}} earlier {{if eq .EarlierChanges 1}}change{{else}}changes{{end}}, see the [full comparison]({{"https://github.com/"}}{{$prInfo.RepoOwner}}/{{$prInfo.RepoName}}/compare/{{.PreviousTag}}...{{.NewTag}}).
+{{ end }}
 </details>
 
 
@@ -94,10 +133,10 @@ Language Image: {{.ImageVersion}}
 <details><summary>Bulk Changes</summary>
 {{ range .BulkChanges }}
 {{ if .PiperCLNumber -}}
-* {{.Type}}: {{.Subject}} (PiperOrigin-RevId: {{.PiperCLNumber}}) ([{{shortSHA .CommitHash}}]({{"https://github.com/"}}{{$prInfo.RepoOwner}}/{{$prInfo.RepoName}}/commit/{{shortSHA .CommitHash}}))
+* {{.Type}}: {{.Subject}} (PiperOrigin-RevId: {{$prInfo.CLLink .PiperCLNumber}}) ([{{shortSHA .CommitHash}}]({{$prInfo.CommitURL .CommitHash}}))
   Libraries: {{.LibraryIDs}}
 {{- else -}}
-* {{.Type}}: {{.Subject}} ([{{shortSHA .CommitHash}}]({{"https://github.com/"}}{{$prInfo.RepoOwner}}/{{$prInfo.RepoName}}/commit/{{shortSHA .CommitHash}}))
+* {{.Type}}: {{.Subject}} ([{{shortSHA .CommitHash}}]({{$prInfo.CommitURL .CommitHash}}))
   Libraries: {{.LibraryIDs}}
 {{- end }}
 {{- end }}
@@ -164,6 +203,36 @@ type releasePRBody struct {
 	Date             string
 	NoteSections     []*releaseNoteSection
 	BulkChanges      []*legacyconfig.Commit
+	// DeprecatedLibraries are the released libraries which are deprecated.
+	DeprecatedLibraries []*legacyconfig.LibraryState
+
+	// commitURLTemplate and clURLTemplate are the commit_url_template and
+	// cl_url_template of the librarian config, see CommitURL and CLLink.
+	commitURLTemplate string
+	clURLTemplate     string
+}
+
+// CommitURL returns the URL of the commit with the given hash, rendered from
+// the commit URL template if any, or on GitHub otherwise. The template comes
+// from the librarian config of the repository, and is not escaped so that
+// URLs such as "https://git.example.com/repo/+/{sha}" are kept as is.
+func (b *releasePRBody) CommitURL(hash string) template.HTML {
+	hash = template.HTMLEscapeString(hash)
+	if b.commitURLTemplate == "" {
+		return template.HTML(template.HTMLEscapeString(fmt.Sprintf("https://github.com/%s/%s/commit/%s", b.RepoOwner, b.RepoName, shortSHA(hash))))
+	}
+	return template.HTML(strings.NewReplacer("{sha}", hash, "{short_sha}", shortSHA(hash)).Replace(b.commitURLTemplate))
+}
+
+// CLLink returns the Piper CL number cl as a markdown link rendered from the
+// CL URL template, or cl itself if there is no template. As for CommitURL,
+// the template is not escaped.
+func (b *releasePRBody) CLLink(cl string) template.HTML {
+	cl = template.HTMLEscapeString(cl)
+	if b.clURLTemplate == "" {
+		return template.HTML(cl)
+	}
+	return template.HTML(fmt.Sprintf("[%s](%s)", cl, strings.ReplaceAll(b.clURLTemplate, "{cl}", cl)))
 }
 
 type releaseNoteSection struct {
@@ -172,6 +241,12 @@ type releaseNoteSection struct {
 	NewTag         string
 	NewVersion     string
 	CommitSections []*commitSection
+	// OmittedChanges is the number of changes left out of CommitSections
+	// because of the changelog entry cap.
+	OmittedChanges int
+	// EarlierChanges is the number of earlier commits which were not scanned
+	// because of the commit cap.
+	EarlierChanges int
 }
 
 type commitSection struct {
@@ -180,21 +255,32 @@ type commitSection struct {
 }
 
 // formatReleaseNotes generates the body for a release pull request.
-func formatReleaseNotes(state *legacyconfig.LibrarianState, ghRepo *legacygithub.Repository) (string, error) {
+// If maxEntries is positive, at most maxEntries changes are listed for each
+// library and the rest are summarized in a single line.
+// If list_other_changes is set in librarianConfig, chore, test and build
+// commits are listed in a single section instead of being left out. Tags are
+// named after the tag_format of librarianConfig, and commits and CL numbers
+// linked after its commit_url_template and cl_url_template, if any.
+func formatReleaseNotes(state *legacyconfig.LibrarianState, ghRepo *legacygithub.Repository, maxEntries int, librarianConfig *legacyconfig.LibrarianConfig) (string, error) {
+	listOtherChanges := librarianConfig != nil && librarianConfig.ListOtherChanges
 	librarianVersion := legacycli.Version()
 	// Separate commits to bulk changes (affects multiple libraries) or library-specific changes because they
 	// appear in different section in the release notes.
 	bulkChangesMap, libraryChanges := separateCommits(state)
 	// Process library specific changes.
 	var releaseSections []*releaseNoteSection
+	var deprecatedLibraries []*legacyconfig.LibraryState
 	for _, library := range state.Libraries {
 		if !library.ReleaseTriggered {
 			continue
 		}
+		if library.Deprecated {
+			deprecatedLibraries = append(deprecatedLibraries, library)
+		}
 		// No need to check the existence of the key, library.ID, because a library without library-specific changes
 		// may appear in the release notes, i.e., in the bulk changes section.
 		commits := libraryChanges[library.ID]
-		section := formatLibraryReleaseNotes(library, commits)
+		section := formatLibraryReleaseNotes(library, librarianConfig, commits, maxEntries, listOtherChanges)
 		releaseSections = append(releaseSections, section)
 	}
 	// Process bulk changes
@@ -207,13 +293,18 @@ func formatReleaseNotes(state *legacyconfig.LibrarianState, ghRepo *legacygithub
 	})
 
 	data := &releasePRBody{
-		LibrarianVersion: librarianVersion,
-		Date:             time.Now().Format("2006-01-02"),
-		RepoOwner:        ghRepo.Owner,
-		RepoName:         ghRepo.Name,
-		ImageVersion:     state.Image,
-		NoteSections:     releaseSections,
-		BulkChanges:      bulkChanges,
+		LibrarianVersion:    librarianVersion,
+		Date:                time.Now().Format("2006-01-02"),
+		RepoOwner:           ghRepo.Owner,
+		RepoName:            ghRepo.Name,
+		ImageVersion:        state.Image,
+		NoteSections:        releaseSections,
+		BulkChanges:         bulkChanges,
+		DeprecatedLibraries: deprecatedLibraries,
+	}
+	if librarianConfig != nil {
+		data.commitURLTemplate = librarianConfig.CommitURLTemplate
+		data.clURLTemplate = librarianConfig.CLURLTemplate
 	}
 
 	var out bytes.Buffer
@@ -221,27 +312,105 @@ func formatReleaseNotes(state *legacyconfig.LibrarianState, ghRepo *legacygithub
 		return "", fmt.Errorf("error executing template: %w", err)
 	}
 
-	return strings.TrimSpace(out.String()), nil
+	return strings.TrimSpace(out.String()) + "\n" + changelogSectionEnd, nil
+}
+
+// deprecationNotice returns the markdown banner announcing that library is
+// deprecated, followed by its deprecation message if any, or an empty string
+// if library is not deprecated.
+func deprecationNotice(library *legacyconfig.LibraryState) string {
+	if !library.Deprecated {
+		return ""
+	}
+	notice := fmt.Sprintf("> [!WARNING]\n> %s is deprecated.", library.ID)
+	if library.DeprecationMessage != "" {
+		message := strings.TrimSpace(library.DeprecationMessage)
+		notice += "\n> " + strings.ReplaceAll(message, "\n", "\n> ")
+	}
+	return notice
+}
+
+// mergeManagedSection replaces the managed changelog section of existing with
+// the one in generated, preserving any content outside of it, e.g., notes
+// added by hand. If either body has no managed section, generated is returned.
+func mergeManagedSection(existing, generated string) string {
+	start, end, ok := managedSectionBounds(existing)
+	if !ok {
+		return generated
+	}
+	generatedStart, generatedEnd, ok := managedSectionBounds(generated)
+	if !ok {
+		return generated
+	}
+	return existing[:start] + generated[generatedStart:generatedEnd] + existing[end:]
+}
+
+// managedSectionBounds returns the start and end offsets of the managed
+// changelog section in body, including its markers.
+func managedSectionBounds(body string) (int, int, bool) {
+	start := strings.Index(body, changelogSectionStart)
+	if start == -1 {
+		return 0, 0, false
+	}
+	end := strings.Index(body[start:], changelogSectionEnd)
+	if end == -1 {
+		return 0, 0, false
+	}
+	return start, start + end + len(changelogSectionEnd), true
 }
 
 // formatLibraryReleaseNotes generates release notes in Markdown format for a single library.
 // It returns the generated release notes and the new version string.
-func formatLibraryReleaseNotes(library *legacyconfig.LibraryState, commits []*legacyconfig.Commit) *releaseNoteSection {
+func formatLibraryReleaseNotes(library *legacyconfig.LibraryState, librarianConfig *legacyconfig.LibrarianConfig, commits []*legacyconfig.Commit, maxEntries int, listOtherChanges bool) *releaseNoteSection {
 	// The version should already be updated to the next version.
 	newVersion := library.Version
-	tagFormat := legacyconfig.DetermineTagFormat(library.ID, library, nil)
+	tagFormat := legacyconfig.DetermineTagFormat(library.ID, library, librarianConfig)
 	newTag := legacyconfig.FormatTag(tagFormat, library.ID, newVersion)
 	previousTag := legacyconfig.FormatTag(tagFormat, library.ID, library.PreviousVersion)
 
 	sort.Slice(commits, func(i, j int) bool {
 		return commits[i].CommitHash < commits[j].CommitHash
 	})
+	sections, omitted := truncateCommitSections(groupCommits(commits, listOtherChanges), maxEntries)
+	section := &releaseNoteSection{
+		LibraryID:      library.ID,
+		NewVersion:     newVersion,
+		PreviousTag:    previousTag,
+		NewTag:         newTag,
+		CommitSections: sections,
+		OmittedChanges: omitted,
+		EarlierChanges: library.EarlierChanges,
+	}
+
+	return section
+}
+
+// groupCommits groups commits into the sections of release notes, keeping the
+// order of commits within each section. Breaking changes come first, whatever
+// their type, followed by a section per type of commitTypeOrder. If
+// listOtherChanges is true, commits of otherChangesTypes follow in a single
+// section. Commits of any other type are left out.
+func groupCommits(commits []*legacyconfig.Commit, listOtherChanges bool) []*commitSection {
+	var breaking, other []*legacyconfig.Commit
 	commitsByType := make(map[string][]*legacyconfig.Commit)
 	for _, commit := range commits {
-		commitsByType[commit.Type] = append(commitsByType[commit.Type], commit)
+		switch {
+		case commit.IsBreaking:
+			breaking = append(breaking, commit)
+		case slices.Contains(otherChangesTypes, commit.Type):
+			other = append(other, commit)
+		default:
+			commitsByType[commit.Type] = append(commitsByType[commit.Type], commit)
+		}
 	}
 
 	var sections []*commitSection
+	if len(breaking) > 0 {
+		sections = append(sections, &commitSection{
+			Heading: breakingChangesHeading,
+			Commits: breaking,
+		})
+	}
 	// Group commits by type, according to commitTypeOrder, to be used in the release notes.
 	for _, ct := range commitTypeOrder {
 		displayName, headingOK := commitTypeToHeading[ct]
@@ -253,16 +422,59 @@ func formatLibraryReleaseNotes(library *legacyconfig.LibraryState, commits []*le
 			})
 		}
 	}
+	if listOtherChanges && len(other) > 0 {
+		sections = append(sections, &commitSection{
+			Heading: otherChangesHeading,
+			Commits: other,
+		})
+	}
+	return sections
+}
 
-	section := &releaseNoteSection{
-		LibraryID:      library.ID,
-		NewVersion:     newVersion,
-		PreviousTag:    previousTag,
-		NewTag:         newTag,
-		CommitSections: sections,
+// orderChanges returns commits in the order of the sections of release notes,
+// see groupCommits, followed by the commits left out of release notes.
+func orderChanges(commits []*legacyconfig.Commit) []*legacyconfig.Commit {
+	var ordered []*legacyconfig.Commit
+	for _, section := range groupCommits(commits, true) {
+		ordered = append(ordered, section.Commits...)
 	}
+	for _, commit := range commits {
+		if !slices.Contains(ordered, commit) {
+			ordered = append(ordered, commit)
+		}
+	}
+	return ordered
+}
 
-	return section
+// truncateCommitSections keeps the first maxEntries commits across the given
+// sections, in order, and drops any section left without commits. It returns
+// the remaining sections and the number of commits dropped. A non-positive
+// maxEntries keeps every commit.
+func truncateCommitSections(sections []*commitSection, maxEntries int) ([]*commitSection, int) {
+	if maxEntries <= 0 {
+		return sections, 0
+	}
+	var (
+		kept    []*commitSection
+		omitted int
+	)
+	remaining := maxEntries
+	for _, section := range sections {
+		if remaining <= 0 {
+			omitted += len(section.Commits)
+			continue
+		}
+		if len(section.Commits) > remaining {
+			omitted += len(section.Commits) - remaining
+			section = &commitSection{
+				Heading: section.Heading,
+				Commits: section.Commits[:remaining],
+			}
+		}
+		remaining -= len(section.Commits)
+		kept = append(kept, section)
+	}
+	return kept, omitted
 }
 
 // separateCommits analyzes all commits associated with triggered releases in the
@@ -42,12 +42,14 @@ func TestFormatReleaseNotes(t *testing.T) {
 	librarianVersion := legacycli.Version()
 
 	for _, test := range []struct {
-		name            string
-		state           *legacyconfig.LibrarianState
-		ghRepo          *legacygithub.Repository
-		wantReleaseNote string
-		wantErr         bool
-		wantErrPhrase   string
+		name             string
+		state            *legacyconfig.LibrarianState
+		ghRepo           *legacygithub.Repository
+		maxEntries       int
+		listOtherChanges bool
+		wantReleaseNote  string
+		wantErr          bool
+		wantErrPhrase    string
 	}{
 		{
 			name: "single library release",
@@ -80,6 +82,326 @@ func TestFormatReleaseNotes(t *testing.T) {
 			ghRepo: &legacygithub.Repository{Owner: "owner", Name: "repo"},
 			wantReleaseNote: fmt.Sprintf(`PR created by the Librarian CLI to initialize a release. Merging this PR will auto trigger a release.
 
+<!-- librarian:changelog -->
+Librarian Version: %s
+Language Image: go:1.21
+<details><summary>my-library: 1.1.0</summary>
+
+## [1.1.0](https://github.com/owner/repo/compare/my-library-1.0.0...my-library-1.1.0) (%s)
+
+### Features
+
+* new feature ([12345678](https://github.com/owner/repo/commit/12345678))
+
+### Bug Fixes
+
+* a bug fix ([fedcba09](https://github.com/owner/repo/commit/fedcba09))
+
+</details>
+<!-- /librarian:changelog -->`,
+				librarianVersion, today),
+		},
+		{
+			name: "breaking changes and other changes",
+			state: &legacyconfig.LibrarianState{
+				Image: "go:1.21",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:              "my-library",
+						Version:         "2.0.0",
+						PreviousVersion: "1.0.0",
+						Changes: []*legacyconfig.Commit{
+							{
+								Type:       "feat",
+								Subject:    "new feature",
+								CommitHash: hash1.String(),
+								LibraryIDs: "my-library",
+							},
+							{
+								Type:       "feat",
+								Subject:    "remove an API",
+								CommitHash: hash2.String(),
+								LibraryIDs: "my-library",
+								IsBreaking: true,
+							},
+							{
+								Type:       "chore",
+								Subject:    "update a dependency",
+								CommitHash: hash3.String(),
+								LibraryIDs: "my-library",
+							},
+							{
+								Type:       "test",
+								Subject:    "add a test",
+								CommitHash: hash4.String(),
+								LibraryIDs: "my-library",
+							},
+							{
+								Type:       "ci",
+								Subject:    "update a workflow",
+								CommitHash: hash5.String(),
+								LibraryIDs: "my-library",
+							},
+						},
+						ReleaseTriggered: true,
+					},
+				},
+			},
+			ghRepo:           &legacygithub.Repository{Owner: "owner", Name: "repo"},
+			listOtherChanges: true,
+			wantReleaseNote: fmt.Sprintf(`PR created by the Librarian CLI to initialize a release. Merging this PR will auto trigger a release.
+
+<!-- librarian:changelog -->
+Librarian Version: %s
+Language Image: go:1.21
+<details><summary>my-library: 2.0.0</summary>
+
+## [2.0.0](https://github.com/owner/repo/compare/my-library-1.0.0...my-library-2.0.0) (%s)
+
+### Breaking Changes
+
+* remove an API ([fedcba09](https://github.com/owner/repo/commit/fedcba09))
+
+### Features
+
+* new feature ([12345678](https://github.com/owner/repo/commit/12345678))
+
+### Other Changes
+
+* update a dependency ([abcdef00](https://github.com/owner/repo/commit/abcdef00))
+
+* add a test ([acdef123](https://github.com/owner/repo/commit/acdef123))
+
+</details>
+<!-- /librarian:changelog -->`,
+				librarianVersion, today),
+		},
+		{
+			name: "other changes left out by default",
+			state: &legacyconfig.LibrarianState{
+				Image: "go:1.21",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:              "my-library",
+						Version:         "1.0.1",
+						PreviousVersion: "1.0.0",
+						Changes: []*legacyconfig.Commit{
+							{
+								Type:       "fix",
+								Subject:    "a bug fix",
+								CommitHash: hash2.String(),
+								LibraryIDs: "my-library",
+							},
+							{
+								Type:       "chore",
+								Subject:    "update a dependency",
+								CommitHash: hash3.String(),
+								LibraryIDs: "my-library",
+							},
+						},
+						ReleaseTriggered: true,
+					},
+				},
+			},
+			ghRepo: &legacygithub.Repository{Owner: "owner", Name: "repo"},
+			wantReleaseNote: fmt.Sprintf(`PR created by the Librarian CLI to initialize a release. Merging this PR will auto trigger a release.
+
+<!-- librarian:changelog -->
+Librarian Version: %s
+Language Image: go:1.21
+<details><summary>my-library: 1.0.1</summary>
+
+## [1.0.1](https://github.com/owner/repo/compare/my-library-1.0.0...my-library-1.0.1) (%s)
+
+### Bug Fixes
+
+* a bug fix ([fedcba09](https://github.com/owner/repo/commit/fedcba09))
+
+</details>
+<!-- /librarian:changelog -->`,
+				librarianVersion, today),
+		},
+		{
+			name: "deprecated library release",
+			state: &legacyconfig.LibrarianState{
+				Image: "go:1.21",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:                 "my-library",
+						Version:            "1.1.0",
+						PreviousVersion:    "1.0.0",
+						Deprecated:         true,
+						DeprecationMessage: "Use my-library-v2 instead.",
+						Changes: []*legacyconfig.Commit{
+							{
+								Type:       "fix",
+								Subject:    "a bug fix",
+								CommitHash: hash2.String(),
+								LibraryIDs: "my-library",
+							},
+						},
+						ReleaseTriggered: true,
+					},
+				},
+			},
+			ghRepo: &legacygithub.Repository{Owner: "owner", Name: "repo"},
+			wantReleaseNote: fmt.Sprintf(`PR created by the Librarian CLI to initialize a release. Merging this PR will auto trigger a release.
+
+<!-- librarian:changelog -->
+> [!WARNING]
+> my-library is deprecated.
+> Use my-library-v2 instead.
+
+Librarian Version: %s
+Language Image: go:1.21
+<details><summary>my-library: 1.1.0</summary>
+
+## [1.1.0](https://github.com/owner/repo/compare/my-library-1.0.0...my-library-1.1.0) (%s)
+
+### Bug Fixes
+
+* a bug fix ([fedcba09](https://github.com/owner/repo/commit/fedcba09))
+
+</details>
+<!-- /librarian:changelog -->`,
+				librarianVersion, today),
+		},
+		{
+			name: "fix with fixes version",
+			state: &legacyconfig.LibrarianState{
+				Image: "go:1.21",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:              "my-library",
+						Version:         "1.1.1",
+						PreviousVersion: "1.1.0",
+						Changes: []*legacyconfig.Commit{
+							{
+								Type:         "fix",
+								Subject:      "a backported fix",
+								CommitHash:   hash2.String(),
+								LibraryIDs:   "my-library",
+								FixesVersion: "1.0.0",
+							},
+						},
+						ReleaseTriggered: true,
+					},
+				},
+			},
+			ghRepo: &legacygithub.Repository{Owner: "owner", Name: "repo"},
+			wantReleaseNote: fmt.Sprintf(`PR created by the Librarian CLI to initialize a release. Merging this PR will auto trigger a release.
+
+<!-- librarian:changelog -->
+Librarian Version: %s
+Language Image: go:1.21
+<details><summary>my-library: 1.1.1</summary>
+
+## [1.1.1](https://github.com/owner/repo/compare/my-library-1.1.0...my-library-1.1.1) (%s)
+
+### Bug Fixes
+
+* a backported fix (fixes 1.0.0) ([fedcba09](https://github.com/owner/repo/commit/fedcba09))
+
+</details>
+<!-- /librarian:changelog -->`,
+				librarianVersion, today),
+		},
+		{
+			name: "single library release, changes over the cap",
+			state: &legacyconfig.LibrarianState{
+				Image: "go:1.21",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:              "my-library",
+						Version:         "1.1.0",
+						PreviousVersion: "1.0.0",
+						Changes: []*legacyconfig.Commit{
+							{
+								Type:       "feat",
+								Subject:    "new feature",
+								CommitHash: hash1.String(),
+								LibraryIDs: "my-library",
+							},
+							{
+								Type:       "fix",
+								Subject:    "a bug fix",
+								CommitHash: hash2.String(),
+								LibraryIDs: "my-library",
+							},
+							{
+								Type:       "fix",
+								Subject:    "another bug fix",
+								CommitHash: hash3.String(),
+								LibraryIDs: "my-library",
+							},
+							{
+								Type:       "docs",
+								Subject:    "update docs",
+								CommitHash: hash4.String(),
+								LibraryIDs: "my-library",
+							},
+						},
+						ReleaseTriggered: true,
+					},
+				},
+			},
+			ghRepo:     &legacygithub.Repository{Owner: "owner", Name: "repo"},
+			maxEntries: 2,
+			wantReleaseNote: fmt.Sprintf(`PR created by the Librarian CLI to initialize a release. Merging this PR will auto trigger a release.
+
+<!-- librarian:changelog -->
+Librarian Version: %s
+Language Image: go:1.21
+<details><summary>my-library: 1.1.0</summary>
+
+## [1.1.0](https://github.com/owner/repo/compare/my-library-1.0.0...my-library-1.1.0) (%s)
+
+### Features
+
+* new feature ([12345678](https://github.com/owner/repo/commit/12345678))
+
+### Bug Fixes
+
+* another bug fix ([abcdef00](https://github.com/owner/repo/commit/abcdef00))
+
+…and 2 more changes, see the [full comparison](https://github.com/owner/repo/compare/my-library-1.0.0...my-library-1.1.0).
+
+</details>
+<!-- /librarian:changelog -->`,
+				librarianVersion, today),
+		},
+		{
+			name: "single library release, changes at the cap",
+			state: &legacyconfig.LibrarianState{
+				Image: "go:1.21",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:              "my-library",
+						Version:         "1.1.0",
+						PreviousVersion: "1.0.0",
+						Changes: []*legacyconfig.Commit{
+							{
+								Type:       "feat",
+								Subject:    "new feature",
+								CommitHash: hash1.String(),
+								LibraryIDs: "my-library",
+							},
+							{
+								Type:       "fix",
+								Subject:    "a bug fix",
+								CommitHash: hash2.String(),
+								LibraryIDs: "my-library",
+							},
+						},
+						ReleaseTriggered: true,
+					},
+				},
+			},
+			ghRepo:     &legacygithub.Repository{Owner: "owner", Name: "repo"},
+			maxEntries: 2,
+			wantReleaseNote: fmt.Sprintf(`PR created by the Librarian CLI to initialize a release. Merging this PR will auto trigger a release.
+
+<!-- librarian:changelog -->
 Librarian Version: %s
 Language Image: go:1.21
 <details><summary>my-library: 1.1.0</summary>
@@ -94,7 +416,50 @@ Language Image: go:1.21
 
 * a bug fix ([fedcba09](https://github.com/owner/repo/commit/fedcba09))
 
-</details>`,
+</details>
+<!-- /librarian:changelog -->`,
+				librarianVersion, today),
+		},
+		{
+			name: "single library release, earlier changes beyond the commit cap",
+			state: &legacyconfig.LibrarianState{
+				Image: "go:1.21",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:              "my-library",
+						Version:         "1.1.0",
+						PreviousVersion: "1.0.0",
+						Changes: []*legacyconfig.Commit{
+							{
+								Type:       "feat",
+								Subject:    "new feature",
+								CommitHash: hash1.String(),
+								LibraryIDs: "my-library",
+							},
+						},
+						EarlierChanges:   12,
+						ReleaseTriggered: true,
+					},
+				},
+			},
+			ghRepo: &legacygithub.Repository{Owner: "owner", Name: "repo"},
+			wantReleaseNote: fmt.Sprintf(`PR created by the Librarian CLI to initialize a release. Merging this PR will auto trigger a release.
+
+<!-- librarian:changelog -->
+Librarian Version: %s
+Language Image: go:1.21
+<details><summary>my-library: 1.1.0</summary>
+
+## [1.1.0](https://github.com/owner/repo/compare/my-library-1.0.0...my-library-1.1.0) (%s)
+
+### Features
+
+* new feature ([12345678](https://github.com/owner/repo/commit/12345678))
+
+…and 12 earlier changes, see the [full comparison](https://github.com/owner/repo/compare/my-library-1.0.0...my-library-1.1.0).
+
+</details>
+<!-- /librarian:changelog -->`,
 				librarianVersion, today),
 		},
 		{
@@ -130,6 +495,7 @@ Language Image: go:1.21
 			ghRepo: &legacygithub.Repository{Owner: "owner", Name: "repo"},
 			wantReleaseNote: fmt.Sprintf(`PR created by the Librarian CLI to initialize a release. Merging this PR will auto trigger a release.
 
+<!-- librarian:changelog -->
 Librarian Version: %s
 Language Image: go:1.21
 <details><summary>my-library: 1.1.0</summary>
@@ -144,7 +510,8 @@ Language Image: go:1.21
 
 * a bug fix (PiperOrigin-RevId: 987654) ([fedcba09](https://github.com/owner/repo/commit/fedcba09))
 
-</details>`,
+</details>
+<!-- /librarian:changelog -->`,
 				librarianVersion, today),
 		},
 		{
@@ -178,6 +545,7 @@ Language Image: go:1.21
 			ghRepo: &legacygithub.Repository{Owner: "owner", Name: "repo"},
 			wantReleaseNote: fmt.Sprintf(`PR created by the Librarian CLI to initialize a release. Merging this PR will auto trigger a release.
 
+<!-- librarian:changelog -->
 Librarian Version: %s
 Language Image: go:1.21
 <details><summary>my-library: 1.1.0</summary>
@@ -190,7 +558,8 @@ Language Image: go:1.21
 
 * another new feature ([fedcba09](https://github.com/owner/repo/commit/fedcba09))
 
-</details>`,
+</details>
+<!-- /librarian:changelog -->`,
 				librarianVersion, today),
 		},
 		{
@@ -233,6 +602,7 @@ Language Image: go:1.21
 			ghRepo: &legacygithub.Repository{Owner: "owner", Name: "repo"},
 			wantReleaseNote: fmt.Sprintf(`PR created by the Librarian CLI to initialize a release. Merging this PR will auto trigger a release.
 
+<!-- librarian:changelog -->
 Librarian Version: %s
 Language Image: go:1.21
 <details><summary>lib-a: 1.1.0</summary>
@@ -254,7 +624,8 @@ Language Image: go:1.21
 
 * fix for b ([fedcba09](https://github.com/owner/repo/commit/fedcba09))
 
-</details>`,
+</details>
+<!-- /librarian:changelog -->`,
 				librarianVersion, today, today),
 		},
 		{
@@ -288,6 +659,7 @@ Language Image: go:1.21
 			ghRepo: &legacygithub.Repository{Owner: "owner", Name: "repo"},
 			wantReleaseNote: fmt.Sprintf(`PR created by the Librarian CLI to initialize a release. Merging this PR will auto trigger a release.
 
+<!-- librarian:changelog -->
 Librarian Version: %s
 Language Image: go:1.21
 <details><summary>my-library: 1.1.0</summary>
@@ -298,7 +670,8 @@ Language Image: go:1.21
 
 * new feature ([12345678](https://github.com/owner/repo/commit/12345678))
 
-</details>`,
+</details>
+<!-- /librarian:changelog -->`,
 				librarianVersion, today),
 		},
 		{
@@ -327,6 +700,7 @@ Language Image: go:1.21
 			ghRepo: &legacygithub.Repository{Owner: "owner", Name: "repo"},
 			wantReleaseNote: fmt.Sprintf(`PR created by the Librarian CLI to initialize a release. Merging this PR will auto trigger a release.
 
+<!-- librarian:changelog -->
 Librarian Version: %s
 Language Image: go:1.21
 <details><summary>my-library: 1.1.0</summary>
@@ -337,7 +711,8 @@ Language Image: go:1.21
 
 * new feature ([12345678](https://github.com/owner/repo/commit/12345678))
 
-</details>`,
+</details>
+<!-- /librarian:changelog -->`,
 				librarianVersion, today),
 		},
 		{
@@ -349,8 +724,10 @@ Language Image: go:1.21
 			ghRepo: &legacygithub.Repository{},
 			wantReleaseNote: fmt.Sprintf(`PR created by the Librarian CLI to initialize a release. Merging this PR will auto trigger a release.
 
+<!-- librarian:changelog -->
 Librarian Version: %s
-Language Image: go:1.21`, librarianVersion),
+Language Image: go:1.21
+<!-- /librarian:changelog -->`, librarianVersion),
 		},
 		{
 			name: "generate with chore",
@@ -378,13 +755,15 @@ Language Image: go:1.21`, librarianVersion),
 			ghRepo: &legacygithub.Repository{Owner: "owner", Name: "repo"},
 			wantReleaseNote: fmt.Sprintf(`PR created by the Librarian CLI to initialize a release. Merging this PR will auto trigger a release.
 
+<!-- librarian:changelog -->
 Librarian Version: %s
 Language Image: go:1.21
 <details><summary>my-library: 1.1.0</summary>
 
 ## [1.1.0](https://github.com/owner/repo/compare/my-library-1.0.0...my-library-1.1.0) (%s)
 
-</details>`,
+</details>
+<!-- /librarian:changelog -->`,
 				librarianVersion, today),
 		},
 		{
@@ -438,6 +817,7 @@ Language Image: go:1.21
 			ghRepo: &legacygithub.Repository{Owner: "owner", Name: "repo"},
 			wantReleaseNote: fmt.Sprintf(`PR created by the Librarian CLI to initialize a release. Merging this PR will auto trigger a release.
 
+<!-- librarian:changelog -->
 Librarian Version: %s
 Language Image: go:1.21
 <details><summary>j: 1.1.0</summary>
@@ -464,7 +844,8 @@ Language Image: go:1.21
   Libraries: j,k,l,m,n,o,p,q,r,s
 * fix: bulk change ([fedcba09](https://github.com/owner/repo/commit/fedcba09))
   Libraries: a,b,c,d,e,f,g,h,i,j,k
-</details>`,
+</details>
+<!-- /librarian:changelog -->`,
 				librarianVersion, today, today),
 		},
 		{
@@ -699,6 +1080,7 @@ Language Image: go:1.21
 			ghRepo: &legacygithub.Repository{Owner: "owner", Name: "repo"},
 			wantReleaseNote: fmt.Sprintf(`PR created by the Librarian CLI to initialize a release. Merging this PR will auto trigger a release.
 
+<!-- librarian:changelog -->
 Librarian Version: %s
 Language Image: go:1.21
 <details><summary>j: 1.1.0</summary>
@@ -799,13 +1181,14 @@ Language Image: go:1.21
   Libraries: j,k,library-1,library-2,library-3,library-4,library-5,library-6,library-7,library-8
 * fix: bulk change ([fedcba09](https://github.com/owner/repo/commit/fedcba09))
   Libraries: a,b,c,d,e,f,g,h,i,j,k
-</details>`,
+</details>
+<!-- /librarian:changelog -->`,
 				librarianVersion, today, today, today, today, today, today, today, today, today, today),
 		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
-			got, err := formatReleaseNotes(test.state, test.ghRepo)
+			got, err := formatReleaseNotes(test.state, test.ghRepo, test.maxEntries, &legacyconfig.LibrarianConfig{ListOtherChanges: test.listOtherChanges})
 			if test.wantErr {
 				if err == nil {
 					t.Fatalf("%s should return error", test.name)
@@ -825,6 +1208,200 @@ Language Image: go:1.21
 	}
 }
 
+func TestFormatLibraryReleaseNotes_TagFormat(t *testing.T) {
+	t.Parallel()
+	library := &legacyconfig.LibraryState{
+		ID:              "one-id",
+		Version:         "1.3.0",
+		PreviousVersion: "1.2.3",
+	}
+	for _, test := range []struct {
+		name            string
+		librarianConfig *legacyconfig.LibrarianConfig
+		wantPreviousTag string
+		wantNewTag      string
+	}{
+		{
+			name:            "default format",
+			wantPreviousTag: "one-id-1.2.3",
+			wantNewTag:      "one-id-1.3.0",
+		},
+		{
+			name:            "configured format",
+			librarianConfig: &legacyconfig.LibrarianConfig{TagFormat: "{{.ID}}/v{{.Version}}"},
+			wantPreviousTag: "one-id/v1.2.3",
+			wantNewTag:      "one-id/v1.3.0",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := formatLibraryReleaseNotes(library, test.librarianConfig, nil, 0, false)
+			if got.PreviousTag != test.wantPreviousTag {
+				t.Errorf("PreviousTag = %q, want %q", got.PreviousTag, test.wantPreviousTag)
+			}
+			if got.NewTag != test.wantNewTag {
+				t.Errorf("NewTag = %q, want %q", got.NewTag, test.wantNewTag)
+			}
+		})
+	}
+}
+
+func TestFormatReleaseNotes_URLTemplates(t *testing.T) {
+	t.Parallel()
+	hash1 := plumbing.NewHash("1234567890abcdef")
+	hash2 := plumbing.NewHash("fedcba0987654321")
+	state := &legacyconfig.LibrarianState{
+		Libraries: []*legacyconfig.LibraryState{
+			{
+				ID:              "my-library",
+				Version:         "1.1.0",
+				PreviousVersion: "1.0.0",
+				Changes: []*legacyconfig.Commit{
+					{
+						Type:          "feat",
+						Subject:       "new feature",
+						CommitHash:    hash1.String(),
+						PiperCLNumber: "123456",
+						LibraryIDs:    "my-library",
+					},
+					{
+						Type:       "fix",
+						Subject:    "a bug fix",
+						CommitHash: hash2.String(),
+						LibraryIDs: "my-library",
+					},
+				},
+				ReleaseTriggered: true,
+			},
+		},
+	}
+	for _, test := range []struct {
+		name            string
+		librarianConfig *legacyconfig.LibrarianConfig
+		want            []string
+	}{
+		{
+			name: "defaults",
+			want: []string{
+				"* new feature (PiperOrigin-RevId: 123456) ([12345678](https://github.com/owner/repo/commit/12345678))",
+				"* a bug fix ([fedcba09](https://github.com/owner/repo/commit/fedcba09))",
+			},
+		},
+		{
+			name: "configured templates",
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				CommitURLTemplate: "https://git.example.com/repo/+/{sha}",
+				CLURLTemplate:     "https://cl.example.com/{cl}",
+			},
+			want: []string{
+				fmt.Sprintf("* new feature (PiperOrigin-RevId: [123456](https://cl.example.com/123456)) ([12345678](https://git.example.com/repo/+/%s))", hash1),
+				fmt.Sprintf("* a bug fix ([fedcba09](https://git.example.com/repo/+/%s))", hash2),
+			},
+		},
+		{
+			name: "short sha",
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				CommitURLTemplate: "https://git.example.com/c/{short_sha}",
+			},
+			want: []string{
+				"* new feature (PiperOrigin-RevId: 123456) ([12345678](https://git.example.com/c/12345678))",
+				"* a bug fix ([fedcba09](https://git.example.com/c/fedcba09))",
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := formatReleaseNotes(state, &legacygithub.Repository{Owner: "owner", Name: "repo"}, 0, test.librarianConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, line := range test.want {
+				if !strings.Contains(got, line+"\n") {
+					t.Errorf("formatReleaseNotes() = %s\nwant line %q", got, line)
+				}
+			}
+		})
+	}
+}
+
+func TestOrderChanges(t *testing.T) {
+	t.Parallel()
+	chore := &legacyconfig.Commit{Type: "chore", Subject: "a chore"}
+	ci := &legacyconfig.Commit{Type: "ci", Subject: "a ci change"}
+	feat := &legacyconfig.Commit{Type: "feat", Subject: "a feature"}
+	fix := &legacyconfig.Commit{Type: "fix", Subject: "a fix"}
+	breakingFix := &legacyconfig.Commit{Type: "fix", Subject: "a breaking fix", IsBreaking: true}
+	got := orderChanges([]*legacyconfig.Commit{ci, chore, fix, feat, breakingFix})
+	want := []*legacyconfig.Commit{breakingFix, feat, fix, chore, ci}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("orderChanges() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMergeManagedSection(t *testing.T) {
+	t.Parallel()
+	generated := `PR created by the Librarian CLI to initialize a release.
+
+<!-- librarian:changelog -->
+## 1.2.0
+<!-- /librarian:changelog -->`
+	for _, test := range []struct {
+		name      string
+		existing  string
+		generated string
+		want      string
+	}{
+		{
+			name: "content outside managed section survives",
+			existing: `Note added above.
+
+<!-- librarian:changelog -->
+## 1.1.0
+<!-- /librarian:changelog -->
+
+Note added below.`,
+			want: `Note added above.
+
+<!-- librarian:changelog -->
+## 1.2.0
+<!-- /librarian:changelog -->
+
+Note added below.`,
+		},
+		{
+			name:     "existing without managed section",
+			existing: "manually written body",
+			want:     generated,
+		},
+		{
+			name:     "existing with unterminated managed section",
+			existing: "<!-- librarian:changelog -->\n## 1.1.0",
+			want:     generated,
+		},
+		{
+			name:     "empty existing",
+			existing: "",
+			want:     generated,
+		},
+		{
+			name:      "generated without managed section",
+			existing:  "<!-- librarian:changelog -->\n## 1.1.0\n<!-- /librarian:changelog -->",
+			generated: "generated body",
+			want:      "generated body",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			if test.generated == "" {
+				test.generated = generated
+			}
+			got := mergeManagedSection(test.existing, test.generated)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("mergeManagedSection() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestFindPiperIDFrom(t *testing.T) {
 	for _, test := range []struct {
 		name    string
@@ -17,14 +17,21 @@ package legacylibrarian
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"regexp"
 	"slices"
+	"strconv"
 	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
 
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacydocker"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacygithub"
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacygitrepo"
 	"github.com/googleapis/librarian/internal/semver"
 )
@@ -36,42 +43,153 @@ type stageRunner struct {
 	ghClient        GitHubClient
 	image           string
 	librarianConfig *legacyconfig.LibrarianConfig
-	library         string
+	libraries       []string
 	libraryVersion  string
 	push            bool
 	repo            legacygitrepo.Repository
 	sourceRepo      legacygitrepo.Repository
 	state           *legacyconfig.LibrarianState
 	workRoot        string
+
+	amend               bool
+	author              *legacygitrepo.Signature
+	batch               []string
+	committer           *legacygitrepo.Signature
+	excludeAuthors      []*regexp.Regexp
+	excludeCommits      []string
+	fetchBeforeStage    bool
+	force               bool
+	ignoreFreeze        bool
+	language            string
+	maxChangelogEntries int
+	maxCommits          int
+	out                 io.Writer
+	outputState         string
+	prTemplate          string
+	prTitles            map[int]string
+	progressInterval    time.Duration
+	rebaseOntoBase      bool
+	reportUnreleased    bool
+	squashMergeTitles   bool
+	strictSemVer        bool
+	// commitTemplate renders the message of the created commits. If
+	// nil, the default message is used.
+	commitTemplate *template.Template
+	// includeHiddenCommits lists the commits which are not released on their
+	// own in the unreleased report.
+	includeHiddenCommits bool
+	// skipLibrariesWithoutSource skips the libraries without source roots,
+	// unless they are requested with the -library flag.
+	skipLibrariesWithoutSource bool
 }
 
 func newStageRunner(cfg *legacyconfig.Config) (*stageRunner, error) {
+	author, err := commitAuthor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	committer, err := commitCommitter(cfg)
+	if err != nil {
+		return nil, err
+	}
 	runner, err := newCommandRunner(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create stage runner: %w", err)
 	}
+	templateText := cfg.CommitMessageTemplate
+	if templateText == "" && runner.librarianConfig != nil && runner.librarianConfig.CommitMessageTemplates != nil {
+		templateText = runner.librarianConfig.CommitMessageTemplates.ReleaseStage
+	}
+	commitTemplate, err := parseCommitMessageTemplate(templateText, defaultReleaseCommitMessage)
+	if err != nil {
+		return nil, err
+	}
+	var excludeAuthors []*regexp.Regexp
+	if runner.librarianConfig != nil {
+		for _, pattern := range runner.librarianConfig.ExcludeCommitAuthors {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid exclude_commit_authors pattern %q: %w", pattern, err)
+			}
+			excludeAuthors = append(excludeAuthors, re)
+		}
+	}
 	return &stageRunner{
-		branch:          cfg.Branch,
-		commit:          cfg.Commit,
-		containerClient: runner.containerClient,
-		ghClient:        runner.ghClient,
-		image:           runner.image,
-		librarianConfig: runner.librarianConfig,
-		library:         cfg.Library,
-		libraryVersion:  cfg.LibraryVersion,
-		push:            cfg.Push,
-		repo:            runner.repo,
-		sourceRepo:      runner.sourceRepo,
-		state:           runner.state,
-		workRoot:        runner.workRoot,
+		amend:                      cfg.Amend,
+		author:                     author,
+		branch:                     cfg.Branch,
+		commit:                     cfg.Commit,
+		commitTemplate:             commitTemplate,
+		committer:                  committer,
+		containerClient:            runner.containerClient,
+		excludeAuthors:             excludeAuthors,
+		excludeCommits:             cfg.ExcludeCommits,
+		fetchBeforeStage:           cfg.FetchBeforeStage,
+		force:                      cfg.Force,
+		ghClient:                   runner.ghClient,
+		ignoreFreeze:               cfg.IgnoreFreeze,
+		image:                      runner.image,
+		includeHiddenCommits:       cfg.IncludeHiddenCommits,
+		language:                   cfg.Language,
+		librarianConfig:            runner.librarianConfig,
+		libraries:                  splitLibraryIDs(cfg.Library),
+		libraryVersion:             cfg.LibraryVersion,
+		maxChangelogEntries:        cfg.MaxChangelogEntries,
+		maxCommits:                 cfg.MaxCommits,
+		out:                        os.Stdout,
+		outputState:                cfg.OutputState,
+		prTemplate:                 cfg.PRTemplate,
+		progressInterval:           cfg.ProgressInterval,
+		push:                       cfg.Push,
+		rebaseOntoBase:             cfg.RebaseOntoBase,
+		repo:                       runner.repo,
+		reportUnreleased:           cfg.ReportUnreleased,
+		skipLibrariesWithoutSource: cfg.SkipLibrariesWithoutSource,
+		sourceRepo:                 runner.sourceRepo,
+		squashMergeTitles:          cfg.SquashMergeTitles,
+		state:                      runner.state,
+		strictSemVer:               cfg.StrictSemVer,
+		workRoot:                   runner.workRoot,
 	}, nil
 }
 
+// splitLibraryIDs returns the library IDs of the comma-separated list ids.
+func splitLibraryIDs(ids string) []string {
+	var libraryIDs []string
+	for _, id := range strings.Split(ids, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			libraryIDs = append(libraryIDs, id)
+		}
+	}
+	return libraryIDs
+}
+
 func (r *stageRunner) run(ctx context.Context) error {
+	if r.strictSemVer {
+		if err := checkSemVer(r.state.Libraries); err != nil {
+			return err
+		}
+	}
+	if !r.reportUnreleased && r.librarianConfig.IsReleaseFrozen(time.Now()) && !r.ignoreFreeze {
+		return fmt.Errorf("releases are frozen until %s by freeze_until in .librarian/config.yaml; use -ignore-freeze to stage releases anyway", r.librarianConfig.FreezeUntil.Format(time.RFC3339))
+	}
+	if err := r.fetchUpstream(); err != nil {
+		return err
+	}
+	if r.reportUnreleased {
+		return r.writeUnreleasedReport(ctx, r.out)
+	}
 	outputDir := filepath.Join(r.workRoot, "output")
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create output dir: %s", outputDir)
 	}
+	batches, err := r.releaseBatches(ctx)
+	if err != nil {
+		return err
+	}
+	if len(batches) > 1 {
+		return r.runBatches(ctx, outputDir, batches)
+	}
 	slog.Info("staging a release", "dir", outputDir)
 	if err := r.runStageCommand(ctx, outputDir); err != nil {
 		return err
@@ -83,33 +201,186 @@ func (r *stageRunner) run(ctx context.Context) error {
 		slog.Info("no release created; skipping the commit/PR")
 		return nil
 	}
+	slog.Info(versionBumpSummary(r.state.Libraries))
+
+	return r.commitRelease(ctx, 0, 0)
+}
+
+// fetchUpstream fetches the branch and tags of the origin remote of the
+// language repository, so that releases are computed from the current tags,
+// and returns an error if the local branch is behind the remote one. It does
+// nothing unless fetchBeforeStage is set and the repository has an origin
+// remote.
+func (r *stageRunner) fetchUpstream() error {
+	if !r.fetchBeforeStage {
+		return nil
+	}
+	remotes, err := r.repo.Remotes()
+	if err != nil {
+		return fmt.Errorf("failed to get remotes: %w", err)
+	}
+	if !slices.ContainsFunc(remotes, func(remote *legacygitrepo.Remote) bool { return remote.Name == "origin" }) {
+		slog.Info("no origin remote; skipping fetch before stage")
+		return nil
+	}
+	behind, err := r.repo.FetchUpstream(r.branch)
+	if err != nil {
+		return fmt.Errorf("failed to fetch upstream: %w", err)
+	}
+	if behind > 0 {
+		return fmt.Errorf("local branch is %d commit(s) behind origin/%s; update it, or use -fetch-before-stage=false to stage anyway", behind, r.branch)
+	}
+	return nil
+}
+
+// releaseBatches returns the IDs of the libraries to release, split into
+// batches of at most MaxLibrariesPerPR libraries each. It returns nil when
+// the release does not need to be split.
+func (r *stageRunner) releaseBatches(ctx context.Context) ([][]string, error) {
+	if r.librarianConfig == nil || r.librarianConfig.MaxLibrariesPerPR == 0 || len(r.libraries) > 0 {
+		return nil, nil
+	}
+	if !r.commit && !r.push {
+		slog.Info("push flag and commit flag are not specified, ignoring max_libraries_per_pr")
+		return nil, nil
+	}
+	var ids []string
+	for _, library := range r.state.Libraries {
+		if !matchesLanguage(library, r.language) || r.skipsWithoutSource(library) {
+			continue
+		}
+		if r.librarianConfig.IsFrozen(library.ID) && !r.force {
+			continue
+		}
+		libraryConfig := r.librarianConfig.LibraryConfigFor(library.ID)
+		if libraryConfig != nil && libraryConfig.ReleaseBlocked {
+			continue
+		}
+		recent, err := r.releasedRecently(library, libraryConfig)
+		if err != nil {
+			return nil, err
+		}
+		if recent {
+			continue
+		}
+		candidate, _, err := r.releaseCandidate(ctx, library)
+		if err != nil {
+			return nil, err
+		}
+		if candidate.ReleaseTriggered {
+			ids = append(ids, library.ID)
+		}
+	}
+	if len(ids) <= r.librarianConfig.MaxLibrariesPerPR {
+		return nil, nil
+	}
+	return slices.Collect(slices.Chunk(ids, r.librarianConfig.MaxLibrariesPerPR)), nil
+}
+
+// runBatches stages a release of each batch of libraries in turn, creating a
+// commit and pull request per batch. Every batch is staged on top of the
+// commit the release started from, so the pull requests can be merged
+// independently of each other.
+func (r *stageRunner) runBatches(ctx context.Context, outputDir string, batches [][]string) error {
+	baseHash, err := r.repo.HeadHash()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD commit: %w", err)
+	}
+	original := make([]legacyconfig.LibraryState, len(r.state.Libraries))
+	for i, library := range r.state.Libraries {
+		original[i] = *library
+	}
+	var released []*legacyconfig.LibraryState
+	for i, batch := range batches {
+		if i > 0 {
+			if err := r.repo.Checkout(baseHash); err != nil {
+				return fmt.Errorf("failed to checkout %s: %w", baseHash, err)
+			}
+			for j, library := range r.state.Libraries {
+				*library = original[j]
+			}
+		}
+		batchDir := filepath.Join(outputDir, strconv.Itoa(i+1))
+		if err := os.MkdirAll(batchDir, 0755); err != nil {
+			return fmt.Errorf("failed to create output dir: %s", batchDir)
+		}
+		slog.Info("staging a release", "dir", batchDir, "part", i+1, "parts", len(batches), "libraries", strings.Join(batch, ","))
+		r.batch = batch
+		if err := r.runStageCommand(ctx, batchDir); err != nil {
+			return err
+		}
+		for _, library := range r.state.Libraries {
+			if library.ReleaseTriggered {
+				copied := *library
+				released = append(released, &copied)
+			}
+		}
+		if err := r.commitRelease(ctx, i+1, len(batches)); err != nil {
+			return err
+		}
+	}
+	slog.Info(versionBumpSummary(released))
+	return nil
+}
 
+// commitRelease saves the librarian state, and commits and pushes the staged
+// release. See [commitInfo] for part and parts.
+func (r *stageRunner) commitRelease(ctx context.Context, part, parts int) error {
 	if err := saveLibrarianState(r.repo.GetDir(), r.state); err != nil {
 		return err
 	}
+	if r.outputState != "" {
+		if err := writeLibrarianState(r.outputState, r.state); err != nil {
+			return fmt.Errorf("failed to write output state: %w", err)
+		}
+	}
 
 	prBodyBuilder := func() (string, error) {
 		gitHubRepo, err := GetGitHubRepositoryFromGitRepo(r.repo)
 		if err != nil {
 			return "", fmt.Errorf("failed to get GitHub repository: %w", err)
 		}
-		return formatReleaseNotes(r.state, gitHubRepo)
+		return formatReleaseNotes(r.state, gitHubRepo, r.maxChangelogEntries, r.librarianConfig)
+	}
+	prBodyBuilder = withPRTemplate(prBodyBuilder, r.repo.GetDir(), r.prTemplate)
+	var releasedIDs []string
+	for _, library := range r.state.Libraries {
+		if library.ReleaseTriggered {
+			releasedIDs = append(releasedIDs, library.ID)
+		}
+	}
+	commitMessage, err := renderCommitMessage(r.commitTemplate, defaultReleaseCommitMessage, r.state, releasedIDs)
+	if err != nil {
+		return err
+	}
+	var pullRequest *legacygithub.PullRequest
+	if r.amend {
+		if pullRequest, err = r.openReleasePullRequest(ctx, part, parts); err != nil {
+			return err
+		}
 	}
 	commitInfo := &commitInfo{
-		branch:        r.branch,
-		commit:        r.commit,
-		commitMessage: "chore: create a release",
-		ghClient:      r.ghClient,
-		prType:        pullRequestRelease,
+		author:          r.author,
+		branch:          r.branch,
+		commit:          r.commit,
+		commitMessage:   commitMessage,
+		committer:       r.committer,
+		ghClient:        r.ghClient,
+		librarianConfig: r.librarianConfig,
+		part:            part,
+		parts:           parts,
+		prType:          pullRequestRelease,
 		// Newly created PRs from the `release stage` command should have a
 		// `release:pending` GitHub tab to be tracked for release.
 		pullRequestLabels: []string{"release:pending"},
 		push:              r.push,
+		rebaseOntoBase:    r.rebaseOntoBase,
 		languageRepo:      r.repo,
 		sourceRepo:        r.sourceRepo,
 		state:             r.state,
 		workRoot:          r.workRoot,
 		prBodyBuilder:     prBodyBuilder,
+		pullRequest:       pullRequest,
 	}
 	if err := commitAndPush(ctx, commitInfo); err != nil {
 		return fmt.Errorf("failed to commit and push: %w", err)
@@ -118,6 +389,29 @@ func (r *stageRunner) run(ctx context.Context) error {
 	return nil
 }
 
+// openReleasePullRequest returns the open release pull request into the base
+// branch created by a previous run, which a run with -amend updates instead of
+// creating a new pull request, or nil if there is none. When the release is
+// split across several pull requests, the one of the given part is returned.
+func (r *stageRunner) openReleasePullRequest(ctx context.Context, part, parts int) (*legacygithub.PullRequest, error) {
+	prs, err := r.ghClient.SearchPullRequests(ctx, fmt.Sprintf("is:pr is:open label:%s base:%s", releasePendingLabel, r.branch))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search open release pull requests: %w", err)
+	}
+	titlePrefix := fmt.Sprintf("chore: librarian %s pull request: ", pullRequestRelease)
+	for _, pr := range prs {
+		if !strings.HasPrefix(pr.GetTitle(), titlePrefix) {
+			continue
+		}
+		if parts > 1 && !strings.HasSuffix(pr.GetTitle(), fmt.Sprintf(" (%d/%d)", part, parts)) {
+			continue
+		}
+		slog.Info("updating the open release pull request", "number", pr.GetNumber())
+		return pr, nil
+	}
+	return nil, nil
+}
+
 // hasLibrariesToRelease searches through the state of each library and checks
 // that there is a single library configured to be triggered.
 func hasLibrariesToRelease(libraryStates []*legacyconfig.LibraryState) bool {
@@ -129,30 +423,205 @@ func hasLibrariesToRelease(libraryStates []*legacyconfig.LibraryState) bool {
 	return false
 }
 
+// writeUnreleasedReport writes a table of all libraries with their current
+// version, the version they would be released at and the number of releasable
+// changes since their last release. Libraries without releasable changes are
+// listed with "-" as their next version. If includeHiddenCommits is set, the
+// commits which are not released on their own, i.e. of the types listed under
+// "Other Changes", are then listed in a "NOT RELEASED" section. The report
+// ends with the summary of the version bumps, see [versionBumpSummary]. The
+// state of the runner is not modified.
+func (r *stageRunner) writeUnreleasedReport(ctx context.Context, w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LIBRARY\tVERSION\tNEXT VERSION\tCHANGES")
+	var (
+		hidden     []hiddenCommit
+		candidates []*legacyconfig.LibraryState
+	)
+	for _, library := range r.state.Libraries {
+		if !matchesLanguage(library, r.language) {
+			continue
+		}
+		if r.librarianConfig.IsFrozen(library.ID) && !r.force {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", library.ID, library.Version, "-", "frozen")
+			continue
+		}
+		if r.librarianConfig != nil {
+			libraryConfig := r.librarianConfig.LibraryConfigFor(library.ID)
+			if libraryConfig != nil && libraryConfig.ReleaseBlocked {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", library.ID, library.Version, "-", "release blocked")
+				continue
+			}
+		}
+		candidate, commits, err := r.releaseCandidate(ctx, library)
+		if err != nil {
+			return err
+		}
+		nextVersion := "-"
+		if candidate.ReleaseTriggered {
+			nextVersion = candidate.Version
+			candidates = append(candidates, candidate)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", library.ID, library.Version, nextVersion, len(candidate.Changes))
+		if !r.includeHiddenCommits {
+			continue
+		}
+		for _, commit := range commits {
+			if slices.Contains(otherChangesTypes, commit.Type) {
+				hidden = append(hidden, hiddenCommit{libraryID: library.ID, commit: commit})
+			}
+		}
+	}
+	if r.includeHiddenCommits {
+		fmt.Fprintln(tw, "\nNOT RELEASED")
+		fmt.Fprintln(tw, "LIBRARY\tCOMMIT\tTYPE\tSUBJECT")
+		for _, h := range hidden {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", h.libraryID, shortSHA(h.commit.CommitHash), h.commit.Type, h.commit.Subject)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%s\n", versionBumpSummary(candidates))
+	return err
+}
+
+// versionBumpSummary returns a one-line summary of the version bumps of the
+// libraries with a triggered release, e.g. "Releasing 12 libraries: 1 major,
+// 4 minor, 7 patch", for release managers to assess the risk of a release.
+// Each bump is classified from the previous and new versions of the library.
+// Pre-release bumps, e.g. from 1.2.0-beta.1 to 1.2.0-beta.2, count as patch
+// bumps, and bumps from or to a version which is not a semantic version, e.g.
+// of a first release, as other bumps.
+func versionBumpSummary(libraries []*legacyconfig.LibraryState) string {
+	counts := map[semver.ChangeLevel]int{}
+	var total int
+	for _, library := range libraries {
+		if library.ReleaseTriggered {
+			counts[versionBump(library.PreviousVersion, library.Version)]++
+			total++
+		}
+	}
+	noun := "libraries"
+	if total == 1 {
+		noun = "library"
+	}
+	summary := fmt.Sprintf("Releasing %d %s: %d major, %d minor, %d patch", total, noun, counts[semver.Major], counts[semver.Minor], counts[semver.Patch])
+	if counts[semver.None] > 0 {
+		summary += fmt.Sprintf(", %d other", counts[semver.None])
+	}
+	return summary
+}
+
+// versionBump returns the level of the change from the previous version to the
+// next one, or semver.None if either is not a semantic version.
+func versionBump(previous, next string) semver.ChangeLevel {
+	p, err := semver.Parse(previous)
+	if err != nil {
+		return semver.None
+	}
+	n, err := semver.Parse(next)
+	if err != nil {
+		return semver.None
+	}
+	switch {
+	case p.Major != n.Major:
+		return semver.Major
+	case p.Minor != n.Minor:
+		return semver.Minor
+	default:
+		return semver.Patch
+	}
+}
+
+// hiddenCommit is a commit of a library which is not released on its own.
+type hiddenCommit struct {
+	libraryID string
+	commit    *legacygitrepo.ConventionalCommit
+}
+
+// releaseCandidate returns a copy of library updated as it would be by
+// staging a release, leaving library itself unchanged, and the commits of
+// library considered for the release.
+func (r *stageRunner) releaseCandidate(ctx context.Context, library *legacyconfig.LibraryState) (*legacyconfig.LibraryState, []*legacygitrepo.ConventionalCommit, error) {
+	candidate := *library
+	commits, err := r.libraryCommits(ctx, &candidate)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := r.updateLibrary(&candidate, commits); err != nil {
+		return nil, nil, err
+	}
+	return &candidate, commits, nil
+}
+
 func (r *stageRunner) runStageCommand(ctx context.Context, outputDir string) error {
 	src := r.repo.GetDir()
 	librariesToRelease := r.state.Libraries
-	if r.library != "" {
-		library := r.state.LibraryByID(r.library)
-		if library == nil {
-			return fmt.Errorf("unable to find library for release: %s", r.library)
+	if len(r.libraries) > 0 {
+		librariesToRelease = nil
+		for _, id := range r.libraries {
+			library := r.state.LibraryByID(id)
+			if library == nil {
+				return fmt.Errorf("unable to find library for release: %s", id)
+			}
+			if !matchesLanguage(library, r.language) {
+				return fmt.Errorf("library %q has language %q, not %q", id, library.Language, r.language)
+			}
+			librariesToRelease = append(librariesToRelease, library)
 		}
-		librariesToRelease = []*legacyconfig.LibraryState{library}
+	}
+	if r.language != "" {
+		librariesToRelease = slices.DeleteFunc(slices.Clone(librariesToRelease), func(library *legacyconfig.LibraryState) bool {
+			return !matchesLanguage(library, r.language)
+		})
+	}
+	if len(r.batch) > 0 {
+		librariesToRelease = slices.DeleteFunc(slices.Clone(librariesToRelease), func(library *legacyconfig.LibraryState) bool {
+			return !slices.Contains(r.batch, library.ID)
+		})
 	}
 	// Mark if there are any library that needs to be released
 	foundReleasableLibrary := false
+	progress := newProgressLogger("release stage", len(librariesToRelease), r.progressInterval)
 	for _, library := range librariesToRelease {
+		if r.skipsWithoutSource(library) {
+			slog.Warn("library has no source roots, skipping", "id", library.ID)
+			progress.libraryDone()
+			continue
+		}
+		if r.librarianConfig.IsFrozen(library.ID) && !r.force {
+			slog.Warn("library is frozen, skipping; use -force to release it", "id", library.ID)
+			progress.libraryDone()
+			continue
+		}
 		if r.librarianConfig != nil {
 			libraryConfig := r.librarianConfig.LibraryConfigFor(library.ID)
-			if libraryConfig != nil && libraryConfig.ReleaseBlocked && r.library != library.ID {
+			if libraryConfig != nil && libraryConfig.ReleaseBlocked && !slices.Contains(r.libraries, library.ID) {
 				// Do not skip the `release_blocked` library if library ID is explicitly specified.
 				slog.Info("library has release_blocked, skipping", "id", library.ID)
+				progress.libraryDone()
 				continue
 			}
+			if !slices.Contains(r.libraries, library.ID) {
+				recent, err := r.releasedRecently(library, libraryConfig)
+				if err != nil {
+					return err
+				}
+				if recent {
+					slog.Info("library was released within min_release_interval, skipping", "id", library.ID, "interval", libraryConfig.MinReleaseInterval)
+					progress.libraryDone()
+					continue
+				}
+			}
+			if libraryConfig != nil {
+				library.ChangelogPath = libraryConfig.ChangelogPath
+			}
 		}
-		if err := r.processLibrary(library); err != nil {
+		if err := r.processLibrary(ctx, library); err != nil {
 			return err
 		}
+		progress.libraryDone()
 
 		// Copy the library files over if a release is needed
 		if library.ReleaseTriggered {
@@ -165,11 +634,18 @@ func (r *stageRunner) runStageCommand(ctx context.Context, outputDir string) err
 		return nil
 	}
 
+	// The container is only told about the requested library when there is
+	// exactly one; otherwise the libraries to release are the ones marked as
+	// triggered in the state.
+	var libraryID string
+	if len(r.libraries) == 1 {
+		libraryID = r.libraries[0]
+	}
 	stageRequest := &legacydocker.ReleaseStageRequest{
 		Branch:          r.branch,
 		Commit:          r.commit,
 		LibrarianConfig: r.librarianConfig,
-		LibraryID:       r.library,
+		LibraryID:       libraryID,
 		LibraryVersion:  r.libraryVersion,
 		Output:          outputDir,
 		RepoDir:         src,
@@ -190,7 +666,7 @@ func (r *stageRunner) runStageCommand(ctx context.Context, outputDir string) err
 	for _, library := range librariesToRelease {
 		// Copy the library files back if a release is needed
 		if library.ReleaseTriggered {
-			if err := copyLibraryFiles(r.state, r.repo.GetDir(), library.ID, outputDir, false); err != nil {
+			if err := copyLibraryFiles(r.state, r.repo.GetDir(), library.ID, outputDir, false, nil); err != nil {
 				return err
 			}
 		}
@@ -199,32 +675,152 @@ func (r *stageRunner) runStageCommand(ctx context.Context, outputDir string) err
 	return copyGlobalAllowlist(r.librarianConfig, r.repo.GetDir(), outputDir, false)
 }
 
+// skipsWithoutSource reports whether library is skipped for having no source
+// roots, which happens when its onboarding is incomplete. Libraries requested
+// with the -library flag are never skipped.
+func (r *stageRunner) skipsWithoutSource(library *legacyconfig.LibraryState) bool {
+	return r.skipLibrariesWithoutSource && len(library.SourceRoots) == 0 && !slices.Contains(r.libraries, library.ID)
+}
+
 // processLibrary wrapper to process the library for release. Helps retrieve latest commits
 // since the last release and passing the changes to updateLibrary.
-func (r *stageRunner) processLibrary(library *legacyconfig.LibraryState) error {
+func (r *stageRunner) processLibrary(ctx context.Context, library *legacyconfig.LibraryState) error {
+	commits, err := r.libraryCommits(ctx, library)
+	if err != nil {
+		return err
+	}
+	return r.updateLibrary(library, commits)
+}
+
+// libraryCommits returns the conventional commits of library since its last
+// release, leaving out the excluded commits, and records in library the
+// number of earlier commits left out because of the commit cap.
+func (r *stageRunner) libraryCommits(ctx context.Context, library *legacyconfig.LibraryState) ([]*legacygitrepo.ConventionalCommit, error) {
 	var tagName string
 	if library.Version != "0.0.0" {
 		tagFormat := legacyconfig.DetermineTagFormat(library.ID, library, r.librarianConfig)
 		tagName = legacyconfig.FormatTag(tagFormat, library.ID, library.Version)
 	}
-	commits, err := getConventionalCommitsSinceLastRelease(r.repo, library, tagName)
+	var rewrite func(*legacygitrepo.Commit) (*legacygitrepo.Commit, error)
+	if r.squashMergeTitles {
+		rewrite = func(commit *legacygitrepo.Commit) (*legacygitrepo.Commit, error) {
+			return r.squashMergeCommit(ctx, commit)
+		}
+	}
+	commits, earlier, err := getConventionalCommitsSinceLastRelease(r.repo, library, tagName, releaseCommitsOptions{
+		maxCommits:   r.maxCommits,
+		rewrite:      rewrite,
+		parseAffects: r.librarianConfig != nil && r.librarianConfig.ParseAffectsLines,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to fetch conventional commits for library, %s: %w", library.ID, err)
+		return nil, fmt.Errorf("failed to fetch conventional commits for library, %s: %w", library.ID, err)
 	}
+	library.EarlierChanges = earlier
 	// Filter specifically for commits relevant to a library
 	commits = filterCommitsByLibraryID(commits, library.ID)
-	return r.updateLibrary(library, commits)
+	return r.dropExcludedCommits(commits), nil
+}
+
+// squashMergeSubjectRegex matches the subject of a squash-merged pull
+// request, e.g. "Add the foo API (#123)", capturing the pull request number.
+var squashMergeSubjectRegex = regexp.MustCompile(`\(#(\d+)\)$`)
+
+// squashMergeCommit returns a copy of commit whose subject is replaced by the
+// title of the pull request it squash-merges, followed by the pull request
+// number, so that the title is parsed as the conventional commit. Commits
+// which are not squash merges, and those of pull requests without a title or
+// whose title cannot be looked up, are returned unchanged.
+func (r *stageRunner) squashMergeCommit(ctx context.Context, commit *legacygitrepo.Commit) (*legacygitrepo.Commit, error) {
+	subject, body, _ := strings.Cut(commit.Message, "\n")
+	matches := squashMergeSubjectRegex.FindStringSubmatch(strings.TrimSpace(subject))
+	if matches == nil {
+		return commit, nil
+	}
+	number, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid pull request number in subject %q: %w", subject, err)
+	}
+	title := r.pullRequestTitle(ctx, number)
+	if title == "" {
+		return commit, nil
+	}
+	rewritten := *commit
+	rewritten.Message = fmt.Sprintf("%s (#%d)", title, number)
+	if body != "" {
+		rewritten.Message += "\n" + body
+	}
+	return &rewritten, nil
+}
+
+// pullRequestTitle returns the title of the pull request with the given
+// number, looking it up on GitHub only once per run. If the lookup fails, a
+// warning is logged and an empty title is returned.
+func (r *stageRunner) pullRequestTitle(ctx context.Context, number int) string {
+	if title, ok := r.prTitles[number]; ok {
+		return title
+	}
+	var title string
+	pr, err := r.ghClient.GetPullRequest(ctx, number)
+	if err != nil {
+		slog.Warn("failed to get pull request, keeping the commit subject", "number", number, "error", err)
+	} else {
+		title = strings.TrimSpace(pr.GetTitle())
+	}
+	if r.prTitles == nil {
+		r.prTitles = map[int]string{}
+	}
+	r.prTitles[number] = title
+	return title
+}
+
+// releasedRecently reports whether the last release of library, dated by the
+// commit of its release tag, is more recent than the min_release_interval of
+// libraryConfig.
+func (r *stageRunner) releasedRecently(library *legacyconfig.LibraryState, libraryConfig *legacyconfig.LibraryConfig) (bool, error) {
+	if libraryConfig == nil || libraryConfig.MinReleaseInterval == 0 || library.Version == "0.0.0" {
+		return false, nil
+	}
+	tagFormat := legacyconfig.DetermineTagFormat(library.ID, library, r.librarianConfig)
+	tagName := legacyconfig.FormatTag(tagFormat, library.ID, library.Version)
+	commit, err := r.repo.GetTagCommit(tagName)
+	if err != nil {
+		return false, fmt.Errorf("failed to find the last release of library %s: %w", library.ID, err)
+	}
+	return time.Since(commit.When) < libraryConfig.MinReleaseInterval, nil
+}
+
+// dropExcludedCommits removes the commits whose hash starts with any of the
+// excluded commit hashes, and the commits whose author name or email matches
+// any of the excluded author patterns.
+func (r *stageRunner) dropExcludedCommits(commits []*legacygitrepo.ConventionalCommit) []*legacygitrepo.ConventionalCommit {
+	if len(r.excludeCommits) == 0 && len(r.excludeAuthors) == 0 {
+		return commits
+	}
+	return slices.DeleteFunc(commits, func(commit *legacygitrepo.ConventionalCommit) bool {
+		for _, hash := range r.excludeCommits {
+			if strings.HasPrefix(commit.CommitHash, strings.ToLower(hash)) {
+				slog.Info("excluding commit from release", "commit", commit.CommitHash)
+				return true
+			}
+		}
+		for _, re := range r.excludeAuthors {
+			if re.MatchString(commit.Author.Name) || re.MatchString(commit.Author.Email) {
+				slog.Info("excluding commit by excluded author from release", "commit", commit.CommitHash, "author", commit.Author.Name)
+				return true
+			}
+		}
+		return false
+	})
 }
 
 // filterCommitsByLibraryID keeps the conventional commits if the given libraryID appears in the Footer or matches
-// the libraryID in the commit.
+// the libraryID in the commit. A Library-IDs footer of "*" matches every library.
 func filterCommitsByLibraryID(commits []*legacygitrepo.ConventionalCommit, libraryID string) []*legacygitrepo.ConventionalCommit {
 	var filteredCommits []*legacygitrepo.ConventionalCommit
 	for _, commit := range commits {
 		if commit.Footers != nil {
 			ids, ok := commit.Footers["Library-IDs"]
-			libraryIDs := strings.Split(ids, ",")
-			if ok && slices.Contains(libraryIDs, libraryID) {
+			if ok && libraryIDsContain(ids, libraryID) {
 				filteredCommits = append(filteredCommits, commit)
 				continue
 			}
@@ -244,35 +840,38 @@ func filterCommitsByLibraryID(commits []*legacygitrepo.ConventionalCommit, libra
 //
 // 3. Set the library's release trigger to true.
 func (r *stageRunner) updateLibrary(library *legacyconfig.LibraryState, commits []*legacygitrepo.ConventionalCommit) error {
+	currentVersion := r.currentVersion(library)
 	var nextVersion string
 	// If library version was explicitly set, attempt to use it. Otherwise, try to determine the version from the commits.
 	if r.libraryVersion != "" {
-		slog.Info("library version override inputted", "currentVersion", library.Version, "inputVersion", r.libraryVersion)
-		nextVersion = semver.MaxVersion(library.Version, r.libraryVersion)
-		slog.Debug("determined the library's next version from version input", "library", library.ID, "nextVersion", nextVersion)
-		// Currently, nextVersion is the max of current version or input version. If nextVersion is equal to the current version,
-		// then the input version is either equal or less than current version and cannot be used for release
-		if nextVersion == library.Version {
+		slog.Info("library version override inputted", "currentVersion", currentVersion, "inputVersion", r.libraryVersion)
+		// The input version must have a higher SemVer precedence than the
+		// current version. Pre-releases are lower than their release, so
+		// 1.2.0-beta.3 can be followed by 1.2.0-beta.4 or 1.2.0, but not by
+		// 1.2.0-beta.3+build.1 which has the same precedence.
+		if semver.Compare(r.libraryVersion, currentVersion) <= 0 {
 			return fmt.Errorf("inputted version is not SemVer greater than the current version. Set a version SemVer greater than current than: %s", library.Version)
 		}
+		nextVersion = r.libraryVersion
+		slog.Debug("determined the library's next version from version input", "library", library.ID, "nextVersion", nextVersion)
 	} else {
 		var err error
-		nextVersion, err = r.determineNextVersion(commits, library.Version, library.ID)
+		nextVersion, err = r.determineNextVersion(commits, currentVersion, library.ID)
 		if err != nil {
 			return err
 		}
 		slog.Debug("determined the library's next version from commits", "library", library.ID, "nextVersion", nextVersion)
 		// Unable to find a releasable unit from the changes
-		if nextVersion == library.Version {
+		if nextVersion == currentVersion {
 			// No library was inputted for release. Skipping this library for release
-			if r.library == "" {
+			if len(r.libraries) == 0 {
 				slog.Info("library does not have any releasable units and will not be released.", "library", library.ID, "version", library.Version)
 				return nil
 			}
 			// Library was inputted for release, but does not contain a releasable unit
 			return fmt.Errorf("library does not have a releasable unit and will not be released. Use the version flag to force a release for: %s", library.ID)
 		}
-		slog.Info("updating library to the next version", "library", library.ID, "currentVersion", library.Version, "nextVersion", nextVersion)
+		slog.Info("updating library to the next version", "library", library.ID, "currentVersion", currentVersion, "nextVersion", nextVersion)
 	}
 
 	// Update the previous version, we need this value when creating release note.
@@ -283,13 +882,77 @@ func (r *stageRunner) updateLibrary(library *legacyconfig.LibraryState, commits
 	return nil
 }
 
+// currentVersion returns the version of library to derive its next version
+// from. Unless strictSemVer is set, a version that is not a complete semantic
+// version is coerced to one when possible, e.g. 1.0 to 1.0.0. Otherwise the
+// version is returned unchanged.
+func (r *stageRunner) currentVersion(library *legacyconfig.LibraryState) string {
+	if r.strictSemVer || semver.IsValid(library.Version) {
+		return library.Version
+	}
+	version, err := semver.Coerce(library.Version)
+	if err != nil {
+		return library.Version
+	}
+	slog.Warn("library version is not a semantic version, coercing it", "library", library.ID, "version", library.Version, "coerced", version)
+	return version
+}
+
+// checkSemVer returns an error listing the libraries whose version is not a
+// complete semantic version. Libraries without a version are ignored.
+func checkSemVer(libraries []*legacyconfig.LibraryState) error {
+	var offenders []string
+	for _, library := range libraries {
+		if library.Version != "" && !semver.IsValid(library.Version) {
+			offenders = append(offenders, fmt.Sprintf("%s (%s)", library.ID, library.Version))
+		}
+	}
+	if len(offenders) > 0 {
+		return fmt.Errorf("libraries with versions that are not semantic versions: %s", strings.Join(offenders, ", "))
+	}
+	return nil
+}
+
+// versionBumps returns the version bumps of commit types configured in the
+// librarian config, if any.
+func (r *stageRunner) versionBumps() map[string]string {
+	if r.librarianConfig == nil {
+		return nil
+	}
+	return r.librarianConfig.VersionBumps
+}
+
+// isDependencyUpdate reports whether the commit updates dependencies, i.e. is
+// of type "deps" or has the "deps" scope, e.g. "chore(deps): ...".
+func isDependencyUpdate(commit *legacygitrepo.ConventionalCommit) bool {
+	return commit.Type == "deps" || commit.Scope == "deps"
+}
+
+// hasOnlyDependencyUpdates reports whether at least one of the commits is a
+// dependency update, and none of the other commits bumps the version.
+func hasOnlyDependencyUpdates(commits []*legacygitrepo.ConventionalCommit, versionBumps map[string]string) bool {
+	var deps, others []*legacygitrepo.ConventionalCommit
+	for _, commit := range commits {
+		if isDependencyUpdate(commit) {
+			deps = append(deps, commit)
+		} else {
+			others = append(others, commit)
+		}
+	}
+	return len(deps) > 0 && getHighestChange(others, versionBumps) == semver.None
+}
+
 // determineNextVersion determines the next valid SemVer version from the commits or from
 // the next_version override value in the legacyconfig.yaml file.
 func (r *stageRunner) determineNextVersion(commits []*legacygitrepo.ConventionalCommit, currentVersion string, libraryID string) (string, error) {
-	nextVersionFromCommits, err := NextVersion(commits, currentVersion)
+	nextVersionFromCommits, err := NextVersion(commits, currentVersion, r.versionBumps())
 	if err != nil {
 		return "", err
 	}
+	if nextVersionFromCommits != currentVersion && !r.librarianConfig.ReleasesOnDepsOnly() && hasOnlyDependencyUpdates(commits, r.versionBumps()) {
+		slog.Info("library only has dependency updates, which are not released on their own", "library", libraryID)
+		nextVersionFromCommits = currentVersion
+	}
 
 	if r.librarianConfig == nil {
 		slog.Debug("no librarian config")
@@ -312,6 +975,7 @@ func (r *stageRunner) determineNextVersion(commits []*legacygitrepo.Conventional
 // converted.
 // Set LibraryIDs to the given libraryID if the conventional commit doesn't have key `Library-IDs` in the Footers;
 // otherwise use the value in the Footers as LibraryIDs.
+// The commits are ordered as the sections of release notes, see orderChanges.
 func toCommit(c []*legacygitrepo.ConventionalCommit, libraryID string) []*legacyconfig.Commit {
 	var commits []*legacyconfig.Commit
 	for _, cc := range c {
@@ -328,7 +992,9 @@ func toCommit(c []*legacygitrepo.ConventionalCommit, libraryID string) []*legacy
 			CommitHash:    cc.CommitHash,
 			PiperCLNumber: cc.Footers["PiperOrigin-RevId"],
 			LibraryIDs:    libraryIDs,
+			FixesVersion:  cc.Footers["Fixes-Version"],
+			IsBreaking:    cc.IsBreaking,
 		})
 	}
-	return commits
+	return orderChanges(commits)
 }
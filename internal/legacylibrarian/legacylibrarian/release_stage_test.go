@@ -15,15 +15,21 @@
 package legacylibrarian
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/google/go-cmp/cmp"
+	gh "github.com/google/go-github/v69/github"
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacygithub"
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacygitrepo"
 	"gopkg.in/yaml.v3"
 )
@@ -54,6 +60,19 @@ func TestNewStageRunner(t *testing.T) {
 			wantErr:    true,
 			wantErrMsg: "failed to create stage runner",
 		},
+		{
+			name: "invalid commit message template",
+			cfg: &legacyconfig.Config{
+				API:                   "some/api",
+				APISource:             newTestGitRepo(t).GetDir(),
+				CommitMessageTemplate: "chore: release {{.Library}}",
+				Repo:                  newTestGitRepo(t).GetDir(),
+				WorkRoot:              t.TempDir(),
+				Image:                 "gcr.io/test/test-image",
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid commit message template",
+		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			_, err := newStageRunner(test.cfg)
@@ -75,6 +94,36 @@ func TestNewStageRunner(t *testing.T) {
 	}
 }
 
+func TestSplitLibraryIDs(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name string
+		ids  string
+		want []string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "single library",
+			ids:  "a",
+			want: []string{"a"},
+		},
+		{
+			name: "multiple libraries",
+			ids:  "a, b,c,",
+			want: []string{"a", "b", "c"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := splitLibraryIDs(test.ids)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("splitLibraryIDs() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestStageRun(t *testing.T) {
 	t.Parallel()
 
@@ -216,6 +265,123 @@ func TestStageRun(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:             "run release stage command for libraries of one language",
+			containerClient:  &mockContainerClient{},
+			dockerStageCalls: 1,
+			setupRunner: func(containerClient *mockContainerClient) *stageRunner {
+				return &stageRunner{
+					workRoot:        t.TempDir(),
+					containerClient: containerClient,
+					language:        "rust",
+					state: &legacyconfig.LibrarianState{
+						Libraries: []*legacyconfig.LibraryState{
+							{
+								ID:       "another-example-id",
+								Language: "dart",
+								Version:  "1.0.0",
+								SourceRoots: []string{
+									"dir3",
+									"dir4",
+								},
+								RemoveRegex: []string{
+									"dir3",
+									"dir4",
+								},
+							},
+							{
+								ID:       "example-id",
+								Language: "rust",
+								Version:  "2.0.0",
+								SourceRoots: []string{
+									"dir1",
+									"dir2",
+								},
+								RemoveRegex: []string{
+									"dir1",
+									"dir2",
+								},
+							},
+						},
+					},
+					repo: &MockRepository{
+						Dir: t.TempDir(),
+						RemotesValue: []*legacygitrepo.Remote{
+							{
+								Name: "origin",
+								URLs: []string{"https://github.com/googleapis/librarian.git"},
+							},
+						},
+						GetCommitsForPathsSinceTagValueByTag: map[string][]*legacygitrepo.Commit{
+							"another-example-id-1.0.0": {
+								{
+									Hash:    plumbing.NewHash("123456"),
+									Message: "feat: another new feature",
+								},
+							},
+							"example-id-2.0.0": {
+								{
+									Hash:    plumbing.NewHash("abcdefg"),
+									Message: "feat: a new feature",
+								},
+							},
+						},
+						ChangedFilesInCommitValueByHash: map[string][]string{
+							plumbing.NewHash("123456").String(): {
+								"dir3/file3.txt",
+								"dir4/file4.txt",
+							},
+							plumbing.NewHash("abcdefg").String(): {
+								"dir1/file1.txt",
+								"dir2/file2.txt",
+							},
+						},
+					},
+					librarianConfig: &legacyconfig.LibrarianConfig{},
+				}
+			},
+			files: map[string]string{
+				"file1.txt":      "",
+				"dir1/file1.txt": "",
+				"dir2/file2.txt": "",
+				"dir3/file3.txt": "",
+				"dir4/file4.txt": "",
+			},
+			want: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:       "another-example-id",
+						Language: "dart",
+						Version:  "1.0.0", // version is not bumped.
+						APIs:     []*legacyconfig.API{},
+						SourceRoots: []string{
+							"dir3",
+							"dir4",
+						},
+						PreserveRegex: []string{},
+						RemoveRegex: []string{
+							"dir3",
+							"dir4",
+						},
+					},
+					{
+						ID:       "example-id",
+						Language: "rust",
+						Version:  "2.1.0", // version is bumped.
+						APIs:     []*legacyconfig.API{},
+						SourceRoots: []string{
+							"dir1",
+							"dir2",
+						},
+						PreserveRegex: []string{},
+						RemoveRegex: []string{
+							"dir1",
+							"dir2",
+						},
+					},
+				},
+			},
+		},
 		{
 			name:             "run release stage command for one library (library id in cfg)",
 			containerClient:  &mockContainerClient{},
@@ -224,7 +390,7 @@ func TestStageRun(t *testing.T) {
 				return &stageRunner{
 					workRoot:        t.TempDir(),
 					containerClient: containerClient,
-					library:         "example-id",
+					libraries:       []string{"example-id"},
 					state: &legacyconfig.LibrarianState{
 						Libraries: []*legacyconfig.LibraryState{
 							{
@@ -487,7 +653,7 @@ func TestStageRun(t *testing.T) {
 					workRoot:        t.TempDir(),
 					containerClient: containerClient,
 					// The library is explicitly specified.
-					library: "blocked-example-id",
+					libraries: []string{"blocked-example-id"},
 					state: &legacyconfig.LibrarianState{
 						Libraries: []*legacyconfig.LibraryState{
 							{
@@ -540,109 +706,117 @@ func TestStageRun(t *testing.T) {
 			},
 		},
 		{
-			name:            "run release stage command for one invalid library (invalid library id in cfg)",
-			containerClient: &mockContainerClient{},
+			name:             "run release stage command, skips frozen library even if explicitly specified",
+			containerClient:  &mockContainerClient{},
+			dockerStageCalls: 0,
 			setupRunner: func(containerClient *mockContainerClient) *stageRunner {
 				return &stageRunner{
 					workRoot:        t.TempDir(),
 					containerClient: containerClient,
-					library:         "does-not-exist",
+					libraries:       []string{"frozen-example-id"},
 					state: &legacyconfig.LibrarianState{
 						Libraries: []*legacyconfig.LibraryState{
 							{
-								ID: "another-example-id",
-							},
-							{
-								ID: "example-id",
+								ID:          "frozen-example-id",
+								Version:     "1.0.0",
+								SourceRoots: []string{"dir1"},
 							},
 						},
 					},
 					repo: &MockRepository{
 						Dir: t.TempDir(),
+						RemotesValue: []*legacygitrepo.Remote{
+							{
+								Name: "origin",
+								URLs: []string{"https://github.com/googleapis/librarian.git"},
+							},
+						},
+						GetCommitsForPathsSinceTagValueByTag: map[string][]*legacygitrepo.Commit{
+							"frozen-example-id-1.0.0": {
+								{
+									Hash:    plumbing.NewHash("123456"),
+									Message: "feat: another new feature",
+								},
+							},
+						},
+						ChangedFilesInCommitValueByHash: map[string][]string{
+							plumbing.NewHash("123456").String(): {
+								"dir1/file1.txt",
+							},
+						},
+					},
+					librarianConfig: &legacyconfig.LibrarianConfig{
+						Libraries: []*legacyconfig.LibraryConfig{
+							{LibraryID: "frozen-example-id", Frozen: true},
+						},
 					},
-					librarianConfig: &legacyconfig.LibrarianConfig{},
 				}
 			},
-			wantErr:    true,
-			wantErrMsg: "unable to find library for release",
 		},
 		{
-			name:             "run release stage command without librarian config (no legacyconfig.yaml file)",
+			name:             "run release stage command, releases frozen library with force",
 			containerClient:  &mockContainerClient{},
 			dockerStageCalls: 1,
 			setupRunner: func(containerClient *mockContainerClient) *stageRunner {
 				return &stageRunner{
 					workRoot:        t.TempDir(),
 					containerClient: containerClient,
-					library:         "example-id",
+					force:           true,
+					libraries:       []string{"frozen-example-id"},
 					state: &legacyconfig.LibrarianState{
 						Libraries: []*legacyconfig.LibraryState{
 							{
-								Version: "1.0.0",
-								ID:      "another-example-id",
-								SourceRoots: []string{
-									"dir3",
-									"dir4",
-								},
+								ID:          "frozen-example-id",
+								Version:     "1.0.0",
+								SourceRoots: []string{"dir1"},
 							},
+						},
+					},
+					repo: &MockRepository{
+						Dir: t.TempDir(),
+						RemotesValue: []*legacygitrepo.Remote{
 							{
-								Version: "2.0.0",
-								ID:      "example-id",
-								SourceRoots: []string{
-									"dir1",
-									"dir2",
-								},
-								RemoveRegex: []string{
-									"dir1",
-									"dir2",
+								Name: "origin",
+								URLs: []string{"https://github.com/googleapis/librarian.git"},
+							},
+						},
+						GetCommitsForPathsSinceTagValueByTag: map[string][]*legacygitrepo.Commit{
+							"frozen-example-id-1.0.0": {
+								{
+									Hash:    plumbing.NewHash("123456"),
+									Message: "feat: another new feature",
 								},
 							},
 						},
+						ChangedFilesInCommitValueByHash: map[string][]string{
+							plumbing.NewHash("123456").String(): {
+								"dir1/file1.txt",
+							},
+						},
+					},
+					librarianConfig: &legacyconfig.LibrarianConfig{
+						Libraries: []*legacyconfig.LibraryConfig{
+							{LibraryID: "frozen-example-id", Frozen: true},
+						},
 					},
-					repo: mockRepoWithReleasableUnit,
 				}
 			},
-			files: map[string]string{
-				"file1.txt":      "",
-				"dir1/file1.txt": "",
-				"dir2/file2.txt": "",
-			},
 			want: &legacyconfig.LibrarianState{
 				Libraries: []*legacyconfig.LibraryState{
 					{
-						Version: "1.0.0",
-						ID:      "another-example-id",
-						APIs:    []*legacyconfig.API{},
-						SourceRoots: []string{
-							"dir3",
-							"dir4",
-						},
+						ID:            "frozen-example-id",
+						Version:       "1.1.0",
+						APIs:          []*legacyconfig.API{},
+						SourceRoots:   []string{"dir1"},
 						PreserveRegex: []string{},
 						RemoveRegex:   []string{},
 					},
-					{
-						Version: "2.1.0",
-						ID:      "example-id",
-						APIs:    []*legacyconfig.API{},
-						SourceRoots: []string{
-							"dir1",
-							"dir2",
-						},
-						PreserveRegex: []string{},
-						RemoveRegex: []string{
-							"dir1",
-							"dir2",
-						},
-					},
 				},
 			},
 		},
 		{
-			name: "docker command returns error",
-			containerClient: &mockContainerClient{
-				stageErr: errors.New("simulated init error"),
-			},
-			// error occurred inside the docker container, there was a single request made to the container
+			name:             "run release stage command, skips recently released library",
+			containerClient:  &mockContainerClient{},
 			dockerStageCalls: 1,
 			setupRunner: func(containerClient *mockContainerClient) *stageRunner {
 				return &stageRunner{
@@ -651,88 +825,113 @@ func TestStageRun(t *testing.T) {
 					state: &legacyconfig.LibrarianState{
 						Libraries: []*legacyconfig.LibraryState{
 							{
+								ID:          "recent-example-id",
 								Version:     "1.0.0",
+								SourceRoots: []string{"dir1"},
+							},
+							{
 								ID:          "example-id",
+								Version:     "2.0.0",
 								SourceRoots: []string{"dir1"},
 							},
 						},
 					},
-					repo:            mockRepoWithReleasableUnit,
-					librarianConfig: &legacyconfig.LibrarianConfig{},
+					repo: &MockRepository{
+						Dir:          t.TempDir(),
+						RemotesValue: mockRepoWithReleasableUnit.RemotesValue,
+						GetTagCommitByTag: map[string]*legacygitrepo.Commit{
+							"recent-example-id-1.0.0": {When: time.Now().Add(-time.Hour)},
+							"example-id-2.0.0":        {When: time.Now().Add(-30 * 24 * time.Hour)},
+						},
+						GetCommitsForPathsSinceTagValue: []*legacygitrepo.Commit{
+							{
+								Message: "feat: a new feature",
+							},
+						},
+						ChangedFilesInCommitValue: []string{"dir1/file1.txt"},
+					},
+					librarianConfig: &legacyconfig.LibrarianConfig{
+						Libraries: []*legacyconfig.LibraryConfig{
+							{LibraryID: "recent-example-id", MinReleaseInterval: 7 * 24 * time.Hour},
+							{LibraryID: "example-id", MinReleaseInterval: 7 * 24 * time.Hour},
+						},
+					},
 				}
 			},
-			wantErr:    true,
-			wantErrMsg: "simulated init error",
-		},
-		{
-			name: "release response from container contains error message",
-			containerClient: &mockContainerClient{
-				wantErrorMsg: true,
+			want: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:            "example-id",
+						Version:       "2.1.0", // version is bumped.
+						APIs:          []*legacyconfig.API{},
+						SourceRoots:   []string{"dir1"},
+						PreserveRegex: []string{},
+						RemoveRegex:   []string{},
+					},
+					{
+						ID:            "recent-example-id",
+						Version:       "1.0.0", // version is NOT bumped.
+						APIs:          []*legacyconfig.API{},
+						SourceRoots:   []string{"dir1"},
+						PreserveRegex: []string{},
+						RemoveRegex:   []string{},
+					},
+				},
 			},
-			// error reported from the docker container, there was a single request made to the container
+		},
+		{
+			name:             "run release stage command, does not skip recently released library if explicitly specified",
+			containerClient:  &mockContainerClient{},
 			dockerStageCalls: 1,
 			setupRunner: func(containerClient *mockContainerClient) *stageRunner {
 				return &stageRunner{
 					workRoot:        t.TempDir(),
 					containerClient: containerClient,
+					libraries:       []string{"recent-example-id"},
 					state: &legacyconfig.LibrarianState{
 						Libraries: []*legacyconfig.LibraryState{
 							{
+								ID:          "recent-example-id",
 								Version:     "1.0.0",
-								ID:          "example-id",
 								SourceRoots: []string{"dir1"},
 							},
 						},
 					},
-					repo:            mockRepoWithReleasableUnit,
-					librarianConfig: &legacyconfig.LibrarianConfig{},
-				}
-			},
-			wantErr:    true,
-			wantErrMsg: "failed with error message: simulated error message",
-		},
-		{
-			name:            "invalid work root",
-			containerClient: &mockContainerClient{},
-			setupRunner: func(containerClient *mockContainerClient) *stageRunner {
-				return &stageRunner{
-					workRoot:        "/invalid/path",
-					containerClient: containerClient,
 					repo: &MockRepository{
-						Dir: t.TempDir(),
-					},
-				}
-			},
-			wantErr:    true,
-			wantErrMsg: "failed to create output dir",
-		},
-		{
-			name:            "failed to get changes from repo when releasing one library",
-			containerClient: &mockContainerClient{},
-			setupRunner: func(containerClient *mockContainerClient) *stageRunner {
-				return &stageRunner{
-					workRoot:        t.TempDir(),
-					containerClient: containerClient,
-					library:         "example-id",
-					state: &legacyconfig.LibrarianState{
-						Libraries: []*legacyconfig.LibraryState{
+						Dir:          t.TempDir(),
+						RemotesValue: mockRepoWithReleasableUnit.RemotesValue,
+						GetTagCommitByTag: map[string]*legacygitrepo.Commit{
+							"recent-example-id-1.0.0": {When: time.Now().Add(-time.Hour)},
+						},
+						GetCommitsForPathsSinceTagValue: []*legacygitrepo.Commit{
 							{
-								ID:          "example-id",
-								SourceRoots: []string{"dir1"},
+								Message: "feat: a new feature",
 							},
 						},
+						ChangedFilesInCommitValue: []string{"dir1/file1.txt"},
 					},
-					repo: &MockRepository{
-						Dir:                             t.TempDir(),
-						GetCommitsForPathsSinceTagError: errors.New("simulated error when getting commits"),
+					librarianConfig: &legacyconfig.LibrarianConfig{
+						Libraries: []*legacyconfig.LibraryConfig{
+							{LibraryID: "recent-example-id", MinReleaseInterval: 7 * 24 * time.Hour},
+						},
 					},
 				}
 			},
-			wantErr:    true,
-			wantErrMsg: "failed to fetch conventional commits for library",
+			want: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:            "recent-example-id",
+						Version:       "1.1.0",
+						APIs:          []*legacyconfig.API{},
+						SourceRoots:   []string{"dir1"},
+						PreserveRegex: []string{},
+						RemoveRegex:   []string{},
+					},
+				},
+			},
 		},
 		{
-			name:            "failed to get changes from repo when releasing multiple libraries",
+			name:            "run release stage command, missing release tag with min release interval",
 			containerClient: &mockContainerClient{},
 			setupRunner: func(containerClient *mockContainerClient) *stageRunner {
 				return &stageRunner{
@@ -742,261 +941,373 @@ func TestStageRun(t *testing.T) {
 						Libraries: []*legacyconfig.LibraryState{
 							{
 								ID:          "example-id",
+								Version:     "1.0.0",
 								SourceRoots: []string{"dir1"},
 							},
 						},
 					},
 					repo: &MockRepository{
-						Dir:                             t.TempDir(),
-						GetCommitsForPathsSinceTagError: errors.New("simulated error when getting commits"),
+						Dir: t.TempDir(),
+					},
+					librarianConfig: &legacyconfig.LibrarianConfig{
+						Libraries: []*legacyconfig.LibraryConfig{
+							{LibraryID: "example-id", MinReleaseInterval: time.Hour},
+						},
 					},
 				}
 			},
 			wantErr:    true,
-			wantErrMsg: "failed to fetch conventional commits for library",
+			wantErrMsg: "failed to find the last release of library example-id",
 		},
 		{
-			name:            "single library has no releasable units, no state change",
+			name:            "run release stage command for one invalid library (invalid library id in cfg)",
 			containerClient: &mockContainerClient{},
 			setupRunner: func(containerClient *mockContainerClient) *stageRunner {
 				return &stageRunner{
-					workRoot:        os.TempDir(),
+					workRoot:        t.TempDir(),
 					containerClient: containerClient,
+					libraries:       []string{"does-not-exist"},
 					state: &legacyconfig.LibrarianState{
 						Libraries: []*legacyconfig.LibraryState{
 							{
-								Version:     "1.0.0",
-								SourceRoots: []string{"dir1"},
+								ID: "another-example-id",
+							},
+							{
+								ID: "example-id",
 							},
 						},
 					},
 					repo: &MockRepository{
 						Dir: t.TempDir(),
-						RemotesValue: []*legacygitrepo.Remote{
-							{
-								Name: "origin",
-								URLs: []string{"https://github.com/googleapis/librarian.git"},
-							},
-						},
-						ChangedFilesInCommitValue: []string{"file.txt"},
-						GetCommitsForPathsSinceTagValue: []*legacygitrepo.Commit{
-							{
-								Message: "chore: not releasable",
-							},
-							{
-								Message: "test: not releasable",
-							},
-							{
-								Message: "build: not releasable",
-							},
-						},
 					},
-					ghClient:        &mockGitHubClient{},
 					librarianConfig: &legacyconfig.LibrarianConfig{},
 				}
 			},
+			wantErr:    true,
+			wantErrMsg: "unable to find library for release",
 		},
 		{
-			name:             "release stage has multiple libraries but only one library has a releasable unit",
+			name:             "run release stage command for multiple libraries",
 			containerClient:  &mockContainerClient{},
 			dockerStageCalls: 1,
 			setupRunner: func(containerClient *mockContainerClient) *stageRunner {
 				return &stageRunner{
-					workRoot:        os.TempDir(),
+					workRoot:        t.TempDir(),
 					containerClient: containerClient,
+					libraries:       []string{"example-id", "third-example-id"},
 					state: &legacyconfig.LibrarianState{
 						Libraries: []*legacyconfig.LibraryState{
 							{
 								Version:     "1.0.0",
 								ID:          "another-example-id",
-								SourceRoots: []string{"dir1"},
+								SourceRoots: []string{"dir3"},
 							},
 							{
 								Version:     "2.0.0",
 								ID:          "example-id",
 								SourceRoots: []string{"dir1"},
 							},
+							{
+								Version:     "3.0.0",
+								ID:          "third-example-id",
+								SourceRoots: []string{"dir2"},
+							},
 						},
 					},
 					repo: &MockRepository{
-						Dir: t.TempDir(),
-						RemotesValue: []*legacygitrepo.Remote{
+						Dir:                       t.TempDir(),
+						RemotesValue:              mockRepoWithReleasableUnit.RemotesValue,
+						ChangedFilesInCommitValue: []string{"dir1/file.txt", "dir2/file.txt"},
+						GetCommitsForPathsSinceTagValue: []*legacygitrepo.Commit{
 							{
-								Name: "origin",
-								URLs: []string{"https://github.com/googleapis/librarian.git"},
-							},
-						},
-						ChangedFilesInCommitValue: []string{"dir1/file.txt"},
-						GetCommitsForPathsSinceTagValueByTag: map[string][]*legacygitrepo.Commit{
-							"another-example-id-1.0.0": {
-								{
-									Message: "chore: not releasable",
-								},
-							},
-							"example-id-2.0.0": {
-								{
-									Message: "feat: a new feature",
-								},
+								Message: "feat: a feature",
 							},
 						},
 					},
-					ghClient:        &mockGitHubClient{},
 					librarianConfig: &legacyconfig.LibrarianConfig{},
 				}
 			},
+			files: map[string]string{
+				"dir1/file1.txt": "",
+				"dir2/file2.txt": "",
+			},
 			want: &legacyconfig.LibrarianState{
 				Libraries: []*legacyconfig.LibraryState{
 					{
+						Version:       "1.0.0",
 						ID:            "another-example-id",
-						Version:       "1.0.0", // version is NOT bumped.
 						APIs:          []*legacyconfig.API{},
-						SourceRoots:   []string{"dir1"},
+						SourceRoots:   []string{"dir3"},
 						PreserveRegex: []string{},
 						RemoveRegex:   []string{},
 					},
 					{
+						Version:       "2.1.0",
 						ID:            "example-id",
-						Version:       "2.1.0", // version is bumped.
 						APIs:          []*legacyconfig.API{},
 						SourceRoots:   []string{"dir1"},
 						PreserveRegex: []string{},
 						RemoveRegex:   []string{},
 					},
+					{
+						Version:       "3.1.0",
+						ID:            "third-example-id",
+						APIs:          []*legacyconfig.API{},
+						SourceRoots:   []string{"dir2"},
+						PreserveRegex: []string{},
+						RemoveRegex:   []string{},
+					},
 				},
 			},
 		},
 		{
-			name:             "inputted library does not have a releasable unit, version is inputted",
-			containerClient:  &mockContainerClient{},
-			dockerStageCalls: 1,
+			name:            "run release stage command for multiple libraries, one does not exist",
+			containerClient: &mockContainerClient{},
 			setupRunner: func(containerClient *mockContainerClient) *stageRunner {
 				return &stageRunner{
-					workRoot:        os.TempDir(),
+					workRoot:        t.TempDir(),
 					containerClient: containerClient,
-					library:         "another-example-id", // release only for this library
-					libraryVersion:  "3.0.0",
+					libraries:       []string{"example-id", "does-not-exist"},
 					state: &legacyconfig.LibrarianState{
 						Libraries: []*legacyconfig.LibraryState{
 							{
-								Version:     "1.0.0",
-								ID:          "another-example-id",
-								SourceRoots: []string{"dir1"},
-							},
-							{
-								Version:     "2.0.0",
-								ID:          "example-id",
-								SourceRoots: []string{"dir1"},
+								ID: "example-id",
 							},
 						},
 					},
 					repo: &MockRepository{
 						Dir: t.TempDir(),
-						RemotesValue: []*legacygitrepo.Remote{
-							{
-								Name: "origin",
-								URLs: []string{"https://github.com/googleapis/librarian.git"},
-							},
-						},
-						ChangedFilesInCommitValue: []string{"dir1/file.txt"},
-						GetCommitsForPathsSinceTagValueByTag: map[string][]*legacygitrepo.Commit{
-							"another-example-id-1.0.0": {
-								{
-									Message: "chore: not releasable",
-								},
-							},
-							"example-id-2.0.0": {
-								{
-									Message: "feat: a new feature",
-								},
-							},
-						},
 					},
-					ghClient:        &mockGitHubClient{},
 					librarianConfig: &legacyconfig.LibrarianConfig{},
 				}
 			},
-			want: &legacyconfig.LibrarianState{
-				Libraries: []*legacyconfig.LibraryState{
-					{
-						Version:       "3.0.0",
-						ID:            "another-example-id",
-						APIs:          []*legacyconfig.API{},
-						SourceRoots:   []string{"dir1"},
-						PreserveRegex: []string{},
-						RemoveRegex:   []string{},
-					},
-					{
-						Version:       "2.0.0",
-						ID:            "example-id",
-						APIs:          []*legacyconfig.API{},
-						SourceRoots:   []string{"dir1"},
-						PreserveRegex: []string{},
-						RemoveRegex:   []string{},
-					},
-				},
-			},
+			wantErr:    true,
+			wantErrMsg: "unable to find library for release: does-not-exist",
 		},
 		{
-			name:             "inputted library does not have a releasable unit, no version inputted",
-			containerClient:  &mockContainerClient{},
-			dockerStageCalls: 0, // version was not inputted, do not trigger a release
+			name:            "run release stage command with strict semver and malformed versions",
+			containerClient: &mockContainerClient{},
 			setupRunner: func(containerClient *mockContainerClient) *stageRunner {
 				return &stageRunner{
-					workRoot:        os.TempDir(),
+					workRoot:        t.TempDir(),
 					containerClient: containerClient,
-					library:         "another-example-id", // release only for this library
+					strictSemVer:    true,
 					state: &legacyconfig.LibrarianState{
 						Libraries: []*legacyconfig.LibraryState{
 							{
-								Version:     "1.0.0",
-								ID:          "another-example-id",
-								SourceRoots: []string{"dir1"},
-							},
-							{
-								Version:     "2.0.0",
-								ID:          "example-id",
-								SourceRoots: []string{"dir1"},
-							},
-						},
-					},
-					repo: &MockRepository{
-						Dir:                       t.TempDir(),
-						ChangedFilesInCommitValue: []string{"dir1/file.txt"},
-						GetCommitsForPathsSinceTagValueByTag: map[string][]*legacygitrepo.Commit{
-							"another-example-id-1.0.0": {
-								{
-									Message: "chore: not releasable",
-								},
-							},
-							"example-id-2.0.0": {
-								{
-									Message: "feat: a new feature",
-								},
+								ID:      "example-id",
+								Version: "1.0",
 							},
 						},
 					},
-					ghClient:        &mockGitHubClient{},
+					repo:            mockRepoWithReleasableUnit,
 					librarianConfig: &legacyconfig.LibrarianConfig{},
 				}
 			},
 			wantErr:    true,
-			wantErrMsg: "library does not have a releasable unit and will not be released. Use the version flag to force a release for",
+			wantErrMsg: "libraries with versions that are not semantic versions: example-id (1.0)",
 		},
 		{
-			name:             "failed to commit and push",
+			name:             "run release stage command without librarian config (no legacyconfig.yaml file)",
 			containerClient:  &mockContainerClient{},
 			dockerStageCalls: 1,
 			setupRunner: func(containerClient *mockContainerClient) *stageRunner {
 				return &stageRunner{
-					workRoot:        os.TempDir(),
+					workRoot:        t.TempDir(),
 					containerClient: containerClient,
-					push:            true,
+					libraries:       []string{"example-id"},
 					state: &legacyconfig.LibrarianState{
 						Libraries: []*legacyconfig.LibraryState{
 							{
-								Version:     "1.0.0",
-								ID:          "example-id",
-								SourceRoots: []string{"dir1"},
+								Version: "1.0.0",
+								ID:      "another-example-id",
+								SourceRoots: []string{
+									"dir3",
+									"dir4",
+								},
+							},
+							{
+								Version: "2.0.0",
+								ID:      "example-id",
+								SourceRoots: []string{
+									"dir1",
+									"dir2",
+								},
+								RemoveRegex: []string{
+									"dir1",
+									"dir2",
+								},
+							},
+						},
+					},
+					repo: mockRepoWithReleasableUnit,
+				}
+			},
+			files: map[string]string{
+				"file1.txt":      "",
+				"dir1/file1.txt": "",
+				"dir2/file2.txt": "",
+			},
+			want: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						Version: "1.0.0",
+						ID:      "another-example-id",
+						APIs:    []*legacyconfig.API{},
+						SourceRoots: []string{
+							"dir3",
+							"dir4",
+						},
+						PreserveRegex: []string{},
+						RemoveRegex:   []string{},
+					},
+					{
+						Version: "2.1.0",
+						ID:      "example-id",
+						APIs:    []*legacyconfig.API{},
+						SourceRoots: []string{
+							"dir1",
+							"dir2",
+						},
+						PreserveRegex: []string{},
+						RemoveRegex: []string{
+							"dir1",
+							"dir2",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "docker command returns error",
+			containerClient: &mockContainerClient{
+				stageErr: errors.New("simulated init error"),
+			},
+			// error occurred inside the docker container, there was a single request made to the container
+			dockerStageCalls: 1,
+			setupRunner: func(containerClient *mockContainerClient) *stageRunner {
+				return &stageRunner{
+					workRoot:        t.TempDir(),
+					containerClient: containerClient,
+					state: &legacyconfig.LibrarianState{
+						Libraries: []*legacyconfig.LibraryState{
+							{
+								Version:     "1.0.0",
+								ID:          "example-id",
+								SourceRoots: []string{"dir1"},
+							},
+						},
+					},
+					repo:            mockRepoWithReleasableUnit,
+					librarianConfig: &legacyconfig.LibrarianConfig{},
+				}
+			},
+			wantErr:    true,
+			wantErrMsg: "simulated init error",
+		},
+		{
+			name: "release response from container contains error message",
+			containerClient: &mockContainerClient{
+				wantErrorMsg: true,
+			},
+			// error reported from the docker container, there was a single request made to the container
+			dockerStageCalls: 1,
+			setupRunner: func(containerClient *mockContainerClient) *stageRunner {
+				return &stageRunner{
+					workRoot:        t.TempDir(),
+					containerClient: containerClient,
+					state: &legacyconfig.LibrarianState{
+						Libraries: []*legacyconfig.LibraryState{
+							{
+								Version:     "1.0.0",
+								ID:          "example-id",
+								SourceRoots: []string{"dir1"},
+							},
+						},
+					},
+					repo:            mockRepoWithReleasableUnit,
+					librarianConfig: &legacyconfig.LibrarianConfig{},
+				}
+			},
+			wantErr:    true,
+			wantErrMsg: "failed with error message: simulated error message",
+		},
+		{
+			name:            "invalid work root",
+			containerClient: &mockContainerClient{},
+			setupRunner: func(containerClient *mockContainerClient) *stageRunner {
+				return &stageRunner{
+					workRoot:        "/invalid/path",
+					containerClient: containerClient,
+					repo: &MockRepository{
+						Dir: t.TempDir(),
+					},
+				}
+			},
+			wantErr:    true,
+			wantErrMsg: "failed to create output dir",
+		},
+		{
+			name:            "failed to get changes from repo when releasing one library",
+			containerClient: &mockContainerClient{},
+			setupRunner: func(containerClient *mockContainerClient) *stageRunner {
+				return &stageRunner{
+					workRoot:        t.TempDir(),
+					containerClient: containerClient,
+					libraries:       []string{"example-id"},
+					state: &legacyconfig.LibrarianState{
+						Libraries: []*legacyconfig.LibraryState{
+							{
+								ID:          "example-id",
+								SourceRoots: []string{"dir1"},
+							},
+						},
+					},
+					repo: &MockRepository{
+						Dir:                             t.TempDir(),
+						GetCommitsForPathsSinceTagError: errors.New("simulated error when getting commits"),
+					},
+				}
+			},
+			wantErr:    true,
+			wantErrMsg: "failed to fetch conventional commits for library",
+		},
+		{
+			name:            "failed to get changes from repo when releasing multiple libraries",
+			containerClient: &mockContainerClient{},
+			setupRunner: func(containerClient *mockContainerClient) *stageRunner {
+				return &stageRunner{
+					workRoot:        t.TempDir(),
+					containerClient: containerClient,
+					state: &legacyconfig.LibrarianState{
+						Libraries: []*legacyconfig.LibraryState{
+							{
+								ID:          "example-id",
+								SourceRoots: []string{"dir1"},
+							},
+						},
+					},
+					repo: &MockRepository{
+						Dir:                             t.TempDir(),
+						GetCommitsForPathsSinceTagError: errors.New("simulated error when getting commits"),
+					},
+				}
+			},
+			wantErr:    true,
+			wantErrMsg: "failed to fetch conventional commits for library",
+		},
+		{
+			name:            "single library has no releasable units, no state change",
+			containerClient: &mockContainerClient{},
+			setupRunner: func(containerClient *mockContainerClient) *stageRunner {
+				return &stageRunner{
+					workRoot:        os.TempDir(),
+					containerClient: containerClient,
+					state: &legacyconfig.LibrarianState{
+						Libraries: []*legacyconfig.LibraryState{
+							{
+								Version:     "1.0.0",
+								SourceRoots: []string{"dir1"},
 							},
 						},
 					},
@@ -1008,58 +1319,329 @@ func TestStageRun(t *testing.T) {
 								URLs: []string{"https://github.com/googleapis/librarian.git"},
 							},
 						},
-						ChangedFilesInCommitValue: []string{"dir1/file.txt"},
+						ChangedFilesInCommitValue: []string{"file.txt"},
 						GetCommitsForPathsSinceTagValue: []*legacygitrepo.Commit{
 							{
-								Message: "feat: a feature",
+								Message: "chore: not releasable",
+							},
+							{
+								Message: "test: not releasable",
+							},
+							{
+								Message: "build: not releasable",
 							},
 						},
-						// This AddAll is used in commitAndPush(). If commitAndPush() is invoked,
-						// then this test should error out
-						AddAllError: errors.New("unable to add all files"),
 					},
+					ghClient:        &mockGitHubClient{},
 					librarianConfig: &legacyconfig.LibrarianConfig{},
 				}
 			},
-			wantErr:    true,
-			wantErrMsg: "failed to commit and push",
 		},
 		{
-			name:             "run release stage command with symbolic link",
+			name:             "release stage has multiple libraries but only one library has a releasable unit",
 			containerClient:  &mockContainerClient{},
 			dockerStageCalls: 1,
 			setupRunner: func(containerClient *mockContainerClient) *stageRunner {
 				return &stageRunner{
-					workRoot:        t.TempDir(),
+					workRoot:        os.TempDir(),
 					containerClient: containerClient,
-					library:         "example-id",
 					state: &legacyconfig.LibrarianState{
 						Libraries: []*legacyconfig.LibraryState{
 							{
-								Version: "1.0.0",
-								ID:      "example-id",
-								SourceRoots: []string{
-									"dir1",
+								Version:     "1.0.0",
+								ID:          "another-example-id",
+								SourceRoots: []string{"dir1"},
+							},
+							{
+								Version:     "2.0.0",
+								ID:          "example-id",
+								SourceRoots: []string{"dir1"},
+							},
+						},
+					},
+					repo: &MockRepository{
+						Dir: t.TempDir(),
+						RemotesValue: []*legacygitrepo.Remote{
+							{
+								Name: "origin",
+								URLs: []string{"https://github.com/googleapis/librarian.git"},
+							},
+						},
+						ChangedFilesInCommitValue: []string{"dir1/file.txt"},
+						GetCommitsForPathsSinceTagValueByTag: map[string][]*legacygitrepo.Commit{
+							"another-example-id-1.0.0": {
+								{
+									Message: "chore: not releasable",
+								},
+							},
+							"example-id-2.0.0": {
+								{
+									Message: "feat: a new feature",
 								},
 							},
 						},
 					},
-					repo:            mockRepoWithReleasableUnit,
+					ghClient:        &mockGitHubClient{},
 					librarianConfig: &legacyconfig.LibrarianConfig{},
 				}
 			},
-			files: map[string]string{
-				"dir1/file1.txt": "hello",
-			},
 			want: &legacyconfig.LibrarianState{
 				Libraries: []*legacyconfig.LibraryState{
 					{
-						ID:      "example-id",
-						Version: "1.1.0",
-						APIs:    []*legacyconfig.API{},
-						SourceRoots: []string{
-							"dir1",
-						},
+						ID:            "another-example-id",
+						Version:       "1.0.0", // version is NOT bumped.
+						APIs:          []*legacyconfig.API{},
+						SourceRoots:   []string{"dir1"},
+						PreserveRegex: []string{},
+						RemoveRegex:   []string{},
+					},
+					{
+						ID:            "example-id",
+						Version:       "2.1.0", // version is bumped.
+						APIs:          []*legacyconfig.API{},
+						SourceRoots:   []string{"dir1"},
+						PreserveRegex: []string{},
+						RemoveRegex:   []string{},
+					},
+				},
+			},
+		},
+		{
+			name:             "inputted library does not have a releasable unit, version is inputted",
+			containerClient:  &mockContainerClient{},
+			dockerStageCalls: 1,
+			setupRunner: func(containerClient *mockContainerClient) *stageRunner {
+				return &stageRunner{
+					workRoot:        os.TempDir(),
+					containerClient: containerClient,
+					libraries:       []string{"another-example-id"}, // release only for this library
+					libraryVersion:  "3.0.0",
+					state: &legacyconfig.LibrarianState{
+						Libraries: []*legacyconfig.LibraryState{
+							{
+								Version:     "1.0.0",
+								ID:          "another-example-id",
+								SourceRoots: []string{"dir1"},
+							},
+							{
+								Version:     "2.0.0",
+								ID:          "example-id",
+								SourceRoots: []string{"dir1"},
+							},
+						},
+					},
+					repo: &MockRepository{
+						Dir: t.TempDir(),
+						RemotesValue: []*legacygitrepo.Remote{
+							{
+								Name: "origin",
+								URLs: []string{"https://github.com/googleapis/librarian.git"},
+							},
+						},
+						ChangedFilesInCommitValue: []string{"dir1/file.txt"},
+						GetCommitsForPathsSinceTagValueByTag: map[string][]*legacygitrepo.Commit{
+							"another-example-id-1.0.0": {
+								{
+									Message: "chore: not releasable",
+								},
+							},
+							"example-id-2.0.0": {
+								{
+									Message: "feat: a new feature",
+								},
+							},
+						},
+					},
+					ghClient:        &mockGitHubClient{},
+					librarianConfig: &legacyconfig.LibrarianConfig{},
+				}
+			},
+			want: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						Version:       "3.0.0",
+						ID:            "another-example-id",
+						APIs:          []*legacyconfig.API{},
+						SourceRoots:   []string{"dir1"},
+						PreserveRegex: []string{},
+						RemoveRegex:   []string{},
+					},
+					{
+						Version:       "2.0.0",
+						ID:            "example-id",
+						APIs:          []*legacyconfig.API{},
+						SourceRoots:   []string{"dir1"},
+						PreserveRegex: []string{},
+						RemoveRegex:   []string{},
+					},
+				},
+			},
+		},
+		{
+			name:             "inputted library does not have a releasable unit, no version inputted",
+			containerClient:  &mockContainerClient{},
+			dockerStageCalls: 0, // version was not inputted, do not trigger a release
+			setupRunner: func(containerClient *mockContainerClient) *stageRunner {
+				return &stageRunner{
+					workRoot:        os.TempDir(),
+					containerClient: containerClient,
+					libraries:       []string{"another-example-id"}, // release only for this library
+					state: &legacyconfig.LibrarianState{
+						Libraries: []*legacyconfig.LibraryState{
+							{
+								Version:     "1.0.0",
+								ID:          "another-example-id",
+								SourceRoots: []string{"dir1"},
+							},
+							{
+								Version:     "2.0.0",
+								ID:          "example-id",
+								SourceRoots: []string{"dir1"},
+							},
+						},
+					},
+					repo: &MockRepository{
+						Dir:                       t.TempDir(),
+						ChangedFilesInCommitValue: []string{"dir1/file.txt"},
+						GetCommitsForPathsSinceTagValueByTag: map[string][]*legacygitrepo.Commit{
+							"another-example-id-1.0.0": {
+								{
+									Message: "chore: not releasable",
+								},
+							},
+							"example-id-2.0.0": {
+								{
+									Message: "feat: a new feature",
+								},
+							},
+						},
+					},
+					ghClient:        &mockGitHubClient{},
+					librarianConfig: &legacyconfig.LibrarianConfig{},
+				}
+			},
+			wantErr:    true,
+			wantErrMsg: "library does not have a releasable unit and will not be released. Use the version flag to force a release for",
+		},
+		{
+			name:             "failed to commit and push",
+			containerClient:  &mockContainerClient{},
+			dockerStageCalls: 1,
+			setupRunner: func(containerClient *mockContainerClient) *stageRunner {
+				return &stageRunner{
+					workRoot:        os.TempDir(),
+					containerClient: containerClient,
+					push:            true,
+					state: &legacyconfig.LibrarianState{
+						Libraries: []*legacyconfig.LibraryState{
+							{
+								Version:     "1.0.0",
+								ID:          "example-id",
+								SourceRoots: []string{"dir1"},
+							},
+						},
+					},
+					repo: &MockRepository{
+						Dir: t.TempDir(),
+						RemotesValue: []*legacygitrepo.Remote{
+							{
+								Name: "origin",
+								URLs: []string{"https://github.com/googleapis/librarian.git"},
+							},
+						},
+						ChangedFilesInCommitValue: []string{"dir1/file.txt"},
+						GetCommitsForPathsSinceTagValue: []*legacygitrepo.Commit{
+							{
+								Message: "feat: a feature",
+							},
+						},
+						// This AddAll is used in commitAndPush(). If commitAndPush() is invoked,
+						// then this test should error out
+						AddAllError: errors.New("unable to add all files"),
+					},
+					librarianConfig: &legacyconfig.LibrarianConfig{},
+				}
+			},
+			wantErr:    true,
+			wantErrMsg: "failed to commit and push",
+		},
+		{
+			name:             "write output state",
+			containerClient:  &mockContainerClient{},
+			dockerStageCalls: 1,
+			setupRunner: func(containerClient *mockContainerClient) *stageRunner {
+				return &stageRunner{
+					workRoot:        t.TempDir(),
+					containerClient: containerClient,
+					libraries:       []string{"example-id"},
+					outputState:     filepath.Join(t.TempDir(), "out", "state.yaml"),
+					state: &legacyconfig.LibrarianState{
+						Libraries: []*legacyconfig.LibraryState{
+							{
+								Version: "1.0.0",
+								ID:      "example-id",
+								SourceRoots: []string{
+									"dir1",
+								},
+							},
+						},
+					},
+					repo:            mockRepoWithReleasableUnit,
+					librarianConfig: &legacyconfig.LibrarianConfig{},
+				}
+			},
+			files: map[string]string{
+				"dir1/file1.txt": "hello",
+			},
+			want: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:            "example-id",
+						Version:       "1.1.0",
+						APIs:          []*legacyconfig.API{},
+						SourceRoots:   []string{"dir1"},
+						PreserveRegex: []string{},
+						RemoveRegex:   []string{},
+					},
+				},
+			},
+		},
+		{
+			name:             "run release stage command with symbolic link",
+			containerClient:  &mockContainerClient{},
+			dockerStageCalls: 1,
+			setupRunner: func(containerClient *mockContainerClient) *stageRunner {
+				return &stageRunner{
+					workRoot:        t.TempDir(),
+					containerClient: containerClient,
+					libraries:       []string{"example-id"},
+					state: &legacyconfig.LibrarianState{
+						Libraries: []*legacyconfig.LibraryState{
+							{
+								Version: "1.0.0",
+								ID:      "example-id",
+								SourceRoots: []string{
+									"dir1",
+								},
+							},
+						},
+					},
+					repo:            mockRepoWithReleasableUnit,
+					librarianConfig: &legacyconfig.LibrarianConfig{},
+				}
+			},
+			files: map[string]string{
+				"dir1/file1.txt": "hello",
+			},
+			want: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:      "example-id",
+						Version: "1.1.0",
+						APIs:    []*legacyconfig.API{},
+						SourceRoots: []string{
+							"dir1",
+						},
 						PreserveRegex: []string{},
 						RemoveRegex:   []string{},
 					},
@@ -1149,24 +1731,369 @@ func TestStageRun(t *testing.T) {
 			if diff := cmp.Diff(test.want, got); diff != "" {
 				t.Errorf("state mismatch (-want +got):\n%s", diff)
 			}
+			if runner.outputState == "" {
+				return
+			}
+			bytes, err = os.ReadFile(runner.outputState)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var gotOutput *legacyconfig.LibrarianState
+			if err := yaml.Unmarshal(bytes, &gotOutput); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(got, gotOutput); diff != "" {
+				t.Errorf("output state mismatch (-want +got):\n%s", diff)
+			}
 		})
 	}
 }
 
-func TestRunStageCommand(t *testing.T) {
+func TestStageRun_MaxLibrariesPerPR(t *testing.T) {
 	t.Parallel()
 	for _, test := range []struct {
-		name   string
-		state  *legacyconfig.LibrarianState
-		config *legacyconfig.LibrarianConfig
-		repo   legacygitrepo.Repository
-		client ContainerClient
-		want   *legacyconfig.LibrarianState
+		name              string
+		libraries         int
+		frozen            int
+		force             bool
+		maxLibrariesPerPR int
+		wantPRs           int
 	}{
 		{
-			name: "global_file_commits_appear_in_multiple_libraries",
-			state: &legacyconfig.LibrarianState{
-				Libraries: []*legacyconfig.LibraryState{
+			name:      "no limit",
+			libraries: 5,
+			wantPRs:   1,
+		},
+		{
+			name:              "under the limit",
+			libraries:         3,
+			maxLibrariesPerPR: 3,
+			wantPRs:           1,
+		},
+		{
+			name:              "split evenly",
+			libraries:         4,
+			maxLibrariesPerPR: 2,
+			wantPRs:           2,
+		},
+		{
+			name:              "split with remainder",
+			libraries:         5,
+			maxLibrariesPerPR: 2,
+			wantPRs:           3,
+		},
+		{
+			name:              "one library per pull request",
+			libraries:         3,
+			maxLibrariesPerPR: 1,
+			wantPRs:           3,
+		},
+		{
+			name:              "frozen libraries not counted",
+			libraries:         5,
+			frozen:            1,
+			maxLibrariesPerPR: 2,
+			wantPRs:           2,
+		},
+		{
+			name:              "frozen libraries counted with force",
+			libraries:         5,
+			frozen:            1,
+			force:             true,
+			maxLibrariesPerPR: 2,
+			wantPRs:           3,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repoDir := t.TempDir()
+			if err := os.MkdirAll(filepath.Join(repoDir, ".librarian"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			state := &legacyconfig.LibrarianState{}
+			librarianConfig := &legacyconfig.LibrarianConfig{
+				MaxLibrariesPerPR: test.maxLibrariesPerPR,
+			}
+			for i := range test.libraries {
+				id := fmt.Sprintf("library-%d", i)
+				state.Libraries = append(state.Libraries, &legacyconfig.LibraryState{
+					ID:          id,
+					Version:     "1.0.0",
+					SourceRoots: []string{"dir"},
+				})
+				if i < test.frozen {
+					librarianConfig.Libraries = append(librarianConfig.Libraries, &legacyconfig.LibraryConfig{
+						LibraryID: id,
+						Frozen:    true,
+					})
+				}
+			}
+			repo := &MockRepository{
+				Dir:           repoDir,
+				HeadHashValue: "5d5b9f0d0a9a0b1f0e4e1c3a2b7d6e5f4c3b2a19",
+				RemotesValue: []*legacygitrepo.Remote{
+					{
+						Name: "origin",
+						URLs: []string{"https://github.com/googleapis/librarian.git"},
+					},
+				},
+				ChangedFilesInCommitValue: []string{"dir/file.txt"},
+				GetCommitsForPathsSinceTagValue: []*legacygitrepo.Commit{
+					{
+						Message: "feat: a feature",
+					},
+				},
+			}
+			containerClient := &mockContainerClient{}
+			ghClient := &mockGitHubClient{
+				createdPR: &legacygithub.PullRequestMetadata{Number: 123, Repo: &legacygithub.Repository{Owner: "googleapis", Name: "librarian"}},
+			}
+			runner := &stageRunner{
+				workRoot:        t.TempDir(),
+				containerClient: containerClient,
+				ghClient:        ghClient,
+				force:           test.force,
+				push:            true,
+				state:           state,
+				repo:            repo,
+				librarianConfig: librarianConfig,
+			}
+			if err := runner.run(t.Context()); err != nil {
+				t.Fatal(err)
+			}
+			if ghClient.createPullRequestCalls != test.wantPRs {
+				t.Errorf("created %d pull requests, want %d", ghClient.createPullRequestCalls, test.wantPRs)
+			}
+			if containerClient.stageCalls != test.wantPRs {
+				t.Errorf("stage calls = %d, want %d", containerClient.stageCalls, test.wantPRs)
+			}
+			if repo.CommitCalls != test.wantPRs {
+				t.Errorf("commit calls = %d, want %d", repo.CommitCalls, test.wantPRs)
+			}
+			if want := test.wantPRs - 1; repo.CheckoutCalls != want {
+				t.Errorf("checkout calls = %d, want %d", repo.CheckoutCalls, want)
+			}
+		})
+	}
+}
+
+func TestStageRunCommitMessageTemplate(t *testing.T) {
+	t.Parallel()
+	repoDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repoDir, ".librarian"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	state := &legacyconfig.LibrarianState{
+		Libraries: []*legacyconfig.LibraryState{
+			{
+				ID:          "library-a",
+				Version:     "1.0.0",
+				SourceRoots: []string{"dir"},
+			},
+		},
+	}
+	repo := &MockRepository{
+		Dir:           repoDir,
+		HeadHashValue: "5d5b9f0d0a9a0b1f0e4e1c3a2b7d6e5f4c3b2a19",
+		RemotesValue: []*legacygitrepo.Remote{
+			{
+				Name: "origin",
+				URLs: []string{"https://github.com/googleapis/librarian.git"},
+			},
+		},
+		ChangedFilesInCommitValue: []string{"dir/file.txt"},
+		GetCommitsForPathsSinceTagValue: []*legacygitrepo.Commit{
+			{
+				Message: "feat: a feature",
+			},
+		},
+	}
+	commitTemplate, err := parseCommitMessageTemplate("chore(PROJ-1): release {{.LibraryID}} {{.Version}}", defaultReleaseCommitMessage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := &stageRunner{
+		commit:          true,
+		commitTemplate:  commitTemplate,
+		containerClient: &mockContainerClient{},
+		ghClient:        &mockGitHubClient{},
+		repo:            repo,
+		state:           state,
+		workRoot:        t.TempDir(),
+	}
+	if err := runner.run(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("chore(PROJ-1): release library-a 1.1.0", repo.LastCommitMessage); diff != "" {
+		t.Errorf("commit message mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestStageRunAmendUpdatesReleasePullRequest(t *testing.T) {
+	t.Parallel()
+	repoDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repoDir, ".librarian"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	state := &legacyconfig.LibrarianState{
+		Libraries: []*legacyconfig.LibraryState{
+			{
+				ID:          "library-a",
+				Version:     "1.0.0",
+				SourceRoots: []string{"dir"},
+			},
+		},
+	}
+	repo := &MockRepository{
+		Dir:           repoDir,
+		HeadHashValue: "5d5b9f0d0a9a0b1f0e4e1c3a2b7d6e5f4c3b2a19",
+		RemotesValue: []*legacygitrepo.Remote{
+			{
+				Name: "origin",
+				URLs: []string{"https://github.com/googleapis/librarian.git"},
+			},
+		},
+		ChangedFilesInCommitValue: []string{"dir/file.txt"},
+		GetCommitsForPathsSinceTagValue: []*legacygitrepo.Commit{
+			{
+				Message: "feat: a feature",
+			},
+		},
+	}
+	const (
+		ref        = "librarian-20250101T000000Z"
+		manualNote = "Please release after the holidays."
+	)
+	number := 12
+	title := "chore: librarian release pull request: 20250101T000000Z"
+	body := manualNote + "\n\n" + changelogSectionStart + "\nold release notes\n" + changelogSectionEnd + "\n\nReviewed by the release team."
+	ghClient := &mockGitHubClient{
+		pullRequests: []*legacygithub.PullRequest{
+			{
+				Number: &number,
+				Title:  &title,
+				Body:   &body,
+				Head:   &gh.PullRequestBranch{Ref: gh.Ptr(ref)},
+			},
+		},
+	}
+	runner := &stageRunner{
+		amend:           true,
+		branch:          "main",
+		containerClient: &mockContainerClient{},
+		ghClient:        ghClient,
+		push:            true,
+		repo:            repo,
+		state:           state,
+		workRoot:        t.TempDir(),
+	}
+	if err := runner.run(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if want := "is:pr is:open label:release:pending base:main"; ghClient.searchPullRequestsQuery != want {
+		t.Errorf("SearchPullRequests() query = %q, want %q", ghClient.searchPullRequestsQuery, want)
+	}
+	if ghClient.createPullRequestCalls != 0 {
+		t.Errorf("CreatePullRequest() called %d times, want 0", ghClient.createPullRequestCalls)
+	}
+	if repo.PushedBranch != ref {
+		t.Errorf("Push() branch = %q, want %q", repo.PushedBranch, ref)
+	}
+	got, ok := ghClient.updatedPullRequestBodies[number]
+	if !ok {
+		t.Fatalf("body of pull request #%d not updated", number)
+	}
+	if !strings.HasPrefix(got, manualNote+"\n\n"+changelogSectionStart) {
+		t.Errorf("updated body should keep the manual note before the managed section, got:\n%s", got)
+	}
+	if !strings.HasSuffix(got, changelogSectionEnd+"\n\nReviewed by the release team.") {
+		t.Errorf("updated body should keep the manual note after the managed section, got:\n%s", got)
+	}
+	if strings.Contains(got, "old release notes") || !strings.Contains(got, "library-a: 1.1.0") {
+		t.Errorf("managed section should be replaced with the new release notes, got:\n%s", got)
+	}
+}
+
+func TestStageRunPRTemplate(t *testing.T) {
+	t.Parallel()
+	repoDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repoDir, ".librarian"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(repoDir, ".github"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	prTemplate := "## Checklist\n\n- [ ] Tests pass"
+	if err := os.WriteFile(filepath.Join(repoDir, ".github", "PULL_REQUEST_TEMPLATE.md"), []byte(prTemplate+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	state := &legacyconfig.LibrarianState{
+		Libraries: []*legacyconfig.LibraryState{
+			{
+				ID:          "library-a",
+				Version:     "1.0.0",
+				SourceRoots: []string{"dir"},
+			},
+		},
+	}
+	repo := &MockRepository{
+		Dir:           repoDir,
+		HeadHashValue: "5d5b9f0d0a9a0b1f0e4e1c3a2b7d6e5f4c3b2a19",
+		RemotesValue: []*legacygitrepo.Remote{
+			{
+				Name: "origin",
+				URLs: []string{"https://github.com/googleapis/librarian.git"},
+			},
+		},
+		ChangedFilesInCommitValue: []string{"dir/file.txt"},
+		GetCommitsForPathsSinceTagValue: []*legacygitrepo.Commit{
+			{
+				Message: "feat: a feature",
+			},
+		},
+	}
+	workRoot := t.TempDir()
+	runner := &stageRunner{
+		commit:          true,
+		containerClient: &mockContainerClient{},
+		ghClient:        &mockGitHubClient{},
+		prTemplate:      ".github/PULL_REQUEST_TEMPLATE.md",
+		repo:            repo,
+		state:           state,
+		workRoot:        workRoot,
+	}
+	if err := runner.run(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(workRoot, prBodyFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(got), prTemplate+"\n\n") {
+		t.Errorf("pull request body should start with the template, got:\n%s", got)
+	}
+	if i := strings.Index(string(got), changelogSectionStart); i < len(prTemplate) {
+		t.Errorf("managed section should follow the template, got:\n%s", got)
+	}
+}
+
+func TestRunStageCommand(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name                       string
+		state                      *legacyconfig.LibrarianState
+		config                     *legacyconfig.LibrarianConfig
+		repo                       legacygitrepo.Repository
+		client                     ContainerClient
+		libraries                  []string
+		libraryVersion             string
+		skipLibrariesWithoutSource bool
+		want                       *legacyconfig.LibrarianState
+	}{
+		{
+			name: "global_file_commits_appear_in_multiple_libraries",
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
 					{
 						ID:      "another-example-id",
 						Version: "1.0.0",
@@ -1275,6 +2202,120 @@ func TestRunStageCommand(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "changelog_path_from_config",
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:          "example-id",
+						Version:     "2.0.0",
+						SourceRoots: []string{"dir1"},
+					},
+				},
+			},
+			config: &legacyconfig.LibrarianConfig{
+				Libraries: []*legacyconfig.LibraryConfig{
+					{LibraryID: "example-id", ChangelogPath: "docs/history.md"},
+				},
+			},
+			repo: &MockRepository{
+				Dir: t.TempDir(),
+				RemotesValue: []*legacygitrepo.Remote{
+					{
+						Name: "origin",
+						URLs: []string{"https://github.com/googleapis/librarian.git"},
+					},
+				},
+				GetCommitsForPathsSinceTagValueByTag: map[string][]*legacygitrepo.Commit{
+					"example-id-2.0.0": {
+						{
+							Hash:    plumbing.NewHash("123456"),
+							Message: "fix: a bug",
+						},
+					},
+				},
+				ChangedFilesInCommitValueByHash: map[string][]string{
+					plumbing.NewHash("123456").String(): {
+						"dir1/file.txt",
+					},
+				},
+			},
+			client: &mockContainerClient{},
+			want: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:              "example-id",
+						Version:         "2.0.1",
+						PreviousVersion: "2.0.0",
+						SourceRoots:     []string{"dir1"},
+						Changes: []*legacyconfig.Commit{
+							{
+								Type:       "fix",
+								Subject:    "a bug",
+								CommitHash: "1234560000000000000000000000000000000000",
+								LibraryIDs: "example-id",
+							},
+						},
+						ReleaseTriggered: true,
+						ChangelogPath:    "docs/history.md",
+					},
+				},
+			},
+		},
+		{
+			name: "library_without_source_roots_skipped",
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:      "no-source-id",
+						Version: "1.0.0",
+					},
+				},
+			},
+			config: &legacyconfig.LibrarianConfig{},
+			repo: &MockRepository{
+				Dir: t.TempDir(),
+				// Fails if the commits of the skipped library are read.
+				GetCommitsForPathsSinceTagError: errors.New("unexpected commits lookup"),
+			},
+			client:                     &mockContainerClient{},
+			skipLibrariesWithoutSource: true,
+			want: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:      "no-source-id",
+						Version: "1.0.0",
+					},
+				},
+			},
+		},
+		{
+			name: "requested_library_without_source_roots_not_skipped",
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:      "no-source-id",
+						Version: "1.0.0",
+					},
+				},
+			},
+			config:                     &legacyconfig.LibrarianConfig{},
+			repo:                       &MockRepository{Dir: t.TempDir()},
+			client:                     &mockContainerClient{},
+			libraries:                  []string{"no-source-id"},
+			libraryVersion:             "1.1.0",
+			skipLibrariesWithoutSource: true,
+			want: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:               "no-source-id",
+						Version:          "1.1.0",
+						PreviousVersion:  "1.0.0",
+						ReleaseTriggered: true,
+					},
+				},
+			},
+		},
 	} {
 		output := t.TempDir()
 		for _, globalFile := range test.config.GlobalFilesAllowlist {
@@ -1287,10 +2328,13 @@ func TestRunStageCommand(t *testing.T) {
 			}
 		}
 		r := &stageRunner{
-			repo:            test.repo,
-			state:           test.state,
-			librarianConfig: test.config,
-			containerClient: test.client,
+			repo:                       test.repo,
+			state:                      test.state,
+			librarianConfig:            test.config,
+			containerClient:            test.client,
+			libraries:                  test.libraries,
+			libraryVersion:             test.libraryVersion,
+			skipLibrariesWithoutSource: test.skipLibrariesWithoutSource,
 		}
 		err := r.runStageCommand(t.Context(), output)
 		if err != nil {
@@ -1303,337 +2347,1479 @@ func TestRunStageCommand(t *testing.T) {
 	}
 }
 
-func TestProcessLibrary(t *testing.T) {
+func TestWriteUnreleasedReport(t *testing.T) {
 	t.Parallel()
-	for _, test := range []struct {
-		name         string
-		libraryState *legacyconfig.LibraryState
-		repo         legacygitrepo.Repository
-		wantErr      bool
-		wantErrMsg   string
-	}{
+	state := &legacyconfig.LibrarianState{
+		Libraries: []*legacyconfig.LibraryState{
+			{
+				ID:          "blocked-id",
+				Version:     "3.0.0",
+				SourceRoots: []string{"dir3"},
+			},
+			{
+				ID:          "changed-id",
+				Version:     "1.0.0",
+				SourceRoots: []string{"dir1"},
+			},
+			{
+				ID:          "unchanged-id",
+				Version:     "2.0.0",
+				SourceRoots: []string{"dir2"},
+			},
+		},
+	}
+	wantState := &legacyconfig.LibrarianState{}
+	for _, library := range state.Libraries {
+		copied := *library
+		wantState.Libraries = append(wantState.Libraries, &copied)
+	}
+	repo := &MockRepository{
+		GetCommitsForPathsSinceTagValueByTag: map[string][]*legacygitrepo.Commit{
+			"changed-id-1.0.0": {
+				{
+					Hash:    plumbing.NewHash("123456"),
+					Message: "feat: add a feature",
+				},
+				{
+					Hash:    plumbing.NewHash("123457"),
+					Message: "fix: fix a bug",
+				},
+			},
+			"unchanged-id-2.0.0": {},
+		},
+		ChangedFilesInCommitValueByHash: map[string][]string{
+			plumbing.NewHash("123456").String(): {"dir1/file.txt"},
+			plumbing.NewHash("123457").String(): {"dir1/file.txt"},
+		},
+	}
+	client := &mockContainerClient{}
+	r := &stageRunner{
+		containerClient: client,
+		librarianConfig: &legacyconfig.LibrarianConfig{
+			Libraries: []*legacyconfig.LibraryConfig{
+				{
+					LibraryID:      "blocked-id",
+					ReleaseBlocked: true,
+				},
+			},
+		},
+		repo:  repo,
+		state: state,
+	}
+	var out bytes.Buffer
+	if err := r.writeUnreleasedReport(t.Context(), &out); err != nil {
+		t.Fatal(err)
+	}
+	want := `LIBRARY       VERSION  NEXT VERSION  CHANGES
+blocked-id    3.0.0    -             release blocked
+changed-id    1.0.0    1.1.0         2
+unchanged-id  2.0.0    -             0
+
+Releasing 1 library: 0 major, 1 minor, 0 patch
+`
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("writeUnreleasedReport() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(wantState, state); diff != "" {
+		t.Errorf("state should not be modified (-want +got):\n%s", diff)
+	}
+	if client.stageCalls != 0 {
+		t.Errorf("stageCalls = %d, want 0", client.stageCalls)
+	}
+}
+
+func TestWriteUnreleasedReportHiddenCommits(t *testing.T) {
+	t.Parallel()
+	state := &legacyconfig.LibrarianState{
+		Libraries: []*legacyconfig.LibraryState{
+			{
+				ID:          "changed-id",
+				Version:     "1.0.0",
+				SourceRoots: []string{"dir1"},
+			},
+			{
+				ID:          "unchanged-id",
+				Version:     "2.0.0",
+				SourceRoots: []string{"dir2"},
+			},
+		},
+	}
+	repo := &MockRepository{
+		GetCommitsForPathsSinceTagValueByTag: map[string][]*legacygitrepo.Commit{
+			"changed-id-1.0.0": {
+				{
+					Hash:    plumbing.NewHash("123456"),
+					Message: "feat: add a feature",
+				},
+				{
+					Hash:    plumbing.NewHash("123457"),
+					Message: "chore: update a config",
+				},
+			},
+			"unchanged-id-2.0.0": {
+				{
+					Hash:    plumbing.NewHash("123458"),
+					Message: "test: add a test",
+				},
+				{
+					Hash:    plumbing.NewHash("123459"),
+					Message: "build: update a build rule",
+				},
+			},
+		},
+		ChangedFilesInCommitValueByHash: map[string][]string{
+			plumbing.NewHash("123456").String(): {"dir1/file.txt"},
+			plumbing.NewHash("123457").String(): {"dir1/file.txt"},
+			plumbing.NewHash("123458").String(): {"dir2/file.txt"},
+			plumbing.NewHash("123459").String(): {"dir2/file.txt"},
+		},
+	}
+	r := &stageRunner{
+		containerClient:      &mockContainerClient{},
+		includeHiddenCommits: true,
+		librarianConfig:      &legacyconfig.LibrarianConfig{},
+		repo:                 repo,
+		state:                state,
+	}
+	var out bytes.Buffer
+	if err := r.writeUnreleasedReport(t.Context(), &out); err != nil {
+		t.Fatal(err)
+	}
+	want := `LIBRARY       VERSION  NEXT VERSION  CHANGES
+changed-id    1.0.0    1.1.0         2
+unchanged-id  2.0.0    -             0
+
+NOT RELEASED
+LIBRARY       COMMIT    TYPE   SUBJECT
+changed-id    12345700  chore  update a config
+unchanged-id  12345800  test   add a test
+unchanged-id  12345900  build  update a build rule
+
+Releasing 1 library: 0 major, 1 minor, 0 patch
+`
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("writeUnreleasedReport() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestVersionBumpSummary(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name      string
+		libraries []*legacyconfig.LibraryState
+		want      string
+	}{
+		{
+			name: "no release",
+			libraries: []*legacyconfig.LibraryState{
+				{ID: "a", Version: "1.0.0"},
+			},
+			want: "Releasing 0 libraries: 0 major, 0 minor, 0 patch",
+		},
+		{
+			name: "one library",
+			libraries: []*legacyconfig.LibraryState{
+				{ID: "a", PreviousVersion: "1.0.0", Version: "1.0.1", ReleaseTriggered: true},
+			},
+			want: "Releasing 1 library: 0 major, 0 minor, 1 patch",
+		},
+		{
+			name: "mixed bumps",
+			libraries: []*legacyconfig.LibraryState{
+				{ID: "major", PreviousVersion: "1.2.3", Version: "2.0.0", ReleaseTriggered: true},
+				{ID: "minor", PreviousVersion: "1.2.3", Version: "1.3.0", ReleaseTriggered: true},
+				{ID: "breaking-pre-1.0", PreviousVersion: "0.2.3", Version: "0.3.0", ReleaseTriggered: true},
+				{ID: "patch", PreviousVersion: "1.2.3", Version: "1.2.4", ReleaseTriggered: true},
+				{ID: "prerelease", PreviousVersion: "1.3.0-beta.1", Version: "1.3.0-beta.2", ReleaseTriggered: true},
+				{ID: "not-released", PreviousVersion: "1.0.0", Version: "2.0.0"},
+			},
+			want: "Releasing 5 libraries: 1 major, 2 minor, 2 patch",
+		},
+		{
+			name: "first release",
+			libraries: []*legacyconfig.LibraryState{
+				{ID: "new", Version: "0.1.0", ReleaseTriggered: true},
+				{ID: "patch", PreviousVersion: "1.2.3", Version: "1.2.4", ReleaseTriggered: true},
+			},
+			want: "Releasing 2 libraries: 0 major, 0 minor, 1 patch, 1 other",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			if got := versionBumpSummary(test.libraries); got != test.want {
+				t.Errorf("versionBumpSummary() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestProcessLibrary(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name         string
+		libraryState *legacyconfig.LibraryState
+		repo         legacygitrepo.Repository
+		wantErr      bool
+		wantErrMsg   string
+	}{
+		{
+			name: "failed to get commit history of one library",
+			libraryState: &legacyconfig.LibraryState{
+				ID:      "one-id",
+				Version: "1.2.3",
+			},
+			repo: &MockRepository{
+				GetCommitsForPathsSinceTagError: errors.New("simulated error when getting commits"),
+			},
+			wantErr:    true,
+			wantErrMsg: "failed to fetch conventional commits for library",
+		},
+		{
+			name: "does not search for git tag for 0.0.0 version",
+			libraryState: &legacyconfig.LibraryState{
+				ID:      "one-id",
+				Version: "0.0.0",
+			},
+			repo: &MockRepository{},
+		},
+	} {
+		state := &legacyconfig.LibrarianState{
+			Libraries: []*legacyconfig.LibraryState{
+				test.libraryState,
+			},
+		}
+		r := &stageRunner{
+			repo:  test.repo,
+			state: state,
+		}
+		err := r.processLibrary(t.Context(), test.libraryState)
+		if test.wantErr {
+			if err == nil {
+				t.Fatal("processLibrary() should return error")
+			}
+			if !strings.Contains(err.Error(), test.wantErrMsg) {
+				t.Errorf("want error message: %q, got %q", test.wantErrMsg, err.Error())
+			}
+			return
+		}
+		if err != nil {
+			t.Errorf("failed to run processLibrary(): %q", err.Error())
+		}
+		if test.libraryState.Version == "0.0.0" && test.repo.(*MockRepository).GetCommitsForPathsSinceTagLastTagName != "" {
+			t.Errorf("GetCommitsForPathsSinceTag should be called with an empty tag name for version 0.0.0, got %q", test.repo.(*MockRepository).GetCommitsForPathsSinceTagLastTagName)
+		}
+	}
+}
+
+func TestStageRunFetchUpstream(t *testing.T) {
+	t.Parallel()
+	origin := []*legacygitrepo.Remote{
+		{
+			Name: "origin",
+			URLs: []string{"https://github.com/googleapis/librarian.git"},
+		},
+	}
+	for _, test := range []struct {
+		name             string
+		fetchBeforeStage bool
+		repo             *MockRepository
+		wantCalls        int
+		wantErrMsg       string
+	}{
+		{
+			name: "disabled",
+			repo: &MockRepository{RemotesValue: origin},
+		},
+		{
+			name:             "no origin remote",
+			fetchBeforeStage: true,
+			repo:             &MockRepository{},
+		},
+		{
+			name:             "up to date",
+			fetchBeforeStage: true,
+			repo:             &MockRepository{RemotesValue: origin},
+			wantCalls:        1,
+		},
+		{
+			name:             "behind the remote",
+			fetchBeforeStage: true,
+			repo: &MockRepository{
+				RemotesValue:        origin,
+				FetchUpstreamBehind: 2,
+			},
+			wantCalls:  1,
+			wantErrMsg: "local branch is 2 commit(s) behind origin/main",
+		},
+		{
+			name:             "fetch fails",
+			fetchBeforeStage: true,
+			repo: &MockRepository{
+				RemotesValue:       origin,
+				FetchUpstreamError: errors.New("fetch error"),
+			},
+			wantCalls:  1,
+			wantErrMsg: "failed to fetch upstream",
+		},
+		{
+			name:             "remotes fail",
+			fetchBeforeStage: true,
+			repo: &MockRepository{
+				RemotesError: errors.New("remotes error"),
+			},
+			wantErrMsg: "failed to get remotes",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			state := &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:          "library-a",
+						Version:     "1.0.0",
+						SourceRoots: []string{"dir"},
+					},
+				},
+			}
+			r := &stageRunner{
+				branch:           "main",
+				containerClient:  &mockContainerClient{},
+				fetchBeforeStage: test.fetchBeforeStage,
+				repo:             test.repo,
+				state:            state,
+				workRoot:         t.TempDir(),
+			}
+			err := r.run(t.Context())
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("run() error = %v, want contains %q", err, test.wantErrMsg)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if test.repo.FetchUpstreamCalls != test.wantCalls {
+				t.Errorf("FetchUpstream calls = %d, want %d", test.repo.FetchUpstreamCalls, test.wantCalls)
+			}
+		})
+	}
+}
+
+func TestStageRunFreeze(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name         string
+		freezeUntil  time.Time
+		ignoreFreeze bool
+		wantFetches  int
+		wantErrMsg   string
+	}{
+		{
+			name:        "no freeze",
+			wantFetches: 1,
+		},
+		{
+			name:        "freeze ended",
+			freezeUntil: time.Now().Add(-time.Hour),
+			wantFetches: 1,
+		},
+		{
+			name:        "frozen",
+			freezeUntil: time.Now().Add(time.Hour),
+			wantErrMsg:  "releases are frozen until",
+		},
+		{
+			name:         "frozen with ignore freeze",
+			freezeUntil:  time.Now().Add(time.Hour),
+			ignoreFreeze: true,
+			wantFetches:  1,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			state := &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:          "library-a",
+						Version:     "1.0.0",
+						SourceRoots: []string{"dir"},
+					},
+				},
+			}
+			repo := &MockRepository{
+				RemotesValue: []*legacygitrepo.Remote{{Name: "origin"}},
+			}
+			r := &stageRunner{
+				containerClient:  &mockContainerClient{},
+				fetchBeforeStage: true,
+				ignoreFreeze:     test.ignoreFreeze,
+				librarianConfig:  &legacyconfig.LibrarianConfig{FreezeUntil: test.freezeUntil},
+				repo:             repo,
+				state:            state,
+				workRoot:         t.TempDir(),
+			}
+			err := r.run(t.Context())
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("run() error = %v, want contains %q", err, test.wantErrMsg)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if repo.FetchUpstreamCalls != test.wantFetches {
+				t.Errorf("FetchUpstream() calls = %d, want %d", repo.FetchUpstreamCalls, test.wantFetches)
+			}
+		})
+	}
+}
+
+func TestProcessLibrary_TagFormat(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name        string
+		version     string
+		tagFormat   string
+		wantTagName string
+	}{
+		{
+			name:        "default format",
+			version:     "1.2.3",
+			wantTagName: "one-id-1.2.3",
+		},
+		{
+			name:        "monorepo format",
+			version:     "1.2.3",
+			tagFormat:   "{id}/v{version}",
+			wantTagName: "one-id/v1.2.3",
+		},
+		{
+			name:        "template format",
+			version:     "1.2.3",
+			tagFormat:   "{{.ID}}/v{{.Version}}",
+			wantTagName: "one-id/v1.2.3",
+		},
+		{
+			name:      "no tag search for 0.0.0",
+			version:   "0.0.0",
+			tagFormat: "{{.ID}}/v{{.Version}}",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			library := &legacyconfig.LibraryState{
+				ID:          "one-id",
+				Version:     test.version,
+				SourceRoots: []string{"dir1"},
+			}
+			repo := &MockRepository{}
+			r := &stageRunner{
+				librarianConfig: &legacyconfig.LibrarianConfig{TagFormat: test.tagFormat},
+				repo:            repo,
+				state: &legacyconfig.LibrarianState{
+					Libraries: []*legacyconfig.LibraryState{library},
+				},
+			}
+			if err := r.processLibrary(t.Context(), library); err != nil {
+				t.Fatal(err)
+			}
+			if repo.GetCommitsForPathsSinceTagLastTagName != test.wantTagName {
+				t.Errorf("tag name = %q, want %q", repo.GetCommitsForPathsSinceTagLastTagName, test.wantTagName)
+			}
+		})
+	}
+}
+
+func TestProcessLibrary_ExcludeCommits(t *testing.T) {
+	t.Parallel()
+	fixHash := plumbing.NewHash("123456")
+	featHash := plumbing.NewHash("abcdef")
+	for _, test := range []struct {
+		name           string
+		excludeCommits []string
+		wantVersion    string
+		wantChanges    []string
+	}{
+		{
+			name:        "no excluded commits",
+			wantVersion: "1.3.0",
+			wantChanges: []string{"add a feature", "fix a bug"},
+		},
+		{
+			name:           "feat commit excluded",
+			excludeCommits: []string{featHash.String()[:7]},
+			wantVersion:    "1.2.4",
+			wantChanges:    []string{"fix a bug"},
+		},
+		{
+			name:           "feat commit excluded by full upper case hash",
+			excludeCommits: []string{strings.ToUpper(featHash.String())},
+			wantVersion:    "1.2.4",
+			wantChanges:    []string{"fix a bug"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			library := &legacyconfig.LibraryState{
+				ID:          "one-id",
+				Version:     "1.2.3",
+				SourceRoots: []string{"dir1"},
+			}
+			r := &stageRunner{
+				excludeCommits: test.excludeCommits,
+				repo: &MockRepository{
+					GetCommitsForPathsSinceTagValueByTag: map[string][]*legacygitrepo.Commit{
+						"one-id-1.2.3": {
+							{
+								Hash:    fixHash,
+								Message: "fix: fix a bug",
+							},
+							{
+								Hash:    featHash,
+								Message: "feat: add a feature",
+							},
+						},
+					},
+					ChangedFilesInCommitValueByHash: map[string][]string{
+						fixHash.String():  {"dir1/file.txt"},
+						featHash.String(): {"dir1/file.txt"},
+					},
+				},
+				state: &legacyconfig.LibrarianState{
+					Libraries: []*legacyconfig.LibraryState{library},
+				},
+			}
+			if err := r.processLibrary(t.Context(), library); err != nil {
+				t.Fatal(err)
+			}
+			if library.Version != test.wantVersion {
+				t.Errorf("version = %q, want %q", library.Version, test.wantVersion)
+			}
+			var gotChanges []string
+			for _, change := range library.Changes {
+				gotChanges = append(gotChanges, change.Subject)
+			}
+			if diff := cmp.Diff(test.wantChanges, gotChanges); diff != "" {
+				t.Errorf("changes mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProcessLibrary_ExcludeAuthors(t *testing.T) {
+	t.Parallel()
+	fixHash := plumbing.NewHash("123456")
+	featHash := plumbing.NewHash("abcdef")
+	human := legacygitrepo.Signature{Name: "Jane Doe", Email: "jane@example.com"}
+	bot := legacygitrepo.Signature{Name: "dependabot[bot]", Email: "49699333+dependabot[bot]@users.noreply.github.com"}
+	for _, test := range []struct {
+		name                 string
+		excludeAuthors       []string
+		featAuthor           legacygitrepo.Signature
+		fixAuthor            legacygitrepo.Signature
+		wantVersion          string
+		wantChanges          []string
+		wantReleaseTriggered bool
+	}{
+		{
+			name:                 "no excluded authors",
+			featAuthor:           bot,
+			fixAuthor:            human,
+			wantVersion:          "1.3.0",
+			wantChanges:          []string{"add a feature", "fix a bug"},
+			wantReleaseTriggered: true,
+		},
+		{
+			name:                 "excluded by name",
+			excludeAuthors:       []string{`^dependabot\[bot\]$`},
+			featAuthor:           bot,
+			fixAuthor:            human,
+			wantVersion:          "1.2.4",
+			wantChanges:          []string{"fix a bug"},
+			wantReleaseTriggered: true,
+		},
+		{
+			name:                 "excluded by email",
+			excludeAuthors:       []string{`@users\.noreply\.github\.com$`},
+			featAuthor:           bot,
+			fixAuthor:            human,
+			wantVersion:          "1.2.4",
+			wantChanges:          []string{"fix a bug"},
+			wantReleaseTriggered: true,
+		},
+		{
+			name:           "only excluded commits",
+			excludeAuthors: []string{`^dependabot\[bot\]$`},
+			featAuthor:     bot,
+			fixAuthor:      bot,
+			wantVersion:    "1.2.3",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			library := &legacyconfig.LibraryState{
+				ID:          "one-id",
+				Version:     "1.2.3",
+				SourceRoots: []string{"dir1"},
+			}
+			var excludeAuthors []*regexp.Regexp
+			for _, pattern := range test.excludeAuthors {
+				excludeAuthors = append(excludeAuthors, regexp.MustCompile(pattern))
+			}
+			r := &stageRunner{
+				excludeAuthors: excludeAuthors,
+				repo: &MockRepository{
+					GetCommitsForPathsSinceTagValueByTag: map[string][]*legacygitrepo.Commit{
+						"one-id-1.2.3": {
+							{
+								Hash:    fixHash,
+								Message: "fix: fix a bug",
+								Author:  test.fixAuthor,
+							},
+							{
+								Hash:    featHash,
+								Message: "feat: add a feature",
+								Author:  test.featAuthor,
+							},
+						},
+					},
+					ChangedFilesInCommitValueByHash: map[string][]string{
+						fixHash.String():  {"dir1/file.txt"},
+						featHash.String(): {"dir1/file.txt"},
+					},
+				},
+				state: &legacyconfig.LibrarianState{
+					Libraries: []*legacyconfig.LibraryState{library},
+				},
+			}
+			if err := r.processLibrary(t.Context(), library); err != nil {
+				t.Fatal(err)
+			}
+			if library.Version != test.wantVersion {
+				t.Errorf("version = %q, want %q", library.Version, test.wantVersion)
+			}
+			if library.ReleaseTriggered != test.wantReleaseTriggered {
+				t.Errorf("ReleaseTriggered = %t, want %t", library.ReleaseTriggered, test.wantReleaseTriggered)
+			}
+			var gotChanges []string
+			for _, change := range library.Changes {
+				gotChanges = append(gotChanges, change.Subject)
+			}
+			if diff := cmp.Diff(test.wantChanges, gotChanges); diff != "" {
+				t.Errorf("changes mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProcessLibrary_SquashMergeTitles(t *testing.T) {
+	t.Parallel()
+	hash := plumbing.NewHash("123456")
+	for _, test := range []struct {
+		name              string
+		squashMergeTitles bool
+		message           string
+		prTitle           string
+		getPullRequestErr error
+		wantVersion       string
+		wantChanges       []string
+		wantLookups       int
+	}{
+		{
+			name:              "pull request title drives the bump",
+			squashMergeTitles: true,
+			message:           "fix: update the client (#12)\n\n* fix: update the client\n* feat: add the foo API",
+			prTitle:           "feat: add the foo API",
+			wantVersion:       "1.3.0",
+			wantChanges:       []string{"add the foo API (#12)"},
+			wantLookups:       1,
+		},
+		{
+			name:              "pull request title with non-conventional subject",
+			squashMergeTitles: true,
+			message:           "Add the foo API (#12)",
+			prTitle:           "feat!: add the foo API",
+			wantVersion:       "2.0.0",
+			wantChanges:       []string{"add the foo API (#12)"},
+			wantLookups:       1,
+		},
+		{
+			name:        "disabled",
+			message:     "fix: update the client (#12)",
+			prTitle:     "feat: add the foo API",
+			wantVersion: "1.2.4",
+			wantChanges: []string{"update the client (#12)"},
+		},
+		{
+			name:              "not a squash merge",
+			squashMergeTitles: true,
+			message:           "fix: update the client",
+			prTitle:           "feat: add the foo API",
+			wantVersion:       "1.2.4",
+			wantChanges:       []string{"update the client"},
+		},
+		{
+			name:              "empty pull request title",
+			squashMergeTitles: true,
+			message:           "fix: update the client (#12)",
+			wantVersion:       "1.2.4",
+			wantChanges:       []string{"update the client (#12)"},
+			wantLookups:       1,
+		},
+		{
+			name:              "lookup error",
+			squashMergeTitles: true,
+			message:           "fix: update the client (#12)",
+			getPullRequestErr: errors.New("not found"),
+			wantVersion:       "1.2.4",
+			wantChanges:       []string{"update the client (#12)"},
+			wantLookups:       1,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			library := &legacyconfig.LibraryState{
+				ID:          "one-id",
+				Version:     "1.2.3",
+				SourceRoots: []string{"dir1"},
+			}
+			title := test.prTitle
+			ghClient := &mockGitHubClient{
+				pullRequest:       &legacygithub.PullRequest{Title: &title},
+				getPullRequestErr: test.getPullRequestErr,
+			}
+			r := &stageRunner{
+				ghClient: ghClient,
+				repo: &MockRepository{
+					GetCommitsForPathsSinceTagValueByTag: map[string][]*legacygitrepo.Commit{
+						"one-id-1.2.3": {{Hash: hash, Message: test.message}},
+					},
+					ChangedFilesInCommitValueByHash: map[string][]string{
+						hash.String(): {"dir1/file.txt"},
+					},
+				},
+				squashMergeTitles: test.squashMergeTitles,
+				state: &legacyconfig.LibrarianState{
+					Libraries: []*legacyconfig.LibraryState{library},
+				},
+			}
+			err := r.processLibrary(t.Context(), library)
+			if ghClient.getPullRequestCalls != test.wantLookups {
+				t.Errorf("GetPullRequest calls = %d, want %d", ghClient.getPullRequestCalls, test.wantLookups)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if library.Version != test.wantVersion {
+				t.Errorf("version = %q, want %q", library.Version, test.wantVersion)
+			}
+			var gotChanges []string
+			for _, change := range library.Changes {
+				gotChanges = append(gotChanges, change.Subject)
+			}
+			if diff := cmp.Diff(test.wantChanges, gotChanges); diff != "" {
+				t.Errorf("changes mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPullRequestTitleCached(t *testing.T) {
+	t.Parallel()
+	title := "feat: add the foo API"
+	ghClient := &mockGitHubClient{pullRequest: &legacygithub.PullRequest{Title: &title}}
+	r := &stageRunner{ghClient: ghClient}
+	for range 2 {
+		got := r.pullRequestTitle(t.Context(), 12)
+		if got != title {
+			t.Errorf("pullRequestTitle() = %q, want %q", got, title)
+		}
+	}
+	if ghClient.getPullRequestCalls != 1 {
+		t.Errorf("GetPullRequest calls = %d, want 1", ghClient.getPullRequestCalls)
+	}
+}
+
+func TestHasOnlyDependencyUpdates(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name    string
+		commits []*legacygitrepo.ConventionalCommit
+		want    bool
+	}{
+		{
+			name: "no commits",
+		},
+		{
+			name:    "docs only",
+			commits: []*legacygitrepo.ConventionalCommit{{Type: "docs"}},
+		},
+		{
+			name:    "dependency updates and docs",
+			commits: []*legacygitrepo.ConventionalCommit{{Type: "fix", Scope: "deps"}, {Type: "deps"}, {Type: "docs"}},
+			want:    true,
+		},
+		{
+			name:    "dependency updates and a fix",
+			commits: []*legacygitrepo.ConventionalCommit{{Type: "deps"}, {Type: "fix"}},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			if got := hasOnlyDependencyUpdates(test.commits, nil); got != test.want {
+				t.Errorf("hasOnlyDependencyUpdates() = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFilterCommitsByLibraryID(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name      string
+		commits   []*legacygitrepo.ConventionalCommit
+		LibraryID string
+		want      []*legacygitrepo.ConventionalCommit
+	}{
+		{
+			name: "commits_all_match_libraryID",
+			commits: []*legacygitrepo.ConventionalCommit{
+				{
+					LibraryID: "library-one",
+					Type:      "feat",
+				},
+				{
+					LibraryID: "library-one",
+					Type:      "chore",
+				},
+				{
+					LibraryID: "library-one",
+					Type:      "deps",
+				},
+			},
+			LibraryID: "library-one",
+			want: []*legacygitrepo.ConventionalCommit{
+				{
+					LibraryID: "library-one",
+					Type:      "feat",
+				},
+				{
+					LibraryID: "library-one",
+					Type:      "chore",
+				},
+				{
+					LibraryID: "library-one",
+					Type:      "deps",
+				},
+			},
+		},
+		{
+			name: "some_commits_match_libraryID",
+			commits: []*legacygitrepo.ConventionalCommit{
+				{
+					LibraryID: "library-one",
+					Type:      "feat",
+				},
+				{
+					LibraryID: "library-two",
+					Type:      "chore",
+				},
+				{
+					LibraryID: "library-three",
+					Type:      "deps",
+				},
+			},
+			LibraryID: "library-one",
+			want: []*legacygitrepo.ConventionalCommit{
+				{
+					LibraryID: "library-one",
+					Type:      "feat",
+				},
+			},
+		},
+		{
+			name: "some_commits_have_library_id_in_footer",
+			commits: []*legacygitrepo.ConventionalCommit{
+				{
+					LibraryID: "library-one",
+					Type:      "feat",
+					Footers: map[string]string{
+						"Library-IDs": "library-one,library-two",
+					},
+				},
+				{
+					LibraryID: "library-two",
+					Type:      "chore",
+					Footers: map[string]string{
+						"Library-IDs": "library-one,library-two",
+					},
+				},
+				{
+					LibraryID: "library-three",
+					Type:      "deps",
+				},
+			},
+			LibraryID: "library-one",
+			want: []*legacygitrepo.ConventionalCommit{
+				{
+					LibraryID: "library-one",
+					Type:      "feat",
+					Footers: map[string]string{
+						"Library-IDs": "library-one,library-two",
+					},
+				},
+				{
+					LibraryID: "library-two",
+					Type:      "chore",
+					Footers: map[string]string{
+						"Library-IDs": "library-one,library-two",
+					},
+				},
+			},
+		},
+		{
+			name: "some_commits_have_library_id_that_is_prefix_of_another",
+			commits: []*legacygitrepo.ConventionalCommit{
+				{
+					LibraryID: "library-one",
+					Type:      "feat",
+					Footers: map[string]string{
+						"Library-IDs": "library-one,library-one_suffix",
+					},
+				},
+				{
+					LibraryID: "library-one-suffix",
+					Type:      "chore",
+					Footers: map[string]string{
+						"Library-IDs": "library-one-suffix",
+					},
+				},
+			},
+			LibraryID: "library-one",
+			want: []*legacygitrepo.ConventionalCommit{
+				{
+					LibraryID: "library-one",
+					Type:      "feat",
+					Footers: map[string]string{
+						"Library-IDs": "library-one,library-one_suffix",
+					},
+				},
+			},
+		},
+		{
+			name: "commit_with_wildcard_in_footer",
+			commits: []*legacygitrepo.ConventionalCommit{
+				{
+					LibraryID: "library-two",
+					Type:      "deps",
+					Footers: map[string]string{
+						"Library-IDs": "*",
+					},
+				},
+				{
+					LibraryID: "library-two",
+					Type:      "feat",
+				},
+			},
+			LibraryID: "library-one",
+			want: []*legacygitrepo.ConventionalCommit{
+				{
+					LibraryID: "library-two",
+					Type:      "deps",
+					Footers: map[string]string{
+						"Library-IDs": "*",
+					},
+				},
+			},
+		},
+		{
+			name: "wildcard_does_not_break_prefix_safety",
+			commits: []*legacygitrepo.ConventionalCommit{
+				{
+					LibraryID: "library-one",
+					Type:      "deps",
+					Footers: map[string]string{
+						"Library-IDs": "*",
+					},
+				},
+				{
+					LibraryID: "library-one",
+					Type:      "feat",
+					Footers: map[string]string{
+						"Library-IDs": "library-one",
+					},
+				},
+				{
+					LibraryID: "library-one-suffix",
+					Type:      "chore",
+					Footers: map[string]string{
+						"Library-IDs": "library-one-suffix",
+					},
+				},
+			},
+			LibraryID: "library-one-suffix",
+			want: []*legacygitrepo.ConventionalCommit{
+				{
+					LibraryID: "library-one",
+					Type:      "deps",
+					Footers: map[string]string{
+						"Library-IDs": "*",
+					},
+				},
+				{
+					LibraryID: "library-one-suffix",
+					Type:      "chore",
+					Footers: map[string]string{
+						"Library-IDs": "library-one-suffix",
+					},
+				},
+			},
+		},
+		{
+			name: "wildcard_only_as_whole_entry",
+			commits: []*legacygitrepo.ConventionalCommit{
+				{
+					LibraryID: "library-two",
+					Type:      "feat",
+					Footers: map[string]string{
+						"Library-IDs": "library-*",
+					},
+				},
+			},
+			LibraryID: "library-one",
+		},
+		{
+			name: "no_commits_match_libraryID",
+			commits: []*legacygitrepo.ConventionalCommit{
+				{
+					LibraryID: "library-one",
+					Type:      "feat",
+				},
+				{
+					LibraryID: "library-two",
+					Type:      "chore",
+				},
+				{
+					LibraryID: "library-three",
+					Type:      "deps",
+				},
+			},
+			LibraryID: "invalid-library",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := filterCommitsByLibraryID(test.commits, test.LibraryID)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("commit filter mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUpdateLibrary(t *testing.T) {
+	releaseOnDepsOnly := false
+	t.Parallel()
+	for _, test := range []struct {
+		name            string
+		libraryState    *legacyconfig.LibraryState
+		libraries       []string // this is the `--library` input
+		libraryVersion  string   // this is the `--version` input
+		strictSemVer    bool
+		librarianConfig *legacyconfig.LibrarianConfig
+		commits         []*legacygitrepo.ConventionalCommit
+		want            *legacyconfig.LibraryState
+		wantErr         bool
+		wantErrMsg      string
+	}{
+		{
+			name: "coerce v-prefixed version",
+			libraryState: &legacyconfig.LibraryState{
+				ID:      "one-id",
+				Version: "v2",
+			},
+			commits: []*legacygitrepo.ConventionalCommit{
+				{
+					Type:    "feat",
+					Subject: "add a feature",
+				},
+			},
+			want: &legacyconfig.LibraryState{
+				ID:              "one-id",
+				Version:         "2.1.0",
+				PreviousVersion: "v2",
+				Changes: []*legacyconfig.Commit{
+					{
+						Type:       "feat",
+						Subject:    "add a feature",
+						LibraryIDs: "one-id",
+					},
+				},
+				ReleaseTriggered: true,
+			},
+		},
+		{
+			name: "coerce version without patch number",
+			libraryState: &legacyconfig.LibraryState{
+				ID:      "one-id",
+				Version: "1.0",
+			},
+			commits: []*legacygitrepo.ConventionalCommit{
+				{
+					Type:    "fix",
+					Subject: "fix a bug",
+				},
+			},
+			want: &legacyconfig.LibraryState{
+				ID:              "one-id",
+				Version:         "1.0.1",
+				PreviousVersion: "1.0",
+				Changes: []*legacyconfig.Commit{
+					{
+						Type:       "fix",
+						Subject:    "fix a bug",
+						LibraryIDs: "one-id",
+					},
+				},
+				ReleaseTriggered: true,
+			},
+		},
 		{
-			name: "failed to get commit history of one library",
+			name: "malformed version cannot be coerced",
 			libraryState: &legacyconfig.LibraryState{
 				ID:      "one-id",
-				Version: "1.2.3",
+				Version: "1.2.3.4",
 			},
-			repo: &MockRepository{
-				GetCommitsForPathsSinceTagError: errors.New("simulated error when getting commits"),
+			commits: []*legacygitrepo.ConventionalCommit{
+				{
+					Type:    "feat",
+					Subject: "add a feature",
+				},
 			},
 			wantErr:    true,
-			wantErrMsg: "failed to fetch conventional commits for library",
+			wantErrMsg: "failed to parse current version",
 		},
 		{
-			name: "does not search for git tag for 0.0.0 version",
+			name: "v-prefixed version with strict semver",
 			libraryState: &legacyconfig.LibraryState{
 				ID:      "one-id",
-				Version: "0.0.0",
-			},
-			repo: &MockRepository{},
-		},
-	} {
-		state := &legacyconfig.LibrarianState{
-			Libraries: []*legacyconfig.LibraryState{
-				test.libraryState,
+				Version: "v2",
 			},
-		}
-		r := &stageRunner{
-			repo:  test.repo,
-			state: state,
-		}
-		err := r.processLibrary(test.libraryState)
-		if test.wantErr {
-			if err == nil {
-				t.Fatal("processLibrary() should return error")
-			}
-			if !strings.Contains(err.Error(), test.wantErrMsg) {
-				t.Errorf("want error message: %q, got %q", test.wantErrMsg, err.Error())
-			}
-			return
-		}
-		if err != nil {
-			t.Errorf("failed to run processLibrary(): %q", err.Error())
-		}
-		if test.libraryState.Version == "0.0.0" && test.repo.(*MockRepository).GetCommitsForPathsSinceTagLastTagName != "" {
-			t.Errorf("GetCommitsForPathsSinceTag should be called with an empty tag name for version 0.0.0, got %q", test.repo.(*MockRepository).GetCommitsForPathsSinceTagLastTagName)
-		}
-	}
-}
-
-func TestFilterCommitsByLibraryID(t *testing.T) {
-	t.Parallel()
-	for _, test := range []struct {
-		name      string
-		commits   []*legacygitrepo.ConventionalCommit
-		LibraryID string
-		want      []*legacygitrepo.ConventionalCommit
-	}{
-		{
-			name: "commits_all_match_libraryID",
+			strictSemVer: true,
 			commits: []*legacygitrepo.ConventionalCommit{
 				{
-					LibraryID: "library-one",
-					Type:      "feat",
-				},
-				{
-					LibraryID: "library-one",
-					Type:      "chore",
-				},
-				{
-					LibraryID: "library-one",
-					Type:      "deps",
+					Type:    "feat",
+					Subject: "add a feature",
 				},
 			},
-			LibraryID: "library-one",
-			want: []*legacygitrepo.ConventionalCommit{
+			wantErr:    true,
+			wantErrMsg: "failed to parse current version",
+		},
+		{
+			name: "update a library, automatic version calculation",
+			libraryState: &legacyconfig.LibraryState{
+				ID:      "one-id",
+				Version: "1.2.3",
+			},
+			commits: []*legacygitrepo.ConventionalCommit{
 				{
-					LibraryID: "library-one",
-					Type:      "feat",
+					Type:    "fix",
+					Subject: "change a typo",
 				},
 				{
-					LibraryID: "library-one",
-					Type:      "chore",
+					Type:    "feat",
+					Subject: "add a config file",
+					Body:    "This is the body.",
+					Footers: map[string]string{"PiperOrigin-RevId": "12345"},
 				},
-				{
-					LibraryID: "library-one",
-					Type:      "deps",
+			},
+			want: &legacyconfig.LibraryState{
+				ID:              "one-id",
+				Version:         "1.3.0",
+				PreviousVersion: "1.2.3",
+				Changes: []*legacyconfig.Commit{
+					{
+						Type:          "feat",
+						Subject:       "add a config file",
+						Body:          "This is the body.",
+						PiperCLNumber: "12345",
+						LibraryIDs:    "one-id",
+					},
+					{
+						Type:       "fix",
+						Subject:    "change a typo",
+						LibraryIDs: "one-id",
+					},
 				},
+				ReleaseTriggered: true,
 			},
 		},
 		{
-			name: "some_commits_match_libraryID",
+			name: "update a library with releasable units, valid version inputted",
+			libraryState: &legacyconfig.LibraryState{
+				ID:      "one-id",
+				Version: "1.2.3",
+			},
+			libraryVersion: "5.0.0",
 			commits: []*legacygitrepo.ConventionalCommit{
 				{
-					LibraryID: "library-one",
-					Type:      "feat",
-				},
-				{
-					LibraryID: "library-two",
-					Type:      "chore",
+					Type:    "fix",
+					Subject: "change a typo",
 				},
 				{
-					LibraryID: "library-three",
-					Type:      "deps",
+					Type:    "feat",
+					Subject: "add a config file",
+					Body:    "This is the body.",
+					Footers: map[string]string{"PiperOrigin-RevId": "12345"},
 				},
 			},
-			LibraryID: "library-one",
-			want: []*legacygitrepo.ConventionalCommit{
-				{
-					LibraryID: "library-one",
-					Type:      "feat",
+			want: &legacyconfig.LibraryState{
+				ID:              "one-id",
+				Version:         "5.0.0", // Use the `--version` value`
+				PreviousVersion: "1.2.3",
+				Changes: []*legacyconfig.Commit{
+					{
+						Type:          "feat",
+						Subject:       "add a config file",
+						Body:          "This is the body.",
+						PiperCLNumber: "12345",
+						LibraryIDs:    "one-id",
+					},
+					{
+						Type:       "fix",
+						Subject:    "change a typo",
+						LibraryIDs: "one-id",
+					},
 				},
+				ReleaseTriggered: true,
 			},
 		},
 		{
-			name: "some_commits_have_library_id_in_footer",
+			name: "update a library with releasable units, invalid version inputted",
+			libraryState: &legacyconfig.LibraryState{
+				ID:      "one-id",
+				Version: "1.2.3",
+			},
+			libraryVersion: "1.0.0",
 			commits: []*legacygitrepo.ConventionalCommit{
 				{
-					LibraryID: "library-one",
-					Type:      "feat",
-					Footers: map[string]string{
-						"Library-IDs": "library-one,library-two",
-					},
-				},
-				{
-					LibraryID: "library-two",
-					Type:      "chore",
-					Footers: map[string]string{
-						"Library-IDs": "library-one,library-two",
-					},
+					Type:    "fix",
+					Subject: "change a typo",
 				},
 				{
-					LibraryID: "library-three",
-					Type:      "deps",
+					Type:    "feat",
+					Subject: "add a config file",
+					Body:    "This is the body.",
+					Footers: map[string]string{"PiperOrigin-RevId": "12345"},
 				},
 			},
-			LibraryID: "library-one",
-			want: []*legacygitrepo.ConventionalCommit{
+			wantErr:    true,
+			wantErrMsg: "inputted version is not SemVer greater than the current version. Set a version SemVer greater than current than",
+		},
+		{
+			name: "pre-release version, automatic bump",
+			libraryState: &legacyconfig.LibraryState{
+				ID:      "one-id",
+				Version: "1.2.0-beta.1",
+			},
+			commits: []*legacygitrepo.ConventionalCommit{
 				{
-					LibraryID: "library-one",
-					Type:      "feat",
-					Footers: map[string]string{
-						"Library-IDs": "library-one,library-two",
-					},
+					Type:    "feat",
+					Subject: "a change",
 				},
-				{
-					LibraryID: "library-two",
-					Type:      "chore",
-					Footers: map[string]string{
-						"Library-IDs": "library-one,library-two",
+			},
+			want: &legacyconfig.LibraryState{
+				ID:              "one-id",
+				Version:         "1.2.0-beta.2",
+				PreviousVersion: "1.2.0-beta.1",
+				Changes: []*legacyconfig.Commit{
+					{
+						Type:       "feat",
+						Subject:    "a change",
+						LibraryIDs: "one-id",
 					},
 				},
+				ReleaseTriggered: true,
 			},
 		},
 		{
-			name: "some_commits_have_library_id_that_is_prefix_of_another",
+			name: "pre-release version, breaking change only bumps pre-release",
+			libraryState: &legacyconfig.LibraryState{
+				ID:      "one-id",
+				Version: "1.2.0-beta.1",
+			},
 			commits: []*legacygitrepo.ConventionalCommit{
 				{
-					LibraryID: "library-one",
-					Type:      "feat",
-					Footers: map[string]string{
-						"Library-IDs": "library-one,library-one_suffix",
-					},
+					Type:    "fix",
+					Subject: "a change",
 				},
-				{
-					LibraryID: "library-one-suffix",
-					Type:      "chore",
-					Footers: map[string]string{
-						"Library-IDs": "library-one-suffix",
+			},
+			want: &legacyconfig.LibraryState{
+				ID:              "one-id",
+				Version:         "1.2.0-beta.2",
+				PreviousVersion: "1.2.0-beta.1",
+				Changes: []*legacyconfig.Commit{
+					{
+						Type:       "fix",
+						Subject:    "a change",
+						LibraryIDs: "one-id",
 					},
 				},
+				ReleaseTriggered: true,
 			},
-			LibraryID: "library-one",
-			want: []*legacygitrepo.ConventionalCommit{
+		},
+		{
+			name: "pre-release version inputted",
+			libraryState: &legacyconfig.LibraryState{
+				ID:      "one-id",
+				Version: "1.2.0-beta.1",
+			},
+			libraryVersion: "1.2.0-beta.2",
+			commits: []*legacygitrepo.ConventionalCommit{
 				{
-					LibraryID: "library-one",
-					Type:      "feat",
-					Footers: map[string]string{
-						"Library-IDs": "library-one,library-one_suffix",
+					Type:    "fix",
+					Subject: "a change",
+				},
+			},
+			want: &legacyconfig.LibraryState{
+				ID:              "one-id",
+				Version:         "1.2.0-beta.2",
+				PreviousVersion: "1.2.0-beta.1",
+				Changes: []*legacyconfig.Commit{
+					{
+						Type:       "fix",
+						Subject:    "a change",
+						LibraryIDs: "one-id",
 					},
 				},
+				ReleaseTriggered: true,
 			},
 		},
 		{
-			name: "no_commits_match_libraryID",
+			name: "pre-release numbers are compared numerically",
+			libraryState: &legacyconfig.LibraryState{
+				ID:      "one-id",
+				Version: "1.2.0-beta.9",
+			},
+			libraryVersion: "1.2.0-beta.10",
 			commits: []*legacygitrepo.ConventionalCommit{
 				{
-					LibraryID: "library-one",
-					Type:      "feat",
-				},
-				{
-					LibraryID: "library-two",
-					Type:      "chore",
+					Type:    "fix",
+					Subject: "a change",
 				},
-				{
-					LibraryID: "library-three",
-					Type:      "deps",
+			},
+			want: &legacyconfig.LibraryState{
+				ID:              "one-id",
+				Version:         "1.2.0-beta.10",
+				PreviousVersion: "1.2.0-beta.9",
+				Changes: []*legacyconfig.Commit{
+					{
+						Type:       "fix",
+						Subject:    "a change",
+						LibraryIDs: "one-id",
+					},
 				},
+				ReleaseTriggered: true,
 			},
-			LibraryID: "invalid-library",
 		},
-	} {
-		t.Run(test.name, func(t *testing.T) {
-			got := filterCommitsByLibraryID(test.commits, test.LibraryID)
-			if diff := cmp.Diff(test.want, got); diff != "" {
-				t.Errorf("commit filter mismatch (-want +got):\n%s", diff)
-			}
-		})
-	}
-}
-
-func TestUpdateLibrary(t *testing.T) {
-	t.Parallel()
-	for _, test := range []struct {
-		name           string
-		libraryState   *legacyconfig.LibraryState
-		library        string // this is the `--library` input
-		libraryVersion string // this is the `--version` input
-		commits        []*legacygitrepo.ConventionalCommit
-		want           *legacyconfig.LibraryState
-		wantErr        bool
-		wantErrMsg     string
-	}{
 		{
-			name: "update a library, automatic version calculation",
+			name: "pre-release identifiers are compared per SemVer",
 			libraryState: &legacyconfig.LibraryState{
 				ID:      "one-id",
-				Version: "1.2.3",
+				Version: "1.2.0-alpha.3",
 			},
+			libraryVersion: "1.2.0-beta.1",
 			commits: []*legacygitrepo.ConventionalCommit{
 				{
 					Type:    "fix",
-					Subject: "change a typo",
-				},
-				{
-					Type:    "feat",
-					Subject: "add a config file",
-					Body:    "This is the body.",
-					Footers: map[string]string{"PiperOrigin-RevId": "12345"},
+					Subject: "a change",
 				},
 			},
 			want: &legacyconfig.LibraryState{
 				ID:              "one-id",
-				Version:         "1.3.0",
-				PreviousVersion: "1.2.3",
+				Version:         "1.2.0-beta.1",
+				PreviousVersion: "1.2.0-alpha.3",
 				Changes: []*legacyconfig.Commit{
 					{
 						Type:       "fix",
-						Subject:    "change a typo",
+						Subject:    "a change",
 						LibraryIDs: "one-id",
 					},
-					{
-						Type:          "feat",
-						Subject:       "add a config file",
-						Body:          "This is the body.",
-						PiperCLNumber: "12345",
-						LibraryIDs:    "one-id",
-					},
 				},
 				ReleaseTriggered: true,
 			},
 		},
 		{
-			name: "update a library with releasable units, valid version inputted",
+			name: "graduate a pre-release version",
 			libraryState: &legacyconfig.LibraryState{
 				ID:      "one-id",
-				Version: "1.2.3",
+				Version: "1.2.0-beta.3",
 			},
-			libraryVersion: "5.0.0",
+			libraryVersion: "1.2.0",
 			commits: []*legacygitrepo.ConventionalCommit{
 				{
 					Type:    "fix",
-					Subject: "change a typo",
-				},
-				{
-					Type:    "feat",
-					Subject: "add a config file",
-					Body:    "This is the body.",
-					Footers: map[string]string{"PiperOrigin-RevId": "12345"},
+					Subject: "a change",
 				},
 			},
 			want: &legacyconfig.LibraryState{
 				ID:              "one-id",
-				Version:         "5.0.0", // Use the `--version` value`
-				PreviousVersion: "1.2.3",
+				Version:         "1.2.0",
+				PreviousVersion: "1.2.0-beta.3",
 				Changes: []*legacyconfig.Commit{
 					{
 						Type:       "fix",
-						Subject:    "change a typo",
+						Subject:    "a change",
 						LibraryIDs: "one-id",
 					},
-					{
-						Type:          "feat",
-						Subject:       "add a config file",
-						Body:          "This is the body.",
-						PiperCLNumber: "12345",
-						LibraryIDs:    "one-id",
-					},
 				},
 				ReleaseTriggered: true,
 			},
 		},
 		{
-			name: "update a library with releasable units, invalid version inputted",
+			name: "pre-release version inputted is not greater",
 			libraryState: &legacyconfig.LibraryState{
 				ID:      "one-id",
-				Version: "1.2.3",
+				Version: "1.2.0-beta.10",
 			},
-			libraryVersion: "1.0.0",
+			libraryVersion: "1.2.0-beta.9",
 			commits: []*legacygitrepo.ConventionalCommit{
 				{
 					Type:    "fix",
-					Subject: "change a typo",
+					Subject: "a change",
 				},
+			},
+			wantErr:    true,
+			wantErrMsg: "inputted version is not SemVer greater than the current version",
+		},
+		{
+			name: "pre-release version inputted only differs by build metadata",
+			libraryState: &legacyconfig.LibraryState{
+				ID:      "one-id",
+				Version: "1.2.0-beta.1",
+			},
+			libraryVersion: "1.2.0-beta.1+build.5",
+			commits: []*legacygitrepo.ConventionalCommit{
 				{
-					Type:    "feat",
-					Subject: "add a config file",
-					Body:    "This is the body.",
-					Footers: map[string]string{"PiperOrigin-RevId": "12345"},
+					Type:    "fix",
+					Subject: "a change",
 				},
 			},
 			wantErr:    true,
-			wantErrMsg: "inputted version is not SemVer greater than the current version. Set a version SemVer greater than current than",
+			wantErrMsg: "inputted version is not SemVer greater than the current version",
+		},
+		{
+			name: "pre-release version inputted is lower than release",
+			libraryState: &legacyconfig.LibraryState{
+				ID:      "one-id",
+				Version: "1.2.0",
+			},
+			libraryVersion: "1.2.0-rc.1",
+			commits: []*legacygitrepo.ConventionalCommit{
+				{
+					Type:    "fix",
+					Subject: "a change",
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: "inputted version is not SemVer greater than the current version",
 		},
 		{
 			name: "update a library with library ids in footer",
@@ -1696,11 +3882,13 @@ func TestUpdateLibrary(t *testing.T) {
 						Subject:    "add another config file",
 						Body:       "This is the body",
 						LibraryIDs: "one-id",
+						IsBreaking: true,
 					},
 					{
 						Type:       "feat",
 						Subject:    "change a typo",
 						LibraryIDs: "one-id",
+						IsBreaking: true,
 					},
 				},
 				ReleaseTriggered: true,
@@ -1723,7 +3911,7 @@ func TestUpdateLibrary(t *testing.T) {
 				ID:      "one-id",
 				Version: "1.2.3",
 			},
-			library: "one-id",
+			libraries: []string{"one-id"},
 			commits: []*legacygitrepo.ConventionalCommit{
 				{
 					Type:    "chore",
@@ -1739,7 +3927,7 @@ func TestUpdateLibrary(t *testing.T) {
 				ID:      "one-id",
 				Version: "1.2.3",
 			},
-			library:        "one-id",
+			libraries:      []string{"one-id"},
 			libraryVersion: "5.0.0",
 			commits: []*legacygitrepo.ConventionalCommit{
 				{
@@ -1761,11 +3949,164 @@ func TestUpdateLibrary(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "dependency updates only, released by default",
+			libraryState: &legacyconfig.LibraryState{
+				ID:      "one-id",
+				Version: "1.2.3",
+			},
+			commits: []*legacygitrepo.ConventionalCommit{
+				{
+					Type:    "fix",
+					Scope:   "deps",
+					Subject: "update dependency foo to v2",
+				},
+			},
+			want: &legacyconfig.LibraryState{
+				ID:              "one-id",
+				Version:         "1.2.4",
+				PreviousVersion: "1.2.3",
+				Changes: []*legacyconfig.Commit{
+					{
+						Type:       "fix",
+						Subject:    "update dependency foo to v2",
+						LibraryIDs: "one-id",
+					},
+				},
+				ReleaseTriggered: true,
+			},
+		},
+		{
+			name: "dependency updates only, not released",
+			libraryState: &legacyconfig.LibraryState{
+				ID:      "one-id",
+				Version: "1.2.3",
+			},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				ReleaseOnDepsOnly: &releaseOnDepsOnly,
+			},
+			commits: []*legacygitrepo.ConventionalCommit{
+				{
+					Type:    "fix",
+					Scope:   "deps",
+					Subject: "update dependency foo to v2",
+				},
+				{
+					Type:    "deps",
+					Subject: "update dependency bar to v3",
+				},
+				{
+					Type:    "docs",
+					Subject: "fix a typo",
+				},
+			},
+			want: &legacyconfig.LibraryState{
+				ID:      "one-id",
+				Version: "1.2.3",
+			},
+		},
+		{
+			name: "dependency updates only, inputted for release",
+			libraryState: &legacyconfig.LibraryState{
+				ID:      "one-id",
+				Version: "1.2.3",
+			},
+			libraries: []string{"one-id"},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				ReleaseOnDepsOnly: &releaseOnDepsOnly,
+			},
+			commits: []*legacygitrepo.ConventionalCommit{
+				{
+					Type:    "fix",
+					Scope:   "deps",
+					Subject: "update dependency foo to v2",
+				},
+			},
+			wantErr:    true,
+			wantErrMsg: "library does not have a releasable unit",
+		},
+		{
+			name: "dependency updates only, with next_version",
+			libraryState: &legacyconfig.LibraryState{
+				ID:      "one-id",
+				Version: "1.2.3",
+			},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				ReleaseOnDepsOnly: &releaseOnDepsOnly,
+				Libraries: []*legacyconfig.LibraryConfig{
+					{
+						LibraryID:   "one-id",
+						NextVersion: "2.0.0",
+					},
+				},
+			},
+			commits: []*legacygitrepo.ConventionalCommit{
+				{
+					Type:    "fix",
+					Scope:   "deps",
+					Subject: "update dependency foo to v2",
+				},
+			},
+			want: &legacyconfig.LibraryState{
+				ID:              "one-id",
+				Version:         "2.0.0",
+				PreviousVersion: "1.2.3",
+				Changes: []*legacyconfig.Commit{
+					{
+						Type:       "fix",
+						Subject:    "update dependency foo to v2",
+						LibraryIDs: "one-id",
+					},
+				},
+				ReleaseTriggered: true,
+			},
+		},
+		{
+			name: "dependency updates with a feature",
+			libraryState: &legacyconfig.LibraryState{
+				ID:      "one-id",
+				Version: "1.2.3",
+			},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				ReleaseOnDepsOnly: &releaseOnDepsOnly,
+			},
+			commits: []*legacygitrepo.ConventionalCommit{
+				{
+					Type:    "fix",
+					Scope:   "deps",
+					Subject: "update dependency foo to v2",
+				},
+				{
+					Type:    "feat",
+					Subject: "add a feature",
+				},
+			},
+			want: &legacyconfig.LibraryState{
+				ID:              "one-id",
+				Version:         "1.3.0",
+				PreviousVersion: "1.2.3",
+				Changes: []*legacyconfig.Commit{
+					{
+						Type:       "feat",
+						Subject:    "add a feature",
+						LibraryIDs: "one-id",
+					},
+					{
+						Type:       "fix",
+						Subject:    "update dependency foo to v2",
+						LibraryIDs: "one-id",
+					},
+				},
+				ReleaseTriggered: true,
+			},
+		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			r := &stageRunner{
-				library:        test.library,
-				libraryVersion: test.libraryVersion,
+				libraries:       test.libraries,
+				libraryVersion:  test.libraryVersion,
+				strictSemVer:    test.strictSemVer,
+				librarianConfig: test.librarianConfig,
 			}
 			err := r.updateLibrary(test.libraryState, test.commits)
 
@@ -1788,6 +4129,49 @@ func TestUpdateLibrary(t *testing.T) {
 	}
 }
 
+func TestCheckSemVer(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name       string
+		libraries  []*legacyconfig.LibraryState
+		wantErrMsg string
+	}{
+		{
+			name: "all semantic versions",
+			libraries: []*legacyconfig.LibraryState{
+				{ID: "a", Version: "1.2.3"},
+				{ID: "b", Version: "0.1.0-beta.1"},
+				{ID: "c"},
+			},
+		},
+		{
+			name: "malformed versions",
+			libraries: []*legacyconfig.LibraryState{
+				{ID: "a", Version: "1.0"},
+				{ID: "b", Version: "1.2.3"},
+				{ID: "c", Version: "v2"},
+			},
+			wantErrMsg: "libraries with versions that are not semantic versions: a (1.0), c (v2)",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkSemVer(test.libraries)
+			if test.wantErrMsg == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("checkSemVer() should return error")
+			}
+			if diff := cmp.Diff(test.wantErrMsg, err.Error()); diff != "" {
+				t.Errorf("checkSemVer() error mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestDetermineNextVersion(t *testing.T) {
 	t.Parallel()
 	for _, test := range []struct {
@@ -1817,6 +4201,21 @@ func TestDetermineNextVersion(t *testing.T) {
 			wantVersion:    "1.1.0",
 			wantErr:        false,
 		},
+		{
+			name: "with configured version bumps",
+			commits: []*legacygitrepo.ConventionalCommit{
+				{Type: "perf"},
+			},
+			config: &legacyconfig.Config{
+				Library: "some-library",
+			},
+			libraryID: "some-library",
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				VersionBumps: map[string]string{"perf": "patch"},
+			},
+			currentVersion: "1.0.0",
+			wantVersion:    "1.0.1",
+		},
 		{
 			name: "with legacyconfig.yaml override version",
 			commits: []*legacygitrepo.ConventionalCommit{
@@ -27,6 +27,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacycli"
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacygitrepo"
 	"gopkg.in/yaml.v3"
@@ -41,13 +42,13 @@ const (
 
 // Utility functions for saving and loading pipeline state and config from various places.
 
-func loadRepoState(repo *legacygitrepo.LocalRepository, source string) (*legacyconfig.LibrarianState, error) {
+func loadRepoState(repo *legacygitrepo.LocalRepository, librarianConfig *legacyconfig.LibrarianConfig, source string) (*legacyconfig.LibrarianState, error) {
 	if repo == nil {
 		slog.Info("repo is nil, skipping state loading")
 		return nil, nil
 	}
 	path := filepath.Join(repo.Dir, legacyconfig.LibrarianDir, librarianStateFile)
-	return parseLibrarianState(path, source)
+	return parseLibrarianState(path, librarianConfig, source)
 }
 
 func loadRepoStateFromGitHub(ctx context.Context, ghClient GitHubClient, branch string) (*legacyconfig.LibrarianState, error) {
@@ -55,7 +56,7 @@ func loadRepoStateFromGitHub(ctx context.Context, ghClient GitHubClient, branch
 	if err != nil {
 		return nil, err
 	}
-	state, err := loadLibrarianStateFromBytes(content, "")
+	state, err := loadLibrarianStateFromBytes(content, nil, "")
 	if err != nil {
 		return nil, err
 	}
@@ -83,20 +84,20 @@ func loadLibrarianConfigFromGitHub(ctx context.Context, ghClient GitHubClient, b
 	return cfg, nil
 }
 
-func parseLibrarianState(path, source string) (*legacyconfig.LibrarianState, error) {
+func parseLibrarianState(path string, librarianConfig *legacyconfig.LibrarianConfig, source string) (*legacyconfig.LibrarianState, error) {
 	bytes, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	return loadLibrarianStateFromBytes(bytes, source)
+	return loadLibrarianStateFromBytes(bytes, librarianConfig, source)
 }
 
-func loadLibrarianStateFromBytes(data []byte, source string) (*legacyconfig.LibrarianState, error) {
+func loadLibrarianStateFromBytes(data []byte, librarianConfig *legacyconfig.LibrarianConfig, source string) (*legacyconfig.LibrarianState, error) {
 	var s legacyconfig.LibrarianState
 	if err := yaml.Unmarshal(data, &s); err != nil {
 		return nil, fmt.Errorf("unmarshaling librarian state: %w", err)
 	}
-	if err := populateServiceConfigIfEmpty(&s, source); err != nil {
+	if err := populateServiceConfigIfEmpty(&s, librarianConfig, source); err != nil {
 		return nil, fmt.Errorf("populating service config: %w", err)
 	}
 	if err := s.Validate(); err != nil {
@@ -125,10 +126,13 @@ func loadLibrarianConfigFromBytes(data []byte) (*legacyconfig.LibrarianConfig, e
 	if err := lc.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid global config: %w", err)
 	}
+	if err := lc.CheckLibrarianVersion(legacycli.Version()); err != nil {
+		return nil, err
+	}
 	return &lc, nil
 }
 
-func populateServiceConfigIfEmpty(state *legacyconfig.LibrarianState, source string) error {
+func populateServiceConfigIfEmpty(state *legacyconfig.LibrarianState, librarianConfig *legacyconfig.LibrarianConfig, source string) error {
 	if source == "" {
 		slog.Info("source not specified, skipping service config population")
 		return nil
@@ -139,11 +143,13 @@ func populateServiceConfigIfEmpty(state *legacyconfig.LibrarianState, source str
 				// Do not change API if the service config has already been set.
 				continue
 			}
-			apiPath := filepath.Join(source, api.Path)
-			serviceConfig, err := findServiceConfigIn(apiPath)
+			serviceConfig, err := findServiceConfig(librarianConfig, library.ID, source, api.Path)
 			if err != nil {
 				return err
 			}
+			if serviceConfig == "" {
+				slog.Info("no service config found; assuming proto-only package", "path", filepath.Join(source, api.Path))
+			}
 			state.Libraries[i].APIs[j].ServiceConfig = serviceConfig
 		}
 	}
@@ -151,6 +157,22 @@ func populateServiceConfigIfEmpty(state *legacyconfig.LibrarianState, source str
 	return nil
 }
 
+// findServiceConfig returns the service config of the API at apiPath of the
+// library with the given ID: the file of the API directory in source matching
+// its entry in the service_configs of the library config, if any, or else the
+// one detected by findServiceConfigIn.
+func findServiceConfig(librarianConfig *legacyconfig.LibrarianConfig, libraryID, source, apiPath string) (string, error) {
+	dir := filepath.Join(source, apiPath)
+	if librarianConfig != nil {
+		if libraryConfig := librarianConfig.LibraryConfigFor(libraryID); libraryConfig != nil {
+			if pattern, ok := libraryConfig.ServiceConfigs[apiPath]; ok {
+				return findServiceConfigMatching(dir, pattern)
+			}
+		}
+	}
+	return findServiceConfigIn(dir)
+}
+
 // findServiceConfigIn detects the service config in a given path.
 //
 // Returns the file name (relative to the given path) if the following criteria
@@ -159,12 +181,16 @@ func populateServiceConfigIfEmpty(state *legacyconfig.LibrarianState, source str
 // 1. the file ends with `.yaml` and it is a valid yaml file.
 //
 // 2. the file contains `type: google.api.Service`.
+//
+// An empty string is returned if no file meets them, and an error if several
+// files do, as the service config of the API must then be configured.
 func findServiceConfigIn(path string) (string, error) {
 	entries, err := os.ReadDir(path)
 	if err != nil {
 		return "", fmt.Errorf("failed to read dir %q: %w", path, err)
 	}
 
+	var serviceConfigs []string
 	for _, entry := range entries {
 		if !strings.HasSuffix(entry.Name(), ".yaml") {
 			continue
@@ -178,17 +204,56 @@ func findServiceConfigIn(path string) (string, error) {
 			return "", err
 		}
 		if value, ok := configMap[serviceConfigType].(string); ok && value == serviceConfigValue {
-			return entry.Name(), nil
+			serviceConfigs = append(serviceConfigs, entry.Name())
 		}
 	}
 
-	slog.Info("no service config found; assuming proto-only package", "path", path)
-	return "", nil
+	switch len(serviceConfigs) {
+	case 0:
+		return "", nil
+	case 1:
+		return serviceConfigs[0], nil
+	default:
+		return "", fmt.Errorf("found %d service configs in %q: %s; set the service config of the API in service_configs of the library config",
+			len(serviceConfigs), path, strings.Join(serviceConfigs, ", "))
+	}
+}
+
+// findServiceConfigMatching returns the name of the only file in the
+// directory at dir matching pattern, as configured in the service_configs of
+// a library, or an error if no file or several files match it.
+func findServiceConfigMatching(dir, pattern string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read dir %q: %w", dir, err)
+	}
+	var matches []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if ok, _ := path.Match(pattern, entry.Name()); ok {
+			matches = append(matches, entry.Name())
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no service config matching %q found in %q", pattern, dir)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("found %d service configs matching %q in %q: %s", len(matches), pattern, dir, strings.Join(matches, ", "))
+	}
 }
 
 func saveLibrarianState(repoDir string, state *legacyconfig.LibrarianState) error {
+	return writeLibrarianState(filepath.Join(repoDir, legacyconfig.LibrarianDir, librarianStateFile), state)
+}
+
+// writeLibrarianState writes state to the YAML file at path, creating its
+// parent directory if needed.
+func writeLibrarianState(path string, state *legacyconfig.LibrarianState) error {
 	sortByLibraryID(state)
-	stateFile := filepath.Join(repoDir, legacyconfig.LibrarianDir, librarianStateFile)
 	var buffer bytes.Buffer
 	encoder := yaml.NewEncoder(&buffer)
 	encoder.SetIndent(2)
@@ -196,7 +261,16 @@ func saveLibrarianState(repoDir string, state *legacyconfig.LibrarianState) erro
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(stateFile, buffer.Bytes(), 0644)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buffer.Bytes(), 0644)
+}
+
+// matchesLanguage reports whether library is of the given language. Every
+// library matches an empty language.
+func matchesLanguage(library *legacyconfig.LibraryState, language string) bool {
+	return language == "" || library.Language == language
 }
 
 // sortByLibraryID sorts legacyconfig.LibraryState with respect to ID.
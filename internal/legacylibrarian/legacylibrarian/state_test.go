@@ -98,7 +98,7 @@ libraries:
 			if err := os.WriteFile(path, []byte(test.content), 0644); err != nil {
 				t.Fatalf("os.WriteFile() failed: %v", err)
 			}
-			got, err := parseLibrarianState(path, test.source)
+			got, err := parseLibrarianState(path, nil, test.source)
 			if (err != nil) != test.wantErr {
 				t.Errorf("parseLibrarianState() error = %v, wantErr %v", err, test.wantErr)
 				return
@@ -134,6 +134,12 @@ func TestFindServiceConfigIn(t *testing.T) {
 			want:    "",
 			wantErr: false,
 		},
+		{
+			name:    "several service configs",
+			path:    filepath.Join("testdata", "several_service_configs"),
+			want:    "",
+			wantErr: true,
+		},
 		{
 			name:    "invalid yaml",
 			path:    filepath.Join("testdata", "invalid_yaml"),
@@ -157,6 +163,63 @@ func TestFindServiceConfigIn(t *testing.T) {
 	}
 }
 
+func TestFindServiceConfigMatching(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		path       string
+		pattern    string
+		want       string
+		wantErrMsg string
+	}{
+		{
+			name:    "file name",
+			path:    filepath.Join("testdata", "several_service_configs"),
+			pattern: "second_service.yaml",
+			want:    "second_service.yaml",
+		},
+		{
+			name:    "glob pattern",
+			path:    filepath.Join("testdata", "several_service_configs"),
+			pattern: "first_*.yaml",
+			want:    "first_service.yaml",
+		},
+		{
+			name:       "no match",
+			path:       filepath.Join("testdata", "several_service_configs"),
+			pattern:    "third_service.yaml",
+			wantErrMsg: `no service config matching "third_service.yaml"`,
+		},
+		{
+			name:       "several matches",
+			path:       filepath.Join("testdata", "several_service_configs"),
+			pattern:    "*_service.yaml",
+			wantErrMsg: `found 2 service configs matching "*_service.yaml"`,
+		},
+		{
+			name:       "non-existent source path",
+			path:       filepath.Join("testdata", "non-existent-path"),
+			pattern:    "*.yaml",
+			wantErrMsg: "failed to read dir",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := findServiceConfigMatching(test.path, test.pattern)
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("findServiceConfigMatching() error = %v, want contains %q", err, test.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("findServiceConfigMatching() mismatch (-want +got): %s", diff)
+			}
+		})
+	}
+}
+
 func TestParseGlobalConfig(t *testing.T) {
 	for _, test := range []struct {
 		name       string
@@ -220,11 +283,12 @@ func TestParseGlobalConfig(t *testing.T) {
 
 func TestPopulateServiceConfig(t *testing.T) {
 	for _, test := range []struct {
-		name    string
-		state   *legacyconfig.LibrarianState
-		path    string
-		want    *legacyconfig.LibrarianState
-		wantErr bool
+		name            string
+		state           *legacyconfig.LibrarianState
+		librarianConfig *legacyconfig.LibrarianConfig
+		path            string
+		want            *legacyconfig.LibrarianState
+		wantErr         bool
 	}{
 		{
 			name: "populate service config",
@@ -263,6 +327,52 @@ func TestPopulateServiceConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "configured service config",
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:   "example-id",
+						APIs: []*legacyconfig.API{{Path: "several_service_configs"}},
+					},
+				},
+			},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				Libraries: []*legacyconfig.LibraryConfig{
+					{
+						LibraryID:      "example-id",
+						ServiceConfigs: map[string]string{"several_service_configs": "second_*.yaml"},
+					},
+				},
+			},
+			path: "testdata",
+			want: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID: "example-id",
+						APIs: []*legacyconfig.API{
+							{
+								Path:          "several_service_configs",
+								ServiceConfig: "second_service.yaml",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "several service configs",
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:   "example-id",
+						APIs: []*legacyconfig.API{{Path: "several_service_configs"}},
+					},
+				},
+			},
+			path:    "testdata",
+			wantErr: true,
+		},
 		{
 			name: "non valid api path",
 			state: &legacyconfig.LibrarianState{
@@ -283,7 +393,7 @@ func TestPopulateServiceConfig(t *testing.T) {
 		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
-			err := populateServiceConfigIfEmpty(test.state, test.path)
+			err := populateServiceConfigIfEmpty(test.state, test.librarianConfig, test.path)
 			if test.wantErr {
 				if err == nil {
 					t.Fatal("findServiceConfigIn() should return error")
@@ -345,6 +455,47 @@ func TestSaveLibrarianState(t *testing.T) {
 	}
 }
 
+func TestWriteLibrarianState(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "out", "state.yaml")
+	state := &legacyconfig.LibrarianState{
+		Image: "gcr.io/test/image:v1.2.3",
+		Libraries: []*legacyconfig.LibraryState{
+			{
+				ID:            "b",
+				Version:       "1.0.0",
+				APIs:          []*legacyconfig.API{},
+				SourceRoots:   []string{"src/b"},
+				PreserveRegex: []string{},
+				RemoveRegex:   []string{},
+			},
+			{
+				ID:            "a",
+				Version:       "2.0.0",
+				APIs:          []*legacyconfig.API{},
+				SourceRoots:   []string{"src/a"},
+				PreserveRegex: []string{},
+				RemoveRegex:   []string{},
+			},
+		},
+	}
+	if err := writeLibrarianState(path, state); err != nil {
+		t.Fatalf("writeLibrarianState() failed: %v", err)
+	}
+
+	gotBytes, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("os.ReadFile() failed: %v", err)
+	}
+	gotState := &legacyconfig.LibrarianState{}
+	if err := yaml.Unmarshal(gotBytes, gotState); err != nil {
+		t.Fatalf("yaml.Unmarshal() failed: %v", err)
+	}
+	if diff := cmp.Diff(state, gotState); diff != "" {
+		t.Errorf("writeLibrarianState() mismatch (-want +got): %s", diff)
+	}
+}
+
 func TestReadLibraryState(t *testing.T) {
 	t.Parallel()
 	for _, test := range []struct {
@@ -57,8 +57,14 @@ var (
 )
 
 type tagRunner struct {
-	ghClient    GitHubClient
-	pullRequest string
+	ghClient          GitHubClient
+	manifest          []*tagManifestEntry
+	manifestOutput    string
+	postReleaseUpdate bool
+	pullRequest       string
+	repo              *legacygithub.Repository
+	since             time.Duration
+	tagType           string
 }
 
 // libraryRelease holds the parsed information from a pull request body.
@@ -86,6 +92,7 @@ func newTagRunner(cfg *legacyconfig.Config) (*tagRunner, error) {
 		return nil, err
 	}
 	ghClient := legacygithub.NewClient(cfg.GitHubToken, repo)
+	ghClient.SetRetryPolicy(cfg.GitHubMaxRetries, cfg.GitHubRetryDelay)
 	// If a custom GitHub API endpoint is provided (for testing),
 	// parse it and set it as the BaseURL on the GitHub client.
 	if cfg.GitHubAPIEndpoint != "" {
@@ -96,8 +103,13 @@ func newTagRunner(cfg *legacyconfig.Config) (*tagRunner, error) {
 		ghClient.BaseURL = endpoint
 	}
 	return &tagRunner{
-		ghClient:    ghClient,
-		pullRequest: cfg.PullRequest,
+		ghClient:          ghClient,
+		manifestOutput:    cfg.ManifestOutput,
+		postReleaseUpdate: cfg.PostReleaseUpdate,
+		pullRequest:       cfg.PullRequest,
+		repo:              repo,
+		since:             cfg.Since,
+		tagType:           cfg.TagType,
 	}, nil
 }
 
@@ -127,6 +139,9 @@ func (r *tagRunner) run(ctx context.Context) error {
 	}
 	if len(prs) == 0 {
 		slog.Info("no pull requests to process, exiting")
+		if r.manifestOutput != "" {
+			return writeTagManifest(r.manifestOutput, nil)
+		}
 		return nil
 	}
 
@@ -141,6 +156,11 @@ func (r *tagRunner) run(ctx context.Context) error {
 	}
 	slog.Info("finished processing all pull requests")
 
+	if r.manifestOutput != "" {
+		if err := writeTagManifest(r.manifestOutput, r.manifest); err != nil {
+			return fmt.Errorf("failed to write manifest: %w", err)
+		}
+	}
 	if hadErrors {
 		return errors.New("failed to process some pull requests")
 	}
@@ -166,9 +186,14 @@ func (r *tagRunner) determinePullRequestsToProcess(ctx context.Context) ([]*lega
 		return []*legacygithub.PullRequest{pr}, nil
 	}
 
+	since := r.since
+	if since == 0 {
+		since = legacyconfig.DefaultSince
+	}
+	mergedAfter := time.Now().Add(-since).Format(time.RFC3339)
 	slog.Info("searching for pull requests to tag and release")
-	thirtyDaysAgo := time.Now().Add(-30 * 24 * time.Hour).Format(time.RFC3339)
-	query := fmt.Sprintf("label:%s merged:>=%s", releasePendingLabel, thirtyDaysAgo)
+	slog.Debug("searching for pull requests merged within the search window", "since", since, "mergedAfter", mergedAfter)
+	query := fmt.Sprintf("label:%s merged:>=%s", releasePendingLabel, mergedAfter)
 	prs, err := r.ghClient.SearchPullRequests(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search pull requests: %w", err)
@@ -200,8 +225,10 @@ func (r *tagRunner) processPullRequest(ctx context.Context, p *legacygithub.Pull
 	// See: go/sdk-librarian:louhi-trigger for details.
 	commitSha := p.GetMergeCommitSHA()
 	tagName := fmt.Sprintf("release-%d", p.GetNumber())
-	if err := r.ghClient.CreateTag(ctx, tagName, commitSha); err != nil {
-		return fmt.Errorf("failed to create tag %s: %w", tagName, err)
+	if err := r.createTagIfMissing(ctx, tagName, func() error {
+		return r.ghClient.CreateTag(ctx, tagName, commitSha)
+	}); err != nil {
+		return err
 	}
 	for _, release := range releases {
 		libraryState := librarianState.LibraryByID(release.Library)
@@ -219,18 +246,92 @@ func (r *tagRunner) processPullRequest(ctx context.Context, p *legacygithub.Pull
 			continue
 		}
 
-		slog.Info("creating release", "library", release.Library, "version", release.Version)
+		commitish, err := r.releaseCommit(ctx, commitSha, targetBranch, libraryConfig)
+		if err != nil {
+			return err
+		}
+		slog.Info("creating release", "library", release.Library, "version", release.Version, "commit", commitish)
 		tagFormat := legacyconfig.DetermineTagFormat(release.Library, libraryState, librarianConfig)
 		tagName := legacyconfig.FormatTag(tagFormat, release.Library, release.Version)
+		// A previous run may have failed after creating some of the releases
+		// of the pull request.
+		existing, err := r.ghClient.GetReleaseByTag(ctx, tagName)
+		if err != nil {
+			return fmt.Errorf("failed to look up release %s: %w", tagName, err)
+		}
+		if existing != nil {
+			slog.Info("release already exists, skipping", "library", release.Library, "tag", tagName)
+			continue
+		}
 		releaseName := fmt.Sprintf("%s %s", release.Library, release.Version)
-		if _, err := r.ghClient.CreateRelease(ctx, tagName, releaseName, release.Body, commitSha); err != nil {
+		body := release.Body
+		if notice := deprecationNotice(libraryState); notice != "" {
+			body = notice + "\n\n" + body
+		}
+		// GitHub creates a lightweight tag along with the release if the tag
+		// does not exist yet.
+		if r.tagType != legacyconfig.TagTypeLightweight {
+			if err := r.createTagIfMissing(ctx, tagName, func() error {
+				return r.ghClient.CreateAnnotatedTag(ctx, tagName, body, commitish)
+			}); err != nil {
+				return err
+			}
+		}
+		created, err := r.ghClient.CreateRelease(ctx, tagName, releaseName, body, commitish)
+		if err != nil {
 			return fmt.Errorf("failed to create release: %w", err)
 		}
-
+		r.manifest = append(r.manifest, &tagManifestEntry{
+			Library:    release.Library,
+			Version:    release.Version,
+			Tag:        tagName,
+			ReleaseURL: created.GetHTMLURL(),
+			Commit:     commitish,
+		})
+	}
+	// The post-release update runs before the label is replaced, so that a
+	// failed update is retried by the next run.
+	if r.postReleaseUpdate {
+		if err := r.runPostReleaseUpdate(ctx, p, librarianConfig, releases); err != nil {
+			return err
+		}
 	}
 	return r.replacePendingLabel(ctx, p)
 }
 
+// releaseCommit returns the commit to tag for the release of a library by a
+// pull request merged into targetBranch as mergeCommit. The merge commit is
+// tagged, unless the library is released from another release branch, in
+// which case the head of that branch is tagged.
+func (r *tagRunner) releaseCommit(ctx context.Context, mergeCommit, targetBranch string, libraryConfig *legacyconfig.LibraryConfig) (string, error) {
+	if libraryConfig == nil || libraryConfig.ReleaseBranch == "" || libraryConfig.ReleaseBranch == targetBranch {
+		return mergeCommit, nil
+	}
+	head, err := r.ghClient.GetBranchHead(ctx, libraryConfig.ReleaseBranch)
+	if err != nil {
+		return "", fmt.Errorf("failed to get head of release branch %s of library %s: %w", libraryConfig.ReleaseBranch, libraryConfig.LibraryID, err)
+	}
+	return head, nil
+}
+
+// createTagIfMissing calls create to create the tag with the given name,
+// unless the tag already exists, so that processing a pull request again
+// after a failure does not fail on the tags created by the previous attempt.
+func (r *tagRunner) createTagIfMissing(ctx context.Context, tagName string, create func() error) error {
+	exists, err := r.ghClient.TagExists(ctx, tagName)
+	if err != nil {
+		return fmt.Errorf("failed to look up tag %s: %w", tagName, err)
+	}
+	if exists {
+		slog.Info("tag already exists, skipping", "tag", tagName)
+		return nil
+	}
+	if err := create(); err != nil {
+		return fmt.Errorf("failed to create tag %s: %w", tagName, err)
+	}
+	return nil
+}
+
 // parsePullRequestBody parses a string containing release notes and returns a slice of ParsedPullRequestBody.
 func parsePullRequestBody(body string) []libraryRelease {
 	slog.Info("parsing pull request body")
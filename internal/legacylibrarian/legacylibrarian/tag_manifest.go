@@ -0,0 +1,54 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// tagManifestEntry describes a release created by the tag command, as written
+// to the manifest requested with -manifest-output.
+type tagManifestEntry struct {
+	// Library is the ID of the released library.
+	Library string `json:"library"`
+	// Version is the released version.
+	Version string `json:"version"`
+	// Tag is the name of the release tag.
+	Tag string `json:"tag"`
+	// ReleaseURL is the URL of the GitHub release.
+	ReleaseURL string `json:"releaseURL"`
+	// Commit is the SHA of the tagged commit, i.e. the merge commit of the
+	// release pull request.
+	Commit string `json:"commit"`
+}
+
+// writeTagManifest writes entries as a JSON array to the file at path,
+// creating its parent directory if needed. An empty manifest is written as an
+// empty array, so that consumers can tell that nothing was released.
+func writeTagManifest(path string, entries []*tagManifestEntry) error {
+	if entries == nil {
+		entries = []*tagManifestEntry{}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
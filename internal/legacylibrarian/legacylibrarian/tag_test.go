@@ -16,8 +16,11 @@ package legacylibrarian
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	gh "github.com/google/go-github/v69/github"
@@ -151,6 +154,46 @@ func TestDeterminePullRequestsToProcess(t *testing.T) {
 	}
 }
 
+func TestDeterminePullRequestsToProcessSince(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		since time.Duration
+		want  time.Duration
+	}{
+		{
+			name: "default window",
+			want: legacyconfig.DefaultSince,
+		},
+		{
+			name:  "custom window",
+			since: 90 * 24 * time.Hour,
+			want:  90 * 24 * time.Hour,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ghClient := &mockGitHubClient{}
+			r := &tagRunner{
+				ghClient: ghClient,
+				since:    test.since,
+			}
+			if _, err := r.determinePullRequestsToProcess(t.Context()); err != nil {
+				t.Fatal(err)
+			}
+			mergedAfter, ok := strings.CutPrefix(ghClient.searchPullRequestsQuery, "label:release:pending merged:>=")
+			if !ok {
+				t.Fatalf("unexpected search query %q", ghClient.searchPullRequestsQuery)
+			}
+			got, err := time.Parse(time.RFC3339, mergedAfter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ago := time.Since(got); ago < test.want || ago > test.want+time.Minute {
+				t.Errorf("searched pull requests merged %s ago, want %s", ago, test.want)
+			}
+		})
+	}
+}
+
 func Test_tagRunner_run(t *testing.T) {
 	pr123 := &legacygithub.PullRequest{}
 	pr456 := &legacygithub.PullRequest{}
@@ -480,7 +523,13 @@ func TestProcessPullRequest(t *testing.T) {
 		wantErrMsg             string
 		wantCreateReleaseCalls int
 		wantReplaceLabelsCalls int
+		tagType                string
 		wantCreateTagCalls     int
+		wantReleaseBody        string
+		wantAnnotatedTags      map[string]string
+		postReleaseUpdate      bool
+		wantUpdatedFiles       map[string]string
+		wantTaggedCommits      map[string]string
 	}{
 		{
 			name: "happy path",
@@ -491,6 +540,67 @@ func TestProcessPullRequest(t *testing.T) {
 			wantCreateReleaseCalls: 1,
 			wantReplaceLabelsCalls: 1,
 			wantCreateTagCalls:     1,
+			wantAnnotatedTags:      map[string]string{"vv1.2.3": "release notes"},
+		},
+		{
+			name: "post release update",
+			pr:   prWithRelease,
+			ghClient: &mockGitHubClient{
+				librarianState: state,
+				librarianConfig: &legacyconfig.LibrarianConfig{
+					PostRelease: &legacyconfig.PostReleaseConfig{
+						VersionsFile: "versions.txt",
+					},
+				},
+				rawContent: []byte("google-cloud-storage:v1.2.2\n"),
+			},
+			postReleaseUpdate:      true,
+			wantCreateReleaseCalls: 1,
+			wantReplaceLabelsCalls: 1,
+			wantCreateTagCalls:     1,
+			wantAnnotatedTags:      map[string]string{"vv1.2.3": "release notes"},
+			wantUpdatedFiles:       map[string]string{"main:versions.txt": "google-cloud-storage:v1.2.3\n"},
+		},
+		{
+			name: "post release update fails",
+			pr:   prWithRelease,
+			ghClient: &mockGitHubClient{
+				librarianState: state,
+				librarianConfig: &legacyconfig.LibrarianConfig{
+					PostRelease: &legacyconfig.PostReleaseConfig{
+						VersionsFile: "versions.txt",
+					},
+				},
+				rawErr: errors.New("internal server error"),
+			},
+			postReleaseUpdate:      true,
+			wantCreateReleaseCalls: 1,
+			wantCreateTagCalls:     1,
+			wantAnnotatedTags:      map[string]string{"vv1.2.3": "release notes"},
+			wantErrMsg:             "failed to read versions file versions.txt",
+		},
+		{
+			name: "lightweight tag type",
+			pr:   prWithRelease,
+			ghClient: &mockGitHubClient{
+				librarianState: state,
+			},
+			tagType:                legacyconfig.TagTypeLightweight,
+			wantCreateReleaseCalls: 1,
+			wantReplaceLabelsCalls: 1,
+			wantCreateTagCalls:     1,
+		},
+		{
+			name: "create annotated tag fails",
+			pr:   prWithRelease,
+			ghClient: &mockGitHubClient{
+				createAnnotatedTagErr: errors.New("create annotated tag error"),
+				librarianState:        state,
+			},
+			tagType:            legacyconfig.TagTypeAnnotated,
+			wantErrMsg:         "failed to create tag vv1.2.3",
+			wantCreateTagCalls: 1,
+			wantAnnotatedTags:  map[string]string{"vv1.2.3": "release notes"},
 		},
 		{
 			name: "no release details",
@@ -513,7 +623,8 @@ func TestProcessPullRequest(t *testing.T) {
 					},
 				},
 			},
-			wantErrMsg: "library google-cloud-storage not found",
+			wantErrMsg:         "library google-cloud-storage not found",
+			wantCreateTagCalls: 1,
 		},
 		{
 			name: "default tag format",
@@ -532,6 +643,7 @@ func TestProcessPullRequest(t *testing.T) {
 			wantCreateReleaseCalls: 1,
 			wantReplaceLabelsCalls: 1,
 			wantCreateTagCalls:     1,
+			wantAnnotatedTags:      map[string]string{"google-cloud-storage-v1.2.3": "release notes"},
 		},
 		{
 			name: "skip_a_library_release",
@@ -559,6 +671,32 @@ func TestProcessPullRequest(t *testing.T) {
 			wantReplaceLabelsCalls: 1,
 			wantCreateTagCalls:     1,
 		},
+		{
+			name: "deprecated library",
+			pr:   prWithRelease,
+			ghClient: &mockGitHubClient{
+				librarianState: &legacyconfig.LibrarianState{
+					Image: "gcr.io/some-project-id/some-test-image:latest",
+					Libraries: []*legacyconfig.LibraryState{
+						{
+							ID:                 "google-cloud-storage",
+							SourceRoots:        []string{"some/path"},
+							Deprecated:         true,
+							DeprecationMessage: "Use google-cloud-storage-v2 instead.",
+						},
+					},
+				},
+			},
+			tagType:                legacyconfig.TagTypeLightweight,
+			wantCreateReleaseCalls: 1,
+			wantReplaceLabelsCalls: 1,
+			wantCreateTagCalls:     1,
+			wantReleaseBody: `> [!WARNING]
+> google-cloud-storage is deprecated.
+> Use google-cloud-storage-v2 instead.
+
+release notes`,
+		},
 		{
 			name: "create release fails",
 			pr:   prWithRelease,
@@ -569,6 +707,7 @@ func TestProcessPullRequest(t *testing.T) {
 			wantErrMsg:             "failed to create release",
 			wantCreateReleaseCalls: 1,
 			wantCreateTagCalls:     1,
+			wantAnnotatedTags:      map[string]string{"vv1.2.3": "release notes"},
 		},
 		{
 			name: "replace labels fails",
@@ -581,6 +720,7 @@ func TestProcessPullRequest(t *testing.T) {
 			wantCreateReleaseCalls: 1,
 			wantReplaceLabelsCalls: 1,
 			wantCreateTagCalls:     1,
+			wantAnnotatedTags:      map[string]string{"vv1.2.3": "release notes"},
 		},
 		{
 			name: "create tag fails",
@@ -592,10 +732,104 @@ func TestProcessPullRequest(t *testing.T) {
 			wantErrMsg:         "failed to create tag",
 			wantCreateTagCalls: 1,
 		},
+		{
+			name: "release already exists",
+			pr:   prWithRelease,
+			ghClient: &mockGitHubClient{
+				librarianState: state,
+				existingTags:   []string{"release-123", "vv1.2.3"},
+				releases: map[string]*legacygithub.RepositoryRelease{
+					"vv1.2.3": {TagName: gh.Ptr("vv1.2.3")},
+				},
+			},
+			wantReplaceLabelsCalls: 1,
+		},
+		{
+			name: "tags already exist",
+			pr:   prWithRelease,
+			ghClient: &mockGitHubClient{
+				librarianState: state,
+				existingTags:   []string{"release-123", "vv1.2.3"},
+			},
+			wantCreateReleaseCalls: 1,
+			wantReplaceLabelsCalls: 1,
+		},
+		{
+			name: "look up tag fails",
+			pr:   prWithRelease,
+			ghClient: &mockGitHubClient{
+				librarianState: state,
+				tagExistsErr:   errors.New("look up tag error"),
+			},
+			wantErrMsg: "failed to look up tag release-123",
+		},
+		{
+			name: "look up release fails",
+			pr:   prWithRelease,
+			ghClient: &mockGitHubClient{
+				librarianState:     state,
+				getReleaseByTagErr: errors.New("look up release error"),
+			},
+			wantErrMsg:         "failed to look up release vv1.2.3",
+			wantCreateTagCalls: 1,
+		},
+		{
+			name: "release branch is the base branch",
+			pr:   prWithRelease,
+			ghClient: &mockGitHubClient{
+				librarianState: state,
+				librarianConfig: &legacyconfig.LibrarianConfig{
+					Libraries: []*legacyconfig.LibraryConfig{
+						{LibraryID: "google-cloud-storage", ReleaseBranch: "main"},
+					},
+				},
+				branchHeads: map[string]string{"main": "main-head"},
+			},
+			wantCreateReleaseCalls: 1,
+			wantReplaceLabelsCalls: 1,
+			wantCreateTagCalls:     1,
+			wantAnnotatedTags:      map[string]string{"vv1.2.3": "release notes"},
+			wantTaggedCommits:      map[string]string{"vv1.2.3": "abcdef"},
+		},
+		{
+			name: "release branch is another branch",
+			pr:   prWithRelease,
+			ghClient: &mockGitHubClient{
+				librarianState: state,
+				librarianConfig: &legacyconfig.LibrarianConfig{
+					Libraries: []*legacyconfig.LibraryConfig{
+						{LibraryID: "google-cloud-storage", ReleaseBranch: "release-v1"},
+					},
+				},
+				branchHeads: map[string]string{"release-v1": "release-v1-head"},
+			},
+			wantCreateReleaseCalls: 1,
+			wantReplaceLabelsCalls: 1,
+			wantCreateTagCalls:     1,
+			wantAnnotatedTags:      map[string]string{"vv1.2.3": "release notes"},
+			wantTaggedCommits:      map[string]string{"vv1.2.3": "release-v1-head"},
+		},
+		{
+			name: "get release branch head fails",
+			pr:   prWithRelease,
+			ghClient: &mockGitHubClient{
+				librarianState: state,
+				librarianConfig: &legacyconfig.LibrarianConfig{
+					Libraries: []*legacyconfig.LibraryConfig{
+						{LibraryID: "google-cloud-storage", ReleaseBranch: "release-v1"},
+					},
+				},
+				getBranchHeadErr: errors.New("branch not found"),
+			},
+			wantErrMsg:         "failed to get head of release branch release-v1 of library google-cloud-storage",
+			wantCreateTagCalls: 1,
+		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			r := &tagRunner{
-				ghClient: test.ghClient,
+				ghClient:          test.ghClient,
+				postReleaseUpdate: test.postReleaseUpdate,
+				tagType:           test.tagType,
 			}
 			err := r.processPullRequest(t.Context(), test.pr)
 			if err != nil {
@@ -615,6 +849,25 @@ func TestProcessPullRequest(t *testing.T) {
 			if test.ghClient.replaceLabelsCalls != test.wantReplaceLabelsCalls {
 				t.Errorf("replaceLabelsCalls = %v, want %v", test.ghClient.replaceLabelsCalls, test.wantReplaceLabelsCalls)
 			}
+			if test.ghClient.createTagCalls != test.wantCreateTagCalls {
+				t.Errorf("createTagCalls = %v, want %v", test.ghClient.createTagCalls, test.wantCreateTagCalls)
+			}
+			if diff := cmp.Diff(test.wantAnnotatedTags, test.ghClient.annotatedTags); diff != "" {
+				t.Errorf("annotated tags mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.wantUpdatedFiles, test.ghClient.updatedFiles); diff != "" {
+				t.Errorf("updated files mismatch (-want +got):\n%s", diff)
+			}
+			if test.wantTaggedCommits != nil {
+				if diff := cmp.Diff(test.wantTaggedCommits, test.ghClient.taggedCommits); diff != "" {
+					t.Errorf("tagged commits mismatch (-want +got):\n%s", diff)
+				}
+			}
+			if test.wantReleaseBody != "" {
+				if diff := cmp.Diff(test.wantReleaseBody, test.ghClient.createdReleaseBody); diff != "" {
+					t.Errorf("release body mismatch (-want +got):\n%s", diff)
+				}
+			}
 		})
 	}
 }
@@ -723,3 +976,87 @@ func Test_tagRunner_run_processPullRequests(t *testing.T) {
 		t.Errorf("replaceLabelsCalls = %v, want 1", ghClient.replaceLabelsCalls)
 	}
 }
+
+func Test_tagRunner_run_manifestOutput(t *testing.T) {
+	branch := "main"
+	pr := &legacygithub.PullRequest{
+		Body: gh.Ptr(`<details><summary>google-cloud-storage: v1.2.3</summary>storage notes</details>
+<details><summary>google-cloud-pubsub: v2.0.0</summary>pubsub notes</details>`),
+		Number:         gh.Ptr(123),
+		MergeCommitSHA: gh.Ptr("abc123"),
+		Labels:         []*gh.Label{{Name: gh.Ptr(releasePendingLabel)}},
+		Base: &gh.PullRequestBranch{
+			Ref: &branch,
+		},
+	}
+	state := &legacyconfig.LibrarianState{
+		Image: "gcr.io/some-project/some-image:latest",
+		Libraries: []*legacyconfig.LibraryState{
+			{
+				ID:          "google-cloud-storage",
+				SourceRoots: []string{"storage"},
+				TagFormat:   "{id}/{version}",
+			},
+			{
+				ID:          "google-cloud-pubsub",
+				SourceRoots: []string{"pubsub"},
+				TagFormat:   "{id}/{version}",
+			},
+		},
+	}
+	for _, test := range []struct {
+		name         string
+		pullRequests []*legacygithub.PullRequest
+		want         string
+	}{
+		{
+			name:         "releases created",
+			pullRequests: []*legacygithub.PullRequest{pr},
+			want: `[
+  {
+    "library": "google-cloud-pubsub",
+    "version": "v2.0.0",
+    "tag": "google-cloud-pubsub/v2.0.0",
+    "releaseURL": "https://github.com/googleapis/repo/releases/1",
+    "commit": "abc123"
+  },
+  {
+    "library": "google-cloud-storage",
+    "version": "v1.2.3",
+    "tag": "google-cloud-storage/v1.2.3",
+    "releaseURL": "https://github.com/googleapis/repo/releases/1",
+    "commit": "abc123"
+  }
+]
+`,
+		},
+		{
+			name: "no pull requests",
+			want: "[]\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			manifestOutput := filepath.Join(t.TempDir(), "out", "manifest.json")
+			r := &tagRunner{
+				ghClient: &mockGitHubClient{
+					pullRequests:   test.pullRequests,
+					librarianState: state,
+					createdRelease: &legacygithub.RepositoryRelease{
+						HTMLURL: gh.Ptr("https://github.com/googleapis/repo/releases/1"),
+					},
+				},
+				manifestOutput: manifestOutput,
+			}
+			if err := r.run(t.Context()); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(manifestOutput)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, string(got)); diff != "" {
+				t.Errorf("manifest mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/google/uuid"
@@ -39,7 +40,10 @@ type testGenerateRunner struct {
 	workRoot               string
 	containerClient        ContainerClient
 	checkUnexpectedChanges bool
-	branchesToDelete       []string
+	// diffOutput is the path of a file to which a unified diff of the
+	// unexpected changes is appended, if set.
+	diffOutput       string
+	branchesToDelete []string
 }
 
 func (r *testGenerateRunner) run(ctx context.Context) error {
@@ -71,6 +75,11 @@ func (r *testGenerateRunner) runTests(ctx context.Context, sourceRepoHead string
 	if err := os.Mkdir(outputDir, 0755); err != nil {
 		return fmt.Errorf("failed to make output directory, %s: %w", outputDir, err)
 	}
+	if r.diffOutput != "" {
+		if err := os.Remove(r.diffOutput); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove previous diff output %s: %w", r.diffOutput, err)
+		}
+	}
 	if r.library != "" {
 		err := r.testSingleLibrary(ctx, r.library, sourceRepoHead, outputDir)
 		if errors.Is(err, errGenerateBlocked) {
@@ -176,7 +185,7 @@ func (r *testGenerateRunner) testSingleLibrary(ctx context.Context, libraryID, s
 	}
 
 	// We capture the error here and pass it to the validation step.
-	generateErr := generateSingleLibrary(ctx, r.containerClient, r.state, libraryState, r.repo, r.sourceRepo, outputDir)
+	_, generateErr := generateSingleLibrary(ctx, r.containerClient, r.state, libraryState, r.repo, r.sourceRepo, outputDir, "", "", "", "", false)
 
 	if err := r.validateGenerateTest(generateErr, protoFileToGUIDs, libraryState); err != nil {
 		return fmt.Errorf("failed in test validation steps: %w", err)
@@ -361,16 +370,21 @@ func (r *testGenerateRunner) validateGenerateTest(generateErr error, protoFileTo
 
 	changedFiles = filterFilesBySourceRoots(changedFiles, libraryState.SourceRoots)
 
+	unexpected := &unexpectedChanges{}
+	newOrDeleted := make(map[string]bool)
 	if r.checkUnexpectedChanges {
 		newAndDeleted, err := r.repo.NewAndDeletedFiles()
 		if err != nil {
 			return fmt.Errorf("failed to get new and deleted files: %w", err)
 		}
-		newAndDeleted = filterFilesBySourceRoots(newAndDeleted, libraryState.SourceRoots)
-		if len(newAndDeleted) > 0 {
-			return fmt.Errorf("expected no new or deleted files, but found: %s", strings.Join(newAndDeleted, ", "))
+		for _, filePath := range filterFilesBySourceRoots(newAndDeleted, libraryState.SourceRoots) {
+			newOrDeleted[filePath] = true
+			if _, err := os.Stat(filepath.Join(r.repo.GetDir(), filePath)); os.IsNotExist(err) {
+				unexpected.deleted = append(unexpected.deleted, filePath)
+			} else {
+				unexpected.added = append(unexpected.added, filePath)
+			}
 		}
-		slog.Debug("validation succeeded: no new or deleted files")
 	}
 
 	guidsToFind := make(map[string]bool)
@@ -415,22 +429,89 @@ func (r *testGenerateRunner) validateGenerateTest(generateErr error, protoFileTo
 	slog.Debug("validation succeeded: all proto changes resulted in generated file changes")
 
 	if r.checkUnexpectedChanges {
-		var unrelatedChanges []string
 		for _, filePath := range changedFiles {
-			if !filesWithGUIDs[filePath] {
-				unrelatedChanges = append(unrelatedChanges, filePath)
+			if !filesWithGUIDs[filePath] && !newOrDeleted[filePath] {
+				unexpected.modified = append(unexpected.modified, filePath)
 			}
 		}
-		if len(unrelatedChanges) > 0 {
-			return fmt.Errorf("found unrelated file changes: %s", strings.Join(unrelatedChanges, ", "))
+		if !unexpected.empty() {
+			if err := r.writeUnexpectedChangesDiff(libraryState.ID, unexpected); err != nil {
+				return err
+			}
+			return fmt.Errorf("found unexpected file changes in library %s:\n%s", libraryState.ID, unexpected)
 		}
-		slog.Debug("validation succeeded: no unrelated file changes found")
+		slog.Debug("validation succeeded: no unexpected file changes found")
 	}
 
 	slog.Debug("all generation validation passed")
 	return nil
 }
 
+// unexpectedChanges lists the files of a library which were added, deleted or
+// modified by the generation, other than the changes caused by the injected
+// proto changes.
+type unexpectedChanges struct {
+	added    []string
+	deleted  []string
+	modified []string
+}
+
+func (c *unexpectedChanges) empty() bool {
+	return len(c.added) == 0 && len(c.deleted) == 0 && len(c.modified) == 0
+}
+
+// paths returns all the unexpectedly changed files, sorted.
+func (c *unexpectedChanges) paths() []string {
+	paths := slices.Concat(c.added, c.deleted, c.modified)
+	slices.Sort(paths)
+	return paths
+}
+
+// String returns the changed files grouped by category, one file per line.
+func (c *unexpectedChanges) String() string {
+	var b strings.Builder
+	for _, category := range []struct {
+		name  string
+		files []string
+	}{
+		{"added", c.added},
+		{"deleted", c.deleted},
+		{"modified", c.modified},
+	} {
+		if len(category.files) == 0 {
+			continue
+		}
+		files := slices.Sorted(slices.Values(category.files))
+		fmt.Fprintf(&b, "%s (%d):\n", category.name, len(files))
+		for _, file := range files {
+			fmt.Fprintf(&b, "  %s\n", file)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// writeUnexpectedChangesDiff appends a unified diff of the unexpected changes
+// of the library to r.diffOutput, if set.
+func (r *testGenerateRunner) writeUnexpectedChangesDiff(libraryID string, changes *unexpectedChanges) error {
+	if r.diffOutput == "" {
+		return nil
+	}
+	diff, err := r.repo.Diff(changes.paths())
+	if err != nil {
+		return fmt.Errorf("failed to diff unexpected changes: %w", err)
+	}
+	f, err := os.OpenFile(r.diffOutput, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open diff output %s: %w", r.diffOutput, err)
+	}
+	if _, err := fmt.Fprintf(f, "# Unexpected changes in library %s\n%s", libraryID, diff); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write diff output %s: %w", r.diffOutput, err)
+	}
+	slog.Info("wrote diff of unexpected changes", "library", libraryID, "path", r.diffOutput)
+	return f.Close()
+}
+
 func filterFilesBySourceRoots(files []string, sourceRoots []string) []string {
 	var filteredFiles []string
 	for _, file := range files {
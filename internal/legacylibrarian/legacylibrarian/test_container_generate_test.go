@@ -23,6 +23,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
 )
 
@@ -47,7 +48,7 @@ func TestValidateGenerateTest(t *testing.T) {
 			},
 			protoFileToGUIDs:       map[string][]string{"some.proto": {"guid-123"}},
 			checkUnexpectedChanges: true,
-			wantErrMsg:             "found unrelated file changes: unrelated.txt",
+			wantErrMsg:             "modified (1):\n  unrelated.txt",
 		},
 		{
 			name: "unrelated changes outside source root",
@@ -86,7 +87,7 @@ func TestValidateGenerateTest(t *testing.T) {
 			newAndDeletedFiles:     []string{"somefile.go"},
 			protoFileToGUIDs:       map[string][]string{},
 			checkUnexpectedChanges: true,
-			wantErrMsg:             "expected no new or deleted files, but found",
+			wantErrMsg:             "added (1):\n  somefile.go",
 		},
 		{
 			name:         "deleted file is a valid change when not checking for unexpected changes",
@@ -99,6 +100,14 @@ func TestValidateGenerateTest(t *testing.T) {
 			checkUnexpectedChanges: false, // This is the key
 			wantErrMsg:             "",    // No error expected
 		},
+		{
+			name:                   "deleted file is an unexpected change",
+			changedFiles:           []string{"deleted.go"},
+			newAndDeletedFiles:     []string{"deleted.go"},
+			protoFileToGUIDs:       map[string][]string{},
+			checkUnexpectedChanges: true,
+			wantErrMsg:             "deleted (1):\n  deleted.go",
+		},
 		{
 			name: "unreadable file causes an error",
 			filesToWrite: map[string]string{
@@ -169,6 +178,57 @@ func TestValidateGenerateTest(t *testing.T) {
 	}
 }
 
+func TestValidateGenerateTest_DiffOutput(t *testing.T) {
+	t.Parallel()
+	tmpDir := t.TempDir()
+	for name, content := range map[string]string{
+		"src/related.go":   "// test-change-guid-123",
+		"src/unrelated.go": "unrelated",
+		"src/added.go":     "added",
+	} {
+		writeTestFile(t, filepath.Join(tmpDir, name), content)
+	}
+	mockRepo := &MockRepository{
+		Dir:                     tmpDir,
+		ChangedFilesValue:       []string{"src/related.go", "src/unrelated.go", "src/added.go", "src/deleted.go"},
+		NewAndDeletedFilesValue: []string{"src/added.go", "src/deleted.go"},
+		DiffValue:               "diff --git a/src/unrelated.go b/src/unrelated.go\n",
+	}
+	diffOutput := filepath.Join(t.TempDir(), "unexpected.diff")
+	runner := &testGenerateRunner{
+		repo:                   mockRepo,
+		checkUnexpectedChanges: true,
+		diffOutput:             diffOutput,
+	}
+	libraryState := &legacyconfig.LibraryState{ID: "lib1", SourceRoots: []string{"src"}}
+
+	err := runner.validateGenerateTest(nil, map[string][]string{"some.proto": {"guid-123"}}, libraryState)
+	if err == nil {
+		t.Fatal("validateGenerateTest() should fail")
+	}
+	wantErr := `found unexpected file changes in library lib1:
+added (1):
+  src/added.go
+deleted (1):
+  src/deleted.go
+modified (1):
+  src/unrelated.go`
+	if diff := cmp.Diff(wantErr, err.Error()); diff != "" {
+		t.Errorf("validateGenerateTest() error mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"src/added.go", "src/deleted.go", "src/unrelated.go"}, mockRepo.DiffPaths); diff != "" {
+		t.Errorf("Diff() paths mismatch (-want +got):\n%s", diff)
+	}
+	got, err := os.ReadFile(diffOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Unexpected changes in library lib1\ndiff --git a/src/unrelated.go b/src/unrelated.go\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("diff output mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestPrepareForGenerateTest(t *testing.T) {
 	t.Parallel()
 
@@ -22,6 +22,7 @@ import (
 	"html/template"
 	"log/slog"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
@@ -35,6 +36,9 @@ const (
 )
 
 type updateImageRunner struct {
+	// allowTag allows r.image to be a floating tag instead of a digest.
+	allowTag               bool
+	author                 *legacygitrepo.Signature
 	branch                 string
 	containerClient        ContainerClient
 	imagesClient           ImageRegistryClient
@@ -45,25 +49,59 @@ type updateImageRunner struct {
 	state                  *legacyconfig.LibrarianState
 	build                  bool
 	push                   bool
+	rebaseOntoBase         bool
 	commit                 bool
+	committer              *legacygitrepo.Signature
 	image                  string
 	workRoot               string
 	test                   bool
 	libraryToTest          string
 	checkUnexpectedChanges bool
+	diffOutput             string
+	// onlyIfAPIChanged discards the libraries whose output is unchanged by
+	// the new image.
+	onlyIfAPIChanged bool
+	// force regenerates the libraries frozen in the librarian config, which
+	// are skipped otherwise.
+	force bool
 }
 
+// errLibraryOutputUnchanged is returned by regenerateSingleLibrary when
+// onlyIfAPIChanged is set and the regenerated library is identical to the
+// committed one.
+var errLibraryOutputUnchanged = errors.New("library output unchanged")
+
 // ImageRegistryClient is an abstraction around interacting with image.
 type ImageRegistryClient interface {
 	FindLatest(ctx context.Context, imageName string) (string, error)
 }
 
+// imageDigestRegex matches the digest of an image reference pinned to a
+// digest, e.g. "gcr.io/foo/bar@sha256:abcd1234".
+var imageDigestRegex = regexp.MustCompile(`@sha256:[0-9a-f]+$`)
+
+// hasImageDigest reports whether the image reference is pinned to a digest,
+// rather than a floating tag which may point to different images over time.
+func hasImageDigest(image string) bool {
+	return imageDigestRegex.MatchString(image)
+}
+
 func newUpdateImageRunner(cfg *legacyconfig.Config) (*updateImageRunner, error) {
+	author, err := commitAuthor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	committer, err := commitCommitter(cfg)
+	if err != nil {
+		return nil, err
+	}
 	runner, err := newCommandRunner(cfg)
 	if err != nil {
 		return nil, err
 	}
 	return &updateImageRunner{
+		allowTag:               cfg.AllowTag,
+		author:                 author,
 		branch:                 cfg.Branch,
 		containerClient:        runner.containerClient,
 		ghClient:               runner.ghClient,
@@ -73,16 +111,24 @@ func newUpdateImageRunner(cfg *legacyconfig.Config) (*updateImageRunner, error)
 		state:                  runner.state,
 		build:                  cfg.Build,
 		commit:                 cfg.Commit,
+		committer:              committer,
 		push:                   cfg.Push,
+		rebaseOntoBase:         cfg.RebaseOntoBase,
 		image:                  cfg.Image,
 		workRoot:               runner.workRoot,
 		test:                   cfg.Test,
 		libraryToTest:          cfg.LibraryToTest,
 		checkUnexpectedChanges: cfg.CheckUnexpectedChanges,
+		diffOutput:             cfg.DiffOutput,
+		onlyIfAPIChanged:       cfg.OnlyIfAPIChanged,
+		force:                  cfg.Force,
 	}, nil
 }
 
 func (r *updateImageRunner) run(ctx context.Context) error {
+	if r.image != "" && !r.allowTag && !hasImageDigest(r.image) {
+		return fmt.Errorf("image %q is not pinned to a digest (@sha256:...); use -allow-tag to use a floating tag", r.image)
+	}
 	imagesClient := r.imagesClient
 	if imagesClient == nil {
 		slog.Info("no imagesClient provided, defaulting to ArtifactRegistry implementation")
@@ -102,6 +148,9 @@ func (r *updateImageRunner) run(ctx context.Context) error {
 			slog.Error("unable to determine latest image to use", "image", r.state.Image)
 			return err
 		}
+		if !hasImageDigest(latestImage) {
+			return fmt.Errorf("latest image %q is not pinned to a digest", latestImage)
+		}
 		r.image = latestImage
 	}
 
@@ -119,6 +168,7 @@ func (r *updateImageRunner) run(ctx context.Context) error {
 	var failedGenerations []*legacyconfig.LibraryState
 	var successfulGenerations []*legacyconfig.LibraryState
 	var skippedGenerationsCount int
+	var unchangedGenerationsCount int
 	sourceHead, err := r.sourceRepo.HeadHash()
 	if err != nil {
 		return err
@@ -126,6 +176,11 @@ func (r *updateImageRunner) run(ctx context.Context) error {
 	outputDir := filepath.Join(r.workRoot, "output")
 	timings := map[string]time.Duration{}
 	for _, libraryState := range r.state.Libraries {
+		if r.librarianConfig.IsFrozen(libraryState.ID) && !r.force {
+			slog.Warn("library is frozen, skipping; use -force to generate it", "library", libraryState.ID)
+			skippedGenerationsCount++
+			continue
+		}
 		if r.librarianConfig.IsGenerationBlocked(libraryState.ID) {
 			slog.Debug("skipping generation for library due to generate_blocked", "library", libraryState.ID)
 			skippedGenerationsCount++
@@ -133,6 +188,11 @@ func (r *updateImageRunner) run(ctx context.Context) error {
 		}
 		startTime := time.Now()
 		err := r.regenerateSingleLibrary(ctx, libraryState, outputDir)
+		if errors.Is(err, errLibraryOutputUnchanged) {
+			slog.Info("library output unchanged by the new image; discarding", "library", libraryState.ID)
+			unchangedGenerationsCount++
+			continue
+		}
 		if err != nil {
 			slog.Error(err.Error(), "library", libraryState.ID, "commit", libraryState.LastGeneratedCommit)
 			failedGenerations = append(failedGenerations, libraryState)
@@ -150,6 +210,7 @@ func (r *updateImageRunner) run(ctx context.Context) error {
 		"all", len(r.state.Libraries),
 		"successes", len(successfulGenerations),
 		"skipped", skippedGenerationsCount,
+		"unchanged", unchangedGenerationsCount,
 		"failures", len(failedGenerations))
 	if err := writeTiming(r.workRoot, timings); err != nil {
 		return err
@@ -159,6 +220,10 @@ func (r *updateImageRunner) run(ctx context.Context) error {
 	if err := r.sourceRepo.Checkout(sourceHead); err != nil {
 		slog.Error(err.Error(), "repository", r.sourceRepo, "HEAD", sourceHead)
 	}
+	if r.onlyIfAPIChanged && len(successfulGenerations) == 0 && len(failedGenerations) == 0 {
+		slog.Info("no library output changed with the new image; skipping commit", "image", r.image)
+		return nil
+	}
 	if r.test {
 		slog.Info("running container tests")
 		testRunner := &testGenerateRunner{
@@ -170,6 +235,7 @@ func (r *updateImageRunner) run(ctx context.Context) error {
 			workRoot:               r.workRoot,
 			containerClient:        r.containerClient,
 			checkUnexpectedChanges: r.checkUnexpectedChanges,
+			diffOutput:             r.diffOutput,
 			branchesToDelete:       []string{},
 		}
 		if err := runContainerGenerateTest(ctx, r.repo, sourceHead, testRunner); err != nil {
@@ -181,13 +247,17 @@ func (r *updateImageRunner) run(ctx context.Context) error {
 	}
 	commitMessage := fmt.Sprintf("feat: update image to %s", r.image)
 	return commitAndPush(ctx, &commitInfo{
+		author:            r.author,
 		branch:            r.branch,
 		commit:            r.commit,
 		commitMessage:     commitMessage,
+		committer:         r.committer,
 		prType:            pullRequestUpdateImage,
 		ghClient:          r.ghClient,
+		librarianConfig:   r.librarianConfig,
 		pullRequestLabels: []string{},
 		push:              r.push,
+		rebaseOntoBase:    r.rebaseOntoBase,
 		languageRepo:      r.repo,
 		sourceRepo:        r.sourceRepo,
 		state:             r.state,
@@ -209,16 +279,29 @@ func (r *updateImageRunner) regenerateSingleLibrary(ctx context.Context, library
 		return fmt.Errorf("error checking out from sourceRepo %w", err)
 	}
 
-	if err := generateSingleLibrary(ctx, r.containerClient, r.state, libraryState, r.repo, r.sourceRepo, outputDir); err != nil {
+	if _, err := generateSingleLibrary(ctx, r.containerClient, r.state, libraryState, r.repo, r.sourceRepo, outputDir, "", "", "", "", false); err != nil {
 		slog.Error("failed to regenerate a single library", "error", err, "ID", libraryState.ID)
 		return err
 	}
 
+	if r.onlyIfAPIChanged {
+		changed, err := libraryOutputChanged(r.repo, libraryState)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			if err := restoreLibrary(libraryState.SourceRoots, r.repo); err != nil {
+				return err
+			}
+			return errLibraryOutputUnchanged
+		}
+	}
+
 	if !r.build {
 		slog.Info("build not specified, skipping build")
 		return nil
 	}
-	if err := buildSingleLibrary(ctx, r.containerClient, r.state, libraryState, r.repo); err != nil {
+	if err := buildSingleLibrary(ctx, r.containerClient, r.state, libraryState, r.repo, ""); err != nil {
 		slog.Error("failed to build a single library", "error", err, "ID", libraryState.ID)
 		return err
 	}
@@ -226,6 +309,21 @@ func (r *updateImageRunner) regenerateSingleLibrary(ctx context.Context, library
 	return nil
 }
 
+// libraryOutputChanged reports whether any file in the source roots of the
+// library differs from the HEAD commit of the language repository.
+func libraryOutputChanged(repo legacygitrepo.Repository, libraryState *legacyconfig.LibraryState) (bool, error) {
+	changedFiles, err := repo.ChangedFiles()
+	if err != nil {
+		return false, fmt.Errorf("failed to get changed files: %w", err)
+	}
+	for _, file := range changedFiles {
+		if isUnderAnyPath(file, libraryState.SourceRoots) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // runContainerGenerateTest creates a temporary commit to ensure a clean
 // repo state for the test runner, runs the tests, and then soft-resets
 // the commit to leave the working directory in its original dirty state
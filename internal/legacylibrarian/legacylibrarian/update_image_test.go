@@ -196,11 +196,15 @@ func TestUpdateImageRunnerRun(t *testing.T) {
 		state                      *legacyconfig.LibrarianState
 		librarianConfig            *legacyconfig.LibrarianConfig
 		image                      string
+		allowTag                   bool
 		build                      bool
 		commit                     bool
 		push                       bool
 		test                       bool
 		libraryToTest              string
+		onlyIfAPIChanged           bool
+		force                      bool
+		changedFiles               []string
 		wantErr                    bool
 		wantErrMsg                 string
 		wantFindLatestCalls        int
@@ -210,12 +214,14 @@ func TestUpdateImageRunnerRun(t *testing.T) {
 		wantCreatePullRequestCalls int
 		wantCreateIssueCalls       int
 		wantCommitMsg              string
+		wantCommitCalls            int
 		checkoutError              error
 		wantImage                  string
 	}{
 		{
-			name:  "specific image",
-			image: "some-image",
+			name:     "specific image",
+			image:    "some-image",
+			allowTag: true,
 			state: &legacyconfig.LibrarianState{
 				Image: "gcr.io/test/image:v1.2.3",
 				Libraries: []*legacyconfig.LibraryState{
@@ -239,8 +245,9 @@ func TestUpdateImageRunnerRun(t *testing.T) {
 			wantImage:           "some-image",
 		},
 		{
-			name:  "no change image",
-			image: "gcr.io/test/image:v1.2.3",
+			name:     "no change image",
+			image:    "gcr.io/test/image:v1.2.3",
+			allowTag: true,
 			state: &legacyconfig.LibrarianState{
 				Image: "gcr.io/test/image:v1.2.3",
 				Libraries: []*legacyconfig.LibraryState{
@@ -263,6 +270,52 @@ func TestUpdateImageRunnerRun(t *testing.T) {
 			wantCheckoutCalls:   2,
 			wantImage:           "gcr.io/test/image:v1.2.3",
 		},
+		{
+			name:  "specific image pinned to a digest",
+			image: "gcr.io/test/image@sha256:abc123",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:   "lib1",
+						APIs: []*legacyconfig.API{{Path: "some/api1"}},
+						SourceRoots: []string{
+							"src/a",
+						},
+						LastGeneratedCommit: "abcd1234",
+					},
+				},
+			},
+			containerClient:     &mockContainerClient{},
+			imagesClient:        &mockImagesClient{},
+			ghClient:            &mockGitHubClient{},
+			wantFindLatestCalls: 0,
+			wantGenerateCalls:   1,
+			wantCheckoutCalls:   2,
+			wantImage:           "gcr.io/test/image@sha256:abc123",
+		},
+		{
+			name:  "specific image with a floating tag",
+			image: "gcr.io/test/image:latest",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:   "lib1",
+						APIs: []*legacyconfig.API{{Path: "some/api1"}},
+						SourceRoots: []string{
+							"src/a",
+						},
+						LastGeneratedCommit: "abcd1234",
+					},
+				},
+			},
+			containerClient: &mockContainerClient{},
+			imagesClient:    &mockImagesClient{},
+			ghClient:        &mockGitHubClient{},
+			wantErr:         true,
+			wantErrMsg:      `image "gcr.io/test/image:latest" is not pinned to a digest`,
+		},
 		{
 			name: "finds latest image",
 			state: &legacyconfig.LibrarianState{
@@ -289,6 +342,30 @@ func TestUpdateImageRunnerRun(t *testing.T) {
 			wantCheckoutCalls:   2,
 			wantImage:           "gcr.io/test/image@sha256:abc123",
 		},
+		{
+			name: "latest image not pinned to a digest",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:   "lib1",
+						APIs: []*legacyconfig.API{{Path: "some/api1"}},
+						SourceRoots: []string{
+							"src/a",
+						},
+						LastGeneratedCommit: "abcd1234",
+					},
+				},
+			},
+			containerClient: &mockContainerClient{},
+			imagesClient: &mockImagesClient{
+				latestImage: "gcr.io/test/image:latest",
+			},
+			ghClient:            &mockGitHubClient{},
+			wantFindLatestCalls: 1,
+			wantErr:             true,
+			wantErrMsg:          `latest image "gcr.io/test/image:latest" is not pinned to a digest`,
+		},
 		{
 			name: "finds image error",
 			state: &legacyconfig.LibrarianState{
@@ -554,6 +631,7 @@ func TestUpdateImageRunnerRun(t *testing.T) {
 			wantBuildCalls:      2,
 			wantCheckoutCalls:   3,
 			wantCommitMsg:       "feat: update image to gcr.io/test/image@sha256:abc123",
+			wantCommitCalls:     1,
 		},
 		{
 			name: "push failure",
@@ -596,6 +674,7 @@ func TestUpdateImageRunnerRun(t *testing.T) {
 			wantCommitMsg:              "feat: update image to gcr.io/test/image@sha256:abc123",
 			wantErr:                    true,
 			wantErrMsg:                 "some API error",
+			wantCommitCalls:            1,
 		},
 		{
 			name: "updates multiple with push",
@@ -642,6 +721,7 @@ func TestUpdateImageRunnerRun(t *testing.T) {
 			wantCheckoutCalls:          3,
 			wantCreatePullRequestCalls: 1,
 			wantCommitMsg:              "feat: update image to gcr.io/test/image@sha256:abc123",
+			wantCommitCalls:            1,
 		},
 		{
 			name: "runs test",
@@ -678,7 +758,8 @@ func TestUpdateImageRunnerRun(t *testing.T) {
 			wantCheckoutCalls:   3,
 			wantErr:             true,
 			// The test setup does not have protos, so the test fails in the preparation step.
-			wantErrMsg: "failed in test preparing steps",
+			wantErrMsg:      "failed in test preparing steps",
+			wantCommitCalls: 1,
 		},
 		{
 			name: "partial updates with push",
@@ -728,6 +809,81 @@ func TestUpdateImageRunnerRun(t *testing.T) {
 			wantCreatePullRequestCalls: 1,
 			wantCreateIssueCalls:       1,
 			wantCommitMsg:              "feat: update image to gcr.io/test/image@sha256:abc123",
+			wantCommitCalls:            1,
+		},
+		{
+			name:             "only if api changed, keeps changed libraries",
+			onlyIfAPIChanged: true,
+			build:            true,
+			commit:           true,
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:                  "lib1",
+						APIs:                []*legacyconfig.API{{Path: "some/api1"}},
+						SourceRoots:         []string{"src/a"},
+						LastGeneratedCommit: "abcd1234",
+					},
+					{
+						ID:                  "lib2",
+						APIs:                []*legacyconfig.API{{Path: "some/api2"}},
+						SourceRoots:         []string{"src/b"},
+						LastGeneratedCommit: "abcd1235",
+					},
+				},
+			},
+			changedFiles: []string{
+				".librarian/state.yaml",
+				"src/a/file.go",
+			},
+			containerClient: &mockContainerClient{},
+			imagesClient: &mockImagesClient{
+				latestImage: "gcr.io/test/image@sha256:abc123",
+			},
+			ghClient:            &mockGitHubClient{},
+			wantFindLatestCalls: 1,
+			wantGenerateCalls:   2,
+			wantBuildCalls:      1,
+			wantCheckoutCalls:   3,
+			wantCommitCalls:     1,
+			wantCommitMsg:       "feat: update image to gcr.io/test/image@sha256:abc123",
+		},
+		{
+			name:             "only if api changed, no library changed",
+			onlyIfAPIChanged: true,
+			build:            true,
+			commit:           true,
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:                  "lib1",
+						APIs:                []*legacyconfig.API{{Path: "some/api1"}},
+						SourceRoots:         []string{"src/a"},
+						LastGeneratedCommit: "abcd1234",
+					},
+					{
+						ID:                  "lib2",
+						APIs:                []*legacyconfig.API{{Path: "some/api2"}},
+						SourceRoots:         []string{"src/b"},
+						LastGeneratedCommit: "abcd1235",
+					},
+				},
+			},
+			changedFiles: []string{
+				".librarian/state.yaml",
+			},
+			containerClient: &mockContainerClient{},
+			imagesClient: &mockImagesClient{
+				latestImage: "gcr.io/test/image@sha256:abc123",
+			},
+			ghClient:            &mockGitHubClient{},
+			wantFindLatestCalls: 1,
+			wantGenerateCalls:   2,
+			wantBuildCalls:      0,
+			wantCheckoutCalls:   3,
+			wantCommitCalls:     0,
 		},
 		{
 			name: "skip generation for library",
@@ -751,14 +907,80 @@ func TestUpdateImageRunnerRun(t *testing.T) {
 					},
 				},
 			},
-			containerClient:     &mockContainerClient{},
-			imagesClient:        &mockImagesClient{},
+			containerClient: &mockContainerClient{},
+			imagesClient: &mockImagesClient{
+				latestImage: "gcr.io/test/image@sha256:abc123",
+			},
+			ghClient:            &mockGitHubClient{},
+			wantFindLatestCalls: 1,
+			wantGenerateCalls:   0,
+			wantBuildCalls:      0,
+			wantCheckoutCalls:   1,
+		},
+		{
+			name: "skip generation for frozen library",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:                  "frozen-lib",
+						APIs:                []*legacyconfig.API{{Path: "some/api1"}},
+						SourceRoots:         []string{"src/a"},
+						LastGeneratedCommit: "abcd1234",
+					},
+				},
+			},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				Libraries: []*legacyconfig.LibraryConfig{
+					{
+						LibraryID: "frozen-lib",
+						Frozen:    true,
+					},
+				},
+			},
+			containerClient: &mockContainerClient{},
+			imagesClient: &mockImagesClient{
+				latestImage: "gcr.io/test/image@sha256:abc123",
+			},
 			ghClient:            &mockGitHubClient{},
 			wantFindLatestCalls: 1,
 			wantGenerateCalls:   0,
 			wantBuildCalls:      0,
 			wantCheckoutCalls:   1,
 		},
+		{
+			name: "force generation for frozen library",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:                  "frozen-lib",
+						APIs:                []*legacyconfig.API{{Path: "some/api1"}},
+						SourceRoots:         []string{"src/a"},
+						LastGeneratedCommit: "abcd1234",
+					},
+				},
+			},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				Libraries: []*legacyconfig.LibraryConfig{
+					{
+						LibraryID: "frozen-lib",
+						Frozen:    true,
+					},
+				},
+			},
+			force:           true,
+			changedFiles:    []string{".librarian/state.yaml"},
+			containerClient: &mockContainerClient{},
+			imagesClient: &mockImagesClient{
+				latestImage: "gcr.io/test/image@sha256:abc123",
+			},
+			ghClient:            &mockGitHubClient{},
+			wantFindLatestCalls: 1,
+			wantGenerateCalls:   1,
+			wantBuildCalls:      0,
+			wantCheckoutCalls:   2,
+		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			testRepo := newTestGitRepoWithState(t, test.state)
@@ -770,26 +992,30 @@ func TestUpdateImageRunnerRun(t *testing.T) {
 						URLs: []string{"https://github.com/googleapis/google-cloud-go.git"},
 					},
 				},
+				ChangedFilesValue: test.changedFiles,
 			}
 			sourceRepo := &MockRepository{
 				CheckoutError: test.checkoutError,
 			}
 			r := &updateImageRunner{
-				branch:          "main",
-				build:           test.build,
-				commit:          test.commit,
-				push:            test.push,
-				test:            test.test,
-				libraryToTest:   test.libraryToTest,
-				image:           test.image,
-				containerClient: test.containerClient,
-				imagesClient:    test.imagesClient,
-				ghClient:        test.ghClient,
-				state:           test.state,
-				librarianConfig: test.librarianConfig,
-				workRoot:        t.TempDir(),
-				repo:            repo,
-				sourceRepo:      sourceRepo,
+				branch:           "main",
+				build:            test.build,
+				commit:           test.commit,
+				push:             test.push,
+				test:             test.test,
+				libraryToTest:    test.libraryToTest,
+				image:            test.image,
+				allowTag:         test.allowTag,
+				onlyIfAPIChanged: test.onlyIfAPIChanged,
+				force:            test.force,
+				containerClient:  test.containerClient,
+				imagesClient:     test.imagesClient,
+				ghClient:         test.ghClient,
+				state:            test.state,
+				librarianConfig:  test.librarianConfig,
+				workRoot:         t.TempDir(),
+				repo:             repo,
+				sourceRepo:       sourceRepo,
 			}
 
 			err := r.run(t.Context())
@@ -817,6 +1043,9 @@ func TestUpdateImageRunnerRun(t *testing.T) {
 			if diff := cmp.Diff(test.wantCreateIssueCalls, test.ghClient.createIssueCalls); diff != "" {
 				t.Errorf("%s: run() createIssueCalls mismatch (-want +got):%s", test.name, diff)
 			}
+			if diff := cmp.Diff(test.wantCommitCalls, repo.CommitCalls); diff != "" {
+				t.Errorf("%s: run() commitCalls mismatch (-want +got):%s", test.name, diff)
+			}
 
 			if test.wantErr {
 				if err == nil {
@@ -842,6 +1071,24 @@ func TestUpdateImageRunnerRun(t *testing.T) {
 	}
 }
 
+func TestHasImageDigest(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		image string
+		want  bool
+	}{
+		{image: "us-central1-docker.pkg.dev/project/repo/image@sha256:0123456789abcdef", want: true},
+		{image: "us-central1-docker.pkg.dev/project/repo/image:latest"},
+		{image: "us-central1-docker.pkg.dev/project/repo/image:v1.2.3"},
+		{image: "us-central1-docker.pkg.dev/project/repo/image"},
+		{image: "us-central1-docker.pkg.dev/project/repo/image@sha256:"},
+	} {
+		if got := hasImageDigest(test.image); got != test.want {
+			t.Errorf("hasImageDigest(%q) = %t, want %t", test.image, got, test.want)
+		}
+	}
+}
+
 func TestFormatUpdateImagePRBody(t *testing.T) {
 	t.Parallel()
 	for _, test := range []struct {
@@ -0,0 +1,150 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+	"gopkg.in/yaml.v3"
+)
+
+type validateRunner struct {
+	out     io.Writer
+	repoDir string
+}
+
+func newValidateRunner(cfg *legacyconfig.Config) (*validateRunner, error) {
+	repoDir := cfg.Repo
+	if isURL(cfg.Repo) {
+		repo, err := cloneOrOpenRepo(cfg.WorkRoot, cfg.Repo, cfg.APISourceDepth, cfg.Branch, cfg.CI, cfg.GitHubToken)
+		if err != nil {
+			return nil, err
+		}
+		repoDir = repo.GetDir()
+	}
+	if repoDir == "" {
+		return nil, errors.New("repo must be specified")
+	}
+	return &validateRunner{
+		out:     os.Stdout,
+		repoDir: repoDir,
+	}, nil
+}
+
+// run reports the problems found in the state.yaml file of the repository,
+// one per line, and returns an error if there is any.
+func (r *validateRunner) run(ctx context.Context) error {
+	statePath := filepath.Join(r.repoDir, legacyconfig.LibrarianDir, librarianStateFile)
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		return err
+	}
+	// The state is validated as part of the checks below, so that all the
+	// problems are reported instead of the first one only.
+	var state legacyconfig.LibrarianState
+	if err := yaml.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("unmarshaling librarian state: %w", err)
+	}
+	librarianConfig, err := parseLibrarianConfig(filepath.Join(r.repoDir, legacyconfig.LibrarianDir, librarianConfigFile))
+	if err != nil {
+		return err
+	}
+
+	issues := validateState(r.repoDir, &state, librarianConfig)
+	if len(issues) == 0 {
+		fmt.Fprintf(r.out, "%s is valid\n", statePath)
+		return nil
+	}
+	for _, issue := range issues {
+		fmt.Fprintln(r.out, issue)
+	}
+	return fmt.Errorf("found %d issue(s) in %s", len(issues), statePath)
+}
+
+// validateState returns the problems found in state: duplicate library IDs,
+// libraries without APIs or source roots, source roots missing from repoDir,
+// libraries without maintainers when librarianConfig requires them, and APIs
+// referenced by several libraries which are not global files. If
+// none of these is found, the other problems reported by
+// [legacyconfig.LibrarianState.Validate] are returned.
+func validateState(repoDir string, state *legacyconfig.LibrarianState, librarianConfig *legacyconfig.LibrarianConfig) []string {
+	var issues []string
+	apiLibraries := make(map[string][]string)
+	seen := make(map[string]bool)
+	for i, library := range state.Libraries {
+		if library == nil {
+			issues = append(issues, fmt.Sprintf("library at index %d is empty", i))
+			continue
+		}
+		if seen[library.ID] {
+			issues = append(issues, fmt.Sprintf("duplicate library ID %q", library.ID))
+		}
+		seen[library.ID] = true
+		if len(library.APIs) == 0 {
+			issues = append(issues, fmt.Sprintf("library %q has no APIs", library.ID))
+		}
+		if len(library.SourceRoots) == 0 {
+			issues = append(issues, fmt.Sprintf("library %q has no source roots", library.ID))
+		}
+		for _, root := range library.SourceRoots {
+			if root == "" {
+				issues = append(issues, fmt.Sprintf("library %q has an empty source root", library.ID))
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(repoDir, root)); err != nil {
+				issues = append(issues, fmt.Sprintf("library %q: source root %q does not exist", library.ID, root))
+			}
+		}
+		for _, api := range library.APIs {
+			if !slices.Contains(apiLibraries[api.Path], library.ID) {
+				apiLibraries[api.Path] = append(apiLibraries[api.Path], library.ID)
+			}
+		}
+	}
+
+	for _, id := range librarianConfig.LibrariesWithoutMaintainers(state) {
+		issues = append(issues, fmt.Sprintf("library %q has no maintainers", id))
+	}
+
+	var globalFiles []string
+	if librarianConfig != nil {
+		globalFiles = librarianConfig.GetGlobalFiles()
+	}
+	var shared []string
+	for apiPath, libraryIDs := range apiLibraries {
+		if len(libraryIDs) > 1 && !slices.Contains(globalFiles, apiPath) {
+			shared = append(shared, apiPath)
+		}
+	}
+	slices.Sort(shared)
+	for _, apiPath := range shared {
+		issues = append(issues, fmt.Sprintf("API %q is referenced by multiple libraries: %s", apiPath, strings.Join(apiLibraries[apiPath], ", ")))
+	}
+
+	if len(issues) == 0 {
+		if err := state.Validate(); err != nil {
+			issues = append(issues, err.Error())
+		}
+	}
+	return issues
+}
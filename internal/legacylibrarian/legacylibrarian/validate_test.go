@@ -0,0 +1,281 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacylibrarian
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+)
+
+func TestValidateState(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name            string
+		state           *legacyconfig.LibrarianState
+		librarianConfig *legacyconfig.LibrarianConfig
+		want            []string
+	}{
+		{
+			name: "valid state",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:          "library-a",
+						APIs:        []*legacyconfig.API{{Path: "google/cloud/a/v1"}},
+						SourceRoots: []string{"a"},
+					},
+				},
+			},
+		},
+		{
+			name: "duplicate library IDs",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:          "library-a",
+						APIs:        []*legacyconfig.API{{Path: "google/cloud/a/v1"}},
+						SourceRoots: []string{"a"},
+					},
+					{
+						ID:          "library-a",
+						APIs:        []*legacyconfig.API{{Path: "google/cloud/a/v2"}},
+						SourceRoots: []string{"a"},
+					},
+				},
+			},
+			want: []string{`duplicate library ID "library-a"`},
+		},
+		{
+			name: "nil library",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					nil,
+					{
+						ID:          "library-a",
+						APIs:        []*legacyconfig.API{{Path: "google/cloud/a/v1"}},
+						SourceRoots: []string{"a"},
+					},
+				},
+			},
+			want: []string{"library at index 0 is empty"},
+		},
+		{
+			name: "library without APIs or source roots",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID: "library-a",
+					},
+				},
+			},
+			want: []string{
+				`library "library-a" has no APIs`,
+				`library "library-a" has no source roots`,
+			},
+		},
+		{
+			name: "empty and missing source roots",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:          "library-a",
+						APIs:        []*legacyconfig.API{{Path: "google/cloud/a/v1"}},
+						SourceRoots: []string{"a", "", "missing"},
+					},
+				},
+			},
+			want: []string{
+				`library "library-a" has an empty source root`,
+				`library "library-a": source root "missing" does not exist`,
+			},
+		},
+		{
+			name: "API referenced by multiple libraries",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:          "library-a",
+						APIs:        []*legacyconfig.API{{Path: "google/cloud/a/v1"}},
+						SourceRoots: []string{"a"},
+					},
+					{
+						ID:          "library-b",
+						APIs:        []*legacyconfig.API{{Path: "google/cloud/a/v1"}},
+						SourceRoots: []string{"b"},
+					},
+				},
+			},
+			want: []string{`API "google/cloud/a/v1" is referenced by multiple libraries: library-a, library-b`},
+		},
+		{
+			name: "API referenced by multiple libraries as a global file",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:          "library-a",
+						APIs:        []*legacyconfig.API{{Path: "google/cloud/common"}},
+						SourceRoots: []string{"a"},
+					},
+					{
+						ID:          "library-b",
+						APIs:        []*legacyconfig.API{{Path: "google/cloud/common"}},
+						SourceRoots: []string{"b"},
+					},
+				},
+			},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				GlobalFilesAllowlist: []*legacyconfig.GlobalFile{
+					{Path: "google/cloud/common", Permissions: legacyconfig.PermissionReadOnly},
+				},
+			},
+		},
+		{
+			name: "libraries without required maintainers",
+			state: &legacyconfig.LibrarianState{
+				Image: "gcr.io/test/image:v1.2.3",
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:          "library-a",
+						APIs:        []*legacyconfig.API{{Path: "google/cloud/a/v1"}},
+						SourceRoots: []string{"a"},
+					},
+					{
+						ID:          "library-b",
+						APIs:        []*legacyconfig.API{{Path: "google/cloud/b/v1"}},
+						SourceRoots: []string{"b"},
+					},
+				},
+			},
+			librarianConfig: &legacyconfig.LibrarianConfig{
+				RequireMaintainers: true,
+				Libraries: []*legacyconfig.LibraryConfig{
+					{LibraryID: "library-b", Maintainers: []string{"octocat"}},
+				},
+			},
+			want: []string{`library "library-a" has no maintainers`},
+		},
+		{
+			name: "other state problems",
+			state: &legacyconfig.LibrarianState{
+				Libraries: []*legacyconfig.LibraryState{
+					{
+						ID:          "library-a",
+						APIs:        []*legacyconfig.API{{Path: "google/cloud/a/v1"}},
+						SourceRoots: []string{"a"},
+					},
+				},
+			},
+			want: []string{"image is required"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repoDir := t.TempDir()
+			for _, dir := range []string{"a", "b"} {
+				if err := os.Mkdir(filepath.Join(repoDir, dir), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got := validateState(repoDir, test.state, test.librarianConfig)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("validateState() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateRun(t *testing.T) {
+	t.Parallel()
+	for _, test := range []struct {
+		name       string
+		state      string
+		wantOut    string
+		wantErrMsg string
+	}{
+		{
+			name: "valid state",
+			state: `image: gcr.io/test/image:v1.2.3
+libraries:
+  - id: library-a
+    apis:
+      - path: google/cloud/a/v1
+    source_roots:
+      - a
+`,
+			wantOut: "is valid",
+		},
+		{
+			name: "invalid state",
+			state: `image: gcr.io/test/image:v1.2.3
+libraries:
+  - id: library-a
+    source_roots:
+      - missing
+  - id: library-a
+    apis:
+      - path: google/cloud/a/v1
+    source_roots:
+      - a
+`,
+			wantOut:    `library "library-a": source root "missing" does not exist`,
+			wantErrMsg: "found 3 issue(s)",
+		},
+		{
+			name:       "malformed state",
+			state:      "libraries: [",
+			wantErrMsg: "unmarshaling librarian state",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			repoDir := t.TempDir()
+			if err := os.MkdirAll(filepath.Join(repoDir, "a"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.MkdirAll(filepath.Join(repoDir, legacyconfig.LibrarianDir), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(repoDir, legacyconfig.LibrarianDir, librarianStateFile), []byte(test.state), 0644); err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			r := &validateRunner{out: &out, repoDir: repoDir}
+			err := r.run(t.Context())
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("run() error = %v, want error containing %q", err, test.wantErrMsg)
+				}
+			} else if err != nil {
+				t.Fatalf("run() failed: %v", err)
+			}
+			if !strings.Contains(out.String(), test.wantOut) {
+				t.Errorf("run() output = %q, want it to contain %q", out.String(), test.wantOut)
+			}
+		})
+	}
+}
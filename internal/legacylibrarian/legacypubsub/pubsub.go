@@ -0,0 +1,97 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package legacypubsub publishes messages to Pub/Sub topics.
+package legacypubsub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"cloud.google.com/go/auth"
+	"cloud.google.com/go/auth/credentials"
+)
+
+const (
+	defaultEndpoint = "https://pubsub.googleapis.com/v1/"
+	pubSubScope     = "https://www.googleapis.com/auth/pubsub"
+)
+
+// Client publishes messages to Pub/Sub topics with the Pub/Sub REST API.
+type Client struct {
+	httpClient *http.Client
+	creds      auth.TokenProvider
+	endpoint   string
+}
+
+// NewClient creates a Client authenticated with application default
+// credentials.
+func NewClient() (*Client, error) {
+	creds, err := credentials.DetectDefault(&credentials.DetectOptions{
+		Scopes: []string{pubSubScope},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find Pub/Sub credentials: %w", err)
+	}
+	return &Client{
+		httpClient: http.DefaultClient,
+		creds:      creds,
+		endpoint:   defaultEndpoint,
+	}, nil
+}
+
+type pubSubMessage struct {
+	Data []byte `json:"data"`
+}
+
+type publishRequest struct {
+	Messages []*pubSubMessage `json:"messages"`
+}
+
+// Publish publishes a message with the given data to topic, which is of the
+// form "projects/{project}/topics/{topic}".
+func (c *Client) Publish(ctx context.Context, topic string, data []byte) error {
+	body, err := json.Marshal(&publishRequest{
+		Messages: []*pubSubMessage{{Data: data}},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+topic+":publish", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.creds != nil {
+		token, err := c.creds.Token(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get Pub/Sub access token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token.Value)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", topic, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to publish to %s: %s: %s", topic, resp.Status, bytes.TrimSpace(respBody))
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package legacypubsub
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/auth"
+	"github.com/google/go-cmp/cmp"
+)
+
+type fakeTokenProvider struct{}
+
+func (fakeTokenProvider) Token(context.Context) (*auth.Token, error) {
+	return &auth.Token{Value: "fake-token"}, nil
+}
+
+func TestPublish(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		status     int
+		response   string
+		wantErrMsg string
+	}{
+		{
+			name:     "success",
+			status:   http.StatusOK,
+			response: `{"messageIds": ["1"]}`,
+		},
+		{
+			name:       "topic not found",
+			status:     http.StatusNotFound,
+			response:   `{"error": {"message": "Resource not found"}}`,
+			wantErrMsg: "404 Not Found",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var gotPath, gotAuthorization string
+			var gotRequest publishRequest
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				gotAuthorization = r.Header.Get("Authorization")
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := json.Unmarshal(body, &gotRequest); err != nil {
+					t.Fatal(err)
+				}
+				w.WriteHeader(test.status)
+				w.Write([]byte(test.response))
+			}))
+			defer server.Close()
+
+			client := &Client{
+				httpClient: server.Client(),
+				creds:      fakeTokenProvider{},
+				endpoint:   server.URL + "/v1/",
+			}
+			err := client.Publish(t.Context(), "projects/my-project/topics/my-topic", []byte(`{"library":"a"}`))
+			if test.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErrMsg) {
+					t.Fatalf("Publish() error = %v, want contains %q", err, test.wantErrMsg)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff("/v1/projects/my-project/topics/my-topic:publish", gotPath); diff != "" {
+				t.Errorf("path mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff("Bearer fake-token", gotAuthorization); diff != "" {
+				t.Errorf("authorization mismatch (-want +got):\n%s", diff)
+			}
+			want := publishRequest{Messages: []*pubSubMessage{{Data: []byte(`{"library":"a"}`)}}}
+			if diff := cmp.Diff(want, gotRequest); diff != "" {
+				t.Errorf("request mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@
 package rust
 
 import (
+	"log/slog"
 	"strings"
 
 	"github.com/googleapis/librarian/internal/config"
@@ -66,9 +67,19 @@ func toSidekickConfig(library *config.Library, channel *config.Channel, googleap
 		if len(library.Rust.PaginationOverrides) > 0 {
 			sidekickCfg.PaginationOverrides = make([]sidekickconfig.PaginationOverride, len(library.Rust.PaginationOverrides))
 			for i, override := range library.Rust.PaginationOverrides {
+				// The generator pages through a single field, the first one
+				// when several are listed.
+				itemField := override.ItemField
+				if itemField == "" && len(override.ItemFields) > 0 {
+					itemField = override.ItemFields[0]
+				}
+				if len(override.ItemFields) > 1 {
+					slog.Warn("pagination override lists several item fields, only the first one is used",
+						"library", library.Name, "id", override.ID, "item_fields", override.ItemFields, "item_field", itemField)
+				}
 				sidekickCfg.PaginationOverrides[i] = sidekickconfig.PaginationOverride{
 					ID:        override.ID,
-					ItemField: override.ItemField,
+					ItemField: itemField,
 				}
 			}
 		}
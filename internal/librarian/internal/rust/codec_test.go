@@ -313,6 +313,45 @@ func TestToSidekickConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "with pagination override with multiple item fields",
+			library: &config.Library{
+				Name: "google-cloud-storage",
+				Rust: &config.RustCrate{
+					PaginationOverrides: []config.RustPaginationOverride{
+						{
+							ID:         ".google.cloud.storage.v1.Storage.ListObjects",
+							ItemFields: []string{"objects", "prefixes"},
+						},
+					},
+				},
+			},
+			channel: &config.Channel{
+				Path:          "google/cloud/storage/v1",
+				ServiceConfig: "google/cloud/storage/v1/storage_v1.yaml",
+			},
+			googleapisDir: "/tmp/googleapis",
+			want: &sidekickconfig.Config{
+				General: sidekickconfig.GeneralConfig{
+					Language:            "rust",
+					SpecificationFormat: "protobuf",
+					ServiceConfig:       "google/cloud/storage/v1/storage_v1.yaml",
+					SpecificationSource: "google/cloud/storage/v1",
+				},
+				Source: map[string]string{
+					"googleapis-root": "/tmp/googleapis",
+				},
+				Codec: map[string]string{
+					"package-name-override": "google-cloud-storage",
+				},
+				PaginationOverrides: []sidekickconfig.PaginationOverride{
+					{
+						ID:        ".google.cloud.storage.v1.Storage.ListObjects",
+						ItemField: "objects",
+					},
+				},
+			},
+		},
 		{
 			name: "with discovery format",
 			library: &config.Library{
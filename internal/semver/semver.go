@@ -114,6 +114,29 @@ func Parse(versionString string) (*Version, error) {
 	return v, nil
 }
 
+// IsValid reports whether versionString is a complete semantic version, with
+// major, minor and patch numbers and no "v" prefix.
+func IsValid(versionString string) bool {
+	if strings.HasPrefix(versionString, "v") || !semver.IsValid("v"+versionString) {
+		return false
+	}
+	core, _, _ := strings.Cut(versionString, "+")
+	core, _, _ = strings.Cut(core, "-")
+	return strings.Count(core, ".") == 2
+}
+
+// Coerce returns the complete semantic version of versionString, which may
+// have a "v" prefix and omit its minor and patch numbers, e.g. "v2" or "1.0".
+// Missing numbers are zero-filled and build metadata is dropped. It returns an
+// error if versionString cannot be read as a semantic version.
+func Coerce(versionString string) (string, error) {
+	vPrefixedVersion := "v" + strings.TrimPrefix(versionString, "v")
+	if !semver.IsValid(vPrefixedVersion) {
+		return "", fmt.Errorf("invalid version format: %s", versionString)
+	}
+	return strings.TrimPrefix(semver.Canonical(vPrefixedVersion), "v"), nil
+}
+
 // String formats a Version struct into a string.
 func (v *Version) String() string {
 	version := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
@@ -164,6 +187,17 @@ func (v *Version) bump(highestChange ChangeLevel) {
 	}
 }
 
+// Compare returns an integer comparing versions a and b by SemVer precedence,
+// in which a pre-release version has lower precedence than its release
+// version, and pre-release identifiers are compared numerically when they are
+// numbers and lexically otherwise, e.g. 1.2.0-beta.2 < 1.2.0-beta.10 < 1.2.0.
+// The result is 0 if a == b, -1 if a < b, and +1 if a > b. Build metadata is
+// ignored. An invalid version, including one with a "v" prefix, is less than
+// any valid version and equal to other invalid versions.
+func Compare(a, b string) int {
+	return semver.Compare("v"+a, "v"+b)
+}
+
 // MaxVersion returns the largest semantic version string among the provided version strings.
 func MaxVersion(versionStrings ...string) string {
 	if len(versionStrings) == 0 {
@@ -255,6 +255,88 @@ func TestDeriveNext(t *testing.T) {
 	}
 }
 
+func TestIsValid(t *testing.T) {
+	for _, test := range []struct {
+		version string
+		want    bool
+	}{
+		{version: "1.2.3", want: true},
+		{version: "1.2.3-alpha.1", want: true},
+		{version: "1.2.3+build.5", want: true},
+		{version: "1.0", want: false},
+		{version: "1", want: false},
+		{version: "v1.2.3", want: false},
+		{version: "v2", want: false},
+		{version: "1.2.3.4", want: false},
+		{version: "latest", want: false},
+		{version: "", want: false},
+	} {
+		t.Run(test.version, func(t *testing.T) {
+			if got := IsValid(test.version); got != test.want {
+				t.Errorf("IsValid(%q) = %v, want %v", test.version, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCoerce(t *testing.T) {
+	for _, test := range []struct {
+		version string
+		want    string
+		wantErr bool
+	}{
+		{version: "1.2.3", want: "1.2.3"},
+		{version: "1.0", want: "1.0.0"},
+		{version: "v2", want: "2.0.0"},
+		{version: "v1.2.3-beta", want: "1.2.3-beta"},
+		{version: "1.2.3+build.5", want: "1.2.3"},
+		{version: "1.2.3.4", wantErr: true},
+		{version: "latest", wantErr: true},
+		{version: "", wantErr: true},
+	} {
+		t.Run(test.version, func(t *testing.T) {
+			got, err := Coerce(test.version)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("Coerce(%q) should return error", test.version)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("Coerce(%q) mismatch (-want +got):\n%s", test.version, diff)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+		want int
+	}{
+		{a: "1.2.3", b: "1.2.3", want: 0},
+		{a: "1.2.3", b: "1.2.4", want: -1},
+		{a: "1.2.0-beta.2", b: "1.2.0-beta.1", want: 1},
+		{a: "1.2.0-beta.2", b: "1.2.0-beta.10", want: -1},
+		{a: "1.2.0-alpha.5", b: "1.2.0-beta.1", want: -1},
+		{a: "1.2.0-beta", b: "1.2.0-beta.1", want: -1},
+		{a: "1.2.0-beta.3", b: "1.2.0", want: -1},
+		{a: "1.2.0-rc.1", b: "1.1.9", want: 1},
+		{a: "1.2.0-beta.1+build.5", b: "1.2.0-beta.1", want: 0},
+		{a: "v1.2.3", b: "1.0.0", want: -1},
+		{a: "latest", b: "v1", want: 0},
+	} {
+		t.Run(test.a+" "+test.b, func(t *testing.T) {
+			if got := Compare(test.a, test.b); got != test.want {
+				t.Errorf("Compare(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+			}
+		})
+	}
+}
+
 func TestMaxVersion(t *testing.T) {
 	for _, test := range []struct {
 		name     string
@@ -281,6 +363,11 @@ func TestMaxVersion(t *testing.T) {
 			versions: []string{"1.2.4", "1.2.4-alpha", "1.2.4-beta"},
 			want:     "1.2.4",
 		},
+		{
+			name:     "numeric pre-release identifiers",
+			versions: []string{"1.2.4-beta.9", "1.2.4-beta.10", "1.2.4-beta.2"},
+			want:     "1.2.4-beta.10",
+		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			got := MaxVersion(test.versions...)
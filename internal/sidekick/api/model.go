@@ -719,6 +719,9 @@ type Field struct {
 	// JSONName is the name of the field as it appears in JSON. Useful for
 	// serializing to JSON.
 	JSONName string
+	// Number is the field number. It is zero for specification formats without
+	// field numbers, such as OpenAPI.
+	Number int32
 	// Optional indicates that the field is marked as optional in proto3.
 	Optional bool
 
@@ -733,6 +736,10 @@ type Field struct {
 	Map bool
 	// Some source specifications allow marking fields as deprecated.
 	Deprecated bool
+	// Sensitive is true if the field carries sensitive data, such as tokens or
+	// passwords, which should not appear in logs or debug output. In Protobuf,
+	// these fields are marked with the `debug_redact` option.
+	Sensitive bool
 	// IsOneOf is true if the field is related to a one-of and not
 	// a proto3 optional field.
 	IsOneOf bool
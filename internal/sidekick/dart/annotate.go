@@ -15,6 +15,7 @@
 package dart
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -119,7 +120,10 @@ type messageAnnotation struct {
 	// A custom body for the message's constructor.
 	ConstructorBody string
 	ToStringLines   []string
-	Model           *api.API
+	// The fields in field number order, used to emit JSON with a stable key
+	// order regardless of the order in which the fields are declared.
+	ToJsonFields []*api.Field
+	Model        *api.API
 }
 
 // HasFields returns true if the message has fields.
@@ -610,10 +614,21 @@ func (annotate *annotateModel) annotateMessage(m *api.Message) {
 		OmitGeneration:  omit || m.IsMap,
 		ConstructorBody: constructorBody,
 		ToStringLines:   toStringLines,
+		ToJsonFields:    fieldsByNumber(m.Fields),
 		Model:           annotate.model,
 	}
 }
 
+// fieldsByNumber returns the fields sorted by field number. Fields without a
+// field number, e.g. from OpenAPI specifications, keep their relative order.
+func fieldsByNumber(fields []*api.Field) []*api.Field {
+	sorted := slices.Clone(fields)
+	slices.SortStableFunc(sorted, func(a, b *api.Field) int {
+		return cmp.Compare(a.Number, b.Number)
+	})
+	return sorted
+}
+
 func createToStringLines(message *api.Message) []string {
 	lines := []string{}
 
@@ -626,6 +641,11 @@ func createToStringLines(message *api.Message) []string {
 			continue
 		}
 
+		// Don't leak sensitive data, such as tokens or passwords.
+		if field.Sensitive {
+			continue
+		}
+
 		var value string
 		if strings.Contains(name, "$") {
 			value = "${" + name + "}"
@@ -510,6 +510,93 @@ func TestAnnotateMessageToString(t *testing.T) {
 	}
 }
 
+func TestAnnotateMessageToStringSensitive(t *testing.T) {
+	message := &api.Message{
+		Name:    "Credentials",
+		ID:      ".test.Credentials",
+		Package: "test",
+		Fields: []*api.Field{
+			{
+				Name:     "user",
+				JSONName: "user",
+				ID:       ".test.Credentials.user",
+				Typez:    api.STRING_TYPE,
+			},
+			{
+				Name:      "password",
+				JSONName:  "password",
+				ID:        ".test.Credentials.password",
+				Typez:     api.STRING_TYPE,
+				Sensitive: true,
+			},
+		},
+	}
+	model := api.NewTestAPI([]*api.Message{message}, []*api.Enum{}, []*api.Service{})
+	annotate := newAnnotateModel(model)
+	annotate.annotateModel(map[string]string{})
+
+	codec := message.Codec.(*messageAnnotation)
+	want := []string{"'user=$user',"}
+	if diff := cmp.Diff(want, codec.ToStringLines); diff != "" {
+		t.Errorf("mismatch in ToStringLines (-want, +got)\n:%s", diff)
+	}
+}
+
+func TestAnnotateMessageToJsonFields(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		fields []*api.Field
+		want   []string
+	}{
+		{
+			name: "declared out of order",
+			fields: []*api.Field{
+				{Name: "display_name", JSONName: "displayName", ID: ".test.Message.display_name", Typez: api.STRING_TYPE, Number: 3},
+				{Name: "name", JSONName: "name", ID: ".test.Message.name", Typez: api.STRING_TYPE, Number: 1},
+				{Name: "size", JSONName: "size", ID: ".test.Message.size", Typez: api.INT32_TYPE, Number: 2},
+			},
+			want: []string{"name", "size", "displayName"},
+		},
+		{
+			name: "without field numbers",
+			fields: []*api.Field{
+				{Name: "display_name", JSONName: "displayName", ID: ".test.Message.display_name", Typez: api.STRING_TYPE},
+				{Name: "name", JSONName: "name", ID: ".test.Message.name", Typez: api.STRING_TYPE},
+				{Name: "size", JSONName: "size", ID: ".test.Message.size", Typez: api.INT32_TYPE},
+			},
+			want: []string{"displayName", "name", "size"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			message := &api.Message{
+				Name:    "Message",
+				ID:      ".test.Message",
+				Package: "test",
+				Fields:  test.fields,
+			}
+			model := api.NewTestAPI([]*api.Message{message}, []*api.Enum{}, []*api.Service{})
+			annotate := newAnnotateModel(model)
+			annotate.annotateModel(map[string]string{})
+
+			// Annotating repeatedly must produce the same order.
+			for range 3 {
+				annotate.annotateMessage(message)
+				var got []string
+				for _, field := range message.Codec.(*messageAnnotation).ToJsonFields {
+					got = append(got, field.JSONName)
+				}
+				if diff := cmp.Diff(test.want, got); diff != "" {
+					t.Errorf("mismatch in ToJsonFields (-want, +got)\n:%s", diff)
+				}
+			}
+			// The declaration order is unchanged.
+			if diff := cmp.Diff(test.fields, message.Fields); diff != "" {
+				t.Errorf("mismatch in Fields (-want, +got)\n:%s", diff)
+			}
+		})
+	}
+}
+
 func TestBuildQueryLines(t *testing.T) {
 	for _, test := range []struct {
 		field *api.Field
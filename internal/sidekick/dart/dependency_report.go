@@ -0,0 +1,84 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dart
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/googleapis/librarian/internal/sidekick/api"
+)
+
+// DependencyConflict describes a package required with different version
+// constraints by different libraries.
+type DependencyConflict struct {
+	// The name of the package, e.g. "http".
+	Package string
+	// A mapping from each version constraint of the package (e.g. "^1.2.0")
+	// to the sorted names of the libraries requiring it.
+	Libraries map[string][]string
+}
+
+// LibraryDependencies returns the package name of the library generated from
+// model with the given codec options, and its dependencies as listed in its
+// pubspec.yaml file, mapped to their version constraints.
+//
+// As for the generated package, the dependencies are the packages imported by
+// the generated code and the packages listed in the `dependencies` option.
+func LibraryDependencies(model *api.API, options map[string]string) (string, map[string]string, error) {
+	annotate := newAnnotateModel(model)
+	if err := annotate.annotateModel(options); err != nil {
+		return "", nil, err
+	}
+	codec := model.Codec.(*modelAnnotations)
+	deps := map[string]string{}
+	for _, dep := range codec.PackageDependencies {
+		deps[dep.Name] = dep.Constraint
+	}
+	return codec.PackageName, deps, nil
+}
+
+// FindDependencyConflicts returns the packages required with different
+// version constraints across libraries, sorted by package name.
+//
+// libraries maps the package name of each library (e.g.
+// google_cloud_secretmanager) to its dependencies, as returned by
+// [LibraryDependencies].
+func FindDependencyConflicts(libraries map[string]map[string]string) []*DependencyConflict {
+	byPackage := map[string]map[string][]string{}
+	for library, deps := range libraries {
+		for name, constraint := range deps {
+			if byPackage[name] == nil {
+				byPackage[name] = map[string][]string{}
+			}
+			byPackage[name][constraint] = append(byPackage[name][constraint], library)
+		}
+	}
+
+	conflicts := []*DependencyConflict{}
+	for name, byConstraint := range byPackage {
+		if len(byConstraint) < 2 {
+			continue
+		}
+		for _, libraries := range byConstraint {
+			slices.Sort(libraries)
+		}
+		conflicts = append(conflicts, &DependencyConflict{Package: name, Libraries: byConstraint})
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Package < conflicts[j].Package
+	})
+	return conflicts
+}
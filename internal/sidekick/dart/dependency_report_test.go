@@ -0,0 +1,136 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dart
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/googleapis/librarian/internal/sidekick/api"
+)
+
+func TestLibraryDependencies(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		options  map[string]string
+		wantName string
+		want     map[string]string
+	}{
+		{
+			name:     "imported packages",
+			wantName: "google_cloud_test",
+			want:     map[string]string{"google_cloud_protobuf": "^7.8.9"},
+		},
+		{
+			name:     "dependencies option",
+			options:  map[string]string{"dependencies": "http, google_cloud_test"},
+			wantName: "google_cloud_test",
+			want: map[string]string{
+				"google_cloud_protobuf": "^7.8.9",
+				"http":                  "^4.5.6",
+			},
+		},
+		{
+			name:     "package name override",
+			options:  map[string]string{"package-name-override": "google_cloud_protobuf"},
+			wantName: "google_cloud_protobuf",
+			want:     map[string]string{},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			model := api.NewTestAPI([]*api.Message{}, []*api.Enum{}, []*api.Service{})
+			model.PackageName = "test"
+			options := maps.Clone(requiredConfig)
+			maps.Copy(options, test.options)
+			gotName, got, err := LibraryDependencies(model, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotName != test.wantName {
+				t.Errorf("LibraryDependencies() name = %q, want %q", gotName, test.wantName)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLibraryDependenciesMissingConstraint(t *testing.T) {
+	model := api.NewTestAPI([]*api.Message{}, []*api.Enum{}, []*api.Service{})
+	options := maps.Clone(requiredConfig)
+	options["dependencies"] = "meta"
+	if _, _, err := LibraryDependencies(model, options); err == nil {
+		t.Errorf("LibraryDependencies(%v) expected an error for the missing constraint of meta", options)
+	}
+}
+
+func TestFindDependencyConflicts(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		libraries map[string]map[string]string
+		want      []*DependencyConflict
+	}{
+		{
+			name: "no conflicts",
+			libraries: map[string]map[string]string{
+				"google_cloud_foo": {"http": "^1.2.0"},
+				"google_cloud_bar": {"http": "^1.2.0", "meta": "^1.0.0"},
+			},
+			want: []*DependencyConflict{},
+		},
+		{
+			name: "divergent constraints",
+			libraries: map[string]map[string]string{
+				"google_cloud_foo": {
+					"http":             "^1.2.0",
+					"google_cloud_gax": "^0.1.0",
+				},
+				"google_cloud_bar": {
+					"http":             "^2.0.0",
+					"google_cloud_gax": "^0.1.0",
+				},
+				"google_cloud_baz": {
+					"http":             "^1.2.0",
+					"google_cloud_gax": "^0.2.0",
+				},
+			},
+			want: []*DependencyConflict{
+				{
+					Package: "google_cloud_gax",
+					Libraries: map[string][]string{
+						"^0.1.0": {"google_cloud_bar", "google_cloud_foo"},
+						"^0.2.0": {"google_cloud_baz"},
+					},
+				},
+				{
+					Package: "http",
+					Libraries: map[string][]string{
+						"^1.2.0": {"google_cloud_baz", "google_cloud_foo"},
+						"^2.0.0": {"google_cloud_bar"},
+					},
+				},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := FindDependencyConflicts(test.libraries)
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
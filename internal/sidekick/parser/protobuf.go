@@ -508,7 +508,9 @@ func processMessage(state *api.APIState, m *descriptorpb.DescriptorProto, mFQN,
 			Name:          mf.GetName(),
 			ID:            mFQN + "." + mf.GetName(),
 			JSONName:      mf.GetJsonName(),
+			Number:        mf.GetNumber(),
 			Deprecated:    mf.GetOptions().GetDeprecated(),
+			Sensitive:     mf.GetOptions().GetDebugRedact(),
 			Optional:      isProtoOptional,
 			IsOneOf:       mf.OneofIndex != nil && !isProtoOptional,
 			AutoPopulated: protobufIsAutoPopulated(mf),
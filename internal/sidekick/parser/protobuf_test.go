@@ -186,6 +186,39 @@ func TestProtobuf_Scalar(t *testing.T) {
 	})
 }
 
+func TestProtobuf_FieldNumbers(t *testing.T) {
+	requireProtoc(t)
+	test := makeAPIForProtobuf(nil, newTestCodeGeneratorRequest(t, "scalar.proto"))
+	message, ok := test.State.MessageByID[".test.Fake"]
+	if !ok {
+		t.Fatalf("Cannot find message %s in API State", ".test.Fake")
+	}
+	got := map[string]int32{}
+	for _, f := range message.Fields {
+		got[f.Name] = f.Number
+	}
+	want := map[string]int32{
+		"f_double":   1,
+		"f_float":    2,
+		"f_int64":    3,
+		"f_uint64":   4,
+		"f_int32":    5,
+		"f_fixed64":  6,
+		"f_fixed32":  7,
+		"f_bool":     8,
+		"f_string":   9,
+		"f_bytes":    12,
+		"f_uint32":   13,
+		"f_sfixed32": 15,
+		"f_sfixed64": 16,
+		"f_sint32":   17,
+		"f_sint64":   18,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch in field numbers (-want, +got)\n:%s", diff)
+	}
+}
+
 func TestProtobuf_ScalarArray(t *testing.T) {
 	requireProtoc(t)
 	test := makeAPIForProtobuf(nil, newTestCodeGeneratorRequest(t, "scalar_array.proto"))
@@ -1679,11 +1712,40 @@ func TestProtobuf_AutoPopulated(t *testing.T) {
 		t.Fatalf("Cannot find method %s in API State", ".test.TestService.CreateFoo")
 	}
 	want := []*api.Field{request_id, request_id_optional, request_id_with_field_behavior}
-	if diff := cmp.Diff(want, method.AutoPopulated); diff != "" {
+	if diff := cmp.Diff(want, method.AutoPopulated, cmpopts.IgnoreFields(api.Field{}, "Number")); diff != "" {
 		t.Errorf("incorrect auto-populated fields on method (-want, +got)\n:%s", diff)
 	}
 }
 
+func TestProtobuf_Sensitive(t *testing.T) {
+	requireProtoc(t)
+	test := makeAPIForProtobuf(nil, newTestCodeGeneratorRequest(t, "sensitive.proto"))
+	m, ok := test.State.MessageByID[".test.Credentials"]
+	if !ok {
+		t.Fatalf("Cannot find %s in API State", ".test.Credentials")
+	}
+	apitest.CheckMessage(t, m, &api.Message{
+		Name:    "Credentials",
+		ID:      ".test.Credentials",
+		Package: "test",
+		Fields: []*api.Field{
+			{
+				Name:     "user",
+				JSONName: "user",
+				ID:       ".test.Credentials.user",
+				Typez:    api.STRING_TYPE,
+			},
+			{
+				Name:      "password",
+				JSONName:  "password",
+				ID:        ".test.Credentials.password",
+				Typez:     api.STRING_TYPE,
+				Sensitive: true,
+			},
+		},
+	})
+}
+
 func TestProtobuf_Deprecated(t *testing.T) {
 	requireProtoc(t)
 	test := makeAPIForProtobuf(nil, newTestCodeGeneratorRequest(t, "deprecated.proto"))
@@ -0,0 +1,201 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package api runs the commands of the legacy librarian from Go programs,
+// without shelling out to the legacylibrarian binary.
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacylibrarian"
+)
+
+// GenerateOptions configures Generate. Each field corresponds to the flag of
+// the generate command of the same name, e.g. APISource to -api-source, and
+// its zero value selects the default of the flag. See the documentation of
+// the legacylibrarian command for the meaning of each flag.
+//
+// RequireServiceConfig is the exception, corresponding to
+// -allow-missing-service-config=false, as the flag is true by default.
+//
+// The GitHub token used with Push is read from the LIBRARIAN_GITHUB_TOKEN
+// environment variable, as it is by the command.
+type GenerateOptions struct {
+	API                   string
+	APISource             string
+	Amend                 bool
+	Branch                string
+	Build                 bool
+	CacheDir              string
+	CommitMessageTemplate string
+	Concurrency           int
+	ContainerCPUs         string
+	ContainerLogDir       string
+	ContainerMemory       string
+	ContainerReuse        bool
+	ContainerTimeout      time.Duration
+	DestPrefix            string
+	DetectRenamedAPIs     bool
+	DryRun                bool
+	FailFast              bool
+	Force                 bool
+	GenerateUnchanged     bool
+	GenerateUnchangedFor  []string
+	GeneratorInput        string
+	HostMount             string
+	Image                 string
+	Language              string
+	Library               string
+	MaxContainers         int
+	Mounts                []string
+	NoLock                bool
+	NoNetwork             []string
+	NormalizeLineEndings  string
+	OnlyChanged           bool
+	Output                string
+	OutputManifest        string
+	OutputState           string
+	PRTemplate            string
+	ProgressInterval      time.Duration
+	PubSubTopic           string
+	Push                  bool
+	Quiet                 bool
+	RebaseOntoBase        bool
+	Repo                  string
+	Report                string
+	RequireServiceConfig  bool
+	SkipConfigure         bool
+}
+
+// GenerateReport is the outcome of Generate.
+type GenerateReport struct {
+	// Generated, Skipped and Failed are the numbers of libraries generated,
+	// skipped and failed.
+	Generated int
+	Skipped   int
+	Failed    int
+	// Libraries are the outcomes for each library considered.
+	Libraries []*LibraryReport
+}
+
+// LibraryReport is the outcome of Generate for a single library.
+type LibraryReport struct {
+	ID string
+	// Action is one of "configured", "regenerated" or "skipped".
+	Action string
+	// Generate is "succeeded" or "failed", and empty for skipped libraries.
+	Generate string
+	// Build is "succeeded" or "failed", and empty if the library was not built.
+	Build               string
+	Duration            time.Duration
+	LastGeneratedCommit string
+	// Error is the error which made the library fail, if any.
+	Error string
+}
+
+// Generate runs the generate command as configured by opts and returns the
+// outcome for each library considered. The report is returned along with the
+// error of the command, if any, and is nil if no library was considered, e.g.
+// because opts are invalid.
+func Generate(ctx context.Context, opts *GenerateOptions) (*GenerateReport, error) {
+	report, err := legacylibrarian.Generate(ctx, opts.config())
+	return newGenerateReport(report), err
+}
+
+// config returns the configuration of the generate command set by opts.
+func (opts *GenerateOptions) config() *legacyconfig.Config {
+	cfg := legacylibrarian.NewGenerateConfig()
+	cfg.API = opts.API
+	setIfNotZero(&cfg.APISource, opts.APISource)
+	cfg.Amend = opts.Amend
+	setIfNotZero(&cfg.Branch, opts.Branch)
+	cfg.Build = opts.Build
+	cfg.CacheDir = opts.CacheDir
+	cfg.CommitMessageTemplate = opts.CommitMessageTemplate
+	setIfNotZero(&cfg.Concurrency, opts.Concurrency)
+	cfg.ContainerCPUs = opts.ContainerCPUs
+	cfg.ContainerLogDir = opts.ContainerLogDir
+	cfg.ContainerMemory = opts.ContainerMemory
+	cfg.ContainerReuse = opts.ContainerReuse
+	setIfNotZero(&cfg.ContainerTimeout, opts.ContainerTimeout)
+	cfg.DestPrefix = opts.DestPrefix
+	cfg.DetectRenamedAPIs = opts.DetectRenamedAPIs
+	cfg.DryRun = opts.DryRun
+	cfg.FailFast = opts.FailFast
+	cfg.Force = opts.Force
+	cfg.GenerateUnchanged = opts.GenerateUnchanged
+	cfg.GenerateUnchangedFor = opts.GenerateUnchangedFor
+	cfg.GeneratorInput = opts.GeneratorInput
+	setIfNotZero(&cfg.HostMount, opts.HostMount)
+	cfg.Image = opts.Image
+	cfg.Language = opts.Language
+	cfg.Library = opts.Library
+	cfg.MaxContainers = opts.MaxContainers
+	cfg.Mounts = opts.Mounts
+	cfg.NoLock = opts.NoLock
+	cfg.NoNetwork = opts.NoNetwork
+	cfg.NormalizeLineEndings = opts.NormalizeLineEndings
+	cfg.OnlyChanged = opts.OnlyChanged
+	cfg.WorkRoot = opts.Output
+	cfg.OutputManifest = opts.OutputManifest
+	cfg.OutputState = opts.OutputState
+	cfg.PRTemplate = opts.PRTemplate
+	cfg.ProgressInterval = opts.ProgressInterval
+	cfg.PubSubTopic = opts.PubSubTopic
+	cfg.Push = opts.Push
+	cfg.Quiet = opts.Quiet
+	cfg.RebaseOntoBase = opts.RebaseOntoBase
+	cfg.Repo = opts.Repo
+	cfg.Report = opts.Report
+	cfg.AllowMissingServiceConfig = !opts.RequireServiceConfig
+	cfg.SkipConfigure = opts.SkipConfigure
+	return cfg
+}
+
+// setIfNotZero sets *dst to value unless value is the zero value, keeping the
+// default of the flag otherwise.
+func setIfNotZero[T comparable](dst *T, value T) {
+	var zero T
+	if value != zero {
+		*dst = value
+	}
+}
+
+// newGenerateReport returns the GenerateReport of report, or nil if report is
+// nil.
+func newGenerateReport(report *legacylibrarian.GenerationReport) *GenerateReport {
+	if report == nil {
+		return nil
+	}
+	result := &GenerateReport{
+		Generated: report.Generated,
+		Skipped:   report.Skipped,
+		Failed:    report.Failed,
+	}
+	for _, library := range report.Libraries {
+		result.Libraries = append(result.Libraries, &LibraryReport{
+			ID:                  library.ID,
+			Action:              library.Action,
+			Generate:            library.Generate,
+			Build:               library.Build,
+			Duration:            time.Duration(library.DurationSeconds * float64(time.Second)),
+			LastGeneratedCommit: library.LastGeneratedCommit,
+			Error:               library.Error,
+		})
+	}
+	return result
+}
@@ -0,0 +1,151 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacyconfig"
+	"github.com/googleapis/librarian/internal/legacylibrarian/legacylibrarian"
+)
+
+func TestGenerateOptionsConfig(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		opts *GenerateOptions
+		want *legacyconfig.Config
+	}{
+		{
+			name: "defaults",
+			opts: &GenerateOptions{},
+			want: &legacyconfig.Config{
+				AllowMissingServiceConfig: true,
+				APISource:                 "https://github.com/googleapis/googleapis",
+				Branch:                    "main",
+				CommandName:               "generate",
+				Concurrency:               1,
+				ContainerTimeout:          legacyconfig.DefaultContainerTimeout,
+				LogFormat:                 "text",
+				LogLevel:                  "info",
+			},
+		},
+		{
+			name: "options",
+			opts: &GenerateOptions{
+				API:                  "google/cloud/x/v1",
+				APISource:            "/path/to/googleapis",
+				Branch:               "dev",
+				Build:                true,
+				Concurrency:          4,
+				ContainerTimeout:     time.Minute,
+				Library:              "x",
+				Mounts:               []string{"/a:/b"},
+				Output:               "/path/to/output",
+				Repo:                 "/path/to/repo",
+				RequireServiceConfig: true,
+			},
+			want: &legacyconfig.Config{
+				API:              "google/cloud/x/v1",
+				APISource:        "/path/to/googleapis",
+				Branch:           "dev",
+				Build:            true,
+				CommandName:      "generate",
+				Concurrency:      4,
+				ContainerTimeout: time.Minute,
+				Library:          "x",
+				LogFormat:        "text",
+				LogLevel:         "info",
+				Mounts:           []string{"/a:/b"},
+				Repo:             "/path/to/repo",
+				WorkRoot:         "/path/to/output",
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.opts.config()
+			if diff := cmp.Diff(test.want, got, cmpopts.IgnoreFields(legacyconfig.Config{}, "GitHubToken", "HostMount")); diff != "" {
+				t.Errorf("config() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewGenerateReport(t *testing.T) {
+	report := &legacylibrarian.GenerationReport{
+		Generated: 1,
+		Failed:    1,
+		Libraries: []*legacylibrarian.LibraryGenerationReport{
+			{
+				ID:                  "a",
+				Action:              "regenerated",
+				Generate:            "succeeded",
+				Build:               "succeeded",
+				DurationSeconds:     1.5,
+				LastGeneratedCommit: "abcd1234",
+			},
+			{
+				ID:       "b",
+				Action:   "regenerated",
+				Generate: "failed",
+				Error:    "generation failed",
+			},
+		},
+	}
+	want := &GenerateReport{
+		Generated: 1,
+		Failed:    1,
+		Libraries: []*LibraryReport{
+			{
+				ID:                  "a",
+				Action:              "regenerated",
+				Generate:            "succeeded",
+				Build:               "succeeded",
+				Duration:            1500 * time.Millisecond,
+				LastGeneratedCommit: "abcd1234",
+			},
+			{
+				ID:       "b",
+				Action:   "regenerated",
+				Generate: "failed",
+				Error:    "generation failed",
+			},
+		},
+	}
+	if diff := cmp.Diff(want, newGenerateReport(report)); diff != "" {
+		t.Errorf("newGenerateReport() mismatch (-want +got):\n%s", diff)
+	}
+	if got := newGenerateReport(nil); got != nil {
+		t.Errorf("newGenerateReport(nil) = %+v, want nil", got)
+	}
+}
+
+func TestGenerateInvalidOptions(t *testing.T) {
+	t.Setenv(legacyconfig.LibrarianGithubToken, "token")
+	report, err := Generate(t.Context(), &GenerateOptions{
+		Amend: true,
+		Push:  true,
+		Repo:  t.TempDir(),
+	})
+	if err == nil || !strings.Contains(err.Error(), "failed to validate config") {
+		t.Errorf("Generate() error = %v, want failed to validate config", err)
+	}
+	if report != nil {
+		t.Errorf("Generate() report = %+v, want nil", report)
+	}
+}